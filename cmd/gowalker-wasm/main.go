@@ -0,0 +1,95 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build js,wasm
+
+// Command gowalker-wasm generates documentation of Go packages in browsers.
+// It exports the global object "gowalker" to JavaScript, whose functions take
+// files of a package directory as an object of file names to contents, and an
+// optional import path that defaults to the module path in go.mod file:
+//
+//	gowalker.json(files, importPath)   Documentation in JSON
+//	gowalker.html(files, importPath)   Standalone HTML page of documentation
+//	gowalker.api(files, importPath)    Exported API in the format of Go api/*.txt files
+//
+// Each function returns a string, or an Error if the package cannot be walked.
+//
+// Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o gowalker.wasm ./cmd/gowalker-wasm
+//
+// and load it with wasm_exec.js in $(go env GOROOT)/misc/wasm.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"syscall/js"
+
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+// walk walks the package of files and import path in arguments.
+func walk(args []js.Value) (*doc.Package, error) {
+	if len(args) == 0 || args[0].Type() != js.TypeObject {
+		return nil, errors.New("files are required")
+	}
+
+	files := make(map[string][]byte)
+	keys := js.Global().Get("Object").Call("keys", args[0])
+	for i := 0; i < keys.Length(); i++ {
+		name := keys.Index(i).String()
+		files[name] = []byte(args[0].Get(name).String())
+	}
+
+	var importPath string
+	if len(args) > 1 && args[1].Type() == js.TypeString {
+		importPath = args[1].String()
+	}
+	return doc.WalkFiles(importPath, files)
+}
+
+// export returns a JavaScript function that walks the package and formats its documentation.
+func export(format func(buf *bytes.Buffer, pdoc *doc.Package) error) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		pdoc, err := walk(args)
+		if err != nil {
+			return js.Global().Get("Error").New(err.Error())
+		}
+
+		var buf bytes.Buffer
+		if err = format(&buf, pdoc); err != nil {
+			return js.Global().Get("Error").New(err.Error())
+		}
+		return buf.String()
+	})
+}
+
+func main() {
+	js.Global().Set("gowalker", map[string]interface{}{
+		"json": export(func(buf *bytes.Buffer, pdoc *doc.Package) error {
+			return json.NewEncoder(buf).Encode(pdoc)
+		}),
+		"html": export(func(buf *bytes.Buffer, pdoc *doc.Package) error {
+			return doc.WritePage(buf, pdoc)
+		}),
+		"api": export(func(buf *bytes.Buffer, pdoc *doc.Package) error {
+			return doc.ExportAPI(buf, pdoc)
+		}),
+	})
+
+	// Functions are only callable while the program is running.
+	select {}
+}
@@ -0,0 +1,377 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Command gowalker generates documentation of Go packages from the command line.
+//
+// Usage:
+//
+//	gowalker doc <importpath|dir|archive>
+//	gowalker json <importpath|dir|archive>
+//	gowalker api <importpath|dir|archive>
+//	gowalker chunks <importpath|dir|archive>...
+//	gowalker strings [-json] <importpath|dir|archive>
+//	gowalker serve [-http addr] [-watch] [-db file] <importpath|dir|archive>
+//	gowalker diff <old> <new>
+//	gowalker export <db>
+//	gowalker import <db>
+//
+// Flag -config before the command loads settings from a configuration file in
+// YAML or TOML, -sort sets the order of declarations, -cost builds
+// commands in local directories to report size and dependencies of their binaries,
+// -coverprofile annotates functions with statement coverage of the profile,
+// -bench attaches results in go test -bench output to benchmarks, and -pprof
+// marks functions that take at least -hot percent of samples as hot paths.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/Unknwon/com"
+
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/index"
+	"github.com/Unknwon/gowalker/pkg/setting"
+	"github.com/Unknwon/gowalker/pkg/sqlitestore"
+)
+
+const usage = `Usage:
+	gowalker doc <importpath|dir|archive>     Print documentation in plain text
+	gowalker json <importpath|dir|archive>    Print documentation in JSON
+	gowalker api <importpath|dir|archive>     Print exported API in the format of Go api/*.txt files
+	gowalker chunks <target>...               Print chunks of documentation of packages and
+	                                          symbols in JSON lines for retrieval pipelines
+	gowalker strings [-json] <target>         Print doc comments to translate as a gettext
+	                                          template, or in JSON with -json
+	gowalker serve [-http addr] [-watch] [-db file] <target>
+	                                          Serve documentation in HTML, -watch
+	                                          reloads it when the directory changes,
+	                                          -db keeps documentation and search
+	                                          index in a SQLite database
+	gowalker diff <old> <new>                 Print API changes between two versions
+	gowalker export <db>                      Write documentation and search index in
+	                                          the SQLite database to stdout as a tar
+	gowalker import <db>                      Read documentation and search index from
+	                                          a tar in stdin to the SQLite database
+
+A target is a local directory, a zip or tar.gz archive, or an import path to be fetched.
+Flag -config file before the command loads settings in YAML or TOML, e.g. credentials of
+code hosting services, the sort mode and GOOS/GOARCH pairs packages are imported for.
+Declarations are ordered by go/doc unless -sort is given before the command, which is
+one of alphabetical, source, file and exported-first. Flag -cost before the command builds
+commands in local directories and reports size and dependencies of their binaries.
+Flag -coverprofile file before the command annotates functions with statement coverage
+of the profile written by go test, coverage.out or cover.out in local directories is
+applied by default. Flag -bench file attaches results in the output of go test -bench
+or benchmark data files to benchmarks of packages. Flag -pprof file marks functions
+and methods that take at least -hot percent (default 5) of samples of the pprof profile,
+including their callees, as hot paths.
+`
+
+var (
+	// Whether to build commands in local directories for cost reports.
+	buildCost bool
+	// Blocks of the coverage profile given by -coverprofile.
+	coverBlocks []doc.CoverBlock
+	// Results of benchmarks given by -bench.
+	benchResults []*doc.BenchResult
+	// Profile given by -pprof and the percentage of samples of hot paths.
+	profile      *doc.Profile
+	hotThreshold float64
+)
+
+// load walks the package of given target.
+func load(target string) (*doc.Package, error) {
+	pdoc, err := walk(target)
+	if err == nil && coverBlocks != nil {
+		doc.ApplyCoverage(pdoc, coverBlocks)
+	}
+	if err == nil && benchResults != nil {
+		doc.ApplyBenchmarks(pdoc, benchResults)
+	}
+	if err == nil && profile != nil {
+		doc.ApplyProfile(pdoc, profile, hotThreshold)
+	}
+	return pdoc, err
+}
+
+func walk(target string) (*doc.Package, error) {
+	switch {
+	case com.IsDir(target):
+		pdoc, err := doc.WalkDir(target, "")
+		if err == nil && buildCost && pdoc.IsCmd {
+			if err = doc.BuildCost(pdoc, target); err != nil {
+				return nil, fmt.Errorf("cost: %v", err)
+			}
+		}
+		return pdoc, err
+	case doc.IsArchive(target):
+		return doc.WalkArchive(target, "")
+	}
+	return doc.Fetch(target)
+}
+
+func fatal(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "gowalker: "+format+"\n", args...)
+	os.Exit(1)
+}
+
+func runDoc(args []string) {
+	if len(args) != 1 {
+		fatal("doc requires exactly one target")
+	}
+	pdoc, err := load(args[0])
+	if err != nil {
+		fatal("%v", err)
+	}
+	writeText(os.Stdout, pdoc)
+}
+
+func runJSON(args []string) {
+	if len(args) != 1 {
+		fatal("json requires exactly one target")
+	}
+	pdoc, err := load(args[0])
+	if err != nil {
+		fatal("%v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(pdoc); err != nil {
+		fatal("%v", err)
+	}
+}
+
+func runAPI(args []string) {
+	if len(args) != 1 {
+		fatal("api requires exactly one target")
+	}
+	pdoc, err := load(args[0])
+	if err != nil {
+		fatal("%v", err)
+	}
+	if err = doc.ExportAPI(os.Stdout, pdoc); err != nil {
+		fatal("%v", err)
+	}
+}
+
+func runChunks(args []string) {
+	if len(args) == 0 {
+		fatal("chunks requires at least one target")
+	}
+	for _, target := range args {
+		pdoc, err := load(target)
+		if err != nil {
+			fatal("load %s: %v", target, err)
+		}
+		if err = doc.ExportChunks(os.Stdout, pdoc); err != nil {
+			fatal("%v", err)
+		}
+	}
+}
+
+func runStrings(args []string) {
+	fs := flag.NewFlagSet("strings", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print in JSON instead of a gettext template")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fatal("strings requires exactly one target")
+	}
+	pdoc, err := load(fs.Arg(0))
+	if err != nil {
+		fatal("%v", err)
+	}
+
+	format := "pot"
+	if *asJSON {
+		format = "json"
+	}
+	if err = doc.ExportStrings(os.Stdout, pdoc, format); err != nil {
+		fatal("%v", err)
+	}
+}
+
+func runDiff(args []string) {
+	if len(args) != 2 {
+		fatal("diff requires old and new targets")
+	}
+	oldDoc, err := load(args[0])
+	if err != nil {
+		fatal("load %s: %v", args[0], err)
+	}
+	newDoc, err := load(args[1])
+	if err != nil {
+		fatal("load %s: %v", args[1], err)
+	}
+
+	d := doc.Diff(oldDoc, newDoc)
+	writeDiff(os.Stdout, d)
+	// Exit with non-zero status for incompatible changes, which is useful in CI.
+	if !d.Compatible() {
+		os.Exit(2)
+	}
+}
+
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("http", "localhost:8080", "HTTP service address")
+	watch := fs.Bool("watch", false, "Reload documentation when files in the directory are changed")
+	db := fs.String("db", "", "SQLite database file to keep walked documentation and the search index")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fatal("serve requires exactly one target")
+	}
+
+	if err := serve(*addr, fs.Arg(0), *watch, *db); err != nil {
+		fatal("%v", err)
+	}
+}
+
+// openCorpus sets the doc store and indexer to the SQLite database.
+func openCorpus(filename string) (*sqlitestore.Store, *index.Index) {
+	store, err := sqlitestore.Open(filename, doc.CodecZstd)
+	if err != nil {
+		fatal("open database: %v", err)
+	}
+	idx, err := index.OpenStorage(store)
+	if err != nil {
+		fatal("open index: %v", err)
+	}
+	doc.SetDocStore(store, doc.RetentionPolicy{})
+	doc.SetIndexer(idx)
+	return store, idx
+}
+
+func runExport(args []string) {
+	if len(args) != 1 {
+		fatal("export requires exactly one database")
+	}
+	store, _ := openCorpus(args[0])
+	defer store.Close()
+
+	if err := doc.ExportCorpus(os.Stdout); err != nil {
+		fatal("%v", err)
+	}
+}
+
+func runImport(args []string) {
+	if len(args) != 1 {
+		fatal("import requires exactly one database")
+	}
+	store, idx := openCorpus(args[0])
+	defer store.Close()
+
+	if err := doc.ImportCorpus(os.Stdin); err != nil {
+		fatal("%v", err)
+	}
+	if err := idx.Save(); err != nil {
+		fatal("save index: %v", err)
+	}
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	configFile := flag.String("config", "", "configuration file in YAML or TOML")
+	sortMode := flag.String("sort", "", "order of declarations")
+	flag.BoolVar(&buildCost, "cost", false, "build commands to report size and dependencies of binaries")
+	coverProfile := flag.String("coverprofile", "", "coverage profile written by go test")
+	benchFile := flag.String("bench", "", "output of go test -bench")
+	pprofFile := flag.String("pprof", "", "pprof profile to find hot paths")
+	flag.Float64Var(&hotThreshold, "hot", 5, "percentage of samples of hot paths")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if len(*configFile) > 0 {
+		if err := setting.LoadFile(*configFile); err != nil {
+			fatal("%v", err)
+		}
+	}
+	if len(*sortMode) == 0 {
+		*sortMode = setting.SortMode
+	}
+	if err := doc.SetGoEnvs(setting.GoEnvs); err != nil {
+		fatal("%v", err)
+	}
+
+	if len(*coverProfile) > 0 {
+		data, err := ioutil.ReadFile(*coverProfile)
+		if err != nil {
+			fatal("%v", err)
+		}
+		if coverBlocks, err = doc.ParseCoverProfile(data); err != nil {
+			fatal("%s: %v", *coverProfile, err)
+		}
+	}
+	if len(*benchFile) > 0 {
+		f, err := os.Open(*benchFile)
+		if err != nil {
+			fatal("%v", err)
+		}
+		benchResults, err = doc.ParseBenchmarks(f)
+		f.Close()
+		if err != nil {
+			fatal("%s: %v", *benchFile, err)
+		}
+	}
+	if len(*pprofFile) > 0 {
+		data, err := ioutil.ReadFile(*pprofFile)
+		if err != nil {
+			fatal("%v", err)
+		}
+		if profile, err = doc.ParseProfile(data); err != nil {
+			fatal("%s: %v", *pprofFile, err)
+		}
+	}
+
+	mode, err := doc.ParseSortMode(*sortMode)
+	if err != nil {
+		fatal("%v", err)
+	}
+	doc.SetSortMode(mode)
+
+	args := flag.Args()[1:]
+	switch flag.Arg(0) {
+	case "doc":
+		runDoc(args)
+	case "json":
+		runJSON(args)
+	case "api":
+		runAPI(args)
+	case "chunks":
+		runChunks(args)
+	case "strings":
+		runStrings(args)
+	case "serve":
+		runServe(args)
+	case "diff":
+		runDiff(args)
+	case "export":
+		runExport(args)
+	case "import":
+		runImport(args)
+	default:
+		fmt.Fprintf(os.Stderr, "gowalker: unknown command %q\n\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
+}
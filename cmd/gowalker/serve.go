@@ -0,0 +1,284 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Unknwon/com"
+	"gopkg.in/fsnotify.v1"
+
+	gwdoc "github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/index"
+	"github.com/Unknwon/gowalker/pkg/sqlitestore"
+)
+
+// Script injected into pages in watch mode to reload when documentation changes.
+const reloadScript = `<script>new EventSource("/_events").onmessage = function() { location.reload(); };</script>`
+
+// renderPage returns HTML page of the package documentation.
+func renderPage(pdoc *gwdoc.Package, watch bool) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gwdoc.WritePage(&buf, pdoc); err != nil {
+		return nil, err
+	}
+	if watch {
+		return bytes.Replace(buf.Bytes(), []byte("</body>"), []byte(reloadScript+"\n</body>"), 1), nil
+	}
+	return buf.Bytes(), nil
+}
+
+// server holds the latest page of the documentation being served.
+type server struct {
+	lock    sync.RWMutex
+	page    []byte
+	hashes  map[string]string // Hashes of walked files.
+	changed chan struct{}     // Closed when the page is changed.
+
+	// Persistent storage of walked documentation and the search index,
+	// only available when a database is given.
+	db  *sqlitestore.Store
+	idx *index.Index
+}
+
+func (s *server) openDB(filename string) (err error) {
+	if s.db, err = sqlitestore.Open(filename, gwdoc.CodecZstd); err != nil {
+		return fmt.Errorf("open database: %v", err)
+	}
+	if s.idx, err = index.OpenStorage(s.db); err != nil {
+		return fmt.Errorf("open index: %v", err)
+	}
+	return nil
+}
+
+// storedDocTTL is how long stored documentation of a fetched package is used without fetching again.
+const storedDocTTL = 24 * time.Hour
+
+// load walks the package of given target, documentation of packages to be fetched
+// is read from the database if it is walked recently or cannot be fetched.
+func (s *server) load(target string) (*gwdoc.Package, error) {
+	if s.db == nil {
+		return load(target)
+	} else if com.IsDir(target) || gwdoc.IsArchive(target) {
+		pdoc, err := load(target)
+		if err == nil {
+			s.store(pdoc)
+		}
+		return pdoc, err
+	}
+
+	stored, err := s.db.Get(target, "")
+	if err != nil && err != gwdoc.ErrDocNotFound {
+		return nil, err
+	}
+	if stored != nil && stored.Provenance != nil && time.Since(stored.Provenance.WalkedAt) < storedDocTTL {
+		return stored, nil
+	}
+
+	pdoc, err := load(target)
+	if err != nil {
+		if stored != nil {
+			log.Printf("Failed to fetch %s, using stored documentation: %v", target, err)
+			return stored, nil
+		}
+		return nil, err
+	}
+	s.store(pdoc)
+	return pdoc, nil
+}
+
+// store saves the documentation and adds it to the search index.
+func (s *server) store(pdoc *gwdoc.Package) {
+	if s.db == nil {
+		return
+	}
+
+	if err := s.db.Put(pdoc); err != nil {
+		log.Printf("Failed to store %s: %v", pdoc.ImportPath, err)
+	}
+	s.idx.Add(pdoc)
+	if err := s.idx.Save(); err != nil {
+		log.Printf("Failed to save index: %v", err)
+	}
+}
+
+// search responds symbols that match the query in JSON.
+func (s *server) search(w http.ResponseWriter, r *http.Request) {
+	q, err := index.ParseQuery(r.FormValue("q"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.idx.Search(q, 20))
+}
+
+func (s *server) setPage(page []byte, hashes map[string]string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.page = page
+	s.hashes = hashes
+	if s.changed != nil {
+		close(s.changed)
+	}
+	s.changed = make(chan struct{})
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.RLock()
+	page := s.page
+	s.lock.RUnlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// serveEvents sends a server-sent event when the page is changed.
+func (s *server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher.Flush()
+
+	s.lock.RLock()
+	changed := s.changed
+	s.lock.RUnlock()
+
+	select {
+	case <-changed:
+		fmt.Fprint(w, "data: reload\n\n")
+		flusher.Flush()
+	case <-r.Context().Done():
+	}
+}
+
+// rewalk walks the directory again and updates the page if any file is changed.
+func (s *server) rewalk(dir string) {
+	pdoc, err := gwdoc.WalkDir(dir, "")
+	if err != nil {
+		log.Printf("Failed to walk %s: %v", dir, err)
+		s.setPage([]byte(fmt.Sprintf("<!DOCTYPE html><html><body><pre>%s</pre>%s</body></html>",
+			template.HTMLEscapeString(err.Error()), reloadScript)), nil)
+		return
+	}
+
+	s.lock.RLock()
+	same := reflect.DeepEqual(s.hashes, pdoc.Provenance.FileHashes)
+	s.lock.RUnlock()
+	if same {
+		return
+	}
+
+	page, err := renderPage(pdoc, true)
+	if err != nil {
+		log.Printf("Failed to render %s: %v", dir, err)
+		return
+	}
+	s.setPage(page, pdoc.Provenance.FileHashes)
+	s.store(pdoc)
+	log.Printf("Documentation of %s reloaded", pdoc.ImportPath)
+}
+
+// Delay of re-walks after file changes, editors usually write files in several steps.
+const watchDelay = 100 * time.Millisecond
+
+// watch re-walks the directory whenever files in it are changed.
+func (s *server) watch(dir string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create watcher: %v", err)
+	}
+	if err = watcher.Add(dir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("watch %s: %v", dir, err)
+	}
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case event := <-watcher.Events:
+				name := filepath.Base(event.Name)
+				if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(watchDelay, func() { s.rewalk(dir) })
+			case err := <-watcher.Errors:
+				log.Printf("Watcher error: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// serve walks the target and serves its documentation at given address.
+// In watch mode, the target must be a directory, and the page is reloaded
+// whenever files in the directory are changed. When a database file is given,
+// walked documentation and the search index are kept in it, and symbols
+// can be searched at "/_search?q=".
+func serve(addr, target string, watch bool, dbPath string) error {
+	if watch && !com.IsDir(target) {
+		return fmt.Errorf("watch mode requires a local directory")
+	}
+
+	s := new(server)
+	if len(dbPath) > 0 {
+		if err := s.openDB(dbPath); err != nil {
+			return err
+		}
+		defer s.db.Close()
+		http.HandleFunc("/_search", s.search)
+	}
+
+	pdoc, err := s.load(target)
+	if err != nil {
+		return err
+	}
+	page, err := renderPage(pdoc, watch)
+	if err != nil {
+		return err
+	}
+
+	s.setPage(page, pdoc.Provenance.FileHashes)
+	http.Handle("/", s)
+	if watch {
+		if err = s.watch(target); err != nil {
+			return err
+		}
+		http.HandleFunc("/_events", s.serveEvents)
+	}
+
+	log.Printf("Serving documentation of %s on http://%s", pdoc.ImportPath, addr)
+	return http.ListenAndServe(addr, nil)
+}
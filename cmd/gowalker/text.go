@@ -0,0 +1,181 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"go/doc"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+
+	gwdoc "github.com/Unknwon/gowalker/pkg/doc"
+)
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]+>`)
+
+// htmlToText returns text content of HTML.
+func htmlToText(s string) string {
+	return strings.TrimSpace(html.UnescapeString(htmlTagPattern.ReplaceAllString(s, "")))
+}
+
+func writeDoc(w io.Writer, text string) {
+	if text = strings.TrimSpace(text); len(text) > 0 {
+		doc.ToText(w, text, "    ", "\t", 76)
+	}
+	fmt.Fprintln(w)
+}
+
+func writeValues(w io.Writer, vals []*gwdoc.Value) {
+	for _, v := range vals {
+		fmt.Fprintf(w, "%s\n", v.Decl)
+		writeDoc(w, v.Doc)
+	}
+}
+
+func writeFuncs(w io.Writer, funcs []*gwdoc.Func) {
+	for _, f := range funcs {
+		fmt.Fprintf(w, "%s\n", f.Decl)
+		if c := f.Coverage; c != nil {
+			fmt.Fprintf(w, "    Coverage: %s of %d statements.\n", c, c.Statements)
+		}
+		if h := f.HotPath; h != nil {
+			fmt.Fprintf(w, "    Hot path #%d: %.1f%% of samples, %.1f%% in itself.\n", h.Rank, h.Cum, h.Flat)
+		}
+		writeDoc(w, f.Doc)
+	}
+}
+
+// writeText writes documentation of the package in plain text, similar to go doc.
+func writeText(w io.Writer, pdoc *gwdoc.Package) {
+	name := pdoc.ImportPath[strings.LastIndex(pdoc.ImportPath, "/")+1:]
+	if pdoc.IsCmd {
+		name = "main"
+	}
+	fmt.Fprintf(w, "package %s // import %q\n\n", name, pdoc.ImportPath)
+	if pdoc.PkgDecl == nil {
+		return
+	}
+	if len(pdoc.Deprecated) > 0 {
+		fmt.Fprintf(w, "Deprecated: %s\n\n", pdoc.Deprecated)
+	}
+	writeDoc(w, htmlToText(pdoc.Doc))
+	if c := pdoc.Cost; c != nil {
+		fmt.Fprintf(w, "COST\n\n    Binary of %s built by %s links %d module dependencies.\n\n",
+			c.SizeString(), c.GoVersion, len(c.Deps))
+	}
+	if c := pdoc.Coverage; c != nil {
+		fmt.Fprintf(w, "COVERAGE\n\n    %s of %d statements.\n\n", c, c.Statements)
+	}
+	if len(pdoc.BazelTargets) > 0 {
+		fmt.Fprint(w, "BAZEL TARGETS\n\n")
+		for _, t := range pdoc.BazelTargets {
+			fmt.Fprintf(w, "    %s (%s)\n", t.Label, t.Kind)
+		}
+		fmt.Fprintln(w)
+	}
+	if len(pdoc.HotPaths) > 0 {
+		fmt.Fprint(w, "HOT PATHS\n\n")
+		for _, f := range pdoc.HotPaths {
+			fmt.Fprintf(w, "    %s\n", f.Name)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(pdoc.Consts) > 0 {
+		fmt.Fprint(w, "CONSTANTS\n\n")
+		writeValues(w, pdoc.Consts)
+	}
+	if len(pdoc.Vars) > 0 {
+		fmt.Fprint(w, "VARIABLES\n\n")
+		writeValues(w, pdoc.Vars)
+	}
+	if len(pdoc.Funcs) > 0 {
+		fmt.Fprint(w, "FUNCTIONS\n\n")
+		writeFuncs(w, pdoc.Funcs)
+	}
+	if len(pdoc.Types) > 0 {
+		fmt.Fprint(w, "TYPES\n\n")
+		for _, t := range pdoc.Types {
+			fmt.Fprintf(w, "%s\n", t.Decl)
+			writeDoc(w, t.Doc)
+			writeValues(w, t.Consts)
+			writeValues(w, t.Vars)
+			writeFuncs(w, t.Funcs)
+			writeFuncs(w, t.Methods)
+		}
+	}
+	writeBenchmarks(w, pdoc.Benchmarks)
+}
+
+func writeBenchmarks(w io.Writer, benchs []*gwdoc.Benchmark) {
+	header := false
+	for _, b := range benchs {
+		if len(b.Results) == 0 {
+			continue
+		}
+		if !header {
+			fmt.Fprint(w, "BENCHMARKS\n\n")
+			header = true
+		}
+		fmt.Fprintf(w, "%s\n", b.Name)
+		for _, r := range b.Results {
+			fmt.Fprintf(w, "    %s-%d\t%s\t%.2f ns/op", r.Name, r.Procs, r.Platform(), r.NsPerOp)
+			if r.BytesPerOp >= 0 {
+				fmt.Fprintf(w, "\t%.0f B/op", r.BytesPerOp)
+			}
+			if r.AllocsPerOp >= 0 {
+				fmt.Fprintf(w, "\t%.0f allocs/op", r.AllocsPerOp)
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func writeChanges(w io.Writer, title string, changes []*gwdoc.Change, decl func(*gwdoc.Change) string) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, c := range changes {
+		fmt.Fprintf(w, "  %s %s\n", c.Kind, c.Name)
+		for _, line := range strings.Split(decl(c), "\n") {
+			fmt.Fprintf(w, "      %s\n", line)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// writeDiff writes API changes in plain text.
+func writeDiff(w io.Writer, d *gwdoc.APIDiff) {
+	if d.Empty() {
+		fmt.Fprintln(w, "No API changes.")
+		return
+	}
+
+	writeChanges(w, "Removed", d.Removed, func(c *gwdoc.Change) string { return c.Old })
+	writeChanges(w, "Changed", d.Changed, func(c *gwdoc.Change) string {
+		return "- " + strings.Replace(c.Old, "\n", "\n- ", -1) + "\n+ " + strings.Replace(c.New, "\n", "\n+ ", -1)
+	})
+	writeChanges(w, "Added", d.Added, func(c *gwdoc.Change) string { return c.New })
+
+	if d.Compatible() {
+		fmt.Fprintln(w, "Changes are backward compatible.")
+	} else {
+		fmt.Fprintln(w, "Changes are NOT backward compatible.")
+	}
+}
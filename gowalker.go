@@ -17,17 +17,32 @@ package main
 
 import (
 	"fmt"
+	"net"
 	"net/http"
+	"path"
 	"strings"
 
 	"github.com/go-macaron/i18n"
-	"github.com/go-macaron/pongo2"
 	"github.com/go-macaron/session"
+	"github.com/minio/minio-go"
+	"github.com/robfig/cron"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	log "gopkg.in/clog.v1"
 	"gopkg.in/macaron.v1"
 
+	"github.com/Unknwon/gowalker/models"
+	"github.com/Unknwon/gowalker/pkg/audit"
+	"github.com/Unknwon/gowalker/pkg/auth"
 	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/index"
+	"github.com/Unknwon/gowalker/pkg/objstore"
+	"github.com/Unknwon/gowalker/pkg/redisstore"
+	"github.com/Unknwon/gowalker/pkg/rpc"
+	"github.com/Unknwon/gowalker/pkg/sandbox"
 	"github.com/Unknwon/gowalker/pkg/setting"
+	"github.com/Unknwon/gowalker/pkg/tenant"
 	"github.com/Unknwon/gowalker/routes"
 	"github.com/Unknwon/gowalker/routes/apiv1"
 )
@@ -48,44 +63,475 @@ func newMacaron() *macaron.Macaron {
 			SkipLogging: setting.ProdMode,
 		},
 	))
+	setRenderer()
+	m.Use(render)
+	m.Use(i18n.I18n())
+	m.Use(session.Sessioner())
+	m.Use(context.Contexter())
+	if setting.CSP.Enabled {
+		routes.InitCSP()
+		m.Use(routes.ContentSecurityPolicy)
+	}
+	// Rate limiting can be enabled by reloading settings.
+	m.Use(routes.RateLimit)
+	m.Use(routes.RejectBlocked)
+	// Generated documentation is served after authentication.
+	if routes.AuthEnabled() {
+		m.Use(routes.RequireAuth)
+	}
 	m.Use(macaron.Static("raw",
 		macaron.StaticOptions{
 			Prefix:      "raw",
 			SkipLogging: setting.ProdMode,
 		}))
-	m.Use(pongo2.Pongoer(pongo2.Options{
-		IndentJSON: !setting.ProdMode,
-	}))
-	m.Use(i18n.I18n())
-	m.Use(session.Sessioner())
-	m.Use(context.Contexter())
 	return m
 }
 
+func objstoreClient() *minio.Client {
+	client, err := minio.New(
+		setting.ObjStore.Endpoint,
+		setting.ObjStore.AccessKey,
+		setting.ObjStore.SecretKey,
+		setting.ObjStore.Secure)
+	if err != nil {
+		log.Fatal(2, "Failed to new object storage client: %v", err)
+	}
+	return client
+}
+
+func objstoreOptions() objstore.Options {
+	return objstore.Options{
+		Bucket: setting.ObjStore.Bucket,
+		Prefix: setting.ObjStore.Prefix,
+		Codec:  docCodec(),
+
+		MaxObjectSize: setting.ObjStore.MaxObjectSize * 1024,
+	}
+}
+
+func docCodec() doc.Codec {
+	codec, err := doc.ParseCodec(setting.DocStore.Codec)
+	if err != nil {
+		log.Fatal(2, "Failed to parse codec of doc store: %v", err)
+	}
+	return codec
+}
+
+// initTenants opens doc stores and indexes of configured tenants.
+func initTenants() {
+	if len(setting.Tenants) == 0 {
+		return
+	}
+
+	var indexes []*index.Index
+	for _, cfg := range setting.Tenants {
+		dir := path.Join(setting.TenantsPath, cfg.ID)
+		var store doc.DocStore = doc.FileDocStore{
+			Dir:   path.Join(dir, "docs"),
+			Codec: docCodec(),
+		}
+		if setting.ObjStore.Enabled {
+			opts := objstoreOptions()
+			opts.Prefix += "tenants/" + cfg.ID + "/"
+			store = objstore.NewDocStore(objstoreClient(), opts)
+		}
+
+		idx, err := index.Open(path.Join(dir, "index.gob"))
+		if err != nil {
+			log.Fatal(2, "Failed to open index of tenant %q: %v", cfg.ID, err)
+		}
+		indexes = append(indexes, idx)
+
+		name := cfg.Name
+		if len(name) == 0 {
+			name = cfg.ID
+		}
+		if err = tenant.Register(&tenant.Tenant{
+			ID:      cfg.ID,
+			Name:    name,
+			Hosts:   cfg.Hosts,
+			Members: cfg.Principals,
+			ACL:     auth.ParseACL(cfg.ACL),
+			Store:   doc.QuotaDocStore{DocStore: store, MaxPackages: cfg.MaxPackages},
+			Index:   idx,
+		}); err != nil {
+			log.Fatal(2, "Failed to register tenant: %v", err)
+		}
+	}
+
+	saveIndexes := func() error {
+		for _, idx := range indexes {
+			if err := idx.Save(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	c := cron.New()
+	if err := c.AddFunc("@every 5m", func() {
+		if err := saveIndexes(); err != nil {
+			log.Error(2, "Failed to save index of tenant: %v", err)
+		}
+	}); err != nil {
+		log.Fatal(2, "Failed to add func: %v", err)
+	}
+	c.Start()
+	onShutdown("tenant indexes", saveIndexes)
+}
+
 func main() {
 	log.Info("Go Walker %s", Version)
 	log.Info("Run Mode: %s", strings.Title(macaron.Env))
 
+	models.Init()
+
+	switch setting.Audit.Sink {
+	case "file":
+		sink, err := audit.OpenFile(setting.Audit.Path)
+		if err != nil {
+			log.Fatal(2, "Failed to open audit log: %v", err)
+		}
+		audit.SetSink(sink)
+		onShutdown("audit log", func() error {
+			audit.SetSink(nil)
+			return sink.Close()
+		})
+	case "sql":
+		audit.SetSink(models.AuditSink{})
+	}
+
+	if len(setting.Private.GoPrivate) > 0 || len(setting.Private.GoNoProxy) > 0 || len(setting.Private.GoNoSumDB) > 0 {
+		doc.SetPrivatePatterns(doc.PrivatePatterns{
+			Private: setting.Private.GoPrivate,
+			NoProxy: setting.Private.GoNoProxy,
+			NoSumDB: setting.Private.GoNoSumDB,
+		})
+	}
+	if len(setting.Private.Netrc) > 0 {
+		doc.SetCredentialStore(doc.NewNetrcStore(setting.Private.Netrc))
+	}
+
+	if len(setting.RepoMappings) > 0 {
+		mappings, err := doc.ParseRepoMappings(setting.RepoMappings)
+		if err != nil {
+			log.Fatal(2, "Failed to parse monorepo mappings: %v", err)
+		}
+		doc.SetRepoMappings(mappings)
+	}
+
+	if setting.Asset.Enabled {
+		doc.SetAssetStore(doc.LocalAssetStore{
+			Dir:       setting.Asset.Path,
+			URLPrefix: "/" + setting.Asset.Path,
+		}, setting.Asset.MaxSize<<10)
+	}
+
+	if setting.Preview.Enabled {
+		routes.InitPreview(setting.Preview.MaxConcurrent)
+	}
+
+	if setting.Playground.Enabled {
+		doc.SetPlayground(&doc.Playground{
+			ShareURL: setting.Playground.ShareURL,
+			LinkURL:  setting.Playground.URL,
+		})
+	}
+
+	if setting.Sandbox.Enabled {
+		routes.InitSandbox(&sandbox.Playground{URL: setting.Sandbox.URL}, sandbox.Limits{
+			Timeout:     setting.Sandbox.Timeout,
+			MaxCodeSize: setting.Sandbox.MaxCodeSize,
+			MaxOutput:   setting.Sandbox.MaxOutput,
+		})
+	}
+
+	var blobs doc.BlobStore
+	if setting.BlobStore.Enabled {
+		blobs = doc.FileBlobStore{Dir: setting.BlobStore.Path}
+		doc.SetBlobStore(blobs)
+	}
+
+	if !setting.ProdMode || setting.DocStore.Enabled {
+		policy := doc.RetentionPolicy{
+			MaxVersions: setting.DocStore.MaxVersions,
+			TTL:         setting.DocStore.TTL,
+		}
+		var store doc.DocStore = doc.FileDocStore{
+			Dir:   setting.DocsGobPath,
+			Codec: docCodec(),
+		}
+		if setting.ObjStore.Enabled {
+			store = objstore.NewDocStore(objstoreClient(), objstoreOptions())
+		}
+		if blobs != nil {
+			store = doc.BlobDocStore{DocStore: store, Blobs: blobs}
+		}
+		store = doc.NewVersionIndexDocStore(store)
+		if setting.DocStore.Delta {
+			store = doc.DeltaDocStore{DocStore: store}
+		}
+		var handler doc.EventHandler
+		if setting.Notify.Enabled {
+			notifier, err := routes.InitNotifier()
+			if err != nil {
+				log.Fatal(2, "Failed to initialize notifier: %v", err)
+			}
+			handler = notifier.Handle
+		}
+		if setting.Feed.Enabled {
+			events, err := doc.OpenEventLog(setting.Feed.Path, setting.Feed.MaxEvents)
+			if err != nil {
+				log.Fatal(2, "Failed to open event log: %v", err)
+			}
+			store = doc.EventDocStore{DocStore: store, Events: events, Handler: handler}
+			doc.SetEventLog(events)
+
+			c := cron.New()
+			if err = c.AddFunc("@every 5m", func() {
+				if err := events.Save(); err != nil {
+					log.Error(2, "Failed to save event log: %v", err)
+				}
+			}); err != nil {
+				log.Fatal(2, "Failed to add func: %v", err)
+			}
+			c.Start()
+			onShutdown("event log", events.Save)
+		} else if handler != nil {
+			store = doc.EventDocStore{DocStore: store, Handler: handler}
+		}
+		doc.SetDocStore(store, policy)
+
+		if policy.MaxVersions > 0 || policy.TTL > 0 {
+			c := cron.New()
+			if err := c.AddFunc("@daily", func() {
+				if _, err := doc.Vacuum(); err != nil {
+					log.Error(2, "Failed to vacuum stored docs: %v", err)
+				}
+			}); err != nil {
+				log.Fatal(2, "Failed to add func: %v", err)
+			}
+			c.Start()
+		}
+	}
+
+	if setting.ObjStore.Enabled {
+		doc.SetCache(objstore.NewCache(objstoreClient(), objstoreOptions()))
+	}
+
+	if setting.Index.Enabled {
+		idx, err := index.Open(setting.Index.Path)
+		if err != nil {
+			log.Fatal(2, "Failed to open index: %v", err)
+		}
+		doc.SetIndexer(idx)
+
+		var vectors *index.MemoryVectorStore
+		if setting.Semantic.Enabled {
+			vectors, err = index.NewMemoryVectorStore(setting.Semantic.Path)
+			if err != nil {
+				log.Fatal(2, "Failed to open vectors: %v", err)
+			}
+			idx.SetEmbedder(&index.HTTPEmbedder{
+				URL:    setting.Semantic.URL,
+				APIKey: setting.Semantic.APIKey,
+				Model:  setting.Semantic.Model,
+			}, vectors)
+			routes.InitSemanticSearch(idx)
+		}
+
+		c := cron.New()
+		if err = c.AddFunc("@every 5m", func() {
+			if err := idx.Save(); err != nil {
+				log.Error(2, "Failed to save index: %v", err)
+			}
+			if vectors != nil {
+				if err := vectors.Save(); err != nil {
+					log.Error(2, "Failed to save vectors: %v", err)
+				}
+			}
+		}); err != nil {
+			log.Fatal(2, "Failed to add func: %v", err)
+		}
+		c.Start()
+		onShutdown("index", idx.Save)
+		if vectors != nil {
+			onShutdown("vectors", vectors.Save)
+		}
+	}
+
+	if setting.Crawler.Locker == "sql" {
+		doc.SetLocker(models.SQLLocker{})
+	}
+	if setting.Redis.Enabled {
+		rs := redisstore.New(setting.Redis.Addr, setting.Redis.Password, setting.Redis.DB, setting.Redis.Prefix)
+		// Cache on Redis takes precedence over the one on object storage.
+		doc.SetCache(rs)
+		if len(setting.Crawler.Locker) == 0 || setting.Crawler.Locker == "redis" {
+			doc.SetLocker(rs)
+		}
+		doc.SetQueue(rs)
+		doc.StartCrawlers(setting.Redis.Crawlers)
+		onShutdown("redis", rs.Close)
+	}
+
+	linkPolicy, err := doc.ParseLinkPolicy(setting.Links.External)
+	if err != nil {
+		log.Fatal(2, "Failed to parse link policy: %v", err)
+	}
+	doc.SetLinkPolicy(linkPolicy)
+
+	sortMode, err := doc.ParseSortMode(setting.SortMode)
+	if err != nil {
+		log.Fatal(2, "Failed to parse sort mode: %v", err)
+	}
+	doc.SetSortMode(sortMode)
+
+	if err = doc.SetGoEnvs(setting.GoEnvs); err != nil {
+		log.Fatal(2, "Failed to set Go environments: %v", err)
+	}
+
+	if setting.Sanitize.Enabled {
+		doc.SetSanitizePolicy(doc.NewSanitizePolicy(setting.Sanitize.ExtraTags, setting.Sanitize.ExtraAttrs))
+	} else {
+		doc.SetSanitizePolicy(nil)
+	}
+
+	if setting.Analysis.Enabled {
+		doc.SetAnalyzer(&doc.VetAnalyzer{
+			Analyzers: setting.Analysis.Analyzers,
+			VetTool:   setting.Analysis.VetTool,
+		})
+	}
+
+	if setting.Translations.Enabled {
+		doc.SetTranslationDir(setting.Translations.Path)
+	}
+
+	if err := routes.InitAuth(); err != nil {
+		log.Fatal(2, "Failed to initialize auth: %v", err)
+	}
+	if err := routes.InitBlocklist(); err != nil {
+		log.Fatal(2, "Failed to open blocklist: %v", err)
+	}
+	if err := routes.InitGitHubApp(); err != nil {
+		log.Fatal(2, "Failed to initialize GitHub App: %v", err)
+	}
+	if setting.Crawler.RespectRobots {
+		doc.SetRobotsAgent(setting.Crawler.UserAgent)
+	}
+	initTenants()
+	if err := routes.InitCrawl(); err != nil {
+		log.Fatal(2, "Failed to resume crawl: %v", err)
+	}
+
+	if setting.GRPC.Enabled {
+		lis, err := net.Listen("tcp", setting.GRPC.Addr)
+		if err != nil {
+			log.Fatal(2, "Failed to listen gRPC: %v", err)
+		}
+		log.Info("Listen gRPC: %s", setting.GRPC.Addr)
+		opts := routes.GRPCServerOptions()
+		if len(setting.GRPC.CertFile) > 0 {
+			creds, err := credentials.NewServerTLSFromFile(setting.GRPC.CertFile, setting.GRPC.KeyFile)
+			if err != nil {
+				log.Fatal(2, "Failed to load gRPC certificate: %v", err)
+			}
+			opts = append(opts, grpc.Creds(creds))
+		}
+		grpcServer = rpc.NewServer(opts...)
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatal(2, "Failed to serve gRPC: %v", err)
+			}
+		}()
+	}
+
 	m := newMacaron()
 	m.Get("/", routes.Home)
 	m.Get("/search", routes.Search)
 	m.Get("/search/json", routes.SearchJSON)
+	m.Get("/search/suggest", routes.SearchSuggest)
+	m.Get("/opensearch.xml", routes.OpenSearch)
 
 	m.Group("/api", func() {
 		m.Group("/v1", func() {
 			m.Get("/badge", apiv1.Badge)
+			m.Get("/stability", routes.APIStability)
+			m.Get("/stability/badge", routes.StabilityBadge)
+			m.Get("/resolve", routes.APIResolve)
+			m.Get("/search/semantic", routes.SemanticSearch)
+			m.Get("/chunks", routes.APIChunks)
+			m.Get("/symbols", routes.APISymbols)
+			m.Get("/strings", routes.APIStrings)
+			m.Post("/run", routes.APIRun)
 		})
+		m.Get("/graphql", routes.GraphQL)
+		m.Post("/graphql", routes.GraphQL)
 	})
 
-	m.Get("/robots.txt", func() string {
-		return `User-agent: *
-Disallow: /search`
+	m.Group("/auth", func() {
+		m.Get("/login", routes.AuthLogin)
+		m.Get("/callback", routes.AuthCallback)
+		m.Get("/logout", routes.AuthLogout)
 	})
+
+	if routes.AdminEnabled() {
+		m.Group("/admin/api", func() {
+			m.Get("/metrics", routes.AdminMetrics)
+			m.Get("/queue", routes.AdminQueue)
+			m.Get("/blocklist", routes.AdminBlocklist)
+			m.Post("/block", routes.AdminBlock)
+			m.Post("/unblock", routes.AdminUnblock)
+			m.Post("/purge", routes.AdminPurge)
+			m.Post("/rewalk", routes.AdminRewalk)
+			m.Get("/crawl", routes.AdminCrawlState)
+			m.Post("/crawl", routes.AdminCrawl)
+			m.Get("/subscriptions", routes.AdminSubscriptions)
+			m.Post("/subscribe", routes.AdminSubscribe)
+			m.Post("/unsubscribe", routes.AdminUnsubscribe)
+			m.Get("/digest", routes.AdminDigest)
+		}, routes.RequireAdmin)
+	}
+
+	if setting.GitHubApp.Enabled {
+		m.Post("/github/webhook", routes.GitHubWebhook)
+	}
+
+	m.Group("/t/:tenant", func() {
+		m.Get("/", routes.TenantHome)
+		m.Get("/search", routes.TenantSearch)
+		m.Post("/walk", routes.TenantWalk)
+		m.Get("/*", routes.TenantDocs)
+	}, routes.Tenanter)
+
+	m.Group("/feeds", func() {
+		m.Get("/new", routes.FeedNew)
+		m.Get("/updated", routes.FeedUpdated)
+		m.Get("/versions/*", routes.FeedVersions)
+	})
+
+	m.Get("/preview", routes.Preview)
+	m.Post("/preview", routes.PreviewPost)
+
+	m.Get("/embed/*", routes.Embed)
+	m.Get("/oembed", routes.OEmbed)
+
+	m.Get("/robots.txt", routes.Robots)
+	m.Get("/sitemap.xml", routes.SitemapIndex)
+	m.Get("/sitemaps/:page", routes.Sitemap)
 	m.Get("/*", routes.Docs)
 
+	watchSettings()
+
 	listenAddr := fmt.Sprintf("0.0.0.0:%d", setting.HTTPPort)
 	log.Info("Listen: http://%s", listenAddr)
-	if err := http.ListenAndServe(listenAddr, m); err != nil {
+	srv := &http.Server{
+		Addr:    listenAddr,
+		Handler: context.Conditional(routes.TenantHosts(m)),
+	}
+	stopped := shutdownOnSignal(srv)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatal(2, "Failed to start server: %v", err)
 	}
+	<-stopped
 }
@@ -0,0 +1,49 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"time"
+
+	"github.com/Unknwon/gowalker/pkg/audit"
+)
+
+// AuditLog is an audit event saved in database.
+type AuditLog struct {
+	ID         int64
+	Time       time.Time `xorm:"INDEX"`
+	Action     string
+	User       string `xorm:"INDEX"`
+	RemoteAddr string
+	ImportPath string `xorm:"INDEX"`
+	Version    string
+	Error      string `xorm:"TEXT"`
+}
+
+// AuditSink saves audit events to the database.
+type AuditSink struct{}
+
+func (AuditSink) Write(e *audit.Event) error {
+	_, err := x.Insert(&AuditLog{
+		Time:       e.Time,
+		Action:     string(e.Action),
+		User:       e.User,
+		RemoteAddr: e.RemoteAddr,
+		ImportPath: e.ImportPath,
+		Version:    e.Version,
+		Error:      e.Error,
+	})
+	return err
+}
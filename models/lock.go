@@ -0,0 +1,63 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// WalkLock is a lock of walking a package held by a server.
+type WalkLock struct {
+	LockKey string `xorm:"pk VARCHAR(255)"`
+	Token   string
+	Expires int64 `xorm:"INDEX"` // Unix time in milliseconds.
+}
+
+// SQLLocker implements doc.Locker on the database, which is shared by servers
+// without Redis.
+type SQLLocker struct{}
+
+func (SQLLocker) Lock(key string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	if _, err := x.Where("lock_key = ? AND expires <= ?", key, now.UnixNano()/int64(time.Millisecond)).Delete(new(WalkLock)); err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	if _, err := x.Insert(&WalkLock{
+		LockKey: key,
+		Token:   token,
+		Expires: now.Add(ttl).UnixNano() / int64(time.Millisecond),
+	}); err != nil {
+		// Insertion fails with duplicate primary key when the lock is held by others.
+		if has, e := x.Where("lock_key = ?", key).Get(new(WalkLock)); e == nil && has {
+			return "", nil
+		}
+		return "", err
+	}
+	return token, nil
+}
+
+func (SQLLocker) Unlock(key, token string) error {
+	_, err := x.Where("lock_key = ? AND token = ?", key, token).Delete(new(WalkLock))
+	return err
+}
@@ -29,7 +29,8 @@ import (
 
 var x *xorm.Engine
 
-func init() {
+// Init initializes the database engine and starts background jobs.
+func Init() {
 	sec := setting.Cfg.Section("database")
 	var err error
 	x, err = xorm.NewEngine("mysql", fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8",
@@ -43,7 +44,14 @@ func init() {
 	x.SetLogger(nil)
 	x.SetMapper(core.GonicMapper{})
 
-	if err = x.Sync(new(PkgInfo), new(PkgRef), new(JSFile)); err != nil {
+	tables := []interface{}{new(PkgInfo), new(PkgRef), new(JSFile)}
+	if setting.Audit.Sink == "sql" {
+		tables = append(tables, new(AuditLog))
+	}
+	if setting.Crawler.Locker == "sql" {
+		tables = append(tables, new(WalkLock))
+	}
+	if err = x.Sync(tables...); err != nil {
 		log.Fatal(2, "Failed to sync database: %v", err)
 	}
 
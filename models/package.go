@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"path"
+	"sort"
 	"strings"
 	"time"
 
@@ -72,6 +73,8 @@ type PkgInfo struct {
 	Created    int64
 
 	JSFile *JSFile `xorm:"-"`
+	// Import paths of older major versions grouped under this one in search results.
+	OlderMajors []string `xorm:"-" json:",omitempty"`
 }
 
 // HasJSFile returns false if JS file must be regenerated,
@@ -433,13 +436,80 @@ func GetGAERepos() ([]*PkgInfo, error) {
 	return getRepos("is_gae_repo")
 }
 
-// SearchPkgInfo searches package information by given keyword.
-func SearchPkgInfo(limit int, keyword string) ([]*PkgInfo, error) {
+// maxSearchBatches is the maximum number of batches of rows to read for a search,
+// more rows than the limit are read when packages are grouped or skipped.
+const maxSearchBatches = 5
+
+// SearchPkgInfo searches package information by given keyword. Packages are skipped
+// if allowed is not nil and returns false for them, before copies of multiple major
+// versions are grouped, so that up to limit results are returned.
+func SearchPkgInfo(limit int, keyword string, allowed func(importPath string) bool) ([]*PkgInfo, error) {
 	if len(keyword) == 0 {
 		return nil, nil
 	}
+
 	pkgs := make([]*PkgInfo, 0, limit)
-	return pkgs, x.Limit(limit).Desc("priority").Desc("stars").Desc("views").Where("import_path like ?", "%"+keyword+"%").Find(&pkgs)
+	groups := make(map[string]bool)
+	for i := 0; i < maxSearchBatches && len(groups) < limit; i++ {
+		batch := make([]*PkgInfo, 0, limit)
+		if err := x.Limit(limit, i*limit).Desc("priority").Desc("stars").Desc("views").Where("import_path like ?", "%"+keyword+"%").Find(&batch); err != nil {
+			return nil, err
+		}
+		for _, p := range batch {
+			if allowed != nil && !allowed(p.ImportPath) {
+				continue
+			}
+			stripped, _ := base.SplitMajorVersion(p.ImportPath)
+			groups[stripped] = true
+			pkgs = append(pkgs, p)
+		}
+		if len(batch) < limit {
+			break
+		}
+	}
+
+	pkgs = dedupMajorVersions(pkgs)
+	if len(pkgs) > limit {
+		pkgs = pkgs[:limit]
+	}
+	return pkgs, nil
+}
+
+// dedupMajorVersions groups packages that have copies of multiple major versions
+// in the list under the latest major version, newer ones first.
+func dedupMajorVersions(pkgs []*PkgInfo) []*PkgInfo {
+	indexes := make(map[string]int)
+	majors := make(map[string]int)
+	deduped := pkgs[:0]
+	for _, p := range pkgs {
+		stripped, major := base.SplitMajorVersion(p.ImportPath)
+		i, ok := indexes[stripped]
+		if !ok {
+			indexes[stripped] = len(deduped)
+			majors[stripped] = major
+			deduped = append(deduped, p)
+			continue
+		}
+
+		latest := deduped[i]
+		if major > majors[stripped] {
+			majors[stripped] = major
+			p.OlderMajors = append([]string{latest.ImportPath}, latest.OlderMajors...)
+			latest.OlderMajors = nil
+			deduped[i] = p
+		} else {
+			latest.OlderMajors = append(latest.OlderMajors, p.ImportPath)
+		}
+	}
+
+	for _, p := range deduped {
+		sort.SliceStable(p.OlderMajors, func(i, j int) bool {
+			_, mi := base.SplitMajorVersion(p.OlderMajors[i])
+			_, mj := base.SplitMajorVersion(p.OlderMajors[j])
+			return mi > mj
+		})
+	}
+	return deduped
 }
 
 func DeletePackageByPath(importPath string) error {
@@ -0,0 +1,113 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package audit records who requested walks and views of which packages.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "gopkg.in/clog.v1"
+)
+
+// Action is the kind of an audited request.
+type Action string
+
+const (
+	ActionView    Action = "view"    // Documentation is served.
+	ActionWalk    Action = "walk"    // Package is walked because it is not available yet.
+	ActionRefresh Action = "refresh" // Package is walked again on request.
+	ActionCrawl   Action = "crawl"   // Packages are crawled recursively on request.
+)
+
+// Event is an audited request.
+type Event struct {
+	Time       time.Time
+	Action     Action
+	User       string // Name of the signed-in user, or name of the background job.
+	RemoteAddr string
+	ImportPath string
+	Version    string // Empty for the default branch.
+	Error      string // Why the request failed, empty if succeeded.
+}
+
+// Sink saves audit events.
+type Sink interface {
+	Write(e *Event) error
+}
+
+var sink Sink
+
+// SetSink sets where events are saved, nil disables auditing.
+func SetSink(s Sink) {
+	sink = s
+}
+
+// Enabled returns true if events are recorded.
+func Enabled() bool {
+	return sink != nil
+}
+
+// Record saves the event to the sink, the time is set if it is zero.
+func Record(e *Event) {
+	if sink == nil {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if err := sink.Write(e); err != nil {
+		log.Error(2, "Failed to write audit event: %v", err)
+	}
+}
+
+// FileSink appends events to a file in JSON lines.
+type FileSink struct {
+	lock sync.Mutex
+	f    *os.File
+}
+
+// OpenFile opens the file to append events, it is created if not exists.
+func OpenFile(filename string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open: %v", err)
+	}
+	return &FileSink{f: f}, nil
+}
+
+func (s *FileSink) Write(e *Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err = s.f.Write(append(data, '\n'))
+	return err
+}
+
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
@@ -0,0 +1,250 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package auth authenticates users of private documentation hosting and
+// authorizes access to packages by import path prefixes.
+package auth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Unknwon/gowalker/pkg/httplib"
+)
+
+// User is an authenticated user.
+type User struct {
+	Name   string
+	Groups []string
+}
+
+// Is returns true if the user matches the principal, which is "*" for
+// any user, "group:<name>" for members of a group, and "user:<name>"
+// or just the name for the user.
+func (u *User) Is(principal string) bool {
+	switch {
+	case principal == "*":
+		return true
+	case strings.HasPrefix(principal, "group:"):
+		group := strings.TrimPrefix(principal, "group:")
+		for _, g := range u.Groups {
+			if g == group {
+				return true
+			}
+		}
+		return false
+	}
+	return strings.TrimPrefix(principal, "user:") == u.Name
+}
+
+// Rule allows principals to access packages whose import paths have the prefix.
+type Rule struct {
+	Prefix     string
+	Principals []string
+}
+
+// matches returns true if the import path is the prefix or under it.
+func (r Rule) matches(importPath string) bool {
+	prefix := strings.TrimSuffix(r.Prefix, "/")
+	return importPath == prefix || strings.HasPrefix(importPath, prefix+"/")
+}
+
+// ACL is the list of rules, the rule with longest matched prefix applies.
+type ACL []Rule
+
+// ParseACL returns the ACL from comma-separated principals by import path prefixes.
+func ParseACL(rules map[string]string) ACL {
+	acl := make(ACL, 0, len(rules))
+	for prefix, principals := range rules {
+		r := Rule{Prefix: prefix}
+		for _, p := range strings.Split(principals, ",") {
+			if p = strings.TrimSpace(p); len(p) > 0 {
+				r.Principals = append(r.Principals, p)
+			}
+		}
+		acl = append(acl, r)
+	}
+	sort.Slice(acl, func(i, j int) bool {
+		return len(acl[i].Prefix) > len(acl[j].Prefix)
+	})
+	return acl
+}
+
+// Allowed returns true if the user can access the package of given import path.
+// Packages not covered by any rule are allowed for all authenticated users.
+func (acl ACL) Allowed(u *User, importPath string) bool {
+	if u == nil {
+		return false
+	}
+
+	for _, r := range acl {
+		if !r.matches(importPath) {
+			continue
+		}
+		for _, p := range r.Principals {
+			if u.Is(p) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Tokens are static API tokens of users.
+type Tokens map[string]*User
+
+// LoadTokens reads tokens from the file, each line of which is the token,
+// the user name and optional comma-separated groups separated by spaces.
+// Empty lines and lines starting with "#" are ignored.
+func LoadTokens(filename string) (Tokens, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open: %v", err)
+	}
+	defer f.Close()
+
+	tokens := make(Tokens)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expect token and user name", n)
+		}
+		u := &User{Name: fields[1]}
+		if len(fields) > 2 {
+			u.Groups = strings.Split(fields[2], ",")
+		}
+		tokens[fields[0]] = u
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read: %v", err)
+	}
+	return tokens, nil
+}
+
+// OAuth2 authenticates users with the authorization code flow of OAuth2,
+// user information is read from the userinfo endpoint of OpenID Connect
+// or a compatible API.
+type OAuth2 struct {
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	RedirectURL  string
+	Scopes       []string
+	// Claims of user information for the user name and groups.
+	NameClaim   string
+	GroupsClaim string
+}
+
+// Discover sets endpoints from the OpenID Connect discovery document of the issuer.
+func (o *OAuth2) Discover(issuer string) error {
+	var config struct {
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	}
+	if err := httplib.Get(strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration").ToJson(&config); err != nil {
+		return fmt.Errorf("get discovery document: %v", err)
+	} else if len(config.AuthorizationEndpoint) == 0 || len(config.TokenEndpoint) == 0 {
+		return errors.New("discovery document has no authorization or token endpoint")
+	}
+
+	o.AuthURL = config.AuthorizationEndpoint
+	o.TokenURL = config.TokenEndpoint
+	o.UserInfoURL = config.UserInfoEndpoint
+	return nil
+}
+
+// AuthCodeURL returns the URL to redirect users to for authorization.
+func (o *OAuth2) AuthCodeURL(state string) string {
+	v := url.Values{
+		"response_type": {"code"},
+		"client_id":     {o.ClientID},
+		"redirect_uri":  {o.RedirectURL},
+		"scope":         {strings.Join(o.Scopes, " ")},
+		"state":         {state},
+	}
+	sep := "?"
+	if strings.Contains(o.AuthURL, "?") {
+		sep = "&"
+	}
+	return o.AuthURL + sep + v.Encode()
+}
+
+// Exchange exchanges the authorization code for an access token and
+// returns the user it belongs to.
+func (o *OAuth2) Exchange(code string) (*User, error) {
+	var token struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err := httplib.Post(o.TokenURL).
+		Header("Accept", "application/json").
+		Param("grant_type", "authorization_code").
+		Param("code", code).
+		Param("redirect_uri", o.RedirectURL).
+		Param("client_id", o.ClientID).
+		Param("client_secret", o.ClientSecret).
+		ToJson(&token); err != nil {
+		return nil, fmt.Errorf("exchange token: %v", err)
+	} else if len(token.Error) > 0 {
+		return nil, fmt.Errorf("exchange token: %s", token.Error)
+	} else if len(token.AccessToken) == 0 {
+		return nil, errors.New("exchange token: no access token")
+	}
+
+	var claims map[string]interface{}
+	if err := httplib.Get(o.UserInfoURL).
+		Header("Accept", "application/json").
+		Header("Authorization", "Bearer "+token.AccessToken).
+		ToJson(&claims); err != nil {
+		return nil, fmt.Errorf("get user info: %v", err)
+	}
+	return o.userFromClaims(claims)
+}
+
+func (o *OAuth2) userFromClaims(claims map[string]interface{}) (*User, error) {
+	u := new(User)
+	for _, claim := range []string{o.NameClaim, "email", "preferred_username", "login", "sub"} {
+		if name, ok := claims[claim].(string); ok && len(name) > 0 {
+			u.Name = name
+			break
+		}
+	}
+	if len(u.Name) == 0 {
+		return nil, errors.New("user info has no user name")
+	}
+
+	if groups, ok := claims[o.GroupsClaim].([]interface{}); ok {
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				u.Groups = append(u.Groups, s)
+			}
+		}
+	}
+	return u, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestACL_Allowed(t *testing.T) {
+	acl := ParseACL(map[string]string{
+		"example.com":            "*",
+		"example.com/team":       "group:team, user:admin",
+		"example.com/team/alice": "alice",
+		"example.com/closed":     "",
+	})
+
+	alice := &User{Name: "alice"}
+	bob := &User{Name: "bob", Groups: []string{"team"}}
+	admin := &User{Name: "admin"}
+	tests := []struct {
+		name       string
+		user       *User
+		importPath string
+		want       bool
+	}{
+		{"anonymous", nil, "example.com/p", false},
+		{"not covered by rules", alice, "other.com/p", true},
+		{"any user", alice, "example.com/p", true},
+		{"member of group", bob, "example.com/team/p", true},
+		{"user principal", admin, "example.com/team/p", true},
+		{"not member of group", alice, "example.com/team/p", false},
+		{"longest prefix applies", alice, "example.com/team/alice/p", true},
+		{"longest prefix denies", bob, "example.com/team/alice", false},
+		{"prefix is not path element", bob, "example.com/teams", true},
+		{"no principals", admin, "example.com/closed", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := acl.Allowed(test.user, test.importPath); got != test.want {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadTokens(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		content string
+		want    Tokens
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			content: "",
+			want:    Tokens{},
+		},
+		{
+			name: "comments and groups",
+			content: `# token user groups
+t1 alice
+
+  t2 bob team,admin
+`,
+			want: Tokens{
+				"t1": {Name: "alice"},
+				"t2": {Name: "bob", Groups: []string{"team", "admin"}},
+			},
+		},
+		{
+			name:    "no user name",
+			content: "t1 alice\nt2\n",
+			wantErr: true,
+		},
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(dir, string(rune('a'+i)))
+			if err := ioutil.WriteFile(filename, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := LoadTokens(filename)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("got no error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := LoadTokens(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("got no error for missing file")
+	}
+}
@@ -19,6 +19,8 @@ import (
 	"path"
 	"regexp"
 	"strings"
+
+	"github.com/Unknwon/com"
 )
 
 var validHost = regexp.MustCompile(`^[-a-z0-9]+(?:\.[-a-z0-9]+)+$`)
@@ -49,6 +51,17 @@ func IsValidRemotePath(importPath string) bool {
 	return true
 }
 
+// IsWellFormedPath returns true if every element of importPath is valid, unlike
+// IsValidRemotePath, hosts are not checked so that private import paths are allowed.
+func IsWellFormedPath(importPath string) bool {
+	for _, part := range strings.Split(importPath, "/") {
+		if !isValidPathElement(part) {
+			return false
+		}
+	}
+	return true
+}
+
 // IsGoRepoPath returns true if path is in $GOROOT/src.
 func IsGoRepoPath(path string) bool {
 	return PathFlag(path)&goRepoPath != 0
@@ -71,6 +84,58 @@ func IsDocFile(n string) bool {
 	return strings.HasPrefix(strings.ToLower(n), "readme")
 }
 
+// IsMetaFile returns true if the file has information about the package other than
+// documentation, e.g. license, go.mod and changelog. Unlike IsDocFile, it does not
+// mean the directory has a package, so meta files are collected only in the
+// directory of the package.
+func IsMetaFile(n string) bool {
+	return n == "go.mod" || n == "CITATION.cff" ||
+		IsLicenseFile(n) || IsChangelogFile(n) || IsMaintainerFile(n) || IsBazelBuildFile(n)
+}
+
+// IsBazelBuildFile returns true if the file is a BUILD file of Bazel.
+func IsBazelBuildFile(n string) bool {
+	return n == "BUILD.bazel" || n == "BUILD"
+}
+
+// IsMaintainerFile returns true if the file could have maintainer information.
+func IsMaintainerFile(n string) bool {
+	return n == "CODEOWNERS" || strings.HasPrefix(strings.ToUpper(n), "MAINTAINERS")
+}
+
+// IsChangelogFile returns true if the file name looks like a changelog file.
+func IsChangelogFile(n string) bool {
+	n = strings.ToLower(n)
+	return strings.HasPrefix(n, "changelog") || strings.HasPrefix(n, "history")
+}
+
+var majorVersionElement = regexp.MustCompile(`^v([2-9]|[1-9][0-9]+)$`)
+
+// SplitMajorVersion returns import path without major version element
+// and the major version, it returns 1 if the import path has no major version.
+func SplitMajorVersion(importPath string) (string, int) {
+	parts := strings.Split(importPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if majorVersionElement.MatchString(parts[i]) {
+			return strings.Join(append(parts[:i:i], parts[i+1:]...), "/"), com.StrTo(parts[i][1:]).MustInt()
+		}
+	}
+	return importPath, 1
+}
+
+var licenseFilePrefixes = []string{"license", "licence", "copying", "unlicense"}
+
+// IsLicenseFile returns true if the file name looks like a license file.
+func IsLicenseFile(n string) bool {
+	n = strings.ToLower(n)
+	for _, prefix := range licenseFilePrefixes {
+		if strings.HasPrefix(n, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 var filterDirNames = []string{
 	"static", "docs", "views", "js", "assets", "public", "img", "css"}
 
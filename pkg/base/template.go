@@ -14,68 +14,27 @@
 
 package base
 
-import (
-	"path/filepath"
-	"strings"
-
-	"github.com/Unknwon/i18n"
-	log "gopkg.in/clog.v1"
-	"gopkg.in/fsnotify.v1"
-
-	"github.com/Unknwon/gowalker/pkg/setting"
-)
-
-func monitorI18nLocale() {
-	log.Info("Monitor i18n locale files enabled")
-
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		log.Fatal(2, "Failed to init locale watcher: %v", err)
-	}
-
-	go func() {
-		for {
-			select {
-			case event := <-watcher.Events:
-				switch filepath.Ext(event.Name) {
-				case ".ini":
-					if err := i18n.ReloadLangs(); err != nil {
-						log.Error(2, "Failed to relaod locale file reloaded: %v", err)
-					}
-					log.Trace("Locale file reloaded: %s", strings.TrimPrefix(event.Name, "conf/locale/"))
-				}
-			}
-		}
-	}()
-
-	if err := watcher.Add("conf/locale"); err != nil {
-		log.Fatal(2, "Failed to start locale watcher: %v", err)
-	}
-}
-
-func init() {
-	if !setting.ProdMode {
-		monitorI18nLocale()
-	}
-}
-
+// SubStr returns length runes of the string from start, followed by "..." if it is cut.
 func SubStr(str string, start, length int) string {
-	if len(str) == 0 {
+	runes := []rune(str)
+	if len(runes) == 0 {
 		return ""
 	}
 	end := start + length
-	if len(str) < end {
+	if len(runes) < end {
 		return str
 	}
-	return str[start:end] + "..."
+	return string(runes[start:end]) + "..."
 }
 
+// RearSubStr returns the last length runes of the string, preceded by "..." if it is cut.
 func RearSubStr(str string, length int) string {
-	if len(str) == 0 {
+	runes := []rune(str)
+	if len(runes) == 0 {
 		return ""
 	}
-	if len(str) < length {
+	if len(runes) < length {
 		return str
 	}
-	return "..." + str[len(str)-length:]
+	return "..." + string(runes[len(runes)-length:])
 }
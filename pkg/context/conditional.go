@@ -0,0 +1,175 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package context
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// etagMatches returns true if the list of If-None-Match header contains the ETag.
+func etagMatches(list, etag string) bool {
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified returns true if the client of the request has a fresh copy
+// of the response with given ETag and modification time.
+func notModified(r *http.Request, etag string, modTime time.Time) bool {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		return false
+	}
+
+	// If-Modified-Since is ignored when If-None-Match is present.
+	if inm := r.Header.Get("If-None-Match"); len(inm) > 0 {
+		return len(etag) > 0 && etagMatches(inm, etag)
+	}
+	if modTime.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !modTime.Truncate(time.Second).After(since)
+}
+
+func writeNotModified(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// CheckModified sets ETag and Last-Modified headers of the response, and responds
+// 304 Not Modified if the client has a fresh copy. It returns true if the response
+// has been written. The ETag must be quoted, and zero modTime is omitted.
+func (c *Context) CheckModified(etag string, modTime time.Time) bool {
+	h := c.Resp.Header()
+	h.Set("ETag", etag)
+	if !modTime.IsZero() {
+		h.Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(c.Req.Request, etag, modTime) {
+		writeNotModified(c.Resp)
+		return true
+	}
+	return false
+}
+
+// bufferableType returns true if responses of the content type are buffered to
+// compute ETags, i.e. HTML pages and JSON, other responses could be large or streamed.
+func bufferableType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "text/html" || mediaType == "application/json"
+}
+
+// bufferedWriter holds the response with status 200 until the handler returns,
+// unless the handler has set validators or Content-Length, or the content type
+// is not bufferable, in which case the response is passed through.
+type bufferedWriter struct {
+	http.ResponseWriter
+	status    int
+	buffering bool
+	buf       bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+
+	h := w.Header()
+	w.buffering = status == http.StatusOK &&
+		len(h.Get("ETag")) == 0 && len(h.Get("Last-Modified")) == 0 && len(h.Get("Content-Length")) == 0 &&
+		bufferableType(h.Get("Content-Type"))
+	if !w.buffering {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *bufferedWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		// Same as net/http, so that the content type is known to decide whether to buffer.
+		if h := w.Header(); len(h.Get("Content-Type")) == 0 {
+			h.Set("Content-Type", http.DetectContentType(p))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffering {
+		return w.buf.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush writes the buffered response and stops buffering, because the handler
+// wants the response to be streamed.
+func (w *bufferedWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	} else if w.buffering {
+		w.buffering = false
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Conditional wraps the handler to honor conditional GET and HEAD requests.
+// HTML and JSON responses with status 200 that do not have an ETag get a strong
+// one computed from the content, and 304 Not Modified is responded instead if
+// the client has a fresh copy. Handlers of other responses check conditional
+// requests by themselves, e.g. with CheckModified.
+func Conditional(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		bw := &bufferedWriter{ResponseWriter: w}
+		handler.ServeHTTP(bw, r)
+		if !bw.buffering {
+			return
+		}
+
+		h := w.Header()
+		sum := sha256.Sum256(bw.buf.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		h.Set("ETag", etag)
+		if notModified(r, etag, time.Time{}) {
+			writeNotModified(w)
+			return
+		}
+
+		w.WriteHeader(bw.status)
+		w.Write(bw.buf.Bytes())
+	})
+}
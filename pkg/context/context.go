@@ -16,6 +16,7 @@ package context
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -93,6 +94,49 @@ func (c *Context) Handle(status int, title string, err error) {
 	c.HTML(status, fmt.Sprintf("status/%d", status))
 }
 
+// ClientIP returns the IP address of the client. Forwarded headers are only
+// honored when the request comes from one of trusted proxies, otherwise
+// clients could choose addresses by themselves.
+func (c *Context) ClientIP() string {
+	ip, _, err := net.SplitHostPort(c.Req.RemoteAddr)
+	if err != nil {
+		ip = c.Req.RemoteAddr
+	}
+	if !trustedProxy(ip) {
+		return ip
+	}
+
+	if forwarded := c.Req.Header.Get("X-Forwarded-For"); len(forwarded) > 0 {
+		// Only the last address is appended by the trusted proxy.
+		addrs := strings.Split(forwarded, ",")
+		if addr := strings.TrimSpace(addrs[len(addrs)-1]); net.ParseIP(addr) != nil {
+			return addr
+		}
+	}
+	if addr := strings.TrimSpace(c.Req.Header.Get("X-Real-IP")); net.ParseIP(addr) != nil {
+		return addr
+	}
+	return ip
+}
+
+// trustedProxy returns true if the IP address is of one of trusted proxies.
+func trustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range setting.TrustedProxies {
+		if _, ipnet, err := net.ParseCIDR(proxy); err == nil {
+			if ipnet.Contains(ip) {
+				return true
+			}
+		} else if ip.Equal(net.ParseIP(proxy)) {
+			return true
+		}
+	}
+	return false
+}
+
 // Contexter initializes a classic context for a request.
 func Contexter() macaron.Handler {
 	return func(c *macaron.Context, f *session.Flash) {
@@ -111,6 +155,7 @@ func Contexter() macaron.Handler {
 		ctx.Data["HasPrefix"] = strings.HasPrefix
 		ctx.Data["int64"] = base.Int64
 		ctx.Data["Year"] = time.Now().Year()
+		ctx.Data["SandboxEnabled"] = setting.Sandbox.Enabled
 
 		c.Map(ctx)
 	}
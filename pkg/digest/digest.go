@@ -0,0 +1,238 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package digest generates email digests of changes of watched packages
+// from events of stored documentation.
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+// Watchlist is the packages that a user watches.
+type Watchlist struct {
+	Name     string
+	Email    string
+	Prefixes []string // Import path prefixes of watched packages.
+}
+
+// Watches returns true if the package is under any prefix of the watchlist.
+func (w *Watchlist) Watches(importPath string) bool {
+	for _, p := range w.Prefixes {
+		p = strings.Trim(p, "/")
+		if importPath == p || strings.HasPrefix(importPath, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Entry is a change of documentation of a package.
+type Entry struct {
+	Kind    doc.EventKind
+	Version string
+	Commit  string
+	Time    time.Time
+	API     *doc.APIDiff
+}
+
+// Package is changes of a package in the period, oldest first.
+type Package struct {
+	ImportPath string
+	Synopsis   string
+	URL        string
+	Entries    []*Entry
+}
+
+// Incompatible returns true if any change removes or changes exported identifiers.
+func (p *Package) Incompatible() bool {
+	for _, e := range p.Entries {
+		if e.API != nil && !e.API.Compatible() {
+			return true
+		}
+	}
+	return false
+}
+
+// Digest is changes of watched packages in the period.
+type Digest struct {
+	Watchlist    *Watchlist
+	Since, Until time.Time
+	Packages     []*Package // Sorted by import path.
+}
+
+// Generate returns the digest of events of packages in the watchlist, events
+// that do not change anything of interest are omitted. Links of packages
+// are under the base URL.
+func Generate(w *Watchlist, events []*doc.DocEvent, since, until time.Time, baseURL string) *Digest {
+	pkgs := make(map[string]*Package)
+	for _, e := range events {
+		if e.Time.Before(since) || !e.Time.Before(until) || !w.Watches(e.ImportPath) {
+			continue
+		}
+		// Updates of default branch are only interesting when APIs change.
+		if e.Kind == doc.EK_Updated && (e.API == nil || e.API.Empty()) {
+			continue
+		}
+
+		p := pkgs[e.ImportPath]
+		if p == nil {
+			p = &Package{
+				ImportPath: e.ImportPath,
+				URL:        strings.TrimSuffix(baseURL, "/") + "/" + e.ImportPath,
+			}
+			pkgs[e.ImportPath] = p
+		}
+		if len(e.Synopsis) > 0 {
+			p.Synopsis = e.Synopsis
+		}
+		p.Entries = append(p.Entries, &Entry{
+			Kind:    e.Kind,
+			Version: e.Version,
+			Commit:  e.Commit,
+			Time:    e.Time,
+			API:     e.API,
+		})
+	}
+
+	d := &Digest{
+		Watchlist: w,
+		Since:     since,
+		Until:     until,
+		Packages:  make([]*Package, 0, len(pkgs)),
+	}
+	for _, p := range pkgs {
+		sort.Slice(p.Entries, func(i, j int) bool {
+			return p.Entries[i].Time.Before(p.Entries[j].Time)
+		})
+		d.Packages = append(d.Packages, p)
+	}
+	sort.Slice(d.Packages, func(i, j int) bool {
+		return d.Packages[i].ImportPath < d.Packages[j].ImportPath
+	})
+	return d
+}
+
+// Empty returns true if no watched package is changed.
+func (d *Digest) Empty() bool {
+	return len(d.Packages) == 0
+}
+
+// Subject returns the subject of the email.
+func (d *Digest) Subject() string {
+	return fmt.Sprintf("Go Walker digest: %d packages changed (%s - %s)",
+		len(d.Packages), d.Since.Format("Jan 2"), d.Until.Format("Jan 2, 2006"))
+}
+
+// Email clients only support inline styles.
+var htmlTpl = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"removed": func(changes []*doc.Change) []string {
+		decls := make([]string, len(changes))
+		for i, c := range changes {
+			decls[i] = c.Old
+		}
+		return decls
+	},
+	"changed": func(changes []*doc.Change) []string {
+		decls := make([]string, len(changes))
+		for i, c := range changes {
+			decls[i] = "- " + strings.Replace(c.Old, "\n", "\n- ", -1) + "\n+ " + strings.Replace(c.New, "\n", "\n+ ", -1)
+		}
+		return decls
+	},
+	"added": func(changes []*doc.Change) []string {
+		decls := make([]string, len(changes))
+		for i, c := range changes {
+			decls[i] = c.New
+		}
+		return decls
+	},
+	"date": func(t time.Time) string {
+		return t.UTC().Format("Jan 2, 2006 15:04 UTC")
+	},
+	"short": func(commit string) string {
+		if len(commit) > 7 {
+			return commit[:7]
+		}
+		return commit
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Subject}}</title></head>
+<body style="font-family: sans-serif; color: #333; max-width: 720px;">
+<h2>Changes of watched packages</h2>
+<p>{{date .Since}} to {{date .Until}}{{if .Watchlist.Name}}, watchlist of {{.Watchlist.Name}}{{end}}.</p>
+{{define "changes"}}{{if .}}<pre style="background: #f5f5f5; padding: 8px; overflow-x: auto;">{{range .}}{{.}}
+{{end}}</pre>{{end}}{{end}}
+{{range .Packages}}
+<h3><a href="{{.URL}}">{{.ImportPath}}</a>{{if .Incompatible}} <span style="color: #c00;">incompatible</span>{{end}}</h3>
+{{if .Synopsis}}<p>{{.Synopsis}}</p>{{end}}
+<ul>
+{{range .Entries}}<li>
+{{if eq .Kind "version"}}New version <b>{{.Version}}</b>{{else if eq .Kind "new"}}First documented{{else}}Default branch updated{{end}}
+{{if .Commit}}at <code>{{short .Commit}}</code>{{end}} on {{date .Time}}
+{{with .API}}
+{{if .Removed}}<p style="color: #c00;">Removed:</p>{{template "changes" (removed .Removed)}}{{end}}
+{{if .Changed}}<p style="color: #c60;">Changed:</p>{{template "changes" (changed .Changed)}}{{end}}
+{{if .Added}}<p style="color: #080;">Added:</p>{{template "changes" (added .Added)}}{{end}}
+{{end}}
+</li>{{end}}
+</ul>
+{{else}}
+<p>No watched packages are changed.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// HTML returns the body of the email in HTML.
+func (d *Digest) HTML() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTpl.Execute(&buf, d); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Sender sends emails, e.g. through SMTP or email services.
+type Sender interface {
+	Send(to, subject string, html []byte) error
+}
+
+// Send generates digests of watchlists from the events and sends them by the sender,
+// watchlists that have no changes are skipped.
+func Send(sender Sender, watchlists []*Watchlist, events []*doc.DocEvent, since, until time.Time, baseURL string) error {
+	for _, w := range watchlists {
+		d := Generate(w, events, since, until, baseURL)
+		if d.Empty() {
+			continue
+		}
+
+		html, err := d.HTML()
+		if err != nil {
+			return fmt.Errorf("render digest of %s: %v", w.Email, err)
+		}
+		if err = sender.Send(w.Email, d.Subject(), html); err != nil {
+			return fmt.Errorf("send digest to %s: %v", w.Email, err)
+		}
+	}
+	return nil
+}
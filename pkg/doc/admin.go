@@ -0,0 +1,326 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Unknwon/com"
+
+	"github.com/Unknwon/gowalker/models"
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+var ErrBlocked = errors.New("package is blocked")
+
+// BlockEntry is a blocked import path, packages under it are blocked as well.
+type BlockEntry struct {
+	ImportPath string
+	Reason     string
+	Time       time.Time
+}
+
+// Blocklist is the list of import paths that are not walked or served,
+// which is saved to a JSON file. It is safe for concurrent use.
+type Blocklist struct {
+	lock    sync.RWMutex
+	path    string // Where to save, empty means not persisted.
+	entries map[string]*BlockEntry
+}
+
+// OpenBlocklist returns the blocklist saved at given path, or an empty one
+// if the file does not exist.
+func OpenBlocklist(filename string) (*Blocklist, error) {
+	b := &Blocklist{
+		path:    filename,
+		entries: make(map[string]*BlockEntry),
+	}
+	if len(filename) == 0 || !com.IsFile(filename) {
+		return b, nil
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read: %v", err)
+	}
+	var entries []*BlockEntry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("decode: %v", err)
+	}
+	for _, e := range entries {
+		b.entries[e.ImportPath] = e
+	}
+	return b, nil
+}
+
+// save writes entries to the file, the caller must hold the lock.
+func (b *Blocklist) save() error {
+	if len(b.path) == 0 {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(b.list(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode: %v", err)
+	}
+	os.MkdirAll(path.Dir(b.path), os.ModePerm)
+	tmpPath := b.path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("write: %v", err)
+	}
+	if err = os.Rename(tmpPath, b.path); err != nil {
+		return fmt.Errorf("rename: %v", err)
+	}
+	return nil
+}
+
+// Block adds the import path to the blocklist.
+func (b *Blocklist) Block(importPath, reason string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.entries[importPath] = &BlockEntry{
+		ImportPath: importPath,
+		Reason:     reason,
+		Time:       time.Now(),
+	}
+	return b.save()
+}
+
+// Unblock removes the import path from the blocklist.
+func (b *Blocklist) Unblock(importPath string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.entries, importPath)
+	return b.save()
+}
+
+func (b *Blocklist) list() []*BlockEntry {
+	entries := make([]*BlockEntry, 0, len(b.entries))
+	for _, e := range b.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ImportPath < entries[j].ImportPath
+	})
+	return entries
+}
+
+// List returns blocked import paths in sorted order.
+func (b *Blocklist) List() []*BlockEntry {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.list()
+}
+
+// IsBlocked returns true if the import path or any of its parents is blocked.
+func (b *Blocklist) IsBlocked(importPath string) bool {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	for p := importPath; ; {
+		if _, ok := b.entries[p]; ok {
+			return true
+		}
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			return false
+		}
+		p = p[:i]
+	}
+}
+
+var (
+	blocklist     *Blocklist
+	blockPatterns string
+)
+
+// SetBlocklist sets the blocklist consulted before packages are walked or served.
+func SetBlocklist(b *Blocklist) {
+	blocklist = b
+}
+
+// SetBlockPatterns sets comma-separated glob patterns of import path prefixes
+// that are blocked in addition to the blocklist, which have the same syntax
+// as GOPRIVATE, e.g. "example.com,*.example.org,github.com/spam".
+func SetBlockPatterns(patterns string) {
+	blockPatterns = patterns
+}
+
+// IsBlocked returns true if the package of given import path must not be walked or served.
+func IsBlocked(importPath string) bool {
+	return matchPrefixPatterns(blockPatterns, importPath) ||
+		(blocklist != nil && blocklist.IsBlocked(importPath))
+}
+
+// Purge removes all stored documentation and information of the package.
+func Purge(importPath string) error {
+	if docStore != nil {
+		versions, err := storedVersions(docStore, importPath)
+		if err != nil {
+			return fmt.Errorf("list stored versions: %v", err)
+		}
+		for _, v := range append(versions, "") {
+			if err = docStore.Delete(importPath, v); err != nil && err != ErrDocNotFound {
+				return fmt.Errorf("delete %s@%s: %v", importPath, v, err)
+			}
+		}
+		if sweeper, ok := docStore.(Sweeper); ok {
+			if err = sweeper.Sweep(); err != nil {
+				return fmt.Errorf("sweep: %v", err)
+			}
+		}
+	}
+	return deletePackage(importPath)
+}
+
+// deletePackage removes information of the package from the index and database,
+// and rendered files of its documentation.
+func deletePackage(importPath string) error {
+	if remover, ok := indexer.(interface {
+		Remove(importPath string)
+	}); ok {
+		remover.Remove(importPath)
+	}
+
+	// Pages are saved as "<path>.js" and "<path>-<n>.js", and READMEs as "<path>_RM_<lang>.js".
+	docPath := path.Join(setting.DocsJSPath, importPath)
+	for _, pattern := range []string{docPath + ".js", docPath + "-*.js", docPath + "_RM_*.js"} {
+		names, _ := filepath.Glob(pattern)
+		for _, name := range names {
+			if strings.HasPrefix(name, docPath+"-") {
+				if _, err := strconv.Atoi(strings.TrimSuffix(name[len(docPath)+1:], ".js")); err != nil {
+					continue // Page of another package like "<path>-go.js".
+				}
+			}
+			if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("remove rendered file: %v", err)
+			}
+		}
+	}
+
+	if err := models.DeletePackageByPath(importPath); err != nil {
+		return fmt.Errorf("delete package info: %v", err)
+	}
+	return nil
+}
+
+// QueueInspector is implemented by queues that can report their length.
+type QueueInspector interface {
+	Len() (int64, error)
+}
+
+// QueueState is the state of the crawl queue and crawlers of this server.
+type QueueState struct {
+	Length   int64    // -1 if the queue cannot report its length.
+	Crawlers int      // Number of crawlers started by this server.
+	Crawling []string // Import paths being walked by crawlers of this server.
+}
+
+var (
+	numCrawlers int32
+	crawling    sync.Map // Import paths being walked by crawlers.
+)
+
+// GetQueueState returns the state of the crawl queue, or nil if the queue is not set.
+func GetQueueState() (*QueueState, error) {
+	if crawlQueue == nil {
+		return nil, nil
+	}
+
+	state := &QueueState{
+		Length:   -1,
+		Crawlers: int(atomic.LoadInt32(&numCrawlers)),
+	}
+	if inspector, ok := crawlQueue.(QueueInspector); ok {
+		n, err := inspector.Len()
+		if err != nil {
+			return nil, fmt.Errorf("get queue length: %v", err)
+		}
+		state.Length = n
+	}
+	crawling.Range(func(k, _ interface{}) bool {
+		state.Crawling = append(state.Crawling, k.(string))
+		return true
+	})
+	sort.Strings(state.Crawling)
+	return state, nil
+}
+
+// Counters of walks since the server is started.
+var walkCounters struct {
+	walks, walkErrors           int64
+	crawlerWalks, crawlerErrors int64
+	crawlerUnchanged            int64
+}
+
+// countWalk increases counters of walks by result of the walk.
+func countWalk(byCrawler bool, err error) {
+	switch {
+	case byCrawler && err != nil:
+		atomic.AddInt64(&walkCounters.crawlerErrors, 1)
+	case byCrawler:
+		atomic.AddInt64(&walkCounters.crawlerWalks, 1)
+	case err != nil:
+		atomic.AddInt64(&walkCounters.walkErrors, 1)
+	default:
+		atomic.AddInt64(&walkCounters.walks, 1)
+	}
+}
+
+// Metrics are statistics of the doc service of this server.
+type Metrics struct {
+	Walks            int64 // Walks requested by users since the server is started.
+	WalkErrors       int64
+	CrawlerWalks     int64 // Walks by crawlers since the server is started.
+	CrawlerErrors    int64
+	CrawlerUnchanged int64 // Walks skipped by crawlers because the upstream has not changed.
+	Blocked          int   // Number of blocked import paths.
+	Goroutines       int
+	HeapAlloc        uint64 // Bytes of allocated heap objects.
+}
+
+// GetMetrics returns current metrics of the doc service.
+func GetMetrics() *Metrics {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	m := &Metrics{
+		Walks:            atomic.LoadInt64(&walkCounters.walks),
+		WalkErrors:       atomic.LoadInt64(&walkCounters.walkErrors),
+		CrawlerWalks:     atomic.LoadInt64(&walkCounters.crawlerWalks),
+		CrawlerErrors:    atomic.LoadInt64(&walkCounters.crawlerErrors),
+		CrawlerUnchanged: atomic.LoadInt64(&walkCounters.crawlerUnchanged),
+		Goroutines:       runtime.NumGoroutine(),
+		HeapAlloc:        mem.HeapAlloc,
+	}
+	if blocklist != nil {
+		m.Blocked = len(blocklist.List())
+	}
+	return m
+}
@@ -0,0 +1,101 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"go/ast"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// aliasTarget returns import path and name of the type aliased by the declaration,
+// e.g. "net/http" and "Client" for "type A = http.Client". The import path is empty
+// for types of the same package or predeclared ones.
+func aliasTarget(decl *ast.GenDecl) (path, name string, ok bool) {
+	if decl == nil || len(decl.Specs) != 1 {
+		return "", "", false
+	}
+	spec, _ := decl.Specs[0].(*ast.TypeSpec)
+	if spec == nil || !spec.Assign.IsValid() {
+		return "", "", false
+	}
+
+	switch x := spec.Type.(type) {
+	case *ast.Ident:
+		return "", x.Name, true
+	case *ast.SelectorExpr:
+		if pkg, _ := x.X.(*ast.Ident); pkg != nil && pkg.Obj != nil && pkg.Obj.Kind == ast.Pkg {
+			if spec, _ := pkg.Obj.Decl.(*ast.ImportSpec); spec != nil {
+				if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+					return path, x.Sel.Name, true
+				}
+			}
+		}
+	}
+	// Aliases of type literals and instantiated generic types.
+	return "", "", true
+}
+
+// setAlias marks the type as an alias and links it to the aliased type.
+func (w *Walker) setAlias(t *Type, decl *ast.GenDecl) {
+	path, name, ok := aliasTarget(decl)
+	if !ok {
+		return
+	}
+	t.IsAlias = true
+	t.AliasOf = name
+
+	switch {
+	case len(name) == 0:
+	case len(path) == 0:
+		if predeclared[name] == notPredeclared {
+			t.AliasURL = "#" + name
+		}
+	default:
+		t.AliasOf = path[strings.LastIndex(path, "/")+1:] + "." + name
+		if url, err := ResolveRef(path, name); err == nil {
+			t.AliasURL = url
+		}
+	}
+}
+
+// moveAliasMethods moves methods declared on aliases to the aliased types of the
+// same package, which are the types that actually own the methods.
+func moveAliasMethods(tps, itps []*Type) {
+	byName := make(map[string]*Type, len(tps)+len(itps))
+	for _, t := range append(tps[:len(tps):len(tps)], itps...) {
+		byName[t.Name] = t
+	}
+
+	byMethodName := func(fns []*Func) func(i, j int) bool {
+		return func(i, j int) bool { return fns[i].Name < fns[j].Name }
+	}
+	for _, t := range byName {
+		if !t.IsAlias || len(t.Methods)+len(t.IMethods) == 0 {
+			continue
+		}
+		target := byName[t.AliasOf]
+		if target == nil || target == t {
+			continue
+		}
+
+		target.Methods = append(target.Methods, t.Methods...)
+		target.IMethods = append(target.IMethods, t.IMethods...)
+		sort.Slice(target.Methods, byMethodName(target.Methods))
+		sort.Slice(target.IMethods, byMethodName(target.IMethods))
+		t.Methods, t.IMethods = nil, nil
+	}
+}
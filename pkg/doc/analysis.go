@@ -0,0 +1,187 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "gopkg.in/clog.v1"
+)
+
+// Finding is a diagnostic reported by an analyzer.
+type Finding struct {
+	Analyzer string // e.g. "printf".
+	Message  string
+	Filename string // Base name of the file.
+	Line     int
+	Col      int
+	Symbol   string // Declaration that contains the finding, e.g. "New" or "Client.Do", empty if none.
+}
+
+// Anchor returns anchor of the declaration in documentation pages, e.g. "Client_Do".
+func (f Finding) Anchor() string {
+	return strings.Replace(f.Symbol, ".", "_", -1)
+}
+
+// Analyzer runs analyses, e.g. go vet or staticcheck, over a walked package
+// whose files are in the directory.
+type Analyzer interface {
+	Analyze(pdoc *Package, dir string) ([]Finding, error)
+}
+
+var analyzer Analyzer
+
+// SetAnalyzer sets the analyzer of walked packages, passing nil disables analyses.
+// Packages are analyzed in background only when their files are on disk, e.g. fetched
+// by VCS commands, and stored to the doc store.
+func SetAnalyzer(a Analyzer) {
+	analyzer = a
+}
+
+// VetAnalyzer runs "go vet" with the go command in PATH, which is wrapped by
+// the sandbox command of settings.
+type VetAnalyzer struct {
+	Analyzers []string // Names of analyzers to run, empty means the default set of go vet.
+	VetTool   string   // Path of an alternative analysis tool, e.g. a staticcheck-based vet tool.
+}
+
+func (a *VetAnalyzer) Analyze(pdoc *Package, dir string) ([]Finding, error) {
+	args := []string{"vet", "-json"}
+	if len(a.VetTool) > 0 {
+		args = append(args, "-vettool="+a.VetTool)
+	}
+	for _, name := range a.Analyzers {
+		args = append(args, "-"+name)
+	}
+	out, err := goVet(dir, append(args, ".")...)
+	if err != nil {
+		return nil, err
+	}
+	return parseVetJSON(out)
+}
+
+// parseVetJSON parses output of "go vet -json", which consists of comment lines of
+// package names and JSON objects of diagnostics by package and analyzer.
+func parseVetJSON(data []byte) ([]Finding, error) {
+	var buf bytes.Buffer
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+	}
+
+	var findings []Finding
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var pkgs map[string]map[string]json.RawMessage
+		if err := dec.Decode(&pkgs); err != nil {
+			return nil, fmt.Errorf("decode: %v", err)
+		}
+		for _, analyzers := range pkgs {
+			for name, raw := range analyzers {
+				var diags []struct {
+					Posn    string `json:"posn"`
+					Message string `json:"message"`
+				}
+				// Failures of analyzers are objects instead of lists.
+				if json.Unmarshal(raw, &diags) != nil {
+					continue
+				}
+				for _, d := range diags {
+					f := Finding{Analyzer: name, Message: d.Message}
+					f.Filename, f.Line, f.Col = parsePosn(d.Posn)
+					findings = append(findings, f)
+				}
+			}
+		}
+	}
+	return findings, nil
+}
+
+// parsePosn parses position in the form of "file:line:col".
+func parsePosn(posn string) (filename string, line, col int) {
+	parts := strings.Split(posn, ":")
+	if n := len(parts); n >= 3 {
+		line, _ = strconv.Atoi(parts[n-2])
+		col, _ = strconv.Atoi(parts[n-1])
+		posn = strings.Join(parts[:n-2], ":")
+	}
+	return filepath.Base(posn), line, col
+}
+
+// attachFindings sets findings of the package and attaches them to declarations
+// that contain them.
+func attachFindings(pdoc *Package, findings []Finding) {
+	type decl struct {
+		span   *Span
+		name   string
+		attach func(f Finding)
+	}
+	var decls []decl
+	values := func(vals []*Value) {
+		for _, v := range vals {
+			v := v
+			decls = append(decls, decl{&v.Span, v.Name, func(f Finding) { v.Findings = append(v.Findings, f) }})
+		}
+	}
+	funcs := func(recv string, fns []*Func) {
+		for _, fn := range fns {
+			fn := fn
+			decls = append(decls, decl{&fn.Span, recv + fn.Name, func(f Finding) { fn.Findings = append(fn.Findings, f) }})
+		}
+	}
+	values(pdoc.Consts)
+	values(pdoc.Vars)
+	funcs("", pdoc.Funcs)
+	for _, t := range pdoc.Types {
+		t := t
+		decls = append(decls, decl{&t.Span, t.Name, func(f Finding) { t.Findings = append(t.Findings, f) }})
+		values(t.Consts)
+		values(t.Vars)
+		funcs("", t.Funcs)
+		funcs(t.Name+".", t.Methods)
+	}
+
+	for i := range findings {
+		for _, d := range decls {
+			if d.span.Filename == findings[i].Filename &&
+				d.span.Line <= findings[i].Line && findings[i].Line <= d.span.EndLine {
+				findings[i].Symbol = d.name
+				d.attach(findings[i])
+				break
+			}
+		}
+	}
+	pdoc.Findings = findings
+}
+
+// analyze runs the analyzer over the package in the directory if it is set.
+func analyze(pdoc *Package, dir string) {
+	if analyzer == nil {
+		return
+	}
+	findings, err := analyzer.Analyze(pdoc, dir)
+	if err != nil {
+		log.Trace("Failed to analyze %q: %v", pdoc.ImportPath, err)
+		return
+	}
+	attachFindings(pdoc, findings)
+}
@@ -0,0 +1,108 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVetJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []Finding
+	}{
+		{
+			name:   "no diagnostics",
+			output: "# example.com/p\n{}\n",
+		},
+		{
+			name: "diagnostics",
+			output: `# example.com/p
+{
+	"example.com/p": {
+		"printf": [
+			{
+				"posn": "/tmp/repo/p.go:12:2",
+				"message": "Printf call has arguments but no formatting directives"
+			}
+		]
+	}
+}
+`,
+			want: []Finding{
+				{Analyzer: "printf", Message: "Printf call has arguments but no formatting directives", Filename: "p.go", Line: 12, Col: 2},
+			},
+		},
+		{
+			name: "failure of analyzer",
+			output: `{
+	"example.com/p": {
+		"copylocks": {"error": "analysis failed"}
+	}
+}
+`,
+		},
+		{
+			name: "multiple packages",
+			output: `# example.com/p
+{"example.com/p": {"unreachable": [{"posn": "/tmp/repo/p.go:3:1", "message": "unreachable code"}]}}
+# example.com/p [example.com/p.test]
+{"example.com/p [example.com/p.test]": {"tests": [{"posn": "p_test.go:5:1", "message": "malformed example"}]}}
+`,
+			want: []Finding{
+				{Analyzer: "unreachable", Message: "unreachable code", Filename: "p.go", Line: 3, Col: 1},
+				{Analyzer: "tests", Message: "malformed example", Filename: "p_test.go", Line: 5, Col: 1},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseVetJSON([]byte(test.output))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := parseVetJSON([]byte("{not json")); err == nil {
+		t.Fatal("got no error for malformed output")
+	}
+}
+
+func TestParsePosn(t *testing.T) {
+	tests := []struct {
+		posn     string
+		filename string
+		line     int
+		col      int
+	}{
+		{"/tmp/repo/p.go:12:2", "p.go", 12, 2},
+		{"p.go:1:1", "p.go", 1, 1},
+		{"p.go", "p.go", 0, 0},
+		{"", ".", 0, 0},
+	}
+	for _, test := range tests {
+		filename, line, col := parsePosn(test.posn)
+		if filename != test.filename || line != test.line || col != test.col {
+			t.Errorf("parsePosn(%q) = %q, %d, %d, want %q, %d, %d",
+				test.posn, filename, line, col, test.filename, test.line, test.col)
+		}
+	}
+}
@@ -0,0 +1,502 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"sort"
+	"strings"
+)
+
+// apiDumper holds the state used when exporting API in the format of
+// api/*.txt files of the Go project.
+type apiDumper struct {
+	prefix string
+	lines  []string
+	consts map[string]string // Constant values known from type information.
+	typed  map[string]bool   // Constants and variables emitted from type information.
+}
+
+func (d *apiDumper) emit(format string, args ...interface{}) {
+	d.lines = append(d.lines, d.prefix+fmt.Sprintf(format, args...))
+}
+
+// apiNormalize rewrites type expression in place to the form used by the API files,
+// which has no parameter names and uses canonical names of basic type aliases.
+func apiNormalize(expr ast.Expr) ast.Expr {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Ident:
+			switch n.Name {
+			case "byte":
+				n.Name = "uint8"
+			case "rune":
+				n.Name = "int32"
+			}
+		case *ast.FuncType:
+			n.Params = unnamedFields(n.Params)
+			n.Results = unnamedFields(n.Results)
+		}
+		return true
+	})
+	return expr
+}
+
+// unnamedFields returns a copy of field list with one unnamed field per name.
+func unnamedFields(list *ast.FieldList) *ast.FieldList {
+	if list == nil {
+		return nil
+	}
+	fields := &ast.FieldList{}
+	for _, f := range list.List {
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			fields.List = append(fields.List, &ast.Field{Type: f.Type})
+		}
+	}
+	return fields
+}
+
+func apiExpr(expr ast.Expr) string {
+	return types.ExprString(apiNormalize(expr))
+}
+
+// apiTypeParams returns type parameter list in brackets, or empty string if there is none.
+func apiTypeParams(list *ast.FieldList) string {
+	if list == nil {
+		return ""
+	}
+	var params []string
+	for _, f := range list.List {
+		constraint := apiExpr(f.Type)
+		for _, name := range f.Names {
+			params = append(params, name.Name+" "+constraint)
+		}
+	}
+	return "[" + strings.Join(params, ", ") + "]"
+}
+
+// apiSignature returns signature of function type without the "func" keyword.
+func apiSignature(ft *ast.FuncType) string {
+	return apiTypeParams(ft.TypeParams) +
+		strings.TrimPrefix(apiExpr(&ast.FuncType{Params: ft.Params, Results: ft.Results}), "func")
+}
+
+// idealType returns type of an untyped constant expression,
+// or empty string if it cannot be determined without type checking.
+func idealType(expr ast.Expr) string {
+	switch x := expr.(type) {
+	case *ast.BasicLit:
+		switch x.Kind {
+		case token.INT:
+			return "ideal-int"
+		case token.FLOAT:
+			return "ideal-float"
+		case token.IMAG:
+			return "ideal-complex"
+		case token.CHAR:
+			return "ideal-char"
+		case token.STRING:
+			return "ideal-string"
+		}
+	case *ast.Ident:
+		switch x.Name {
+		case "iota":
+			return "ideal-int"
+		case "true", "false":
+			return "ideal-bool"
+		}
+	case *ast.ParenExpr:
+		return idealType(x.X)
+	case *ast.UnaryExpr:
+		if x.Op == token.NOT {
+			return "ideal-bool"
+		}
+		return idealType(x.X)
+	case *ast.BinaryExpr:
+		switch x.Op {
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ, token.LAND, token.LOR:
+			return "ideal-bool"
+		case token.SHL, token.SHR:
+			return idealType(x.X)
+		}
+		if typ := idealType(x.X); len(typ) > 0 {
+			return typ
+		}
+		return idealType(x.Y)
+	case *ast.CallExpr:
+		switch fun := x.Fun.(type) {
+		case *ast.Ident:
+			if fun.Name == "len" || fun.Name == "cap" {
+				return "ideal-int"
+			}
+			return apiExpr(fun)
+		case *ast.SelectorExpr:
+			// Conversions to types of other packages, e.g. time.Duration(1).
+			return apiExpr(fun)
+		}
+	}
+	return ""
+}
+
+// defaultType returns type of a variable declared without explicit type,
+// or empty string if it cannot be determined without type checking.
+func defaultType(expr ast.Expr) string {
+	switch x := expr.(type) {
+	case *ast.BasicLit:
+		return map[token.Token]string{
+			token.INT:    "int",
+			token.FLOAT:  "float64",
+			token.IMAG:   "complex128",
+			token.CHAR:   "int32",
+			token.STRING: "string",
+		}[x.Kind]
+	case *ast.CompositeLit:
+		if x.Type != nil {
+			return apiExpr(x.Type)
+		}
+	case *ast.UnaryExpr:
+		if x.Op == token.AND {
+			if typ := defaultType(x.X); len(typ) > 0 {
+				return "*" + typ
+			}
+		}
+	case *ast.CallExpr:
+		if sel, ok := x.Fun.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok &&
+				(pkg.Name == "errors" && sel.Sel.Name == "New" || pkg.Name == "fmt" && sel.Sel.Name == "Errorf") {
+				return "error"
+			}
+		}
+	}
+	return ""
+}
+
+// constValue returns value of a constant expression if it is a literal.
+func constValue(expr ast.Expr) string {
+	switch x := expr.(type) {
+	case *ast.BasicLit:
+		if x.Kind != token.CHAR {
+			return x.Value
+		}
+	case *ast.UnaryExpr:
+		if lit, ok := x.X.(*ast.BasicLit); ok && x.Op == token.SUB && lit.Kind != token.STRING {
+			return "-" + lit.Value
+		}
+	}
+	return ""
+}
+
+func (d *apiDumper) values(vals []*Value) {
+	for _, v := range vals {
+		gd, err := parseDecl(v.Decl)
+		if err != nil {
+			continue
+		}
+
+		// Constants without type and value inherit them from previous spec in the group.
+		var typ string
+		var exprs []ast.Expr
+		for _, spec := range gd.Specs {
+			vs, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			if vs.Type != nil {
+				typ = apiExpr(vs.Type)
+			}
+			if len(vs.Values) > 0 {
+				exprs = vs.Values
+				if vs.Type == nil {
+					typ = ""
+				}
+			}
+
+			for i, name := range vs.Names {
+				// Values known from type checking have been emitted.
+				if !ast.IsExported(name.Name) || d.typed[name.Name] {
+					continue
+				}
+
+				var expr ast.Expr
+				if i < len(exprs) {
+					expr = exprs[i]
+				}
+				if gd.Tok == token.VAR {
+					t := typ
+					if len(t) == 0 && expr != nil {
+						t = defaultType(expr)
+					}
+					if len(t) > 0 {
+						d.emit("var %s %s", name.Name, t)
+					}
+					continue
+				}
+
+				t := typ
+				if len(t) == 0 && expr != nil {
+					t = idealType(expr)
+				}
+				if len(t) > 0 {
+					d.emit("const %s %s", name.Name, t)
+				}
+				val, ok := d.consts[name.Name]
+				if !ok && len(vs.Values) > 0 && expr != nil {
+					val = constValue(expr)
+				}
+				if len(val) > 0 {
+					d.emit("const %s = %s", name.Name, val)
+				}
+			}
+		}
+	}
+}
+
+// idealTypes are names of untyped basic types in API files.
+var idealTypes = map[types.BasicKind]string{
+	types.UntypedBool:    "ideal-bool",
+	types.UntypedInt:     "ideal-int",
+	types.UntypedRune:    "ideal-char",
+	types.UntypedFloat:   "ideal-float",
+	types.UntypedComplex: "ideal-complex",
+	types.UntypedString:  "ideal-string",
+}
+
+// apiTypeString returns the type in the form of API files, types of other
+// packages are qualified by package names. It returns empty string if the
+// type is invalid, e.g. it depends on unknown declarations of imports.
+func apiTypeString(typ types.Type, pkg *types.Package) string {
+	if basic, ok := typ.(*types.Basic); ok {
+		if basic.Kind() == types.Invalid {
+			return ""
+		} else if name, ok := idealTypes[basic.Kind()]; ok {
+			return name
+		}
+	}
+
+	s := types.TypeString(typ, func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		return p.Name()
+	})
+	if strings.Contains(s, "invalid type") {
+		return ""
+	}
+	// Parse it again to use canonical names of basic type aliases and drop parameter names.
+	expr, err := parser.ParseExpr(s)
+	if err != nil {
+		return ""
+	}
+	return apiExpr(expr)
+}
+
+// apiValues returns exported package-level constants and variables with types
+// and values from type checking.
+func (w *Walker) apiValues() []*APIValue {
+	if w.tpkg == nil {
+		return nil
+	}
+
+	var vals []*APIValue
+	scope := w.tpkg.Scope()
+	for _, name := range scope.Names() {
+		if !ast.IsExported(name) {
+			continue
+		}
+		switch obj := scope.Lookup(name).(type) {
+		case *types.Const:
+			typ := apiTypeString(obj.Type(), w.tpkg)
+			if len(typ) == 0 || obj.Val().Kind() == constant.Unknown {
+				continue
+			}
+			vals = append(vals, &APIValue{
+				Name:  name,
+				Const: true,
+				Type:  typ,
+				Value: obj.Val().ExactString(),
+			})
+		case *types.Var:
+			if typ := apiTypeString(obj.Type(), w.tpkg); len(typ) > 0 {
+				vals = append(vals, &APIValue{Name: name, Type: typ})
+			}
+		}
+	}
+	return vals
+}
+
+// parseFuncDecl parses a function declaration printed by the walker.
+func parseFuncDecl(decl string) (*ast.FuncDecl, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+decl, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range file.Decls {
+		if fd, ok := d.(*ast.FuncDecl); ok {
+			return fd, nil
+		}
+	}
+	return nil, fmt.Errorf("no function declaration found")
+}
+
+func (d *apiDumper) funcs(funcs []*Func) {
+	for _, f := range funcs {
+		fd, err := parseFuncDecl(f.Decl)
+		if err != nil {
+			continue
+		}
+
+		if fd.Recv == nil || len(fd.Recv.List) == 0 {
+			d.emit("func %s%s", fd.Name.Name, apiSignature(fd.Type))
+			continue
+		}
+		d.emit("method (%s) %s%s", apiExpr(fd.Recv.List[0].Type), fd.Name.Name, apiSignature(fd.Type))
+	}
+}
+
+func (d *apiDumper) typeSpec(ts *ast.TypeSpec) {
+	name := ts.Name.Name + apiTypeParams(ts.TypeParams)
+	if ts.Assign.IsValid() {
+		d.emit("type %s = %s", name, apiExpr(ts.Type))
+		return
+	}
+
+	switch t := ts.Type.(type) {
+	case *ast.StructType:
+		d.emit("type %s struct", name)
+		for _, f := range t.Fields.List {
+			if len(f.Names) == 0 {
+				// Embedded field is named after its type.
+				expr := f.Type
+				if star, ok := expr.(*ast.StarExpr); ok {
+					expr = star.X
+				}
+				if sel, ok := expr.(*ast.SelectorExpr); ok {
+					expr = sel.Sel
+				}
+				if ident, ok := expr.(*ast.Ident); ok && ast.IsExported(ident.Name) {
+					d.emit("type %s struct, embedded %s", name, apiExpr(f.Type))
+				}
+				continue
+			}
+			for _, n := range f.Names {
+				if ast.IsExported(n.Name) {
+					d.emit("type %s struct, %s %s", name, n.Name, apiExpr(f.Type))
+				}
+			}
+		}
+
+	case *ast.InterfaceType:
+		var methods []string
+		unexported := false
+		for _, f := range t.Methods.List {
+			if len(f.Names) == 0 {
+				// Method sets of embedded interfaces are not known without type checking.
+				d.emit("type %s interface, embedded %s", name, apiExpr(f.Type))
+				continue
+			}
+			for _, n := range f.Names {
+				if !ast.IsExported(n.Name) {
+					unexported = true
+					continue
+				}
+				methods = append(methods, n.Name)
+				d.emit("type %s interface, %s%s", name, n.Name, apiSignature(f.Type.(*ast.FuncType)))
+			}
+		}
+		if unexported {
+			d.emit("type %s interface, unexported methods", name)
+		}
+		sort.Strings(methods)
+		if len(methods) == 0 {
+			d.emit("type %s interface {}", name)
+		} else {
+			d.emit("type %s interface { %s }", name, strings.Join(methods, ", "))
+		}
+
+	default:
+		d.emit("type %s %s", name, apiExpr(ts.Type))
+	}
+}
+
+// ExportAPI writes exported API of the package to w in the format of api/*.txt
+// files of the Go project, one line per symbol in sorted order, which can be
+// consumed by existing API compatibility tools. Packages walked without type
+// information omit types of untyped variables and values of constants that are
+// not literals. Method sets of embedded interfaces are always omitted.
+// It should be called before the package is rendered, because rendering
+// overwrites declarations with HTML.
+func ExportAPI(w io.Writer, pdoc *Package) error {
+	if pdoc.PkgDecl == nil {
+		return nil
+	}
+
+	d := &apiDumper{
+		prefix: "pkg " + pdoc.ImportPath + ", ",
+		consts: make(map[string]string),
+		typed:  make(map[string]bool),
+	}
+	for _, t := range pdoc.Types {
+		for _, v := range t.EnumValues {
+			d.consts[v.Name] = v.Value
+		}
+	}
+
+	for _, v := range pdoc.APIValues {
+		d.typed[v.Name] = true
+		if v.Const {
+			d.emit("const %s %s", v.Name, v.Type)
+			d.emit("const %s = %s", v.Name, v.Value)
+		} else {
+			d.emit("var %s %s", v.Name, v.Type)
+		}
+	}
+
+	d.values(pdoc.Consts)
+	d.values(pdoc.Vars)
+	d.funcs(pdoc.Funcs)
+	for _, t := range pdoc.Types {
+		gd, err := parseDecl(t.Decl)
+		if err != nil {
+			return fmt.Errorf("parse declaration of %q: %v", t.Name, err)
+		}
+		for _, spec := range gd.Specs {
+			if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name == t.Name {
+				d.typeSpec(ts)
+			}
+		}
+		d.values(t.Consts)
+		d.values(t.Vars)
+		d.funcs(t.Funcs)
+		d.funcs(t.Methods)
+	}
+
+	sort.Strings(d.lines)
+	for _, line := range d.lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
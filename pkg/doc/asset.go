@@ -0,0 +1,246 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	log "gopkg.in/clog.v1"
+)
+
+// AssetStore stores assets referenced by documentation, e.g. images in README.
+type AssetStore interface {
+	// Put saves the asset with given name and returns the URL to access it.
+	Put(name string, data []byte) (string, error)
+}
+
+var (
+	assetStore   AssetStore
+	maxAssetSize int64
+)
+
+// SetAssetStore sets the store to mirror images referenced by README,
+// images larger than maxSize in bytes are not mirrored.
+// Passing nil store disables mirroring.
+func SetAssetStore(store AssetStore, maxSize int64) {
+	assetStore = store
+	maxAssetSize = maxSize
+}
+
+// LocalAssetStore saves assets to a local directory that is served with URL prefix.
+type LocalAssetStore struct {
+	Dir       string
+	URLPrefix string
+}
+
+func (s LocalAssetStore) Put(name string, data []byte) (string, error) {
+	localPath := path.Join(s.Dir, name)
+	if err := os.MkdirAll(path.Dir(localPath), os.ModePerm); err != nil {
+		return "", err
+	} else if err = ioutil.WriteFile(localPath, data, 0644); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(s.URLPrefix, "/") + "/" + name, nil
+}
+
+// assetCacheTTL is how long mirrored URL of an image is reused without downloading it again.
+const assetCacheTTL = 24 * time.Hour
+
+var imgSrcPattern = regexp.MustCompile(`(<img\s[^>]*?src=")([^"]+)(")`)
+
+// maxMirroredImages is the maximum number of images to download for a README,
+// the rest of images are left as is.
+const maxMirroredImages = 20
+
+// imageTypes are content types of images to mirror. Other types, e.g. SVG which
+// can contain scripts, are not served from the origin of the site.
+var imageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// maxAssetRedirects is the maximum number of redirects to follow when downloading an image.
+const maxAssetRedirects = 3
+
+// isPublicIP returns true if ip is not a loopback, private, link-local or unspecified address.
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		// 10.0.0.0/8, 172.16.0.0/12, 192.168.0.0/16, 100.64.0.0/10 (CGNAT) and 0.0.0.0/8.
+		return ip4[0] != 10 && ip4[0] != 0 &&
+			!(ip4[0] == 172 && ip4[1]&0xf0 == 16) &&
+			!(ip4[0] == 192 && ip4[1] == 168) &&
+			!(ip4[0] == 100 && ip4[1]&0xc0 == 64)
+	}
+	// fc00::/7 unique local addresses.
+	return ip[0]&0xfe != 0xfc
+}
+
+// dialPublic resolves the host and dials only to public addresses,
+// so that URLs in READMEs cannot reach services of the internal network.
+func dialPublic(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: *dialTimeout}
+	for _, a := range addrs {
+		if isPublicIP(a.IP) {
+			return dialer.DialContext(ctx, network, net.JoinHostPort(a.IP.String(), port))
+		}
+	}
+	return nil, fmt.Errorf("no public address for %q", host)
+}
+
+// assetClient downloads images from untrusted URLs. It never sends credentials of code hosts,
+// connects only to public addresses and follows limited number of redirects.
+var assetClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext:           dialPublic,
+		ResponseHeaderTimeout: *requestTimeout / 2,
+	},
+	Timeout: *requestTimeout,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxAssetRedirects {
+			return errors.New("too many redirects")
+		} else if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("unsupported scheme: %s", req.URL.Scheme)
+		}
+		return nil
+	},
+}
+
+// fetchAsset downloads the image with size limit.
+func fetchAsset(rawURL string) ([]byte, string, error) {
+	resp, err := assetClient.Get(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	} else if resp.ContentLength > maxAssetSize {
+		return nil, "", fmt.Errorf("too large: %d", resp.ContentLength)
+	}
+
+	contentType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !imageTypes[contentType] {
+		return nil, "", fmt.Errorf("unsupported content type: %s", resp.Header.Get("Content-Type"))
+	}
+
+	// Content-Length is not always presented, so check the size again.
+	data, err := ioutil.ReadAll(&io.LimitedReader{R: resp.Body, N: maxAssetSize + 1})
+	if err != nil {
+		return nil, "", err
+	} else if int64(len(data)) > maxAssetSize {
+		return nil, "", fmt.Errorf("too large: more than %d", maxAssetSize)
+	}
+	return data, contentType, nil
+}
+
+// readmeBaseURL returns the URL that relative links in README are relative to.
+func readmeBaseURL(pdoc *Package) string {
+	if !strings.HasPrefix(pdoc.ProjectPath, "github.com/") || pdoc.Provenance == nil {
+		return ""
+	}
+	return fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s/",
+		strings.TrimPrefix(pdoc.ProjectPath, "github.com/"), pdoc.Provenance.Ref,
+		strings.TrimPrefix(strings.TrimPrefix(pdoc.ImportPath, pdoc.ProjectPath), "/"))
+}
+
+// mirrorImages downloads images referenced by the rendered README to the asset store,
+// and rewrites the image URLs to the mirrored ones. Images failed to mirror and images
+// beyond maxMirroredImages are left as is.
+func mirrorImages(pdoc *Package, data []byte) []byte {
+	base, _ := url.Parse(readmeBaseURL(pdoc))
+	fetched := 0
+	return imgSrcPattern.ReplaceAllFunc(data, func(m []byte) []byte {
+		parts := imgSrcPattern.FindSubmatch(m)
+		ref, err := url.Parse(html.UnescapeString(string(parts[2])))
+		if err != nil {
+			return m
+		}
+		if !ref.IsAbs() {
+			if base == nil || len(base.Host) == 0 {
+				return m
+			}
+			ref = base.ResolveReference(ref)
+		}
+		if ref.Scheme != "http" && ref.Scheme != "https" {
+			return m
+		}
+
+		cacheKey := "asset:" + pdoc.ImportPath + ":" + ref.String()
+		if sharedCache != nil {
+			if assetURL, err := sharedCache.Get(cacheKey); err == nil {
+				return []byte(string(parts[1]) + html.EscapeString(string(assetURL)) + string(parts[3]))
+			}
+		}
+
+		if fetched >= maxMirroredImages {
+			return m
+		}
+		fetched++
+
+		asset, contentType, err := fetchAsset(ref.String())
+		if err != nil {
+			log.Trace("Skip mirroring image %q: %v", ref, err)
+			return m
+		}
+
+		sum := sha1.Sum([]byte(ref.String()))
+		name := hex.EncodeToString(sum[:])
+		if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+			name += exts[0]
+		}
+		assetURL, err := assetStore.Put(path.Join(pdoc.ImportPath, name), asset)
+		if err != nil {
+			log.Error(2, "Failed to put asset %q: %v", ref, err)
+			return m
+		}
+		if sharedCache != nil {
+			if err = sharedCache.Set(cacheKey, []byte(assetURL), assetCacheTTL); err != nil {
+				log.Error(2, "Failed to cache asset URL %q: %v", ref, err)
+			}
+		}
+		return []byte(string(parts[1]) + html.EscapeString(assetURL) + string(parts[3]))
+	})
+}
@@ -0,0 +1,184 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"strings"
+)
+
+// BazelTarget is a Go rule declared in the BUILD file of the package directory.
+type BazelTarget struct {
+	Kind       string // go_library, go_test or go_binary.
+	Name       string
+	Label      string // e.g. "//pkg/foo:foo", or relative one e.g. ":foo" if the workspace is unknown.
+	ImportPath string // Attribute importpath of the rule.
+	Srcs       []string
+	Deps       []string
+	Visibility []string // Visibility of the rule, or default visibility of the BUILD file.
+}
+
+// bazelGoRules is the set of rules that are recorded as targets.
+var bazelGoRules = map[string]bool{
+	"go_library": true,
+	"go_test":    true,
+	"go_binary":  true,
+}
+
+// bazelToken is a token of BUILD files, which are in Starlark. Kind is one of
+// 'i' for identifiers, 's' for strings and the punctuation itself.
+type bazelToken struct {
+	kind byte
+	text string
+}
+
+// bazelTokens splits the BUILD file into tokens, numbers and operators other than
+// the ones used by calls and lists are dropped.
+func bazelTokens(data []byte) []bazelToken {
+	s := string(data)
+	var toks []bazelToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '#':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			quote := s[i : i+1]
+			if strings.HasPrefix(s[i:], strings.Repeat(quote, 3)) {
+				quote = s[i : i+3]
+			}
+			j := i + len(quote)
+			var b strings.Builder
+			for j < len(s) && !strings.HasPrefix(s[j:], quote) {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+				j++
+			}
+			toks = append(toks, bazelToken{'s', b.String()})
+			i = j + len(quote)
+		case c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+			j := i
+			for j < len(s) && (s[j] == '_' || 'a' <= s[j] && s[j] <= 'z' || 'A' <= s[j] && s[j] <= 'Z' || '0' <= s[j] && s[j] <= '9') {
+				j++
+			}
+			toks = append(toks, bazelToken{'i', s[i:j]})
+			i = j
+		case strings.IndexByte("()[]{},=", c) >= 0:
+			toks = append(toks, bazelToken{c, s[i : i+1]})
+			i++
+		default:
+			i++
+		}
+	}
+	return toks
+}
+
+// bazelArgs returns arguments of the call whose opening parenthesis is at toks[i],
+// keyword arguments that are lists of strings or strings are returned, and the index
+// after the closing parenthesis.
+func bazelArgs(toks []bazelToken, i int) (map[string][]string, int) {
+	args := make(map[string][]string)
+	depth := 0
+	key := ""
+	for ; i < len(toks); i++ {
+		t := toks[i]
+		switch t.kind {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth--; depth == 0 {
+				return args, i + 1
+			}
+		case ',':
+			if depth == 1 {
+				key = ""
+			}
+		case 'i':
+			if depth == 1 && i+1 < len(toks) && toks[i+1].kind == '=' {
+				key = t.text
+				args[key] = nil
+				i++
+			} else if len(key) > 0 && i+1 < len(toks) && toks[i+1].kind == '(' {
+				// Values of calls, e.g. glob and select, are not known.
+				_, i = bazelArgs(toks, i+1)
+				i--
+			}
+		case 's':
+			if len(key) > 0 && depth <= 2 {
+				args[key] = append(args[key], t.text)
+			}
+		}
+	}
+	return args, i
+}
+
+// parseBazelBuild returns Go targets declared in the BUILD file, pkg is the
+// package of the BUILD file in the workspace, e.g. "pkg/foo", or "." if unknown.
+func parseBazelBuild(data []byte, pkg string) []*BazelTarget {
+	toks := bazelTokens(data)
+
+	var targets []*BazelTarget
+	var defaultVisibility []string
+	for i := 0; i < len(toks); i++ {
+		// Only top-level calls are rules.
+		if toks[i].kind != 'i' || i+1 >= len(toks) || toks[i+1].kind != '(' {
+			continue
+		}
+		kind := toks[i].text
+		args, end := bazelArgs(toks, i+1)
+		i = end - 1
+
+		switch {
+		case kind == "package":
+			defaultVisibility = args["default_visibility"]
+		case bazelGoRules[kind] && len(args["name"]) == 1:
+			t := &BazelTarget{
+				Kind:       kind,
+				Name:       args["name"][0],
+				Srcs:       args["srcs"],
+				Deps:       args["deps"],
+				Visibility: args["visibility"],
+			}
+			if len(args["importpath"]) == 1 {
+				t.ImportPath = args["importpath"][0]
+			}
+			if pkg == "." {
+				t.Label = ":" + t.Name
+			} else {
+				t.Label = "//" + pkg + ":" + t.Name
+			}
+			targets = append(targets, t)
+		}
+	}
+
+	for _, t := range targets {
+		if t.Visibility == nil {
+			t.Visibility = defaultVisibility
+		}
+	}
+	return targets
+}
+
+// bazelPackage returns package of the BUILD file in the workspace, assuming the
+// workspace is at root of the project, or "." if the project path is unknown.
+func bazelPackage(importPath, projectPath string) string {
+	if len(projectPath) == 0 || !strings.HasPrefix(importPath, projectPath) {
+		return "."
+	}
+	return strings.Trim(importPath[len(projectPath):], "/")
+}
@@ -0,0 +1,159 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBazelTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []bazelToken
+	}{
+		{
+			name: "call",
+			src:  `go_library(name = "foo", srcs = ["a.go"])`,
+			want: []bazelToken{
+				{'i', "go_library"}, {'(', "("}, {'i', "name"}, {'=', "="}, {'s', "foo"}, {',', ","},
+				{'i', "srcs"}, {'=', "="}, {'[', "["}, {'s', "a.go"}, {']', "]"}, {')', ")"},
+			},
+		},
+		{
+			name: "comments and operators",
+			src:  "# go_library(\nx = 1 + y2 # z\n",
+			want: []bazelToken{{'i', "x"}, {'=', "="}, {'i', "y2"}},
+		},
+		{
+			name: "strings",
+			src:  `'a' "b\"c" """d"e""" '''f'''`,
+			want: []bazelToken{{'s', "a"}, {'s', `b"c`}, {'s', `d"e`}, {'s', "f"}},
+		},
+		{
+			name: "unterminated string",
+			src:  `x = "abc`,
+			want: []bazelToken{{'i', "x"}, {'=', "="}, {'s', "abc"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := bazelTokens([]byte(test.src)); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseBazelBuild(t *testing.T) {
+	build := `load("@io_bazel_rules_go//go:def.bzl", "go_library", "go_test")
+
+package(default_visibility = ["//visibility:public"])
+
+go_library(
+    name = "foo",
+    srcs = glob(["*.go"], exclude = ["*_test.go"]) + ["extra.go"],
+    importpath = "example.com/foo",
+    deps = [
+        "//bar",
+        "@org_golang_x_text//:text",
+    ],
+)
+
+go_test(
+    name = "foo_test",
+    srcs = ["foo_test.go"],
+    embed = [":foo"],
+    visibility = ["//visibility:private"],
+)
+
+cc_library(name = "c")
+
+go_binary(name = select({"a": "x", "b": "y"}))
+`
+	tests := []struct {
+		pkg  string
+		want []*BazelTarget
+	}{
+		{
+			pkg: "pkg/foo",
+			want: []*BazelTarget{
+				{
+					Kind:       "go_library",
+					Name:       "foo",
+					Label:      "//pkg/foo:foo",
+					ImportPath: "example.com/foo",
+					Srcs:       []string{"extra.go"},
+					Deps:       []string{"//bar", "@org_golang_x_text//:text"},
+					Visibility: []string{"//visibility:public"},
+				},
+				{
+					Kind:       "go_test",
+					Name:       "foo_test",
+					Label:      "//pkg/foo:foo_test",
+					Srcs:       []string{"foo_test.go"},
+					Visibility: []string{"//visibility:private"},
+				},
+			},
+		},
+		{
+			pkg: ".",
+			want: []*BazelTarget{
+				{
+					Kind:       "go_library",
+					Name:       "foo",
+					Label:      ":foo",
+					ImportPath: "example.com/foo",
+					Srcs:       []string{"extra.go"},
+					Deps:       []string{"//bar", "@org_golang_x_text//:text"},
+					Visibility: []string{"//visibility:public"},
+				},
+				{
+					Kind:       "go_test",
+					Name:       "foo_test",
+					Label:      ":foo_test",
+					Srcs:       []string{"foo_test.go"},
+					Visibility: []string{"//visibility:private"},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.pkg, func(t *testing.T) {
+			got := parseBazelBuild([]byte(build), test.pkg)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBazelPackage(t *testing.T) {
+	tests := []struct {
+		importPath, projectPath string
+		want                    string
+	}{
+		{"github.com/a/b/pkg/foo", "github.com/a/b", "pkg/foo"},
+		{"github.com/a/b", "github.com/a/b", ""},
+		{"github.com/a/b", "", "."},
+		{"github.com/c/d", "github.com/a/b", "."},
+	}
+	for _, test := range tests {
+		if got := bazelPackage(test.importPath, test.projectPath); got != test.want {
+			t.Errorf("bazelPackage(%q, %q) = %q, want %q", test.importPath, test.projectPath, got, test.want)
+		}
+	}
+}
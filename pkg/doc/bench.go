@@ -0,0 +1,180 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Benchmark is a benchmark function in test files of the package.
+type Benchmark struct {
+	Name string // e.g. "BenchmarkEncode".
+	Doc  string
+	Span
+
+	Results []*BenchResult // Set by ApplyBenchmarks.
+}
+
+// BenchResult is a result line of "go test -bench" output.
+type BenchResult struct {
+	Name   string // Full name without the GOMAXPROCS suffix, e.g. "BenchmarkEncode/small".
+	Procs  int    // GOMAXPROCS, 1 if the suffix is absent.
+	GOOS   string
+	GOARCH string
+	CPU    string
+	Pkg    string // Import path of the package, from the configuration line "pkg: ...".
+
+	N           int // Iterations.
+	NsPerOp     float64
+	BytesPerOp  float64 // -1 if not reported.
+	AllocsPerOp float64 // -1 if not reported.
+	MBPerSec    float64 // -1 if not reported.
+}
+
+// Platform returns the platform of the result, e.g. "linux/amd64".
+func (r *BenchResult) Platform() string {
+	if len(r.GOOS) == 0 && len(r.GOARCH) == 0 {
+		return ""
+	}
+	return r.GOOS + "/" + r.GOARCH
+}
+
+// isBenchmark reports whether the name is a benchmark function name,
+// using the same rule as the go command.
+func isBenchmark(name string) bool {
+	if !strings.HasPrefix(name, "Benchmark") {
+		return false
+	}
+	if len(name) == len("Benchmark") {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(name[len("Benchmark"):])
+	return !unicode.IsLower(r)
+}
+
+// benchmarks returns benchmark functions declared in the test file.
+func (w *Walker) benchmarks(file *ast.File) []*Benchmark {
+	var list []*Benchmark
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !isBenchmark(fn.Name.Name) ||
+			fn.Type.Params == nil || len(fn.Type.Params.List) != 1 {
+			continue
+		}
+		list = append(list, &Benchmark{
+			Name: fn.Name.Name,
+			Doc:  fn.Doc.Text(),
+			Span: w.span(fn.Pos(), fn.End()),
+		})
+	}
+	return list
+}
+
+// ParseBenchmarks parses output of "go test -bench" or files in the benchmark
+// data format. Configuration lines such as "goos: linux" apply to results after them.
+func ParseBenchmarks(r io.Reader) ([]*BenchResult, error) {
+	var results []*BenchResult
+	config := make(map[string]string)
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		line := s.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if i := strings.Index(line, ":"); i > 0 && !strings.HasPrefix(line, "Benchmark") {
+			key := line[:i]
+			if strings.IndexFunc(key, unicode.IsSpace) == -1 && strings.ToLower(key[:1]) == key[:1] {
+				config[key] = strings.TrimSpace(line[i+1:])
+			}
+			continue
+		}
+
+		// Format: BenchmarkName-8  iterations  value unit  value unit...
+		if !isBenchmark(fields[0]) || len(fields) < 4 || len(fields)%2 != 0 {
+			continue
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		res := &BenchResult{
+			Name:        fields[0],
+			Procs:       1,
+			GOOS:        config["goos"],
+			GOARCH:      config["goarch"],
+			CPU:         config["cpu"],
+			Pkg:         config["pkg"],
+			N:           n,
+			BytesPerOp:  -1,
+			AllocsPerOp: -1,
+			MBPerSec:    -1,
+		}
+		if i := strings.LastIndex(res.Name, "-"); i > 0 {
+			if procs, err := strconv.Atoi(res.Name[i+1:]); err == nil {
+				res.Name, res.Procs = res.Name[:i], procs
+			}
+		}
+		for i := 2; i < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad value %q of %s", fields[i], res.Name)
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				res.NsPerOp = v
+			case "B/op":
+				res.BytesPerOp = v
+			case "allocs/op":
+				res.AllocsPerOp = v
+			case "MB/s":
+				res.MBPerSec = v
+			}
+		}
+		results = append(results, res)
+	}
+	return results, s.Err()
+}
+
+// ApplyBenchmarks attaches results to benchmarks of the package by names of
+// top-level benchmarks, results of other packages are ignored.
+func ApplyBenchmarks(pdoc *Package, results []*BenchResult) {
+	benchs := make(map[string]*Benchmark, len(pdoc.Benchmarks))
+	for _, b := range pdoc.Benchmarks {
+		b.Results = nil
+		benchs[b.Name] = b
+	}
+	for _, r := range results {
+		if len(r.Pkg) > 0 && r.Pkg != pdoc.ImportPath {
+			continue
+		}
+		name := r.Name
+		if i := strings.Index(name, "/"); i > 0 {
+			name = name[:i]
+		}
+		if b := benchs[name]; b != nil {
+			b.Results = append(b.Results, r)
+		}
+	}
+}
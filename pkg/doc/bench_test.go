@@ -0,0 +1,99 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsBenchmark(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"Benchmark", true},
+		{"BenchmarkEncode", true},
+		{"Benchmark_encode", true},
+		{"BenchmarkÉcrire", true},
+		{"Benchmarker", false},
+		{"TestEncode", false},
+	}
+	for _, test := range tests {
+		if got := isBenchmark(test.name); got != test.want {
+			t.Errorf("isBenchmark(%q) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseBenchmarks(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []*BenchResult
+	}{
+		{
+			name: "go test output",
+			output: `goos: linux
+goarch: amd64
+pkg: a/b
+cpu: Intel(R) Xeon(R) CPU @ 2.20GHz
+BenchmarkEncode/small-8         	 1000000	      1052 ns/op	  97.31 MB/s	     256 B/op	       3 allocs/op
+BenchmarkDecode   	     500	   2400000 ns/op
+--- FAIL: BenchmarkBroken
+PASS
+ok  	a/b	3.210s
+`,
+			want: []*BenchResult{
+				{Name: "BenchmarkEncode/small", Procs: 8, GOOS: "linux", GOARCH: "amd64", CPU: "Intel(R) Xeon(R) CPU @ 2.20GHz",
+					Pkg: "a/b", N: 1000000, NsPerOp: 1052, BytesPerOp: 256, AllocsPerOp: 3, MBPerSec: 97.31},
+				{Name: "BenchmarkDecode", Procs: 1, GOOS: "linux", GOARCH: "amd64", CPU: "Intel(R) Xeon(R) CPU @ 2.20GHz",
+					Pkg: "a/b", N: 500, NsPerOp: 2400000, BytesPerOp: -1, AllocsPerOp: -1, MBPerSec: -1},
+			},
+		},
+		{
+			name: "configuration changes",
+			output: `pkg: a/b
+BenchmarkA-4 10 1.5 ns/op
+pkg: a/c
+BenchmarkB-name 10 2 ns/op 1 custom/op
+`,
+			want: []*BenchResult{
+				{Name: "BenchmarkA", Procs: 4, Pkg: "a/b", N: 10, NsPerOp: 1.5, BytesPerOp: -1, AllocsPerOp: -1, MBPerSec: -1},
+				{Name: "BenchmarkB-name", Procs: 1, Pkg: "a/c", N: 10, NsPerOp: 2, BytesPerOp: -1, AllocsPerOp: -1, MBPerSec: -1},
+			},
+		},
+		{
+			name:   "not results",
+			output: "Benchmarker 10 1 ns/op\nBenchmarkA 10\nBenchmarkA ten 1 ns/op\nBenchmarkA 10 1 ns/op extra\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseBenchmarks(strings.NewReader(test.output))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := ParseBenchmarks(strings.NewReader("BenchmarkA 10 fast ns/op\n")); err == nil {
+		t.Fatal("got no error for bad value")
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Unknwon/com"
+)
+
+// Revision represents the commit that last modified a declaration.
+type Revision struct {
+	Commit string
+	Author string
+	Email  string
+	Time   time.Time
+}
+
+// funcEnds returns end positions of function declarations by their start positions,
+// because function bodies are removed when documentation is computed.
+func funcEnds(files map[string]*ast.File) map[token.Pos]token.Pos {
+	ends := make(map[token.Pos]token.Pos)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok {
+				ends[fd.Pos()] = fd.End()
+			}
+		}
+	}
+	return ends
+}
+
+// isBlameHeader returns true if the line is the header line of a blame entry,
+// which is in the form of "<sha1> <orig line> <final line> [<group lines>]".
+func isBlameHeader(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || len(fields[0]) != 40 {
+		return false
+	}
+	for _, c := range fields[0] {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// blameFile returns revisions of each line of the file by line number,
+// the first element is always nil because line number starts from 1.
+func blameFile(dir, name string) ([]*Revision, error) {
+	stdout, err := gitBlame(dir, name)
+	if err != nil {
+		return nil, fmt.Errorf("git blame %q: %v", name, err)
+	}
+
+	revs := []*Revision{nil}
+	commits := make(map[string]*Revision)
+	var cur *Revision
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			// Content of the line ends the entry.
+			revs = append(revs, cur)
+		case isBlameHeader(line):
+			sha := line[:40]
+			if commits[sha] == nil {
+				commits[sha] = &Revision{Commit: sha}
+			}
+			cur = commits[sha]
+		case cur == nil:
+			continue
+		case strings.HasPrefix(line, "author "):
+			cur.Author = line[len("author "):]
+		case strings.HasPrefix(line, "author-mail "):
+			cur.Email = strings.Trim(line[len("author-mail "):], "<>")
+		case strings.HasPrefix(line, "author-time "):
+			cur.Time = time.Unix(com.StrTo(line[len("author-time "):]).MustInt64(), 0).UTC()
+		}
+	}
+	return revs, scanner.Err()
+}
+
+// lastModified returns the latest revision within the line span.
+func lastModified(revs []*Revision, line, endLine int) *Revision {
+	var last *Revision
+	for i := line; i <= endLine && i < len(revs); i++ {
+		if revs[i] != nil && (last == nil || revs[i].Time.After(last.Time)) {
+			last = revs[i]
+		}
+	}
+	return last
+}
+
+// allFuncs returns all functions and methods of the package.
+func allFuncs(pdoc *Package) []*Func {
+	funcs := append(append([]*Func{}, pdoc.Funcs...), pdoc.Ifuncs...)
+	for _, t := range append(append([]*Type{}, pdoc.Types...), pdoc.Itypes...) {
+		funcs = append(funcs, t.Funcs...)
+		funcs = append(funcs, t.IFuncs...)
+		funcs = append(funcs, t.Methods...)
+		funcs = append(funcs, t.IMethods...)
+	}
+	return funcs
+}
+
+// Blame sets last modified revisions of functions and methods of the package
+// by running "git blame" on source files in dir, which must be in a git checkout.
+func Blame(pdoc *Package, dir string) error {
+	if pdoc.PkgDecl == nil {
+		return nil
+	}
+
+	files := make(map[string][]*Revision)
+	for _, f := range allFuncs(pdoc) {
+		if len(f.Filename) == 0 {
+			continue
+		}
+
+		revs, ok := files[f.Filename]
+		if !ok {
+			var err error
+			revs, err = blameFile(dir, path.Base(f.Filename))
+			if err != nil {
+				return err
+			}
+			files[f.Filename] = revs
+		}
+		f.LastModified = lastModified(revs, f.Line, f.EndLine)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/Unknwon/com"
+	log "gopkg.in/clog.v1"
+)
+
+var ErrBlobNotFound = errors.New("blob does not found")
+
+// BlobStore stores contents of source files by their hashes, so files shared
+// by versions and forks of a repository are only stored and downloaded once.
+type BlobStore interface {
+	// Get returns ErrBlobNotFound if the blob does not exist.
+	Get(hash string) ([]byte, error)
+	// Put is a no-op if the blob already exists.
+	Put(hash string, data []byte) error
+}
+
+var blobStore BlobStore
+
+// SetBlobStore sets the store to keep contents of fetched source files.
+// Passing nil store disables it.
+func SetBlobStore(store BlobStore) {
+	blobStore = store
+}
+
+// BlobHash returns the Git blob ID of data, which is the same as hashes of
+// files in Git trees, thus blobs can be looked up before downloading files.
+func BlobHash(data []byte) string {
+	h := sha1.New()
+	h.Write([]byte("blob " + strconv.Itoa(len(data)) + "\x00"))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// FileBlobStore saves blobs in a local directory, sharded by first two characters of hashes.
+type FileBlobStore struct {
+	Dir string
+}
+
+func (s FileBlobStore) filename(hash string) string {
+	if len(hash) < 3 {
+		return path.Join(s.Dir, hash)
+	}
+	return path.Join(s.Dir, hash[:2], hash[2:])
+}
+
+func (s FileBlobStore) Get(hash string) ([]byte, error) {
+	data, err := ioutil.ReadFile(s.filename(hash))
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	}
+	return data, err
+}
+
+func (s FileBlobStore) Put(hash string, data []byte) error {
+	filename := s.filename(hash)
+	if com.IsFile(filename) {
+		return nil
+	}
+	if err := os.MkdirAll(path.Dir(filename), os.ModePerm); err != nil {
+		return err
+	}
+
+	// Same blob may be saved concurrently, write to a unique temporary file
+	// and rename it, so readers never see partial files.
+	f, err := ioutil.TempFile(path.Dir(filename), ".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// fetchFiles reads files that have known hashes from the blob store and downloads
+// the others, downloaded files are saved to the blob store.
+func fetchFiles(client *http.Client, files []com.RawFile, header http.Header) error {
+	if blobStore == nil {
+		return com.FetchFiles(client, files, header)
+	}
+
+	missing := make([]com.RawFile, 0, len(files))
+	for _, f := range files {
+		src, ok := f.(*Source)
+		if !ok || len(src.Hash) == 0 {
+			missing = append(missing, f)
+			continue
+		}
+
+		data, err := blobStore.Get(src.Hash)
+		if err != nil {
+			if err != ErrBlobNotFound {
+				log.Warn("Failed to get blob %s: %v", src.Hash, err)
+			}
+			missing = append(missing, f)
+			continue
+		}
+		src.SetData(data)
+	}
+	if len(missing) == 0 {
+		return nil
+	} else if err := com.FetchFiles(client, missing, header); err != nil {
+		return err
+	}
+
+	for _, f := range missing {
+		hash := BlobHash(f.Data())
+		if src, ok := f.(*Source); ok {
+			src.Hash = hash
+		}
+		if err := blobStore.Put(hash, f.Data()); err != nil {
+			log.Warn("Failed to put blob %s: %v", hash, err)
+		}
+	}
+	return nil
+}
+
+// readBlobs loads data of sources that only have hashes from the blob store.
+func readBlobs(srcs []*Source) error {
+	if blobStore == nil {
+		return nil
+	}
+
+	for _, src := range srcs {
+		if len(src.SrcData) > 0 || len(src.Hash) == 0 {
+			continue
+		}
+
+		data, err := blobStore.Get(src.Hash)
+		if err != nil {
+			return fmt.Errorf("read %s: %v", src.SrcName, err)
+		}
+		src.SetData(data)
+	}
+	return nil
+}
+
+// BlobDocStore stores contents of source files of documentation in a blob store,
+// and only keeps their hashes in the underlying store.
+type BlobDocStore struct {
+	DocStore
+	Blobs BlobStore
+}
+
+// stripSources saves contents of sources to the blob store and returns copies of them without data.
+func (s BlobDocStore) stripSources(srcs []*Source) ([]*Source, error) {
+	stripped := make([]*Source, len(srcs))
+	for i, src := range srcs {
+		cp := *src
+		cp.Hash = BlobHash(cp.SrcData)
+		if err := s.Blobs.Put(cp.Hash, cp.SrcData); err != nil {
+			return nil, fmt.Errorf("put blob of %s: %v", cp.SrcName, err)
+		}
+		cp.SrcData = nil
+		stripped[i] = &cp
+	}
+	return stripped, nil
+}
+
+func (s BlobDocStore) Put(pdoc *Package) error {
+	if pdoc.PkgDecl == nil {
+		return s.DocStore.Put(pdoc)
+	}
+
+	// Copy the package to keep sources of the given one intact.
+	cp, decl := *pdoc, *pdoc.PkgDecl
+	cp.PkgDecl = &decl
+	var err error
+	if decl.Files, err = s.stripSources(decl.Files); err != nil {
+		return err
+	} else if decl.TestFiles, err = s.stripSources(decl.TestFiles); err != nil {
+		return err
+	}
+	return s.DocStore.Put(&cp)
+}
+
+func (s BlobDocStore) Get(importPath, version string) (*Package, error) {
+	pdoc, err := s.DocStore.Get(importPath, version)
+	if err != nil || pdoc.PkgDecl == nil {
+		return pdoc, err
+	}
+
+	for _, src := range append(pdoc.Files, pdoc.TestFiles...) {
+		if len(src.SrcData) > 0 || len(src.Hash) == 0 {
+			continue
+		}
+		if src.SrcData, err = s.Blobs.Get(src.Hash); err != nil {
+			return nil, fmt.Errorf("get blob of %s: %v", src.SrcName, err)
+		}
+	}
+	return pdoc, nil
+}
+
+func (s BlobDocStore) Versions(importPath string) ([]string, error) {
+	return storedVersions(s.DocStore, importPath)
+}
+
+// Sweep calls Sweep of the underlying store if it is a Sweeper.
+func (s BlobDocStore) Sweep() error {
+	if sweeper, ok := s.DocStore.(Sweeper); ok {
+		return sweeper.Sweep()
+	}
+	return nil
+}
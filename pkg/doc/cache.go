@@ -0,0 +1,38 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrCacheMiss = errors.New("cache miss")
+
+// Cache stores short-lived data that can be shared between servers.
+type Cache interface {
+	// Get returns ErrCacheMiss if the key does not exist or is expired.
+	Get(key string) ([]byte, error)
+	// Set saves data with given key, zero ttl means it never expires.
+	Set(key string, data []byte, ttl time.Duration) error
+	Delete(key string) error
+}
+
+var sharedCache Cache
+
+// SetCache sets the cache shared between servers, passing nil disables caching.
+func SetCache(c Cache) {
+	sharedCache = c
+}
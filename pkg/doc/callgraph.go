@@ -0,0 +1,184 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+// FuncRef refers to a function or method documented in the same package.
+type FuncRef struct {
+	Name   string // e.g. "New" or "Client.Do".
+	Anchor string // e.g. "New" or "Client_Do".
+}
+
+// defaultWalkMode returns walk mode of fetched packages based on settings.
+func defaultWalkMode() WalkMode {
+	if setting.EnableCallGraph {
+		return WM_All | WM_CallGraph
+	}
+	return WM_All
+}
+
+// funcRef returns reference of the function object, it returns nil
+// if the function or its receiver type is not exported.
+func funcRef(obj *types.Func) *FuncRef {
+	if !obj.Exported() {
+		return nil
+	}
+
+	recv := obj.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return &FuncRef{obj.Name(), obj.Name()}
+	}
+
+	typ := recv.Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok || !named.Obj().Exported() {
+		return nil
+	}
+	// Methods of interfaces are not documented separately.
+	if _, ok = named.Underlying().(*types.Interface); ok {
+		return nil
+	}
+	return &FuncRef{named.Obj().Name() + "." + obj.Name(), named.Obj().Name() + "_" + obj.Name()}
+}
+
+// staticCalls returns functions of the package that are called directly
+// by each function declaration.
+func (w *Walker) staticCalls(files map[string]*ast.File) map[*types.Func][]*types.Func {
+	if w.info == nil || w.tpkg == nil {
+		return nil
+	}
+
+	calls := make(map[*types.Func][]*types.Func)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fdecl, ok := decl.(*ast.FuncDecl)
+			if !ok || fdecl.Body == nil {
+				continue
+			}
+			caller, ok := w.info.Defs[fdecl.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+
+			seen := make(map[*types.Func]bool)
+			ast.Inspect(fdecl.Body, func(node ast.Node) bool {
+				call, ok := node.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+
+				fun := call.Fun
+				for {
+					paren, ok := fun.(*ast.ParenExpr)
+					if !ok {
+						break
+					}
+					fun = paren.X
+				}
+
+				var ident *ast.Ident
+				switch fun := fun.(type) {
+				case *ast.Ident:
+					ident = fun
+				case *ast.SelectorExpr:
+					ident = fun.Sel
+				default:
+					return true
+				}
+				callee, ok := w.info.Uses[ident].(*types.Func)
+				if ok && callee.Pkg() == w.tpkg && !seen[callee] {
+					seen[callee] = true
+					calls[caller] = append(calls[caller], callee)
+				}
+				return true
+			})
+		}
+	}
+	return calls
+}
+
+func sortFuncRefs(refs []*FuncRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Name < refs[j].Name
+	})
+}
+
+// setCallGraph sets Calls and CalledBy of exported functions and methods.
+// Calls through unexported functions are attributed to the exported caller,
+// calls through interfaces or function values are not tracked.
+func (w *Walker) setCallGraph() {
+	if w.calls == nil {
+		return
+	}
+
+	funcs := make(map[string]*Func)
+	for _, f := range w.Pdoc.Funcs {
+		funcs[f.Name] = f
+	}
+	for _, t := range w.Pdoc.Types {
+		for _, f := range t.Funcs {
+			funcs[f.Name] = f
+		}
+		for _, m := range t.Methods {
+			funcs[t.Name+"."+m.Name] = m
+		}
+	}
+
+	calls := w.calls
+	for caller := range calls {
+		ref := funcRef(caller)
+		if ref == nil || funcs[ref.Name] == nil {
+			continue
+		}
+
+		f := funcs[ref.Name]
+		visited := map[*types.Func]bool{caller: true}
+		var walk func(*types.Func)
+		walk = func(fn *types.Func) {
+			for _, callee := range calls[fn] {
+				if visited[callee] {
+					continue
+				}
+				visited[callee] = true
+
+				calleeRef := funcRef(callee)
+				if calleeRef == nil {
+					walk(callee)
+					continue
+				}
+				if g := funcs[calleeRef.Name]; g != nil {
+					f.Calls = append(f.Calls, calleeRef)
+					g.CalledBy = append(g.CalledBy, ref)
+				}
+			}
+		}
+		walk(caller)
+	}
+
+	for _, f := range funcs {
+		sortFuncRefs(f.Calls)
+		sortFuncRefs(f.CalledBy)
+	}
+}
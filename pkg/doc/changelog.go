@@ -0,0 +1,219 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Unknwon/com"
+)
+
+// Release represents changes of a version.
+type Release struct {
+	Version string
+	Date    string // In the form of "2006-01-02" if known.
+	Notes   string // In Markdown format.
+}
+
+var (
+	releaseVersionPattern = regexp.MustCompile(`\bv?[0-9]+\.[0-9]+(?:\.[0-9]+)?(?:-[0-9A-Za-z.]+)?\b`)
+	releaseDatePattern    = regexp.MustCompile(`[0-9]{4}-[0-9]{2}-[0-9]{2}`)
+	setextPattern         = regexp.MustCompile(`^(=+|-+)\s*$`)
+)
+
+// parseReleaseHeading returns version and date in the heading,
+// it returns empty version if the heading is not for a release.
+func parseReleaseHeading(heading string) (version, date string) {
+	if strings.Contains(strings.ToLower(heading), "unreleased") {
+		return "Unreleased", ""
+	}
+	// Date must be removed first, otherwise its parts could be recognized as a version.
+	date = releaseDatePattern.FindString(heading)
+	heading = releaseDatePattern.ReplaceAllString(heading, "")
+	return releaseVersionPattern.FindString(heading), date
+}
+
+// fenceMarker returns the opening marker of fenced code block, e.g. "```" or "~~~~",
+// it returns empty string if the line does not open a fenced code block.
+func fenceMarker(line string) string {
+	line = strings.TrimSpace(line)
+	for _, c := range []string{"`", "~"} {
+		if n := len(line) - len(strings.TrimLeft(line, c)); n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// parseChangelog parses sections of each version from the changelog
+// in Markdown or plain text format, a section starts with a heading that
+// contains a version number, e.g. "## [1.2.0] - 2018-01-02" or "v1.2.0 (2018-01-02)\n====".
+func parseChangelog(data []byte) []*Release {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), " \t\r"))
+	}
+
+	var releases []*Release
+	var cur *Release
+	var notes []string
+	level := 0
+	flush := func() {
+		if cur != nil {
+			cur.Notes = strings.TrimSpace(strings.Join(notes, "\n"))
+			releases = append(releases, cur)
+		}
+		cur, notes = nil, nil
+	}
+
+	fence := ""
+	for i := 0; i < len(lines); i++ {
+		start, line := i, lines[i]
+
+		// Lines in fenced code blocks are never headings.
+		if marker := fenceMarker(line); len(fence) > 0 || len(marker) > 0 {
+			if len(fence) == 0 {
+				fence = marker
+			} else if strings.HasPrefix(strings.TrimSpace(line), fence) &&
+				len(strings.Trim(strings.TrimSpace(line), fence[:1])) == 0 {
+				fence = ""
+			}
+			if cur != nil {
+				notes = append(notes, line)
+			}
+			continue
+		}
+
+		// Get heading and its level, setext headings are treated as level 1 and 2.
+		heading, headingLevel := "", 0
+		switch {
+		case strings.HasPrefix(line, "#"):
+			headingLevel = len(line) - len(strings.TrimLeft(line, "#"))
+			heading = strings.TrimSpace(line[headingLevel:])
+		case i+1 < len(lines) && len(strings.TrimSpace(line)) > 0 && setextPattern.MatchString(lines[i+1]):
+			headingLevel = 1
+			if lines[i+1][0] == '-' {
+				headingLevel = 2
+			}
+			heading = strings.TrimSpace(line)
+			i++
+		}
+
+		if headingLevel == 0 || (cur != nil && headingLevel > level) {
+			if cur != nil {
+				notes = append(notes, lines[start:i+1]...)
+			}
+			continue
+		}
+
+		flush()
+		if version, date := parseReleaseHeading(heading); len(version) > 0 {
+			cur = &Release{Version: version, Date: date}
+			level = headingLevel
+		}
+	}
+	flush()
+	return releases
+}
+
+// normalizeVersion returns version without "v" prefix for comparison.
+func normalizeVersion(version string) string {
+	return strings.TrimPrefix(strings.ToLower(version), "v")
+}
+
+// ReleaseNotes returns the changes of given version, it returns the latest
+// release if version is empty, and nil if no changes are found.
+func (p *PkgDecl) ReleaseNotes(version string) *Release {
+	for _, r := range p.Changelog {
+		if len(version) == 0 && r.Version != "Unreleased" {
+			return r
+		} else if len(version) > 0 && normalizeVersion(r.Version) == normalizeVersion(version) {
+			return r
+		}
+	}
+	return nil
+}
+
+// mergeReleases appends releases that are not presented in the changelog.
+func mergeReleases(changelog, releases []*Release) []*Release {
+	versions := make(map[string]bool)
+	for _, r := range changelog {
+		versions[normalizeVersion(r.Version)] = true
+	}
+	for _, r := range releases {
+		if !versions[normalizeVersion(r.Version)] {
+			changelog = append(changelog, r)
+		}
+	}
+	return changelog
+}
+
+// maxReleaseResults is the maximum number of repositories kept by releasesCache.
+const maxReleaseResults = 1000
+
+// releasesCache keeps releases of GitHub repositories by revision,
+// so packages of the same repository at the same commit share one request.
+var releasesCache = struct {
+	sync.Mutex
+	releases map[string][]*Release
+}{releases: make(map[string][]*Release)}
+
+// getGitHubReleases returns release notes of the GitHub repository at the commit.
+func getGitHubReleases(match map[string]string, commit string) ([]*Release, error) {
+	key := com.Expand("{owner}/{repo}@", match) + commit
+	releasesCache.Lock()
+	releases, ok := releasesCache.releases[key]
+	releasesCache.Unlock()
+	if ok {
+		return releases, nil
+	}
+
+	var ghReleases []struct {
+		TagName     string `json:"tag_name"`
+		Body        string `json:"body"`
+		Draft       bool   `json:"draft"`
+		PublishedAt string `json:"published_at"`
+	}
+	if err := com.HttpGetJSON(Client,
+		com.Expand("https://api.github.com/repos/{owner}/{repo}/releases?per_page=30&{cred}", match), &ghReleases); err != nil {
+		return nil, fmt.Errorf("get releases: %v", err)
+	}
+
+	releases = make([]*Release, 0, len(ghReleases))
+	for _, r := range ghReleases {
+		if r.Draft {
+			continue
+		}
+		releases = append(releases, &Release{
+			Version: r.TagName,
+			Date:    releaseDatePattern.FindString(r.PublishedAt),
+			Notes:   strings.TrimSpace(r.Body),
+		})
+	}
+
+	releasesCache.Lock()
+	if len(releasesCache.releases) >= maxReleaseResults {
+		releasesCache.releases = make(map[string][]*Release)
+	}
+	releasesCache.releases[key] = releases
+	releasesCache.Unlock()
+	return releases, nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"encoding/json"
+	"go/ast"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Maximum size in bytes of chunks of package documentation, which is split by paragraphs.
+const maxDocChunkSize = 2000
+
+// DocChunk is a retrieval-friendly piece of documentation of a package or symbol.
+type DocChunk struct {
+	ID         string `json:"id"` // e.g. "github.com/foo/bar@v1.0.0#Client.Do"
+	ImportPath string `json:"import_path"`
+	Version    string `json:"version,omitempty"`
+	Symbol     string `json:"symbol,omitempty"`
+	Kind       string `json:"kind"` // "package" or one of kinds of SymbolInfo.
+	Signature  string `json:"signature,omitempty"`
+	Doc        string `json:"doc,omitempty"`
+	Example    string `json:"example,omitempty"`
+	URL        string `json:"url,omitempty"`
+	// Text combines all of above for embedding and retrieval.
+	Text string `json:"text"`
+}
+
+// exampleOf returns code of the first example of given name, e.g. "Client_Do",
+// empty name means examples of the package.
+func exampleOf(pdoc *Package, name string) string {
+	for _, e := range pdoc.Examples {
+		// Suffixes of examples begin with lower case.
+		suffix := strings.TrimPrefix(e.Name, name+"_")
+		if e.Name == name || (len(name) == 0 && !ast.IsExported(e.Name)) ||
+			(len(suffix) < len(e.Name) && !ast.IsExported(suffix)) {
+			return strings.TrimSpace(e.Code)
+		}
+	}
+	return ""
+}
+
+// splitParagraphs splits the text into parts of at most size bytes
+// by paragraphs, unless a paragraph is longer than size.
+func splitParagraphs(text string, size int) []string {
+	var parts []string
+	var buf strings.Builder
+	for _, p := range strings.Split(text, "\n\n") {
+		p = strings.TrimSpace(p)
+		if len(p) == 0 {
+			continue
+		}
+		if buf.Len() > 0 && buf.Len()+len(p)+2 > size {
+			parts = append(parts, buf.String())
+			buf.Reset()
+		}
+		if buf.Len() > 0 {
+			buf.WriteString("\n\n")
+		}
+		buf.WriteString(p)
+	}
+	if buf.Len() > 0 {
+		parts = append(parts, buf.String())
+	}
+	return parts
+}
+
+func (c *DocChunk) setText() {
+	name := c.ImportPath
+	if len(c.Symbol) > 0 {
+		name += "." + c.Symbol
+	}
+	texts := []string{name}
+	for _, s := range []string{c.Signature, c.Doc} {
+		if len(s) > 0 {
+			texts = append(texts, s)
+		}
+	}
+	if len(c.Example) > 0 {
+		texts = append(texts, "Example:\n"+c.Example)
+	}
+	c.Text = strings.Join(texts, "\n\n")
+}
+
+// Chunks splits documentation of the package into chunks of the package and
+// each exported symbol. It must be called before the package is rendered.
+func Chunks(pdoc *Package) []*DocChunk {
+	id := pdoc.ImportPath
+	if len(pdoc.Tag) > 0 {
+		id += "@" + pdoc.Tag
+	}
+
+	var chunks []*DocChunk
+	add := func(c *DocChunk) {
+		c.ImportPath = pdoc.ImportPath
+		c.Version = pdoc.Tag
+		c.Doc = strings.TrimSpace(c.Doc)
+		if len(c.ID) == 0 {
+			c.ID = id + "#" + c.Symbol
+		}
+		c.setText()
+		chunks = append(chunks, c)
+	}
+
+	kind := "package"
+	if pdoc.IsCmd {
+		kind = "command"
+	}
+	var docText string
+	if pdoc.PkgDecl != nil {
+		docText = htmlDocText(pdoc.Doc)
+	}
+	parts := splitParagraphs(docText, maxDocChunkSize)
+	if len(parts) == 0 {
+		parts = []string{pdoc.Synopsis}
+	}
+	for i, part := range parts {
+		c := &DocChunk{Kind: kind, Doc: part}
+		if i == 0 && pdoc.PkgDecl != nil {
+			c.Example = exampleOf(pdoc, "")
+		} else if i > 0 {
+			c.ID = id + "#doc-" + strconv.Itoa(i+1)
+		}
+		add(c)
+	}
+	if pdoc.PkgDecl == nil {
+		return chunks
+	}
+
+	values := func(vals []*Value, kind string) {
+		for _, v := range vals {
+			for _, name := range v.Names() {
+				if ast.IsExported(name) {
+					add(&DocChunk{Symbol: name, Kind: kind, Signature: v.Decl, Doc: v.Doc, URL: v.URL})
+				}
+			}
+		}
+	}
+	funcs := func(fs []*Func, recv string) {
+		for _, f := range fs {
+			c := &DocChunk{Symbol: f.Name, Kind: "func", Signature: f.Decl, Doc: f.Doc, URL: f.URL}
+			c.Example = exampleOf(pdoc, f.Name)
+			if len(recv) > 0 {
+				c.Symbol, c.Kind = recv+"."+f.Name, "method"
+				c.Example = exampleOf(pdoc, recv+"_"+f.Name)
+			}
+			add(c)
+		}
+	}
+
+	values(pdoc.Consts, "const")
+	values(pdoc.Vars, "var")
+	funcs(pdoc.Funcs, "")
+	for _, t := range pdoc.Types {
+		add(&DocChunk{Symbol: t.Name, Kind: "type", Signature: t.Decl, Doc: t.Doc, URL: t.URL,
+			Example: exampleOf(pdoc, t.Name)})
+		values(t.Consts, "const")
+		values(t.Vars, "var")
+		funcs(t.Funcs, "")
+		funcs(t.Methods, t.Name)
+	}
+	return chunks
+}
+
+// ExportChunks writes chunks of documentation of the package to w in JSON lines.
+// It must be called before the package is rendered.
+func ExportChunks(w io.Writer, pdoc *Package) error {
+	enc := json.NewEncoder(w)
+	for _, c := range Chunks(pdoc) {
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
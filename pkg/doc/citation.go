@@ -0,0 +1,205 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+)
+
+// CitationAuthor represents an author of CITATION.cff, it is either a person or an entity.
+type CitationAuthor struct {
+	GivenNames  string `yaml:"given-names"`
+	FamilyNames string `yaml:"family-names"`
+	Name        string `yaml:"name"` // Name of an entity.
+	ORCID       string `yaml:"orcid"`
+	Affiliation string `yaml:"affiliation"`
+}
+
+// FullName returns the name for display.
+func (a CitationAuthor) FullName() string {
+	if len(a.FamilyNames) == 0 {
+		return a.Name
+	}
+	return strings.TrimSpace(a.GivenNames + " " + a.FamilyNames)
+}
+
+// Citation represents how to cite the software, parsed from CITATION.cff.
+type Citation struct {
+	Type         string           `yaml:"type"`
+	Message      string           `yaml:"message"`
+	Title        string           `yaml:"title"`
+	Version      string           `yaml:"version"`
+	DOI          string           `yaml:"doi"`
+	DateReleased string           `yaml:"date-released"`
+	URL          string           `yaml:"url"`
+	Repository   string           `yaml:"repository-code"`
+	Journal      string           `yaml:"journal"`
+	Year         string           `yaml:"year"`
+	Authors      []CitationAuthor `yaml:"authors"`
+	Identifiers  []struct {
+		Type  string `yaml:"type"`
+		Value string `yaml:"value"`
+	} `yaml:"identifiers"`
+
+	// Preferred is the work that authors prefer to be cited instead of the software.
+	Preferred *Citation `yaml:"preferred-citation"`
+}
+
+// parseCitation parses CITATION.cff in YAML format.
+func parseCitation(data []byte) (*Citation, error) {
+	c := new(Citation)
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("unmarshal: %v", err)
+	}
+	c.fillDOI()
+	if c.Preferred != nil {
+		c.Preferred.fillDOI()
+	}
+	return c, nil
+}
+
+// fillDOI sets DOI from identifiers if it is not set directly.
+func (c *Citation) fillDOI() {
+	if len(c.DOI) > 0 {
+		return
+	}
+	for _, id := range c.Identifiers {
+		if id.Type == "doi" {
+			c.DOI = id.Value
+			return
+		}
+	}
+}
+
+// Cited returns the citation that should be used, which is the preferred citation if presents.
+func (c *Citation) Cited() *Citation {
+	if c.Preferred != nil {
+		return c.Preferred
+	}
+	return c
+}
+
+// year returns year of the work.
+func (c *Citation) year() string {
+	if len(c.Year) > 0 {
+		return c.Year
+	} else if len(c.DateReleased) >= 4 {
+		return c.DateReleased[:4]
+	}
+	return "n.d."
+}
+
+// Text returns APA-like reference text of the cited work.
+func (c *Citation) Text() string {
+	c = c.Cited()
+
+	names := make([]string, 0, len(c.Authors))
+	for _, a := range c.Authors {
+		if len(a.FamilyNames) == 0 {
+			names = append(names, a.Name)
+			continue
+		}
+
+		name := a.FamilyNames
+		var initials []string
+		for _, given := range strings.Fields(a.GivenNames) {
+			initials = append(initials, string([]rune(given)[0])+".")
+		}
+		if len(initials) > 0 {
+			name += ", " + strings.Join(initials, " ")
+		}
+		names = append(names, name)
+	}
+
+	var buf bytes.Buffer
+	switch len(names) {
+	case 0:
+	case 1:
+		buf.WriteString(names[0])
+	default:
+		buf.WriteString(strings.Join(names[:len(names)-1], ", "))
+		buf.WriteString(", & " + names[len(names)-1])
+	}
+	fmt.Fprintf(&buf, " (%s). %s", c.year(), c.Title)
+	if len(c.Version) > 0 {
+		fmt.Fprintf(&buf, " (Version %s)", c.Version)
+	}
+	if len(c.Journal) > 0 {
+		fmt.Fprintf(&buf, ". %s", c.Journal)
+	}
+	buf.WriteString(".")
+
+	switch {
+	case len(c.DOI) > 0:
+		buf.WriteString(" https://doi.org/" + c.DOI)
+	case len(c.URL) > 0:
+		buf.WriteString(" " + c.URL)
+	case len(c.Repository) > 0:
+		buf.WriteString(" " + c.Repository)
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// BibTeX returns BibTeX entry of the cited work.
+func (c *Citation) BibTeX() string {
+	c = c.Cited()
+
+	entryType := "software"
+	if c.Type == "article" {
+		entryType = "article"
+	}
+
+	names := make([]string, 0, len(c.Authors))
+	key := "unknown"
+	for i, a := range c.Authors {
+		if len(a.FamilyNames) == 0 {
+			names = append(names, "{"+a.Name+"}")
+		} else {
+			names = append(names, a.FamilyNames+", "+a.GivenNames)
+		}
+		if i == 0 {
+			key = strings.ToLower(strings.Map(func(r rune) rune {
+				if unicode.IsLetter(r) || unicode.IsDigit(r) {
+					return r
+				}
+				return -1
+			}, a.FamilyNames+a.Name))
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "@%s{%s%s,\n", entryType, key, c.year())
+	fields := [][2]string{
+		{"author", strings.Join(names, " and ")},
+		{"title", c.Title},
+		{"journal", c.Journal},
+		{"version", c.Version},
+		{"doi", c.DOI},
+		{"url", c.URL},
+		{"year", c.year()},
+	}
+	for _, f := range fields {
+		if len(f[1]) > 0 {
+			fmt.Fprintf(&buf, "  %s = {%s},\n", f[0], f[1])
+		}
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
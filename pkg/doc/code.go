@@ -92,6 +92,12 @@ func (v *annotationVisitor) add(kind AnnotationKind, importPath string) {
 	v.annotations = append(v.annotations, Annotation{Kind: kind, ImportPath: importPath})
 }
 
+// addRef adds an ExportLinkAnnotation to the symbol declared by the package
+// of given import path, which is empty for the current package.
+func (v *annotationVisitor) addRef(importPath, symbol string) {
+	v.annotations = append(v.annotations, Annotation{Kind: ExportLinkAnnotation, ImportPath: importPath, Symbol: symbol})
+}
+
 func (v *annotationVisitor) ignoreName() {
 	v.add(-1, "")
 }
@@ -127,7 +133,7 @@ func (v *annotationVisitor) Visit(n ast.Node) ast.Visitor {
 		case n.Obj == nil && predeclared[n.Name] != notPredeclared:
 			v.add(BuiltinAnnotation, "")
 		case n.Obj != nil && ast.IsExported(n.Name):
-			v.add(ExportLinkAnnotation, "")
+			v.addRef("", n.Name)
 		default:
 			v.ignoreName()
 		}
@@ -140,7 +146,7 @@ func (v *annotationVisitor) Visit(n ast.Node) ast.Visitor {
 						if path == "C" {
 							v.ignoreName()
 						} else {
-							v.add(ExportLinkAnnotation, path)
+							v.addRef(path, n.Sel.Name)
 						}
 						return nil
 					}
@@ -223,6 +229,18 @@ type Annotation struct {
 	Pos, End   int16
 	Kind       AnnotationKind
 	ImportPath string
+	Symbol     string // Referenced symbol of ExportLinkAnnotation.
+}
+
+// URL returns URL of documentation of the symbol that the ExportLinkAnnotation
+// refers to, which is an anchor when the symbol is declared by the current package.
+func (a Annotation) URL() (string, error) {
+	if a.Kind != ExportLinkAnnotation {
+		return "", ErrRefNotFound
+	} else if len(a.ImportPath) == 0 {
+		return "#" + a.Symbol, nil
+	}
+	return ResolveRef(a.ImportPath, a.Symbol)
 }
 
 type Code struct {
@@ -0,0 +1,162 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec is the compression algorithm of serialized data.
+type Codec byte
+
+const (
+	CodecNone Codec = iota
+	CodecGzip
+	CodecZstd
+)
+
+var codecNames = []string{"none", "gzip", "zstd"}
+
+func (c Codec) String() string {
+	if int(c) < len(codecNames) {
+		return codecNames[c]
+	}
+	return fmt.Sprintf("Codec(%d)", c)
+}
+
+// ParseCodec returns the codec of given name, empty name means CodecNone.
+func ParseCodec(name string) (Codec, error) {
+	if len(name) == 0 {
+		return CodecNone, nil
+	}
+	for i, n := range codecNames {
+		if strings.EqualFold(name, n) {
+			return Codec(i), nil
+		}
+	}
+	return CodecNone, fmt.Errorf("unknown codec %q", name)
+}
+
+// blobMagic starts the header of compressed data, which is followed by a byte of the codec.
+// Data without the header is stored before compression is supported, and is not compressed.
+var blobMagic = []byte("GWB\x01")
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// initZstd creates the encoder and decoder that are safe for concurrent use.
+func initZstd() error {
+	zstdOnce.Do(func() {
+		if zstdEncoder, zstdErr = zstd.NewWriter(nil); zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	return zstdErr
+}
+
+// Compress returns data compressed by the codec with the header.
+func Compress(data []byte, codec Codec) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, len(blobMagic)+1+len(data)/4))
+	buf.Write(blobMagic)
+	buf.WriteByte(byte(codec))
+
+	switch codec {
+	case CodecNone:
+		buf.Write(data)
+	case CodecGzip:
+		w := gzip.NewWriter(buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		} else if err = w.Close(); err != nil {
+			return nil, err
+		}
+	case CodecZstd:
+		if err := initZstd(); err != nil {
+			return nil, fmt.Errorf("init zstd: %v", err)
+		}
+		return zstdEncoder.EncodeAll(data, buf.Bytes()), nil
+	default:
+		return nil, fmt.Errorf("unknown codec %d", codec)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress returns original data of the output of Compress
+// with the codec in the header.
+func Decompress(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, blobMagic) || len(data) == len(blobMagic) {
+		return data, nil
+	}
+
+	codec := Codec(data[len(blobMagic)])
+	data = data[len(blobMagic)+1:]
+	switch codec {
+	case CodecNone:
+		return data, nil
+	case CodecGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	case CodecZstd:
+		if err := initZstd(); err != nil {
+			return nil, fmt.Errorf("init zstd: %v", err)
+		}
+		return zstdDecoder.DecodeAll(data, nil)
+	}
+	return nil, fmt.Errorf("unknown codec %d", codec)
+}
+
+// EncodePackage serializes the package and compresses it by the codec.
+func EncodePackage(pdoc *Package, codec Codec) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(pdoc); err != nil {
+		return nil, fmt.Errorf("encode: %v", err)
+	}
+	data, err := Compress(buf.Bytes(), codec)
+	if err != nil {
+		return nil, fmt.Errorf("compress: %v", err)
+	}
+	return data, nil
+}
+
+// DecodePackage returns the package serialized by EncodePackage.
+func DecodePackage(data []byte) (*Package, error) {
+	data, err := Decompress(data)
+	if err != nil {
+		return nil, fmt.Errorf("decompress: %v", err)
+	}
+
+	pdoc := new(Package)
+	if err = gob.NewDecoder(bytes.NewReader(data)).Decode(pdoc); err != nil {
+		return nil, fmt.Errorf("decode: %v", err)
+	}
+	return pdoc, nil
+}
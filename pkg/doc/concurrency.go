@@ -0,0 +1,119 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"go/types"
+)
+
+// ConcurrencyHint indicates how a function or type exposes concurrency in its API.
+type ConcurrencyHint uint
+
+const (
+	CH_ChanParam      ConcurrencyHint = 1 << iota // Accepts channels.
+	CH_ChanResult                                 // Returns bidirectional channels.
+	CH_RecvOnlyResult                             // Returns receive-only channels.
+	CH_SendOnlyResult                             // Returns send-only channels.
+	CH_SyncPrimitive                              // Contains sync primitives.
+)
+
+// IsAsync returns true if channels are exposed through the signature.
+func (h ConcurrencyHint) IsAsync() bool {
+	return h&(CH_ChanParam|CH_ChanResult|CH_RecvOnlyResult|CH_SendOnlyResult) != 0
+}
+
+// chanOf returns channel type that is directly exposed by given type,
+// looking through pointers, slices, arrays and maps.
+func chanOf(typ types.Type) *types.Chan {
+	for {
+		switch t := typ.(type) {
+		case *types.Chan:
+			return t
+		case *types.Pointer:
+			typ = t.Elem()
+		case *types.Slice:
+			typ = t.Elem()
+		case *types.Array:
+			typ = t.Elem()
+		case *types.Map:
+			typ = t.Elem()
+		default:
+			return nil
+		}
+	}
+}
+
+func signatureConcurrency(sig *types.Signature) ConcurrencyHint {
+	var hint ConcurrencyHint
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if chanOf(params.At(i).Type()) != nil {
+			hint |= CH_ChanParam
+		}
+	}
+
+	results := sig.Results()
+	for i := 0; i < results.Len(); i++ {
+		ch := chanOf(results.At(i).Type())
+		if ch == nil {
+			continue
+		}
+		switch ch.Dir() {
+		case types.RecvOnly:
+			hint |= CH_RecvOnlyResult
+		case types.SendOnly:
+			hint |= CH_SendOnlyResult
+		default:
+			hint |= CH_ChanResult
+		}
+	}
+	return hint
+}
+
+func isSyncType(typ types.Type) bool {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	path := named.Obj().Pkg().Path()
+	return path == "sync" || path == "sync/atomic"
+}
+
+// typeConcurrency returns concurrency hints of the type with given name.
+func (w *Walker) typeConcurrency(name string) ConcurrencyHint {
+	if w.tpkg == nil {
+		return 0
+	}
+	tn, ok := w.tpkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return 0
+	}
+
+	var hint ConcurrencyHint
+	switch t := tn.Type().Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if isSyncType(t.Field(i).Type()) {
+				hint |= CH_SyncPrimitive
+			}
+		}
+	case *types.Signature:
+		hint |= signatureConcurrency(t)
+	}
+	return hint
+}
@@ -0,0 +1,155 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	log "gopkg.in/clog.v1"
+)
+
+// CorpusIndexer is implemented by indexers that can be exported with the corpus.
+type CorpusIndexer interface {
+	Indexer
+	WriteEntries(w io.Writer) error
+	// ReadEntries adds entries written by WriteEntries to the index.
+	ReadEntries(r io.Reader) error
+}
+
+const (
+	corpusIndexName  = "index.gob"
+	corpusDocsPrefix = "docs/"
+)
+
+func corpusDocName(importPath, version string) string {
+	if len(version) == 0 {
+		version = "_"
+	}
+	return corpusDocsPrefix + importPath + "/@v/" + version + ".gob"
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// ExportCorpus writes all packages in the doc store and the index to w as a tar.
+// The index is the first file if the indexer is a CorpusIndexer, which is followed
+// by packages encoded by EncodePackage.
+func ExportCorpus(w io.Writer) error {
+	if docStore == nil {
+		return fmt.Errorf("doc store is not set")
+	}
+
+	tw := tar.NewWriter(w)
+	if ci, ok := indexer.(CorpusIndexer); ok {
+		var buf bytes.Buffer
+		if err := ci.WriteEntries(&buf); err != nil {
+			return fmt.Errorf("write index: %v", err)
+		} else if err = writeTarFile(tw, corpusIndexName, buf.Bytes(), time.Now()); err != nil {
+			return err
+		}
+	}
+
+	metas, err := docStore.List()
+	if err != nil {
+		return fmt.Errorf("list: %v", err)
+	}
+	for _, m := range metas {
+		pdoc, err := docStore.Get(m.ImportPath, m.Version)
+		if err == ErrDocNotFound {
+			continue // Deleted in the meantime.
+		} else if err != nil {
+			return fmt.Errorf("get %s@%s: %v", m.ImportPath, m.Version, err)
+		}
+
+		data, err := EncodePackage(pdoc, CodecNone)
+		if err != nil {
+			return fmt.Errorf("encode %s@%s: %v", m.ImportPath, m.Version, err)
+		} else if err = writeTarFile(tw, corpusDocName(m.ImportPath, m.Version), data, time.Unix(m.Walked, 0)); err != nil {
+			return err
+		}
+	}
+	log.Trace("Exported %d packages", len(metas))
+	return tw.Close()
+}
+
+// ImportCorpus saves packages written by ExportCorpus to the doc store and
+// adds them to the index. Packages are indexed one by one if the corpus
+// has no index or the indexer is not a CorpusIndexer.
+func ImportCorpus(r io.Reader) error {
+	if docStore == nil {
+		return fmt.Errorf("doc store is not set")
+	}
+
+	tr := tar.NewReader(r)
+	indexed := false
+	n := 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("read: %v", err)
+		}
+
+		switch {
+		case hdr.Name == corpusIndexName:
+			ci, ok := indexer.(CorpusIndexer)
+			if !ok {
+				continue
+			}
+			if err = ci.ReadEntries(tr); err != nil {
+				return fmt.Errorf("read index: %v", err)
+			}
+			indexed = true
+
+		case strings.HasPrefix(hdr.Name, corpusDocsPrefix) && path.Ext(hdr.Name) == ".gob":
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return fmt.Errorf("read %s: %v", hdr.Name, err)
+			}
+			pdoc, err := DecodePackage(data)
+			if err != nil {
+				return fmt.Errorf("decode %s: %v", hdr.Name, err)
+			}
+
+			if err = docStore.Put(pdoc); err != nil {
+				return fmt.Errorf("store %s: %v", hdr.Name, err)
+			}
+			if !indexed && indexer != nil && len(pdoc.Tag) == 0 {
+				indexer.Add(pdoc)
+			}
+			n++
+		}
+	}
+	log.Trace("Imported %d packages", n)
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"debug/buildinfo"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CostReport is the cost of the binary of a command, which helps authors
+// of command line tools keep track of bloat.
+type CostReport struct {
+	Size      int64 // In bytes.
+	GoVersion string
+	Deps      []string // Module dependencies linked into the binary, e.g. "golang.org/x/text v0.3.0".
+	Settings  []string // Build settings, e.g. "GOOS=linux".
+	BuildTime time.Duration
+}
+
+// SizeString returns the size in a human-readable form, e.g. "4.2 MB".
+func (r *CostReport) SizeString() string {
+	switch {
+	case r.Size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(r.Size)/(1<<20))
+	case r.Size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(r.Size)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", r.Size)
+}
+
+// Maximum duration of building a command.
+const buildTimeout = 5 * time.Minute
+
+// BuildCost builds the command in the local directory with the go command in PATH
+// and environment of the process, and sets the cost report of the package from
+// the binary. Binaries are built without cgo, and dependencies are downloaded by
+// the go command.
+func BuildCost(pdoc *Package, dir string) error {
+	return buildCost(pdoc, dir, false)
+}
+
+// buildCost is like BuildCost, but builds untrusted code in the sandbox when untrusted is true.
+func buildCost(pdoc *Package, dir string, untrusted bool) error {
+	if !pdoc.IsCmd {
+		return fmt.Errorf("%s is not a command", pdoc.ImportPath)
+	}
+
+	tmp, err := ioutil.TempDir("", "gowalker-build")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	bin := filepath.Join(tmp, "main")
+	start := time.Now()
+	if err = goBuild(dir, bin, untrusted); err != nil {
+		return fmt.Errorf("build: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	fi, err := os.Stat(bin)
+	if err != nil {
+		return err
+	}
+	info, err := buildinfo.ReadFile(bin)
+	if err != nil {
+		return fmt.Errorf("read build info: %v", err)
+	}
+
+	report := &CostReport{
+		Size:      fi.Size(),
+		GoVersion: info.GoVersion,
+		BuildTime: elapsed,
+	}
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		report.Deps = append(report.Deps, dep.Path+" "+dep.Version)
+	}
+	for _, s := range info.Settings {
+		report.Settings = append(report.Settings, s.Key+"="+s.Value)
+	}
+	pdoc.Cost = report
+	return nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Coverage is the statement coverage of a function or package.
+type Coverage struct {
+	Statements int // Number of statements.
+	Covered    int // Number of statements executed at least once.
+}
+
+// Percent returns percentage of covered statements.
+func (c *Coverage) Percent() float64 {
+	if c.Statements == 0 {
+		return 0
+	}
+	return 100 * float64(c.Covered) / float64(c.Statements)
+}
+
+func (c *Coverage) String() string {
+	return fmt.Sprintf("%.1f%%", c.Percent())
+}
+
+func (c *Coverage) add(b CoverBlock) {
+	c.Statements += b.NumStmt
+	if b.Count > 0 {
+		c.Covered += b.NumStmt
+	}
+}
+
+// CoverBlock is a block of statements in a coverage profile.
+type CoverBlock struct {
+	Filename            string // File name in the profile, e.g. "github.com/foo/bar/bar.go".
+	StartLine, StartCol int
+	EndLine, EndCol     int
+	NumStmt             int
+	Count               int // Times the block is executed, or 1 in set mode.
+}
+
+// ParseCoverProfile parses a coverage profile written by "go test -coverprofile".
+// Counts of the same block in profiles concatenated from multiple runs are added.
+func ParseCoverProfile(data []byte) ([]CoverBlock, error) {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if !strings.HasPrefix(lines[0], "mode: ") {
+		return nil, fmt.Errorf("bad mode line: %q", lines[0])
+	}
+
+	var blocks []CoverBlock
+	seen := make(map[string]int) // Position -> index in blocks.
+	for i, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "mode: ") {
+			continue
+		}
+
+		// Format: name.go:line.column,line.column numberOfStatements count
+		b := CoverBlock{}
+		colon := strings.LastIndex(line, ":")
+		if colon == -1 {
+			return nil, fmt.Errorf("line %d: bad block %q", i+2, line)
+		}
+		b.Filename = line[:colon]
+		_, err := fmt.Sscanf(line[colon+1:], "%d.%d,%d.%d %d %d",
+			&b.StartLine, &b.StartCol, &b.EndLine, &b.EndCol, &b.NumStmt, &b.Count)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad block %q: %v", i+2, line, err)
+		}
+
+		pos := line[:strings.Index(line, " ")]
+		if j, ok := seen[pos]; ok {
+			blocks[j].Count += b.Count
+			continue
+		}
+		seen[pos] = len(blocks)
+		blocks = append(blocks, b)
+	}
+	return blocks, nil
+}
+
+// ApplyCoverage sets statement coverage of functions and methods of the package,
+// and the package itself, by blocks in files of the package in the profile.
+// Profiles record files by import path, so the import path of the package must
+// match the profile.
+func ApplyCoverage(pdoc *Package, blocks []CoverBlock) {
+	files := make(map[string][]CoverBlock) // Base name -> blocks.
+	total := new(Coverage)
+	for _, b := range blocks {
+		if path.Dir(b.Filename) != pdoc.ImportPath {
+			continue
+		}
+		name := path.Base(b.Filename)
+		files[name] = append(files[name], b)
+		total.add(b)
+	}
+	if len(files) == 0 {
+		return
+	}
+	pdoc.Coverage = total
+
+	funcs := func(fns []*Func) {
+		for _, fn := range fns {
+			c := new(Coverage)
+			for _, b := range files[fn.Filename] {
+				if fn.Line <= b.StartLine && b.StartLine <= fn.EndLine {
+					c.add(b)
+				}
+			}
+			if c.Statements > 0 {
+				fn.Coverage = c
+			}
+		}
+	}
+	types := func(tps []*Type) {
+		for _, t := range tps {
+			funcs(t.Funcs)
+			funcs(t.Methods)
+			funcs(t.IFuncs)
+			funcs(t.IMethods)
+		}
+	}
+	funcs(pdoc.Funcs)
+	funcs(pdoc.Ifuncs)
+	types(pdoc.Types)
+	types(pdoc.Itypes)
+}
+
+// Names of coverage profiles that are picked up in directories of local packages.
+var coverProfileNames = []string{"coverage.out", "cover.out"}
+
+// readCoverProfile applies the coverage profile in the directory to the package if any.
+func readCoverProfile(pdoc *Package, dir string) error {
+	for _, name := range coverProfileNames {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		blocks, err := ParseCoverProfile(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		ApplyCoverage(pdoc, blocks)
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCoverProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		want    []CoverBlock
+		wantErr bool
+	}{
+		{
+			name:    "set mode",
+			profile: "mode: set\na/b/b.go:3.14,5.2 1 1\na/b/b.go:7.10,9.3 2 0\n",
+			want: []CoverBlock{
+				{"a/b/b.go", 3, 14, 5, 2, 1, 1},
+				{"a/b/b.go", 7, 10, 9, 3, 2, 0},
+			},
+		},
+		{
+			name: "concatenated profiles",
+			profile: `mode: count
+a/b/b.go:3.14,5.2 1 3
+mode: count
+a/b/b.go:3.14,5.2 1 2
+C:/a/b/c.go:1.1,2.2 1 0
+`,
+			want: []CoverBlock{
+				{"a/b/b.go", 3, 14, 5, 2, 1, 5},
+				{"C:/a/b/c.go", 1, 1, 2, 2, 1, 0},
+			},
+		},
+		{
+			name:    "mode only",
+			profile: "mode: atomic\n",
+		},
+		{
+			name:    "no mode",
+			profile: "a/b/b.go:3.14,5.2 1 1\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad block",
+			profile: "mode: set\na/b/b.go 1 1\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad numbers",
+			profile: "mode: set\na/b/b.go:3.14,5 1 1\n",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseCoverProfile([]byte(test.profile))
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("got no error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCoverage_Percent(t *testing.T) {
+	tests := []struct {
+		c    Coverage
+		want string
+	}{
+		{Coverage{}, "0.0%"},
+		{Coverage{Statements: 3, Covered: 1}, "33.3%"},
+		{Coverage{Statements: 4, Covered: 4}, "100.0%"},
+	}
+	for _, test := range tests {
+		if got := test.c.String(); got != test.want {
+			t.Errorf("%+v: got %q, want %q", test.c, got, test.want)
+		}
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"path"
 	"regexp"
@@ -51,6 +52,7 @@ type service struct {
 // services is the list of source code control services handled by gowalker.
 var services = []*service{
 	{githubPattern, "github.com/", getGitHubDoc},
+	{gopkgPattern, "gopkg.in/", getGopkgDoc},
 	// {googlePattern, "code.google.com/", getGoogleDoc},
 	// {bitbucketPattern, "bitbucket.org/", getBitbucketDoc},
 	// {launchpadPattern, "launchpad.net/", getLaunchpadDoc},
@@ -60,6 +62,16 @@ var services = []*service{
 // getStatic gets a document from a statically known service.
 // It returns ErrNoServiceMatch if the import path is not recognized.
 func getStatic(importPath, etag string) (pdoc *Package, err error) {
+	return getStaticWithTemplates(importPath, etag, nil)
+}
+
+// sourceTemplateKeys is the list of match keys of source link templates
+// that are published by projects, see setSourceTemplates.
+var sourceTemplateKeys = []string{"browseTpl", "lineFmt", "viewDirPath"}
+
+// getStaticWithTemplates is same as getStatic but source links of the document
+// are built with given templates instead of the ones of the service.
+func getStaticWithTemplates(importPath, etag string, tpls map[string]string) (pdoc *Package, err error) {
 	for _, s := range services {
 		if s.get == nil || !strings.HasPrefix(importPath, s.prefix) {
 			continue
@@ -78,6 +90,11 @@ func getStatic(importPath, etag string) (pdoc *Package, err error) {
 				match[n] = m[i]
 			}
 		}
+		for _, key := range sourceTemplateKeys {
+			if len(tpls[key]) > 0 {
+				match[key] = tpls[key]
+			}
+		}
 		return s.get(match, etag)
 	}
 	return nil, ErrNoServiceMatch
@@ -94,6 +111,7 @@ func attrValue(attrs []xml.Attr, name string) string {
 
 func parseMeta(scheme, importPath string, r io.Reader) (map[string]string, error) {
 	var match map[string]string
+	var sources [][]string
 
 	d := xml.NewDecoder(r)
 	d.Strict = false
@@ -112,11 +130,18 @@ metaScan:
 			if strings.EqualFold(t.Name.Local, "body") {
 				break metaScan
 			}
-			if !strings.EqualFold(t.Name.Local, "meta") ||
-				attrValue(t.Attr, "name") != "go-import" {
+			if !strings.EqualFold(t.Name.Local, "meta") {
 				continue metaScan
 			}
 			f := strings.Fields(attrValue(t.Attr, "content"))
+			if attrValue(t.Attr, "name") == "go-source" {
+				if len(f) == 4 {
+					sources = append(sources, f)
+				}
+				continue metaScan
+			} else if attrValue(t.Attr, "name") != "go-import" {
+				continue metaScan
+			}
 			if len(f) != 3 ||
 				!strings.HasPrefix(importPath, f[0]) ||
 				!(len(importPath) == len(f[0]) || importPath[len(f[0])] == '/') {
@@ -156,9 +181,62 @@ metaScan:
 	if match == nil {
 		return nil, errors.New("<meta> not found")
 	}
+
+	// The go-source meta tag must have same prefix as the go-import meta tag.
+	for _, f := range sources {
+		if f[0] != match["projectRoot"] {
+			continue
+		}
+		match["sourceHome"] = f[1]
+		if f[2] != "_" {
+			match["sourceDir"] = f[2]
+		}
+		if f[3] != "_" {
+			match["sourceFile"] = f[3]
+		}
+		break
+	}
 	return match, nil
 }
 
+// expandSourceTemplate converts a directory or file URL template of go-source meta tag
+// to the form that com.Expand accepts, the file name is represented by "{0}".
+// Scheme is removed as other source links.
+func expandSourceTemplate(tpl, dir string) string {
+	if i := strings.Index(tpl, "://"); i > -1 {
+		tpl = tpl[i+3:]
+	}
+	dir = strings.TrimPrefix(dir, "/")
+	slashDir := dir
+	if len(dir) > 0 {
+		slashDir = "/" + dir
+	}
+	return strings.NewReplacer("{dir}", dir, "{/dir}", slashDir, "{file}", "{0}").Replace(tpl)
+}
+
+// setSourceTemplates sets browse URL template, line format and view directory path
+// derived from templates of go-source meta tag to the match. The part after file name
+// in the file URL template is used as line format.
+func setSourceTemplates(match map[string]string) {
+	if len(match["sourceDir"]) > 0 {
+		match["viewDirPath"] = expandSourceTemplate(match["sourceDir"], match["dir"])
+	}
+
+	fileTpl := match["sourceFile"]
+	i := strings.LastIndex(fileTpl, "{file}")
+	if i == -1 {
+		return
+	}
+	i += len("{file}")
+	match["browseTpl"] = expandSourceTemplate(fileTpl[:i], match["dir"])
+
+	// Line number could be referred multiple times, e.g. "#L{line}-L{line}".
+	lineFmt := strings.Replace(fileTpl[i:], "%", "%%", -1)
+	if strings.Contains(lineFmt, "{line}") {
+		match["lineFmt"] = strings.Replace(lineFmt, "{line}", "%[1]d", -1)
+	}
+}
+
 func fetchMeta(importPath string) (map[string]string, error) {
 	uri := importPath
 	if !strings.Contains(uri, "/") {
@@ -168,13 +246,24 @@ func fetchMeta(importPath string) (map[string]string, error) {
 	uri = uri + "?go-get=1"
 
 	scheme := "https"
-	resp, err := Client.Get(scheme + "://" + uri)
-	if err != nil || resp.StatusCode != 200 {
+	resp, err := getWithRobots(scheme + "://" + uri)
+	if err == ErrRobotsDisallowed {
+		return nil, err
+	}
+	// Private modules are never fetched over insecure protocol.
+	if isNoProxy(importPath) {
+		if err != nil {
+			return nil, err
+		} else if resp.StatusCode != 200 {
+			resp.Body.Close()
+			return nil, fmt.Errorf("fetch meta: %s", resp.Status)
+		}
+	} else if err != nil || resp.StatusCode != 200 {
 		if err == nil {
 			resp.Body.Close()
 		}
 		scheme = "http"
-		resp, err = Client.Get(scheme + "://" + uri)
+		resp, err = getWithRobots(scheme + "://" + uri)
 		if err != nil {
 			return nil, err
 		}
@@ -199,6 +288,9 @@ func getDynamic(importPath, etag string) (pdoc *Package, err error) {
 		}
 	}
 
+	// Source link templates must be derived before directory is changed for Go subrepos.
+	setSourceTemplates(match)
+
 	isGoSubrepo := false
 	if strings.HasPrefix(match["repo"], "go.googlesource.com") {
 		isGoSubrepo = true
@@ -206,12 +298,16 @@ func getDynamic(importPath, etag string) (pdoc *Package, err error) {
 		match["repo"] = "github.com/golang"
 	}
 
-	pdoc, err = getStatic(com.Expand("{repo}{dir}", match), etag)
+	pdoc, err = getStaticWithTemplates(com.Expand("{repo}{dir}", match), etag, match)
 	if err == ErrNoServiceMatch {
 		pdoc, err = getVCSDoc(match, etag)
 	} else if pdoc != nil {
 		pdoc.ImportPath = importPath
 		pdoc.IsGoSubrepo = isGoSubrepo
+		// Module path is usually the vanity import path.
+		if pdoc.SupersededBy == importPath {
+			pdoc.SupersededBy = ""
+		}
 	}
 	if err != nil {
 		return nil, err
@@ -220,8 +316,39 @@ func getDynamic(importPath, etag string) (pdoc *Package, err error) {
 	return pdoc, err
 }
 
+// Fetcher fetches and walks packages instead of code hosting services.
+// Packages are used as returned, e.g. README is not rendered.
+type Fetcher interface {
+	// Fetch returns ErrPackageNotModified if the package has the etag.
+	Fetch(importPath, etag string) (*Package, error)
+}
+
+var fetcher Fetcher
+
+// SetFetcher sets the fetcher of all packages, which is used to walk packages
+// without network in tests. Passing nil restores fetching from code hosting services.
+func SetFetcher(f Fetcher) {
+	fetcher = f
+}
+
 func crawlDoc(importPath, etag string) (pdoc *Package, err error) {
-	switch {
+	if fetcher != nil {
+		return fetcher.Fetch(importPath, etag)
+	}
+
+	// Projects of legacy hosts have been moved to new places,
+	// documentation is walked and stored under the new import path.
+	if newPath := LegacyImportPath(importPath); len(newPath) > 0 {
+		return crawlDoc(newPath, etag)
+	}
+
+	if err = fireHooks(&Event{Phase: PhaseFetch, ImportPath: importPath}); err != nil {
+		return nil, err
+	}
+
+	switch m := repoMappingOf(importPath); {
+	case m != nil:
+		pdoc, err = getMapped(m, importPath, etag)
 	case base.IsGoRepoPath(importPath):
 		pdoc, err = getGolangDoc(importPath, etag)
 	case base.IsGAERepoPath(strings.TrimPrefix(importPath, "google.golang.org/")):
@@ -246,13 +373,27 @@ func crawlDoc(importPath, etag string) (pdoc *Package, err error) {
 
 	// Render README
 	for name, content := range pdoc.Readme {
+		// README of private packages must not be sent to public services.
+		if IsPrivate(pdoc.ImportPath) {
+			pdoc.Readme[name] = []byte("<pre>" + html.EscapeString(string(content)) + "</pre>")
+			continue
+		}
+
 		p, err := httplib.Post("https://api.github.com/markdown/raw?"+setting.GitHubCredentials).
 			Header("Content-Type", "text/plain").Body(content).Bytes()
 		if err != nil {
 			return nil, fmt.Errorf("error rendering README: %v", err)
 		}
-		pdoc.Readme[name] = p
+		p = renderReadmeDiagrams(p)
+		if assetStore != nil {
+			p = mirrorImages(pdoc, p)
+		}
+		pdoc.Readme[name] = []byte(SanitizeHTML(string(p)))
 	}
 
+	// Examples of private packages must not be shared to the public playground.
+	if !IsPrivate(pdoc.ImportPath) {
+		setPlayLinks(pdoc.Examples)
+	}
 	return pdoc, nil
 }
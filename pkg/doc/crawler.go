@@ -0,0 +1,234 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/pkg/audit"
+	"github.com/Unknwon/gowalker/pkg/base"
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+var ErrWalkInProgress = errors.New("package is being walked, please try again later")
+
+// Locker provides locks that are shared between servers.
+type Locker interface {
+	// Lock returns the token to release the lock, or empty string if the key
+	// is locked by others. The lock is released automatically after ttl.
+	Lock(key string, ttl time.Duration) (string, error)
+	Unlock(key, token string) error
+}
+
+// Queue is a queue of import paths to be walked that is shared between crawlers.
+type Queue interface {
+	// Push does nothing if the import path is already in the queue.
+	Push(importPath string) error
+	// Pop returns empty string if the queue is still empty after timeout.
+	Pop(timeout time.Duration) (string, error)
+}
+
+var (
+	locker     Locker = NewMemoryLocker()
+	crawlQueue Queue
+)
+
+// SetLocker sets the locker to prevent servers from walking the same package at the same time,
+// the default one only works within the process and nil disables locking.
+func SetLocker(l Locker) {
+	locker = l
+}
+
+// SetQueue sets the queue that imports of walked packages are pushed to for crawlers.
+func SetQueue(q Queue) {
+	crawlQueue = q
+}
+
+// lockWalk acquires the lock of walking given package and returns the function to release it.
+// It returns ErrShuttingDown after Shutdown is called.
+func lockWalk(importPath string) (func(), error) {
+	if !beginWalk(importPath) {
+		return nil, ErrShuttingDown
+	}
+	if locker == nil {
+		return func() { endWalk(importPath) }, nil
+	}
+
+	key := "walk:" + importPath
+	token, err := locker.Lock(key, setting.Current().FetchTimeout)
+	if err != nil {
+		endWalk(importPath)
+		return nil, fmt.Errorf("lock: %v", err)
+	} else if len(token) == 0 {
+		endWalk(importPath)
+		return nil, ErrWalkInProgress
+	}
+	return func() {
+		if err := locker.Unlock(key, token); err != nil {
+			log.Error(2, "Failed to unlock %q: %v", key, err)
+		}
+		endWalk(importPath)
+	}, nil
+}
+
+// storedDoc returns stored documentation of the default branch, or nil if it does not exist.
+func storedDoc(importPath string) *Package {
+	if docStore == nil {
+		return nil
+	}
+
+	pdoc, err := docStore.Get(importPath, "")
+	if err != nil {
+		if err != ErrDocNotFound {
+			log.Error(2, "Failed to get stored doc %q: %v", importPath, err)
+		}
+		return nil
+	}
+	return pdoc
+}
+
+// isFresh returns true if the documentation is walked or checked within the refresh interval.
+func isFresh(pdoc *Package) bool {
+	return pdoc.Provenance != nil && time.Since(pdoc.Provenance.LastChecked()) <= setting.RefreshInterval
+}
+
+// freshStoredDoc returns stored documentation of the default branch
+// if it is walked or checked within the refresh interval.
+func freshStoredDoc(importPath string) *Package {
+	if pdoc := storedDoc(importPath); pdoc != nil && isFresh(pdoc) {
+		return pdoc
+	}
+	return nil
+}
+
+// markUnchanged records the stored documentation is checked to be up to date
+// with the upstream, so it is fresh again without walking.
+func markUnchanged(pdoc *Package) error {
+	if pdoc.Provenance == nil {
+		pdoc.Provenance = new(Provenance)
+	}
+	pdoc.Provenance.CheckedAt = time.Now().UTC()
+	return docStore.Put(pdoc)
+}
+
+// enqueueImports pushes imported packages to the crawl queue.
+func enqueueImports(pdoc *Package) {
+	if crawlQueue == nil {
+		return
+	}
+
+	for _, importPath := range pdoc.Imports {
+		if base.IsGoRepoPath(importPath) || !base.IsValidRemotePath(importPath) || IsBlocked(importPath) {
+			continue
+		}
+		if err := crawlQueue.Push(importPath); err != nil {
+			log.Error(2, "Failed to push %q to crawl queue: %v", importPath, err)
+			return
+		}
+	}
+}
+
+// prewalk walks the package to the store without rendering, it is rendered
+// from the store when the package is requested. Imports of the package are
+// not pushed to the queue, so crawlers only walk one hop from requested packages.
+// It returns the stored documentation if it is walked recently, or the upstream
+// has not changed since it is walked.
+func prewalk(importPath string) (*Package, error) {
+	if IsBlocked(importPath) {
+		return nil, ErrBlocked
+	}
+	stored := storedDoc(importPath)
+	if stored != nil && isFresh(stored) {
+		return stored, nil
+	}
+
+	unlock, err := lockWalk(importPath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Fetchers check the etag cheaply before downloading files.
+	var etag string
+	if stored != nil {
+		etag = stored.Etag
+	}
+	pdoc, err := fetchDoc(importPath, etag)
+	if err == ErrPackageNotModified {
+		atomic.AddInt64(&walkCounters.crawlerUnchanged, 1)
+		return stored, markUnchanged(stored)
+	}
+	countWalk(true, err)
+	if err != nil {
+		return nil, err
+	}
+	setSubdirSynopses(pdoc)
+	if indexer != nil {
+		indexer.Add(pdoc)
+	}
+	audit.Record(&audit.Event{
+		Action:     audit.ActionWalk,
+		User:       "crawler",
+		ImportPath: importPath,
+	})
+	return pdoc, putDoc(docStore, pdoc)
+}
+
+// StartCrawlers starts given number of crawlers that walk packages in the queue,
+// it does nothing if the queue or store is not set. Crawlers stop after Shutdown
+// is called, and put popped packages back to the queue.
+func StartCrawlers(n int) {
+	if crawlQueue == nil || docStore == nil {
+		return
+	}
+
+	atomic.AddInt32(&numCrawlers, int32(n))
+	for i := 0; i < n; i++ {
+		go func() {
+			defer atomic.AddInt32(&numCrawlers, -1)
+			for !isDraining() {
+				importPath, err := crawlQueue.Pop(time.Minute)
+				if err != nil {
+					log.Error(2, "Failed to pop crawl queue: %v", err)
+					time.Sleep(10 * time.Second)
+					continue
+				} else if len(importPath) == 0 {
+					continue
+				}
+
+				crawling.Store(importPath, true)
+				_, err = prewalk(importPath)
+				crawling.Delete(importPath)
+				if err == ErrShuttingDown {
+					if err = crawlQueue.Push(importPath); err != nil {
+						log.Error(2, "Failed to push %q back to crawl queue: %v", importPath, err)
+					}
+					return
+				} else if err == ErrWalkInProgress || err == ErrBlocked {
+					continue
+				} else if err != nil {
+					log.Trace("Crawler: failed to walk %q: %v", importPath, err)
+					continue
+				}
+				log.Trace("Crawler: checked %q", importPath)
+			}
+		}()
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// cspHash returns the CSP source expression that allows the inline content by its hash.
+func cspHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+}
+
+// CSPPolicy returns the recommended Content-Security-Policy of pages rendered by the
+// built-in renderers, which emit no inline scripts, event handlers or style attributes.
+// Styles of standalone and embedded pages are allowed by their hashes. Extra sources are
+// allowed for scripts and styles, e.g. hosts of distributed documentation files.
+func CSPPolicy(extraSrcs ...string) string {
+	extra := ""
+	if len(extraSrcs) > 0 {
+		extra = " " + strings.Join(extraSrcs, " ")
+	}
+	return strings.Join([]string{
+		"default-src 'self'",
+		"script-src 'self' https://www.google-analytics.com" + extra,
+		"style-src 'self' https://unpkg.com https://use.fontawesome.com " +
+			cspHash(pageStyle) + " " + cspHash(embedStyle) + extra,
+		"font-src 'self' https://use.fontawesome.com",
+		// Images of README and badges are hosted anywhere.
+		"img-src 'self' https: data:",
+		"connect-src 'self' https://www.google-analytics.com",
+		"object-src 'none'",
+		"base-uri 'self'",
+		"form-action 'self'",
+	}, "; ")
+}
@@ -0,0 +1,350 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Delta describes a package that is stored as changes to the package of a base version,
+// declarations and files that are unchanged are stored as references to the base.
+type Delta struct {
+	Base string // Version of the base package.
+	// References of each list in order.
+	Consts, Vars, Funcs, Types, Ifuncs, Itypes, Files, TestFiles []DeltaRef
+}
+
+// DeltaRef refers to an unchanged declaration or file of the base package.
+type DeltaRef struct {
+	Index     int        // Index in the list of the base package, -1 means stored in the package itself.
+	Positions []DeltaPos // Positions in this version.
+}
+
+// DeltaPos is the position of a declaration or file, which usually changes
+// between versions even if the content does not.
+type DeltaPos struct {
+	URL, RawURL string
+	Span
+}
+
+// deltaItem is an element of lists that can be delta encoded.
+type deltaItem interface {
+	deltaKey() string
+	positions() []DeltaPos
+	setPositions([]DeltaPos)
+}
+
+func (v *Value) deltaKey() string          { return v.Decl }
+func (v *Value) positions() []DeltaPos     { return []DeltaPos{{URL: v.URL, Span: v.Span}} }
+func (v *Value) setPositions(p []DeltaPos) { v.URL, v.Span = p[0].URL, p[0].Span }
+
+func (f *Func) deltaKey() string          { return f.Decl }
+func (f *Func) positions() []DeltaPos     { return []DeltaPos{{URL: f.URL, Span: f.Span}} }
+func (f *Func) setPositions(p []DeltaPos) { f.URL, f.Span = p[0].URL, p[0].Span }
+
+func (s *Source) deltaKey() string      { return s.SrcName }
+func (s *Source) positions() []DeltaPos { return []DeltaPos{{URL: s.BrowseUrl, RawURL: s.RawSrcUrl}} }
+func (s *Source) setPositions(p []DeltaPos) {
+	s.BrowseUrl, s.RawSrcUrl = p[0].URL, p[0].RawURL
+}
+
+func (t *Type) deltaKey() string { return t.Decl }
+
+// positions returns positions of the type and its associated declarations.
+func (t *Type) positions() []DeltaPos {
+	ps := []DeltaPos{{URL: t.URL, Span: t.Span}}
+	for _, v := range append(t.Consts, t.Vars...) {
+		ps = append(ps, v.positions()...)
+	}
+	for _, list := range [][]*Func{t.Funcs, t.Methods, t.IFuncs, t.IMethods} {
+		for _, f := range list {
+			ps = append(ps, f.positions()...)
+		}
+	}
+	return ps
+}
+
+func (t *Type) setPositions(ps []DeltaPos) {
+	t.URL, t.Span = ps[0].URL, ps[0].Span
+	ps = ps[1:]
+	for _, v := range append(t.Consts, t.Vars...) {
+		v.setPositions(ps[:1])
+		ps = ps[1:]
+	}
+	for _, list := range [][]*Func{t.Funcs, t.Methods, t.IFuncs, t.IMethods} {
+		for _, f := range list {
+			f.setPositions(ps[:1])
+			ps = ps[1:]
+		}
+	}
+}
+
+// sameContent returns true if two items are same except positions.
+func sameContent(a, b deltaItem) bool {
+	pa, pb := a.positions(), b.positions()
+	if len(pa) != len(pb) {
+		return false
+	}
+
+	a.setPositions(make([]DeltaPos, len(pa)))
+	b.setPositions(make([]DeltaPos, len(pb)))
+	same := reflect.DeepEqual(a, b)
+	a.setPositions(pa)
+	b.setPositions(pb)
+	return same
+}
+
+// deltaLists returns pointers to lists of the package that are delta encoded,
+// and the corresponding references of the delta.
+func deltaLists(p *PkgDecl, d *Delta) ([]interface{}, []*[]DeltaRef) {
+	return []interface{}{&p.Consts, &p.Vars, &p.Funcs, &p.Types, &p.Ifuncs, &p.Itypes, &p.Files, &p.TestFiles},
+		[]*[]DeltaRef{&d.Consts, &d.Vars, &d.Funcs, &d.Types, &d.Ifuncs, &d.Itypes, &d.Files, &d.TestFiles}
+}
+
+// encodeDelta returns the package as changes to the base package of given version,
+// and the fraction of items that are unchanged. It does not modify both packages.
+func encodeDelta(base *Package, baseVersion string, pdoc *Package) (*Package, float64) {
+	d := &Delta{Base: baseVersion}
+	decl := *pdoc.PkgDecl
+	decl.Delta = d
+
+	baseLists, _ := deltaLists(base.PkgDecl, new(Delta))
+	lists, refLists := deltaLists(&decl, d)
+	total, unchanged := 0, 0
+	for i := range lists {
+		baseList := reflect.ValueOf(baseLists[i]).Elem()
+		list := reflect.ValueOf(lists[i]).Elem()
+		if list.Len() == 0 {
+			continue
+		}
+
+		// Index items of the base by key, an item can only be referenced once.
+		candidates := make(map[string][]int)
+		for j := 0; j < baseList.Len(); j++ {
+			key := baseList.Index(j).Interface().(deltaItem).deltaKey()
+			candidates[key] = append(candidates[key], j)
+		}
+
+		refs := make([]DeltaRef, list.Len())
+		kept := reflect.MakeSlice(list.Type(), 0, 0)
+		for j := 0; j < list.Len(); j++ {
+			item := list.Index(j).Interface().(deltaItem)
+			refs[j].Index = -1
+			for k, idx := range candidates[item.deltaKey()] {
+				if sameContent(item, baseList.Index(idx).Interface().(deltaItem)) {
+					refs[j] = DeltaRef{Index: idx, Positions: item.positions()}
+					cands := candidates[item.deltaKey()]
+					candidates[item.deltaKey()] = append(cands[:k:k], cands[k+1:]...)
+					break
+				}
+			}
+			if refs[j].Index < 0 {
+				kept = reflect.Append(kept, list.Index(j))
+			} else {
+				unchanged++
+			}
+		}
+		total += list.Len()
+
+		*refLists[i] = refs
+		list.Set(kept)
+	}
+
+	delta := *pdoc
+	delta.PkgDecl = &decl
+	if total == 0 {
+		return &delta, 0
+	}
+	return &delta, float64(unchanged) / float64(total)
+}
+
+// decodeDelta reconstructs the package from its delta and the base package,
+// items of the base package are reused.
+func decodeDelta(base, delta *Package) (*Package, error) {
+	d := delta.Delta
+	decl := *delta.PkgDecl
+	decl.Delta = nil
+
+	baseLists, _ := deltaLists(base.PkgDecl, new(Delta))
+	lists, refLists := deltaLists(&decl, d)
+	for i := range lists {
+		baseList := reflect.ValueOf(baseLists[i]).Elem()
+		list := reflect.ValueOf(lists[i]).Elem()
+		refs := *refLists[i]
+		if len(refs) == 0 {
+			continue
+		}
+
+		full := reflect.MakeSlice(list.Type(), 0, len(refs))
+		next := 0
+		for _, ref := range refs {
+			if ref.Index < 0 {
+				if next >= list.Len() {
+					return nil, fmt.Errorf("missing changed item of list %d", i)
+				}
+				full = reflect.Append(full, list.Index(next))
+				next++
+				continue
+			}
+
+			if ref.Index >= baseList.Len() {
+				return nil, fmt.Errorf("reference %d out of range of list %d", ref.Index, i)
+			}
+			item := baseList.Index(ref.Index)
+			if len(item.Interface().(deltaItem).positions()) != len(ref.Positions) {
+				return nil, fmt.Errorf("mismatched positions of item %d of list %d", ref.Index, i)
+			}
+			item.Interface().(deltaItem).setPositions(ref.Positions)
+			full = reflect.Append(full, item)
+		}
+		list.Set(full)
+	}
+
+	pdoc := *delta
+	pdoc.PkgDecl = &decl
+	return &pdoc, nil
+}
+
+// minDeltaRatio is the minimum fraction of unchanged items for a version to be
+// stored as a delta, otherwise it is stored in full and becomes a new base.
+const minDeltaRatio = 0.5
+
+// DeltaDocStore stores tagged versions of packages as deltas to a full version of
+// the same package in the underlying store, and reconstructs them transparently.
+// Documentation of the default branch is always stored in full.
+type DeltaDocStore struct {
+	DocStore
+}
+
+// fullVersion returns the base of the newest tagged version of the package,
+// which is the version itself if it is stored in full.
+func (s DeltaDocStore) fullVersion(importPath string) (string, *Package, error) {
+	versions, err := storedVersions(s.DocStore, importPath)
+	if err != nil {
+		return "", nil, err
+	} else if len(versions) == 0 {
+		return "", nil, nil
+	}
+
+	version := versions[0]
+	pdoc, err := s.DocStore.Get(importPath, version)
+	if err != nil {
+		return "", nil, err
+	}
+	if pdoc.Delta != nil {
+		version = pdoc.Delta.Base
+		if pdoc, err = s.DocStore.Get(importPath, version); err != nil {
+			return "", nil, err
+		}
+	}
+	if pdoc.Delta != nil {
+		return "", nil, nil
+	}
+	return version, pdoc, nil
+}
+
+func (s DeltaDocStore) Get(importPath, version string) (*Package, error) {
+	pdoc, err := s.DocStore.Get(importPath, version)
+	if err != nil || pdoc.PkgDecl == nil || pdoc.Delta == nil {
+		return pdoc, err
+	}
+
+	base, err := s.DocStore.Get(importPath, pdoc.Delta.Base)
+	if err != nil {
+		return nil, fmt.Errorf("get base %s: %v", pdoc.Delta.Base, err)
+	}
+	return decodeDelta(base, pdoc)
+}
+
+// Put stores a new tagged version as a delta to the base of the newest version.
+// Versions stored in full are never stored again as deltas, because other versions
+// may be based on them, and stored deltas keep their bases.
+func (s DeltaDocStore) Put(pdoc *Package) error {
+	if pdoc.PkgDecl == nil || len(pdoc.Tag) == 0 {
+		return s.DocStore.Put(pdoc)
+	}
+
+	var (
+		baseVersion string
+		base        *Package
+	)
+	prev, err := s.DocStore.Get(pdoc.ImportPath, pdoc.Tag)
+	switch {
+	case err == nil && (prev.PkgDecl == nil || prev.Delta == nil):
+		return s.DocStore.Put(pdoc)
+	case err == nil:
+		baseVersion = prev.Delta.Base
+		if base, err = s.DocStore.Get(pdoc.ImportPath, baseVersion); err != nil {
+			return fmt.Errorf("get base %s: %v", baseVersion, err)
+		}
+	case err == ErrDocNotFound:
+		if baseVersion, base, err = s.fullVersion(pdoc.ImportPath); err != nil {
+			return fmt.Errorf("get base: %v", err)
+		}
+	default:
+		return fmt.Errorf("get stored version: %v", err)
+	}
+	if base == nil || base.PkgDecl == nil {
+		return s.DocStore.Put(pdoc)
+	}
+
+	delta, ratio := encodeDelta(base, baseVersion, pdoc)
+	if ratio < minDeltaRatio {
+		return s.DocStore.Put(pdoc)
+	}
+	return s.DocStore.Put(delta)
+}
+
+// Delete stores versions that are based on the deleted one in full before deleting it.
+func (s DeltaDocStore) Delete(importPath, version string) error {
+	if len(version) > 0 {
+		versions, err := storedVersions(s.DocStore, importPath)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range versions {
+			if v == version {
+				continue
+			}
+			pdoc, err := s.DocStore.Get(importPath, v)
+			if err != nil {
+				return err
+			} else if pdoc.PkgDecl == nil || pdoc.Delta == nil || pdoc.Delta.Base != version {
+				continue
+			}
+
+			if pdoc, err = s.Get(importPath, v); err != nil {
+				return err
+			} else if err = s.DocStore.Put(pdoc); err != nil {
+				return fmt.Errorf("store %s in full: %v", v, err)
+			}
+		}
+	}
+	return s.DocStore.Delete(importPath, version)
+}
+
+func (s DeltaDocStore) Versions(importPath string) ([]string, error) {
+	return storedVersions(s.DocStore, importPath)
+}
+
+// Sweep calls Sweep of the underlying store if it is a Sweeper.
+func (s DeltaDocStore) Sweep() error {
+	if sweeper, ok := s.DocStore.(Sweeper); ok {
+		return sweeper.Sweep()
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Unknwon/gowalker/models"
+)
+
+// deltaTestPackage returns the package of the version with functions of given
+// declarations, which are placed at the line of their indexes plus the offset.
+func deltaTestPackage(version string, offset int, decls ...string) *Package {
+	pdoc := &Package{
+		PkgInfo: &models.PkgInfo{ImportPath: "example.com/p"},
+		PkgDecl: &PkgDecl{Tag: version},
+	}
+	for i, decl := range decls {
+		line := i + offset
+		pdoc.Funcs = append(pdoc.Funcs, &Func{
+			Name: fmt.Sprintf("F%d", i),
+			Decl: decl,
+			URL:  fmt.Sprintf("https://example.com/p/blob/%s/p.go#L%d", version, line),
+			Span: Span{Filename: "p.go", Line: line, EndLine: line},
+		})
+	}
+	return pdoc
+}
+
+func deltaTestFuncs(pdoc *Package) []string {
+	funcs := make([]string, len(pdoc.Funcs))
+	for i, fn := range pdoc.Funcs {
+		funcs[i] = fmt.Sprintf("%s %s %s %d", fn.Name, fn.Decl, fn.URL, fn.Line)
+	}
+	return funcs
+}
+
+func TestDeltaDocStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "delta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := DeltaDocStore{NewVersionIndexDocStore(FileDocStore{Dir: dir})}
+
+	docs := map[string]*Package{
+		"v1.0.0": deltaTestPackage("v1.0.0", 1, "func A()", "func B()", "func C()", "func D()"),
+		// Declarations are moved and one of them is changed.
+		"v1.1.0": deltaTestPackage("v1.1.0", 10, "func A()", "func B(int)", "func C()", "func D()"),
+		// Declarations are mostly changed.
+		"v2.0.0": deltaTestPackage("v2.0.0", 1, "func A(int)", "func B(int)", "func C(int)", "func D()"),
+		"v2.1.0": deltaTestPackage("v2.1.0", 1, "func A(int)", "func B(int)", "func C(int)", "func D()"),
+	}
+	steps := []struct {
+		name    string
+		put     string // Version to put, or
+		delete  string // version to delete.
+		base    map[string]string
+		missing []string
+	}{
+		{
+			name: "first version is full",
+			put:  "v1.0.0",
+			base: map[string]string{"v1.0.0": ""},
+		},
+		{
+			name: "similar version is delta",
+			put:  "v1.1.0",
+			base: map[string]string{"v1.0.0": "", "v1.1.0": "v1.0.0"},
+		},
+		{
+			name: "changed version is full",
+			put:  "v2.0.0",
+			base: map[string]string{"v1.0.0": "", "v1.1.0": "v1.0.0", "v2.0.0": ""},
+		},
+		{
+			name: "new version is based on full version",
+			put:  "v2.1.0",
+			base: map[string]string{"v1.0.0": "", "v1.1.0": "v1.0.0", "v2.0.0": "", "v2.1.0": "v2.0.0"},
+		},
+		{
+			name: "full version stays full",
+			put:  "v1.0.0",
+			base: map[string]string{"v1.0.0": "", "v1.1.0": "v1.0.0", "v2.0.0": "", "v2.1.0": "v2.0.0"},
+		},
+		{
+			name: "delta stays on its base",
+			put:  "v1.1.0",
+			base: map[string]string{"v1.0.0": "", "v1.1.0": "v1.0.0", "v2.0.0": "", "v2.1.0": "v2.0.0"},
+		},
+		{
+			name:    "dependents are stored in full before deleting base",
+			delete:  "v1.0.0",
+			base:    map[string]string{"v1.1.0": "", "v2.0.0": "", "v2.1.0": "v2.0.0"},
+			missing: []string{"v1.0.0"},
+		},
+	}
+	for _, step := range steps {
+		if len(step.put) > 0 {
+			err = store.Put(docs[step.put])
+		} else {
+			err = store.Delete("example.com/p", step.delete)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+
+		for version, base := range step.base {
+			raw, err := store.DocStore.Get("example.com/p", version)
+			if err != nil {
+				t.Fatalf("%s: get raw %s: %v", step.name, version, err)
+			}
+			var rawBase string
+			if raw.Delta != nil {
+				rawBase = raw.Delta.Base
+			}
+			if rawBase != base {
+				t.Errorf("%s: base of %s is %q, want %q", step.name, version, rawBase, base)
+			}
+
+			pdoc, err := store.Get("example.com/p", version)
+			if err != nil {
+				t.Fatalf("%s: get %s: %v", step.name, version, err)
+			}
+			got, want := deltaTestFuncs(pdoc), deltaTestFuncs(docs[version])
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: functions of %s are %q, want %q", step.name, version, got, want)
+			}
+		}
+		for _, version := range step.missing {
+			if _, err = store.Get("example.com/p", version); err != ErrDocNotFound {
+				t.Errorf("%s: get %s: got error %v, want %v", step.name, version, err, ErrDocNotFound)
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	log "gopkg.in/clog.v1"
+)
+
+// DiagramRenderer renders diagrams in README and documentation comments.
+type DiagramRenderer interface {
+	// Render returns HTML of the diagram source in given language,
+	// which is either "mermaid" or "plantuml".
+	Render(lang, source string) (string, error)
+}
+
+var diagramRenderer DiagramRenderer
+
+// SetDiagramRenderer sets the renderer of diagrams, diagrams are left
+// as code blocks when no renderer is set.
+func SetDiagramRenderer(r DiagramRenderer) {
+	diagramRenderer = r
+}
+
+var (
+	// Code blocks of README rendered by GitHub, e.g. <pre lang="mermaid"><code>...</code></pre>.
+	fencedDiagramPattern = regexp.MustCompile(`(?s)<pre lang="(mermaid|plantuml)"><code>(.*?)</code></pre>` +
+		`|<div class="highlight highlight-source-(mermaid|plantuml)"><pre>(.*?)</pre></div>`)
+	// Code blocks of documentation comments.
+	preBlockPattern = regexp.MustCompile(`(?s)<pre>(.*?)</pre>`)
+	htmlTagPattern  = regexp.MustCompile(`<[^>]+>`)
+)
+
+var mermaidKeywords = []string{
+	"graph", "flowchart", "sequenceDiagram", "classDiagram", "stateDiagram",
+	"erDiagram", "gantt", "pie", "journey", "gitGraph", "mindmap"}
+
+// diagramLang returns the diagram language of the code block in documentation
+// comments, it returns empty string if the code block is not a diagram.
+func diagramLang(source string) string {
+	source = strings.TrimSpace(source)
+	if strings.HasPrefix(source, "@startuml") {
+		return "plantuml"
+	}
+
+	first := strings.Fields(source + " ")
+	if len(first) == 0 {
+		return ""
+	}
+	for _, keyword := range mermaidKeywords {
+		if first[0] == keyword || strings.HasPrefix(first[0], keyword+"-") {
+			return "mermaid"
+		}
+	}
+	return ""
+}
+
+// renderDiagram renders the diagram and returns the original block if fails.
+func renderDiagram(lang, source, block string) string {
+	source = html.UnescapeString(htmlTagPattern.ReplaceAllString(source, ""))
+	out, err := diagramRenderer.Render(lang, source)
+	if err != nil {
+		log.Trace("Failed to render %s diagram: %v", lang, err)
+		return block
+	}
+	return out
+}
+
+// renderReadmeDiagrams renders fenced diagram code blocks in rendered README.
+func renderReadmeDiagrams(data []byte) []byte {
+	if diagramRenderer == nil {
+		return data
+	}
+	return fencedDiagramPattern.ReplaceAllFunc(data, func(block []byte) []byte {
+		m := fencedDiagramPattern.FindSubmatch(block)
+		if len(m[1]) > 0 {
+			return []byte(renderDiagram(string(m[1]), string(m[2]), string(block)))
+		}
+		return []byte(renderDiagram(string(m[3]), string(m[4]), string(block)))
+	})
+}
+
+// renderDiagrams renders diagram code blocks in documentation comments in HTML.
+func renderDiagrams(doc string) string {
+	if diagramRenderer == nil || !strings.Contains(doc, "<pre>") {
+		return doc
+	}
+	return preBlockPattern.ReplaceAllStringFunc(doc, func(block string) string {
+		source := preBlockPattern.FindStringSubmatch(block)[1]
+		lang := diagramLang(html.UnescapeString(source))
+		if len(lang) == 0 {
+			return block
+		}
+		return renderDiagram(lang, source, block)
+	})
+}
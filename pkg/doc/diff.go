@@ -0,0 +1,153 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"sort"
+	"strings"
+)
+
+// Change represents a change of an exported identifier between two versions.
+type Change struct {
+	Name     string // e.g. "Client" or "Client.Do" for methods.
+	Kind     string // One of "const", "var", "func", "type" and "method".
+	Old, New string // Declarations, empty if the identifier does not exist.
+}
+
+// APIDiff contains changes of exported identifiers between two versions.
+type APIDiff struct {
+	Added, Removed, Changed []*Change
+}
+
+// Compatible returns true if no identifier is removed or changed.
+func (d *APIDiff) Compatible() bool {
+	return len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Empty returns true if there is no change.
+func (d *APIDiff) Empty() bool {
+	return d.Compatible() && len(d.Added) == 0
+}
+
+// normalizeDecl removes comments and formatting of the declaration,
+// so that only changes of the declaration itself are reported.
+func normalizeDecl(decl string) string {
+	var lines []string
+	for _, line := range strings.Split(decl, "\n") {
+		if i := strings.Index(line, "//"); i > -1 {
+			line = line[:i]
+		}
+		if line = strings.Join(strings.Fields(line), " "); len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	decl = strings.Join(lines, " ")
+	decl = strings.Replace(decl, "( ", "(", -1)
+	decl = strings.Replace(decl, ", )", ")", -1)
+	return strings.Replace(decl, " )", ")", -1)
+}
+
+// apiDecls returns declarations of exported identifiers of the package.
+func apiDecls(pdoc *Package) map[string]*Change {
+	decls := make(map[string]*Change)
+	if pdoc.PkgDecl == nil {
+		return decls
+	}
+
+	addValues := func(vals []*Value, kind string) {
+		for _, v := range vals {
+			for _, name := range v.Names() {
+				// Declarations of grouped values change whenever the group changes,
+				// use the line of the identifier instead.
+				decls[name] = &Change{Name: name, Kind: kind, Old: valueLine(v.Decl, name)}
+			}
+		}
+	}
+	addFuncs := func(funcs []*Func, recv string) {
+		kind := "func"
+		if len(recv) > 0 {
+			kind = "method"
+			recv += "."
+		}
+		for _, f := range funcs {
+			decls[recv+f.Name] = &Change{Name: recv + f.Name, Kind: kind, Old: f.Decl}
+		}
+	}
+
+	addValues(pdoc.Consts, "const")
+	addValues(pdoc.Vars, "var")
+	addFuncs(pdoc.Funcs, "")
+	for _, t := range pdoc.Types {
+		decls[t.Name] = &Change{Name: t.Name, Kind: "type", Old: t.Decl}
+		addValues(t.Consts, "const")
+		addValues(t.Vars, "var")
+		addFuncs(t.Funcs, "")
+		addFuncs(t.Methods, t.Name)
+	}
+	return decls
+}
+
+func sortChanges(changes []*Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+}
+
+// Diff returns changes of exported identifiers from old to new version of
+// a package. Both packages must not have been rendered, because rendering
+// overwrites declarations with HTML.
+func Diff(oldDoc, newDoc *Package) *APIDiff {
+	oldDecls, newDecls := apiDecls(oldDoc), apiDecls(newDoc)
+
+	d := new(APIDiff)
+	for name, c := range oldDecls {
+		nc, ok := newDecls[name]
+		switch {
+		case !ok:
+			d.Removed = append(d.Removed, c)
+		case normalizeDecl(c.Old) != normalizeDecl(nc.Old):
+			d.Changed = append(d.Changed, &Change{Name: name, Kind: c.Kind, Old: c.Old, New: nc.Old})
+		}
+	}
+	for name, c := range newDecls {
+		if _, ok := oldDecls[name]; !ok {
+			d.Added = append(d.Added, &Change{Name: name, Kind: c.Kind, New: c.Old})
+		}
+	}
+
+	sortChanges(d.Added)
+	sortChanges(d.Removed)
+	sortChanges(d.Changed)
+	return d
+}
+
+// valueLine returns the normalized line that declares the value in the declaration.
+func valueLine(decl, name string) string {
+	for _, line := range strings.Split(decl, "\n") {
+		line = normalizeDecl(line)
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ' ' || r == ','
+		})
+		for _, f := range fields {
+			if f == name {
+				return strings.TrimPrefix(strings.TrimPrefix(line, "const "), "var ")
+			}
+			if f == "=" {
+				break
+			}
+		}
+	}
+	return normalizeDecl(decl)
+}
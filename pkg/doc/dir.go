@@ -0,0 +1,86 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Unknwon/gowalker/models"
+)
+
+// isPackageDir returns true if the directory could contain packages that are importable.
+func isPackageDir(name string) bool {
+	return len(name) > 0 && name[0] != '_' && name[0] != '.' &&
+		name != "testdata" && name != "vendor"
+}
+
+// subdirectories returns direct subdirectories of the directory that contain Go files
+// in themselves or their descendants, paths are file paths relative to the repository root.
+func subdirectories(dirPrefix string, paths []string) []DirInfo {
+	infos := make(map[string]*DirInfo)
+CheckPath:
+	for _, p := range paths {
+		if !strings.HasPrefix(p, dirPrefix) || !strings.HasSuffix(p, ".go") {
+			continue
+		}
+
+		parts := strings.Split(p[len(dirPrefix):], "/")
+		if len(parts) == 1 {
+			continue
+		}
+		for _, part := range parts[:len(parts)-1] {
+			if !isPackageDir(part) {
+				continue CheckPath
+			}
+		}
+
+		info, ok := infos[parts[0]]
+		if !ok {
+			info = &DirInfo{Name: parts[0]}
+			infos[parts[0]] = info
+		}
+		if len(parts) == 2 && !strings.HasSuffix(p, "_test.go") {
+			info.HasGo = true
+		}
+	}
+
+	dirs := make([]DirInfo, 0, len(infos))
+	for _, info := range infos {
+		dirs = append(dirs, *info)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return dirs[i].Name < dirs[j].Name
+	})
+	return dirs
+}
+
+// subdirNames returns names of subdirectories.
+func subdirNames(dirs []DirInfo) []string {
+	names := make([]string, len(dirs))
+	for i := range dirs {
+		names[i] = dirs[i].Name
+	}
+	return names
+}
+
+// setSubdirSynopses sets synopses of subdirectories that have been walked before.
+func setSubdirSynopses(pdoc *Package) {
+	for i := range pdoc.Subdirectories {
+		if pinfo, err := models.GetPkgInfo(pdoc.ImportPath + "/" + pdoc.Subdirectories[i].Name); err == nil {
+			pdoc.Subdirectories[i].Synopsis = pinfo.Synopsis
+		}
+	}
+}
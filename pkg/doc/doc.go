@@ -16,7 +16,6 @@ package doc
 
 import (
 	"bytes"
-	"encoding/gob"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -176,6 +175,8 @@ func FormatCode(w io.Writer, code *string, links []*Link) {
 						fmt.Fprintf(w, `<a class="ext" title="%s" target="_blank" href="%s">%s</a>%s`,
 							link.Comment, link.Path, link.Name, seg[l-1:])
 					}
+				default:
+					fmt.Fprintf(w, "%s", seg)
 				}
 			} else if seg[len(seg)-1] == ' ' {
 				fmt.Fprintf(w, "<span id=\"%s\">%s</span> ", seg[:len(seg)-1], seg[:len(seg)-1])
@@ -215,7 +216,13 @@ func findType(name string, links []*Link) (*Link, bool) {
 			// Functions and types from imported packages.
 			if l.Name == left {
 				if len(l.Path) > 0 {
-					return &Link{Name: name, Path: "/" + l.Path + "#" + right}, true
+					url, err := ResolveRef(l.Path, right)
+					if err == ErrRefUnlinked {
+						return &Link{}, true
+					} else if err != nil {
+						url = "/" + l.Path + "#" + right
+					}
+					return &Link{Name: name, Path: url}, true
 				} else {
 					return &Link{Name: name, Path: "#" + right}, true
 				}
@@ -430,12 +437,31 @@ type exportSearchObject struct {
 	Title string `json:"title"`
 }
 
-// renderDoc renders and saves the documentation file,
-// and returns the new JSFile object corresponding to this generation.
-func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JSFile, error) {
+// RenderHTML renders documentation of the package to HTML, declarations of
+// the package are overwritten with HTML.
+func RenderHTML(render macaron.Render, pdoc *Package) ([]byte, error) {
+	if err := fireHooks(&Event{Phase: PhaseRender, ImportPath: pdoc.ImportPath, Pdoc: pdoc}); err != nil {
+		return nil, err
+	}
+
 	data := make(map[string]interface{})
 	data["PkgFullIntro"] = pdoc.Doc
 	data["IsGoRepo"] = pdoc.IsGoRepo
+	data["Advisories"] = pdoc.Advisories
+	data["Findings"] = pdoc.Findings
+	data["Deprecated"] = pdoc.Deprecated
+	data["SupersededBy"] = pdoc.SupersededBy
+	data["MajorVersions"] = pdoc.MajorVersions
+	if canonical := CanonicalPath(pdoc.ImportPath); canonical != pdoc.ImportPath {
+		data["Canonical"] = canonical
+	}
+	data["Release"] = pdoc.ReleaseNotes(pdoc.Tag)
+	data["Maintainers"] = pdoc.Maintainers
+	data["Citation"] = pdoc.Citation
+	data["Cost"] = pdoc.Cost
+	data["Coverage"] = pdoc.Coverage
+	data["HotPaths"] = pdoc.HotPaths
+	data["BazelTargets"] = pdoc.BazelTargets
 
 	exports := make([]exportSearchObject, 0, 10)
 
@@ -489,7 +515,8 @@ func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JS
 		pdoc.IsHasExport = true
 		data["IsHasExports"] = true
 		exportDataSrc, _ := json.Marshal(exports)
-		data["ExportDataSrc"] = "<script>var exportDataSrc = " + string(exportDataSrc) + ";</script>"
+		// Data blocks are not executed, so pages work with Content Security Policy without inline scripts.
+		data["ExportDataSrc"] = `<script type="application/json" id="_export_data">` + string(exportDataSrc) + "</script>"
 	}
 
 	pdoc.IsHasConst = len(pdoc.Consts) > 0
@@ -507,7 +534,7 @@ func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JS
 		if len(v.Doc) > 0 {
 			buf.Reset()
 			doc.ToHTML(&buf, v.Doc, nil)
-			v.Doc = buf.String()
+			v.Doc = SanitizeHTML(renderDiagrams(buf.String()))
 		}
 		buf.Reset()
 		v.Decl = template.HTMLEscapeString(v.Decl)
@@ -524,7 +551,7 @@ func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JS
 		if len(v.Doc) > 0 {
 			buf.Reset()
 			doc.ToHTML(&buf, v.Doc, nil)
-			v.Doc = buf.String()
+			v.Doc = SanitizeHTML(renderDiagrams(buf.String()))
 		}
 		buf.Reset()
 		FormatCode(&buf, &v.Decl, links)
@@ -532,6 +559,15 @@ func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JS
 		pdoc.Vars[i] = v
 	}
 
+	// Benchmarks that have results.
+	var benchs []*Benchmark
+	for _, b := range pdoc.Benchmarks {
+		if len(b.Results) > 0 {
+			benchs = append(benchs, b)
+		}
+	}
+	data["Benchmarks"] = benchs
+
 	// Files.
 	if len(pdoc.Files) > 0 {
 		pdoc.IsHasFile = true
@@ -551,7 +587,6 @@ func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JS
 		data["ViewFilePath"] = viewFilePath
 	}
 
-	var err error
 	renderFuncs(pdoc)
 
 	data["Funcs"] = pdoc.Funcs
@@ -559,7 +594,7 @@ func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JS
 		if len(f.Doc) > 0 {
 			buf.Reset()
 			doc.ToHTML(&buf, f.Doc, nil)
-			f.Doc = buf.String()
+			f.Doc = SanitizeHTML(renderDiagrams(buf.String()))
 		}
 		buf.Reset()
 		FormatCode(&buf, &f.Decl, links)
@@ -576,7 +611,7 @@ func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JS
 			if len(v.Doc) > 0 {
 				buf.Reset()
 				doc.ToHTML(&buf, v.Doc, nil)
-				v.Doc = buf.String()
+				v.Doc = SanitizeHTML(renderDiagrams(buf.String()))
 			}
 			buf.Reset()
 			v.Decl = template.HTMLEscapeString(v.Decl)
@@ -589,7 +624,7 @@ func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JS
 			if len(v.Doc) > 0 {
 				buf.Reset()
 				doc.ToHTML(&buf, v.Doc, nil)
-				v.Doc = buf.String()
+				v.Doc = SanitizeHTML(renderDiagrams(buf.String()))
 			}
 			buf.Reset()
 			FormatCode(&buf, &v.Decl, links)
@@ -601,7 +636,7 @@ func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JS
 			if len(f.Doc) > 0 {
 				buf.Reset()
 				doc.ToHTML(&buf, f.Doc, nil)
-				f.Doc = buf.String()
+				f.Doc = SanitizeHTML(renderDiagrams(buf.String()))
 			}
 			buf.Reset()
 			FormatCode(&buf, &f.Decl, links)
@@ -615,7 +650,7 @@ func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JS
 			if len(m.Doc) > 0 {
 				buf.Reset()
 				doc.ToHTML(&buf, m.Doc, nil)
-				m.Doc = buf.String()
+				m.Doc = SanitizeHTML(renderDiagrams(buf.String()))
 			}
 			buf.Reset()
 			FormatCode(&buf, &m.Decl, links)
@@ -628,7 +663,7 @@ func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JS
 		if len(t.Doc) > 0 {
 			buf.Reset()
 			doc.ToHTML(&buf, t.Doc, nil)
-			t.Doc = buf.String()
+			t.Doc = SanitizeHTML(renderDiagrams(buf.String()))
 		}
 		buf.Reset()
 		FormatCode(&buf, &t.Decl, links)
@@ -645,11 +680,40 @@ func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JS
 	})
 
 	for _, e := range pdoc.Examples {
+		if len(e.Doc) > 0 {
+			buf.Reset()
+			doc.ToHTML(&buf, e.Doc, nil)
+			e.Doc = SanitizeHTML(buf.String())
+		}
 		buf.Reset()
+		e.Code = template.HTMLEscapeString(e.Code)
 		FormatCode(&buf, &e.Code, links)
 		e.Code = buf.String()
 	}
 
+	// Usage samples.
+	for _, s := range pdoc.Samples {
+		buf.Reset()
+		s.Code = template.HTMLEscapeString(s.Code)
+		FormatCode(&buf, &s.Code, links)
+		s.Code = buf.String()
+	}
+	data["Samples"] = pdoc.Samples
+	for _, s := range pdoc.Snippets {
+		buf.Reset()
+		s.Code = template.HTMLEscapeString(s.Code)
+		FormatCode(&buf, &s.Code, links)
+		s.Code = buf.String()
+	}
+	data["Snippets"] = pdoc.Snippets
+	if qs := pdoc.QuickStart; qs != nil && len(qs.Code) > 0 {
+		buf.Reset()
+		qs.Code = template.HTMLEscapeString(qs.Code)
+		FormatCode(&buf, &qs.Code, links)
+		qs.Code = buf.String()
+	}
+	data["QuickStart"] = pdoc.QuickStart
+
 	data["ProjectPath"] = pdoc.ProjectPath
 	data["ImportPath"] = pdoc.ImportPath
 
@@ -659,6 +723,20 @@ func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JS
 	}
 
 	result, err := render.HTMLBytes("docs/tpl", data)
+	if err != nil {
+		return nil, err
+	}
+	e := &Event{Phase: PhaseRender, After: true, ImportPath: pdoc.ImportPath, Pdoc: pdoc, HTML: result}
+	if err = fireHooks(e); err != nil {
+		return nil, err
+	}
+	return e.HTML, nil
+}
+
+// renderDoc renders and saves the documentation file,
+// and returns the new JSFile object corresponding to this generation.
+func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JSFile, error) {
+	result, err := RenderHTML(render, pdoc)
 	if err != nil {
 		return nil, fmt.Errorf("rendering HTML: %v", err)
 	}
@@ -669,7 +747,6 @@ func renderDoc(render macaron.Render, pdoc *Package, docPath string) (*models.JS
 	}
 	SavePkgDoc(pdoc.ImportPath, pdoc.Readme)
 
-	data["UtcTime"] = time.Unix(pdoc.Created, 0).UTC()
 	return &models.JSFile{
 		Etag:          pdoc.Etag,
 		Status:        models.JSFileStatusGenerated,
@@ -684,29 +761,51 @@ const (
 	RequestTypeRefresh
 )
 
+// fetchDoc fetches and walks package from VCS with timeout.
+func fetchDoc(importPath, etag string) (*Package, error) {
+	c := make(chan crawlResult, 1)
+	go func() {
+		pdoc, err := crawlDoc(importPath, etag)
+		c <- crawlResult{pdoc, err}
+	}()
+
+	select {
+	case cr := <-c:
+		return cr.pdoc, cr.err
+	case <-time.After(setting.Current().FetchTimeout):
+		return nil, ErrFetchTimeout
+	}
+}
+
 // CheckPackage checks package by import path.
 func CheckPackage(importPath string, render macaron.Render, rt requestType) (*models.PkgInfo, error) {
 	// Trim prefix of standard library
 	importPath = strings.TrimPrefix(importPath, "github.com/golang/go/tree/master/src")
+	if newPath := LegacyImportPath(importPath); len(newPath) > 0 {
+		importPath = newPath
+	}
+	if IsBlocked(importPath) {
+		return nil, ErrBlocked
+	}
 
 	pinfo, err := models.GetPkgInfo(importPath)
 	if rt != RequestTypeRefresh {
 		if err == nil {
-			gobPath := setting.DocsGobPath + importPath + ".gob"
-			if !setting.ProdMode && com.IsFile(gobPath) {
-				pdoc := new(Package)
-				fr, err := os.Open(gobPath)
-				if err != nil {
-					return nil, fmt.Errorf("read gob: %v", err)
-				} else if err = gob.NewDecoder(fr).Decode(pdoc); err != nil {
-					fr.Close()
-					return nil, fmt.Errorf("decode gob: %v", err)
+			if docStore != nil {
+				if err := docStore.Touch(importPath, ""); err != nil && err != ErrDocNotFound {
+					log.Error(2, "Touch stored doc %q: %v", importPath, err)
 				}
-				fr.Close()
 
-				_, err = renderDoc(render, pdoc, importPath)
-				if err != nil {
-					return nil, fmt.Errorf("render cached doc: %v", err)
+				// Render stored documentation again to reflect changes of templates in development.
+				if !setting.ProdMode {
+					pdoc, err := docStore.Get(importPath, "")
+					if err == nil {
+						if _, err = renderDoc(render, pdoc, importPath); err != nil {
+							return nil, fmt.Errorf("render stored doc: %v", err)
+						}
+					} else if err != ErrDocNotFound {
+						return nil, fmt.Errorf("get stored doc: %v", err)
+					}
 				}
 			}
 
@@ -729,51 +828,53 @@ func CheckPackage(importPath string, render macaron.Render, rt requestType) (*mo
 		etag = pinfo.Etag
 	}
 
-	// Fetch package from VCS
-	c := make(chan crawlResult, 1)
-	go func() {
-		pdoc, err := crawlDoc(importPath, etag)
-		c <- crawlResult{pdoc, err}
-	}()
+	unlock, err := lockWalk(importPath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
+	// Use documentation that is walked by crawlers recently if possible.
 	var pdoc *Package
-	err = nil // Reset
-	select {
-	case cr := <-c:
-		if cr.err == nil {
-			pdoc = cr.pdoc
-		} else {
-			err = cr.err
+	if rt != RequestTypeRefresh {
+		pdoc = freshStoredDoc(importPath)
+	}
+	if pdoc == nil {
+		pdoc, err = fetchDoc(importPath, etag)
+		if err != ErrPackageNotModified {
+			countWalk(false, err)
+		}
+		if err != nil {
+			if err == ErrPackageNotModified {
+				log.Trace("Package has not been modified: %s", pinfo.ImportPath)
+				// Update time so cannot refresh too often
+				pinfo.Created = time.Now().UTC().Unix()
+				if stored := storedDoc(importPath); stored != nil && stored.Etag == etag {
+					if err = markUnchanged(stored); err != nil {
+						log.Error(2, "Failed to mark stored doc %q unchanged: %v", importPath, err)
+					}
+				}
+				return pinfo, models.SavePkgInfo(pinfo, false)
+			} else if err == ErrInvalidRemotePath {
+				return nil, ErrInvalidRemotePath // Allow caller to make redirect to search.
+			}
+			return nil, fmt.Errorf("check package: %v", err)
 		}
-	case <-time.After(setting.FetchTimeout):
-		err = ErrFetchTimeout
 	}
 
-	if err != nil {
-		if err == ErrPackageNotModified {
-			log.Trace("Package has not been modified: %s", pinfo.ImportPath)
-			// Update time so cannot refresh too often
-			pinfo.Created = time.Now().UTC().Unix()
-			return pinfo, models.SavePkgInfo(pinfo, false)
-		} else if err == ErrInvalidRemotePath {
-			return nil, ErrInvalidRemotePath // Allow caller to make redirect to search.
-		}
-		return nil, fmt.Errorf("check package: %v", err)
+	setSubdirSynopses(pdoc)
+	if indexer != nil {
+		indexer.Add(pdoc)
 	}
 
-	if !setting.ProdMode {
-		gobPath := setting.DocsGobPath + importPath + ".gob"
-		os.MkdirAll(path.Dir(gobPath), os.ModePerm)
-		fw, err := os.Create(gobPath)
-		if err != nil {
-			return nil, fmt.Errorf("create gob: %v", err)
-		}
-		defer fw.Close()
-		if err = gob.NewEncoder(fw).Encode(pdoc); err != nil {
-			return nil, fmt.Errorf("encode gob: %v", err)
+	if docStore != nil {
+		if err = putDoc(docStore, pdoc); err != nil {
+			return nil, fmt.Errorf("store doc: %v", err)
 		}
 	}
 
+	enqueueImports(pdoc)
+
 	log.Trace("Walked package %q, Goroutine #%d", pdoc.ImportPath, runtime.NumGoroutine())
 
 	jsFile, err := renderDoc(render, pdoc, importPath)
@@ -0,0 +1,167 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package doctest provides snapshot testing of documentation produced by the walker.
+// Packages of a corpus are walked and their JSON is compared with golden files, so
+// any change of the walker output shows up as a diff. Forks can run the same suite
+// on their own corpora with RunCorpus.
+package doctest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+// Update makes Compare rewrite golden files with current snapshots instead of
+// comparing, which is set by the -update flag of tests.
+var Update = flag.Bool("update", false, "rewrite golden files of snapshot tests")
+
+// rootPlaceholder replaces the root directory in snapshots.
+const rootPlaceholder = "$ROOT"
+
+// Snapshot returns indented JSON of the package. Paths under the root directory
+// are made relative to "$ROOT", and times and versions of the walker and toolchain
+// are cleared, so snapshots are the same on every machine.
+func Snapshot(pdoc *doc.Package, root string) ([]byte, error) {
+	p := *pdoc
+	if p.PkgInfo != nil {
+		info := *p.PkgInfo
+		info.Created = 0
+		info.LastViewed = 0
+		p.PkgInfo = &info
+	}
+	if p.Provenance != nil {
+		prov := *p.Provenance
+		prov.WalkerVersion = ""
+		prov.GoVersion = ""
+		prov.WalkedAt = time.Time{}
+		prov.WallTime = 0
+		p.Provenance = &prov
+	}
+
+	data, err := json.MarshalIndent(&p, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if len(root) == 0 {
+		return append(data, '\n'), nil
+	}
+
+	for _, dir := range []string{root, filepath.ToSlash(root)} {
+		quoted, _ := json.Marshal(dir)
+		data = bytes.Replace(data, quoted[1:len(quoted)-1], []byte(rootPlaceholder), -1)
+	}
+	return append(data, '\n'), nil
+}
+
+// diffLines returns a description of the first different line of two snapshots.
+func diffLines(got, want []byte) string {
+	gotLines := strings.Split(string(got), "\n")
+	wantLines := strings.Split(string(want), "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			return fmt.Sprintf("line %d:\n\tgot:  %s\n\twant: %s", i+1, g, w)
+		}
+	}
+	return ""
+}
+
+// Compare reports an error if the snapshot differs from the golden file. The
+// golden file is written instead when the update flag is set.
+func Compare(t testing.TB, golden string, snapshot []byte) {
+	t.Helper()
+
+	if *Update {
+		if err := os.MkdirAll(filepath.Dir(golden), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(golden, snapshot, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create golden files)", err)
+	}
+	if !bytes.Equal(snapshot, want) {
+		t.Errorf("snapshot differs from %s at %s", golden, diffLines(snapshot, want))
+	}
+}
+
+// Corpus returns names of packages in the corpus directory, each of which
+// is a subdirectory.
+func Corpus(dir string) ([]string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, fi := range fis {
+		if fi.IsDir() && !strings.HasPrefix(fi.Name(), ".") && !strings.HasPrefix(fi.Name(), "_") {
+			names = append(names, fi.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// RunCorpus walks every package of the corpus directory with import path
+// "<importPrefix>/<name>", and compares its snapshot with "<goldenDir>/<name>.json"
+// in a subtest named by the package.
+func RunCorpus(t *testing.T, corpusDir, goldenDir, importPrefix string) {
+	names, err := Corpus(corpusDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := filepath.Abs(corpusDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range names {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			pdoc, err := doc.WalkDir(filepath.Join(corpusDir, name), importPrefix+"/"+name)
+			if err != nil {
+				t.Fatalf("walk: %v", err)
+			}
+			snapshot, err := Snapshot(pdoc, root)
+			if err != nil {
+				t.Fatalf("snapshot: %v", err)
+			}
+			Compare(t, filepath.Join(goldenDir, name+".json"), snapshot)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+// Package cgo wraps functions of the C standard library.
+package cgo
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import "unsafe"
+
+// Buffer is memory allocated by C.
+type Buffer struct {
+	ptr  unsafe.Pointer
+	size int
+}
+
+// Alloc allocates a buffer of given size, which must be freed by Free.
+func Alloc(size int) *Buffer {
+	return &Buffer{ptr: C.malloc(C.size_t(size)), size: size}
+}
+
+// Free frees the buffer.
+func (b *Buffer) Free() {
+	C.free(b.ptr)
+}
+
+// Len returns size of the buffer.
+func (b *Buffer) Len() int {
+	return b.size
+}
@@ -0,0 +1,6 @@
+//go:build !cgo
+
+package cgo
+
+// Enabled reports whether the package is built with cgo.
+const Enabled = false
@@ -0,0 +1,14 @@
+// Package generated declares a type whose String method is generated.
+package generated
+
+//go:generate stringer -type=Color
+
+// Color is a color of traffic lights.
+type Color int
+
+// Colors of traffic lights.
+const (
+	Red Color = iota
+	Yellow
+	Green
+)
@@ -0,0 +1,55 @@
+// Package generics declares generic types and functions.
+package generics
+
+// Number is a constraint that permits any numeric type.
+type Number interface {
+	~int | ~int32 | ~int64 | ~float32 | ~float64
+}
+
+// Sum returns the sum of values.
+func Sum[T Number](values ...T) T {
+	var sum T
+	for _, v := range values {
+		sum += v
+	}
+	return sum
+}
+
+// Map returns results of calling fn with each element of s.
+func Map[S ~[]E, E, R any](s S, fn func(E) R) []R {
+	results := make([]R, 0, len(s))
+	for _, v := range s {
+		results = append(results, fn(v))
+	}
+	return results
+}
+
+// Pair holds a key and its value.
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// NewPair returns a pair of the key and the value.
+func NewPair[K comparable, V any](key K, value V) *Pair[K, V] {
+	return &Pair[K, V]{Key: key, Value: value}
+}
+
+// Swap returns a pair with the key and the value swapped.
+func (p *Pair[K, V]) Swap() *Pair[V, K] {
+	return nil
+}
+
+// Set is a set of comparable values.
+type Set[T comparable] map[T]struct{}
+
+// Add adds the value to the set.
+func (s Set[T]) Add(v T) {
+	s[v] = struct{}{}
+}
+
+// Has returns true if the set contains the value.
+func (s Set[T]) Has(v T) bool {
+	_, ok := s[v]
+	return ok
+}
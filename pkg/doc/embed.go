@@ -0,0 +1,69 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bytes"
+	"go/doc"
+	"html/template"
+	"io"
+)
+
+// Style of embedded pages, which is allowed by its hash in CSPPolicy.
+const embedStyle = `
+body { font: 14px/1.5 sans-serif; margin: 0; padding: 8px 12px; color: #333; }
+header { display: flex; justify-content: space-between; border-bottom: 1px solid #eee; padding-bottom: 4px; }
+header a { color: #375eab; text-decoration: none; font-weight: bold; }
+header span { color: #999; font-size: 12px; }
+pre { background: #f5f5f5; padding: 8px; margin: 8px 0; overflow-x: auto; font-size: 13px; }
+p { margin: 4px 0; }
+`
+
+var embedTpl = template.Must(template.New("embed").Funcs(template.FuncMap{
+	"comment": func(text string) template.HTML {
+		var buf bytes.Buffer
+		doc.ToHTML(&buf, text, nil)
+		return template.HTML(buf.String())
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.ImportPath}}.{{.Symbol.Name}} - Go Walker</title>
+<base target="_blank">
+<style>` + embedStyle + `</style>
+</head>
+<body>
+<header>
+<a href="{{.Link}}">{{.ImportPath}}.{{.Symbol.Name}}</a>
+<span>{{.Symbol.Kind}}{{if .Version}} · {{.Version}}{{end}} · Go Walker</span>
+</header>
+<pre>{{.Symbol.Decl}}</pre>
+{{comment .Symbol.Doc}}
+</body>
+</html>
+`))
+
+// WriteEmbed writes a compact HTML page of documentation of the symbol of the
+// package, which is meant to be embedded in iframes. The link refers to the
+// documentation page of the symbol.
+func WriteEmbed(w io.Writer, pdoc *Package, sym *SymbolInfo, link string) error {
+	return embedTpl.Execute(w, map[string]interface{}{
+		"ImportPath": pdoc.ImportPath,
+		"Version":    pdoc.Tag,
+		"Symbol":     sym,
+		"Link":       link,
+	})
+}
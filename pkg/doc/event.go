@@ -0,0 +1,292 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Unknwon/com"
+	log "gopkg.in/clog.v1"
+)
+
+// EventKind is the kind of a change of stored documentation.
+type EventKind string
+
+const (
+	EK_New     EventKind = "new"     // Default branch of a package is stored for the first time.
+	EK_Updated EventKind = "updated" // Source files of default branch of a package are changed.
+	EK_Version EventKind = "version" // A tagged version of a package is stored for the first time.
+)
+
+// DocEvent is a change of stored documentation.
+type DocEvent struct {
+	Kind       EventKind
+	ImportPath string
+	Version    string // Tag of the package, empty for the default branch.
+	Synopsis   string
+	Commit     string
+	Time       time.Time
+	API        *APIDiff // Changes of exported identifiers from the previous version, if any.
+}
+
+// EventLog keeps the latest events of stored documentation in memory,
+// which can be saved to and loaded from a gob file.
+// It is safe for concurrent use.
+type EventLog struct {
+	lock   sync.RWMutex
+	path   string // Where to save, empty means not persisted.
+	max    int
+	events []*DocEvent // Oldest first.
+	dirty  bool
+}
+
+// NewEventLog returns an empty event log in memory that keeps at most max events.
+func NewEventLog(max int) *EventLog {
+	return &EventLog{max: max}
+}
+
+// OpenEventLog returns the event log saved at given path, or an empty log
+// if the file does not exist.
+func OpenEventLog(filename string, max int) (*EventLog, error) {
+	l := NewEventLog(max)
+	l.path = filename
+	if !com.IsFile(filename) {
+		return l, nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open: %v", err)
+	}
+	defer f.Close()
+
+	if err = gob.NewDecoder(f).Decode(&l.events); err != nil {
+		return nil, fmt.Errorf("decode: %v", err)
+	}
+	l.trim()
+	return l, nil
+}
+
+func (l *EventLog) trim() {
+	if l.max > 0 && len(l.events) > l.max {
+		l.events = append([]*DocEvent(nil), l.events[len(l.events)-l.max:]...)
+	}
+}
+
+// Append adds an event to the log, dropping the oldest ones beyond the limit.
+func (l *EventLog) Append(e *DocEvent) {
+	l.lock.Lock()
+	l.events = append(l.events, e)
+	l.trim()
+	l.dirty = true
+	l.lock.Unlock()
+}
+
+// Recent returns at most n latest events of given kind, latest first.
+// Events are not filtered by import path if it is empty.
+func (l *EventLog) Recent(kind EventKind, importPath string, n int) []*DocEvent {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	var events []*DocEvent
+	for i := len(l.events) - 1; i >= 0 && len(events) < n; i-- {
+		e := l.events[i]
+		if e.Kind == kind && (len(importPath) == 0 || e.ImportPath == importPath) {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Between returns events that happened within [since, until), oldest first.
+func (l *EventLog) Between(since, until time.Time) []*DocEvent {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	var events []*DocEvent
+	for _, e := range l.events {
+		if !e.Time.Before(since) && e.Time.Before(until) {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Save writes events to the file where the log is opened
+// if it has been changed since last save.
+func (l *EventLog) Save() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if len(l.path) == 0 || !l.dirty {
+		return nil
+	}
+
+	os.MkdirAll(path.Dir(l.path), os.ModePerm)
+	tmpPath := l.path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("create: %v", err)
+	}
+	if err = gob.NewEncoder(f).Encode(l.events); err != nil {
+		f.Close()
+		return fmt.Errorf("encode: %v", err)
+	}
+	f.Close()
+
+	if err = os.Rename(tmpPath, l.path); err != nil {
+		return fmt.Errorf("rename: %v", err)
+	}
+	l.dirty = false
+	return nil
+}
+
+// EventHandler is called when documentation is stored with its event, and
+// changes of exported identifiers from the previous version if available.
+type EventHandler func(e *DocEvent, d *APIDiff)
+
+// EventDocStore records events to the log and calls the handler when
+// documentation is stored by the underlying DocStore. Either the log or
+// the handler may be nil.
+type EventDocStore struct {
+	DocStore
+	Events  *EventLog
+	Handler EventHandler
+}
+
+// docEvent returns the event of storing pdoc in place of prev, which is nil
+// if the documentation did not exist, or nil if nothing has changed.
+func docEvent(prev, pdoc *Package) *DocEvent {
+	e := &DocEvent{
+		ImportPath: pdoc.ImportPath,
+		Version:    pdoc.Tag,
+		Synopsis:   pdoc.Synopsis,
+		Time:       time.Now(),
+	}
+	if pdoc.Provenance != nil {
+		e.Commit = pdoc.Provenance.Commit
+	}
+
+	switch {
+	case prev != nil && len(pdoc.Tag) > 0:
+		return nil // Tagged versions never change.
+	case prev != nil:
+		if prev.Provenance != nil && pdoc.Provenance != nil &&
+			reflect.DeepEqual(prev.Provenance.FileHashes, pdoc.Provenance.FileHashes) {
+			return nil
+		}
+		e.Kind = EK_Updated
+	case len(pdoc.Tag) > 0:
+		e.Kind = EK_Version
+	default:
+		e.Kind = EK_New
+	}
+	return e
+}
+
+func (s EventDocStore) Put(pdoc *Package) error {
+	prev, err := s.DocStore.Get(pdoc.ImportPath, pdoc.Tag)
+	if err != nil && err != ErrDocNotFound {
+		log.Error(2, "Get stored doc %s@%s: %v", pdoc.ImportPath, pdoc.Tag, err)
+		prev = nil
+	}
+
+	if err = s.DocStore.Put(pdoc); err != nil {
+		return err
+	}
+	e := docEvent(prev, pdoc)
+	if e == nil {
+		return nil
+	}
+	e.API = s.apiDiff(e, prev, pdoc)
+	if s.Events != nil {
+		s.Events.Append(e)
+	}
+	if s.Handler != nil {
+		s.Handler(e, e.API)
+	}
+	return nil
+}
+
+// apiDiff returns changes of exported identifiers of the event from the previous
+// version, which is the last walk of default branch or the previous tagged version,
+// or nil if there is no previous version.
+func (s EventDocStore) apiDiff(e *DocEvent, prev, pdoc *Package) *APIDiff {
+	switch e.Kind {
+	case EK_Updated:
+		return Diff(prev, pdoc)
+	case EK_Version:
+		versions, err := storedVersions(s.DocStore, pdoc.ImportPath)
+		if err != nil {
+			log.Error(2, "List stored versions of %s: %v", pdoc.ImportPath, err)
+			return nil
+		}
+		for i, v := range versions {
+			if v != pdoc.Tag || i+1 >= len(versions) {
+				continue
+			}
+			old, err := s.DocStore.Get(pdoc.ImportPath, versions[i+1])
+			if err != nil {
+				log.Error(2, "Get stored doc %s@%s: %v", pdoc.ImportPath, versions[i+1], err)
+				return nil
+			}
+			return Diff(old, pdoc)
+		}
+	}
+	return nil
+}
+
+func (s EventDocStore) Versions(importPath string) ([]string, error) {
+	return storedVersions(s.DocStore, importPath)
+}
+
+func (s EventDocStore) Sweep() error {
+	if sweeper, ok := s.DocStore.(Sweeper); ok {
+		return sweeper.Sweep()
+	}
+	return nil
+}
+
+var eventLog *EventLog
+
+// SetEventLog sets the log of events to be served as feeds.
+func SetEventLog(l *EventLog) {
+	eventLog = l
+}
+
+// RecentEvents returns at most n latest events of given kind, latest first,
+// or nil if the event log is not set. Events are not filtered by import path
+// if it is empty.
+func RecentEvents(kind EventKind, importPath string, n int) []*DocEvent {
+	if eventLog == nil {
+		return nil
+	}
+	return eventLog.Recent(kind, importPath, n)
+}
+
+// EventsBetween returns events that happened within [since, until), oldest first,
+// or nil if the event log is not set.
+func EventsBetween(since, until time.Time) []*DocEvent {
+	if eventLog == nil {
+		return nil
+	}
+	return eventLog.Between(since, until)
+}
@@ -0,0 +1,155 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// FailureMode is a panic or a returned sentinel error of a function.
+type FailureMode struct {
+	Value string // Argument of panic or name of the error, e.g. "io.EOF".
+	Cond  string // Condition of the enclosing if statement or case clause, empty if unknown.
+}
+
+// failureModes contains failure modes of a function declaration.
+type failureModes struct {
+	Panics, Errors []FailureMode
+}
+
+// maxFailureValue is the maximum length of printed panic arguments.
+const maxFailureValue = 80
+
+func shortExpr(x ast.Expr) string {
+	s := types.ExprString(x)
+	if len(s) > maxFailureValue {
+		s = s[:maxFailureValue-3] + "..."
+	}
+	return s
+}
+
+// enclosingCond returns the condition under which the node at the top of the stack
+// is executed, according to the innermost if statement or case clause.
+func enclosingCond(stack []ast.Node) string {
+	for i := len(stack) - 2; i >= 0; i-- {
+		child := stack[i+1]
+		switch n := stack[i].(type) {
+		case *ast.IfStmt:
+			if child == n.Body {
+				return shortExpr(n.Cond)
+			}
+			return ""
+		case *ast.CaseClause:
+			if len(n.List) == 0 || i < 2 {
+				return ""
+			}
+			// Only tagless switch has conditions as case expressions.
+			if sw, ok := stack[i-2].(*ast.SwitchStmt); ok && sw.Tag == nil {
+				conds := make([]string, len(n.List))
+				for j, x := range n.List {
+					conds[j] = shortExpr(x)
+				}
+				return strings.Join(conds, " || ")
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// isSentinelError returns true if the expression refers to a package-level variable
+// like ErrNotFound or io.EOF.
+func (w *Walker) isSentinelError(x ast.Expr) bool {
+	var ident *ast.Ident
+	switch x := x.(type) {
+	case *ast.Ident:
+		ident = x
+	case *ast.SelectorExpr:
+		if pkg, ok := x.X.(*ast.Ident); !ok || pkg.Obj == nil || pkg.Obj.Kind != ast.Pkg {
+			return false
+		}
+		ident = x.Sel
+	default:
+		return false
+	}
+
+	// Type information decides when it is available.
+	if w.info != nil {
+		if obj := w.info.Uses[ident]; obj != nil {
+			v, ok := obj.(*types.Var)
+			return ok && v.Pkg() != nil && v.Parent() == v.Pkg().Scope() &&
+				types.Implements(v.Type(), errorInterface)
+		}
+	}
+	return strings.HasPrefix(ident.Name, "Err") || ident.Name == "EOF"
+}
+
+var errorInterface = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+
+func appendFailure(modes []FailureMode, mode FailureMode) []FailureMode {
+	for _, m := range modes {
+		if m == mode {
+			return modes
+		}
+	}
+	return append(modes, mode)
+}
+
+// funcFailures returns panics and returned sentinel errors of function declarations
+// by their start positions, calls in function literals are not counted. It must be
+// called before function bodies are trimmed by go/doc.
+func (w *Walker) funcFailures(files map[string]*ast.File) map[token.Pos]*failureModes {
+	failures := make(map[token.Pos]*failureModes)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fdecl, ok := decl.(*ast.FuncDecl)
+			if !ok || fdecl.Body == nil {
+				continue
+			}
+
+			modes := new(failureModes)
+			var stack []ast.Node
+			ast.Inspect(fdecl.Body, func(node ast.Node) bool {
+				if node == nil {
+					stack = stack[:len(stack)-1]
+					return true
+				}
+				if _, ok := node.(*ast.FuncLit); ok {
+					return false
+				}
+				stack = append(stack, node)
+
+				switch n := node.(type) {
+				case *ast.CallExpr:
+					if fun, ok := n.Fun.(*ast.Ident); ok && fun.Name == "panic" && fun.Obj == nil && len(n.Args) == 1 {
+						modes.Panics = appendFailure(modes.Panics, FailureMode{shortExpr(n.Args[0]), enclosingCond(stack)})
+					}
+				case *ast.ReturnStmt:
+					if len(n.Results) > 0 && w.isSentinelError(n.Results[len(n.Results)-1]) {
+						modes.Errors = appendFailure(modes.Errors, FailureMode{shortExpr(n.Results[len(n.Results)-1]), enclosingCond(stack)})
+					}
+				}
+				return true
+			})
+			if len(modes.Panics)+len(modes.Errors) > 0 {
+				failures[fdecl.Pos()] = modes
+			}
+		}
+	}
+	return failures
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzSynopsis(f *testing.F) {
+	f.Add("Package doc walks Go packages. It does more.")
+	f.Add("Package 中文 是一个 示例。第二句。")
+	f.Add("# Heading\n\nParagraph.")
+	f.Add(strings.Repeat("word ", 100))
+	f.Add(strings.Repeat("汉字", 200))
+	f.Fuzz(func(t *testing.T, s string) {
+		syn := synopsis(s)
+		if !utf8.ValidString(syn) {
+			t.Errorf("synopsis(%q) = %q is not valid UTF-8", s, syn)
+		}
+		if len(syn) > 301 {
+			t.Errorf("synopsis(%q) has %d bytes", s, len(syn))
+		}
+	})
+}
+
+func FuzzPrintCode(f *testing.F) {
+	f.Add("package p\n\nfunc F() {\n\treturn\n}\n")
+	f.Add("package p\n\nfunc F() { return }\n")
+	f.Add("package p\n\nfunc (T) M(\n) {\n}\n")
+	f.Add("package p\n\n//line p.go:1\nfunc F() {\n}\n")
+	f.Fuzz(func(t *testing.T, src string) {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+		if err != nil {
+			return
+		}
+
+		w := &Walker{
+			Fset: fset,
+			SrcFiles: map[string]*Source{
+				"p.go": {SrcName: "p.go", BrowseUrl: "p.go", SrcData: []byte(src)},
+			},
+			SrcLines: make(map[string][]string),
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				w.printCode(fn)
+			}
+		}
+	})
+}
+
+func FuzzReadme(f *testing.F) {
+	f.Add("README.md", []byte("# Title\n\n```go\nfmt.Println()\n```\n"))
+	f.Add("readme_zh.md", []byte("~~~golang\nx := 1\n~~~~\n```\n"))
+	f.Add("README", []byte("````go\n```\n````"))
+	f.Fuzz(func(t *testing.T, name string, data []byte) {
+		switch lang := readmeLang(name); lang {
+		case "", "en", "zh":
+		default:
+			t.Errorf("readmeLang(%q) = %q", name, lang)
+		}
+		for _, block := range readmeCodeBlocks(data) {
+			if len(block) > len(data) {
+				t.Errorf("code block %q is longer than README", block)
+			}
+		}
+	})
+}
+
+func FuzzReadZip(f *testing.F) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if w, err := zw.Create("repo-master/p.go"); err == nil {
+		w.Write([]byte("package p\n"))
+	}
+	zw.Close()
+	f.Add(buf.Bytes())
+
+	buf.Reset()
+	zw = zip.NewWriter(&buf)
+	if w, err := zw.Create("../p.go"); err == nil {
+		w.Write([]byte("package p\n"))
+	}
+	zw.Close()
+	f.Add(buf.Bytes())
+	f.Fuzz(func(t *testing.T, data []byte) {
+		files, _ := readZip(data)
+		checkArchiveFiles(t, files)
+	})
+}
+
+// checkArchiveFiles checks files read from an archive are within the limits.
+func checkArchiveFiles(t *testing.T, files []*archiveFile) {
+	if len(files) > maxArchiveEntries {
+		t.Errorf("read %d files", len(files))
+	}
+	total := 0
+	for _, f := range files {
+		total += len(f.data)
+		if strings.HasPrefix(f.name, "/") || strings.Contains(f.name, "\\") || path.Clean("/"+f.name) != "/"+f.name {
+			t.Errorf("unsafe file name %q", f.name)
+		}
+	}
+	if total > maxArchiveSize {
+		t.Errorf("read %d bytes", total)
+	}
+}
+
+func FuzzReadTarGz(f *testing.F) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	content := []byte("package p\n")
+	tw.WriteHeader(&tar.Header{Name: "repo-master/p.go", Mode: 0644, Size: int64(len(content))})
+	tw.Write(content)
+	tw.Close()
+	gw.Close()
+	f.Add(buf.Bytes())
+	f.Fuzz(func(t *testing.T, data []byte) {
+		files, _ := readTarGz(data)
+		checkArchiveFiles(t, files)
+	})
+}
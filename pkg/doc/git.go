@@ -0,0 +1,128 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build !js
+
+package doc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Unknwon/com"
+	log "gopkg.in/clog.v1"
+)
+
+// Functions that run git commands, which are not available in browsers.
+
+var lsremoteRe = regexp.MustCompile(`(?m)^([0-9a-f]{40})\s+refs/(?:tags|heads)/(.+)$`)
+
+func downloadGit(schemes []string, repo, savedEtag string) (string, string, error) {
+	var p []byte
+	var scheme string
+	for i := range schemes {
+		cmd := gitCommand(schemes[i], repo, "ls-remote", "--heads", "--tags", schemes[i]+"://"+repo+".git")
+		var err error
+		p, err = cmd.Output()
+		if err == nil {
+			scheme = schemes[i]
+			break
+		}
+	}
+
+	if scheme == "" {
+		return "", "", com.NotFoundError{"VCS not found"}
+	}
+
+	tags := make(map[string]string)
+	for _, m := range lsremoteRe.FindAllSubmatch(p, -1) {
+		tags[string(m[2])] = string(m[1])
+	}
+
+	tag, commit, err := bestTag(tags, "master")
+	if err != nil {
+		return "", "", err
+	}
+
+	etag := scheme + "-" + commit
+
+	if etag == savedEtag {
+		return "", "", ErrPackageNotModified
+	}
+
+	dir := path.Join(repoRoot, repo+".git")
+	p, err = ioutil.ReadFile(path.Join(dir, ".git/HEAD"))
+	switch {
+	case err != nil:
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return "", "", err
+		}
+		cmd := gitCommand(scheme, repo, "clone", scheme+"://"+repo, dir)
+		if err := cmd.Run(); err != nil {
+			return "", "", err
+		}
+	case string(bytes.TrimRight(p, "\n")) == commit:
+		return tag, etag, nil
+	default:
+		cmd := gitCommand(scheme, repo, "fetch")
+		cmd.Dir = dir
+		if err := cmd.Run(); err != nil {
+			return "", "", err
+		}
+	}
+
+	cmd := exec.Command("git", "checkout", "--detach", "--force", commit)
+	cmd.Dir = dir
+	if err := cmd.Run(); err != nil {
+		return "", "", err
+	}
+
+	return tag, etag, nil
+}
+
+// gitCommand returns the git command that accesses the remote repository,
+// credentials of the host are sent as an HTTP header over HTTPS.
+func gitCommand(scheme, repo string, args ...string) *exec.Cmd {
+	var config []string
+	if scheme == "https" {
+		host := repo
+		if i := strings.Index(host, "/"); i >= 0 {
+			host = host[:i]
+		}
+		if username, password, ok := credentials(repo); ok {
+			auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+			config = []string{"-c", "http.https://" + host + "/.extraHeader=Authorization: Basic " + auth}
+		}
+	}
+
+	// Credentials are not logged.
+	log.Trace("git %s", strings.Join(args, " "))
+	cmd := exec.Command("git", append(config, args...)...)
+	// Fail instead of waiting for input when credentials are required.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	return cmd
+}
+
+// gitBlame returns output of "git blame --line-porcelain" of the file in the directory.
+func gitBlame(dir, name string) ([]byte, error) {
+	cmd := exec.Command("git", "blame", "--line-porcelain", "--", name)
+	cmd.Dir = dir
+	return cmd.Output()
+}
@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"path"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -72,6 +73,36 @@ type RepoCommit struct {
 	} `json:"commit"`
 }
 
+// getGitHubTag returns the tag of the commit in the GitHub repository,
+// or empty string if the commit is not tagged by recent tags.
+func getGitHubTag(match map[string]string, commit string) (string, error) {
+	var tags []struct {
+		Name   string `json:"name"`
+		Commit struct {
+			Sha string `json:"sha"`
+		} `json:"commit"`
+	}
+	if err := com.HttpGetJSON(Client,
+		com.Expand("https://api.github.com/repos/{owner}/{repo}/tags?per_page=100&{cred}", match), &tags); err != nil {
+		return "", fmt.Errorf("get tags: %v", err)
+	}
+
+	var tagged []*DocMeta
+	for _, t := range tags {
+		if t.Commit.Sha == commit {
+			tagged = append(tagged, &DocMeta{Version: t.Name})
+		}
+	}
+	if len(tagged) == 0 {
+		return "", nil
+	}
+	// Prefer the newest semantic version when the commit has several tags.
+	sort.Slice(tagged, func(i, j int) bool {
+		return newerVersion(tagged[i], tagged[j])
+	})
+	return tagged[0].Version, nil
+}
+
 func getGitHubDoc(match map[string]string, etag string) (_ *Package, err error) {
 	match["cred"] = setting.GitHubCredentials
 
@@ -85,6 +116,15 @@ func getGitHubDoc(match map[string]string, etag string) (_ *Package, err error)
 		match["tag"] = repoInfo.DefaultBranch
 	}
 
+	// Check revision once the branch is known, so unchanged repositories are cheap to check.
+	commit, err := getGithubRevision(com.Expand("github.com/{owner}/{repo}", match), match["tag"])
+	if err != nil {
+		return nil, fmt.Errorf("get revision: %v", err)
+	}
+	if commit == etag {
+		return nil, ErrPackageNotModified
+	}
+
 	// Check if last commit time is behind upstream for fork repository.
 	if repoInfo.Fork {
 		url := com.Expand("https://api.github.com/repos/{owner}/{repo}/commits?per_page=1&{cred}", match)
@@ -111,38 +151,13 @@ func getGitHubDoc(match map[string]string, etag string) (_ *Package, err error)
 		}
 	}
 
-	// Check revision.
-	var commit string
-	if strings.HasPrefix(match["importPath"], "gopkg.in") {
-		// FIXME: get commit ID of gopkg.in indepdently.
-		var obj struct {
-			Sha string `json:"sha"`
-		}
-
-		if err := com.HttpGetJSON(Client,
-			com.Expand("https://gopm.io/api/v1/revision?pkgname={importPath}", match), &obj); err != nil {
-			return nil, fmt.Errorf("get gopkg.in revision: %v", err)
-		}
-
-		commit = obj.Sha
-		match["tag"] = commit
-		log.Trace("Import path %q found commit: %s", match["importPath"], commit)
-	} else {
-		commit, err = getGithubRevision(com.Expand("github.com/{owner}/{repo}", match), match["tag"])
-		if err != nil {
-			return nil, fmt.Errorf("get revision: %v", err)
-		}
-		if commit == etag {
-			return nil, ErrPackageNotModified
-		}
-	}
-
 	// Get files.
 	var tree struct {
 		Tree []struct {
 			Url  string
 			Path string
 			Type string
+			Sha  string
 		}
 		Url string
 	}
@@ -159,60 +174,143 @@ func getGitHubDoc(match map[string]string, etag string) (_ *Package, err error)
 	}
 
 	// Get source file data and subdirectories.
+	treeDirs := make(map[string]bool)
+	goMods := make([]string, 0, 3)
+	for _, node := range tree.Tree {
+		switch {
+		case node.Type == "tree":
+			treeDirs[node.Path] = true
+		case path.Base(node.Path) == "go.mod":
+			goMods = append(goMods, node.Path)
+		}
+	}
+
 	dirPrefix := match["dir"]
 	if dirPrefix != "" {
-		dirPrefix = dirPrefix[1:] + "/"
+		dirPrefix = majorVersionDir(dirPrefix[1:], func(dir string) bool {
+			return treeDirs[dir]
+		})
+		if dirPrefix != "" {
+			dirPrefix += "/"
+		}
 	}
-	dirLevel := len(strings.Split(dirPrefix, "/"))
-	dirLength := len(dirPrefix)
-	dirMap := make(map[string]bool)
 	files := make([]com.RawFile, 0, 10)
+	paths := make([]string, 0, len(tree.Tree))
 
 	for _, node := range tree.Tree {
 		// Skip directories and files in wrong directories, get them later.
 		if node.Type != "blob" || !strings.HasPrefix(node.Path, dirPrefix) {
 			continue
 		}
+		paths = append(paths, node.Path)
 
-		// Get files and check if directories have acceptable files.
-		if d, f := path.Split(node.Path); base.IsDocFile(f) {
-			// Check if file is in the directory that is corresponding to import path.
-			if d == dirPrefix {
-				files = append(files, &Source{
-					SrcName:   f,
-					BrowseUrl: com.Expand("github.com/{owner}/{repo}/blob/{tag}/{0}", match, node.Path),
-					RawSrcUrl: com.Expand("https://raw.github.com/{owner}/{repo}/{tag}/{0}?{1}", match, node.Path, setting.GitHubCredentials),
-				})
-				continue
+		// Get files that are in the directory corresponding to import path.
+		if d, f := path.Split(node.Path); d == dirPrefix && (base.IsDocFile(f) || base.IsMetaFile(f)) {
+			browseUrl := com.Expand("github.com/{owner}/{repo}/blob/{tag}/{0}", match, node.Path)
+			if len(match["browseTpl"]) > 0 {
+				browseUrl = com.Expand(match["browseTpl"], nil, f)
 			}
+			files = append(files, &Source{
+				SrcName:   f,
+				BrowseUrl: browseUrl,
+				RawSrcUrl: com.Expand("https://raw.github.com/{owner}/{repo}/{tag}/{0}?{1}", match, node.Path, setting.GitHubCredentials),
+				Hash:      node.Sha,
+			})
+		}
+	}
 
-			// Otherwise, check if it's a direct sub-directory of import path.
-			if len(strings.Split(d, "/"))-dirLevel == 1 {
-				dirMap[d[dirLength:len(d)-1]] = true
-				continue
-			}
+	subdirs := subdirectories(dirPrefix, paths)
+	if len(files) == 0 && len(subdirs) == 0 {
+		return nil, ErrPackageNoGoFile
+	}
+
+	// Licenses, changelogs, maintainers, citations and go.mod of subdirectories are usually placed in root directory.
+	hasGoMod := false
+	for _, f := range files {
+		if f.Name() == "go.mod" {
+			hasGoMod = true
+			break
 		}
 	}
+	for _, node := range tree.Tree {
+		if node.Type != "blob" {
+			continue
+		}
 
-	dirs := base.MapToSortedStrings(dirMap)
+		isRoot := !strings.Contains(node.Path, "/")
+		switch {
+		case isRoot && len(dirPrefix) == 0:
+			// Files of root directory have been collected.
+			continue
+		case isRoot && (base.IsLicenseFile(node.Path) || base.IsChangelogFile(node.Path) ||
+			base.IsMaintainerFile(node.Path) || node.Path == "CITATION.cff" ||
+			(node.Path == "go.mod" && !hasGoMod)):
+		case node.Path == ".github/CODEOWNERS" || node.Path == "docs/CODEOWNERS":
+		default:
+			continue
+		}
 
-	if len(files) == 0 && len(dirs) == 0 {
-		return nil, ErrPackageNoGoFile
-	} else if err := com.FetchFiles(Client, files, githubRawHeader); err != nil {
+		files = append(files, &Source{
+			SrcName:   path.Base(node.Path),
+			BrowseUrl: com.Expand("github.com/{owner}/{repo}/blob/{tag}/{0}", match, node.Path),
+			RawSrcUrl: com.Expand("https://raw.github.com/{owner}/{repo}/{tag}/{0}?{1}", match, node.Path, setting.GitHubCredentials),
+			Hash:      node.Sha,
+		})
+	}
+
+	if err := fetchFiles(Client, files, githubRawHeader); err != nil {
 		return nil, fmt.Errorf("fetch files: %v", err)
 	}
 
+	// Usage samples may be placed in any directory of the repository.
+	blobs := make([]string, 0, len(tree.Tree))
+	blobHashes := make(map[string]string, len(tree.Tree))
+	for _, node := range tree.Tree {
+		if node.Type == "blob" {
+			blobs = append(blobs, node.Path)
+			blobHashes[node.Path] = node.Sha
+		}
+	}
+	var sampleSrcs []*Source
+	var sampleFetches []com.RawFile
+	for _, p := range sampleFiles(blobs) {
+		src := &Source{
+			SrcName:   p,
+			BrowseUrl: com.Expand("github.com/{owner}/{repo}/blob/{tag}/{0}", match, p),
+			RawSrcUrl: com.Expand("https://raw.github.com/{owner}/{repo}/{tag}/{0}?{1}", match, p, setting.GitHubCredentials),
+			Hash:      blobHashes[p],
+		}
+		sampleSrcs = append(sampleSrcs, src)
+		sampleFetches = append(sampleFetches, src)
+	}
+	if len(sampleFetches) > 0 {
+		if err := fetchFiles(Client, sampleFetches, githubRawHeader); err != nil {
+			log.Warn("Failed to fetch usage samples of %q: %v", match["importPath"], err)
+			sampleSrcs = nil
+		}
+	}
+
 	// Start generating data.
 	// IsGoSubrepo check has been placed to crawl.getDynamic.
+	lineFmt, viewDirPath := "#L%d", com.Expand("github.com/{owner}/{repo}/tree/{tag}/{importPath}", match)
+	if len(match["browseTpl"]) > 0 {
+		lineFmt = match["lineFmt"]
+	}
+	if len(match["viewDirPath"]) > 0 {
+		viewDirPath = match["viewDirPath"]
+	}
+
 	w := &Walker{
-		LineFmt: "#L%d",
+		Fetcher: "github",
+		Ref:     match["tag"],
+		LineFmt: lineFmt,
 		Pdoc: &Package{
 			PkgInfo: &models.PkgInfo{
 				ImportPath:  match["importPath"],
 				ProjectPath: com.Expand("github.com/{owner}/{repo}", match),
-				ViewDirPath: com.Expand("github.com/{owner}/{repo}/tree/{tag}/{importPath}", match),
+				ViewDirPath: viewDirPath,
 				Etag:        commit,
-				Subdirs:     strings.Join(dirs, "|"),
+				Subdirs:     strings.Join(subdirNames(subdirs), "|"),
 			},
 		},
 	}
@@ -229,15 +327,44 @@ func getGitHubDoc(match map[string]string, etag string) (_ *Package, err error)
 	}
 
 	pdoc, err := w.Build(&WalkRes{
-		WalkDepth: WD_All,
-		WalkType:  WT_Memory,
-		WalkMode:  WM_All,
-		Srcs:      srcs,
+		WalkDepth:  WD_All,
+		WalkType:   WT_Memory,
+		WalkMode:   defaultWalkMode(),
+		Srcs:       srcs,
+		SampleSrcs: sampleSrcs,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error walking package: %v", err)
 	}
 
+	pdoc.Subdirectories = subdirs
+
+	// The walked commit is also stored as the tagged version.
+	if pdoc.Tag, err = getGitHubTag(match, commit); err != nil {
+		log.Warn("Failed to get tags of %q: %v", pdoc.ImportPath, err)
+	}
+
+	// Releases are only fetched when the revision has changed, see the etag check above.
+	releases, err := getGitHubReleases(match, commit)
+	if err != nil {
+		log.Warn("Failed to get releases of %q: %v", pdoc.ImportPath, err)
+	}
+	pdoc.Changelog = mergeReleases(pdoc.Changelog, releases)
+
+	if setting.GitHubFetchContributors && len(pdoc.Maintainers) == 0 {
+		contributors, err := getGitHubContributors(match)
+		if err != nil {
+			log.Warn("Failed to get contributors of %q: %v", pdoc.ImportPath, err)
+		}
+		pdoc.Maintainers = append(pdoc.Maintainers, contributors...)
+	}
+	pdoc.MajorVersions = majorVersions(pdoc.ImportPath, pdoc.ProjectPath, goMods)
+
+	if repoInfo.Fork && len(repoInfo.Parent.FullName) > 0 {
+		pdoc.ForkOf = "github.com/" + repoInfo.Parent.FullName +
+			strings.TrimPrefix(pdoc.ImportPath, com.Expand("github.com/{owner}/{repo}", match))
+	}
+
 	// Get stars.
 	var repoTree struct {
 		Stars int64 `json:"watchers"`
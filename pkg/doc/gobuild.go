@@ -0,0 +1,144 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build !js
+
+package doc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+var errNoToolchain = errors.New("go command is not found")
+
+// goCommand returns the go command with the arguments to be run in the directory.
+// Commands of untrusted code are wrapped by the sandbox command of settings, and
+// run with a minimal environment without credentials of the server, temporary
+// directories that are removed by the returned function, and modules that are
+// only downloaded from the proxy of settings and must match go.sum.
+func goCommand(ctx context.Context, dir string, untrusted bool, args ...string) (*exec.Cmd, func(), error) {
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		return nil, nil, errNoToolchain
+	}
+
+	if !untrusted {
+		cmd := exec.CommandContext(ctx, goCmd, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "CGO_ENABLED=0", "GOFLAGS=-mod=mod", "GOWORK=off")
+		return cmd, func() {}, nil
+	}
+
+	tmp, err := ioutil.TempDir("", "gowalker-go")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+	for _, name := range []string{"home", "cache", "modcache", "tmp"} {
+		if err = os.Mkdir(filepath.Join(tmp, name), 0700); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+
+	goProxy := setting.GoProxy
+	if len(goProxy) == 0 {
+		goProxy = "off"
+	}
+
+	name, cmdArgs := goCmd, args
+	if sandbox := strings.Fields(setting.GoSandbox); len(sandbox) > 0 {
+		name, cmdArgs = sandbox[0], append(append(sandbox[1:], goCmd), args...)
+	}
+	cmd := exec.CommandContext(ctx, name, cmdArgs...)
+	cmd.Dir = dir
+	cmd.Env = []string{
+		"PATH=" + filepath.Dir(goCmd),
+		"HOME=" + filepath.Join(tmp, "home"),
+		"GOPATH=" + filepath.Join(tmp, "home", "go"),
+		"GOCACHE=" + filepath.Join(tmp, "cache"),
+		"GOMODCACHE=" + filepath.Join(tmp, "modcache"),
+		"TMPDIR=" + filepath.Join(tmp, "tmp"),
+		"GOENV=off",
+		"GOTOOLCHAIN=local",
+		"GOFLAGS=-mod=readonly",
+		"GOPROXY=" + goProxy,
+		"GOSUMDB=sum.golang.org",
+		"GONOSUMDB=",
+		"GOPRIVATE=",
+		"GOINSECURE=",
+		"GOWORK=off",
+		"GOTELEMETRY=off",
+		// Cgo is disabled so that no C compiler is run on untrusted code.
+		"CGO_ENABLED=0",
+	}
+	return cmd, cleanup, nil
+}
+
+// goBuild builds the main package in the directory to the file.
+func goBuild(dir, output string, untrusted bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), buildTimeout)
+	defer cancel()
+
+	cmd, cleanup, err := goCommand(ctx, dir, untrusted, "build", "-trimpath", "-o", output, ".")
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); len(msg) > 0 {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// goVet runs "go vet" with the arguments on untrusted code in the directory and returns
+// its diagnostics, which are written to stderr by older versions of the go command and
+// stdout by newer.
+func goVet(dir string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), buildTimeout)
+	defer cancel()
+
+	cmd, cleanup, err := goCommand(ctx, dir, true, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Diagnostics in JSON do not fail the command.
+	if err = cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); len(msg) > 0 {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	return append(stdout.Bytes(), stderr.Bytes()...), nil
+}
@@ -52,6 +52,7 @@ func getGolangDoc(importPath, etag string) (*Package, error) {
 			Url  string
 			Path string
 			Type string
+			Sha  string
 		}
 		Url string
 	}
@@ -62,47 +63,39 @@ func getGolangDoc(importPath, etag string) (*Package, error) {
 	}
 
 	dirPrefix := "src/" + importPath + "/"
-	dirLevel := len(strings.Split(dirPrefix, "/"))
-	dirLength := len(dirPrefix)
-	dirMap := make(map[string]bool)
 	files := make([]com.RawFile, 0, 10)
+	paths := make([]string, 0, len(tree.Tree))
 
 	for _, node := range tree.Tree {
 		// Skip directories and files in irrelevant directories.
 		if node.Type != "blob" || !strings.HasPrefix(node.Path, dirPrefix) {
 			continue
 		}
-
-		// Get files and check if directories have acceptable files.
-		if d, f := path.Split(node.Path); base.IsDocFile(f) {
-			// Check if file is in the directory that is corresponding to import path.
-			if d == dirPrefix {
-				files = append(files, &Source{
-					SrcName:   f,
-					BrowseUrl: com.Expand("github.com/golang/go/blob/master/{0}", nil, node.Path),
-					RawSrcUrl: com.Expand("https://raw.github.com/golang/go/master/{0}?{1}", nil, node.Path, setting.GitHubCredentials),
-				})
-				continue
-			}
-
-			// Otherwise, check if it's a direct sub-directory of import path.
-			if len(strings.Split(d, "/"))-dirLevel == 1 {
-				dirMap[d[dirLength:len(d)-1]] = true
-				continue
-			}
+		paths = append(paths, node.Path)
+
+		// Get files that are in the directory corresponding to import path.
+		if d, f := path.Split(node.Path); d == dirPrefix && (base.IsDocFile(f) || base.IsMetaFile(f)) {
+			files = append(files, &Source{
+				SrcName:   f,
+				BrowseUrl: com.Expand("github.com/golang/go/blob/master/{0}", nil, node.Path),
+				RawSrcUrl: com.Expand("https://raw.github.com/golang/go/master/{0}?{1}", nil, node.Path, setting.GitHubCredentials),
+				Hash:      node.Sha,
+			})
 		}
 	}
 
-	dirs := base.MapToSortedStrings(dirMap)
+	subdirs := subdirectories(dirPrefix, paths)
 
-	if len(files) == 0 && len(dirs) == 0 {
+	if len(files) == 0 && len(subdirs) == 0 {
 		return nil, ErrPackageNoGoFile
-	} else if err := com.FetchFiles(Client, files, githubRawHeader); err != nil {
+	} else if err := fetchFiles(Client, files, githubRawHeader); err != nil {
 		return nil, fmt.Errorf("fetch files: %v", err)
 	}
 
 	// Start generating data.
 	w := &Walker{
+		Fetcher: "golang",
+		Ref:     "master",
 		LineFmt: "#L%d",
 		Pdoc: &Package{
 			PkgInfo: &models.PkgInfo{
@@ -111,7 +104,7 @@ func getGolangDoc(importPath, etag string) (*Package, error) {
 				ViewDirPath: "github.com/golang/go/tree/master/src/" + importPath,
 				Etag:        commit,
 				IsGoRepo:    true,
-				Subdirs:     strings.Join(dirs, "|"),
+				Subdirs:     strings.Join(subdirNames(subdirs), "|"),
 			},
 		},
 	}
@@ -130,12 +123,13 @@ func getGolangDoc(importPath, etag string) (*Package, error) {
 	pdoc, err := w.Build(&WalkRes{
 		WalkDepth: WD_All,
 		WalkType:  WT_Memory,
-		WalkMode:  WM_All,
+		WalkMode:  defaultWalkMode(),
 		Srcs:      srcs,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("walk package: %v", err)
 	}
+	pdoc.Subdirectories = subdirs
 
 	return pdoc, nil
 }
@@ -0,0 +1,178 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Unknwon/gowalker/pkg/base"
+)
+
+// parseGoMod returns module path and deprecation message of a go.mod file.
+// Following the convention of Go command, deprecation message is
+// the paragraph starts with "Deprecated: " in comments of module directive.
+func parseGoMod(data []byte) (modulePath, deprecated string) {
+	var comments []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "//") {
+			comments = append(comments, strings.TrimSpace(strings.TrimPrefix(line, "//")))
+			continue
+		} else if !strings.HasPrefix(line, "module") {
+			comments = comments[:0]
+			continue
+		}
+
+		line = strings.TrimSpace(strings.TrimPrefix(line, "module"))
+		if i := strings.Index(line, "//"); i > -1 {
+			comments = append(comments, strings.TrimSpace(line[i+2:]))
+			line = strings.TrimSpace(line[:i])
+		}
+		if path, err := strconv.Unquote(line); err == nil {
+			line = path
+		}
+		modulePath = line
+		break
+	}
+
+	for i, c := range comments {
+		if !strings.HasPrefix(c, "Deprecated: ") {
+			continue
+		}
+		msg := []string{strings.TrimPrefix(c, "Deprecated: ")}
+		for _, c = range comments[i+1:] {
+			if len(c) == 0 {
+				break
+			}
+			msg = append(msg, c)
+		}
+		deprecated = strings.Join(msg, " ")
+		break
+	}
+	return modulePath, deprecated
+}
+
+var (
+	majorVersionPattern = regexp.MustCompile(`/v[0-9]+$`)
+	importPathPattern   = regexp.MustCompile(`[a-z0-9][a-z0-9.-]*\.[a-z]+(/[A-Za-z0-9_.~+-]+)+`)
+)
+
+// trimMajorVersion returns module path without major version suffix.
+func trimMajorVersion(modulePath string) string {
+	return majorVersionPattern.ReplaceAllString(modulePath, "")
+}
+
+// supersededBy returns the import path that users should use instead of
+// the one being walked, it returns empty string if the import path is still maintained.
+func supersededBy(importPath, projectPath, modulePath, deprecated string) string {
+	// Deprecated modules usually tell where to go in the message.
+	if len(deprecated) > 0 {
+		if path := importPathPattern.FindString(deprecated); len(path) > 0 {
+			return strings.TrimRight(path, ".")
+		}
+	}
+
+	if len(modulePath) == 0 || importPath == modulePath ||
+		strings.HasPrefix(importPath, modulePath+"/") {
+		return ""
+	}
+
+	// Module path could be declared by go.mod of root directory or the package itself.
+	rel := strings.TrimPrefix(importPath, projectPath)
+	expected := modulePath
+	if len(rel) > 0 && !strings.HasSuffix(trimMajorVersion(modulePath), rel) {
+		if strings.HasPrefix(rel, "/") {
+			expected += rel
+		} else {
+			// The import path is not under project path, nothing could be inferred.
+			return ""
+		}
+	}
+	if expected == importPath {
+		return ""
+	}
+	return expected
+}
+
+// setModule records module information of the go.mod file.
+func (w *Walker) setModule(data []byte) {
+	w.Pdoc.ModulePath, w.Pdoc.Deprecated = parseGoMod(data)
+	w.Pdoc.SupersededBy = supersededBy(w.Pdoc.ImportPath, w.Pdoc.ProjectPath,
+		w.Pdoc.ModulePath, w.Pdoc.Deprecated)
+}
+
+// majorVersionDir returns the directory that contains source files of given directory
+// of import path. The major version element is removed when it does not exist,
+// because the repository uses major branch instead of major subdirectory.
+func majorVersionDir(dir string, isDir func(string) bool) string {
+	parts := strings.Split(dir, "/")
+	for i := range parts {
+		if _, major := base.SplitMajorVersion("_/" + parts[i]); major == 1 {
+			continue
+		}
+
+		if !isDir(strings.Join(parts[:i+1], "/")) {
+			return strings.Join(append(parts[:i:i], parts[i+1:]...), "/")
+		}
+		break
+	}
+	return dir
+}
+
+// majorVersions returns import paths of all major versions of the package
+// based on go.mod files of major subdirectories in the repository.
+// It returns nil if there is only one major version.
+func majorVersions(importPath, projectPath string, goMods []string) []string {
+	stripped, _ := base.SplitMajorVersion(importPath)
+	if !strings.HasPrefix(stripped, projectPath) {
+		return nil
+	}
+	rel := stripped[len(projectPath):]
+
+	majors := make(map[int]string)
+	for _, name := range goMods {
+		dir := path.Dir(name)
+		if dir == "." {
+			majors[1] = projectPath + rel
+			continue
+		}
+
+		if p, major := base.SplitMajorVersion(projectPath + "/" + dir); major > 1 && p == projectPath {
+			majors[major] = projectPath + "/" + dir + rel
+		}
+	}
+	if len(majors) < 2 {
+		return nil
+	}
+
+	versions := make([]int, 0, len(majors))
+	for major := range majors {
+		versions = append(versions, major)
+	}
+	sort.Ints(versions)
+
+	paths := make([]string, len(versions))
+	for i, major := range versions {
+		paths[i] = majors[major]
+	}
+	return paths
+}
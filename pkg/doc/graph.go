@@ -0,0 +1,172 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Unknwon/gowalker/pkg/base"
+)
+
+// GraphFormat is the output format of import graph.
+type GraphFormat int
+
+const (
+	GF_DOT  GraphFormat = iota // Graphviz DOT language.
+	GF_JSON                    // JSON of nodes and edges.
+)
+
+// GraphOptions contains options of exporting import graph.
+type GraphOptions struct {
+	Format GraphFormat
+	// Whether to include standard library packages.
+	Std bool
+	// Maximum number of edges from root packages to be included,
+	// 0 means no limit. Root packages are the given packages that
+	// are not imported by any other given package.
+	Depth int
+}
+
+type graphNode struct {
+	ImportPath string `json:"id"`
+	Internal   bool   `json:"internal"` // Whether the package is one of the given packages.
+	Std        bool   `json:"std"`
+	Depth      int    `json:"depth"`
+}
+
+type graphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type importGraph struct {
+	Nodes []*graphNode `json:"nodes"`
+	Edges []*graphEdge `json:"edges"`
+}
+
+// buildImportGraph walks imports of packages breadth-first from root packages.
+func buildImportGraph(pkgs []*Package, opts GraphOptions) *importGraph {
+	imports := make(map[string][]string, len(pkgs))
+	imported := make(map[string]bool)
+	var paths []string
+	for _, pdoc := range pkgs {
+		if pdoc.PkgDecl == nil {
+			continue
+		}
+		if _, ok := imports[pdoc.ImportPath]; ok {
+			continue
+		}
+		paths = append(paths, pdoc.ImportPath)
+		for _, path := range pdoc.Imports {
+			if path == "C" || (!opts.Std && base.IsGoRepoPath(path)) {
+				continue
+			}
+			imports[pdoc.ImportPath] = append(imports[pdoc.ImportPath], path)
+			imported[path] = true
+		}
+		if imports[pdoc.ImportPath] == nil {
+			imports[pdoc.ImportPath] = []string{}
+		}
+	}
+	sort.Strings(paths)
+
+	var queue []string
+	for _, path := range paths {
+		if !imported[path] {
+			queue = append(queue, path)
+		}
+	}
+	// Packages form cycles only, start from all of them.
+	if len(queue) == 0 {
+		queue = paths
+	}
+
+	g := new(importGraph)
+	nodes := make(map[string]*graphNode)
+	for _, path := range queue {
+		nodes[path] = &graphNode{ImportPath: path, Internal: true}
+		g.Nodes = append(g.Nodes, nodes[path])
+	}
+	for len(queue) > 0 {
+		from := nodes[queue[0]]
+		queue = queue[1:]
+		if opts.Depth > 0 && from.Depth >= opts.Depth {
+			continue
+		}
+
+		for _, path := range imports[from.ImportPath] {
+			g.Edges = append(g.Edges, &graphEdge{from.ImportPath, path})
+			if _, ok := nodes[path]; ok {
+				continue
+			}
+
+			_, internal := imports[path]
+			nodes[path] = &graphNode{
+				ImportPath: path,
+				Internal:   internal,
+				Std:        base.IsGoRepoPath(path),
+				Depth:      from.Depth + 1,
+			}
+			g.Nodes = append(g.Nodes, nodes[path])
+			if internal {
+				queue = append(queue, path)
+			}
+		}
+	}
+	return g
+}
+
+func (g *importGraph) writeDOT(w io.Writer) error {
+	if _, err := io.WriteString(w, "digraph imports {\n\trankdir=LR;\n\tnode [shape=box];\n"); err != nil {
+		return err
+	}
+	for _, n := range g.Nodes {
+		attrs := ""
+		switch {
+		case n.Std:
+			attrs = " [style=dotted]"
+		case !n.Internal:
+			attrs = " [style=dashed]"
+		}
+		if _, err := fmt.Fprintf(w, "\t%q%s;\n", n.ImportPath, attrs); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges {
+		if _, err := fmt.Fprintf(w, "\t%q -> %q;\n", e.From, e.To); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}\n")
+	return err
+}
+
+// ExportImportGraph writes import graph of given packages to w in given format,
+// so that internal package structure of a module can be visualized.
+func ExportImportGraph(w io.Writer, pkgs []*Package, opts GraphOptions) error {
+	g := buildImportGraph(pkgs, opts)
+	switch opts.Format {
+	case GF_DOT:
+		return g.writeDOT(w)
+	case GF_JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(g)
+	}
+	return fmt.Errorf("unknown graph format: %d", opts.Format)
+}
@@ -0,0 +1,311 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// parseDecl parses a declaration printed by the walker back into its AST form.
+func parseDecl(decl string) (*ast.GenDecl, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+decl, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	} else if len(file.Decls) == 0 {
+		return nil, fmt.Errorf("no declaration found")
+	}
+
+	gd, ok := file.Decls[0].(*ast.GenDecl)
+	if !ok {
+		return nil, fmt.Errorf("not a general declaration")
+	}
+	return gd, nil
+}
+
+var graphqlScalars = map[string]string{
+	"bool":    "Boolean",
+	"string":  "String",
+	"byte":    "Int",
+	"rune":    "Int",
+	"int":     "Int",
+	"int8":    "Int",
+	"int16":   "Int",
+	"int32":   "Int",
+	"int64":   "Int",
+	"uint":    "Int",
+	"uint8":   "Int",
+	"uint16":  "Int",
+	"uint32":  "Int",
+	"uint64":  "Int",
+	"uintptr": "Int",
+	"float32": "Float",
+	"float64": "Float",
+}
+
+// graphqlExporter holds the state used when exporting GraphQL type definitions.
+type graphqlExporter struct {
+	specs     map[string]*ast.TypeSpec // All exported type specs by name.
+	enums     map[string][]string      // Enum values by type name.
+	scalars   map[string]bool          // Custom scalars used by fields.
+	resolving map[string]bool          // Named types whose underlying types are being resolved.
+}
+
+// typeName returns GraphQL type name of given Go type expression,
+// and whether the type is nullable by nature.
+func (e *graphqlExporter) typeName(expr ast.Expr) (string, bool) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if name, ok := graphqlScalars[t.Name]; ok {
+			return name, false
+		}
+		if _, ok := e.enums[t.Name]; ok {
+			return t.Name, false
+		}
+		if spec, ok := e.specs[t.Name]; ok {
+			switch spec.Type.(type) {
+			case *ast.StructType:
+				return t.Name, false
+			case *ast.Ident, *ast.ArrayType, *ast.StarExpr:
+				// Named types of basic or list types are represented by their underlying types,
+				// recursive ones are not representable.
+				if e.resolving[t.Name] {
+					break
+				}
+				e.resolving[t.Name] = true
+				name, nullable := e.typeName(spec.Type)
+				delete(e.resolving, t.Name)
+				return name, nullable
+			}
+		}
+	case *ast.StarExpr:
+		name, _ := e.typeName(t.X)
+		return name, true
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return "String", true
+		}
+		name, nullable := e.typeName(t.Elt)
+		if !nullable {
+			name += "!"
+		}
+		return "[" + name + "]", true
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok && x.Name == "time" && t.Sel.Name == "Time" {
+			e.scalars["Time"] = true
+			return "Time", false
+		}
+	}
+
+	e.scalars["JSON"] = true
+	return "JSON", true
+}
+
+// fieldName returns GraphQL field name and whether it is omitted when empty,
+// it returns empty name if the field should be skipped.
+func fieldName(name string, tag *ast.BasicLit) (string, bool) {
+	if !ast.IsExported(name) {
+		return "", false
+	}
+
+	if tag != nil {
+		if s, err := strconv.Unquote(tag.Value); err == nil {
+			opts := strings.Split(reflect.StructTag(s).Get("json"), ",")
+			if opts[0] == "-" && len(opts) == 1 {
+				return "", false
+			}
+			omitEmpty := false
+			for _, opt := range opts[1:] {
+				if opt == "omitempty" {
+					omitEmpty = true
+				}
+			}
+			if len(opts[0]) > 0 {
+				return opts[0], omitEmpty
+			}
+		}
+	}
+
+	// Lower the first rune to follow GraphQL naming convention.
+	rs := []rune(name)
+	rs[0] = unicode.ToLower(rs[0])
+	return string(rs), false
+}
+
+func (e *graphqlExporter) writeFields(w io.Writer, st *ast.StructType, visited map[string]bool) {
+	for _, f := range st.Fields.List {
+		// Inline fields of embedded struct types that are declared in this package.
+		if len(f.Names) == 0 {
+			expr := f.Type
+			if star, ok := expr.(*ast.StarExpr); ok {
+				expr = star.X
+			}
+			if ident, ok := expr.(*ast.Ident); ok && !visited[ident.Name] {
+				if spec, ok := e.specs[ident.Name]; ok {
+					if embedded, ok := spec.Type.(*ast.StructType); ok {
+						visited[ident.Name] = true
+						e.writeFields(w, embedded, visited)
+					}
+				}
+			}
+			continue
+		}
+
+		for _, name := range f.Names {
+			fname, omitEmpty := fieldName(name.Name, f.Tag)
+			if len(fname) == 0 {
+				continue
+			}
+			tname, nullable := e.typeName(f.Type)
+			if !nullable && !omitEmpty {
+				tname += "!"
+			}
+			fmt.Fprintf(w, "  %s: %s\n", fname, tname)
+		}
+	}
+}
+
+func writeDescription(w io.Writer, indent, doc string) {
+	doc = strings.TrimSpace(doc)
+	if len(doc) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s\"\"\"\n%s%s\n%s\"\"\"\n", indent, indent,
+		strings.Replace(doc, "\n", "\n"+indent, -1), indent)
+}
+
+// enumValues returns names of exported constants declared with given type.
+func enumValues(t *Type) []string {
+	var names []string
+	for _, v := range t.Consts {
+		gd, err := parseDecl(v.Decl)
+		if err != nil {
+			continue
+		}
+
+		// Constants without explicit type inherit the type of previous spec in the group.
+		var typeName string
+		for _, spec := range gd.Specs {
+			vs := spec.(*ast.ValueSpec)
+			if vs.Type != nil {
+				typeName = ""
+				if ident, ok := vs.Type.(*ast.Ident); ok {
+					typeName = ident.Name
+				}
+			} else if len(vs.Values) > 0 {
+				typeName = ""
+			}
+			if typeName != t.Name {
+				continue
+			}
+
+			for _, name := range vs.Names {
+				if ast.IsExported(name.Name) {
+					names = append(names, name.Name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// ExportGraphQL writes GraphQL type definitions of exported structs and enums
+// of the package to w. Enums are derived from constant groups that share a type.
+// It should be called before the package is rendered, because rendering
+// overwrites declarations with HTML.
+func ExportGraphQL(w io.Writer, pdoc *Package) error {
+	if pdoc.PkgDecl == nil {
+		return nil
+	}
+
+	e := &graphqlExporter{
+		specs:     make(map[string]*ast.TypeSpec),
+		enums:     make(map[string][]string),
+		scalars:   make(map[string]bool),
+		resolving: make(map[string]bool),
+	}
+	for _, t := range pdoc.Types {
+		gd, err := parseDecl(t.Decl)
+		if err != nil {
+			return fmt.Errorf("parse declaration of %q: %v", t.Name, err)
+		}
+		for _, spec := range gd.Specs {
+			if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name == t.Name {
+				e.specs[t.Name] = ts
+			}
+		}
+
+		spec, ok := e.specs[t.Name]
+		if !ok {
+			continue
+		}
+		if len(t.EnumValues) > 0 {
+			for _, v := range t.EnumValues {
+				e.enums[t.Name] = append(e.enums[t.Name], v.Name)
+			}
+		} else if ident, ok := spec.Type.(*ast.Ident); ok && graphqlScalars[ident.Name] != "" {
+			// Fall back to parse declarations for packages walked without type information.
+			if names := enumValues(t); len(names) > 0 {
+				e.enums[t.Name] = names
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, t := range pdoc.Types {
+		if names, ok := e.enums[t.Name]; ok {
+			writeDescription(&buf, "", t.Doc)
+			fmt.Fprintf(&buf, "enum %s {\n", t.Name)
+			for _, name := range names {
+				fmt.Fprintf(&buf, "  %s\n", name)
+			}
+			buf.WriteString("}\n\n")
+			continue
+		}
+
+		spec, ok := e.specs[t.Name]
+		if !ok {
+			continue
+		}
+		st, ok := spec.Type.(*ast.StructType)
+		if !ok {
+			continue
+		}
+		writeDescription(&buf, "", t.Doc)
+		fmt.Fprintf(&buf, "type %s {\n", t.Name)
+		e.writeFields(&buf, st, map[string]bool{t.Name: true})
+		buf.WriteString("}\n\n")
+	}
+
+	for _, name := range []string{"JSON", "Time"} {
+		if e.scalars[name] {
+			fmt.Fprintf(w, "scalar %s\n\n", name)
+		}
+	}
+	_, err := w.Write(bytes.TrimRight(buf.Bytes(), "\n"))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"fmt"
+	"go/ast"
+	"sync"
+)
+
+// Phase is a phase of walking and rendering a package.
+type Phase int
+
+const (
+	PhaseFetch  Phase = iota // Files of the package are fetched.
+	PhaseParse               // Go files are parsed.
+	PhaseDoc                 // Declarations and documentation are extracted.
+	PhaseRender              // Documentation is rendered to HTML.
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseFetch:
+		return "fetch"
+	case PhaseParse:
+		return "parse"
+	case PhaseDoc:
+		return "doc"
+	case PhaseRender:
+		return "render"
+	}
+	return fmt.Sprintf("Phase(%d)", int(p))
+}
+
+// Event is passed to hooks before and after each phase. Events of a package
+// are fired in the same goroutine, so that hooks can time phases by pairs of them.
+// Before events of PhaseFetch are only fired for packages fetched from remote.
+type Event struct {
+	Phase      Phase
+	After      bool // Fired after the phase, otherwise before.
+	ImportPath string
+
+	// Package being walked or rendered, declarations are set after PhaseDoc.
+	Pdoc *Package
+	// Fetched files, set after PhaseFetch, and hooks can drop or replace them.
+	Srcs []*Source
+	// Parsed Go files by name, set after PhaseParse, and hooks can modify them.
+	Files map[string]*ast.File
+	// Rendered page, set after PhaseRender, and hooks can replace it.
+	HTML []byte
+}
+
+// Hook is called on events of phases, returning an error vetoes the walk
+// or rendering of the package, which fails with the error.
+type Hook func(e *Event) error
+
+type namedHook struct {
+	name string
+	hook Hook
+}
+
+var hooks struct {
+	sync.RWMutex
+	list []namedHook
+}
+
+// RegisterHook registers the hook by unique name. Hooks are called in the order
+// of registration.
+func RegisterHook(name string, hook Hook) error {
+	hooks.Lock()
+	defer hooks.Unlock()
+	for _, h := range hooks.list {
+		if h.name == name {
+			return fmt.Errorf("hook %q already exists", name)
+		}
+	}
+	hooks.list = append(hooks.list, namedHook{name, hook})
+	return nil
+}
+
+// UnregisterHook removes the hook of given name if it exists.
+func UnregisterHook(name string) {
+	hooks.Lock()
+	defer hooks.Unlock()
+	for i, h := range hooks.list {
+		if h.name == name {
+			hooks.list = append(hooks.list[:i:i], hooks.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// fireHooks calls registered hooks with the event until one of them vetoes.
+func fireHooks(e *Event) error {
+	hooks.RLock()
+	list := hooks.list
+	hooks.RUnlock()
+
+	for _, h := range list {
+		if err := h.hook(e); err != nil {
+			when := "before"
+			if e.After {
+				when = "after"
+			}
+			return fmt.Errorf("hook %q %s %s: %v", h.name, when, e.Phase, err)
+		}
+	}
+	return nil
+}
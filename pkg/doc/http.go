@@ -37,6 +37,7 @@ type transport struct {
 }
 
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = withCredentials(req)
 	timer := time.AfterFunc(*requestTimeout, func() {
 		t.t.CancelRequest(req)
 		log.Warn("Canceled request for %s", req.URL)
@@ -0,0 +1,194 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Unknwon/com"
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+var (
+	gopkgPattern     = regexp.MustCompile(`^gopkg\.in(?P<path>/.+)$`)
+	gopkgPathPattern = regexp.MustCompile(`^/(?:([a-zA-Z0-9][-a-zA-Z0-9]+)/)?([a-zA-Z][-.a-zA-Z0-9]*)\.((?:v0|v[1-9][0-9]*)(?:\.0|\.[1-9][0-9]*){0,2})(?:\.git)?((?:/[a-zA-Z0-9][-.a-zA-Z0-9]*)*)$`)
+)
+
+// parseVersion returns numbers of a version string like "v1.2.3",
+// it returns nil if the string is not a valid version.
+func parseVersion(s string) []int {
+	if !strings.HasPrefix(s, "v") {
+		return nil
+	}
+
+	fields := strings.Split(s[1:], ".")
+	nums := make([]int, len(fields))
+	for i := range fields {
+		num, err := com.StrTo(fields[i]).Int()
+		if err != nil || num < 0 {
+			return nil
+		}
+		nums[i] = num
+	}
+	return nums
+}
+
+// bestGopkgRef returns the name of branch or tag with highest version that
+// matches the version selector of gopkg.in, e.g. "v2" matches "v2", "v2.1" and "v2.1.3".
+func bestGopkgRef(refs []string, selector string) (string, error) {
+	want := parseVersion(selector)
+
+	var best string
+	var bestVer []int
+CheckRef:
+	for _, ref := range refs {
+		ver := parseVersion(ref)
+		if len(ver) < len(want) {
+			continue
+		}
+		for i := range want {
+			if ver[i] != want[i] {
+				continue CheckRef
+			}
+		}
+
+		for i := 0; i < len(ver); i++ {
+			if i >= len(bestVer) || ver[i] > bestVer[i] {
+				best, bestVer = ref, ver
+				break
+			} else if ver[i] < bestVer[i] {
+				break
+			}
+		}
+	}
+
+	if len(best) == 0 {
+		return "", com.NotFoundError{fmt.Sprintf("no branch or tag matches %s", selector)}
+	}
+	return best, nil
+}
+
+// maxGopkgRefPages is the maximum number of pages of branches and tags to list
+// for a gopkg.in import path.
+const maxGopkgRefPages = 10
+
+// linkNextPattern matches the URL of next page in the Link header of paginated API responses.
+var linkNextPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// getJSONPage decodes JSON response of the URL into v, and returns
+// the URL of next page, which is empty string if it is the last page.
+func getJSONPage(url string, v interface{}) (string, error) {
+	resp, err := Client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", com.NotFoundError{fmt.Sprintf("resource not found: %s", url)}
+	} else if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("get %s: status %d", url, resp.StatusCode)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", err
+	}
+
+	if m := linkNextPattern.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+		return m[1], nil
+	}
+	return "", nil
+}
+
+// getGopkgDoc gets package documentation of gopkg.in import path from the
+// corresponding GitHub repository and branch or tag.
+func getGopkgDoc(match map[string]string, etag string) (*Package, error) {
+	m := gopkgPathPattern.FindStringSubmatch(match["path"])
+	if m == nil {
+		return nil, fmt.Errorf("unsupported gopkg.in import path: %s", match["importPath"])
+	}
+	match["owner"] = m[1]
+	match["repo"] = m[2]
+	if len(match["owner"]) == 0 {
+		match["owner"] = "go-" + match["repo"]
+	}
+	match["dir"] = m[4]
+	match["cred"] = setting.GitHubCredentials
+
+	var names []string
+	url := com.Expand("https://api.github.com/repos/{owner}/{repo}/git/refs?per_page=100&{cred}", match)
+	for page := 0; len(url) > 0 && page < maxGopkgRefPages; page++ {
+		var refs []struct {
+			Ref string `json:"ref"`
+		}
+		next, err := getJSONPage(url, &refs)
+		if err != nil {
+			return nil, fmt.Errorf("get refs: %v", err)
+		}
+		for _, ref := range refs {
+			names = append(names, strings.TrimPrefix(strings.TrimPrefix(ref.Ref, "refs/heads/"), "refs/tags/"))
+		}
+		url = next
+	}
+
+	tag, err := bestGopkgRef(names, m[3])
+	if err != nil {
+		return nil, err
+	}
+	match["tag"] = tag
+	log.Trace("Import path %q found branch or tag: %s", match["importPath"], tag)
+	return getGitHubDoc(match, etag)
+}
+
+// legacyPaths is the list of import path prefixes of hosts that are no longer
+// available, and the prefixes that projects have been moved to.
+var legacyPaths = []struct {
+	prefix, newPrefix string
+}{
+	{"code.google.com/p/go.net", "golang.org/x/net"},
+	{"code.google.com/p/go.crypto", "golang.org/x/crypto"},
+	{"code.google.com/p/go.text", "golang.org/x/text"},
+	{"code.google.com/p/go.tools", "golang.org/x/tools"},
+	{"code.google.com/p/go.image", "golang.org/x/image"},
+	{"code.google.com/p/go.exp", "golang.org/x/exp"},
+	{"code.google.com/p/go.talks", "golang.org/x/talks"},
+	{"code.google.com/p/go.blog", "golang.org/x/blog"},
+	{"code.google.com/p/goprotobuf", "github.com/golang/protobuf"},
+	{"code.google.com/p/gogoprotobuf", "github.com/gogo/protobuf"},
+	{"code.google.com/p/snappy-go/snappy", "github.com/golang/snappy"},
+	{"code.google.com/p/go-uuid/uuid", "github.com/pborman/uuid"},
+	{"code.google.com/p/google-api-go-client", "google.golang.org/api"},
+	{"code.google.com/p/gcfg", "gopkg.in/gcfg.v1"},
+	{"labix.org/v2/mgo", "gopkg.in/mgo.v2"},
+	{"launchpad.net/goyaml", "gopkg.in/yaml.v1"},
+	{"launchpad.net/gocheck", "gopkg.in/check.v1"},
+	{"github.com/codegangsta/cli", "github.com/urfave/cli"},
+}
+
+// LegacyImportPath returns the import path that the legacy import path
+// has been moved to, it returns empty string if the import path is not legacy.
+func LegacyImportPath(importPath string) string {
+	for _, p := range legacyPaths {
+		if importPath == p.prefix || strings.HasPrefix(importPath, p.prefix+"/") {
+			return p.newPrefix + importPath[len(p.prefix):]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bytes"
+)
+
+// licensePatterns is the list of well known licenses and phrases that must all
+// present in the license text, more specific licenses must be placed first.
+var licensePatterns = []struct {
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-3.0", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-2.1", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 2.1"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 2"}},
+	{"MPL-2.0", []string{"Mozilla Public License", "2.0"}},
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-2-Clause", []string{"Redistribution and use in source and binary forms"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute this software for any"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+	{"Unlicense", []string{"This is free and unencumbered software released into the public domain"}},
+	{"WTFPL", []string{"DO WHAT THE FUCK YOU WANT TO PUBLIC LICENSE"}},
+}
+
+// appendLicense appends the license to the list if it is not presented,
+// the same license is often in several files, e.g. "LICENSE" and "LICENSE.md".
+func appendLicense(licenses []string, license string) []string {
+	for _, l := range licenses {
+		if l == license {
+			return licenses
+		}
+	}
+	return append(licenses, license)
+}
+
+// detectLicense returns SPDX identifier of the license text,
+// it returns empty string if the license is not recognized.
+func detectLicense(data []byte) string {
+	// Normalize whitespaces because license texts are usually wrapped at random columns.
+	data = bytes.Join(bytes.Fields(data), []byte(" "))
+
+CheckLicense:
+	for _, p := range licensePatterns {
+		for _, phrase := range p.phrases {
+			if !bytes.Contains(data, []byte(phrase)) {
+				continue CheckLicense
+			}
+		}
+		return p.id
+	}
+	return ""
+}
@@ -0,0 +1,362 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Unknwon/com"
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/models"
+)
+
+// Fetch fetches and walks the latest version of the package of given import path.
+func Fetch(importPath string) (*Package, error) {
+	return crawlDoc(importPath, "")
+}
+
+// localImportPath returns import path of the directory based on the nearest
+// go.mod file or GOPATH, or base name of the directory if neither applies.
+func localImportPath(dir string) string {
+	var elems []string
+	for cur := dir; ; cur = filepath.Dir(cur) {
+		if data, err := ioutil.ReadFile(filepath.Join(cur, "go.mod")); err == nil {
+			if modulePath, _ := parseGoMod(data); len(modulePath) > 0 {
+				for i := len(elems) - 1; i >= 0; i-- {
+					modulePath += "/" + elems[i]
+				}
+				return modulePath
+			}
+		}
+
+		if filepath.Dir(cur) == cur {
+			break
+		}
+		elems = append(elems, filepath.Base(cur))
+	}
+
+	for _, gopath := range filepath.SplitList(build.Default.GOPATH) {
+		rel, err := filepath.Rel(filepath.Join(gopath, "src"), dir)
+		if err == nil && !strings.HasPrefix(rel, "..") && rel != "." {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(dir)
+}
+
+// WalkDir walks the package in local directory. The import path is derived
+// from go.mod file when it is empty. Coverage profile named coverage.out or
+// cover.out in the directory is applied to the package.
+func WalkDir(dir, importPath string) (*Package, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(importPath) == 0 {
+		importPath = localImportPath(dir)
+	}
+
+	w := &Walker{
+		Fetcher: "local",
+		LineFmt: "#L%d",
+		Pdoc: &Package{
+			PkgInfo: &models.PkgInfo{
+				ImportPath:  importPath,
+				ViewDirPath: dir,
+			},
+		},
+	}
+	pdoc, err := w.Build(&WalkRes{
+		WalkDepth: WD_All,
+		WalkType:  WT_Local,
+		WalkMode:  defaultWalkMode(),
+		RootPath:  dir,
+	})
+	if err != nil {
+		return nil, err
+	}
+	analyze(pdoc, dir)
+	if err = readCoverProfile(pdoc, dir); err != nil {
+		log.Warn("Failed to read coverage profile of %q: %v", pdoc.ImportPath, err)
+	}
+	return pdoc, nil
+}
+
+const (
+	maxArchiveSize    = 100 << 20 // Maximum total size of files to be read from an archive.
+	maxArchiveEntries = 10000     // Maximum number of entries of an archive.
+)
+
+var (
+	errArchiveTooLarge       = errors.New("archive is too large")
+	errArchiveTooManyEntries = errors.New("archive has too many entries")
+)
+
+type archiveFile struct {
+	name string
+	data []byte
+}
+
+// archivePath normalizes separators of the entry name to slashes, and rejects
+// absolute paths and paths that contain ".." elements, which escape the archive.
+func archivePath(name string) (string, error) {
+	name = strings.Replace(name, "\\", "/", -1)
+	if strings.HasPrefix(name, "/") || (len(name) > 1 && name[1] == ':') {
+		return "", fmt.Errorf("absolute path in archive: %q", name)
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem == ".." {
+			return "", fmt.Errorf("path escapes archive: %q", name)
+		}
+	}
+	return path.Clean(name), nil
+}
+
+// archiveFiles collects files read from an archive and enforces the limits.
+type archiveFiles struct {
+	files []*archiveFile
+	names map[string]bool
+	total int
+}
+
+// add reads the entry of given name from r, which must not be read after
+// the total size of files exceeds maxArchiveSize.
+func (fs *archiveFiles) add(name string, r io.Reader) error {
+	name, err := archivePath(name)
+	if err != nil {
+		return err
+	} else if fs.names[name] {
+		return fmt.Errorf("duplicate entry in archive: %q", name)
+	}
+
+	p, err := ioutil.ReadAll(io.LimitReader(r, int64(maxArchiveSize-fs.total+1)))
+	if err != nil {
+		return err
+	}
+	if fs.total += len(p); fs.total > maxArchiveSize {
+		return errArchiveTooLarge
+	}
+
+	if fs.names == nil {
+		fs.names = make(map[string]bool)
+	}
+	fs.names[name] = true
+	fs.files = append(fs.files, &archiveFile{name, p})
+	return nil
+}
+
+func readZip(data []byte) ([]*archiveFile, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	} else if len(r.File) > maxArchiveEntries {
+		return nil, errArchiveTooManyEntries
+	}
+
+	var fs archiveFiles
+	for _, f := range r.File {
+		// Skip directories and symbolic links.
+		if !f.Mode().IsRegular() {
+			continue
+		} else if f.UncompressedSize64 > uint64(maxArchiveSize-fs.total) {
+			return nil, errArchiveTooLarge
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		err = fs.add(f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fs.files, nil
+}
+
+// limitedReader is like io.LimitedReader but fails with errArchiveTooLarge
+// once the limit is reached, so decompression of archive bombs stops early.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, errArchiveTooLarge
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+func readTarGz(data []byte) ([]*archiveFile, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	// Headers and contents of skipped entries are also decompressed.
+	tr := tar.NewReader(&limitedReader{gr, 2 * maxArchiveSize})
+	var fs archiveFiles
+	for n := 0; ; n++ {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		} else if n >= maxArchiveEntries {
+			return nil, errArchiveTooManyEntries
+		}
+
+		// Skip directories, links and special files.
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		} else if hdr.Size > int64(maxArchiveSize-fs.total) {
+			return nil, errArchiveTooLarge
+		}
+		if err = fs.add(hdr.Name, tr); err != nil {
+			return nil, err
+		}
+	}
+	return fs.files, nil
+}
+
+// WalkArchive walks the package at root of the zip or gzipped tarball file.
+// The single top-level directory that archives of code hosting services
+// usually have is stripped. The import path is derived from go.mod file
+// in the archive when it is empty.
+func WalkArchive(filename, importPath string) (*Package, error) {
+	return WalkArchiveDir(filename, "", importPath)
+}
+
+// WalkArchiveDir walks the package in the directory of the archive, which is
+// relative to root of the archive after the single top-level directory is stripped.
+// The import path is derived from go.mod file at root of the archive when it is empty.
+func WalkArchiveDir(filename, dir, importPath string) (*Package, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return WalkArchiveData(filename, data, dir, importPath)
+}
+
+// WalkArchiveData is same as WalkArchiveDir but walks the archive in memory,
+// e.g. an uploaded one. The format and the default import path are derived
+// from the file name. Nothing is stored.
+func WalkArchiveData(filename string, data []byte, dir, importPath string) (*Package, error) {
+	dir = strings.Trim(dir, "/")
+	var (
+		files []*archiveFile
+		err   error
+	)
+	switch {
+	case strings.HasSuffix(filename, ".zip"):
+		files, err = readZip(data)
+	case strings.HasSuffix(filename, ".tar.gz"), strings.HasSuffix(filename, ".tgz"):
+		files, err = readTarGz(data)
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %s", filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read archive: %v", err)
+	} else if len(files) == 0 {
+		return nil, errors.New("empty archive")
+	}
+
+	// Strip the single top-level directory.
+	prefix := files[0].name[:strings.Index(files[0].name, "/")+1]
+	for _, f := range files {
+		if len(prefix) == 0 || !strings.HasPrefix(f.name, prefix) {
+			prefix = ""
+			break
+		}
+	}
+
+	var (
+		srcs       []*Source
+		modulePath string
+	)
+	for _, f := range files {
+		name := strings.TrimPrefix(f.name, prefix)
+		if name == "go.mod" {
+			modulePath, _ = parseGoMod(f.data)
+		}
+
+		if len(dir) > 0 {
+			if !strings.HasPrefix(name, dir+"/") {
+				continue
+			}
+			name = strings.TrimPrefix(name, dir+"/")
+		}
+		if strings.Contains(name, "/") || strings.HasPrefix(name, ".") {
+			continue
+		}
+		srcs = append(srcs, &Source{
+			SrcName:   name,
+			BrowseUrl: path.Join(filepath.Base(filename), f.name),
+			SrcData:   f.data,
+		})
+	}
+	if len(importPath) == 0 {
+		importPath = modulePath
+		if len(importPath) == 0 {
+			importPath = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(
+				filepath.Base(filename), ".zip"), ".tar.gz"), ".tgz")
+		}
+		if len(dir) > 0 {
+			importPath += "/" + dir
+		}
+	}
+
+	w := &Walker{
+		Fetcher: "archive",
+		LineFmt: "#L%d",
+		Pdoc: &Package{
+			PkgInfo: &models.PkgInfo{
+				ImportPath: importPath,
+			},
+		},
+	}
+	return w.Build(&WalkRes{
+		WalkDepth: WD_All,
+		WalkType:  WT_Memory,
+		WalkMode:  defaultWalkMode(),
+		Srcs:      srcs,
+	})
+}
+
+// IsArchive returns true if the file name has extension of supported archives.
+func IsArchive(name string) bool {
+	return com.IsFile(name) && (strings.HasSuffix(name, ".zip") ||
+		strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"))
+}
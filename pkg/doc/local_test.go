@@ -0,0 +1,197 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+func TestArchivePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "repo-master/p.go", want: "repo-master/p.go"},
+		{name: "repo-master/./a//p.go", want: "repo-master/a/p.go"},
+		{name: `repo-master\a\p.go`, want: "repo-master/a/p.go"},
+		{name: "repo-master/", want: "repo-master"},
+		{name: "a..b/p.go", want: "a..b/p.go"},
+		{name: "/etc/passwd", wantErr: true},
+		{name: `\etc\passwd`, wantErr: true},
+		{name: `C:\p.go`, wantErr: true},
+		{name: "c:p.go", wantErr: true},
+		{name: "../p.go", wantErr: true},
+		{name: "repo/../../p.go", wantErr: true},
+		{name: `repo\..\p.go`, wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := archivePath(test.name)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("archivePath(%q) = %q, want error", test.name, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("archivePath(%q) = %q, %v, want %q", test.name, got, err, test.want)
+		}
+	}
+}
+
+// archiveEntry is an entry of archives created by tests.
+type archiveEntry struct {
+	name string
+	size int64 // Declared size of tarball entries when it is not the size of data.
+	data string
+	dir  bool
+}
+
+func testZip(t *testing.T, entries []archiveEntry) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		name := e.name
+		if e.dir {
+			name += "/"
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(e.data))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testTarGz writes headers of entries as declared, contents of entries that are
+// declared larger than their data are not completed.
+func testTarGz(t *testing.T, entries []archiveEntry) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.data)), Typeflag: tar.TypeReg}
+		if e.dir {
+			hdr.Typeflag, hdr.Size = tar.TypeDir, 0
+		} else if e.size > 0 {
+			hdr.Size = e.size
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(e.data))
+	}
+	tw.Flush()
+	gw.Close()
+	return buf.Bytes()
+}
+
+func TestReadArchive(t *testing.T) {
+	many := make([]archiveEntry, maxArchiveEntries+1)
+	for i := range many {
+		many[i] = archiveEntry{name: "repo/d", dir: true}
+	}
+
+	tests := []struct {
+		name    string
+		entries []archiveEntry
+		want    []string // Names of files.
+		wantErr error
+	}{
+		{
+			name: "files",
+			entries: []archiveEntry{
+				{name: "repo", dir: true},
+				{name: "repo/p.go", data: "package p\n"},
+				{name: "repo/./q.go", data: "package p\n"},
+			},
+			want: []string{"repo/p.go", "repo/q.go"},
+		},
+		{
+			name: "path escapes archive",
+			entries: []archiveEntry{
+				{name: "../p.go", data: "package p\n"},
+			},
+		},
+		{
+			name: "duplicate entries",
+			entries: []archiveEntry{
+				{name: "repo/p.go", data: "package p\n"},
+				{name: "repo//p.go", data: "package q\n"},
+			},
+		},
+		{
+			name:    "too many entries",
+			entries: many,
+			wantErr: errArchiveTooManyEntries,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for format, read := range map[string]func([]byte) ([]*archiveFile, error){
+				"zip":    readZip,
+				"tar.gz": readTarGz,
+			} {
+				var data []byte
+				if format == "zip" {
+					data = testZip(t, test.entries)
+				} else {
+					data = testTarGz(t, test.entries)
+				}
+
+				files, err := read(data)
+				if test.want == nil {
+					if err == nil || (test.wantErr != nil && err != test.wantErr) {
+						t.Errorf("%s: got error %v, want %v", format, err, test.wantErr)
+					}
+					continue
+				} else if err != nil {
+					t.Fatalf("%s: %v", format, err)
+				}
+
+				names := make([]string, len(files))
+				for i, f := range files {
+					names[i] = f.name
+				}
+				if strings.Join(names, ",") != strings.Join(test.want, ",") {
+					t.Errorf("%s: got files %q, want %q", format, names, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadArchive_TooLarge(t *testing.T) {
+	// Declared size of the entry is checked before reading.
+	data := testTarGz(t, []archiveEntry{{name: "repo/p.go", size: maxArchiveSize + 1, data: "package p\n"}})
+	if _, err := readTarGz(data); err != errArchiveTooLarge {
+		t.Fatalf("got error %v, want %v", err, errArchiveTooLarge)
+	}
+
+	// Total size of files is checked when reading.
+	fs := archiveFiles{total: maxArchiveSize - 1}
+	if err := fs.add("repo/p.go", strings.NewReader("package p\n")); err != errArchiveTooLarge {
+		t.Fatalf("got error %v, want %v", err, errArchiveTooLarge)
+	}
+}
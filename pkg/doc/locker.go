@@ -0,0 +1,69 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+type memoryLock struct {
+	token   string
+	expires time.Time
+}
+
+// MemoryLocker is a Locker of a single process, which is used by default
+// so requests and crawlers of the process never walk the same package at
+// the same time. It is safe for concurrent use.
+type MemoryLocker struct {
+	lock  sync.Mutex
+	locks map[string]memoryLock
+}
+
+// NewMemoryLocker returns a new MemoryLocker.
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{
+		locks: make(map[string]memoryLock),
+	}
+}
+
+func (l *MemoryLocker) Lock(key string, ttl time.Duration) (string, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if lock, ok := l.locks[key]; ok && now.Before(lock.expires) {
+		return "", nil
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	l.locks[key] = memoryLock{token, now.Add(ttl)}
+	return token, nil
+}
+
+func (l *MemoryLocker) Unlock(key, token string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.locks[key].token == token {
+		delete(l.locks, key)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Unknwon/com"
+)
+
+// Maintainer represents a person or team who maintains the package.
+type Maintainer struct {
+	Name          string
+	Email         string
+	Handle        string // Username or team name of the VCS host, without "@".
+	Source        string // One of "CODEOWNERS", "MAINTAINERS", "go.mod" and "contributors".
+	Contributions int    // Number of commits, only available from contributors.
+}
+
+var (
+	maintainerEmailPattern  = regexp.MustCompile(`<?([A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]+)>?`)
+	maintainerHandlePattern = regexp.MustCompile(`(?:^|[\s(\[])@([A-Za-z0-9][A-Za-z0-9-]*(?:/[A-Za-z0-9_.-]+)?)|github\.com/([A-Za-z0-9][A-Za-z0-9-]*)`)
+)
+
+// parseMaintainer parses a maintainer in forms like "Name <email> (@handle)",
+// "[Name](https://github.com/handle)" or "@handle".
+func parseMaintainer(s, source string) *Maintainer {
+	m := &Maintainer{Source: source}
+	if match := maintainerEmailPattern.FindStringSubmatch(s); match != nil {
+		m.Email = match[1]
+		s = strings.Replace(s, match[0], "", 1)
+	}
+	if match := maintainerHandlePattern.FindStringSubmatch(s); match != nil {
+		m.Handle = match[1] + match[2]
+		s = strings.Replace(s, match[0], "", 1)
+	}
+
+	// What left is the name, remove Markdown links and list markers.
+	if i := strings.Index(s, "]("); i > -1 {
+		s = s[:i]
+	}
+	m.Name = strings.Trim(s, " \t-*+[]()<>,")
+	if len(m.Name) == 0 && len(m.Email) == 0 && len(m.Handle) == 0 {
+		return nil
+	}
+	return m
+}
+
+// parseMaintainersFile parses a MAINTAINERS file that has a maintainer per line.
+func parseMaintainersFile(data []byte) []*Maintainer {
+	var maintainers []*Maintainer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' || line[0] == '=' || strings.HasSuffix(line, ":") {
+			continue
+		}
+		if m := parseMaintainer(line, "MAINTAINERS"); m != nil {
+			maintainers = append(maintainers, m)
+		}
+	}
+	return maintainers
+}
+
+// codeOwnersMatch returns true if the pattern of CODEOWNERS matches the directory,
+// which is relative to the repository root.
+func codeOwnersMatch(pattern, dir string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+	pattern = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(pattern, "**"), "*"), "/")
+	switch {
+	case len(pattern) == 0:
+		return true
+	case !strings.Contains(pattern, "/") && strings.Contains(pattern, "*"):
+		// Patterns of file names, e.g. "*.go".
+		ok, _ := path.Match(pattern, "doc.go")
+		return ok
+	case dir == pattern || strings.HasPrefix(dir, pattern+"/"):
+		return true
+	}
+	ok, _ := path.Match(pattern, dir)
+	return ok
+}
+
+// parseCodeOwners returns owners of the directory in CODEOWNERS file,
+// the last matching pattern takes the precedence.
+func parseCodeOwners(data []byte, dir string) []*Maintainer {
+	var owners []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || !codeOwnersMatch(fields[0], dir) {
+			continue
+		}
+		owners = fields[1:]
+	}
+
+	maintainers := make([]*Maintainer, 0, len(owners))
+	for _, owner := range owners {
+		if strings.HasPrefix(owner, "#") {
+			break
+		}
+		if m := parseMaintainer(owner, "CODEOWNERS"); m != nil {
+			maintainers = append(maintainers, m)
+		}
+	}
+	return maintainers
+}
+
+// parseGoModMaintainers parses maintainers in comments of go.mod
+// like "// Maintainers: Name <email>, @handle".
+func parseGoModMaintainers(data []byte) []*Maintainer {
+	var maintainers []*Maintainer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "//") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "//"))
+		i := strings.Index(line, ":")
+		if i == -1 {
+			continue
+		}
+		switch strings.ToLower(line[:i]) {
+		case "maintainer", "maintainers", "owner", "owners", "author", "authors":
+		default:
+			continue
+		}
+
+		for _, s := range strings.Split(line[i+1:], ",") {
+			if m := parseMaintainer(s, "go.mod"); m != nil {
+				maintainers = append(maintainers, m)
+			}
+		}
+	}
+	return maintainers
+}
+
+// setMaintainers parses maintainer information from the file.
+func (w *Walker) setMaintainers(src *Source) {
+	var maintainers []*Maintainer
+	switch {
+	case src.Name() == "CODEOWNERS":
+		maintainers = parseCodeOwners(src.Data(), strings.TrimPrefix(w.Pdoc.ImportPath, w.Pdoc.ProjectPath+"/"))
+	case src.Name() == "go.mod":
+		maintainers = parseGoModMaintainers(src.Data())
+	default:
+		maintainers = parseMaintainersFile(src.Data())
+	}
+	w.Pdoc.Maintainers = append(w.Pdoc.Maintainers, maintainers...)
+}
+
+// getGitHubContributors returns top contributors of the GitHub repository.
+func getGitHubContributors(match map[string]string) ([]*Maintainer, error) {
+	var contributors []struct {
+		Login         string `json:"login"`
+		Type          string `json:"type"`
+		Contributions int    `json:"contributions"`
+	}
+	if err := com.HttpGetJSON(Client,
+		com.Expand("https://api.github.com/repos/{owner}/{repo}/contributors?per_page=10&{cred}", match), &contributors); err != nil {
+		return nil, fmt.Errorf("get contributors: %v", err)
+	}
+
+	maintainers := make([]*Maintainer, 0, len(contributors))
+	for _, c := range contributors {
+		if c.Type == "Bot" {
+			continue
+		}
+		maintainers = append(maintainers, &Maintainer{
+			Handle:        c.Login,
+			Source:        "contributors",
+			Contributions: c.Contributions,
+		})
+	}
+	return maintainers, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Unknwon/gowalker/models"
+)
+
+// WalkFiles walks the package of given files in memory, which are keyed by names
+// in the package directory. The import path is derived from go.mod file when it
+// is empty. It does not access the file system or network, and is used where
+// neither is available, e.g. in browsers.
+func WalkFiles(importPath string, files map[string][]byte) (*Package, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	srcs := make([]*Source, 0, len(names))
+	for _, name := range names {
+		if strings.Contains(name, "/") || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if name == "go.mod" && len(importPath) == 0 {
+			importPath, _ = parseGoMod(files[name])
+		}
+		srcs = append(srcs, &Source{
+			SrcName:   name,
+			BrowseUrl: path.Join(importPath, name),
+			SrcData:   files[name],
+		})
+	}
+	if len(importPath) == 0 {
+		return nil, errors.New("import path is required without go.mod file")
+	}
+
+	w := &Walker{
+		Fetcher: "memory",
+		LineFmt: "#L%d",
+		Pdoc: &Package{
+			PkgInfo: &models.PkgInfo{
+				ImportPath: importPath,
+			},
+		},
+	}
+	return w.Build(&WalkRes{
+		WalkDepth: WD_All,
+		WalkType:  WT_Memory,
+		WalkMode:  defaultWalkMode(),
+		Srcs:      srcs,
+	})
+}
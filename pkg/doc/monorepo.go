@@ -0,0 +1,112 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// RepoMapping maps packages under an import path prefix to a subdirectory of a
+// repository, so that modules published from a monorepo are walked from their own
+// directories without <meta> tags or fetchers of the organization.
+type RepoMapping struct {
+	Prefix string // Import path prefix, e.g. "go.example.com/billing".
+	Repo   string // Root of the repository, e.g. "github.com/example/platform".
+	Dir    string // Subdirectory of the prefix in the repository, e.g. "modules/billing".
+	VCS    string // VCS of repositories that are not on known hosting services, default is git.
+}
+
+var repoMappings []*RepoMapping
+
+// ParseRepoMappings parses mappings by import path prefix, each value is the root of
+// the repository followed by optional subdirectory and VCS separated by spaces, e.g.
+// "github.com/example/platform modules/billing" or "git.example.com/platform . git".
+func ParseRepoMappings(values map[string]string) ([]*RepoMapping, error) {
+	mappings := make([]*RepoMapping, 0, len(values))
+	for prefix, value := range values {
+		fields := strings.Fields(value)
+		if len(fields) == 0 || len(fields) > 3 {
+			return nil, fmt.Errorf("bad mapping of %q: %q", prefix, value)
+		}
+		m := &RepoMapping{
+			Prefix: strings.Trim(prefix, "/"),
+			Repo:   strings.Trim(fields[0], "/"),
+			VCS:    "git",
+		}
+		if len(fields) > 1 {
+			m.Dir = strings.Trim(path.Clean(fields[1]), "/.")
+		}
+		if len(fields) > 2 {
+			m.VCS = fields[2]
+		}
+		if vcsCmds[m.VCS] == nil {
+			return nil, fmt.Errorf("bad mapping of %q: VCS not supported: %s", prefix, m.VCS)
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, nil
+}
+
+// SetRepoMappings sets mappings of import path prefixes to repositories,
+// the longest matched prefix applies.
+func SetRepoMappings(mappings []*RepoMapping) {
+	sort.SliceStable(mappings, func(i, j int) bool {
+		return len(mappings[i].Prefix) > len(mappings[j].Prefix)
+	})
+	repoMappings = mappings
+}
+
+// repoMappingOf returns the mapping that applies to the import path, or nil if none.
+func repoMappingOf(importPath string) *RepoMapping {
+	for _, m := range repoMappings {
+		if importPath == m.Prefix || strings.HasPrefix(importPath, m.Prefix+"/") {
+			return m
+		}
+	}
+	return nil
+}
+
+// getMapped gets the document of the package from the repository of the mapping.
+// The prefix is used as project path, so that each mapped module is walked separately.
+func getMapped(m *RepoMapping, importPath, etag string) (*Package, error) {
+	dir := path.Join(m.Dir, strings.TrimPrefix(importPath, m.Prefix))
+	if dir = strings.Trim(dir, "/"); len(dir) > 0 {
+		dir = "/" + dir
+	}
+	match := map[string]string{
+		// Same as matches of go-import meta tags, see parseMeta.
+		"importPath":  importPath,
+		"repo":        m.Repo,
+		"vcs":         m.VCS,
+		"dir":         dir,
+		"projectRoot": m.Prefix,
+		"projectName": path.Base(m.Prefix),
+		"projectURL":  "https://" + m.Prefix,
+	}
+
+	pdoc, err := getStaticWithTemplates(m.Repo+dir, etag, nil)
+	if err == ErrNoServiceMatch {
+		return getVCSDoc(match, etag)
+	} else if err != nil {
+		return nil, err
+	}
+	pdoc.ImportPath = importPath
+	pdoc.ProjectPath = m.Prefix
+	pdoc.SupersededBy = supersededBy(importPath, m.Prefix, pdoc.ModulePath, pdoc.Deprecated)
+	return pdoc, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bytes"
+	"go/doc"
+	"html/template"
+	"io"
+)
+
+// Style of standalone pages, which is allowed by its hash in CSPPolicy.
+const pageStyle = `
+body { font-family: sans-serif; max-width: 960px; margin: 0 auto; padding: 1em; }
+pre { background: #f5f5f5; padding: .5em; overflow-x: auto; }
+`
+
+var pageTpl = template.Must(template.New("page").Funcs(template.FuncMap{
+	"comment": func(text string) template.HTML {
+		var buf bytes.Buffer
+		doc.ToHTML(&buf, text, nil)
+		return template.HTML(buf.String())
+	},
+	"safe": func(s string) template.HTML {
+		return template.HTML(s)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.ImportPath}} - Go Walker</title>
+<style>` + pageStyle + `</style>
+</head>
+<body>
+<h1>{{if .IsCmd}}Command{{else}}Package{{end}} {{.ImportPath}}</h1>
+{{if .Deprecated}}<p><b>Deprecated:</b> {{.Deprecated}}</p>{{end}}
+{{safe .Doc}}
+{{define "values"}}{{range .}}<pre>{{.Decl}}</pre>{{comment .Doc}}{{end}}{{end}}
+{{define "funcs"}}{{range .}}<h3 id="{{.Name}}">func {{.Name}}</h3><pre>{{.Decl}}</pre>{{comment .Doc}}{{end}}{{end}}
+{{if .Consts}}<h2>Constants</h2>{{template "values" .Consts}}{{end}}
+{{if .Vars}}<h2>Variables</h2>{{template "values" .Vars}}{{end}}
+{{if .Funcs}}<h2>Functions</h2>{{template "funcs" .Funcs}}{{end}}
+{{if .Types}}<h2>Types</h2>{{range .Types}}
+<h3 id="{{.Name}}">type {{.Name}}</h3>
+<pre>{{.Decl}}</pre>
+{{comment .Doc}}
+{{template "values" .Consts}}
+{{template "values" .Vars}}
+{{template "funcs" .Funcs}}
+{{template "funcs" .Methods}}
+{{end}}{{end}}
+</body>
+</html>
+`))
+
+// WritePage writes a standalone HTML page of the package documentation, which
+// does not need templates and static files of the server.
+func WritePage(w io.Writer, pdoc *Package) error {
+	return pageTpl.Execute(w, pdoc)
+}
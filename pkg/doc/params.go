@@ -0,0 +1,149 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"go/ast"
+	"go/types"
+	"regexp"
+	"strings"
+)
+
+// Param represents a parameter or result of a function.
+type Param struct {
+	Name string // Empty for unnamed ones.
+	Type string
+	Doc  string // Sentences of the doc comment of the function that describe it.
+}
+
+// HasParamDocs returns true if any parameter or result of the function is documented.
+func (f *Func) HasParamDocs() bool {
+	for _, list := range [][]Param{f.Params, f.Results} {
+		for _, p := range list {
+			if len(p.Doc) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var sentenceEnd = regexp.MustCompile(`[.!?]\s+`)
+
+// sentences splits plain text doc comment into sentences with collapsed spaces.
+func sentences(text string) []string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) == 0 {
+		return nil
+	}
+
+	var list []string
+	start := 0
+	for _, loc := range sentenceEnd.FindAllStringIndex(text, -1) {
+		list = append(list, text[start:loc[0]+1])
+		start = loc[1]
+	}
+	if start < len(text) {
+		list = append(list, text[start:])
+	}
+	return list
+}
+
+// paramDoc returns sentences that describe the named parameter or result by
+// conventions like "The ctx parameter ...", "The n result ..." and "ctx is ...".
+func paramDoc(sents []string, name string) string {
+	if len(name) == 0 || name == "_" {
+		return ""
+	}
+
+	name = regexp.QuoteMeta(name)
+	mention := regexp.MustCompile(`(?i:\bthe\s+)?\b` + name +
+		`\s+(?i:parameter|param|argument|arg|result|return value)s?\b`)
+	subject := regexp.MustCompile(`^(?i:the\s+)?` + name +
+		`\s+(?:is|are|must|should|may|can|specifies|controls|determines|defaults|indicates|receives)\b`)
+
+	var doc []string
+	for _, s := range sents {
+		if mention.MatchString(s) || subject.MatchString(s) {
+			doc = append(doc, s)
+		}
+	}
+	return strings.Join(doc, " ")
+}
+
+// resultDoc returns sentences that describe an unnamed result, which are the ones
+// mention "returns" for the only non-error result, or also "error" for the error result.
+func resultDoc(sents []string, typ string, only bool) string {
+	var doc []string
+	for _, s := range sents {
+		lower := strings.ToLower(s)
+		if !strings.Contains(lower, "return") {
+			continue
+		}
+		switch {
+		case typ == "error" && strings.Contains(lower, "error"),
+			only && typ != "error":
+			doc = append(doc, s)
+		}
+	}
+	return strings.Join(doc, " ")
+}
+
+// fieldParams converts the field list of a function type to parameters,
+// which are documented by sentences of the doc comment.
+func fieldParams(fields *ast.FieldList, sents []string, isResult bool) []Param {
+	if fields == nil {
+		return nil
+	}
+
+	var params []Param
+	for _, field := range fields.List {
+		typ := types.ExprString(field.Type)
+		if len(field.Names) == 0 {
+			params = append(params, Param{Type: typ})
+			continue
+		}
+		for _, name := range field.Names {
+			params = append(params, Param{
+				Name: name.Name,
+				Type: typ,
+				Doc:  paramDoc(sents, name.Name),
+			})
+		}
+	}
+
+	if isResult {
+		values := 0
+		for _, p := range params {
+			if p.Type != "error" {
+				values++
+			}
+		}
+		for i := range params {
+			if len(params[i].Name) == 0 {
+				params[i].Doc = resultDoc(sents, params[i].Type, values == 1)
+			}
+		}
+	}
+	return params
+}
+
+// setParams sets parameters and results of the function from its declaration
+// and plain text doc comment.
+func setParams(f *Func, decl *ast.FuncDecl) {
+	sents := sentences(f.Doc)
+	f.Params = fieldParams(decl.Type.Params, sents, false)
+	f.Results = fieldParams(decl.Type.Results, sents, true)
+}
@@ -0,0 +1,148 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "gopkg.in/clog.v1"
+)
+
+// Playground shares programs of examples to the Go Playground, so rendered
+// documentation can link to run them.
+type Playground struct {
+	ShareURL string       // Endpoint of the share API, e.g. https://play.golang.org/share
+	LinkURL  string       // Prefix of links to shared programs, e.g. https://go.dev/play/p/
+	Client   *http.Client // Default client with timeout is used if nil.
+}
+
+var defaultPlayClient = &http.Client{Timeout: 10 * time.Second}
+
+// Share posts the program and returns the link to it.
+func (p *Playground) Share(code string) (string, error) {
+	client := p.Client
+	if client == nil {
+		client = defaultPlayClient
+	}
+	resp, err := client.Post(p.ShareURL, "text/plain; charset=utf-8", strings.NewReader(code))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	} else if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+
+	id := string(bytes.TrimSpace(data))
+	if len(id) == 0 || strings.ContainsAny(id, "/?#<>\"' ") {
+		return "", fmt.Errorf("unexpected share ID %q", id)
+	}
+	return p.LinkURL + id, nil
+}
+
+// maxPlayLinks is the maximum number of links of shared programs to be cached.
+const maxPlayLinks = 10000
+
+var (
+	playground *Playground
+	playLinks  = struct {
+		sync.Mutex
+		links map[[sha256.Size]byte]string // Hash of program -> link.
+	}{links: make(map[[sha256.Size]byte]string)}
+	// Programs to be shared in background.
+	playQueue = make(chan string, 100)
+)
+
+// SetPlayground sets the playground to share examples of fetched public packages,
+// and starts sharing programs in background. Passing nil disables sharing.
+func SetPlayground(p *Playground) {
+	if playground == nil && p != nil {
+		go sharePlays()
+	}
+	playground = p
+}
+
+// setPlayLinks sets links of runnable examples that have been shared, and queues
+// the others to be shared in background, so walks never wait for the playground.
+// Links of the queued ones are set when the package is walked again.
+func setPlayLinks(examples []*Example) {
+	if playground == nil {
+		return
+	}
+
+	for _, e := range examples {
+		if len(e.Play) == 0 {
+			continue
+		}
+
+		sum := sha256.Sum256([]byte(e.Play))
+		playLinks.Lock()
+		link, ok := playLinks.links[sum]
+		playLinks.Unlock()
+		if ok {
+			e.PlayURL = link
+			continue
+		}
+
+		select {
+		case playQueue <- e.Play:
+		default:
+			// Drop the program when the queue is full, it is queued again next time.
+		}
+	}
+}
+
+// sharePlays shares queued programs to the playground one by one.
+func sharePlays() {
+	for code := range playQueue {
+		p := playground
+		if p == nil {
+			continue
+		}
+
+		sum := sha256.Sum256([]byte(code))
+		playLinks.Lock()
+		_, ok := playLinks.links[sum]
+		playLinks.Unlock()
+		if ok {
+			continue
+		}
+
+		link, err := p.Share(code)
+		if err != nil {
+			log.Warn("Failed to share example to the playground: %v", err)
+			continue
+		}
+
+		playLinks.Lock()
+		if len(playLinks.links) >= maxPlayLinks {
+			playLinks.links = make(map[[sha256.Size]byte]string)
+		}
+		playLinks.links[sum] = link
+		playLinks.Unlock()
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	log "gopkg.in/clog.v1"
+)
+
+// PostProcessor enriches packages after they are built by the walker, e.g. adds
+// links to internal systems or metadata of the organization.
+type PostProcessor interface {
+	Process(ctx context.Context, pdoc *Package) error
+}
+
+// PostProcessorFunc is an adapter to use ordinary functions as post-processors.
+type PostProcessorFunc func(ctx context.Context, pdoc *Package) error
+
+func (f PostProcessorFunc) Process(ctx context.Context, pdoc *Package) error {
+	return f(ctx, pdoc)
+}
+
+type namedPostProcessor struct {
+	name string
+	PostProcessor
+}
+
+var postProcessors struct {
+	sync.RWMutex
+	list []namedPostProcessor
+}
+
+// RegisterPostProcessor registers the post-processor by unique name. Post-processors
+// run in the order of registration after packages are walked.
+func RegisterPostProcessor(name string, p PostProcessor) error {
+	postProcessors.Lock()
+	defer postProcessors.Unlock()
+	for _, np := range postProcessors.list {
+		if np.name == name {
+			return fmt.Errorf("post-processor %q already exists", name)
+		}
+	}
+	postProcessors.list = append(postProcessors.list, namedPostProcessor{name, p})
+	return nil
+}
+
+// UnregisterPostProcessor removes the post-processor of given name if it exists.
+func UnregisterPostProcessor(name string) {
+	postProcessors.Lock()
+	defer postProcessors.Unlock()
+	for i, np := range postProcessors.list {
+		if np.name == name {
+			postProcessors.list = append(postProcessors.list[:i:i], postProcessors.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// postProcess runs registered post-processors over the package. Errors are logged
+// and do not stop walking, so that a broken enrichment does not take documentation
+// down, but the rest are skipped when the context is done.
+func postProcess(ctx context.Context, pdoc *Package) error {
+	postProcessors.RLock()
+	list := postProcessors.list
+	postProcessors.RUnlock()
+
+	for _, np := range list {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := np.Process(ctx, pdoc); err != nil {
+			log.Warn("Post-processor %q failed on %q: %v", np.name, pdoc.ImportPath, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"sync"
+
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+// postWalk is the work on a walked package that can take much longer than
+// fetching it, i.e. analyses and building the command. It is done in background after the
+// package is stored, so it is limited by neither the fetch timeout nor the walk lock.
+type postWalk struct {
+	store      DocStore
+	importPath string
+	version    string
+	etag       string
+	dir        string // Checked out directory of the package.
+}
+
+var (
+	// Packages to be analyzed and built in background.
+	postWalkQueue = make(chan *postWalk, 100)
+	startPostWalk sync.Once
+)
+
+// queuePostWalk queues the stored package to be analyzed and built if its files
+// are checked out, results are stored and shown when the documentation is
+// rendered again. The package is dropped when the queue is full.
+func queuePostWalk(store DocStore, pdoc *Package) {
+	if len(pdoc.checkoutDir) == 0 || (analyzer == nil && !(setting.EnableCost && pdoc.IsCmd)) {
+		return
+	}
+	startPostWalk.Do(func() { go postWalks() })
+
+	select {
+	case postWalkQueue <- &postWalk{
+		store:      store,
+		importPath: pdoc.ImportPath,
+		version:    pdoc.Tag,
+		etag:       pdoc.Etag,
+		dir:        pdoc.checkoutDir,
+	}:
+	default:
+		log.Warn("Skip analyzing and building %q: queue is full", pdoc.ImportPath)
+	}
+}
+
+// postWalks analyzes and builds queued packages one by one.
+func postWalks() {
+	for w := range postWalkQueue {
+		if err := w.run(); err != nil {
+			log.Warn("Failed to analyze and build %q: %v", w.importPath, err)
+		}
+	}
+}
+
+func (w *postWalk) run() error {
+	pdoc, err := w.store.Get(w.importPath, w.version)
+	if err == ErrDocNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	// Skip packages that are done already, or walked again since the checkout may have changed.
+	if pdoc.Etag != w.etag || pdoc.Findings != nil || pdoc.Cost != nil {
+		return nil
+	}
+
+	analyze(pdoc, w.dir)
+	if setting.EnableCost && pdoc.IsCmd {
+		if err = buildCost(pdoc, w.dir, true); err != nil {
+			log.Warn("Failed to build %q: %v", w.importPath, err)
+		}
+	}
+	return putDoc(w.store, pdoc)
+}
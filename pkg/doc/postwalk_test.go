@@ -0,0 +1,85 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Unknwon/gowalker/models"
+)
+
+type testAnalyzer []Finding
+
+func (a testAnalyzer) Analyze(pdoc *Package, dir string) ([]Finding, error) {
+	return a, nil
+}
+
+func TestPostWalk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "postwalk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := FileDocStore{Dir: dir}
+
+	findings := []Finding{{Analyzer: "printf", Message: "bad format", Filename: "p.go", Line: 1}}
+	defer SetAnalyzer(analyzer)
+	SetAnalyzer(testAnalyzer(findings))
+	if err = store.Put(&Package{
+		PkgInfo: &models.PkgInfo{ImportPath: "example.com/p", Etag: "new"},
+		PkgDecl: &PkgDecl{},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		etag     string // Etag of the queued walk.
+		findings []Finding
+	}{
+		{
+			name: "package is walked again",
+			etag: "old",
+		},
+		{
+			name:     "package is analyzed",
+			etag:     "new",
+			findings: findings,
+		},
+		{
+			name:     "package is analyzed already",
+			etag:     "new",
+			findings: findings,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := &postWalk{store: store, importPath: "example.com/p", etag: test.etag, dir: dir}
+			if err := w.run(); err != nil {
+				t.Fatal(err)
+			}
+
+			pdoc, err := store.Get("example.com/p", "")
+			if err != nil {
+				t.Fatal(err)
+			} else if !reflect.DeepEqual(pdoc.Findings, test.findings) {
+				t.Fatalf("got %+v, want %+v", pdoc.Findings, test.findings)
+			}
+		})
+	}
+}
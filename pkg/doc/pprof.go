@@ -0,0 +1,336 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// HotPath indicates a function takes a significant share of samples in a profile.
+type HotPath struct {
+	Flat float64 // Percentage of samples in the function itself.
+	Cum  float64 // Percentage of samples in the function and its callees.
+	Rank int     // 1 for the hottest function of the package.
+}
+
+// ProfileFunc is the samples of a function in a profile.
+type ProfileFunc struct {
+	Name      string // e.g. "github.com/foo/bar.(*Client).Do".
+	Flat, Cum int64
+}
+
+// Profile is a pprof profile reduced to samples of functions.
+type Profile struct {
+	SampleType string // e.g. "cpu".
+	Unit       string // e.g. "nanoseconds".
+	Total      int64
+	Funcs      map[string]*ProfileFunc
+}
+
+// Profiles are encoded in protocol buffers wire format, see profile.proto of
+// github.com/google/pprof, which is decoded by hand to avoid the dependency.
+const (
+	pbVarint = 0
+	pbBytes  = 2
+)
+
+var errProfileTruncated = errors.New("truncated profile")
+
+type pbField struct {
+	num    int
+	varint uint64
+	data   []byte
+}
+
+// pbEachField calls fn with fields of the message in order.
+func pbEachField(b []byte, fn func(f pbField) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errProfileTruncated
+		}
+		b = b[n:]
+
+		f := pbField{num: int(tag >> 3)}
+		switch wireType := tag & 7; wireType {
+		case pbVarint:
+			if f.varint, n = binary.Uvarint(b); n <= 0 {
+				return errProfileTruncated
+			}
+			b = b[n:]
+		case pbBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				return errProfileTruncated
+			}
+			f.data = b[n : n+int(size)]
+			b = b[n+int(size):]
+		case 1: // 64-bit
+			if len(b) < 8 {
+				return errProfileTruncated
+			}
+			b = b[8:]
+			continue
+		case 5: // 32-bit
+			if len(b) < 4 {
+				return errProfileTruncated
+			}
+			b = b[4:]
+			continue
+		default:
+			return fmt.Errorf("unsupported wire type %d", wireType)
+		}
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pbNumbers appends numbers of a repeated integer field, which are either packed or not.
+func pbNumbers(list []uint64, f pbField) ([]uint64, error) {
+	if f.data == nil {
+		return append(list, f.varint), nil
+	}
+	for b := f.data; len(b) > 0; {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errProfileTruncated
+		}
+		list = append(list, v)
+		b = b[n:]
+	}
+	return list, nil
+}
+
+// ParseProfile parses a profile in pprof format, which is usually gzipped.
+// Samples are measured by the default sample type, or the last one if unset.
+func ParseProfile(data []byte) (*Profile, error) {
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = ioutil.ReadAll(gr); err != nil {
+			return nil, fmt.Errorf("decompress: %v", err)
+		}
+	}
+
+	var (
+		strs        []string
+		sampleTypes [][2]uint64             // Indexes of type and unit in string table.
+		samples     [][2][]uint64           // Location IDs and values.
+		locations   = map[uint64][]uint64{} // ID -> function IDs, innermost first.
+		funcs       = map[uint64]uint64{}   // ID -> index of name in string table.
+		defaultType uint64
+	)
+	err := pbEachField(data, func(f pbField) (err error) {
+		switch f.num {
+		case 1: // sample_type
+			var vt [2]uint64
+			err = pbEachField(f.data, func(f pbField) error {
+				if f.num == 1 || f.num == 2 {
+					vt[f.num-1] = f.varint
+				}
+				return nil
+			})
+			sampleTypes = append(sampleTypes, vt)
+		case 2: // sample
+			var s [2][]uint64
+			err = pbEachField(f.data, func(f pbField) (err error) {
+				if f.num == 1 || f.num == 2 {
+					s[f.num-1], err = pbNumbers(s[f.num-1], f)
+				}
+				return err
+			})
+			samples = append(samples, s)
+		case 4: // location
+			var id uint64
+			var fns []uint64
+			err = pbEachField(f.data, func(f pbField) error {
+				switch f.num {
+				case 1:
+					id = f.varint
+				case 4: // line
+					return pbEachField(f.data, func(f pbField) error {
+						if f.num == 1 {
+							fns = append(fns, f.varint)
+						}
+						return nil
+					})
+				}
+				return nil
+			})
+			locations[id] = fns
+		case 5: // function
+			var id, name uint64
+			err = pbEachField(f.data, func(f pbField) error {
+				switch f.num {
+				case 1:
+					id = f.varint
+				case 2:
+					name = f.varint
+				}
+				return nil
+			})
+			funcs[id] = name
+		case 6: // string_table
+			strs = append(strs, string(f.data))
+		case 14: // default_sample_type
+			defaultType = f.varint
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	} else if len(sampleTypes) == 0 {
+		return nil, errors.New("no sample types")
+	}
+
+	str := func(i uint64) string {
+		if i < uint64(len(strs)) {
+			return strs[i]
+		}
+		return ""
+	}
+	idx := len(sampleTypes) - 1
+	for i, vt := range sampleTypes {
+		if defaultType > 0 && vt[0] == defaultType {
+			idx = i
+		}
+	}
+
+	p := &Profile{
+		SampleType: str(sampleTypes[idx][0]),
+		Unit:       str(sampleTypes[idx][1]),
+		Funcs:      make(map[string]*ProfileFunc),
+	}
+	for _, s := range samples {
+		if idx >= len(s[1]) {
+			continue
+		}
+		v := int64(s[1][idx])
+		p.Total += v
+
+		seen := make(map[string]bool)
+		for i, loc := range s[0] {
+			for j, id := range locations[loc] {
+				name := str(funcs[id])
+				fn := p.Funcs[name]
+				if fn == nil {
+					fn = &ProfileFunc{Name: name}
+					p.Funcs[name] = fn
+				}
+				if i == 0 && j == 0 {
+					fn.Flat += v
+				}
+				if !seen[name] {
+					fn.Cum += v
+					seen[name] = true
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+// Receivers, type arguments and closures in function names of profiles,
+// e.g. "(*Client).Do", "Map[...]" and "Serve.func1".
+var (
+	profileRecvPattern    = regexp.MustCompile(`^\(\*?([^)]+)\)`)
+	profileClosurePattern = regexp.MustCompile(`(\.func\d+|\.gowrap\d+)+(\.\d+)*$`)
+)
+
+// profileSymbol returns symbol of the function name in the package,
+// e.g. "Client.Do", or empty if the function is not in the package.
+func profileSymbol(importPath, name string) string {
+	if !strings.HasPrefix(name, importPath+".") {
+		return ""
+	}
+	name = name[len(importPath)+1:]
+	name = strings.Replace(name, "[...]", "", -1)
+	name = profileRecvPattern.ReplaceAllString(name, "$1")
+	return profileClosurePattern.ReplaceAllString(name, "")
+}
+
+// ApplyProfile marks functions and methods of the package as hot paths whose
+// cumulative samples are at least the percentage of total samples of the profile.
+// Samples of closures are counted as their enclosing functions.
+func ApplyProfile(pdoc *Package, p *Profile, threshold float64) {
+	if p.Total == 0 {
+		return
+	}
+
+	// Percentages by symbol, the cumulative ones of closures may overlap
+	// so the greatest is taken.
+	stats := make(map[string]*HotPath)
+	for _, fn := range p.Funcs {
+		sym := profileSymbol(pdoc.ImportPath, fn.Name)
+		if len(sym) == 0 {
+			continue
+		}
+		h := stats[sym]
+		if h == nil {
+			h = new(HotPath)
+			stats[sym] = h
+		}
+		h.Flat += 100 * float64(fn.Flat) / float64(p.Total)
+		if cum := 100 * float64(fn.Cum) / float64(p.Total); cum > h.Cum {
+			h.Cum = cum
+		}
+	}
+
+	type hotFunc struct {
+		fn  *Func
+		ref *FuncRef
+	}
+	var hot []hotFunc
+	funcs := func(recv string, fns []*Func) {
+		for _, fn := range fns {
+			fn.HotPath = nil
+			if h := stats[recv+fn.Name]; h != nil && h.Cum >= threshold {
+				fn.HotPath = h
+				anchor := fn.Name
+				if len(recv) > 0 {
+					anchor = strings.Replace(recv, ".", "_", 1) + fn.Name
+				}
+				hot = append(hot, hotFunc{fn, &FuncRef{recv + fn.Name, anchor}})
+			}
+		}
+	}
+	funcs("", pdoc.Funcs)
+	for _, t := range pdoc.Types {
+		funcs("", t.Funcs)
+		funcs(t.Name+".", t.Methods)
+	}
+
+	sort.SliceStable(hot, func(i, j int) bool {
+		return hot[i].fn.HotPath.Cum > hot[j].fn.HotPath.Cum
+	})
+	pdoc.HotPaths = make([]*FuncRef, len(hot))
+	for i, h := range hot {
+		h.fn.HotPath.Rank = i + 1
+		pdoc.HotPaths[i] = h.ref
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func pbAppendVarint(b []byte, num int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|pbVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func pbAppendBytes(b []byte, num int, data []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|pbBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func pbPacked(vs ...uint64) []byte {
+	var b []byte
+	for _, v := range vs {
+		b = binary.AppendUvarint(b, v)
+	}
+	return b
+}
+
+// testProfile returns a profile of two samples, the default sample type is
+// the type of given index in the string table if it is not zero.
+func testProfile(defaultType uint64) []byte {
+	var b []byte
+	for _, s := range []string{"", "cpu", "nanoseconds", "samples", "count",
+		"a/b.F", "a/b.(*T).M", "a/b.F.func1", "runtime.main"} {
+		b = pbAppendBytes(b, 6, []byte(s))
+	}
+	b = pbAppendBytes(b, 1, pbAppendVarint(pbAppendVarint(nil, 1, 3), 2, 4))
+	b = pbAppendBytes(b, 1, pbAppendVarint(pbAppendVarint(nil, 1, 1), 2, 2))
+
+	// Functions of IDs 1 to 4 are names at index 5 to 8 of the string table.
+	for id := uint64(1); id <= 4; id++ {
+		b = pbAppendBytes(b, 5, pbAppendVarint(pbAppendVarint(nil, 1, id), 2, id+4))
+	}
+	// Location 2 has the closure inlined into F, the innermost is first.
+	b = pbAppendBytes(b, 4, pbAppendBytes(pbAppendVarint(nil, 1, 1), 4, pbAppendVarint(nil, 1, 2)))
+	b = pbAppendBytes(b, 4, pbAppendBytes(pbAppendBytes(pbAppendVarint(nil, 1, 2),
+		4, pbAppendVarint(nil, 1, 3)), 4, pbAppendVarint(nil, 1, 1)))
+	b = pbAppendBytes(b, 4, pbAppendBytes(pbAppendVarint(nil, 1, 3), 4, pbAppendVarint(nil, 1, 4)))
+
+	// Sample of packed numbers.
+	b = pbAppendBytes(b, 2, pbAppendBytes(pbAppendBytes(nil, 1, pbPacked(1, 2, 3)), 2, pbPacked(1, 10)))
+	// Sample of unpacked numbers.
+	s := pbAppendVarint(pbAppendVarint(nil, 1, 2), 1, 3)
+	s = pbAppendVarint(pbAppendVarint(s, 2, 1), 2, 30)
+	b = pbAppendBytes(b, 2, s)
+
+	if defaultType > 0 {
+		b = pbAppendVarint(b, 14, defaultType)
+	}
+	return b
+}
+
+func TestParseProfile(t *testing.T) {
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	gw.Write(testProfile(0))
+	gw.Close()
+
+	cpu := &Profile{
+		SampleType: "cpu",
+		Unit:       "nanoseconds",
+		Total:      40,
+		Funcs: map[string]*ProfileFunc{
+			"a/b.(*T).M":   {Name: "a/b.(*T).M", Flat: 10, Cum: 10},
+			"a/b.F.func1":  {Name: "a/b.F.func1", Flat: 30, Cum: 40},
+			"a/b.F":        {Name: "a/b.F", Cum: 40},
+			"runtime.main": {Name: "runtime.main", Cum: 40},
+		},
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want *Profile
+	}{
+		{"last sample type", testProfile(0), cpu},
+		{"gzipped", gzipped.Bytes(), cpu},
+		{"default sample type", testProfile(3), &Profile{
+			SampleType: "samples",
+			Unit:       "count",
+			Total:      2,
+			Funcs: map[string]*ProfileFunc{
+				"a/b.(*T).M":   {Name: "a/b.(*T).M", Flat: 1, Cum: 1},
+				"a/b.F.func1":  {Name: "a/b.F.func1", Flat: 1, Cum: 2},
+				"a/b.F":        {Name: "a/b.F", Cum: 2},
+				"runtime.main": {Name: "runtime.main", Cum: 2},
+			},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseProfile(test.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+
+	for _, data := range [][]byte{
+		nil,                            // No sample types.
+		pbAppendBytes(nil, 6, nil)[:1], // Truncated.
+		{0x0b},                         // Unsupported wire type.
+	} {
+		if _, err := ParseProfile(data); err == nil {
+			t.Errorf("ParseProfile(%x): got no error", data)
+		}
+	}
+}
+
+func TestProfileSymbol(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"a/b.F", "F"},
+		{"a/b.(*Client).Do", "Client.Do"},
+		{"a/b.T.M", "T.M"},
+		{"a/b.Map[...]", "Map"},
+		{"a/b.(*List[...]).Push", "List.Push"},
+		{"a/b.Serve.func1", "Serve"},
+		{"a/b.Serve.func1.2", "Serve"},
+		{"a/b.(*T).M.gowrap1", "T.M"},
+		{"a/bc.F", ""},
+		{"a/b/c.F", ""},
+	}
+	for _, test := range tests {
+		if got := profileSymbol("a/b", test.name); got != test.want {
+			t.Errorf("profileSymbol(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Unknwon/com"
+	log "gopkg.in/clog.v1"
+)
+
+// PrivatePatterns are comma-separated glob patterns of module path prefixes,
+// which have the same meaning as GOPRIVATE, GONOPROXY and GONOSUMDB.
+type PrivatePatterns struct {
+	Private string
+	NoProxy string // Default to Private if empty.
+	NoSumDB string // Default to Private if empty.
+}
+
+var privatePatterns PrivatePatterns
+
+func init() {
+	// Same as the go command by default.
+	SetPrivatePatterns(PrivatePatterns{
+		Private: os.Getenv("GOPRIVATE"),
+		NoProxy: os.Getenv("GONOPROXY"),
+		NoSumDB: os.Getenv("GONOSUMDB"),
+	})
+	SetCredentialStore(NewNetrcStore(""))
+}
+
+// SetPrivatePatterns sets patterns of private modules.
+func SetPrivatePatterns(p PrivatePatterns) {
+	if len(p.NoProxy) == 0 {
+		p.NoProxy = p.Private
+	}
+	if len(p.NoSumDB) == 0 {
+		p.NoSumDB = p.Private
+	}
+	privatePatterns = p
+}
+
+// matchPrefixPatterns returns true if any prefix of the target matches a glob
+// pattern in the comma-separated list, it is the same as the go command does.
+func matchPrefixPatterns(globs, target string) bool {
+	for len(globs) > 0 {
+		var glob string
+		if i := strings.Index(globs, ","); i >= 0 {
+			glob, globs = globs[:i], globs[i+1:]
+		} else {
+			glob, globs = globs, ""
+		}
+		glob = strings.TrimSuffix(strings.TrimSpace(glob), "/")
+		if len(glob) == 0 {
+			continue
+		}
+
+		// Match the pattern against the prefix of same number of path elements.
+		n := strings.Count(glob, "/")
+		prefix := target
+		for i := 0; i < len(target); i++ {
+			if target[i] == '/' {
+				if n == 0 {
+					prefix = target[:i]
+					break
+				}
+				n--
+			}
+		}
+		if n > 0 {
+			continue
+		}
+		if matched, _ := path.Match(glob, prefix); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPrivate returns true if the import path matches GOPRIVATE patterns.
+func IsPrivate(importPath string) bool {
+	return matchPrefixPatterns(privatePatterns.Private, importPath)
+}
+
+// isNoProxy returns true if the package must be fetched from its origin directly
+// without falling back to insecure protocols.
+func isNoProxy(importPath string) bool {
+	return matchPrefixPatterns(privatePatterns.NoProxy, importPath)
+}
+
+// isNoSumDB returns true if public databases must not be consulted for the package,
+// e.g. the vulnerability database.
+func isNoSumDB(importPath string) bool {
+	return matchPrefixPatterns(privatePatterns.NoSumDB, importPath)
+}
+
+// CredentialStore provides credentials to fetch from hosts of private modules.
+type CredentialStore interface {
+	// Credentials returns false if there are no credentials for the host.
+	Credentials(host string) (username, password string, ok bool)
+}
+
+var credentialStore CredentialStore
+
+// SetCredentialStore sets the store of credentials that are sent to hosts
+// over HTTPS by both HTTP requests and git commands.
+func SetCredentialStore(s CredentialStore) {
+	credentialStore = s
+}
+
+type netrcLogin struct {
+	username, password string
+}
+
+// NetrcStore reads credentials from a .netrc file.
+type NetrcStore struct {
+	once   sync.Once
+	path   string
+	logins map[string]netrcLogin
+}
+
+// NewNetrcStore returns the store of the .netrc file at given path, an empty path
+// means $NETRC or .netrc in the home directory.
+func NewNetrcStore(filename string) *NetrcStore {
+	if len(filename) == 0 {
+		filename = os.Getenv("NETRC")
+	}
+	if len(filename) == 0 {
+		home, _ := com.HomeDir()
+		filename = filepath.Join(home, ".netrc")
+	}
+	return &NetrcStore{path: filename}
+}
+
+// parseNetrc parses machine, default, login and password tokens of .netrc.
+func parseNetrc(data string) map[string]netrcLogin {
+	logins := make(map[string]netrcLogin)
+	var (
+		machine string
+		l       netrcLogin
+		inEntry bool
+	)
+	flush := func() {
+		if inEntry {
+			logins[machine] = l
+		}
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		switch scanner.Text() {
+		case "machine":
+			flush()
+			scanner.Scan()
+			machine, l, inEntry = scanner.Text(), netrcLogin{}, true
+		case "default":
+			// The default login is never used as the go command does,
+			// otherwise it would be sent to arbitrary hosts.
+			flush()
+			inEntry = false
+		case "login":
+			scanner.Scan()
+			l.username = scanner.Text()
+		case "password":
+			scanner.Scan()
+			l.password = scanner.Text()
+		case "macdef":
+			// Macros are not supported, and they end the entry.
+			flush()
+			inEntry = false
+		}
+	}
+	flush()
+	return logins
+}
+
+func (s *NetrcStore) Credentials(host string) (string, string, bool) {
+	s.once.Do(func() {
+		data, err := ioutil.ReadFile(s.path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Warn("Failed to read netrc %q: %v", s.path, err)
+			}
+			return
+		}
+		s.logins = parseNetrc(string(data))
+	})
+
+	l, ok := s.logins[host]
+	return l.username, l.password, ok
+}
+
+// credentials returns credentials of the host of the target from the credential
+// store, the target is the host followed by path, e.g. "git.corp.com/team/repo".
+// Credentials are only sent to targets that match GOPRIVATE or GONOPROXY patterns,
+// never to other hosts, e.g. the ones published by go-import meta tags.
+func credentials(target string) (string, string, bool) {
+	target = strings.TrimSuffix(target, "/")
+	if credentialStore == nil || (!IsPrivate(target) && !isNoProxy(target)) {
+		return "", "", false
+	}
+
+	host := target
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+	return credentialStore.Credentials(host)
+}
+
+// withCredentials returns a copy of the request with basic authentication
+// if it is sent over HTTPS to a private host and there are credentials for it.
+func withCredentials(req *http.Request) *http.Request {
+	if req.URL.Scheme != "https" || len(req.Header.Get("Authorization")) > 0 {
+		return req
+	}
+	username, password, ok := credentials(req.URL.Hostname() + req.URL.Path)
+	if !ok {
+		return req
+	}
+
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	r.SetBasicAuth(username, password)
+	return r
+}
@@ -0,0 +1,130 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseNetrc(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want map[string]netrcLogin
+	}{
+		{
+			name: "empty",
+			want: map[string]netrcLogin{},
+		},
+		{
+			name: "machines",
+			data: "machine a.com login u password p\nmachine b.com\n\tlogin v\n",
+			want: map[string]netrcLogin{
+				"a.com": {"u", "p"},
+				"b.com": {"v", ""},
+			},
+		},
+		{
+			name: "default is ignored",
+			data: "default login d password x\nmachine a.com login u\n",
+			want: map[string]netrcLogin{
+				"a.com": {"u", ""},
+			},
+		},
+		{
+			name: "macdef ends entry",
+			data: "machine a.com login u macdef init\nlogin x\n\nmachine b.com login v\n",
+			want: map[string]netrcLogin{
+				"a.com": {"u", ""},
+				"b.com": {"v", ""},
+			},
+		},
+		{
+			name: "later entry wins",
+			data: "machine a.com login u1\nmachine a.com login u2\n",
+			want: map[string]netrcLogin{
+				"a.com": {"u2", ""},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := parseNetrc(test.data); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatchPrefixPatterns(t *testing.T) {
+	tests := []struct {
+		globs, target string
+		want          bool
+	}{
+		{"corp.com", "corp.com/x/y", true},
+		{"*.corp.com", "git.corp.com/r", true},
+		{"corp.com/team", "corp.com/team/r", true},
+		{"corp.com/team", "corp.com/other", false},
+		{"corp.com/team/r/x", "corp.com/team", false},
+		{"other.com, corp.com/", "corp.com", true},
+		{"corp.co", "corp.com/x", false},
+		{"", "corp.com", false},
+	}
+	for _, test := range tests {
+		if got := matchPrefixPatterns(test.globs, test.target); got != test.want {
+			t.Errorf("matchPrefixPatterns(%q, %q) = %v, want %v", test.globs, test.target, got, test.want)
+		}
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netrc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, ".netrc")
+	if err = ioutil.WriteFile(filename, []byte("machine git.corp.com login u password p\nmachine github.com login g password q\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	defer SetPrivatePatterns(privatePatterns)
+	defer SetCredentialStore(credentialStore)
+	SetPrivatePatterns(PrivatePatterns{Private: "git.corp.com"})
+	SetCredentialStore(NewNetrcStore(filename))
+
+	tests := []struct {
+		target             string
+		username, password string
+		ok                 bool
+	}{
+		{"git.corp.com/team/repo", "u", "p", true},
+		{"git.corp.com/", "u", "p", true},
+		// Credentials are never sent to public hosts even if they are in .netrc.
+		{"github.com/foo/bar", "", "", false},
+		{"other.corp.com/repo", "", "", false},
+	}
+	for _, test := range tests {
+		username, password, ok := credentials(test.target)
+		if username != test.username || password != test.password || ok != test.ok {
+			t.Errorf("credentials(%q) = %q, %q, %v, want %q, %q, %v", test.target,
+				username, password, ok, test.username, test.password, test.ok)
+		}
+	}
+}
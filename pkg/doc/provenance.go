@@ -0,0 +1,66 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"runtime"
+	"time"
+
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+// Provenance records where documentation comes from and how it is generated,
+// so consumers can audit exactly what was documented and when.
+type Provenance struct {
+	Ref           string // Branch or tag name.
+	Commit        string // Commit ID or etag of the source.
+	Fetcher       string // Name of the fetcher, e.g. "github".
+	WalkerVersion string
+	GoVersion     string // Version of the toolchain that walked the package.
+	WalkedAt      time.Time
+	WallTime      time.Duration
+	FileHashes    map[string]string // Hex-encoded SHA-256 of files by name.
+	CheckedAt     time.Time         // Last time the upstream is found unchanged since the walk.
+}
+
+// LastChecked returns the last time the package is known to be up to date with the upstream.
+func (p *Provenance) LastChecked() time.Time {
+	if p.CheckedAt.After(p.WalkedAt) {
+		return p.CheckedAt
+	}
+	return p.WalkedAt
+}
+
+// setProvenance sets provenance of the package walked since given time.
+func (w *Walker) setProvenance(srcs []*Source, start time.Time) {
+	hashes := make(map[string]string, len(srcs))
+	for _, src := range srcs {
+		sum := sha256.Sum256(src.Data())
+		hashes[src.Name()] = hex.EncodeToString(sum[:])
+	}
+
+	w.Pdoc.Provenance = &Provenance{
+		Ref:           w.Ref,
+		Commit:        w.Pdoc.Etag,
+		Fetcher:       w.Fetcher,
+		WalkerVersion: setting.AppVer,
+		GoVersion:     runtime.Version(),
+		WalkedAt:      start.UTC(),
+		WallTime:      time.Since(start),
+		FileHashes:    hashes,
+	}
+}
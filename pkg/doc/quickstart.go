@@ -0,0 +1,137 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"go/ast"
+	"sort"
+	"strings"
+)
+
+// QuickStart is a synthesized block that shows how to start using a package.
+type QuickStart struct {
+	Constructor string // Name of the primary constructor, e.g. "New" or "NewClient".
+	Type        string // Name of the most central exported type.
+	Code        string // Code of the best example, usage sample or snippet.
+	CodeFrom    string // Where the code comes from: "example", "sample" or "snippet".
+}
+
+// typeRefCounts returns numbers of references to exported types declared by
+// the files, which do not include the declarations themselves.
+func typeRefCounts(files map[string]*ast.File) map[string]int {
+	counts := make(map[string]int)
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			ident, ok := n.(*ast.Ident)
+			if !ok || ident.Obj == nil || ident.Obj.Kind != ast.Typ || !ast.IsExported(ident.Name) {
+				return true
+			}
+			if spec, ok := ident.Obj.Decl.(*ast.TypeSpec); ok && spec.Name == ident {
+				return true
+			}
+			counts[ident.Name]++
+			return true
+		})
+	}
+	return counts
+}
+
+// constructorScore returns how likely the function is the primary constructor
+// of the package, 0 means it is not a constructor.
+func constructorScore(name, typeName, centralType string) int {
+	switch {
+	case !strings.HasPrefix(name, "New"):
+		return 0
+	case name == "New" || (typeName == centralType && name == "New"+centralType):
+		return 3
+	case typeName == centralType:
+		return 2
+	}
+	return 1
+}
+
+// setQuickStart composes the quick start of the package from the primary
+// constructor, the most central exported type and the best example.
+func (w *Walker) setQuickStart(files map[string]*ast.File) {
+	qs := new(QuickStart)
+
+	counts := typeRefCounts(files)
+	types := make([]*Type, len(w.Pdoc.Types))
+	copy(types, w.Pdoc.Types)
+	sort.SliceStable(types, func(i, j int) bool {
+		return counts[types[i].Name] > counts[types[j].Name]
+	})
+	if len(types) > 0 && counts[types[0].Name] > 0 {
+		qs.Type = types[0].Name
+	}
+
+	best := 0
+	for _, f := range w.Pdoc.Funcs {
+		if score := constructorScore(f.Name, "", qs.Type); score > best {
+			best, qs.Constructor = score, f.Name
+		}
+	}
+	for _, t := range w.Pdoc.Types {
+		for _, f := range t.Funcs {
+			if score := constructorScore(f.Name, t.Name, qs.Type); score > best {
+				best, qs.Constructor = score, f.Name
+			}
+		}
+	}
+
+	// Package examples come first, followed by examples of the constructor and the type.
+	var example *Example
+	for i, name := range []string{"", qs.Constructor, qs.Type} {
+		if i > 0 && len(name) == 0 {
+			continue
+		}
+		for _, e := range w.Pdoc.Examples {
+			if example != nil {
+				break
+			}
+			if i == 0 {
+				// Suffixes of package examples begin with lower case.
+				if len(e.Name) == 0 || !ast.IsExported(e.Name) {
+					example = e
+				}
+			} else if e.Name == name || strings.HasPrefix(e.Name, name+"_") {
+				example = e
+			}
+		}
+	}
+	switch {
+	case example != nil:
+		qs.Code, qs.CodeFrom = example.Code, "example"
+	case len(w.Pdoc.Examples) > 0:
+		qs.Code, qs.CodeFrom = w.Pdoc.Examples[0].Code, "example"
+	case len(w.Pdoc.Samples) > 0:
+		qs.Code, qs.CodeFrom = w.Pdoc.Samples[0].Code, "sample"
+	case len(w.Pdoc.Snippets) > 0:
+		snippet := w.Pdoc.Snippets[0]
+		for _, s := range w.Pdoc.Snippets {
+			if s.Verified {
+				snippet = s
+				break
+			}
+		}
+		qs.Code, qs.CodeFrom = snippet.Code, "snippet"
+	}
+
+	qs.Code = strings.TrimSpace(qs.Code)
+	if len(qs.Constructor) == 0 && len(qs.Type) == 0 && len(qs.Code) == 0 {
+		return
+	}
+	w.Pdoc.QuickStart = qs
+}
@@ -0,0 +1,243 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/pkg/base"
+)
+
+// CrawlItem is a package in the frontier of a recursive crawl.
+type CrawlItem struct {
+	ImportPath string
+	Depth      int // Hops from seeds, which are of depth 0.
+	Attempts   int `json:",omitempty"` // Times the package was being walked by others.
+}
+
+// CrawlState is the state of a recursive crawl, which is saved as checkpoints
+// to resume the crawl after a crash or restart.
+type CrawlState struct {
+	Seeds    []string
+	MaxDepth int // 0 means unlimited.
+	// Packages to be walked in order, and all packages ever added to it.
+	Frontier []CrawlItem
+	Visited  map[string]bool
+	Walked   int
+	Failed   int
+	Started  time.Time
+	Updated  time.Time // When the checkpoint is saved.
+	Done     bool
+}
+
+const (
+	// Interval between checkpoints of recursive crawls.
+	crawlCheckpointInterval = time.Minute
+	// Times to put back packages that are being walked by others.
+	maxCrawlAttempts = 3
+)
+
+// Crawl walks packages to the doc store recursively from seeds through their
+// imports, and saves checkpoints of its state to a file periodically. Packages
+// walked before a checkpoint are walked again when resumed, which reuses their
+// stored documentation if it is walked recently. It is safe for concurrent use.
+type Crawl struct {
+	path string // Where to save checkpoints, empty means not persisted.
+
+	lock    sync.Mutex
+	state   CrawlState
+	running bool
+}
+
+// NewCrawl returns a crawl of packages within maxDepth hops of seeds, 0 means unlimited.
+func NewCrawl(checkpoint string, seeds []string, maxDepth int) *Crawl {
+	c := &Crawl{
+		path: checkpoint,
+		state: CrawlState{
+			Seeds:    seeds,
+			MaxDepth: maxDepth,
+			Visited:  make(map[string]bool),
+			Started:  time.Now(),
+		},
+	}
+	for _, importPath := range seeds {
+		c.add(importPath, 0)
+	}
+	return c
+}
+
+// ErrNoCheckpoint is returned by ResumeCrawl when the checkpoint does not exist.
+var ErrNoCheckpoint = errors.New("checkpoint of crawl does not exist")
+
+// ResumeCrawl returns the crawl of the checkpoint.
+func ResumeCrawl(checkpoint string) (*Crawl, error) {
+	data, err := ioutil.ReadFile(checkpoint)
+	if os.IsNotExist(err) {
+		return nil, ErrNoCheckpoint
+	} else if err != nil {
+		return nil, fmt.Errorf("read: %v", err)
+	}
+
+	c := &Crawl{path: checkpoint}
+	if err = json.Unmarshal(data, &c.state); err != nil {
+		return nil, fmt.Errorf("decode: %v", err)
+	}
+	if c.state.Visited == nil {
+		c.state.Visited = make(map[string]bool)
+	}
+	return c, nil
+}
+
+// add appends the package to the frontier if it is not visited and should be walked,
+// the caller must hold the lock.
+func (c *Crawl) add(importPath string, depth int) {
+	if c.state.Visited[importPath] ||
+		base.IsGoRepoPath(importPath) || !base.IsValidRemotePath(importPath) || IsBlocked(importPath) {
+		return
+	}
+	c.state.Visited[importPath] = true
+	c.state.Frontier = append(c.state.Frontier, CrawlItem{ImportPath: importPath, Depth: depth})
+}
+
+// save writes the state to the checkpoint, the caller must hold the lock.
+func (c *Crawl) save() error {
+	if len(c.path) == 0 {
+		return nil
+	}
+
+	c.state.Updated = time.Now()
+	data, err := json.Marshal(c.state)
+	if err != nil {
+		return fmt.Errorf("encode: %v", err)
+	}
+	os.MkdirAll(path.Dir(c.path), os.ModePerm)
+	tmpPath := c.path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("write: %v", err)
+	}
+	if err = os.Rename(tmpPath, c.path); err != nil {
+		return fmt.Errorf("rename: %v", err)
+	}
+	return nil
+}
+
+// State returns a copy of the state of the crawl without the visited set.
+func (c *Crawl) State() CrawlState {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	state := c.state
+	state.Frontier = append([]CrawlItem(nil), c.state.Frontier...)
+	state.Visited = nil
+	return state
+}
+
+// Running returns true if the crawl is running.
+func (c *Crawl) Running() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.running
+}
+
+// next pops the next package of the frontier.
+func (c *Crawl) next() (CrawlItem, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.state.Frontier) == 0 {
+		return CrawlItem{}, false
+	}
+	item := c.state.Frontier[0]
+	c.state.Frontier = c.state.Frontier[1:]
+	return item, true
+}
+
+// Run walks packages in the frontier until it is empty, ctx is done or the server
+// is shutting down, and saves a checkpoint when it returns. It requires the doc store.
+func (c *Crawl) Run(ctx context.Context) error {
+	if docStore == nil {
+		return errors.New("doc store is not set")
+	}
+
+	c.lock.Lock()
+	if c.running {
+		c.lock.Unlock()
+		return errors.New("crawl is already running")
+	}
+	c.running = true
+	c.lock.Unlock()
+
+	lastSave := time.Now()
+	err := func() error {
+		for ctx.Err() == nil {
+			item, ok := c.next()
+			if !ok {
+				return nil
+			}
+
+			crawling.Store(item.ImportPath, true)
+			pdoc, err := prewalk(item.ImportPath)
+			crawling.Delete(item.ImportPath)
+
+			c.lock.Lock()
+			switch {
+			case err == ErrShuttingDown:
+				// Put it back to be walked when resumed.
+				c.state.Frontier = append([]CrawlItem{item}, c.state.Frontier...)
+				c.lock.Unlock()
+				return err
+			case err == ErrWalkInProgress && item.Attempts+1 < maxCrawlAttempts:
+				item.Attempts++
+				c.state.Frontier = append(c.state.Frontier, item)
+			case err != nil:
+				c.state.Failed++
+				log.Trace("Crawl: failed to walk %q: %v", item.ImportPath, err)
+			default:
+				c.state.Walked++
+				if c.state.MaxDepth == 0 || item.Depth < c.state.MaxDepth {
+					for _, importPath := range pdoc.Imports {
+						c.add(importPath, item.Depth+1)
+					}
+				}
+			}
+
+			if time.Since(lastSave) >= crawlCheckpointInterval {
+				if err = c.save(); err != nil {
+					log.Error(2, "Failed to save checkpoint of crawl: %v", err)
+				}
+				lastSave = time.Now()
+			}
+			c.lock.Unlock()
+		}
+		return ctx.Err()
+	}()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.running = false
+	c.state.Done = err == nil
+	if e := c.save(); e != nil {
+		return fmt.Errorf("save checkpoint: %v", e)
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+)
+
+// ResourceHint indicates resources that a function or type manages beyond its call.
+type ResourceHint uint
+
+const (
+	RH_Goroutine ResourceHint = 1 << iota // Starts goroutines.
+	RH_Finalizer                          // Registers finalizers by runtime.SetFinalizer.
+	RH_Closer                             // Returns or is a value that should be closed.
+)
+
+// StartsGoroutines returns true if goroutines are started.
+func (h ResourceHint) StartsGoroutines() bool {
+	return h&RH_Goroutine != 0
+}
+
+// SetsFinalizer returns true if finalizers are registered.
+func (h ResourceHint) SetsFinalizer() bool {
+	return h&RH_Finalizer != 0
+}
+
+// NeedsClose returns true if callers should remember to call Close.
+func (h ResourceHint) NeedsClose() bool {
+	return h&RH_Closer != 0
+}
+
+// isPkgSelector returns true if the expression selects name from the imported package.
+func isPkgSelector(x ast.Expr, path, name string) bool {
+	sel, ok := x.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok || pkg.Obj == nil || pkg.Obj.Kind != ast.Pkg {
+		return false
+	}
+	spec, ok := pkg.Obj.Decl.(*ast.ImportSpec)
+	if !ok {
+		return false
+	}
+	p, err := strconv.Unquote(spec.Path.Value)
+	return err == nil && p == path
+}
+
+// funcResources returns goroutines and finalizers started by bodies of function
+// declarations by their start positions, including ones in function literals.
+// It must be called before function bodies are trimmed by go/doc.
+func funcResources(files map[string]*ast.File) map[token.Pos]ResourceHint {
+	hints := make(map[token.Pos]ResourceHint)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fdecl, ok := decl.(*ast.FuncDecl)
+			if !ok || fdecl.Body == nil {
+				continue
+			}
+
+			var hint ResourceHint
+			ast.Inspect(fdecl.Body, func(node ast.Node) bool {
+				switch n := node.(type) {
+				case *ast.GoStmt:
+					hint |= RH_Goroutine
+				case *ast.CallExpr:
+					if isPkgSelector(n.Fun, "runtime", "SetFinalizer") {
+						hint |= RH_Finalizer
+					}
+				}
+				return true
+			})
+			if hint != 0 {
+				hints[fdecl.Pos()] = hint
+			}
+		}
+	}
+	return hints
+}
+
+// hasCloseMethod returns true if the type or its pointer has method Close
+// without parameters.
+func hasCloseMethod(typ types.Type) bool {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if _, ok := typ.(*types.Named); !ok {
+		return false
+	}
+
+	mset := types.NewMethodSet(typ)
+	if _, ok := typ.Underlying().(*types.Interface); !ok {
+		mset = types.NewMethodSet(types.NewPointer(typ))
+	}
+	sel := mset.Lookup(nil, "Close")
+	if sel == nil {
+		return false
+	}
+	sig, ok := sel.Obj().Type().(*types.Signature)
+	return ok && sig.Params().Len() == 0
+}
+
+// returnsCloser returns true if any result of the signature should be closed.
+func returnsCloser(sig *types.Signature) bool {
+	results := sig.Results()
+	for i := 0; i < results.Len(); i++ {
+		if hasCloseMethod(results.At(i).Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeResources returns resource hints of the type declared in the package.
+func (w *Walker) typeResources(name string) ResourceHint {
+	if w.tpkg == nil {
+		return 0
+	}
+	if tn, ok := w.tpkg.Scope().Lookup(name).(*types.TypeName); ok && hasCloseMethod(tn.Type()) {
+		return RH_Closer
+	}
+	return 0
+}
@@ -0,0 +1,246 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "gopkg.in/clog.v1"
+)
+
+var ErrRobotsDisallowed = errors.New("fetching is disallowed by robots.txt of the host")
+
+// robotsRule is an Allow or Disallow line of robots.txt.
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// match returns true if the pattern matches the path, "*" matches any sequence
+// of characters and "$" at the end matches the end of the path.
+func (r robotsRule) match(p string) bool {
+	pattern := r.pattern
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(p, parts[0]) {
+		return false
+	}
+	p = p[len(parts[0]):]
+	for i, part := range parts[1:] {
+		// The last part must match the end of the path when anchored.
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(p, part)
+		}
+		j := strings.Index(p, part)
+		if j < 0 {
+			return false
+		}
+		p = p[j+len(part):]
+	}
+	return !anchored || len(p) == 0
+}
+
+// robotsPolicy is the rules of robots.txt that apply to the user agent.
+type robotsPolicy struct {
+	rules   []robotsRule
+	fetched time.Time
+}
+
+// allowed returns true if the path is allowed. The rule of longest pattern
+// applies, and Allow wins when patterns have the same length.
+func (p *robotsPolicy) allowed(path string) bool {
+	allow, length := true, -1
+	for _, r := range p.rules {
+		if !r.match(path) {
+			continue
+		}
+		if len(r.pattern) > length || (len(r.pattern) == length && r.allow) {
+			allow, length = r.allow, len(r.pattern)
+		}
+	}
+	return allow
+}
+
+// parseRobots returns rules of the most specific group of robots.txt that
+// applies to the user agent, or rules of "*" group if no group matches.
+func parseRobots(r io.Reader, userAgent string) *robotsPolicy {
+	// Groups are matched by the product token, e.g. "go-walker" of "Go-Walker/1.0".
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexAny(token, " /"); i >= 0 {
+		token = token[:i]
+	}
+	var (
+		specific, wildcard []robotsRule
+		matchedSpecific    bool
+		inAgents           bool // Previous line is User-agent.
+		isSpecific, isAny  bool // Current group applies to the user agent.
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				isSpecific, isAny = false, false
+			}
+			inAgents = true
+
+			agent := strings.ToLower(value)
+			if agent == "*" {
+				isAny = true
+			} else if agent == token {
+				isSpecific = true
+				matchedSpecific = true
+			}
+		case "allow", "disallow":
+			inAgents = false
+			// Empty Disallow allows everything.
+			if len(value) == 0 {
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", pattern: value}
+			if isSpecific {
+				specific = append(specific, rule)
+			} else if isAny {
+				wildcard = append(wildcard, rule)
+			}
+		default:
+			inAgents = false
+		}
+	}
+
+	if matchedSpecific {
+		return &robotsPolicy{rules: specific}
+	}
+	return &robotsPolicy{rules: wildcard}
+}
+
+const robotsTTL = 24 * time.Hour
+
+var robots = struct {
+	sync.Mutex
+	userAgent string // Empty means robots.txt is not respected.
+	policies  map[string]*robotsPolicy
+}{
+	policies: make(map[string]*robotsPolicy),
+}
+
+// SetRobotsAgent sets the user agent whose rules of robots.txt are respected
+// when fetching from hosts that are not known code hosting services.
+// Passing empty string disables checking robots.txt.
+func SetRobotsAgent(userAgent string) {
+	robots.Lock()
+	defer robots.Unlock()
+	robots.userAgent = userAgent
+	robots.policies = make(map[string]*robotsPolicy)
+}
+
+// fetchRobots returns the policy of the host. Missing robots.txt allows
+// everything, and server errors disallow everything until fetched again.
+func fetchRobots(scheme, host, userAgent string) *robotsPolicy {
+	req, err := http.NewRequest("GET", scheme+"://"+host+"/robots.txt", nil)
+	if err != nil {
+		return &robotsPolicy{}
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := Client.Do(req)
+	if err != nil {
+		// The host is unreachable and fetching will fail anyway.
+		return &robotsPolicy{}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == 200:
+		return parseRobots(io.LimitReader(resp.Body, 500<<10), userAgent)
+	case resp.StatusCode >= 500:
+		log.Warn("Fetch robots.txt of %s: %s", host, resp.Status)
+		return &robotsPolicy{rules: []robotsRule{{pattern: "/"}}}
+	}
+	return &robotsPolicy{}
+}
+
+// checkRobots returns ErrRobotsDisallowed if robots.txt of the host disallows
+// the URL for the user agent.
+func checkRobots(rawURL string) error {
+	robots.Lock()
+	userAgent := robots.userAgent
+	robots.Unlock()
+	if len(userAgent) == 0 {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	key := u.Scheme + "://" + u.Host
+	robots.Lock()
+	policy := robots.policies[key]
+	robots.Unlock()
+	if policy == nil || time.Since(policy.fetched) > robotsTTL {
+		policy = fetchRobots(u.Scheme, u.Host, userAgent)
+		policy.fetched = time.Now()
+		robots.Lock()
+		robots.policies[key] = policy
+		robots.Unlock()
+	}
+
+	if !policy.allowed(u.RequestURI()) {
+		return ErrRobotsDisallowed
+	}
+	return nil
+}
+
+// getWithRobots sends a GET request with the user agent after checking robots.txt.
+func getWithRobots(rawURL string) (*http.Response, error) {
+	if err := checkRobots(rawURL); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	robots.Lock()
+	if len(robots.userAgent) > 0 {
+		req.Header.Set("User-Agent", robots.userAgent)
+	}
+	robots.Unlock()
+	return Client.Do(req)
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRobotsRule_Match(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/", "/any", true},
+		{"/private", "/private/x", true},
+		{"/private", "/public", false},
+		{"/*.go", "/a/b.go", true},
+		{"/*.go", "/a/b.txt", false},
+		{"/*.go$", "/a/b.go", true},
+		{"/*.go$", "/a/b.go.txt", false},
+		{"/a/*/c", "/a/b/c/d", true},
+		{"/a$", "/a", true},
+		{"/a$", "/ab", false},
+	}
+	for _, test := range tests {
+		if got := (robotsRule{pattern: test.pattern}).match(test.path); got != test.want {
+			t.Errorf("match(%q, %q) = %v, want %v", test.pattern, test.path, got, test.want)
+		}
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		name    string
+		robots  string
+		allowed map[string]bool // Path -> allowed.
+	}{
+		{
+			name:    "empty",
+			allowed: map[string]bool{"/": true, "/x": true},
+		},
+		{
+			name: "wildcard group",
+			robots: `User-agent: *
+Disallow: /private # Comment
+Allow: /private/public
+Disallow:
+`,
+			allowed: map[string]bool{
+				"/":                 true,
+				"/private/x":        false,
+				"/private/public/x": true,
+			},
+		},
+		{
+			name: "specific group wins",
+			robots: `User-agent: *
+Disallow: /
+
+User-agent: Go-Walker
+Disallow: /secret
+`,
+			allowed: map[string]bool{
+				"/":       true,
+				"/secret": false,
+			},
+		},
+		{
+			name: "consecutive user agents share rules",
+			robots: `User-agent: other
+User-agent: go-walker
+Disallow: /a
+
+User-agent: other
+Disallow: /b
+`,
+			allowed: map[string]bool{
+				"/a": false,
+				"/b": true,
+			},
+		},
+		{
+			name: "allow wins on same length",
+			robots: `user-agent: *
+disallow: /a
+allow: /a
+`,
+			allowed: map[string]bool{"/a": true},
+		},
+		{
+			name: "other agents",
+			robots: `User-agent: googlebot
+Disallow: /
+`,
+			allowed: map[string]bool{"/": true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := parseRobots(strings.NewReader(test.robots), "Go-Walker/1.0")
+			for path, want := range test.allowed {
+				if got := p.allowed(path); got != want {
+					t.Errorf("allowed(%q) = %v, want %v", path, got, want)
+				}
+			}
+		})
+	}
+}
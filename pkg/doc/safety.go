@@ -0,0 +1,82 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// SafetyFlag indicates usage of features that bypass type safety.
+type SafetyFlag uint
+
+const (
+	SF_Unsafe   SafetyFlag = 1 << iota // Imports "unsafe".
+	SF_Reflect                         // Uses "reflect" heavily.
+	SF_Linkname                        // Links to symbols of other packages by "//go:linkname".
+)
+
+// reflectHeavyUses is the minimum number of references to package "reflect"
+// to consider a package uses reflection heavily.
+const reflectHeavyUses = 10
+
+// linknameTarget returns the remote symbol of a "//go:linkname" directive,
+// it returns empty string if the comment is not such directive or no remote is given.
+func linknameTarget(comment string) string {
+	if !strings.HasPrefix(comment, "//go:linkname ") {
+		return ""
+	}
+	fields := strings.Fields(comment[len("//go:linkname "):])
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[1]
+}
+
+// safetyFlags inspects given files for unsafe, reflect and linkname usages.
+// It must be called before the AST is processed by go/doc.
+func (w *Walker) safetyFlags(files map[string]*ast.File) SafetyFlag {
+	var flags SafetyFlag
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			if spec.Path.Value == `"unsafe"` {
+				flags |= SF_Unsafe
+			}
+		}
+
+		for _, group := range file.Comments {
+			for _, c := range group.List {
+				target := linknameTarget(c.Text)
+				if i := strings.LastIndex(target, "."); i > 0 && target[:i] != w.Pdoc.ImportPath {
+					flags |= SF_Linkname
+				}
+			}
+		}
+	}
+
+	if w.info != nil {
+		reflectUses := 0
+		for _, obj := range w.info.Uses {
+			if pkgName, ok := obj.(*types.PkgName); ok && pkgName.Imported().Path() == "reflect" {
+				reflectUses++
+			}
+		}
+		if reflectUses >= reflectHeavyUses {
+			flags |= SF_Reflect
+		}
+	}
+	return flags
+}
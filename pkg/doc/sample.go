@@ -0,0 +1,146 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxSampleDirFiles = 3        // Directories of more Go files are not small samples.
+	maxSampleFiles    = 30       // Go files of samples to fetch per repository.
+	maxSampleSize     = 8 * 1024 // Bytes of source code of each sample.
+	maxSamples        = 5
+)
+
+// sampleDirNames are names of directories that usually contain small main
+// packages showing how packages of the repository are used.
+var sampleDirNames = map[string]bool{
+	"example":   true,
+	"examples":  true,
+	"_example":  true,
+	"_examples": true,
+	"cmd":       true,
+}
+
+// isSampleDir returns true if the directory relative to repository root
+// is inside a directory of usage samples.
+func isSampleDir(dir string) bool {
+	for _, name := range strings.Split(dir, "/") {
+		if sampleDirNames[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleFiles returns paths of Go files that may be usage samples from
+// paths of files relative to repository root.
+func sampleFiles(paths []string) []string {
+	dirs := make(map[string][]string)
+	for _, p := range paths {
+		dir, name := path.Split(p)
+		dir = strings.TrimSuffix(dir, "/")
+		if strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") && isSampleDir(dir) {
+			dirs[dir] = append(dirs[dir], p)
+		}
+	}
+
+	names := make([]string, 0, len(dirs))
+	for dir, files := range dirs {
+		if len(files) <= maxSampleDirFiles {
+			names = append(names, dir)
+		}
+	}
+	sort.Strings(names)
+
+	files := make([]string, 0, maxSampleFiles)
+	for _, dir := range names {
+		// Smaller directories after this one may still fit.
+		if len(files)+len(dirs[dir]) > maxSampleFiles {
+			continue
+		}
+		files = append(files, dirs[dir]...)
+	}
+	return files
+}
+
+// setSamples sets main packages of given sources that import the package as
+// its usage samples. Names of sources are paths relative to repository root.
+func (w *Walker) setSamples(srcs []*Source) {
+	dirs := make(map[string][]*Source)
+	for _, src := range srcs {
+		dir := path.Dir(src.Name())
+		dirs[dir] = append(dirs[dir], src)
+	}
+	names := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		names = append(names, dir)
+	}
+	sort.Strings(names)
+
+	fset := token.NewFileSet()
+Dirs:
+	for _, dir := range names {
+		files := dirs[dir]
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].Name() < files[j].Name()
+		})
+
+		var importer *Source
+		var code []string
+		size := 0
+		for _, src := range files {
+			file, err := parser.ParseFile(fset, src.Name(), src.Data(), parser.ImportsOnly)
+			if err != nil || file.Name.Name != "main" {
+				continue Dirs
+			}
+			for _, spec := range file.Imports {
+				if p, _ := strconv.Unquote(spec.Path.Value); p == w.Pdoc.ImportPath && importer == nil {
+					importer = src
+				}
+			}
+
+			size += len(src.Data())
+			if size > maxSampleSize {
+				continue Dirs
+			}
+			code = append(code, string(src.Data()))
+		}
+		if len(code) > 1 {
+			// Name files when there are more than one.
+			for i := range code {
+				code[i] = "// " + path.Base(files[i].Name()) + "\n\n" + code[i]
+			}
+		}
+		if importer == nil {
+			continue
+		}
+
+		w.Pdoc.Samples = append(w.Pdoc.Samples, &Sample{
+			Dir:  dir,
+			Code: strings.Join(code, "\n"),
+			URL:  importer.BrowseUrl,
+		})
+		if len(w.Pdoc.Samples) == maxSamples {
+			return
+		}
+	}
+}
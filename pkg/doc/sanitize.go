@@ -0,0 +1,257 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"html"
+	"strings"
+)
+
+// SanitizePolicy is the allowlist of HTML elements and attributes kept in rendered
+// documentation and README, everything else is removed to prevent stored XSS.
+type SanitizePolicy struct {
+	Tags  map[string]bool
+	Attrs map[string]bool // Attributes allowed on all allowed elements.
+}
+
+var (
+	defaultSanitizeTags = []string{
+		"a", "abbr", "b", "blockquote", "br", "caption", "code", "dd", "del", "details", "div",
+		"dl", "dt", "em", "h1", "h2", "h3", "h4", "h5", "h6", "hr", "i", "img", "ins", "kbd",
+		"li", "ol", "p", "pre", "q", "s", "samp", "small", "span", "strike", "strong", "sub",
+		"summary", "sup", "table", "tbody", "td", "tfoot", "th", "thead", "tr", "tt", "u", "ul"}
+	defaultSanitizeAttrs = []string{
+		"align", "alt", "aria-hidden", "cite", "class", "colspan", "datetime", "dir", "height",
+		"href", "id", "lang", "name", "open", "rowspan", "span", "src", "start", "title", "width"}
+
+	// Elements whose content is removed along with them.
+	rawTextTags = map[string]bool{"script": true, "style": true}
+	// Attributes whose values are URLs.
+	urlAttrs = map[string]bool{"href": true, "src": true, "cite": true}
+	// Schemes allowed in URLs, URLs without schemes are relative.
+	urlSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+)
+
+// NewSanitizePolicy returns the default policy with extra allowed elements and attributes.
+func NewSanitizePolicy(extraTags, extraAttrs []string) *SanitizePolicy {
+	p := &SanitizePolicy{
+		Tags:  make(map[string]bool),
+		Attrs: make(map[string]bool),
+	}
+	for _, t := range append(defaultSanitizeTags, extraTags...) {
+		if t = strings.ToLower(strings.TrimSpace(t)); len(t) > 0 && !rawTextTags[t] {
+			p.Tags[t] = true
+		}
+	}
+	for _, a := range append(defaultSanitizeAttrs, extraAttrs...) {
+		// Event handlers are never allowed.
+		if a = strings.ToLower(strings.TrimSpace(a)); len(a) > 0 && !strings.HasPrefix(a, "on") {
+			p.Attrs[a] = true
+		}
+	}
+	return p
+}
+
+var sanitizePolicy = NewSanitizePolicy(nil, nil)
+
+// SetSanitizePolicy sets the policy to sanitize rendered HTML, passing nil disables sanitization.
+func SetSanitizePolicy(p *SanitizePolicy) {
+	sanitizePolicy = p
+}
+
+// SanitizeHTML sanitizes the HTML by the policy set by SetSanitizePolicy.
+func SanitizeHTML(s string) string {
+	if sanitizePolicy == nil {
+		return s
+	}
+	return sanitizePolicy.Sanitize(s)
+}
+
+func isASCIILetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// safeURL returns true if the URL is relative or has an allowed scheme.
+func safeURL(u string) bool {
+	// Browsers ignore control characters and spaces in schemes.
+	u = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, u)
+	i := strings.IndexAny(u, ":/?#")
+	if i == -1 || u[i] != ':' {
+		return true
+	}
+	return urlSchemes[strings.ToLower(u[:i])]
+}
+
+// htmlTag is a start or end tag being parsed.
+type htmlTag struct {
+	name  string
+	end   bool
+	attrs []string // Pairs of names and unescaped values.
+}
+
+// parseTag parses the tag at the start of s, which begins with '<',
+// and returns the length of the tag or 0 if s does not start with a tag.
+func parseTag(s string) (*htmlTag, int) {
+	t := new(htmlTag)
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		t.end = true
+		i++
+	}
+	if i >= len(s) || !isASCIILetter(s[i]) {
+		return nil, 0
+	}
+	start := i
+	for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '/' && s[i] != '>' {
+		i++
+	}
+	t.name = strings.ToLower(s[start:i])
+
+	for i < len(s) {
+		switch {
+		case s[i] == '>':
+			return t, i + 1
+		case isHTMLSpace(s[i]) || s[i] == '/':
+			i++
+			continue
+		}
+
+		start = i
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		name := strings.ToLower(s[start:i])
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) || s[i] != '=' {
+			t.attrs = append(t.attrs, name, "")
+			continue
+		}
+		i++
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+
+		var val string
+		if q := s[i]; q == '"' || q == '\'' {
+			end := strings.IndexByte(s[i+1:], q)
+			if end == -1 {
+				return nil, 0
+			}
+			val = s[i+1 : i+1+end]
+			i += end + 2
+		} else {
+			start = i
+			for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+				i++
+			}
+			val = s[start:i]
+		}
+		t.attrs = append(t.attrs, name, html.UnescapeString(val))
+	}
+	return nil, 0
+}
+
+// indexFold returns the index of the first case-insensitive instance of substr in s, or -1.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Sanitize returns the HTML with elements and attributes that are not allowed removed.
+// Text of removed elements is kept except for scripts and styles, comments are removed,
+// and URLs with schemes other than http, https and mailto are dropped.
+func (p *SanitizePolicy) Sanitize(s string) string {
+	var buf strings.Builder
+	buf.Grow(len(s))
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i == -1 {
+			buf.WriteString(s)
+			break
+		}
+		buf.WriteString(s[:i])
+		s = s[i:]
+
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			if end := strings.Index(s[4:], "-->"); end != -1 {
+				s = s[4+end+3:]
+			} else {
+				s = ""
+			}
+			continue
+		case strings.HasPrefix(s, "<!") || strings.HasPrefix(s, "<?"):
+			if end := strings.IndexByte(s, '>'); end != -1 {
+				s = s[end+1:]
+			} else {
+				s = ""
+			}
+			continue
+		}
+
+		t, n := parseTag(s)
+		if t == nil {
+			buf.WriteString("&lt;")
+			s = s[1:]
+			continue
+		}
+		s = s[n:]
+
+		if rawTextTags[t.name] && !t.end {
+			if end := indexFold(s, "</"+t.name); end != -1 {
+				s = s[end:]
+			} else {
+				s = ""
+			}
+			continue
+		}
+		if !p.Tags[t.name] {
+			continue
+		}
+
+		if t.end {
+			buf.WriteString("</" + t.name + ">")
+			continue
+		}
+		buf.WriteString("<" + t.name)
+		for j := 0; j < len(t.attrs); j += 2 {
+			name, val := t.attrs[j], t.attrs[j+1]
+			if !p.Attrs[name] || (urlAttrs[name] && !safeURL(val)) {
+				continue
+			}
+			buf.WriteString(" " + name + `="` + html.EscapeString(val) + `"`)
+		}
+		buf.WriteString(">")
+	}
+	return buf.String()
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"testing"
+)
+
+func TestSanitizePolicy_Sanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"plain text", "a < b & c", "a &lt; b & c"},
+		{"allowed elements", `<p class="x">Hi <b>there</b></p>`, `<p class="x">Hi <b>there</b></p>`},
+		{"event handlers", `<p onclick="alert(1)">hi</p>`, `<p>hi</p>`},
+		{"unknown elements keep text", `<blink>hi</blink>`, `hi`},
+		{"scripts and styles", `<script>alert("<p>")</script><STYLE>p{}</style>ok`, `ok`},
+		{"unterminated script", `<script>alert(1)`, ``},
+		{"comments", `a<!-- <script> -->b<!-- c`, `ab`},
+		{"doctype", `<!DOCTYPE html><?xml?>a`, `a`},
+		{"case insensitive", `<IMG SRC=x.png ALT='a "b"'>`, `<img src="x.png" alt="a &#34;b&#34;">`},
+		{"javascript URL", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"obfuscated scheme", `<a href=" java&#09;script:alert(1)">x</a>`, `<a>x</a>`},
+		{"data URL", `<img src="data:text/html,x">`, `<img>`},
+		{"safe URLs", `<a href="https://a.com/?q=1&amp;r=2">x</a><a href="/p#x">y</a><a href="mailto:a@b.com">z</a>`,
+			`<a href="https://a.com/?q=1&amp;r=2">x</a><a href="/p#x">y</a><a href="mailto:a@b.com">z</a>`},
+		{"relative URL with colon", `<a href="/a:b">x</a>`, `<a href="/a:b">x</a>`},
+		{"boolean attribute", `<details open><summary>s</summary></details>`, `<details open=""><summary>s</summary></details>`},
+		{"self-closing", `<br/><hr />`, `<br><hr>`},
+		{"unterminated quote", `<p title="x>y`, `&lt;p title="x>y`},
+	}
+	p := NewSanitizePolicy(nil, nil)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := p.Sanitize(test.html); got != test.want {
+				t.Fatalf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewSanitizePolicy(t *testing.T) {
+	p := NewSanitizePolicy([]string{" IFRAME ", "script"}, []string{"style", "onload"})
+	tests := []struct {
+		html string
+		want string
+	}{
+		{`<iframe style="x" onload="y"></iframe>`, `<iframe style="x"></iframe>`},
+		// Scripts can never be allowed.
+		{`<script>x</script>`, ``},
+	}
+	for _, test := range tests {
+		if got := p.Sanitize(test.html); got != test.want {
+			t.Errorf("Sanitize(%q) = %q, want %q", test.html, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Unknwon/gowalker/pkg/base"
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+type spdxDocument struct {
+	SPDXVersion       string `json:"spdxVersion"`
+	DataLicense       string `json:"dataLicense"`
+	SPDXID            string `json:"SPDXID"`
+	Name              string `json:"name"`
+	DocumentNamespace string `json:"documentNamespace"`
+	CreationInfo      struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	} `json:"creationInfo"`
+	Packages      []*spdxPackage      `json:"packages"`
+	Relationships []*spdxRelationship `json:"relationships"`
+}
+
+const spdxNoAssertion = "NOASSERTION"
+
+func spdxID(i int) string {
+	return fmt.Sprintf("SPDXRef-Package-%d", i)
+}
+
+func purl(importPath, version string) spdxExternalRef {
+	locator := "pkg:golang/" + importPath
+	if len(version) > 0 {
+		locator += "@" + version
+	}
+	return spdxExternalRef{
+		ReferenceCategory: "PACKAGE-MANAGER",
+		ReferenceType:     "purl",
+		ReferenceLocator:  locator,
+	}
+}
+
+// ExportSPDX writes a SPDX 2.3 SBOM document in JSON format of the package
+// to w, dependencies are collected from imports excluding standard library.
+func ExportSPDX(w io.Writer, pdoc *Package) error {
+	version := pdoc.Etag
+	created := time.Now().UTC()
+	if pdoc.Provenance != nil {
+		if len(pdoc.Provenance.Commit) > 0 {
+			version = pdoc.Provenance.Commit
+		}
+		created = pdoc.Provenance.WalkedAt
+	}
+
+	doc := &spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              pdoc.ImportPath,
+		DocumentNamespace: fmt.Sprintf("https://gowalker.org/spdx/%s-%s", pdoc.ImportPath, version),
+	}
+	doc.CreationInfo.Created = created.Format(time.RFC3339)
+	doc.CreationInfo.Creators = []string{"Tool: gowalker-" + setting.AppVer}
+
+	license := spdxNoAssertion
+	if len(pdoc.Licenses) > 0 {
+		// Documentation stored before licenses were deduplicated may contain duplicates.
+		var licenses []string
+		for _, l := range pdoc.Licenses {
+			licenses = appendLicense(licenses, l)
+		}
+		license = strings.Join(licenses, " AND ")
+	}
+	downloadLocation := spdxNoAssertion
+	if len(pdoc.ProjectPath) > 0 {
+		downloadLocation = "https://" + pdoc.ProjectPath
+	}
+	doc.Packages = append(doc.Packages, &spdxPackage{
+		Name:             pdoc.ImportPath,
+		SPDXID:           spdxID(0),
+		VersionInfo:      version,
+		DownloadLocation: downloadLocation,
+		LicenseConcluded: spdxNoAssertion,
+		LicenseDeclared:  license,
+		CopyrightText:    spdxNoAssertion,
+		ExternalRefs:     []spdxExternalRef{purl(pdoc.ImportPath, version)},
+	})
+	doc.Relationships = append(doc.Relationships, &spdxRelationship{
+		SPDXElementID:      doc.SPDXID,
+		RelationshipType:   "DESCRIBES",
+		RelatedSPDXElement: spdxID(0),
+	})
+
+	if pdoc.PkgDecl != nil {
+		for _, path := range pdoc.Imports {
+			if path == "C" || base.IsGoRepoPath(path) {
+				continue
+			}
+
+			id := spdxID(len(doc.Packages))
+			doc.Packages = append(doc.Packages, &spdxPackage{
+				Name:             path,
+				SPDXID:           id,
+				DownloadLocation: spdxNoAssertion,
+				LicenseConcluded: spdxNoAssertion,
+				LicenseDeclared:  spdxNoAssertion,
+				CopyrightText:    spdxNoAssertion,
+				ExternalRefs:     []spdxExternalRef{purl(path, "")},
+			})
+			doc.Relationships = append(doc.Relationships, &spdxRelationship{
+				SPDXElementID:      spdxID(0),
+				RelationshipType:   "DEPENDS_ON",
+				RelatedSPDXElement: id,
+			})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
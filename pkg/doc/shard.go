@@ -0,0 +1,229 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+)
+
+// SymbolPage is a page of declarations of a kind in the package.
+type SymbolPage struct {
+	Kind   string // One of "const", "var", "func" and "type".
+	Offset int
+	Total  int      // Number of declarations of the kind.
+	Values []*Value `json:",omitempty"` // For "const" and "var".
+	Funcs  []*Func  `json:",omitempty"`
+	Types  []*Type  `json:",omitempty"` // With their methods.
+}
+
+// pageRange returns the range of the page in a list of n items.
+func pageRange(n, offset, limit int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > n {
+		offset = n
+	}
+	end := n
+	if limit > 0 && offset+limit < n {
+		end = offset + limit
+	}
+	return offset, end
+}
+
+// SymbolPage returns at most limit exported declarations of the kind starting
+// from offset, which allows serving gigantic packages piece by piece. Zero limit
+// means no limit. It returns nil if the kind is unknown.
+func (pdoc *Package) SymbolPage(kind string, offset, limit int) *SymbolPage {
+	if pdoc.PkgDecl == nil {
+		return nil
+	}
+
+	page := &SymbolPage{Kind: kind}
+	switch kind {
+	case "const", "var":
+		vals := pdoc.Consts
+		if kind == "var" {
+			vals = pdoc.Vars
+		}
+		start, end := pageRange(len(vals), offset, limit)
+		page.Offset, page.Total, page.Values = start, len(vals), vals[start:end]
+	case "func":
+		start, end := pageRange(len(pdoc.Funcs), offset, limit)
+		page.Offset, page.Total, page.Funcs = start, len(pdoc.Funcs), pdoc.Funcs[start:end]
+	case "type":
+		start, end := pageRange(len(pdoc.Types), offset, limit)
+		page.Offset, page.Total, page.Types = start, len(pdoc.Types), pdoc.Types[start:end]
+	default:
+		return nil
+	}
+	return page
+}
+
+// shardBuilder fills shards of declarations up to the maximum size.
+type shardBuilder struct {
+	maxSize  int
+	overhead int // Size of type information of a shard.
+	size     int
+	shards   []*File
+
+	// Sizes of declarations are measured by an encoder that only sends
+	// type information once, like the one that encodes the shard.
+	buf bytes.Buffer
+	enc *gob.Encoder
+}
+
+func newShardBuilder(maxSize int) (*shardBuilder, error) {
+	b := &shardBuilder{maxSize: maxSize}
+	b.enc = gob.NewEncoder(&b.buf)
+	// Type information of all declarations is sent with the type of shards.
+	if err := gob.NewEncoder(&b.buf).Encode(new(File)); err != nil {
+		return nil, err
+	}
+	b.overhead = b.buf.Len()
+	return b, nil
+}
+
+// next returns the shard that the declaration of given size should be put in.
+func (b *shardBuilder) next(size int) *File {
+	if len(b.shards) == 0 || b.size+size > b.maxSize {
+		b.shards = append(b.shards, new(File))
+		b.size = b.overhead
+	}
+	b.size += size
+	return b.shards[len(b.shards)-1]
+}
+
+// add puts the declaration into the shard by its size.
+func (b *shardBuilder) add(decl interface{}, appendTo func(*File)) error {
+	b.buf.Reset()
+	if err := b.enc.Encode(decl); err != nil {
+		return err
+	}
+	appendTo(b.next(b.buf.Len()))
+	return nil
+}
+
+// addDecls puts declarations of the package into shards in order.
+func (b *shardBuilder) addDecls(pdoc *Package) error {
+	for _, v := range pdoc.Consts {
+		if err := b.add(v, func(f *File) { f.Consts = append(f.Consts, v) }); err != nil {
+			return err
+		}
+	}
+	for _, v := range pdoc.Vars {
+		if err := b.add(v, func(f *File) { f.Vars = append(f.Vars, v) }); err != nil {
+			return err
+		}
+	}
+	for _, fn := range pdoc.Funcs {
+		if err := b.add(fn, func(f *File) { f.Funcs = append(f.Funcs, fn) }); err != nil {
+			return err
+		}
+	}
+	for _, fn := range pdoc.Ifuncs {
+		if err := b.add(fn, func(f *File) { f.Ifuncs = append(f.Ifuncs, fn) }); err != nil {
+			return err
+		}
+	}
+	for _, t := range pdoc.Types {
+		if err := b.add(t, func(f *File) { f.Types = append(f.Types, t) }); err != nil {
+			return err
+		}
+	}
+	for _, t := range pdoc.Itypes {
+		if err := b.add(t, func(f *File) { f.Itypes = append(f.Itypes, t) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeShards serializes the package into multiple blobs, each of which is roughly no more than
+// maxSize bytes unless a single declaration is larger than that. The first blob is
+// the package without declarations, and others are shards of declarations in order.
+// Blobs are compressed by the codec, and can be decoded by DecodeShards.
+func EncodeShards(pdoc *Package, codec Codec, maxSize int) ([][]byte, error) {
+	if pdoc.PkgDecl == nil {
+		return nil, errors.New("package has no declarations")
+	}
+
+	head := *pdoc
+	decl := *pdoc.PkgDecl
+	decl.File = File{}
+	head.PkgDecl = &decl
+	data, err := EncodePackage(&head, codec)
+	if err != nil {
+		return nil, fmt.Errorf("header: %v", err)
+	}
+	blobs := [][]byte{data}
+
+	b, err := newShardBuilder(maxSize - len(blobMagic) - 1)
+	if err != nil {
+		return nil, fmt.Errorf("measure: %v", err)
+	}
+	if err = b.addDecls(pdoc); err != nil {
+		return nil, fmt.Errorf("measure: %v", err)
+	}
+
+	for i, shard := range b.shards {
+		var buf bytes.Buffer
+		if err = gob.NewEncoder(&buf).Encode(shard); err != nil {
+			return nil, fmt.Errorf("encode shard %d: %v", i, err)
+		}
+		data, err = Compress(buf.Bytes(), codec)
+		if err != nil {
+			return nil, fmt.Errorf("compress shard %d: %v", i, err)
+		}
+		blobs = append(blobs, data)
+	}
+	return blobs, nil
+}
+
+// DecodeShards returns the package serialized by EncodeShards.
+func DecodeShards(blobs [][]byte) (*Package, error) {
+	if len(blobs) == 0 {
+		return nil, errors.New("no blobs")
+	}
+
+	pdoc, err := DecodePackage(blobs[0])
+	if err != nil {
+		return nil, fmt.Errorf("header: %v", err)
+	}
+	if pdoc.PkgDecl == nil {
+		pdoc.PkgDecl = new(PkgDecl)
+	}
+
+	for i, data := range blobs[1:] {
+		data, err = Decompress(data)
+		if err != nil {
+			return nil, fmt.Errorf("decompress shard %d: %v", i, err)
+		}
+		shard := new(File)
+		if err = gob.NewDecoder(bytes.NewReader(data)).Decode(shard); err != nil {
+			return nil, fmt.Errorf("decode shard %d: %v", i, err)
+		}
+		pdoc.Consts = append(pdoc.Consts, shard.Consts...)
+		pdoc.Vars = append(pdoc.Vars, shard.Vars...)
+		pdoc.Funcs = append(pdoc.Funcs, shard.Funcs...)
+		pdoc.Ifuncs = append(pdoc.Ifuncs, shard.Ifuncs...)
+		pdoc.Types = append(pdoc.Types, shard.Types...)
+		pdoc.Itypes = append(pdoc.Itypes, shard.Itypes...)
+	}
+	return pdoc, nil
+}
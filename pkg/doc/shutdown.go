@@ -0,0 +1,166 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	log "gopkg.in/clog.v1"
+)
+
+// ErrShuttingDown is returned by walks that are requested after Shutdown is called.
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// walks tracks walks in progress, which hold locks of their packages.
+var walks = struct {
+	sync.Mutex
+	wg       sync.WaitGroup
+	draining bool
+	paths    map[string]int // Number of walks in progress by import path.
+}{
+	paths: make(map[string]int),
+}
+
+// beginWalk records the walk of the package, it returns false when shutting down.
+func beginWalk(importPath string) bool {
+	walks.Lock()
+	defer walks.Unlock()
+	if walks.draining {
+		return false
+	}
+	walks.wg.Add(1)
+	walks.paths[importPath]++
+	return true
+}
+
+func endWalk(importPath string) {
+	walks.Lock()
+	defer walks.Unlock()
+	if walks.paths[importPath]--; walks.paths[importPath] <= 0 {
+		delete(walks.paths, importPath)
+	}
+	walks.wg.Done()
+}
+
+// isDraining returns true if Shutdown is called.
+func isDraining() bool {
+	walks.Lock()
+	defer walks.Unlock()
+	return walks.draining
+}
+
+// walkingPaths returns import paths of walks in progress in sorted order.
+func walkingPaths() []string {
+	walks.Lock()
+	defer walks.Unlock()
+	paths := make([]string, 0, len(walks.paths))
+	for p := range walks.paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+type shutdownFunc struct {
+	name string
+	fn   func() error
+}
+
+var shutdownFuncs struct {
+	sync.Mutex
+	list []shutdownFunc
+}
+
+// RegisterShutdown registers the function by unique name to be called by Shutdown
+// after walks are finished, e.g. to save indexes and close connections of stores.
+// Functions are called in the reverse order of registration.
+func RegisterShutdown(name string, fn func() error) error {
+	shutdownFuncs.Lock()
+	defer shutdownFuncs.Unlock()
+	for _, f := range shutdownFuncs.list {
+		if f.name == name {
+			return fmt.Errorf("shutdown function %q already exists", name)
+		}
+	}
+	shutdownFuncs.list = append(shutdownFuncs.list, shutdownFunc{name, fn})
+	return nil
+}
+
+// UnregisterShutdown removes the shutdown function of given name if it exists.
+func UnregisterShutdown(name string) {
+	shutdownFuncs.Lock()
+	defer shutdownFuncs.Unlock()
+	for i, f := range shutdownFuncs.list {
+		if f.name == name {
+			shutdownFuncs.list = append(shutdownFuncs.list[:i:i], shutdownFuncs.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// Shutdown stops accepting walks and crawl jobs, and waits for walks in progress
+// to finish. When ctx is done before that, packages of unfinished walks are pushed
+// to the crawl queue to be walked again by other servers or after restart, and
+// ctx.Err() is returned. Registered shutdown functions are called in either case.
+func Shutdown(ctx context.Context) error {
+	walks.Lock()
+	walks.draining = true
+	walks.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		walks.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		checkpointWalks()
+	}
+
+	shutdownFuncs.Lock()
+	list := shutdownFuncs.list
+	shutdownFuncs.list = nil
+	shutdownFuncs.Unlock()
+	for i := len(list) - 1; i >= 0; i-- {
+		if e := list[i].fn(); e != nil {
+			log.Error(2, "Failed to shut down %s: %v", list[i].name, e)
+			if err == nil {
+				err = fmt.Errorf("%s: %v", list[i].name, e)
+			}
+		}
+	}
+	return err
+}
+
+// checkpointWalks pushes packages of walks in progress to the crawl queue.
+func checkpointWalks() {
+	for _, importPath := range walkingPaths() {
+		if crawlQueue == nil {
+			log.Warn("Walk of %q is not finished", importPath)
+			continue
+		}
+		if err := crawlQueue.Push(importPath); err != nil {
+			log.Error(2, "Failed to push unfinished walk of %q to crawl queue: %v", importPath, err)
+		}
+	}
+}
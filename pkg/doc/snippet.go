@@ -0,0 +1,100 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bufio"
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+const maxSnippets = 10
+
+// readmeCodeBlocks returns contents of fenced code blocks of Go in the Markdown.
+func readmeCodeBlocks(data []byte) []string {
+	var blocks []string
+	var fence string // Fence of the current block, empty when outside of blocks.
+	var isGo bool
+	var buf bytes.Buffer
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if len(fence) == 0 {
+			if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+				info := strings.TrimLeft(trimmed, trimmed[:1])
+				fence = trimmed[:len(trimmed)-len(info)]
+				lang := strings.Fields(info)
+				isGo = len(lang) > 0 && (strings.EqualFold(lang[0], "go") || strings.EqualFold(lang[0], "golang"))
+				buf.Reset()
+			}
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, fence) && len(strings.Trim(trimmed, fence[:1])) == 0 {
+			if isGo && len(strings.TrimSpace(buf.String())) > 0 {
+				blocks = append(blocks, strings.TrimRight(buf.String(), "\n"))
+			}
+			fence = ""
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	return blocks
+}
+
+// parseSnippet returns true if the code parses as a Go file, declarations
+// or statements, and whether it is a complete Go file.
+func parseSnippet(code string) (valid, complete bool) {
+	fset := token.NewFileSet()
+	if _, err := parser.ParseFile(fset, "", code, 0); err == nil {
+		return true, true
+	} else if strings.HasPrefix(strings.TrimSpace(code), "package ") {
+		return false, false
+	}
+
+	if _, err := parser.ParseFile(fset, "", "package p\n"+code, 0); err == nil {
+		return true, false
+	} else if _, err = parser.ParseFile(fset, "", "package p\nfunc _() {\n"+code+"\n}", 0); err == nil {
+		return true, false
+	}
+	return false, false
+}
+
+// setSnippets sets Go code blocks of README that parse as usage snippets.
+func (w *Walker) setSnippets() {
+	readme := w.Pdoc.Readme["en"]
+	if len(readme) == 0 {
+		readme = w.Pdoc.Readme["zh"]
+	}
+
+	for _, code := range readmeCodeBlocks(readme) {
+		valid, complete := parseSnippet(code)
+		if !valid {
+			continue
+		}
+		w.Pdoc.Snippets = append(w.Pdoc.Snippets, &Snippet{
+			Code:     code,
+			Verified: complete,
+		})
+		if len(w.Pdoc.Snippets) == maxSnippets {
+			return
+		}
+	}
+}
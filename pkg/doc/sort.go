@@ -0,0 +1,116 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+	"strings"
+)
+
+// SortMode indicates how declarations of documentation are ordered.
+type SortMode string
+
+const (
+	SortDefault       SortMode = ""               // Order yielded by go/doc.
+	SortAlphabetical  SortMode = "alphabetical"   // By names.
+	SortSource        SortMode = "source"         // By file names and then lines.
+	SortFile          SortMode = "file"           // By file names and then names.
+	SortExportedFirst SortMode = "exported-first" // Exported ones first, and then by names.
+)
+
+var sortMode = SortDefault
+
+// ParseSortMode returns the sort mode of given name, empty name means SortDefault.
+func ParseSortMode(name string) (SortMode, error) {
+	switch m := SortMode(strings.ToLower(name)); m {
+	case SortDefault, SortAlphabetical, SortSource, SortFile, SortExportedFirst:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown sort mode %q", name)
+}
+
+// SetSortMode sets the order of declarations of walked packages whose WalkRes
+// does not specify one.
+func SetSortMode(m SortMode) {
+	sortMode = m
+}
+
+// declKey is what declarations are compared by.
+type declKey struct {
+	Name string
+	Span
+}
+
+func (m SortMode) less(a, b declKey) bool {
+	switch m {
+	case SortSource:
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Line < b.Line
+	case SortFile:
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+	case SortExportedFirst:
+		if ea, eb := ast.IsExported(a.Name), ast.IsExported(b.Name); ea != eb {
+			return ea
+		}
+	}
+	return a.Name < b.Name
+}
+
+func (m SortMode) sortValues(vals []*Value) {
+	sort.SliceStable(vals, func(i, j int) bool {
+		return m.less(declKey{vals[i].Name, vals[i].Span}, declKey{vals[j].Name, vals[j].Span})
+	})
+}
+
+func (m SortMode) sortFuncs(funcs []*Func) {
+	sort.SliceStable(funcs, func(i, j int) bool {
+		return m.less(declKey{funcs[i].Name, funcs[i].Span}, declKey{funcs[j].Name, funcs[j].Span})
+	})
+}
+
+func (m SortMode) sortTypes(tps []*Type) {
+	sort.SliceStable(tps, func(i, j int) bool {
+		return m.less(declKey{tps[i].Name, tps[i].Span}, declKey{tps[j].Name, tps[j].Span})
+	})
+	for _, t := range tps {
+		m.sortValues(t.Consts)
+		m.sortValues(t.Vars)
+		m.sortFuncs(t.Funcs)
+		m.sortFuncs(t.IFuncs)
+		m.sortFuncs(t.Methods)
+		m.sortFuncs(t.IMethods)
+	}
+}
+
+// SortDecls orders declarations of the package and its types by the mode,
+// SortDefault leaves them unchanged.
+func SortDecls(pdoc *Package, m SortMode) {
+	if m == SortDefault || pdoc.PkgDecl == nil {
+		return
+	}
+
+	m.sortValues(pdoc.Consts)
+	m.sortValues(pdoc.Vars)
+	m.sortFuncs(pdoc.Funcs)
+	m.sortFuncs(pdoc.Ifuncs)
+	m.sortTypes(pdoc.Types)
+	m.sortTypes(pdoc.Itypes)
+}
@@ -0,0 +1,174 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Bumps of semantic versions.
+const (
+	BumpMajor   = "major"
+	BumpMinor   = "minor"
+	BumpPatch   = "patch"
+	BumpNone    = "none"    // Same version.
+	BumpUnknown = "unknown" // Either version is not a semantic version.
+)
+
+// StabilityPolicy decides which API changes are allowed between versions.
+// Breaking changes are always allowed on major versions, and never allowed
+// on minor and patch versions.
+type StabilityPolicy struct {
+	AllowV0Breaking  bool // Allow breaking changes between v0 versions.
+	NoPatchAdditions bool // Disallow added identifiers on patch versions.
+}
+
+// StabilityResult is the evaluation of API changes between two versions.
+type StabilityResult struct {
+	OldVersion, NewVersion string
+	Bump                   string
+	Pass                   bool
+	Reason                 string // Why the changes fail, empty if pass.
+	Diff                   *APIDiff
+}
+
+// semverCore returns major, minor and patch numbers of the semantic version,
+// prerelease and build metadata are ignored, e.g. "v1.2.3-rc.1+incompatible".
+func semverCore(v string) []int {
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	nums := parseVersion(v)
+	if len(nums) == 0 || len(nums) > 3 {
+		return nil
+	}
+	for len(nums) < 3 {
+		nums = append(nums, 0)
+	}
+	return nums
+}
+
+// VersionBump returns which part of the semantic version is increased
+// from old to new version.
+func VersionBump(oldVersion, newVersion string) string {
+	ov, nv := semverCore(oldVersion), semverCore(newVersion)
+	switch {
+	case ov == nil || nv == nil:
+		return BumpUnknown
+	case ov[0] != nv[0]:
+		return BumpMajor
+	case ov[1] != nv[1]:
+		return BumpMinor
+	case ov[2] != nv[2]:
+		return BumpPatch
+	}
+	return BumpNone
+}
+
+// CheckStability evaluates API changes between two versions against the policy.
+// Changes between versions that are not semantic versions are treated as minor.
+func CheckStability(oldVersion, newVersion string, d *APIDiff, policy StabilityPolicy) *StabilityResult {
+	r := &StabilityResult{
+		OldVersion: oldVersion,
+		NewVersion: newVersion,
+		Bump:       VersionBump(oldVersion, newVersion),
+		Pass:       true,
+		Diff:       d,
+	}
+
+	breaking := len(d.Removed) + len(d.Changed)
+	ov := semverCore(oldVersion)
+	switch {
+	case r.Bump == BumpMajor:
+	case breaking > 0 && policy.AllowV0Breaking && ov != nil && ov[0] == 0:
+	case breaking > 0:
+		r.Pass = false
+		r.Reason = fmt.Sprintf("%d identifiers are removed or changed without a major version", breaking)
+	case r.Bump == BumpPatch && policy.NoPatchAdditions && len(d.Added) > 0:
+		r.Pass = false
+		r.Reason = fmt.Sprintf("%d identifiers are added on a patch version", len(d.Added))
+	}
+	return r
+}
+
+// maxStabilityResults is the maximum number of results kept by CheckStoredStability.
+const maxStabilityResults = 1000
+
+// stabilityCache keeps results of stored versions by import path, versions and policy.
+var stabilityCache = struct {
+	sync.Mutex
+	results map[string]*StabilityResult
+}{results: make(map[string]*StabilityResult)}
+
+// CheckStoredStability evaluates API changes of the package between two stored
+// versions, empty versions are the latest two tagged versions in the doc store.
+func CheckStoredStability(importPath, oldVersion, newVersion string, policy StabilityPolicy) (*StabilityResult, error) {
+	if docStore == nil {
+		return nil, ErrDocNotFound
+	}
+
+	if len(oldVersion) == 0 || len(newVersion) == 0 {
+		versions, err := StoredVersions(importPath)
+		if err != nil {
+			return nil, fmt.Errorf("list versions: %v", err)
+		}
+		if len(newVersion) == 0 {
+			if len(versions) == 0 {
+				return nil, ErrDocNotFound
+			}
+			newVersion = versions[0]
+		}
+		if len(oldVersion) == 0 {
+			// The previous version of the new one.
+			for i, v := range versions {
+				if v == newVersion && i+1 < len(versions) {
+					oldVersion = versions[i+1]
+				}
+			}
+			if len(oldVersion) == 0 {
+				return nil, ErrDocNotFound
+			}
+		}
+	}
+
+	// Tagged versions never change, so results are reused until versions are deleted.
+	key := fmt.Sprintf("%s@%s..%s:%v", importPath, oldVersion, newVersion, policy)
+	stabilityCache.Lock()
+	r := stabilityCache.results[key]
+	stabilityCache.Unlock()
+	if r != nil {
+		return r, nil
+	}
+
+	oldDoc, err := docStore.Get(importPath, oldVersion)
+	if err != nil {
+		return nil, err
+	}
+	newDoc, err := docStore.Get(importPath, newVersion)
+	if err != nil {
+		return nil, err
+	}
+	r = CheckStability(oldVersion, newVersion, Diff(oldDoc, newDoc), policy)
+
+	stabilityCache.Lock()
+	if len(stabilityCache.results) >= maxStabilityResults {
+		stabilityCache.results = make(map[string]*StabilityResult)
+	}
+	stabilityCache.results[key] = r
+	stabilityCache.Unlock()
+	return r, nil
+}
@@ -0,0 +1,484 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/models"
+)
+
+var ErrDocNotFound = errors.New("documentation does not found")
+
+// DocMeta is the information of a stored documentation that is used to manage retention.
+type DocMeta struct {
+	ImportPath string
+	Module     string // Module path or project path the package belongs to.
+	Version    string // Tag of the package, empty for the default branch.
+	Walked     int64
+	Viewed     int64
+}
+
+// DocStore stores walked documentation of packages by import path and version.
+// Empty version refers to documentation of the default branch.
+type DocStore interface {
+	// Get returns ErrDocNotFound if the documentation does not exist.
+	Get(importPath, version string) (*Package, error)
+	Put(pdoc *Package) error
+	// Touch records the documentation is viewed.
+	Touch(importPath, version string) error
+	Delete(importPath, version string) error
+	List() ([]*DocMeta, error)
+}
+
+// Sweeper is implemented by stores that need to clean up data
+// which is no longer referenced after deletions.
+type Sweeper interface {
+	Sweep() error
+}
+
+// RetentionPolicy decides which stored documentation should be removed by Vacuum.
+type RetentionPolicy struct {
+	MaxVersions int           // Latest tagged versions to keep per module, 0 means unlimited.
+	TTL         time.Duration // Expire documentation not viewed or walked within the period, 0 means never.
+}
+
+var (
+	docStore  DocStore
+	retention RetentionPolicy
+)
+
+// SetDocStore sets the store to save walked documentation and its retention policy.
+// Passing nil store disables storing.
+func SetDocStore(store DocStore, policy RetentionPolicy) {
+	docStore = store
+	retention = policy
+}
+
+// ModuleOf returns path of the module that the package belongs to.
+func ModuleOf(pdoc *Package) string {
+	switch {
+	case len(pdoc.ModulePath) > 0:
+		return pdoc.ModulePath
+	case len(pdoc.ProjectPath) > 0:
+		return pdoc.ProjectPath
+	}
+	return pdoc.ImportPath
+}
+
+// newerVersion returns true if version a is newer than b. Semantic versions
+// are newer than others, which are compared by walked time.
+func newerVersion(a, b *DocMeta) bool {
+	va, vb := parseVersion(a.Version), parseVersion(b.Version)
+	switch {
+	case va == nil && vb == nil:
+		return a.Walked > b.Walked
+	case vb == nil:
+		return true
+	case va == nil:
+		return false
+	}
+
+	for i := 0; i < len(va) && i < len(vb); i++ {
+		if va[i] != vb[i] {
+			return va[i] > vb[i]
+		}
+	}
+	return len(va) > len(vb)
+}
+
+// expiredDocs returns stored documentation that should be removed by the policy at given time.
+func expiredDocs(metas []*DocMeta, policy RetentionPolicy, now time.Time) []*DocMeta {
+	var expired []*DocMeta
+	versions := make(map[string]map[string]*DocMeta) // Module -> version -> newest one of packages.
+	for _, m := range metas {
+		lastUsed := m.Walked
+		if m.Viewed > lastUsed {
+			lastUsed = m.Viewed
+		}
+		if policy.TTL > 0 && now.Sub(time.Unix(lastUsed, 0)) > policy.TTL {
+			expired = append(expired, m)
+			continue
+		}
+
+		// Documentation of the default branch is always kept.
+		if len(m.Version) == 0 {
+			continue
+		}
+		if versions[m.Module] == nil {
+			versions[m.Module] = make(map[string]*DocMeta)
+		}
+		if v := versions[m.Module][m.Version]; v == nil || m.Walked > v.Walked {
+			versions[m.Module][m.Version] = m
+		}
+	}
+	if policy.MaxVersions <= 0 {
+		return expired
+	}
+
+	for module, vers := range versions {
+		if len(vers) <= policy.MaxVersions {
+			continue
+		}
+
+		list := make([]*DocMeta, 0, len(vers))
+		for _, v := range vers {
+			list = append(list, v)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return newerVersion(list[i], list[j])
+		})
+		old := make(map[string]bool)
+		for _, v := range list[policy.MaxVersions:] {
+			old[v.Version] = true
+		}
+
+		for _, m := range metas {
+			if m.Module == module && old[m.Version] {
+				expired = append(expired, m)
+			}
+		}
+	}
+	return expired
+}
+
+// StoredDocs returns metadata of all stored documentation,
+// or nil if the doc store is not set.
+func StoredDocs() ([]*DocMeta, error) {
+	if docStore == nil {
+		return nil, nil
+	}
+	return docStore.List()
+}
+
+// StoredDoc returns stored documentation of the package at given version,
+// it returns ErrDocNotFound if the doc store is not set.
+func StoredDoc(importPath, version string) (*Package, error) {
+	if docStore == nil {
+		return nil, ErrDocNotFound
+	}
+	return docStore.Get(importPath, version)
+}
+
+// maxFetchedDocs is the maximum number of documentation kept by LatestDoc.
+const maxFetchedDocs = 100
+
+// fetchedDocs keeps documentation fetched by LatestDoc by import path.
+var fetchedDocs = struct {
+	sync.Mutex
+	docs map[string]*Package
+}{docs: make(map[string]*Package)}
+
+// LatestDoc returns documentation of the default branch of the walked package.
+// It is read from the doc store, or fetched again and kept in memory until the
+// etag of the package changes when the doc store is not set.
+func LatestDoc(pinfo *models.PkgInfo) (*Package, error) {
+	if docStore != nil {
+		return docStore.Get(pinfo.ImportPath, "")
+	}
+
+	fetchedDocs.Lock()
+	pdoc := fetchedDocs.docs[pinfo.ImportPath]
+	fetchedDocs.Unlock()
+	if pdoc != nil && pdoc.Etag == pinfo.Etag {
+		return pdoc, nil
+	}
+
+	pdoc, err := crawlDoc(pinfo.ImportPath, "")
+	if err != nil {
+		return nil, err
+	}
+
+	fetchedDocs.Lock()
+	if len(fetchedDocs.docs) >= maxFetchedDocs {
+		fetchedDocs.docs = make(map[string]*Package)
+	}
+	fetchedDocs.docs[pinfo.ImportPath] = pdoc
+	fetchedDocs.Unlock()
+	return pdoc, nil
+}
+
+// WalkStored walks the latest version of the package into the doc store and index.
+func WalkStored(importPath string) (*Package, error) {
+	if docStore == nil {
+		return nil, errors.New("doc store is not enabled")
+	}
+	return WalkInto(docStore, indexer, importPath)
+}
+
+// putDoc stores documentation of the default branch, and also stores it as
+// the tagged version when the walked commit is tagged. Checked out packages
+// are then queued to be analyzed and built in background.
+func putDoc(store DocStore, pdoc *Package) error {
+	if pdoc.PkgDecl == nil || len(pdoc.Tag) == 0 {
+		if err := store.Put(pdoc); err != nil {
+			return err
+		}
+		queuePostWalk(store, pdoc)
+		return nil
+	}
+	if err := store.Put(pdoc); err != nil {
+		return fmt.Errorf("store version %s: %v", pdoc.Tag, err)
+	}
+
+	latest, decl := *pdoc, *pdoc.PkgDecl
+	decl.Tag = ""
+	latest.PkgDecl = &decl
+	if err := store.Put(&latest); err != nil {
+		return err
+	}
+	queuePostWalk(store, pdoc)
+	return nil
+}
+
+// StoredVersions returns tagged versions of the package in the doc store, newest first.
+func StoredVersions(importPath string) ([]string, error) {
+	if docStore == nil {
+		return nil, nil
+	}
+	return storedVersions(docStore, importPath)
+}
+
+// storedVersions returns tagged versions of the package in the store, newest first.
+// It lists the whole store unless the store is a Versioner.
+func storedVersions(store DocStore, importPath string) ([]string, error) {
+	if v, ok := store.(Versioner); ok {
+		return v.Versions(importPath)
+	}
+
+	metas, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	var tagged []*DocMeta
+	for _, m := range metas {
+		if m.ImportPath == importPath && len(m.Version) > 0 {
+			tagged = append(tagged, m)
+		}
+	}
+	sort.Slice(tagged, func(i, j int) bool {
+		return newerVersion(tagged[i], tagged[j])
+	})
+
+	versions := make([]string, len(tagged))
+	for i, m := range tagged {
+		versions[i] = m.Version
+	}
+	return versions, nil
+}
+
+// Vacuum removes stored documentation according to the retention policy,
+// and returns the number of removed ones.
+func Vacuum() (int, error) {
+	if docStore == nil {
+		return 0, nil
+	}
+
+	metas, err := docStore.List()
+	if err != nil {
+		return 0, fmt.Errorf("list: %v", err)
+	}
+
+	removed := 0
+	for _, m := range expiredDocs(metas, retention, time.Now()) {
+		if err = docStore.Delete(m.ImportPath, m.Version); err != nil {
+			return removed, fmt.Errorf("delete %s@%s: %v", m.ImportPath, m.Version, err)
+		}
+		// The package is walked again when it is requested after documentation
+		// of the default branch is removed.
+		if len(m.Version) == 0 {
+			if err = deletePackage(m.ImportPath); err != nil {
+				return removed, fmt.Errorf("delete %s: %v", m.ImportPath, err)
+			}
+		}
+		log.Trace("Vacuum: removed %s@%s", m.ImportPath, m.Version)
+		removed++
+	}
+
+	if sweeper, ok := docStore.(Sweeper); ok && removed > 0 {
+		if err = sweeper.Sweep(); err != nil {
+			return removed, fmt.Errorf("sweep: %v", err)
+		}
+	}
+	return removed, nil
+}
+
+// FileDocStore saves documentation as gob files in a local directory.
+// Each file contains the DocMeta followed by the Package encoded by
+// EncodePackage, and the modified time of the file is used as viewed time.
+type FileDocStore struct {
+	Dir   string
+	Codec Codec // Used to compress stored packages.
+}
+
+const defaultVersionName = "_"
+
+// validDocPath returns false if the import path or the version could refer to
+// a file outside of the directory of the package once they are joined.
+func validDocPath(importPath, version string) bool {
+	if strings.ContainsAny(version, `/\`) || strings.Contains(version, "..") ||
+		strings.Contains(importPath, `\`) {
+		return false
+	}
+	for _, elem := range strings.Split(importPath, "/") {
+		if len(elem) == 0 || elem == "." || elem == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// filename returns ErrDocNotFound if the import path or the version is invalid,
+// because no documentation is ever stored for them.
+func (s FileDocStore) filename(importPath, version string) (string, error) {
+	if !validDocPath(importPath, version) {
+		return "", ErrDocNotFound
+	}
+	if len(version) == 0 {
+		version = defaultVersionName
+	}
+	return path.Join(s.Dir, importPath, "@v", version+".gob"), nil
+}
+
+func (s FileDocStore) Get(importPath, version string) (*Package, error) {
+	filename, err := s.filename(importPath, version)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrDocNotFound
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(f)
+	var data []byte
+	if err = dec.Decode(new(DocMeta)); err != nil {
+		return nil, fmt.Errorf("decode meta: %v", err)
+	} else if err = dec.Decode(&data); err != nil {
+		return nil, fmt.Errorf("decode package: %v", err)
+	}
+	return DecodePackage(data)
+}
+
+func (s FileDocStore) Put(pdoc *Package) error {
+	data, err := EncodePackage(pdoc, s.Codec)
+	if err != nil {
+		return err
+	}
+
+	filename, err := s.filename(pdoc.ImportPath, pdoc.Tag)
+	if err != nil {
+		return fmt.Errorf("invalid import path %q or version %q", pdoc.ImportPath, pdoc.Tag)
+	}
+	if err = os.MkdirAll(path.Dir(filename), os.ModePerm); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so readers never see partial files.
+	tmp := filename + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	enc := gob.NewEncoder(f)
+	if err = enc.Encode(&DocMeta{
+		ImportPath: pdoc.ImportPath,
+		Module:     ModuleOf(pdoc),
+		Version:    pdoc.Tag,
+		Walked:     time.Now().Unix(),
+	}); err == nil {
+		err = enc.Encode(data)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+func (s FileDocStore) Touch(importPath, version string) error {
+	filename, err := s.filename(importPath, version)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = os.Chtimes(filename, now, now)
+	if os.IsNotExist(err) {
+		return ErrDocNotFound
+	}
+	return err
+}
+
+func (s FileDocStore) Delete(importPath, version string) error {
+	filename, err := s.filename(importPath, version)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// Remove the version directory when it becomes empty.
+	os.Remove(path.Dir(filename))
+	return nil
+}
+
+func (s FileDocStore) List() ([]*DocMeta, error) {
+	var metas []*DocMeta
+	err := filepath.Walk(s.Dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fi.IsDir() || !strings.HasSuffix(p, ".gob") || filepath.Base(filepath.Dir(p)) != "@v" {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		m := new(DocMeta)
+		if err = gob.NewDecoder(f).Decode(m); err != nil {
+			log.Warn("Skip invalid documentation file %q: %v", p, err)
+			return nil
+		}
+		m.Viewed = fi.ModTime().Unix()
+		metas = append(metas, m)
+		return nil
+	})
+	return metas, err
+}
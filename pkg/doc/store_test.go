@@ -0,0 +1,76 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Unknwon/gowalker/models"
+)
+
+func TestFileDocStore_InvalidPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := FileDocStore{Dir: dir}
+
+	tests := []struct {
+		name       string
+		importPath string
+		version    string
+	}{
+		{name: "parent directory in import path", importPath: "example.com/../../etc"},
+		{name: "current directory in import path", importPath: "example.com/./p"},
+		{name: "absolute import path", importPath: "/example.com/p"},
+		{name: "empty element in import path", importPath: "example.com//p"},
+		{name: "trailing slash in import path", importPath: "example.com/p/"},
+		{name: "backslash in import path", importPath: `example.com\..\p`},
+		{name: "slash in version", importPath: "example.com/p", version: "v1.0.0/x"},
+		{name: "backslash in version", importPath: "example.com/p", version: `v1.0.0\x`},
+		{name: "parent directory in version", importPath: "example.com/p", version: ".."},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := store.Get(test.importPath, test.version); err != ErrDocNotFound {
+				t.Fatalf("Get: got %v, want %v", err, ErrDocNotFound)
+			}
+			if err := store.Touch(test.importPath, test.version); err != ErrDocNotFound {
+				t.Fatalf("Touch: got %v, want %v", err, ErrDocNotFound)
+			}
+			if err := store.Delete(test.importPath, test.version); err != ErrDocNotFound {
+				t.Fatalf("Delete: got %v, want %v", err, ErrDocNotFound)
+			}
+
+			pdoc := &Package{
+				PkgInfo: &models.PkgInfo{ImportPath: test.importPath},
+				PkgDecl: &PkgDecl{Tag: test.version},
+			}
+			if err := store.Put(pdoc); err == nil {
+				t.Fatal("Put: got nil error")
+			}
+		})
+	}
+
+	metas, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(metas) > 0 {
+		t.Fatalf("got %d stored docs, want none", len(metas))
+	}
+}
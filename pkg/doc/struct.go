@@ -18,6 +18,7 @@ import (
 	"go/ast"
 	"go/doc"
 	"go/token"
+	"go/types"
 	"os"
 	"time"
 
@@ -30,6 +31,7 @@ type Source struct {
 	BrowseUrl string
 	RawSrcUrl string
 	SrcData   []byte
+	Hash      string // Git blob ID of the data, which is kept in the blob store if enabled.
 }
 
 func (s *Source) Name() string       { return s.SrcName }
@@ -44,12 +46,36 @@ func (s *Source) SetData(p []byte)   { s.SrcData = p }
 
 // Example represents function or method examples.
 type Example struct {
-	Name string
-	Doc  string
+	Name    string
+	Doc     string
+	Code    string
+	Play    string // Complete program of the example, empty if it does not run alone.
+	PlayURL string // Link of the program shared to the Go Playground.
+	Output  string
+	IsUsed  bool // Indicates if it's used by any kind object.
+}
+
+// Sample is a small main package in the repository that imports the package,
+// which shows how the package is used besides testable examples.
+type Sample struct {
+	Dir  string // Directory relative to repository root, e.g. "examples/server".
+	Code string
+	URL  string // VCS URL of the file that imports the package.
+}
+
+// Snippet is a Go code block in README that parses.
+type Snippet struct {
 	Code string
-	//Play   string
-	Output string
-	IsUsed bool // Indicates if it's used by any kind object.
+	// Whether it is a complete Go file, otherwise it only parses as
+	// declarations or statements.
+	Verified bool
+}
+
+// Span is the range of a declaration in source file.
+type Span struct {
+	Filename  string
+	Line, Col int // Start position.
+	EndLine   int
 }
 
 // Value represents constants and variable
@@ -58,6 +84,9 @@ type Value struct {
 	Doc           string
 	Decl, FmtDecl string // Normal and formatted form of declaration.
 	URL           string // VCS URL.
+	Span
+
+	Findings []Finding // Set by analyzers.
 }
 
 // Func represents functions
@@ -68,6 +97,85 @@ type Func struct {
 	URL            string // VCS URL.
 	Code           string // Included field 'Decl', formatted.
 	Examples       []*Example
+
+	Params, Results []Param
+
+	AcceptsContext   bool // The first parameter is context.Context.
+	ReturnsError     bool // The last result is error.
+	MisplacedContext bool // Accepts context.Context but not as the first parameter.
+	MisplacedError   bool // Returns error but not as the last result.
+
+	Concurrency ConcurrencyHint // Channels exposed by the signature.
+	Resources   ResourceHint    // Goroutines, finalizers and closers of the function.
+
+	Panics []FailureMode // Values of panic calls in the body.
+	Errors []FailureMode // Sentinel errors returned by the body.
+
+	Advisories []*Advisory // Known vulnerabilities that affect the function.
+	Findings   []Finding   // Set by analyzers.
+	Coverage   *Coverage   // Statement coverage, set by ApplyCoverage.
+	HotPath    *HotPath    // Set by ApplyProfile.
+
+	Span
+	LastModified *Revision // Set by Blame.
+
+	// Exported functions of the package that are called by or call
+	// this function, only available in WM_CallGraph mode.
+	Calls, CalledBy []*FuncRef
+}
+
+// APIValue is an exported package-level constant or variable whose type and value
+// are determined by type checking.
+type APIValue struct {
+	Name  string
+	Const bool
+	Type  string // In the form of api/*.txt files, e.g. "ideal-int" or "*bytes.Buffer".
+	Value string // Exact value of the constant, empty for variables.
+}
+
+// EnumValue represents a constant of an enum-like type.
+type EnumValue struct {
+	Name   string
+	Value  string // Constant value in Go syntax.
+	Doc    string
+	String string // Representation generated by stringer, if any.
+}
+
+// Capabilities indicates which standard interfaces are implemented by a type.
+type Capabilities struct {
+	Stringer        bool // fmt.Stringer
+	Error           bool // error
+	JSONMarshaler   bool // json.Marshaler
+	JSONUnmarshaler bool // json.Unmarshaler
+	TextMarshaler   bool // encoding.TextMarshaler
+	TextUnmarshaler bool // encoding.TextUnmarshaler
+	SQLScanner      bool // sql.Scanner
+	SQLValuer       bool // driver.Valuer
+	SortInterface   bool // sort.Interface
+}
+
+// Names returns names of implemented interfaces.
+func (c Capabilities) Names() []string {
+	var names []string
+	for _, v := range []struct {
+		ok   bool
+		name string
+	}{
+		{c.Stringer, "fmt.Stringer"},
+		{c.Error, "error"},
+		{c.JSONMarshaler, "json.Marshaler"},
+		{c.JSONUnmarshaler, "json.Unmarshaler"},
+		{c.TextMarshaler, "encoding.TextMarshaler"},
+		{c.TextUnmarshaler, "encoding.TextUnmarshaler"},
+		{c.SQLScanner, "sql.Scanner"},
+		{c.SQLValuer, "driver.Valuer"},
+		{c.SortInterface, "sort.Interface"},
+	} {
+		if v.ok {
+			names = append(names, v.name)
+		}
+	}
+	return names
 }
 
 // Type represents structs and interfaces.
@@ -76,6 +184,11 @@ type Type struct {
 	Doc           string
 	Decl, FmtDecl string // Normal and formatted form of declaration.
 	URL           string // VCS URL.
+	Span
+
+	IsAlias  bool   // Declared as "type A = B".
+	AliasOf  string // Aliased type, e.g. "B" or "http.Client", empty for type literals.
+	AliasURL string // Link to documentation of the aliased type.
 
 	Consts, Vars []*Value
 	Funcs        []*Func // Exported functions that return this type.
@@ -84,6 +197,13 @@ type Type struct {
 	IFuncs   []*Func // Internal functions that return this type.
 	IMethods []*Func // Internal methods.
 
+	EnumValues   []*EnumValue // Typed constants when the type is used as an enum.
+	Capabilities Capabilities // Standard interfaces implemented by the type.
+	Concurrency  ConcurrencyHint
+	Resources    ResourceHint
+	Advisories   []*Advisory // Known vulnerabilities that affect the type.
+	Findings     []Finding   // Set by analyzers.
+
 	Examples []*Example
 }
 
@@ -100,6 +220,13 @@ type File struct {
 	Itypes []*Type
 }
 
+// DirInfo represents a subdirectory that contains Go packages.
+type DirInfo struct {
+	Name     string
+	Synopsis string // Synopsis of the package if it has been walked.
+	HasGo    bool   // Whether the directory itself has Go files.
+}
+
 // PkgDecl is package declaration in database acceptable form.
 type PkgDecl struct {
 	Tag string // Current tag of project.
@@ -108,11 +235,49 @@ type PkgDecl struct {
 	File
 
 	Examples             []*Example // Function or method example.
+	Samples              []*Sample  // Usage samples in example and command directories.
+	Snippets             []*Snippet // Usage snippets in README.
 	Imports, TestImports []string   // Imports.
 	Files, TestFiles     []*Source  // Source files.
 
+	Benchmarks []*Benchmark // Benchmark functions in test files.
+
 	Notes []string // Source code notes.
 	Dirs  []string // Subdirectories
+
+	APIValues []*APIValue // Exported constants and variables, nil without type information.
+
+	Subdirectories []DirInfo // Direct subdirectories that contain Go packages.
+
+	SafetyFlags SafetyFlag  // Usages of unsafe, reflect and linkname.
+	Advisories  []*Advisory // Known vulnerabilities that affect the package.
+	Findings    []Finding   // Diagnostics of analyzers in the package.
+
+	Provenance *Provenance
+	Licenses   []string // SPDX identifiers of detected licenses.
+
+	ModulePath   string // Module path declared in go.mod.
+	Deprecated   string // Deprecation message of the module.
+	SupersededBy string // Import path that should be used instead.
+
+	MajorVersions []string // Import paths of all major versions.
+	ForkOf        string   // Import path of the package in upstream repository if it is a fork.
+
+	Changelog   []*Release    // Changes of versions, latest first.
+	Maintainers []*Maintainer // Maintainers and top contributors.
+	Citation    *Citation     // How to cite the package.
+	QuickStart  *QuickStart   // How to start using the package.
+	Cost        *CostReport   // Cost of the binary of a command, set by BuildCost.
+	Coverage    *Coverage     // Statement coverage of the package, set by ApplyCoverage.
+	HotPaths    []*FuncRef    // Hot functions and methods, hottest first, set by ApplyProfile.
+
+	BazelTargets []*BazelTarget // Go rules in the BUILD file of the directory.
+
+	// Exported identifiers of imported packages that are referenced,
+	// e.g. "net/http.Get".
+	Refs []string
+
+	Delta *Delta // Set when the package is stored as changes to another version.
 }
 
 // Package represents the full documentation and declaration of a project or package.
@@ -132,16 +297,28 @@ type Package struct {
 
 	IsHasFile   bool
 	IsHasSubdir bool
+
+	checkoutDir string // Directory of checked out files to be analyzed and built, see queuePostWalk.
 }
 
 // Walker holds the state used when building the documentation.
 type Walker struct {
-	LineFmt  string
-	Pdoc     *Package
-	apkg     *ast.Package
-	Examples []*doc.Example // Function or method example.
-	Fset     *token.FileSet
-	SrcLines map[string][]string // Source file line slices.
-	SrcFiles map[string]*Source
-	Buf      []byte // scratch space for printNode method.
+	Fetcher string // Name of the fetcher that provides source files.
+	Ref     string // Branch or tag name of source files.
+	LineFmt string
+	Pdoc    *Package
+	apkg    *ast.Package
+	tpkg    *types.Package
+	info    *types.Info
+	// Data generated by stringer of types.
+	stringers map[string]*stringer
+	funcEnds  map[token.Pos]token.Pos
+	failures  map[token.Pos]*failureModes
+	resources map[token.Pos]ResourceHint
+	calls     map[*types.Func][]*types.Func // Static calls between functions of the package.
+	Examples  []*doc.Example                // Function or method example.
+	Fset      *token.FileSet
+	SrcLines  map[string][]string // Source file line slices.
+	SrcFiles  map[string]*Source
+	Buf       []byte // scratch space for printNode method.
 }
@@ -0,0 +1,227 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"strings"
+)
+
+// SymbolInfo is the documentation of a symbol at a source position.
+type SymbolInfo struct {
+	Name string // Qualified by receiver type for methods, e.g. "Client.Do".
+	Kind string // One of "const", "var", "func", "type" and "method".
+	Decl string
+	Doc  string
+	URL  string // VCS URL.
+}
+
+// contains returns true if given position is inside the span.
+func (s Span) contains(file string, line, col int) bool {
+	if len(s.Filename) == 0 || path.Base(s.Filename) != path.Base(file) {
+		return false
+	}
+	if line < s.Line || line > s.EndLine {
+		return false
+	}
+	return line > s.Line || col <= 0 || col >= s.Col
+}
+
+// symbolFinder keeps the innermost declaration that contains a position.
+type symbolFinder struct {
+	file      string
+	line, col int
+
+	span Span
+	info *SymbolInfo
+}
+
+func (f *symbolFinder) match(s Span) bool {
+	if !s.contains(f.file, f.line, f.col) {
+		return false
+	}
+	return f.info == nil || s.EndLine-s.Line < f.span.EndLine-f.span.Line
+}
+
+func (f *symbolFinder) values(vals []*Value, kind string) {
+	for _, v := range vals {
+		if f.match(v.Span) {
+			f.span = v.Span
+			f.info = &SymbolInfo{
+				Name: v.nameAt(f.line),
+				Kind: kind,
+				Decl: v.Decl,
+				Doc:  v.Doc,
+				URL:  v.URL,
+			}
+		}
+	}
+}
+
+func (f *symbolFinder) funcs(funcs []*Func, recv string) {
+	for _, fn := range funcs {
+		if !f.match(fn.Span) {
+			continue
+		}
+		f.span = fn.Span
+		f.info = &SymbolInfo{
+			Name: fn.Name,
+			Kind: "func",
+			Decl: fn.Decl,
+			Doc:  fn.Doc,
+			URL:  fn.URL,
+		}
+		if len(recv) > 0 {
+			f.info.Name = recv + "." + fn.Name
+			f.info.Kind = "method"
+		}
+	}
+}
+
+func (f *symbolFinder) types(types []*Type) {
+	for _, t := range types {
+		if f.match(t.Span) {
+			f.span = t.Span
+			f.info = &SymbolInfo{
+				Name: t.Name,
+				Kind: "type",
+				Decl: t.Decl,
+				Doc:  t.Doc,
+				URL:  t.URL,
+			}
+		}
+		f.values(t.Consts, "const")
+		f.values(t.Vars, "var")
+		f.funcs(t.Funcs, "")
+		f.funcs(t.IFuncs, "")
+		f.funcs(t.Methods, t.Name)
+		f.funcs(t.IMethods, t.Name)
+	}
+}
+
+// nameAt returns name of the value declared at given line of source file,
+// it falls back to the first name when the line cannot be located.
+func (v *Value) nameAt(line int) string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", "package p\n"+v.Decl, 0)
+	if err != nil || len(file.Decls) == 0 {
+		return v.Name
+	}
+	gd, ok := file.Decls[0].(*ast.GenDecl)
+	if !ok {
+		return v.Name
+	}
+
+	name := v.Name
+	for _, spec := range gd.Specs {
+		vs, ok := spec.(*ast.ValueSpec)
+		if !ok || len(vs.Names) == 0 {
+			continue
+		}
+		// Declaration starts at line 2 after the package clause.
+		if len(name) == 0 || v.Line+fset.Position(vs.Pos()).Line-2 <= line {
+			name = vs.Names[0].Name
+		}
+	}
+	return name
+}
+
+// SymbolAt returns documentation of the innermost declaration that contains
+// given position in the source file, or nil if there is none. Only the base
+// name of file is compared, and column is ignored when it is not positive.
+// It should be called before the package is rendered, because rendering
+// overwrites declarations with HTML.
+func SymbolAt(pdoc *Package, file string, line, col int) *SymbolInfo {
+	if pdoc.PkgDecl == nil {
+		return nil
+	}
+
+	f := &symbolFinder{file: file, line: line, col: col}
+	f.values(pdoc.Consts, "const")
+	f.values(pdoc.Vars, "var")
+	f.funcs(pdoc.Funcs, "")
+	f.funcs(pdoc.Ifuncs, "")
+	f.types(pdoc.Types)
+	f.types(pdoc.Itypes)
+	return f.info
+}
+
+// LookupSymbol returns documentation of the exported symbol of given name,
+// or nil if there is none. Methods are named as "Type.Method" or "Type_Method"
+// as anchors of documentation pages. Like SymbolAt, it should be called before
+// the package is rendered.
+func LookupSymbol(pdoc *Package, name string) *SymbolInfo {
+	if pdoc.PkgDecl == nil || len(name) == 0 {
+		return nil
+	}
+
+	lookupValues := func(vals []*Value, kind string) *SymbolInfo {
+		for _, v := range vals {
+			for _, n := range v.Names() {
+				if n == name {
+					return &SymbolInfo{Name: n, Kind: kind, Decl: v.Decl, Doc: v.Doc, URL: v.URL}
+				}
+			}
+		}
+		return nil
+	}
+	lookupFuncs := func(funcs []*Func, recv, name string) *SymbolInfo {
+		for _, f := range funcs {
+			if f.Name != name {
+				continue
+			}
+			if len(recv) > 0 {
+				return &SymbolInfo{Name: recv + "." + f.Name, Kind: "method", Decl: f.Decl, Doc: f.Doc, URL: f.URL}
+			}
+			return &SymbolInfo{Name: f.Name, Kind: "func", Decl: f.Decl, Doc: f.Doc, URL: f.URL}
+		}
+		return nil
+	}
+
+	if i := strings.IndexAny(name, "._"); i > 0 {
+		recv, method := name[:i], name[i+1:]
+		for _, t := range pdoc.Types {
+			if t.Name == recv {
+				if info := lookupFuncs(t.Methods, recv, method); info != nil {
+					return info
+				}
+			}
+		}
+	}
+
+	if info := lookupValues(pdoc.Consts, "const"); info != nil {
+		return info
+	} else if info = lookupValues(pdoc.Vars, "var"); info != nil {
+		return info
+	} else if info = lookupFuncs(pdoc.Funcs, "", name); info != nil {
+		return info
+	}
+	for _, t := range pdoc.Types {
+		if t.Name == name {
+			return &SymbolInfo{Name: t.Name, Kind: "type", Decl: t.Decl, Doc: t.Doc, URL: t.URL}
+		}
+		if info := lookupValues(t.Consts, "const"); info != nil {
+			return info
+		} else if info = lookupValues(t.Vars, "var"); info != nil {
+			return info
+		} else if info = lookupFuncs(t.Funcs, "", name); info != nil {
+			return info
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrQuotaExceeded = errors.New("quota of stored packages exceeded")
+
+// QuotaDocStore limits the number of packages stored by the underlying DocStore,
+// new versions of stored packages are always allowed.
+type QuotaDocStore struct {
+	DocStore
+	MaxPackages int // 0 means unlimited.
+}
+
+func (s QuotaDocStore) Put(pdoc *Package) error {
+	if s.MaxPackages <= 0 {
+		return s.DocStore.Put(pdoc)
+	}
+
+	metas, err := s.DocStore.List()
+	if err != nil {
+		return fmt.Errorf("list: %v", err)
+	}
+	pkgs := make(map[string]bool)
+	for _, m := range metas {
+		pkgs[m.ImportPath] = true
+	}
+	if !pkgs[pdoc.ImportPath] && len(pkgs) >= s.MaxPackages {
+		return ErrQuotaExceeded
+	}
+	return s.DocStore.Put(pdoc)
+}
+
+func (s QuotaDocStore) Versions(importPath string) ([]string, error) {
+	return storedVersions(s.DocStore, importPath)
+}
+
+func (s QuotaDocStore) Sweep() error {
+	if sweeper, ok := s.DocStore.(Sweeper); ok {
+		return sweeper.Sweep()
+	}
+	return nil
+}
+
+// WalkInto walks the latest version of the package and saves it to the store
+// and index, which are of a corpus other than the default one, e.g. of a tenant.
+// The index is not updated if it is nil.
+func WalkInto(store DocStore, idx interface {
+	Add(pdoc *Package)
+}, importPath string) (*Package, error) {
+	if IsBlocked(importPath) {
+		return nil, ErrBlocked
+	}
+
+	unlock, err := lockWalk(importPath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	pdoc, err := fetchDoc(importPath, "")
+	countWalk(false, err)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = putDoc(store, pdoc); err != nil {
+		return nil, err
+	}
+	if idx != nil {
+		idx.Add(pdoc)
+	}
+	return pdoc, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package testsupport helps services built on the walker test without network
+// or real repositories, by an in-memory fetcher, canned source files and builders
+// of Package fixtures.
+package testsupport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+// ErrNotFound is returned by Fetcher for packages that are not added.
+var ErrNotFound = errors.New("resource not found")
+
+// Fetcher walks packages from files in memory. Etags of packages are hashes
+// of their files, so they are the same in every run.
+type Fetcher struct {
+	lock    sync.Mutex
+	files   map[string]map[string][]byte // Import path -> name -> content.
+	fetches map[string]int
+}
+
+// NewFetcher returns a fetcher without packages.
+func NewFetcher() *Fetcher {
+	return &Fetcher{
+		files:   make(map[string]map[string][]byte),
+		fetches: make(map[string]int),
+	}
+}
+
+// Add adds or replaces files of the package, which are keyed by names in the
+// package directory as doc.WalkFiles accepts.
+func (f *Fetcher) Add(importPath string, files map[string][]byte) *Fetcher {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.files[importPath] = files
+	return f
+}
+
+// Remove removes the package, later fetches of it return ErrNotFound.
+func (f *Fetcher) Remove(importPath string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	delete(f.files, importPath)
+}
+
+// Fetches returns the number of times the package has been fetched.
+func (f *Fetcher) Fetches(importPath string) int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.fetches[importPath]
+}
+
+// etag returns the hash of the files.
+func etag(files map[string][]byte) string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write(files[name])
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// Fetch implements doc.Fetcher.
+func (f *Fetcher) Fetch(importPath, etagValue string) (*doc.Package, error) {
+	f.lock.Lock()
+	f.fetches[importPath]++
+	files, ok := f.files[importPath]
+	f.lock.Unlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	tag := etag(files)
+	if tag == etagValue {
+		return nil, doc.ErrPackageNotModified
+	}
+	pdoc, err := doc.WalkFiles(importPath, files)
+	if err != nil {
+		return nil, err
+	}
+	pdoc.Etag = tag
+	return pdoc, nil
+}
+
+// Install sets the fetcher of all packages until the test finishes.
+func (f *Fetcher) Install(tb testing.TB) {
+	doc.SetFetcher(f)
+	tb.Cleanup(func() {
+		doc.SetFetcher(nil)
+	})
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package testsupport
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+func TestFetcher(t *testing.T) {
+	const importPath = "example.com/hello"
+	f := NewFetcher()
+
+	if _, err := f.Fetch(importPath, ""); err != ErrNotFound {
+		t.Fatalf("got %v, want %v", err, ErrNotFound)
+	}
+
+	f.Add(importPath, Hello(importPath))
+	pdoc, err := f.Fetch(importPath, "")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(pdoc.Etag) == 0 {
+		t.Fatal("got empty etag")
+	}
+	etag := pdoc.Etag
+
+	tests := []struct {
+		name    string
+		files   map[string][]byte // Files to change to, nil means unchanged.
+		etag    string
+		wantErr error
+		changed bool // Whether the etag is changed.
+	}{
+		{
+			name:    "not modified",
+			etag:    etag,
+			wantErr: doc.ErrPackageNotModified,
+		},
+		{
+			name: "stale etag",
+			etag: "other",
+		},
+		{
+			name:    "files are changed",
+			files:   Command(importPath),
+			etag:    etag,
+			changed: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.files != nil {
+				f.Add(importPath, test.files)
+			}
+
+			pdoc, err := f.Fetch(importPath, test.etag)
+			if err != test.wantErr {
+				t.Fatalf("got %v, want %v", err, test.wantErr)
+			} else if err != nil {
+				return
+			}
+			if changed := pdoc.Etag != etag; changed != test.changed {
+				t.Fatalf("got etag %q, want changed %v from %q", pdoc.Etag, test.changed, etag)
+			}
+		})
+	}
+
+	if got, want := f.Fetches(importPath), 2+len(tests); got != want {
+		t.Fatalf("got %d fetches, want %d", got, want)
+	}
+
+	f.Remove(importPath)
+	if _, err := f.Fetch(importPath, ""); err != ErrNotFound {
+		t.Fatalf("got %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestFetcher_Install(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testsupport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := doc.FileDocStore{Dir: dir}
+
+	const importPath = "example.com/hello"
+	f := NewFetcher().Add(importPath, Hello(importPath))
+	f.Install(t)
+
+	pdoc, err := doc.WalkInto(store, nil, importPath)
+	if err != nil {
+		t.Fatal(err)
+	} else if got := f.Fetches(importPath); got != 1 {
+		t.Fatalf("got %d fetches, want 1", got)
+	}
+
+	stored, err := store.Get(importPath, "")
+	if err != nil {
+		t.Fatal(err)
+	} else if stored.Etag != pdoc.Etag || stored.Synopsis != "Package hello says hello." {
+		t.Fatalf("got etag %q and synopsis %q, want %q and %q",
+			stored.Etag, stored.Synopsis, pdoc.Etag, "Package hello says hello.")
+	}
+}
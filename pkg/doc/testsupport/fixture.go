@@ -0,0 +1,176 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package testsupport
+
+import (
+	"path"
+	"strconv"
+
+	"github.com/Unknwon/gowalker/models"
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+// PackageBuilder builds Package fixtures without walking source files.
+// Declarations are added in order, and links of them refer to a fake
+// source file "<name>.go" in the package directory.
+type PackageBuilder struct {
+	pdoc  *doc.Package
+	types map[string]*doc.Type
+}
+
+// NewPackage returns a builder of the package, which is named by the last
+// element of the import path.
+func NewPackage(importPath string) *PackageBuilder {
+	return &PackageBuilder{
+		pdoc: &doc.Package{
+			PkgInfo: &models.PkgInfo{
+				ImportPath: importPath,
+				Name:       path.Base(importPath),
+				Etag:       "fixture",
+			},
+			Readme:  make(map[string][]byte),
+			PkgDecl: &doc.PkgDecl{},
+		},
+		types: make(map[string]*doc.Type),
+	}
+}
+
+func (b *PackageBuilder) url(line int) string {
+	return b.pdoc.ImportPath + "/" + b.pdoc.Name + ".go#L" + strconv.Itoa(line)
+}
+
+// nextLine returns a line number that is different for every declaration.
+func (b *PackageBuilder) nextLine() int {
+	n := 1 + len(b.pdoc.Consts) + len(b.pdoc.Vars) + len(b.pdoc.Funcs) + len(b.pdoc.Types)
+	for _, t := range b.pdoc.Types {
+		n += len(t.Funcs) + len(t.Methods)
+	}
+	return n * 10
+}
+
+// Synopsis sets the synopsis of the package.
+func (b *PackageBuilder) Synopsis(synopsis string) *PackageBuilder {
+	b.pdoc.Synopsis = synopsis
+	return b
+}
+
+// Doc sets the documentation of the package in HTML, as the walker renders it.
+func (b *PackageBuilder) Doc(html string) *PackageBuilder {
+	b.pdoc.Doc = html
+	return b
+}
+
+// Version sets the tag of the package.
+func (b *PackageBuilder) Version(tag string) *PackageBuilder {
+	b.pdoc.Tag = tag
+	return b
+}
+
+// Readme sets README of the language, e.g. "en".
+func (b *PackageBuilder) Readme(lang string, html string) *PackageBuilder {
+	b.pdoc.Readme[lang] = []byte(html)
+	return b
+}
+
+// Imports sets imports of the package.
+func (b *PackageBuilder) Imports(importPaths ...string) *PackageBuilder {
+	b.pdoc.Imports = importPaths
+	return b
+}
+
+func (b *PackageBuilder) value(name, decl, docText string) *doc.Value {
+	line := b.nextLine()
+	return &doc.Value{
+		Name: name,
+		Doc:  docText,
+		Decl: decl,
+		URL:  b.url(line),
+		Span: doc.Span{Filename: b.pdoc.Name + ".go", Line: line, Col: 1, EndLine: line},
+	}
+}
+
+func (b *PackageBuilder) fn(name, decl, docText string) *doc.Func {
+	line := b.nextLine()
+	return &doc.Func{
+		Name: name,
+		Doc:  docText,
+		Decl: decl,
+		URL:  b.url(line),
+		Span: doc.Span{Filename: b.pdoc.Name + ".go", Line: line, Col: 1, EndLine: line + 2},
+	}
+}
+
+// Const adds a constant, e.g. Const("Max", "const Max = 10", "Max is the maximum.").
+func (b *PackageBuilder) Const(name, decl, docText string) *PackageBuilder {
+	b.pdoc.Consts = append(b.pdoc.Consts, b.value(name, decl, docText))
+	return b
+}
+
+// Var adds a variable.
+func (b *PackageBuilder) Var(name, decl, docText string) *PackageBuilder {
+	b.pdoc.Vars = append(b.pdoc.Vars, b.value(name, decl, docText))
+	return b
+}
+
+// Func adds a function, e.g. Func("Parse", "func Parse(s string) (*T, error)", "Parse parses s.").
+func (b *PackageBuilder) Func(name, decl, docText string) *PackageBuilder {
+	b.pdoc.Funcs = append(b.pdoc.Funcs, b.fn(name, decl, docText))
+	return b
+}
+
+// Type adds a type.
+func (b *PackageBuilder) Type(name, decl, docText string) *PackageBuilder {
+	line := b.nextLine()
+	t := &doc.Type{
+		Name: name,
+		Doc:  docText,
+		Decl: decl,
+		URL:  b.url(line),
+		Span: doc.Span{Filename: b.pdoc.Name + ".go", Line: line, Col: 1, EndLine: line},
+	}
+	b.pdoc.Types = append(b.pdoc.Types, t)
+	b.types[name] = t
+	return b
+}
+
+// Constructor adds a function that returns the type, which must have been added.
+func (b *PackageBuilder) Constructor(typeName, name, decl, docText string) *PackageBuilder {
+	t := b.mustType(typeName)
+	t.Funcs = append(t.Funcs, b.fn(name, decl, docText))
+	return b
+}
+
+// Method adds a method of the type, which must have been added.
+func (b *PackageBuilder) Method(typeName, name, decl, docText string) *PackageBuilder {
+	t := b.mustType(typeName)
+	t.Methods = append(t.Methods, b.fn(name, decl, docText))
+	return b
+}
+
+func (b *PackageBuilder) mustType(name string) *doc.Type {
+	t := b.types[name]
+	if t == nil {
+		panic("testsupport: type " + name + " is not added")
+	}
+	return t
+}
+
+// Build returns the package. The builder should not be used afterwards.
+func (b *PackageBuilder) Build() *doc.Package {
+	b.pdoc.IsHasConst = len(b.pdoc.Consts) > 0
+	b.pdoc.IsHasVar = len(b.pdoc.Vars) > 0
+	b.pdoc.IsHasExport = b.pdoc.IsHasConst || b.pdoc.IsHasVar || len(b.pdoc.Funcs) > 0 || len(b.pdoc.Types) > 0
+	return b.pdoc
+}
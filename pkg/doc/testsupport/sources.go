@@ -0,0 +1,135 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package testsupport
+
+import (
+	"path"
+)
+
+// Source files returned by functions below are new maps every time,
+// so tests can modify them freely.
+
+// Hello returns files of a module with a small library package, which has
+// documentation of every kind of declaration, an example and README.
+func Hello(importPath string) map[string][]byte {
+	return map[string][]byte{
+		"go.mod": []byte("module " + importPath + "\n\ngo 1.18\n"),
+		"README.md": []byte("# " + path.Base(importPath) + "\n\nSay hello.\n\n```go\n" +
+			"fmt.Println(hello.Greet(\"world\"))\n```\n"),
+		"hello.go": []byte(`// Package hello says hello.
+package hello
+
+import "errors"
+
+// DefaultName is the name greeted when the name is empty.
+const DefaultName = "world"
+
+// ErrTooLong is returned when the name is too long.
+var ErrTooLong = errors.New("name is too long")
+
+// Greet returns the greeting to the name.
+func Greet(name string) string {
+	if len(name) == 0 {
+		name = DefaultName
+	}
+	return "Hello, " + name + "!"
+}
+
+// Greeter greets with a custom greeting.
+type Greeter struct {
+	Greeting string
+}
+
+// NewGreeter returns a greeter with the greeting.
+func NewGreeter(greeting string) *Greeter {
+	return &Greeter{Greeting: greeting}
+}
+
+// Greet returns the greeting to the name, or ErrTooLong if the name is
+// longer than 64 bytes.
+func (g *Greeter) Greet(name string) (string, error) {
+	if len(name) > 64 {
+		return "", ErrTooLong
+	}
+	return g.Greeting + ", " + name + "!", nil
+}
+`),
+		"example_test.go": []byte(`package hello_test
+
+import (
+	"fmt"
+
+	"` + importPath + `"
+)
+
+func ExampleGreet() {
+	fmt.Println(hello.Greet("gopher"))
+	// Output: Hello, gopher!
+}
+`),
+	}
+}
+
+// Command returns files of a module with a main package.
+func Command(importPath string) map[string][]byte {
+	return map[string][]byte{
+		"go.mod": []byte("module " + importPath + "\n\ngo 1.18\n"),
+		"main.go": []byte(`// Command ` + path.Base(importPath) + ` prints its arguments.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Println(os.Args[1:])
+}
+`),
+	}
+}
+
+// Generic returns files of a module with generic types and functions.
+func Generic(importPath string) map[string][]byte {
+	return map[string][]byte{
+		"go.mod": []byte("module " + importPath + "\n\ngo 1.18\n"),
+		"set.go": []byte(`// Package set implements sets of comparable values.
+package set
+
+// Set is a set of comparable values.
+type Set[T comparable] map[T]struct{}
+
+// Of returns a set of the values.
+func Of[T comparable](values ...T) Set[T] {
+	s := make(Set[T], len(values))
+	for _, v := range values {
+		s.Add(v)
+	}
+	return s
+}
+
+// Add adds the value to the set.
+func (s Set[T]) Add(v T) {
+	s[v] = struct{}{}
+}
+
+// Has returns true if the set contains the value.
+func (s Set[T]) Has(v T) bool {
+	_, ok := s[v]
+	return ok
+}
+`),
+	}
+}
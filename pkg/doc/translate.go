@@ -0,0 +1,306 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/doc"
+	"html"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DocString is a translatable doc comment of the package or a symbol.
+type DocString struct {
+	Symbol      string `json:"symbol"` // Empty for the package, e.g. "Client.Do" for methods.
+	Source      string `json:"source"`
+	Translation string `json:"translation,omitempty"`
+}
+
+// Catalog contains translations of doc comments of a package to a language.
+type Catalog struct {
+	ImportPath string       `json:"import_path"`
+	Lang       string       `json:"lang"` // e.g. "zh-CN".
+	Strings    []*DocString `json:"strings"`
+}
+
+var blankLinesPattern = regexp.MustCompile(`\n(?:[ \t]*\n)+`)
+
+// htmlDocText returns plain text of documentation rendered by go/doc.ToHTML,
+// paragraphs are separated by blank lines.
+func htmlDocText(s string) string {
+	s = strings.Replace(s, "<p>", "\n\n<p>", -1)
+	s = strings.Replace(s, "<pre>", "\n\n<pre>", -1)
+	s = html.UnescapeString(htmlTagPattern.ReplaceAllString(s, ""))
+	return strings.TrimSpace(blankLinesPattern.ReplaceAllString(s, "\n\n"))
+}
+
+// eachDocString calls fn with the symbol and the doc comment of the package and every
+// exported symbol. Doc comment of the package is passed in plain text, and set back
+// in HTML. It must be called before the package is rendered.
+func eachDocString(pdoc *Package, fn func(symbol string, doc *string)) {
+	if pdoc.PkgDecl == nil {
+		return
+	}
+
+	text := htmlDocText(pdoc.Doc)
+	if fn("", &text); text != htmlDocText(pdoc.Doc) {
+		var buf bytes.Buffer
+		doc.ToHTML(&buf, text, nil)
+		pdoc.Doc = buf.String()
+	}
+
+	values := func(vals []*Value) {
+		for _, v := range vals {
+			fn(v.Name, &v.Doc)
+		}
+	}
+	funcs := func(recv string, funcs []*Func) {
+		for _, f := range funcs {
+			fn(recv+f.Name, &f.Doc)
+		}
+	}
+	values(pdoc.Consts)
+	values(pdoc.Vars)
+	funcs("", pdoc.Funcs)
+	for _, t := range pdoc.Types {
+		fn(t.Name, &t.Doc)
+		values(t.Consts)
+		values(t.Vars)
+		funcs("", t.Funcs)
+		funcs(t.Name+".", t.Methods)
+	}
+}
+
+// DocStrings returns translatable doc comments of the package, symbols without
+// doc comments are skipped.
+func DocStrings(pdoc *Package) []*DocString {
+	var strs []*DocString
+	eachDocString(pdoc, func(symbol string, doc *string) {
+		if text := strings.TrimSpace(*doc); len(text) > 0 {
+			strs = append(strs, &DocString{Symbol: symbol, Source: text})
+		}
+	})
+	return strs
+}
+
+// poQuote writes the string in PO format, which is split into lines after newlines.
+func poQuote(w io.Writer, keyword, s string) {
+	if !strings.Contains(s, "\n") {
+		fmt.Fprintf(w, "%s %s\n", keyword, strconv.Quote(s))
+		return
+	}
+	fmt.Fprintf(w, "%s \"\"\n", keyword)
+	for len(s) > 0 {
+		i := strings.Index(s, "\n") + 1
+		if i == 0 {
+			i = len(s)
+		}
+		fmt.Fprintln(w, strconv.Quote(s[:i]))
+		s = s[i:]
+	}
+}
+
+// writePO writes the catalog as a PO file, or a POT template when translations are empty.
+func writePO(w io.Writer, cat *Catalog) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Documentation of %s.\n", cat.ImportPath)
+	poQuote(bw, "msgid", "")
+	header := "Content-Type: text/plain; charset=UTF-8\nX-Import-Path: " + cat.ImportPath + "\n"
+	if len(cat.Lang) > 0 {
+		header += "Language: " + cat.Lang + "\n"
+	}
+	poQuote(bw, "msgstr", header)
+
+	for _, s := range cat.Strings {
+		fmt.Fprintln(bw)
+		poQuote(bw, "msgctxt", s.Symbol)
+		poQuote(bw, "msgid", s.Source)
+		poQuote(bw, "msgstr", s.Translation)
+	}
+	return bw.Flush()
+}
+
+// ExportStrings writes translatable doc comments of the package in the format,
+// which is "pot" for a gettext template or "json" for a Catalog without translations.
+func ExportStrings(w io.Writer, pdoc *Package, format string) error {
+	cat := &Catalog{
+		ImportPath: pdoc.ImportPath,
+		Strings:    DocStrings(pdoc),
+	}
+	switch format {
+	case "pot", "":
+		return writePO(w, cat)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cat)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// parsePO parses catalog from a PO file, entries without msgstr are skipped.
+func parsePO(data []byte) (*Catalog, error) {
+	cat := new(Catalog)
+	var (
+		entry   map[string]string
+		keyword string
+	)
+	flush := func() {
+		if entry == nil {
+			return
+		}
+		if len(entry["msgid"]) == 0 {
+			// Header entry.
+			for _, line := range strings.Split(entry["msgstr"], "\n") {
+				if i := strings.Index(line, ":"); i > 0 {
+					switch strings.TrimSpace(line[:i]) {
+					case "Language":
+						cat.Lang = strings.TrimSpace(line[i+1:])
+					case "X-Import-Path":
+						cat.ImportPath = strings.TrimSpace(line[i+1:])
+					}
+				}
+			}
+		} else if len(entry["msgstr"]) > 0 {
+			cat.Strings = append(cat.Strings, &DocString{
+				Symbol:      entry["msgctxt"],
+				Source:      entry["msgid"],
+				Translation: entry["msgstr"],
+			})
+		}
+		entry = nil
+	}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case len(line) == 0:
+			flush()
+			continue
+		case line[0] == '#':
+			continue
+		}
+
+		if line[0] != '"' {
+			j := strings.Index(line, " ")
+			if j == -1 {
+				return nil, fmt.Errorf("line %d: missing string", i+1)
+			}
+			keyword, line = line[:j], strings.TrimSpace(line[j+1:])
+			switch keyword {
+			case "msgctxt", "msgid", "msgstr":
+			default:
+				return nil, fmt.Errorf("line %d: unsupported keyword %q", i+1, keyword)
+			}
+			// A context starts a new entry, even without blank lines in between.
+			if entry != nil && (keyword == "msgctxt" || (keyword == "msgid" && len(entry["msgid"]) > 0)) {
+				flush()
+			}
+			if entry == nil {
+				entry = make(map[string]string)
+			}
+		} else if entry == nil {
+			return nil, fmt.Errorf("line %d: string without keyword", i+1)
+		}
+
+		s, err := strconv.Unquote(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		entry[keyword] += s
+	}
+	flush()
+	return cat, nil
+}
+
+// ParseCatalog parses the catalog in JSON or PO format.
+func ParseCatalog(data []byte) (*Catalog, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		cat := new(Catalog)
+		if err := json.Unmarshal(trimmed, cat); err != nil {
+			return nil, err
+		}
+		return cat, nil
+	}
+	return parsePO(data)
+}
+
+// ApplyTranslations replaces doc comments of the package with translations in the
+// catalog, and returns the number of replaced ones. Translations whose source differs
+// from the current doc comment are outdated and not applied. It must be called before
+// the package is rendered.
+func ApplyTranslations(pdoc *Package, cat *Catalog) int {
+	translations := make(map[string]*DocString, len(cat.Strings))
+	for _, s := range cat.Strings {
+		if len(strings.TrimSpace(s.Translation)) > 0 {
+			translations[s.Symbol] = s
+		}
+	}
+
+	applied := 0
+	eachDocString(pdoc, func(symbol string, doc *string) {
+		if s := translations[symbol]; s != nil && s.Source == strings.TrimSpace(*doc) {
+			*doc = s.Translation
+			applied++
+		}
+	})
+	return applied
+}
+
+var translationDir string
+
+// SetTranslationDir sets the directory of catalogs, which are named by import path
+// and language, e.g. "github.com/foo/bar/zh-CN.po" or "github.com/foo/bar/zh-CN.json".
+// Empty directory disables translations.
+func SetTranslationDir(dir string) {
+	translationDir = dir
+}
+
+// ErrNoCatalog is returned when the package has no translations to the language.
+var ErrNoCatalog = errors.New("catalog not found")
+
+// LoadCatalog returns the catalog of the package in the language from the translation
+// directory, it returns ErrNoCatalog if the catalog does not exist.
+func LoadCatalog(importPath, lang string) (*Catalog, error) {
+	if len(translationDir) == 0 || strings.Contains(lang, "/") || strings.Contains(importPath, "..") {
+		return nil, ErrNoCatalog
+	}
+
+	for _, ext := range []string{".po", ".json"} {
+		data, err := ioutil.ReadFile(path.Join(translationDir, importPath, lang+ext))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		cat, err := ParseCatalog(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s%s: %v", lang, ext, err)
+		}
+		return cat, nil
+	}
+	return nil, ErrNoCatalog
+}
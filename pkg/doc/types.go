@@ -0,0 +1,438 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/doc"
+	"go/token"
+	"go/types"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fakeImporter returns packages that only declare type names referenced by
+// the files being checked, which is sufficient to type check declarations
+// without importing any dependency.
+type fakeImporter struct {
+	pkgs  map[string]*types.Package
+	names map[string]map[string]bool // Referenced type names by import path.
+}
+
+func (imp *fakeImporter) Import(path string) (*types.Package, error) {
+	pkg := imp.pkgs[path]
+	if pkg == nil {
+		pkg = types.NewPackage(path, guessPackageName(path))
+		for name := range imp.names[path] {
+			tn := types.NewTypeName(token.NoPos, pkg, name, nil)
+			types.NewNamed(tn, types.NewInterfaceType(nil, nil).Complete(), nil)
+			pkg.Scope().Insert(tn)
+		}
+		pkg.MarkComplete()
+		imp.pkgs[path] = pkg
+	}
+	return pkg, nil
+}
+
+// collectTypeRefs returns qualified identifiers that are used as types,
+// grouped by import path.
+func collectTypeRefs(files []*ast.File) map[string]map[string]bool {
+	refs := make(map[string]map[string]bool)
+	for _, file := range files {
+		imports := make(map[string]string) // Local name -> import path
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			if spec.Name != nil {
+				imports[spec.Name.Name] = path
+			} else {
+				imports[guessPackageName(path)] = path
+			}
+		}
+
+		var inType func(ast.Node) bool
+		inType = func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			if x, ok := sel.X.(*ast.Ident); ok && imports[x.Name] != "" {
+				path := imports[x.Name]
+				if refs[path] == nil {
+					refs[path] = make(map[string]bool)
+				}
+				refs[path][sel.Sel.Name] = true
+			}
+			return false
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.Field:
+				ast.Inspect(n.Type, inType)
+			case *ast.TypeSpec:
+				ast.Inspect(n.Type, inType)
+			case *ast.ValueSpec:
+				if n.Type != nil {
+					ast.Inspect(n.Type, inType)
+				}
+			case *ast.CompositeLit:
+				if n.Type != nil {
+					ast.Inspect(n.Type, inType)
+				}
+			}
+			return true
+		})
+	}
+	return refs
+}
+
+// typeCheck type checks given files and saves the result for later analysis.
+// Errors are ignored because dependencies are never imported for real.
+func (w *Walker) typeCheck(files map[string]*ast.File) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	astFiles := make([]*ast.File, 0, len(files))
+	for _, name := range names {
+		astFiles = append(astFiles, files[name])
+	}
+
+	conf := types.Config{
+		Importer: &fakeImporter{
+			pkgs:  make(map[string]*types.Package),
+			names: collectTypeRefs(astFiles),
+		},
+		Error:       func(error) {},
+		FakeImportC: true,
+	}
+	w.info = &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	w.tpkg, _ = conf.Check(w.Pdoc.ImportPath, w.Fset, astFiles, w.info)
+	w.stringers = w.findStringers(astFiles)
+}
+
+// stringer contains data generated by the stringer tool for a type.
+type stringer struct {
+	hasMethod bool
+	names     string
+	index     []int
+	offset    int64 // Minimum value subtracted before indexing.
+}
+
+func (s *stringer) toString(val constant.Value) string {
+	v, ok := constant.Int64Val(constant.ToInt(val))
+	if !ok {
+		return ""
+	}
+	v -= s.offset
+	if v < 0 || v+1 >= int64(len(s.index)) || s.index[v+1] > len(s.names) {
+		return ""
+	}
+	return s.names[s.index[v]:s.index[v+1]]
+}
+
+var stringerVarPattern = regexp.MustCompile(`^_([A-Za-z0-9]+)_(name|index)$`)
+
+// findStringers collects data generated by the stringer tool. It must be called
+// before the AST is processed by go/doc, which drops unexported declarations
+// and function bodies.
+func (w *Walker) findStringers(files []*ast.File) map[string]*stringer {
+	stringers := make(map[string]*stringer)
+	get := func(name string) *stringer {
+		if stringers[name] == nil {
+			stringers[name] = new(stringer)
+		}
+		return stringers[name]
+	}
+
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Name.Name != "String" || decl.Recv == nil || len(decl.Recv.List) != 1 || decl.Body == nil {
+					continue
+				}
+				ident, ok := decl.Recv.List[0].Type.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				s := get(ident.Name)
+				s.hasMethod = true
+				// Stringer subtracts minimum value when it is not zero, i.e. "i -= 1".
+				ast.Inspect(decl.Body, func(n ast.Node) bool {
+					if as, ok := n.(*ast.AssignStmt); ok && as.Tok == token.SUB_ASSIGN && len(as.Rhs) == 1 {
+						if bl, ok := as.Rhs[0].(*ast.BasicLit); ok {
+							s.offset, _ = strconv.ParseInt(bl.Value, 10, 64)
+						}
+					}
+					return true
+				})
+
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					vs, ok := spec.(*ast.ValueSpec)
+					if !ok {
+						continue
+					}
+					for i, name := range vs.Names {
+						m := stringerVarPattern.FindStringSubmatch(name.Name)
+						if m == nil {
+							continue
+						}
+
+						switch m[2] {
+						case "name":
+							if c, ok := w.info.Defs[name].(*types.Const); ok && c.Val().Kind() == constant.String {
+								get(m[1]).names = constant.StringVal(c.Val())
+							}
+						case "index":
+							if i >= len(vs.Values) {
+								continue
+							}
+							lit, ok := vs.Values[i].(*ast.CompositeLit)
+							if !ok {
+								continue
+							}
+							index := make([]int, 0, len(lit.Elts))
+							for _, elt := range lit.Elts {
+								bl, ok := elt.(*ast.BasicLit)
+								if !ok {
+									break
+								}
+								n, err := strconv.Atoi(bl.Value)
+								if err != nil {
+									break
+								}
+								index = append(index, n)
+							}
+							if len(index) == len(lit.Elts) {
+								get(m[1]).index = index
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for name, s := range stringers {
+		if !s.hasMethod || len(s.names) == 0 || len(s.index) == 0 {
+			delete(stringers, name)
+		}
+	}
+	return stringers
+}
+
+// enumValues returns values of constants that are declared with the type,
+// it returns nil if the type is not an enum-like type.
+func (w *Walker) enumValues(d *doc.Type) []*EnumValue {
+	if w.tpkg == nil || len(d.Consts) == 0 {
+		return nil
+	}
+
+	tn, ok := w.tpkg.Scope().Lookup(d.Name).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	basic, ok := tn.Type().Underlying().(*types.Basic)
+	if !ok || basic.Info()&(types.IsInteger|types.IsString|types.IsFloat) == 0 {
+		return nil
+	}
+
+	s := w.stringers[d.Name]
+	var vals []*EnumValue
+	for _, c := range d.Consts {
+		for _, spec := range c.Decl.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for _, name := range vs.Names {
+				obj, ok := w.info.Defs[name].(*types.Const)
+				if !ok || !types.Identical(obj.Type(), tn.Type()) || name.Name == "_" {
+					continue
+				}
+
+				val := &EnumValue{
+					Name:  name.Name,
+					Value: obj.Val().ExactString(),
+				}
+				if vs.Doc != nil {
+					val.Doc = strings.TrimSpace(vs.Doc.Text())
+				} else if vs.Comment != nil {
+					val.Doc = strings.TrimSpace(vs.Comment.Text())
+				}
+				if s != nil {
+					val.String = s.toString(obj.Val())
+				}
+				vals = append(vals, val)
+			}
+		}
+	}
+	return vals
+}
+
+// signatureString returns the signature in the form of "(T1, T2) (R1, R2)"
+// without parameter names, and types qualified by full import paths.
+func signatureString(sig *types.Signature) string {
+	qualifier := func(pkg *types.Package) string { return pkg.Path() }
+	tuple := func(t *types.Tuple, variadic bool) string {
+		strs := make([]string, t.Len())
+		for i := 0; i < t.Len(); i++ {
+			typ := t.At(i).Type()
+			if variadic && i == t.Len()-1 {
+				strs[i] = "..." + types.TypeString(typ.(*types.Slice).Elem(), qualifier)
+			} else {
+				strs[i] = types.TypeString(typ, qualifier)
+			}
+			if strs[i] == "any" {
+				strs[i] = "interface{}"
+			}
+		}
+		return strings.Join(strs, ", ")
+	}
+
+	str := "(" + tuple(sig.Params(), sig.Variadic()) + ")"
+	switch sig.Results().Len() {
+	case 0:
+	case 1:
+		str += " " + tuple(sig.Results(), false)
+	default:
+		str += " (" + tuple(sig.Results(), false) + ")"
+	}
+	return str
+}
+
+// capabilityMethods is the list of methods required by standard interfaces.
+var capabilityMethods = []struct {
+	set     func(*Capabilities)
+	methods map[string]string // Method name -> signature
+}{
+	{func(c *Capabilities) { c.Stringer = true }, map[string]string{"String": "() string"}},
+	{func(c *Capabilities) { c.Error = true }, map[string]string{"Error": "() string"}},
+	{func(c *Capabilities) { c.JSONMarshaler = true }, map[string]string{"MarshalJSON": "() ([]byte, error)"}},
+	{func(c *Capabilities) { c.JSONUnmarshaler = true }, map[string]string{"UnmarshalJSON": "([]byte) error"}},
+	{func(c *Capabilities) { c.TextMarshaler = true }, map[string]string{"MarshalText": "() ([]byte, error)"}},
+	{func(c *Capabilities) { c.TextUnmarshaler = true }, map[string]string{"UnmarshalText": "([]byte) error"}},
+	{func(c *Capabilities) { c.SQLScanner = true }, map[string]string{"Scan": "(interface{}) error"}},
+	{func(c *Capabilities) { c.SQLValuer = true }, map[string]string{"Value": "() (database/sql/driver.Value, error)"}},
+	{func(c *Capabilities) { c.SortInterface = true }, map[string]string{
+		"Len":  "() int",
+		"Less": "(int, int) bool",
+		"Swap": "(int, int)",
+	}},
+}
+
+// capabilities returns standard interfaces implemented by the type or its pointer.
+func (w *Walker) capabilities(name string) Capabilities {
+	var c Capabilities
+	if w.tpkg == nil {
+		return c
+	}
+	tn, ok := w.tpkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return c
+	}
+
+	mset := types.NewMethodSet(types.NewPointer(tn.Type()))
+	if _, ok := tn.Type().Underlying().(*types.Interface); ok {
+		mset = types.NewMethodSet(tn.Type())
+	}
+	sigs := make(map[string]string, mset.Len())
+	for i := 0; i < mset.Len(); i++ {
+		obj := mset.At(i).Obj()
+		if sig, ok := obj.Type().(*types.Signature); ok {
+			sigs[obj.Name()] = signatureString(sig)
+		}
+	}
+
+CheckCapability:
+	for _, cm := range capabilityMethods {
+		for name, sig := range cm.methods {
+			if sigs[name] != sig {
+				continue CheckCapability
+			}
+		}
+		cm.set(&c)
+	}
+	return c
+}
+
+func isContextType(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Name() != "Context" {
+		return false
+	}
+	path := named.Obj().Pkg().Path()
+	return path == "context" || path == "golang.org/x/net/context"
+}
+
+func isErrorType(typ types.Type) bool {
+	return types.Identical(typ, types.Universe.Lookup("error").Type())
+}
+
+// annotateFunc sets information of the function that derived from its declaration.
+func (w *Walker) annotateFunc(f *Func, decl *ast.FuncDecl) {
+	// Function bodies are trimmed by go/doc, use end positions collected in advance.
+	f.Span = w.span(decl.Pos(), w.funcEnds[decl.Pos()])
+	setParams(f, decl)
+	if modes := w.failures[decl.Pos()]; modes != nil {
+		f.Panics, f.Errors = modes.Panics, modes.Errors
+	}
+	f.Resources = w.resources[decl.Pos()]
+
+	if w.info == nil {
+		return
+	}
+	obj, ok := w.info.Defs[decl.Name].(*types.Func)
+	if !ok {
+		return
+	}
+	sig := obj.Type().(*types.Signature)
+	f.Concurrency = signatureConcurrency(sig)
+	if returnsCloser(sig) {
+		f.Resources |= RH_Closer
+	}
+
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if isContextType(params.At(i).Type()) {
+			if i == 0 {
+				f.AcceptsContext = true
+			} else {
+				f.MisplacedContext = true
+			}
+		}
+	}
+
+	results := sig.Results()
+	for i := 0; i < results.Len(); i++ {
+		if isErrorType(results.At(i).Type()) {
+			if i == results.Len()-1 {
+				f.ReturnsError = true
+			} else {
+				f.MisplacedError = true
+			}
+		}
+	}
+}
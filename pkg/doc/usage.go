@@ -0,0 +1,128 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+	"strconv"
+)
+
+// Indexer indexes walked packages for cross-package analyses.
+type Indexer interface {
+	// Add indexes the package before it is rendered.
+	Add(pdoc *Package)
+	// Canonical returns import path of the upstream package if the package
+	// of given import path is a fork or vendored copy, or the import path itself.
+	Canonical(importPath string) string
+	// Score returns quality score between 0 and 1 of the package.
+	Score(importPath string) float64
+}
+
+var indexer Indexer
+
+// SetIndexer sets the indexer of walked packages, nil disables indexing.
+func SetIndexer(idx Indexer) {
+	indexer = idx
+}
+
+// CanonicalPath returns import path of the upstream package if the package
+// of given import path is known to be a fork or copy of it.
+func CanonicalPath(importPath string) string {
+	if indexer == nil {
+		return importPath
+	}
+	return indexer.Canonical(importPath)
+}
+
+// Score returns quality score between 0 and 1 of the package of given
+// import path, it returns 0 if the package has not been indexed.
+func Score(importPath string) float64 {
+	if indexer == nil {
+		return 0
+	}
+	return indexer.Score(importPath)
+}
+
+// externalRefs returns qualified identifiers of imported packages that
+// are referenced by files, e.g. "net/http.Get".
+func (w *Walker) externalRefs(files map[string]*ast.File) []string {
+	refs := make(map[string]bool)
+	for _, file := range files {
+		imports := make(map[string]string) // Local name -> import path
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || path == "C" {
+				continue
+			}
+			name := guessPackageName(path)
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			if name != "_" && name != "." {
+				imports[name] = path
+			}
+		}
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			x, ok := sel.X.(*ast.Ident)
+			if !ok || imports[x.Name] == "" {
+				return true
+			}
+			// Skip local identifiers that shadow the package name.
+			if w.info != nil {
+				if obj, ok := w.info.Uses[x]; ok {
+					if _, ok = obj.(*types.PkgName); !ok {
+						return true
+					}
+				}
+			}
+			refs[imports[x.Name]+"."+sel.Sel.Name] = true
+			return false
+		})
+	}
+
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Names returns names of constants or variables declared by the value.
+func (v *Value) Names() []string {
+	gd, err := parseDecl(v.Decl)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, spec := range gd.Specs {
+		if vs, ok := spec.(*ast.ValueSpec); ok {
+			for _, name := range vs.Names {
+				if name.Name != "_" {
+					names = append(names, name.Name)
+				}
+			}
+		}
+	}
+	return names
+}
@@ -19,12 +19,12 @@ import (
 	"archive/zip"
 	"bytes"
 	"errors"
-	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
-	"os/exec"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -32,6 +32,7 @@ import (
 
 	"github.com/Unknwon/gowalker/models"
 	"github.com/Unknwon/gowalker/pkg/base"
+	"github.com/Unknwon/gowalker/pkg/setting"
 )
 
 // TODO: specify with command line flag
@@ -93,98 +94,13 @@ var vcsCmds = map[string]*vcsCmd{
 	},
 }
 
-var lsremoteRe = regexp.MustCompile(`(?m)^([0-9a-f]{40})\s+refs/(?:tags|heads)/(.+)$`)
-
-func downloadGit(schemes []string, repo, savedEtag string) (string, string, error) {
-	var p []byte
-	var scheme string
-	for i := range schemes {
-		cmd := exec.Command("git", "ls-remote", "--heads", "--tags", schemes[i]+"://"+repo+".git")
-		log.Println(strings.Join(cmd.Args, " "))
-		var err error
-		p, err = cmd.Output()
-		if err == nil {
-			scheme = schemes[i]
-			break
-		}
-	}
-
-	if scheme == "" {
-		return "", "", com.NotFoundError{"VCS not found"}
-	}
-
-	tags := make(map[string]string)
-	for _, m := range lsremoteRe.FindAllSubmatch(p, -1) {
-		tags[string(m[2])] = string(m[1])
-	}
-
-	tag, commit, err := bestTag(tags, "master")
-	if err != nil {
-		return "", "", err
-	}
-
-	etag := scheme + "-" + commit
-
-	if etag == savedEtag {
-		return "", "", ErrPackageNotModified
-	}
-
-	dir := path.Join(repoRoot, repo+".git")
-	p, err = ioutil.ReadFile(path.Join(dir, ".git/HEAD"))
-	switch {
-	case err != nil:
-		if err := os.MkdirAll(dir, 0777); err != nil {
-			return "", "", err
-		}
-		cmd := exec.Command("git", "clone", scheme+"://"+repo, dir)
-		log.Println(strings.Join(cmd.Args, " "))
-		if err := cmd.Run(); err != nil {
-			return "", "", err
-		}
-	case string(bytes.TrimRight(p, "\n")) == commit:
-		return tag, etag, nil
-	default:
-		cmd := exec.Command("git", "fetch")
-		log.Println(strings.Join(cmd.Args, " "))
-		cmd.Dir = dir
-		if err := cmd.Run(); err != nil {
-			return "", "", err
-		}
-	}
-
-	cmd := exec.Command("git", "checkout", "--detach", "--force", commit)
-	cmd.Dir = dir
-	if err := cmd.Run(); err != nil {
-		return "", "", err
-	}
-
-	return tag, etag, nil
-}
-
-var (
-	vcsPattern       = regexp.MustCompile(`^(?P<repo>(?:[a-z0-9.\-]+\.)+[a-z0-9.\-]+(?::[0-9]+)?/[A-Za-z0-9_.\-/]*?)\.(?P<vcs>bzr|git|hg|svn)(?P<dir>/[A-Za-z0-9_.\-/]*)?$`)
-	gopkgPathPattern = regexp.MustCompile(`^/(?:([a-zA-Z0-9][-a-zA-Z0-9]+)/)?([a-zA-Z][-.a-zA-Z0-9]*)\.((?:v0|v[1-9][0-9]*)(?:\.0|\.[1-9][0-9]*){0,2})(?:\.git)?((?:/[a-zA-Z0-9][-.a-zA-Z0-9]*)*)$`)
-)
+var vcsPattern = regexp.MustCompile(`^(?P<repo>(?:[a-z0-9.\-]+\.)+[a-z0-9.\-]+(?::[0-9]+)?/[A-Za-z0-9_.\-/]*?)\.(?P<vcs>bzr|git|hg|svn)(?P<dir>/[A-Za-z0-9_.\-/]*)?$`)
 
 func getVCSDoc(match map[string]string, etagSaved string) (*Package, error) {
 	if strings.HasPrefix(match["importPath"], "golang.org/x/") {
 		match["owner"] = "golang"
 		match["repo"] = path.Dir(strings.TrimPrefix(match["importPath"], "golang.org/x/"))
 		return getGitHubDoc(match, etagSaved)
-	} else if strings.HasPrefix(match["importPath"], "gopkg.in/") {
-		m := gopkgPathPattern.FindStringSubmatch(strings.TrimPrefix(match["importPath"], "gopkg.in"))
-		if m == nil {
-			return nil, fmt.Errorf("unsupported gopkg.in import path: %s", match["importPath"])
-		}
-		user := m[1]
-		repo := m[2]
-		if len(user) == 0 {
-			user = "go-" + repo
-		}
-		match["owner"] = user
-		match["repo"] = repo
-		match["tag"] = m[3]
-		return getGitHubDoc(match, etagSaved)
 	}
 
 	cmd := vcsCmds[match["vcs"]]
@@ -220,6 +136,10 @@ func getVCSDoc(match map[string]string, etagSaved string) (*Package, error) {
 	// Find source location.
 
 	urlTemplate, urlMatch, lineFmt := lookupURLTemplate(match["repo"], match["dir"], tag)
+	// Templates published by go-source meta tag take precedence.
+	if len(match["browseTpl"]) > 0 {
+		urlTemplate, urlMatch, lineFmt = match["browseTpl"], nil, match["lineFmt"]
+	}
 
 	// Slurp source files.
 
@@ -239,7 +159,7 @@ func getVCSDoc(match map[string]string, etagSaved string) (*Package, error) {
 	// Get source file data.
 	var files []com.RawFile
 	for _, fi := range fis {
-		if fi.IsDir() || !base.IsDocFile(fi.Name()) {
+		if fi.IsDir() || !(base.IsDocFile(fi.Name()) || base.IsMetaFile(fi.Name())) {
 			continue
 		}
 		b, err := ioutil.ReadFile(path.Join(d, fi.Name()))
@@ -253,12 +173,21 @@ func getVCSDoc(match map[string]string, etagSaved string) (*Package, error) {
 		})
 	}
 
+	sampleSrcs, err := readSamples(path.Join(repoRoot, com.Expand("{repo}.{vcs}", match)), match["repo"], tag)
+	if err != nil {
+		log.Printf("Failed to read usage samples of %q: %v", match["importPath"], err)
+	}
+
 	// Start generating data.
 	w := &Walker{
+		Fetcher: match["vcs"],
+		Ref:     tag,
 		LineFmt: lineFmt,
 		Pdoc: &Package{
 			PkgInfo: &models.PkgInfo{
-				ImportPath: match["importPath"],
+				ImportPath:  match["importPath"],
+				ProjectPath: match["projectRoot"],
+				ViewDirPath: match["viewDirPath"],
 			},
 		},
 	}
@@ -269,12 +198,92 @@ func getVCSDoc(match map[string]string, etagSaved string) (*Package, error) {
 		srcs = append(srcs, s)
 	}
 
-	return w.Build(&WalkRes{
-		WalkDepth: WD_All,
-		WalkType:  WT_Memory,
-		WalkMode:  WM_All,
-		Srcs:      srcs,
+	pdoc, err := w.Build(&WalkRes{
+		WalkDepth:  WD_All,
+		WalkType:   WT_Memory,
+		WalkMode:   defaultWalkMode(),
+		Srcs:       srcs,
+		SampleSrcs: sampleSrcs,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	if setting.EnableBlame {
+		if err = Blame(pdoc, d); err != nil {
+			log.Printf("Failed to blame %q: %v", match["importPath"], err)
+		}
+	}
+	// Analyses and the build are done in background after the package is stored.
+	pdoc.checkoutDir = d
+	return pdoc, nil
+}
+
+// readSamples reads Go files of usage samples in the checked out repository.
+func readSamples(root, repo, tag string) ([]*Source, error) {
+	var paths []string
+	err := filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if fi.IsDir() && strings.HasPrefix(fi.Name(), ".") && p != root {
+			return filepath.SkipDir
+		}
+		// Symbolic links and devices may refer to files outside of the repository.
+		if fi.Mode().IsRegular() {
+			rel, _ := filepath.Rel(root, p)
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var srcs []*Source
+	for _, p := range sampleFiles(paths) {
+		data, err := readSample(path.Join(root, p))
+		if err != nil {
+			return nil, err
+		} else if data == nil {
+			continue
+		}
+		src := &Source{
+			SrcName: p,
+			SrcData: data,
+		}
+		if urlTemplate, urlMatch, _ := lookupURLTemplate(repo, "/"+path.Dir(p), tag); len(urlTemplate) > 0 {
+			// Links of templates are prefixed by schemes.
+			url := com.Expand(urlTemplate, urlMatch, path.Base(p))
+			src.BrowseUrl = strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://")
+		}
+		srcs = append(srcs, src)
+	}
+	return srcs, nil
+}
+
+// readSample returns content of the regular file, or nil if it is not
+// a regular file or larger than the maximum size of samples.
+func readSample(name string) ([]byte, error) {
+	fi, err := os.Lstat(name)
+	if err != nil {
+		return nil, err
+	} else if !fi.Mode().IsRegular() || fi.Size() > maxSampleSize {
+		return nil, nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(f, maxSampleSize+1))
+	if err != nil {
+		return nil, err
+	} else if len(data) > maxSampleSize {
+		return nil, nil
+	}
+	return data, nil
 }
 
 var defaultTags = map[string]string{"git": "master", "hg": "default", "svn": "trunk"}
@@ -340,7 +349,7 @@ func getRepoByArchive(match map[string]string, downloadPath string) (bool, strin
 		//fmt.Println(fileName)
 
 		// Get files and check if directories have acceptable files.
-		if d, fn := path.Split(fileName); base.IsDocFile(fn) &&
+		if d, fn := path.Split(fileName); (base.IsDocFile(fn) || d == dirPrefix && base.IsMetaFile(fn)) &&
 			base.FilterDirName(d) {
 			// Check if it's a Go file.
 			if !isGoPro && strings.HasSuffix(fn, ".go") {
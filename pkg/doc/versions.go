@@ -0,0 +1,140 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Versioner is implemented by stores that find tagged versions of a package
+// without listing the whole store.
+type Versioner interface {
+	// Versions returns tagged versions of the package, newest first.
+	Versions(importPath string) ([]string, error)
+}
+
+// versionIndexTTL is how long the index is used before it is loaded from the
+// underlying store again, which may be changed by other instances.
+const versionIndexTTL = 10 * time.Minute
+
+// VersionIndexDocStore indexes tagged versions of packages stored by the underlying DocStore.
+// The index is loaded on first use and kept up to date with puts and deletes.
+type VersionIndexDocStore struct {
+	DocStore
+
+	lock     sync.Mutex
+	loaded   time.Time
+	versions map[string]map[string]*DocMeta // Import path -> version -> metadata.
+}
+
+// NewVersionIndexDocStore returns a store that indexes tagged versions of given store.
+func NewVersionIndexDocStore(store DocStore) *VersionIndexDocStore {
+	return &VersionIndexDocStore{DocStore: store}
+}
+
+// load lists the underlying store if the index is not loaded or has expired.
+// It must be called with the lock held.
+func (s *VersionIndexDocStore) load() error {
+	if s.versions != nil && time.Since(s.loaded) < versionIndexTTL {
+		return nil
+	}
+
+	metas, err := s.DocStore.List()
+	if err != nil {
+		return err
+	}
+	s.versions = make(map[string]map[string]*DocMeta)
+	for _, m := range metas {
+		if len(m.Version) > 0 {
+			s.add(m)
+		}
+	}
+	s.loaded = time.Now()
+	return nil
+}
+
+func (s *VersionIndexDocStore) add(m *DocMeta) {
+	if s.versions[m.ImportPath] == nil {
+		s.versions[m.ImportPath] = make(map[string]*DocMeta)
+	}
+	s.versions[m.ImportPath][m.Version] = m
+}
+
+func (s *VersionIndexDocStore) Versions(importPath string) ([]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	metas := make([]*DocMeta, 0, len(s.versions[importPath]))
+	for _, m := range s.versions[importPath] {
+		metas = append(metas, m)
+	}
+	sort.Slice(metas, func(i, j int) bool {
+		return newerVersion(metas[i], metas[j])
+	})
+
+	versions := make([]string, len(metas))
+	for i, m := range metas {
+		versions[i] = m.Version
+	}
+	return versions, nil
+}
+
+func (s *VersionIndexDocStore) Put(pdoc *Package) error {
+	if err := s.DocStore.Put(pdoc); err != nil {
+		return err
+	} else if pdoc.PkgDecl == nil || len(pdoc.Tag) == 0 {
+		return nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.versions != nil {
+		s.add(&DocMeta{
+			ImportPath: pdoc.ImportPath,
+			Module:     ModuleOf(pdoc),
+			Version:    pdoc.Tag,
+			Walked:     time.Now().Unix(),
+		})
+	}
+	return nil
+}
+
+func (s *VersionIndexDocStore) Delete(importPath, version string) error {
+	if err := s.DocStore.Delete(importPath, version); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.versions != nil && len(version) > 0 {
+		delete(s.versions[importPath], version)
+		if len(s.versions[importPath]) == 0 {
+			delete(s.versions, importPath)
+		}
+	}
+	return nil
+}
+
+func (s *VersionIndexDocStore) Sweep() error {
+	if sweeper, ok := s.DocStore.(Sweeper); ok {
+		return sweeper.Sweep()
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	log "gopkg.in/clog.v1"
+)
+
+// Advisory represents a known vulnerability that affects a package.
+type Advisory struct {
+	ID      string   // e.g. "GO-2020-0001"
+	Aliases []string // e.g. CVE and GHSA IDs
+	Summary string
+	URL     string
+	// Affected symbols in the form of "Func", "Type" or "Type.Method",
+	// empty means symbol-level data is not available.
+	Symbols []string
+}
+
+// VulnProvider looks up known vulnerabilities, e.g. from OSV or Go vulnerability database.
+type VulnProvider interface {
+	// Advisories returns advisories that affect the package of given import path
+	// within the module at given version. The version is a tag name or commit ID.
+	Advisories(modulePath, importPath, version string) ([]*Advisory, error)
+}
+
+var vulnProvider VulnProvider
+
+// SetVulnProvider sets the provider to be consulted during walks,
+// nil disables vulnerability annotations.
+func SetVulnProvider(p VulnProvider) {
+	vulnProvider = p
+}
+
+func advisoriesOf(advs []*Advisory, symbol string) []*Advisory {
+	var matched []*Advisory
+	for _, adv := range advs {
+		for _, s := range adv.Symbols {
+			if s == symbol {
+				matched = append(matched, adv)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// checkVulns attaches advisories reported by the provider to the package,
+// and to functions and types when symbol-level data is available.
+func (w *Walker) checkVulns() {
+	// Private modules are not looked up in public databases.
+	if vulnProvider == nil || isNoSumDB(w.Pdoc.ImportPath) {
+		return
+	}
+
+	modulePath := w.Pdoc.ProjectPath
+	if len(modulePath) == 0 {
+		modulePath = w.Pdoc.ImportPath
+	}
+	version := w.Pdoc.Tag
+	if len(version) == 0 {
+		version = w.Pdoc.Etag
+	}
+
+	advs, err := vulnProvider.Advisories(modulePath, w.Pdoc.ImportPath, version)
+	if err != nil {
+		log.Warn("Failed to get advisories of %q: %v", w.Pdoc.ImportPath, err)
+		return
+	} else if len(advs) == 0 {
+		return
+	}
+	w.Pdoc.Advisories = advs
+
+	for _, f := range w.Pdoc.Funcs {
+		f.Advisories = advisoriesOf(advs, f.Name)
+	}
+	for _, t := range w.Pdoc.Types {
+		t.Advisories = advisoriesOf(advs, t.Name)
+		for _, f := range t.Funcs {
+			f.Advisories = advisoriesOf(advs, f.Name)
+		}
+		for _, m := range t.Methods {
+			m.Advisories = advisoriesOf(advs, t.Name+"."+m.Name)
+		}
+	}
+}
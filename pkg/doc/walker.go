@@ -17,11 +17,13 @@ package doc
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"go/ast"
 	"go/build"
 	"go/doc"
+	"go/format"
 	"go/parser"
 	"go/printer"
 	"go/token"
@@ -29,12 +31,17 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/Unknwon/com"
+
+	"github.com/Unknwon/gowalker/pkg/base"
 )
 
 // WalkDepth indicates how far the process goes.
@@ -63,6 +70,7 @@ const (
 	WM_All WalkMode = 1 << iota
 	WM_NoReadme
 	WM_NoExample
+	WM_CallGraph // Analyze calls between exported functions.
 )
 
 type WalkRes struct {
@@ -72,14 +80,44 @@ type WalkRes struct {
 	RootPath string    // For WT_Local mode.
 	Srcs     []*Source // For WT_Memory mode.
 	BuildAll bool
+	// Go files of usage samples found by sampleFiles, named by paths relative to repository root.
+	SampleSrcs []*Source
+	// Order of declarations, the one set by SetSortMode is used if empty.
+	SortMode SortMode
+	// Passed to post-processors, context.Background() is used if nil.
+	Context context.Context
 }
 
 // ------------------------------
 // WT_Local
 // ------------------------------
 
-func (w *Walker) setLocalContext(ctxt *build.Context) {
-	ctxt.IsAbsPath = path.IsAbs
+// readLocalSources returns files in the directory as sources,
+// subdirectories and hidden files are skipped.
+func readLocalSources(dir string) ([]*Source, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	srcs := make([]*Source, 0, len(fis))
+	for _, fi := range fis {
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+
+		fpath := filepath.Join(dir, fi.Name())
+		data, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			return nil, err
+		}
+		srcs = append(srcs, &Source{
+			SrcName:   fi.Name(),
+			BrowseUrl: fpath,
+			SrcData:   data,
+		})
+	}
+	return srcs, nil
 }
 
 // ------------------------------
@@ -94,6 +132,10 @@ func (w *Walker) readDir(dir string) ([]os.FileInfo, error) {
 	for _, src := range w.SrcFiles {
 		fis = append(fis, src)
 	}
+	// Sorted as ioutil.ReadDir, so that files are in the same order in every walk.
+	sort.Slice(fis, func(i, j int) bool {
+		return fis[i].Name() < fis[j].Name()
+	})
 	return fis, nil
 }
 
@@ -126,8 +168,31 @@ var badSynopsisPrefixes = []string{
 	"vim:",
 }
 
+// isWideScript returns true if the rune belongs to scripts that are written
+// without spaces between words.
+func isWideScript(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) ||
+		unicode.In(r, unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar) ||
+		(r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF)
+}
+
+// readmeLang returns language of the README file by its name,
+// or empty string if it is not a README file.
+func readmeLang(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasPrefix(name, "readme_zh") || strings.HasPrefix(name, "readme_cn"):
+		return "zh"
+	case strings.HasPrefix(name, "readme"):
+		return "en"
+	}
+	return ""
+}
+
 // Synopsis extracts the first sentence from s. All runs of whitespace are
-// replaced by a single space.
+// replaced by a single space, except those between characters of scripts
+// written without spaces. Sentences end with a period followed by whitespace,
+// or with a full stop of CJK languages.
 func synopsis(s string) string {
 	parts := strings.SplitN(s, "\n\n", 2)
 	s = parts[0]
@@ -139,35 +204,58 @@ func synopsis(s string) string {
 		space
 	)
 	last := space
+	var prev rune
+	pendingSpace := false
 Loop:
-	for i := 0; i < len(s); i++ {
-		b := s[i]
-		switch b {
+	for _, r := range s {
+		switch r {
 		case ' ', '\t', '\r', '\n':
 			switch last {
 			case period:
 				break Loop
 			case other:
-				buf = append(buf, ' ')
+				pendingSpace = true
 				last = space
 			}
-		case '.':
+			continue
+		}
+
+		if pendingSpace {
+			if !isWideScript(prev) || !isWideScript(r) {
+				buf = append(buf, ' ')
+			}
+			pendingSpace = false
+		}
+		buf = append(buf, string(r)...)
+		prev = r
+
+		switch r {
+		case '.', '\u06D4': // Arabic full stop is used like a period.
 			last = period
-			buf = append(buf, b)
+		case '\u3002', '\uFF0E', '\uFF01', '\uFF1F': // Full stops, exclamation and question marks of CJK.
+			break Loop
 		default:
 			last = other
-			buf = append(buf, b)
 		}
 	}
 
 	// Ensure that synopsis fits an App Engine datastore text property.
 	const m = 297
 	if len(buf) > m {
-		buf = buf[:m]
-		if i := bytes.LastIndex(buf, []byte{' '}); i >= 0 {
-			buf = buf[:i]
+		// Cut at the boundary of runes.
+		n := m
+		for n > 0 && !utf8.RuneStart(buf[n]) {
+			n--
+		}
+		buf = buf[:n]
+		if r, _ := utf8.DecodeLastRune(buf); isWideScript(r) {
+			buf = append(buf, "..."...)
+		} else {
+			if i := bytes.LastIndex(buf, []byte{' '}); i >= 0 {
+				buf = buf[:i]
+			}
+			buf = append(buf, " ..."...)
 		}
-		buf = append(buf, " ..."...)
 	}
 
 	s = string(buf)
@@ -188,6 +276,21 @@ Loop:
 	return strings.TrimRight(s, " \t\n\r")
 }
 
+// guessPackageName guesses the package name without importing it.
+func guessPackageName(path string) string {
+	// Start with the last element of the path.
+	name := path[strings.LastIndex(path, "/")+1:]
+
+	// Trim commonly used prefixes and suffixes containing illegal name
+	// runes.
+	name = strings.TrimSuffix(name, ".go")
+	name = strings.TrimSuffix(name, "-go")
+	name = strings.TrimPrefix(name, "go.")
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimPrefix(name, "biogo.")
+	return name
+}
+
 // poorMansImporter returns a (dummy) package object named
 // by the last path component of the provided package path
 // (as is the convention for packages). This is sufficient
@@ -197,19 +300,7 @@ Loop:
 func poorMansImporter(imports map[string]*ast.Object, path string) (*ast.Object, error) {
 	pkg := imports[path]
 	if pkg == nil {
-		// Guess the package name without importing it. Start with the last
-		// element of the path.
-		name := path[strings.LastIndex(path, "/")+1:]
-
-		// Trim commonly used prefixes and suffixes containing illegal name
-		// runes.
-		name = strings.TrimSuffix(name, ".go")
-		name = strings.TrimSuffix(name, "-go")
-		name = strings.TrimPrefix(name, "go.")
-		name = strings.TrimPrefix(name, "go-")
-		name = strings.TrimPrefix(name, "biogo.")
-
-		pkg = ast.NewObj(ast.Pkg, name)
+		pkg = ast.NewObj(ast.Pkg, guessPackageName(path))
 		pkg.Data = ast.NewScope(nil) // required by ast.NewPackage for dot-import
 		imports[path] = pkg
 	}
@@ -260,23 +351,22 @@ func (w *Walker) getExamples() {
 			output = ""
 		}
 
-		// play := ""
-		// if e.Play != nil {
-		// 	w.buf = w.buf[:0]
-		// 	if err := format.Node(sliceWriter{&w.buf}, w.fset, e.Play); err != nil {
-		// 		play = err.Error()
-		// 	} else {
-		// 		play = string(w.buf)
-		// 	}
-		// }
+		// Complete program is only available for examples in external test packages.
+		play := ""
+		if e.Play != nil {
+			var buf bytes.Buffer
+			if err := format.Node(&buf, w.Fset, e.Play); err == nil {
+				play = buf.String()
+			}
+		}
 
 		docs = append(docs, &Example{
 			Name:   e.Name,
 			Doc:    e.Doc,
 			Code:   code,
+			Play:   play,
 			Output: output,
 		})
-		//Play:   play
 	}
 
 	w.Pdoc.Examples = docs
@@ -294,16 +384,30 @@ func (w *Walker) printPos(pos token.Pos) string {
 	if src == nil || src.BrowseUrl == "" {
 		// src can be nil when line comments are used (//line <file>:<line>).
 		return ""
+	} else if len(w.LineFmt) == 0 {
+		return src.BrowseUrl
 	}
 	return src.BrowseUrl + fmt.Sprintf(w.LineFmt, position.Line)
 }
 
+// span returns source range of a declaration from pos to end.
+func (w *Walker) span(pos, end token.Pos) Span {
+	position := w.Fset.Position(pos)
+	return Span{
+		Filename: position.Filename,
+		Line:     position.Line,
+		Col:      position.Column,
+		EndLine:  w.Fset.Position(end).Line,
+	}
+}
+
 func (w *Walker) values(vdocs []*doc.Value) (vals []*Value) {
 	for _, d := range vdocs {
 		vals = append(vals, &Value{
 			Decl: w.printDecl(d.Decl),
 			URL:  w.printPos(d.Decl.Pos()),
 			Doc:  d.Doc,
+			Span: w.span(d.Decl.Pos(), d.Decl.End()),
 		})
 	}
 
@@ -315,7 +419,7 @@ func (w *Walker) printCode(decl ast.Node) string {
 	pos := decl.Pos()
 	posPos := w.Fset.Position(pos)
 	src := w.SrcFiles[posPos.Filename]
-	if src == nil || src.BrowseUrl == "" {
+	if src == nil || src.BrowseUrl == "" || posPos.Line < 1 {
 		// src can be nil when line comments are used (//line <file>:<line>).
 		return ""
 	}
@@ -367,7 +471,7 @@ func (w *Walker) funcs(fdocs []*doc.Func) (funcs []*Func, ifuncs []*Func) {
 			// default:
 			// 	exampleName = d.Recv + "_" + d.Name
 			// }
-			funcs = append(funcs, &Func{
+			f := &Func{
 				Decl: w.printDecl(d.Decl),
 				URL:  w.printPos(d.Decl.Pos()),
 				Doc:  d.Doc,
@@ -375,17 +479,21 @@ func (w *Walker) funcs(fdocs []*doc.Func) (funcs []*Func, ifuncs []*Func) {
 				Code: w.printCode(d.Decl),
 				// Recv:     d.Recv,
 				// Examples: w.getExamples(exampleName),
-			})
+			}
+			w.annotateFunc(f, d.Decl)
+			funcs = append(funcs, f)
 			continue
 		}
 
-		ifuncs = append(ifuncs, &Func{
+		f := &Func{
 			Decl: w.printDecl(d.Decl),
 			URL:  w.printPos(d.Decl.Pos()),
 			Doc:  d.Doc,
 			Name: d.Name,
 			Code: w.printCode(d.Decl),
-		})
+		}
+		w.annotateFunc(f, d.Decl)
+		ifuncs = append(ifuncs, f)
 	}
 
 	return funcs, ifuncs
@@ -399,18 +507,24 @@ func (w *Walker) types(tdocs []*doc.Type) (tps []*Type, itps []*Type) {
 
 		if unicode.IsUpper(rune(d.Name[0])) || isBuiltIn {
 			tps = append(tps, &Type{
-				Doc:      d.Doc,
-				Name:     d.Name,
-				Decl:     w.printDecl(d.Decl),
-				URL:      w.printPos(d.Decl.Pos()),
-				Consts:   w.values(d.Consts),
-				Vars:     w.values(d.Vars),
-				Funcs:    funcs,
-				IFuncs:   ifuncs,
-				Methods:  meths,
-				IMethods: imeths,
+				Doc:          d.Doc,
+				Name:         d.Name,
+				Decl:         w.printDecl(d.Decl),
+				URL:          w.printPos(d.Decl.Pos()),
+				Span:         w.span(d.Decl.Pos(), d.Decl.End()),
+				Consts:       w.values(d.Consts),
+				Vars:         w.values(d.Vars),
+				Funcs:        funcs,
+				IFuncs:       ifuncs,
+				Methods:      meths,
+				IMethods:     imeths,
+				EnumValues:   w.enumValues(d),
+				Capabilities: w.capabilities(d.Name),
+				Concurrency:  w.typeConcurrency(d.Name),
+				Resources:    w.typeResources(d.Name),
 				// Examples: w.getExamples(d.Name),
 			})
+			w.setAlias(tps[len(tps)-1], d.Decl)
 			continue
 		}
 
@@ -419,6 +533,7 @@ func (w *Walker) types(tdocs []*doc.Type) (tps []*Type, itps []*Type) {
 			Name:     d.Name,
 			Decl:     w.printDecl(d.Decl),
 			URL:      w.printPos(d.Decl.Pos()),
+			Span:     w.span(d.Decl.Pos(), d.Decl.End()),
 			Consts:   w.values(d.Consts),
 			Vars:     w.values(d.Vars),
 			Funcs:    funcs,
@@ -426,7 +541,9 @@ func (w *Walker) types(tdocs []*doc.Type) (tps []*Type, itps []*Type) {
 			Methods:  meths,
 			IMethods: imeths,
 		})
+		w.setAlias(itps[len(itps)-1], d.Decl)
 	}
+	moveAliasMethods(tps, itps)
 	return tps, itps
 }
 
@@ -439,14 +556,39 @@ func (w *Walker) isCgo() bool {
 	return false
 }
 
-var goEnvs = []struct{ GOOS, GOARCH string }{
+type goEnv struct{ GOOS, GOARCH string }
+
+var defaultGoEnvs = []goEnv{
 	{"linux", "amd64"},
 	{"darwin", "amd64"},
 	{"windows", "amd64"},
 }
 
+var goEnvs = defaultGoEnvs
+
+// SetGoEnvs sets GOOS/GOARCH pairs that walked packages are imported for,
+// e.g. "linux/arm64", empty means linux, darwin and windows on amd64.
+func SetGoEnvs(envs []string) error {
+	if len(envs) == 0 {
+		goEnvs = defaultGoEnvs
+		return nil
+	}
+
+	list := make([]goEnv, 0, len(envs))
+	for _, env := range envs {
+		i := strings.Index(env, "/")
+		if i <= 0 || i == len(env)-1 || strings.Count(env, "/") > 1 {
+			return fmt.Errorf("invalid GOOS/GOARCH pair %q", env)
+		}
+		list = append(list, goEnv{strings.TrimSpace(env[:i]), strings.TrimSpace(env[i+1:])})
+	}
+	goEnvs = list
+	return nil
+}
+
 // Build generates documentation from given source files through 'WalkType'.
 func (w *Walker) Build(wr *WalkRes) (*Package, error) {
+	start := time.Now()
 	ctxt := build.Context{
 		CgoEnabled:  true,
 		ReleaseTags: build.Default.ReleaseTags,
@@ -468,25 +610,61 @@ func (w *Walker) Build(wr *WalkRes) (*Package, error) {
 			return nil, errors.New("WT_Local: cannot find specific directory or it's a file")
 		}
 
-		w.setLocalContext(&ctxt)
-		return nil, errors.New("Hasn't supported yet!")
+		srcs, err := readLocalSources(wr.RootPath)
+		if err != nil {
+			return nil, errors.New("WT_Local: read directory: " + err.Error())
+		}
+		wr.Srcs = srcs
+		// Walk files in memory once they are loaded.
+		fallthrough
 	case WT_Memory:
+		e := &Event{Phase: PhaseFetch, After: true, ImportPath: w.Pdoc.ImportPath, Pdoc: w.Pdoc, Srcs: wr.Srcs}
+		if err := fireHooks(e); err != nil {
+			return nil, err
+		}
+		wr.Srcs = e.Srcs
+		if err := readBlobs(wr.Srcs); err != nil {
+			return nil, err
+		}
+
 		// Convert source files.
 		w.SrcFiles = make(map[string]*Source)
 		w.Pdoc.Readme = make(map[string][]byte)
+		hasBuildBazel := false
 		for _, src := range wr.Srcs {
-			srcName := strings.ToLower(src.Name()) // For readme comparation.
 			switch {
 			case strings.HasSuffix(src.Name(), ".go"):
 				w.SrcFiles[src.Name()] = src
+			case base.IsBazelBuildFile(src.Name()):
+				// BUILD.bazel takes precedence over BUILD as Bazel does.
+				if !hasBuildBazel {
+					w.Pdoc.BazelTargets = parseBazelBuild(src.Data(), bazelPackage(w.Pdoc.ImportPath, w.Pdoc.ProjectPath))
+					hasBuildBazel = src.Name() == "BUILD.bazel"
+				}
+			case src.Name() == "go.mod":
+				w.setModule(src.Data())
+				w.setMaintainers(src)
+			case base.IsMaintainerFile(src.Name()):
+				w.setMaintainers(src)
+			case src.Name() == "CITATION.cff":
+				// Malformed citation file should not stop generating documentation.
+				if citation, err := parseCitation(src.Data()); err == nil {
+					w.Pdoc.Citation = citation
+				}
+			case base.IsChangelogFile(src.Name()):
+				w.Pdoc.Changelog = parseChangelog(src.Data())
+			case base.IsLicenseFile(src.Name()):
+				if license := detectLicense(src.Data()); len(license) > 0 {
+					w.Pdoc.Licenses = appendLicense(w.Pdoc.Licenses, license)
+				}
 			case len(w.Pdoc.Tag) > 0 || (wr.WalkMode&WM_NoReadme != 0):
 				// This means we are not on the latest version of the code,
 				// so we do not collect the README files.
 				continue
-			case strings.HasPrefix(srcName, "readme_zh") || strings.HasPrefix(srcName, "readme_cn"):
-				w.Pdoc.Readme["zh"] = src.Data()
-			case strings.HasPrefix(srcName, "readme"):
-				w.Pdoc.Readme["en"] = src.Data()
+			default:
+				if lang := readmeLang(src.Name()); len(lang) > 0 {
+					w.Pdoc.Readme[lang] = src.Data()
+				}
 			}
 		}
 
@@ -529,9 +707,13 @@ func (w *Walker) Build(wr *WalkRes) (*Package, error) {
 
 	// Check depth.
 	if wr.WalkDepth <= WD_Imports {
+		w.setProvenance(wr.Srcs, start)
 		return w.Pdoc, nil
 	}
 
+	if err = fireHooks(&Event{Phase: PhaseParse, ImportPath: w.Pdoc.ImportPath, Pdoc: w.Pdoc, Srcs: wr.Srcs}); err != nil {
+		return nil, err
+	}
 	w.Fset = token.NewFileSet()
 	// Parse the Go files
 	files := make(map[string]*ast.File)
@@ -546,7 +728,24 @@ func (w *Walker) Build(wr *WalkRes) (*Package, error) {
 		files[name] = file
 	}
 
+	if err = fireHooks(&Event{Phase: PhaseParse, After: true, ImportPath: w.Pdoc.ImportPath, Pdoc: w.Pdoc, Srcs: wr.Srcs, Files: files}); err != nil {
+		return nil, err
+	} else if err = fireHooks(&Event{Phase: PhaseDoc, ImportPath: w.Pdoc.ImportPath, Pdoc: w.Pdoc, Files: files}); err != nil {
+		return nil, err
+	}
+
 	w.apkg, _ = ast.NewPackage(w.Fset, files, poorMansImporter, nil)
+	w.typeCheck(files)
+	w.Pdoc.APIValues = w.apiValues()
+	w.funcEnds = funcEnds(files)
+	w.failures = w.funcFailures(files)
+	w.resources = funcResources(files)
+	w.Pdoc.Refs = w.externalRefs(files)
+	// Function bodies are trimmed by go/doc, collect calls in advance.
+	if wr.WalkMode&WM_CallGraph != 0 {
+		w.calls = w.staticCalls(files)
+	}
+	w.Pdoc.SafetyFlags = w.safetyFlags(files)
 
 	// Find examples in the test files.
 	for _, name := range append(bpkg.TestGoFiles, bpkg.XTestGoFiles...) {
@@ -557,6 +756,7 @@ func (w *Walker) Build(wr *WalkRes) (*Package, error) {
 		}
 		w.Pdoc.TestFiles = append(w.Pdoc.TestFiles, w.SrcFiles[name])
 		//w.pdoc.TestSourceSize += len(w.srcs[name].data)
+		w.Pdoc.Benchmarks = append(w.Pdoc.Benchmarks, w.benchmarks(file)...)
 
 		if wr.WalkMode&WM_NoExample != 0 {
 			continue
@@ -574,13 +774,15 @@ func (w *Walker) Build(wr *WalkRes) (*Package, error) {
 	pdoc.Doc = strings.TrimRight(pdoc.Doc, " \t\n\r")
 	var buf bytes.Buffer
 	doc.ToHTML(&buf, pdoc.Doc, nil)
-	w.Pdoc.Doc = buf.String()
+	w.Pdoc.Doc = SanitizeHTML(renderDiagrams(buf.String()))
 	// Highlight first sentence.
 	w.Pdoc.Doc = strings.Replace(w.Pdoc.Doc, "<p>", "<p><b>", 1)
 	w.Pdoc.Doc = strings.Replace(w.Pdoc.Doc, "</p>", "</b></p>", 1)
 
 	if wr.WalkMode&WM_NoExample == 0 {
 		w.getExamples()
+		w.setSamples(wr.SampleSrcs)
+		w.setSnippets()
 	}
 
 	w.SrcLines = make(map[string][]string)
@@ -588,9 +790,30 @@ func (w *Walker) Build(wr *WalkRes) (*Package, error) {
 	w.Pdoc.Funcs, w.Pdoc.Ifuncs = w.funcs(pdoc.Funcs)
 	w.Pdoc.Types, w.Pdoc.Itypes = w.types(pdoc.Types)
 	w.Pdoc.Vars = w.values(pdoc.Vars)
+	if len(wr.SortMode) > 0 {
+		SortDecls(w.Pdoc, wr.SortMode)
+	} else {
+		SortDecls(w.Pdoc, sortMode)
+	}
 	w.Pdoc.ImportPaths = strings.Join(pdoc.Imports, "|")
 	w.Pdoc.ImportNum = int64(len(pdoc.Imports))
 	//w.Pdoc.Notes = w.notes(pdoc.Notes)
+	if wr.WalkMode&WM_CallGraph != 0 {
+		w.setCallGraph()
+	}
+	w.setQuickStart(files)
+	w.checkVulns()
+	if err = fireHooks(&Event{Phase: PhaseDoc, After: true, ImportPath: w.Pdoc.ImportPath, Pdoc: w.Pdoc, Files: files}); err != nil {
+		return nil, err
+	}
+	w.setProvenance(wr.Srcs, start)
 
+	ctx := wr.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := postProcess(ctx, w.Pdoc); err != nil {
+		return nil, errors.New("Walker.Build -> post-process: " + err.Error())
+	}
 	return w.Pdoc, nil
 }
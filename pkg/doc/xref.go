@@ -0,0 +1,127 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package doc
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"github.com/Unknwon/com"
+
+	"github.com/Unknwon/gowalker/pkg/base"
+)
+
+var (
+	ErrRefNotFound = errors.New("referenced symbol does not found")
+	// ErrRefUnlinked is returned when the symbol is outside of the corpus
+	// and the link policy is LinkNone.
+	ErrRefUnlinked = errors.New("referenced symbol is not linked")
+)
+
+// LinkPolicy decides where references to symbols of packages outside of the corpus link to.
+type LinkPolicy string
+
+const (
+	LinkPkgGoDev LinkPolicy = "pkg.go.dev" // Documentation on pkg.go.dev.
+	LinkOrigin   LinkPolicy = "origin"     // Source of the package in its repository.
+	LinkNone     LinkPolicy = "none"       // Rendered without links.
+)
+
+// FallbackDocsURL is where references to packages outside of the corpus link to by LinkPkgGoDev.
+const FallbackDocsURL = "https://pkg.go.dev/"
+
+var linkPolicy = LinkPkgGoDev
+
+// ParseLinkPolicy returns the link policy of given name, empty name means LinkPkgGoDev.
+func ParseLinkPolicy(name string) (LinkPolicy, error) {
+	switch p := LinkPolicy(strings.ToLower(name)); p {
+	case "":
+		return LinkPkgGoDev, nil
+	case LinkPkgGoDev, LinkOrigin, LinkNone:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown link policy %q", name)
+}
+
+// SetLinkPolicy sets where references to symbols outside of the corpus link to.
+func SetLinkPolicy(p LinkPolicy) {
+	linkPolicy = p
+}
+
+// originURL returns URL of the source directory of the package in its repository.
+func originURL(importPath string) string {
+	if base.IsGoRepoPath(importPath) {
+		return "https://github.com/golang/go/tree/master/src/" + importPath
+	} else if m := githubPattern.FindStringSubmatch(importPath); m != nil {
+		match := make(map[string]string)
+		for i, n := range githubPattern.SubexpNames() {
+			if n != "" {
+				match[n] = m[i]
+			}
+		}
+		if len(match["dir"]) == 0 {
+			return com.Expand("https://github.com/{owner}/{repo}", match)
+		}
+		return com.Expand("https://github.com/{owner}/{repo}/tree/HEAD{dir}", match)
+	}
+	// Other hosts redirect browsers to repositories or documentation in most cases.
+	return "https://" + importPath
+}
+
+// SymbolIndexer is implemented by indexers that know exported symbols of indexed packages.
+type SymbolIndexer interface {
+	// HasSymbol returns whether the package of given import path is indexed,
+	// and whether it declares the symbol.
+	HasSymbol(importPath, name string) (indexed, declared bool)
+}
+
+// ResolveRef returns URL of documentation of the symbol declared by the package
+// of given import path, e.g. "Client" or "Client.Do" for methods. Packages in
+// the corpus are linked to pages of this site, and others are linked to
+// the link policy. When the indexer does not know symbols, pages of this site
+// are always used as before, which walk packages on demand.
+func ResolveRef(importPath, symbol string) (string, error) {
+	name := symbol
+	if i := strings.LastIndex(symbol, "."); i > -1 {
+		name = symbol[i+1:]
+	}
+	if len(importPath) == 0 || !ast.IsExported(name) {
+		return "", ErrRefNotFound
+	} else if IsBlocked(importPath) {
+		return "", ErrBlocked
+	}
+
+	si, ok := indexer.(SymbolIndexer)
+	if !ok {
+		return "/" + importPath + "#" + strings.Replace(symbol, ".", "_", 1), nil
+	}
+	indexed, declared := si.HasSymbol(importPath, symbol)
+	switch {
+	case indexed && declared:
+		return "/" + importPath + "#" + strings.Replace(symbol, ".", "_", 1), nil
+	case indexed:
+		return "", ErrRefNotFound
+	}
+
+	switch linkPolicy {
+	case LinkOrigin:
+		return originURL(importPath), nil
+	case LinkNone:
+		return "", ErrRefUnlinked
+	}
+	return FallbackDocsURL + importPath + "#" + symbol, nil
+}
@@ -0,0 +1,315 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package ghapp is a client of GitHub App API to review documentation and
+// exported API changes of pull requests.
+package ghapp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// App authenticates as a GitHub App and its installations.
+type App struct {
+	ID            int64
+	Key           *rsa.PrivateKey
+	WebhookSecret string
+	APIURL        string // Default is https://api.github.com.
+	Client        *http.Client
+
+	lock   sync.Mutex
+	tokens map[int64]*installationToken // Installation ID -> token.
+}
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ParsePrivateKey parses PEM encoded RSA private key of the app,
+// in either PKCS #1 or PKCS #8 form.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return rsaKey, nil
+}
+
+// New returns a new app with the private key file, the webhook secret
+// is required to verify deliveries.
+func New(id int64, keyFile, webhookSecret string) (*App, error) {
+	if len(webhookSecret) == 0 {
+		return nil, errors.New("webhook secret is required")
+	}
+
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %v", err)
+	}
+	key, err := ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %v", err)
+	}
+	return &App{
+		ID:            id,
+		Key:           key,
+		WebhookSecret: webhookSecret,
+		APIURL:        "https://api.github.com",
+		Client:        &http.Client{Timeout: time.Minute},
+		tokens:        make(map[int64]*installationToken),
+	}, nil
+}
+
+// VerifySignature returns true if the signature of X-Hub-Signature-256 header
+// matches the payload of the webhook.
+func (a *App) VerifySignature(payload []byte, signature string) bool {
+	// Anyone can sign payloads with an empty secret.
+	if len(a.WebhookSecret) == 0 || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(a.WebhookSecret))
+	mac.Write(payload)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+// jwt returns a JSON Web Token that authenticates as the app.
+func (a *App) jwt() (string, error) {
+	enc := base64.RawURLEncoding
+	now := time.Now()
+	claims, err := json.Marshal(map[string]interface{}{
+		// Allow clock drift between servers.
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signed := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.Key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + enc.EncodeToString(sig), nil
+}
+
+// token returns an access token of the installation, which is cached until
+// shortly before it expires.
+func (a *App) token(installationID int64) (string, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if t := a.tokens[installationID]; t != nil && time.Until(t.ExpiresAt) > 5*time.Minute {
+		return t.Token, nil
+	}
+
+	jwt, err := a.jwt()
+	if err != nil {
+		return "", fmt.Errorf("sign JWT: %v", err)
+	}
+	t := new(installationToken)
+	if err = a.do("POST", fmt.Sprintf("/app/installations/%d/access_tokens", installationID), "Bearer "+jwt, nil, t); err != nil {
+		return "", err
+	}
+	a.tokens[installationID] = t
+	return t.Token, nil
+}
+
+// do sends a request to the API and decodes the JSON response to v if not nil.
+func (a *App) do(method, path, authorization string, body, v interface{}) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, a.APIURL+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", authorization)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		p, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, p)
+	} else if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Installation is a client that acts as an installation of the app.
+type Installation struct {
+	app *App
+	ID  int64
+}
+
+// Installation returns the client of the installation.
+func (a *App) Installation(id int64) *Installation {
+	return &Installation{app: a, ID: id}
+}
+
+func (i *Installation) do(method, path string, body, v interface{}) error {
+	token, err := i.app.token(i.ID)
+	if err != nil {
+		return fmt.Errorf("get installation token: %v", err)
+	}
+	return i.app.do(method, path, "token "+token, body, v)
+}
+
+// PullRequestFiles returns names of files changed by the pull request of the repository,
+// which is in the form of "owner/name".
+func (i *Installation) PullRequestFiles(repo string, number int) ([]string, error) {
+	var names []string
+	// The API lists at most 3000 files.
+	for page := 1; page <= 30; page++ {
+		var files []struct {
+			Filename         string `json:"filename"`
+			PreviousFilename string `json:"previous_filename"`
+		}
+		if err := i.do("GET", fmt.Sprintf("/repos/%s/pulls/%d/files?per_page=100&page=%d", repo, number, page), nil, &files); err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			names = append(names, f.Filename)
+			if len(f.PreviousFilename) > 0 {
+				names = append(names, f.PreviousFilename)
+			}
+		}
+		if len(files) < 100 {
+			break
+		}
+	}
+	return names, nil
+}
+
+// Maximum size of downloaded tarballs.
+const maxTarballSize = 100 << 20
+
+// DownloadTarball saves the gzipped tarball of the repository at the commit to the file.
+func (i *Installation) DownloadTarball(repo, sha, filename string) error {
+	token, err := i.app.token(i.ID)
+	if err != nil {
+		return fmt.Errorf("get installation token: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/repos/%s/tarball/%s", i.app.APIURL, repo, sha), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "token "+token)
+	resp, err := i.app.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("download tarball: %s", resp.Status)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxTarballSize+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > maxTarballSize {
+		err = fmt.Errorf("tarball is larger than %d bytes", maxTarballSize)
+	}
+	return err
+}
+
+// CheckRunOutput is the report of a check run, the summary and text are in Markdown.
+type CheckRunOutput struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+	Text    string `json:"text,omitempty"`
+}
+
+// CheckRun is a check run of a commit.
+type CheckRun struct {
+	ID         int64           `json:"id,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	HeadSHA    string          `json:"head_sha,omitempty"`
+	Status     string          `json:"status,omitempty"`     // queued, in_progress or completed.
+	Conclusion string          `json:"conclusion,omitempty"` // success, neutral, failure, etc., required when completed.
+	DetailsURL string          `json:"details_url,omitempty"`
+	Output     *CheckRunOutput `json:"output,omitempty"`
+}
+
+// CreateCheckRun creates the check run, and sets its ID.
+func (i *Installation) CreateCheckRun(repo string, run *CheckRun) error {
+	return i.do("POST", "/repos/"+repo+"/check-runs", run, run)
+}
+
+// UpdateCheckRun updates the check run of given ID.
+func (i *Installation) UpdateCheckRun(repo string, run *CheckRun) error {
+	// ID and commit of the check run cannot be updated.
+	update := *run
+	update.ID, update.HeadSHA = 0, ""
+	return i.do("PATCH", fmt.Sprintf("/repos/%s/check-runs/%d", repo, run.ID), &update, nil)
+}
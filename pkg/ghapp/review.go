@@ -0,0 +1,348 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ghapp
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/index"
+)
+
+// CheckRunName is the name of check runs created by the app.
+const CheckRunName = "Go Walker"
+
+// PackageReview is the changes of a package between base and head of a pull request.
+type PackageReview struct {
+	ImportPath    string
+	Dir           string // Relative to root of the repository.
+	IsNew         bool
+	IsRemoved     bool
+	Diff          *doc.APIDiff
+	BaseCoverage  float64 // Fraction of exported identifiers that are documented.
+	HeadCoverage  float64
+	Undocumented  []string // Exported identifiers that are added or changed without documentation.
+	Documentation string   // Package documentation of head in plain text.
+}
+
+// Review is the changes of packages of a pull request.
+type Review struct {
+	Packages []*PackageReview
+}
+
+// Compatible returns true if no exported identifier or package is removed or changed.
+func (r *Review) Compatible() bool {
+	for _, p := range r.Packages {
+		if p.IsRemoved || !p.Diff.Compatible() {
+			return false
+		}
+	}
+	return true
+}
+
+// ChangedDirs returns directories of changed Go files, except for vendored
+// packages and test data.
+func ChangedDirs(files []string) []string {
+	set := make(map[string]bool)
+	for _, name := range files {
+		if !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		dir := path.Dir(name)
+		if dir == "." {
+			dir = ""
+		}
+		skip := false
+		for _, elem := range strings.Split(dir, "/") {
+			if elem == "vendor" || elem == "testdata" || strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			set[dir] = true
+		}
+	}
+
+	dirs := make([]string, 0, len(set))
+	for dir := range set {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// undocumented returns names of added or changed identifiers that have no documentation.
+func undocumented(entry *index.Entry, d *doc.APIDiff) []string {
+	changed := make(map[string]bool)
+	for _, c := range d.Added {
+		changed[c.Name] = true
+	}
+	for _, c := range d.Changed {
+		changed[c.Name] = true
+	}
+
+	var names []string
+	for _, sym := range entry.Symbols {
+		if changed[sym.Name] && len(strings.TrimSpace(sym.Doc)) == 0 {
+			names = append(names, sym.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// walkArchiveDir returns false if the package does not exist in the archive,
+// i.e. it fails to walk or has no Go files, and an empty package is returned.
+func walkArchiveDir(filename, dir, importPath string) (*doc.Package, bool) {
+	pdoc, err := doc.WalkArchiveDir(filename, dir, importPath)
+	if err != nil || pdoc.PkgDecl == nil || len(pdoc.Files) == 0 {
+		return &doc.Package{}, false
+	}
+	return pdoc, true
+}
+
+// ReviewArchives reviews packages in the directories between base and head
+// tarballs of the repository. Import paths of packages are the root path joined
+// with directories, or derived from go.mod files if the root path is empty.
+func ReviewArchives(baseFile, headFile, rootPath string, dirs []string) *Review {
+	r := new(Review)
+	for _, dir := range dirs {
+		importPath := ""
+		if len(rootPath) > 0 {
+			importPath = strings.TrimSuffix(rootPath+"/"+dir, "/")
+		}
+
+		baseDoc, baseOK := walkArchiveDir(baseFile, dir, importPath)
+		headDoc, headOK := walkArchiveDir(headFile, dir, importPath)
+		if !baseOK && !headOK {
+			continue
+		}
+
+		p := &PackageReview{Dir: dir}
+		if !baseOK {
+			p.IsNew = true
+		} else {
+			p.ImportPath = baseDoc.ImportPath
+			p.BaseCoverage = index.NewEntry(baseDoc).DocCoverage
+		}
+		if !headOK {
+			p.IsRemoved = true
+		} else {
+			entry := index.NewEntry(headDoc)
+			p.ImportPath = headDoc.ImportPath
+			p.HeadCoverage = entry.DocCoverage
+			p.Documentation = strings.TrimSpace(entry.Doc)
+		}
+
+		p.Diff = doc.Diff(baseDoc, headDoc)
+		if !p.IsRemoved {
+			p.Undocumented = undocumented(index.NewEntry(headDoc), p.Diff)
+		}
+		if p.IsNew || p.IsRemoved || !p.Diff.Empty() || p.BaseCoverage != p.HeadCoverage {
+			r.Packages = append(r.Packages, p)
+		}
+	}
+	return r
+}
+
+// Maximum length of summary and text of check run outputs.
+const maxOutputLength = 65535
+
+func truncate(s string) string {
+	if len(s) <= maxOutputLength {
+		return s
+	}
+	return s[:maxOutputLength-len("\n\n...")] + "\n\n..."
+}
+
+func writeChanges(buf *bytes.Buffer, title string, changes []*doc.Change, decl func(*doc.Change) string) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintf(buf, "**%s**\n\n```go\n", title)
+	for _, c := range changes {
+		fmt.Fprintln(buf, decl(c))
+	}
+	buf.WriteString("```\n\n")
+}
+
+// Output returns the report of the review in Markdown.
+func (r *Review) Output() *CheckRunOutput {
+	if len(r.Packages) == 0 {
+		return &CheckRunOutput{
+			Title:   "No API or documentation changes",
+			Summary: "No exported identifiers or documentation coverage of packages are changed.",
+		}
+	}
+
+	var summary bytes.Buffer
+	summary.WriteString("| Package | API | Documented |\n|---|---|---|\n")
+	for _, p := range r.Packages {
+		api := "compatible"
+		switch {
+		case p.IsNew:
+			api = "new package"
+		case p.IsRemoved:
+			api = "**removed**"
+		case p.Diff.Empty():
+			api = "unchanged"
+		case !p.Diff.Compatible():
+			api = "**incompatible**"
+		}
+		fmt.Fprintf(&summary, "| `%s` | %s | %.0f%% → %.0f%% |\n", p.ImportPath, api, p.BaseCoverage*100, p.HeadCoverage*100)
+	}
+
+	var text bytes.Buffer
+	for _, p := range r.Packages {
+		fmt.Fprintf(&text, "### %s\n\n", p.ImportPath)
+		if p.IsNew && len(p.Documentation) > 0 {
+			fmt.Fprintf(&text, "%s\n\n", p.Documentation)
+		}
+		writeChanges(&text, "Removed", p.Diff.Removed, func(c *doc.Change) string { return c.Old })
+		writeChanges(&text, "Changed", p.Diff.Changed, func(c *doc.Change) string {
+			return "- " + strings.Replace(c.Old, "\n", "\n- ", -1) + "\n+ " + strings.Replace(c.New, "\n", "\n+ ", -1)
+		})
+		writeChanges(&text, "Added", p.Diff.Added, func(c *doc.Change) string { return c.New })
+		if len(p.Undocumented) > 0 {
+			fmt.Fprintf(&text, "Undocumented: `%s`\n\n", strings.Join(p.Undocumented, "`, `"))
+		}
+	}
+
+	title := "API changes are backward compatible"
+	if !r.Compatible() {
+		title = "API changes are NOT backward compatible"
+	}
+	return &CheckRunOutput{
+		Title:   title,
+		Summary: truncate(summary.String()),
+		Text:    truncate(text.String()),
+	}
+}
+
+// Conclusion returns conclusion of the check run, incompatible changes are
+// neutral so that they are noticed but do not block merging.
+func (r *Review) Conclusion() string {
+	if r.Compatible() {
+		return "success"
+	}
+	return "neutral"
+}
+
+// PullRequestEvent is the payload of webhooks of pull request events.
+type PullRequestEvent struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+		Base struct {
+			SHA string `json:"sha"`
+		} `json:"base"`
+	} `json:"pull_request"`
+	Repository struct {
+		FullName string `json:"full_name"`
+		HTMLURL  string `json:"html_url"`
+	} `json:"repository"`
+	Installation struct {
+		ID int64 `json:"id"`
+	} `json:"installation"`
+}
+
+// NeedsReview returns true if the event changes code of the pull request.
+func (e *PullRequestEvent) NeedsReview() bool {
+	switch e.Action {
+	case "opened", "synchronize", "reopened":
+		return e.Installation.ID > 0
+	}
+	return false
+}
+
+// rootPath returns the import path of the repository, e.g. "github.com/owner/name".
+func (e *PullRequestEvent) rootPath() string {
+	return strings.TrimPrefix(strings.TrimPrefix(e.Repository.HTMLURL, "https://"), "http://")
+}
+
+// ReviewPullRequest walks packages changed by the pull request at base and
+// head commits, and reports the API and documentation changes in a check run
+// of the head commit.
+func (a *App) ReviewPullRequest(e *PullRequestEvent) error {
+	inst := a.Installation(e.Installation.ID)
+	repo := e.Repository.FullName
+	run := &CheckRun{
+		Name:    CheckRunName,
+		HeadSHA: e.PullRequest.Head.SHA,
+		Status:  "in_progress",
+	}
+	if err := inst.CreateCheckRun(repo, run); err != nil {
+		return fmt.Errorf("create check run: %v", err)
+	}
+
+	review, err := a.review(inst, e)
+	run.Status = "completed"
+	if err != nil {
+		run.Conclusion = "failure"
+		run.Output = &CheckRunOutput{
+			Title:   "Failed to walk packages",
+			Summary: truncate(err.Error()),
+		}
+	} else {
+		run.Conclusion = review.Conclusion()
+		run.Output = review.Output()
+	}
+	if uerr := inst.UpdateCheckRun(repo, run); uerr != nil {
+		return fmt.Errorf("update check run: %v", uerr)
+	}
+	return err
+}
+
+func (a *App) review(inst *Installation, e *PullRequestEvent) (*Review, error) {
+	repo := e.Repository.FullName
+	files, err := inst.PullRequestFiles(repo, e.Number)
+	if err != nil {
+		return nil, fmt.Errorf("list files: %v", err)
+	}
+	dirs := ChangedDirs(files)
+	if len(dirs) == 0 {
+		return new(Review), nil
+	}
+
+	tmpDir, err := ioutil.TempDir("", "gowalker-ghapp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	baseFile := filepath.Join(tmpDir, "base.tar.gz")
+	headFile := filepath.Join(tmpDir, "head.tar.gz")
+	if err = inst.DownloadTarball(repo, e.PullRequest.Base.SHA, baseFile); err != nil {
+		return nil, fmt.Errorf("download base: %v", err)
+	} else if err = inst.DownloadTarball(repo, e.PullRequest.Head.SHA, headFile); err != nil {
+		return nil, fmt.Errorf("download head: %v", err)
+	}
+
+	log.Trace("GitHub App: reviewing %d directories of %s#%d", len(dirs), repo, e.Number)
+	return ReviewArchives(baseFile, headFile, e.rootPath(), dirs), nil
+}
@@ -0,0 +1,314 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package graphql executes GraphQL queries against objects that resolve their
+// own fields, without a declared schema. Introspection other than __typename
+// is not supported.
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrNoField is returned by Object.Resolve when the object does not have the field.
+var ErrNoField = errors.New("no such field")
+
+// Object is a GraphQL object whose fields are resolved on demand.
+type Object interface {
+	TypeName() string
+	// Resolve returns value of the field with given arguments. The value is
+	// an Object, a slice of Objects, or a scalar that can be encoded to JSON.
+	// Nil pointers of Objects are resolved as null.
+	Resolve(field string, args map[string]interface{}) (interface{}, error)
+}
+
+// Request is a GraphQL request in JSON.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Error is an error of the request or of resolving a field.
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Response is a GraphQL response in JSON.
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// orderedMap is a JSON object that keeps keys in the order of selections.
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{values: make(map[string]interface{})}
+}
+
+func (m *orderedMap) set(key string, val interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = val
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// maxResolvedFields is the maximum number of fields resolved by a request,
+// fields of each element of lists are counted separately.
+const maxResolvedFields = 10000
+
+type executor struct {
+	doc      *Document
+	vars     map[string]interface{}
+	errors   []*Error
+	resolved int
+}
+
+// Execute executes the query operation of the request against the root object.
+func Execute(root Object, req *Request) *Response {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	var op *Operation
+	for _, o := range doc.Operations {
+		if len(req.OperationName) == 0 || o.Name == req.OperationName {
+			if op != nil {
+				return &Response{Errors: []*Error{{Message: "operationName is required for multiple operations"}}}
+			}
+			op = o
+		}
+	}
+	switch {
+	case op == nil:
+		return &Response{Errors: []*Error{{Message: fmt.Sprintf("unknown operation %q", req.OperationName)}}}
+	case op.Type != "query":
+		return &Response{Errors: []*Error{{Message: fmt.Sprintf("%s is not supported", op.Type)}}}
+	}
+
+	e := &executor{
+		doc:  doc,
+		vars: make(map[string]interface{}),
+	}
+	for _, v := range op.Variables {
+		val, ok := req.Variables[v.Name]
+		switch {
+		case ok:
+			e.vars[v.Name] = val
+		case v.HasDefault:
+			e.vars[v.Name] = e.resolveValue(v.Default)
+		case v.NonNull:
+			return &Response{Errors: []*Error{{Message: fmt.Sprintf("variable $%s is required", v.Name)}}}
+		}
+	}
+
+	resp := &Response{Data: e.selectObject(root, op.Selections, nil, 1)}
+	resp.Errors = e.errors
+	return resp
+}
+
+// resolveValue replaces variables and enum values in the value with their values.
+func (e *executor) resolveValue(v Value) interface{} {
+	switch v := v.(type) {
+	case Variable:
+		return e.vars[string(v)]
+	case EnumValue:
+		return string(v)
+	case []Value:
+		list := make([]interface{}, len(v))
+		for i := range v {
+			list[i] = e.resolveValue(v[i])
+		}
+		return list
+	case map[string]Value:
+		obj := make(map[string]interface{}, len(v))
+		for k := range v {
+			obj[k] = e.resolveValue(v[k])
+		}
+		return obj
+	}
+	return v
+}
+
+func (e *executor) arguments(args []*Argument) map[string]interface{} {
+	vals := make(map[string]interface{}, len(args))
+	for _, arg := range args {
+		vals[arg.Name] = e.resolveValue(arg.Value)
+	}
+	return vals
+}
+
+// included returns false if the selection is excluded by @skip or @include directives.
+func (e *executor) included(dirs []*Directive) bool {
+	for _, d := range dirs {
+		cond, _ := e.arguments(d.Args)["if"].(bool)
+		if (d.Name == "skip" && cond) || (d.Name == "include" && !cond) {
+			return false
+		}
+	}
+	return true
+}
+
+// collectFields flattens fragments of selections on the object into fields.
+func (e *executor) collectFields(obj Object, sels []Selection, fields []*Field, visited map[string]bool) []*Field {
+	for _, sel := range sels {
+		switch sel := sel.(type) {
+		case *Field:
+			if e.included(sel.Directives) {
+				fields = append(fields, sel)
+			}
+		case *InlineFragment:
+			if e.included(sel.Directives) && (len(sel.TypeCond) == 0 || sel.TypeCond == obj.TypeName()) {
+				fields = e.collectFields(obj, sel.Selections, fields, visited)
+			}
+		case *FragmentSpread:
+			f := e.doc.Fragments[sel.Name]
+			if f == nil || visited[sel.Name] || !e.included(sel.Directives) || f.TypeCond != obj.TypeName() {
+				continue
+			}
+			visited[sel.Name] = true
+			fields = e.collectFields(obj, f.Selections, fields, visited)
+		}
+	}
+	return fields
+}
+
+func (e *executor) addError(path []interface{}, format string, args ...interface{}) {
+	e.errors = append(e.errors, &Error{
+		Message: fmt.Sprintf(format, args...),
+		Path:    append([]interface{}(nil), path...),
+	})
+}
+
+// selectObject resolves fields of the object at given depth of selection sets,
+// which can be deeper than that of the document by nested fragments.
+func (e *executor) selectObject(obj Object, sels []Selection, path []interface{}, depth int) *orderedMap {
+	if depth > MaxDepth {
+		e.addError(path, "selections are nested deeper than %d", MaxDepth)
+		return nil
+	}
+
+	m := newOrderedMap()
+	for _, f := range e.collectFields(obj, sels, nil, make(map[string]bool)) {
+		fieldPath := append(path, f.Key())
+		e.resolved++
+		if e.resolved > maxResolvedFields {
+			if e.resolved == maxResolvedFields+1 {
+				e.addError(fieldPath, "more than %d fields are resolved", maxResolvedFields)
+			}
+			return m
+		}
+		if f.Name == "__typename" {
+			m.set(f.Key(), obj.TypeName())
+			continue
+		}
+
+		val, err := obj.Resolve(f.Name, e.arguments(f.Args))
+		if err != nil {
+			if err == ErrNoField {
+				e.addError(fieldPath, "cannot query field %q on type %q", f.Name, obj.TypeName())
+			} else {
+				e.addError(fieldPath, "%v", err)
+			}
+			m.set(f.Key(), nil)
+			continue
+		}
+		m.set(f.Key(), e.completeValue(val, f, fieldPath, depth+1))
+	}
+	return m
+}
+
+// completeValue selects fields of objects in the value.
+func (e *executor) completeValue(val interface{}, f *Field, path []interface{}, depth int) interface{} {
+	if val == nil {
+		return nil
+	}
+	if obj, ok := val.(Object); ok {
+		if rv := reflect.ValueOf(obj); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil
+		} else if len(f.Selections) == 0 {
+			e.addError(path, "field %q of type %q must have a selection of subfields", f.Name, obj.TypeName())
+			return nil
+		}
+		return e.selectObject(obj, f.Selections, path, depth)
+	}
+
+	rv := reflect.ValueOf(val)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Implements(reflect.TypeOf((*Object)(nil)).Elem()) {
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = e.completeValue(rv.Index(i).Interface(), f, append(path, i), depth)
+		}
+		return list
+	}
+
+	if len(f.Selections) > 0 {
+		e.addError(path, "field %q is a scalar and must not have a selection", f.Name)
+		return nil
+	}
+	return val
+}
+
+// String returns the string argument, or empty if it is not a string.
+func String(args map[string]interface{}, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+// Int returns the integer argument, or given default value if it is not a number.
+func Int(args map[string]interface{}, name string, def int) int {
+	switch v := args[name].(type) {
+	case int64:
+		return int(v)
+	case float64: // Variables decoded from JSON.
+		return int(v)
+	}
+	return def
+}
+
+// Bool returns the boolean argument, or false if it is not a boolean.
+func Bool(args map[string]interface{}, name string) bool {
+	b, _ := args[name].(bool)
+	return b
+}
@@ -0,0 +1,757 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Document is a parsed GraphQL document.
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation is an operation definition of a document.
+type Operation struct {
+	Type       string // query, mutation or subscription.
+	Name       string
+	Variables  []*VariableDef
+	Directives []*Directive
+	Selections []Selection
+}
+
+// VariableDef is a variable definition of an operation.
+type VariableDef struct {
+	Name       string
+	Type       string
+	Default    Value
+	NonNull    bool
+	HasDefault bool
+}
+
+// Fragment is a named fragment definition.
+type Fragment struct {
+	Name       string
+	TypeCond   string
+	Directives []*Directive
+	Selections []Selection
+}
+
+// Selection is one of *Field, *FragmentSpread and *InlineFragment.
+type Selection interface{}
+
+// Field is a field selection.
+type Field struct {
+	Alias      string
+	Name       string
+	Args       []*Argument
+	Directives []*Directive
+	Selections []Selection
+}
+
+// Key returns the response key of the field.
+func (f *Field) Key() string {
+	if len(f.Alias) > 0 {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// FragmentSpread is a spread of a named fragment.
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+}
+
+// InlineFragment is an inline fragment with optional type condition.
+type InlineFragment struct {
+	TypeCond   string
+	Directives []*Directive
+	Selections []Selection
+}
+
+// Argument is a named argument of a field or directive.
+type Argument struct {
+	Name  string
+	Value Value
+}
+
+// Directive is a directive of a selection, e.g. @skip(if: true).
+type Directive struct {
+	Name string
+	Args []*Argument
+}
+
+// Value is one of Variable, EnumValue, []Value, map[string]Value,
+// or a constant of int64, float64, string, bool and nil.
+type Value interface{}
+
+// Variable refers to a variable of the operation.
+type Variable string
+
+// EnumValue is an enum literal, which is resolved as a string.
+type EnumValue string
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+	pos  int
+}
+
+// SyntaxError is returned when the document is invalid.
+type SyntaxError struct {
+	Pos int // Byte offset in the document.
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %d: %s", e.Pos, e.Msg)
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// next returns the next token, ignoring white spaces, commas and comments.
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			l.pos++
+			continue
+		}
+		break
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("!$():=@[]{}|&", c) >= 0:
+		l.pos++
+		return token{tokPunct, string(c), start}, nil
+	case c == '.':
+		if !strings.HasPrefix(l.src[l.pos:], "...") {
+			return token{}, &SyntaxError{start, "unexpected \".\""}
+		}
+		l.pos += 3
+		return token{tokPunct, "...", start}, nil
+	case isNameStart(c):
+		for l.pos < len(l.src) && (isNameStart(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{tokName, l.src[start:l.pos], start}, nil
+	case c == '-' || isDigit(c):
+		return l.number()
+	case c == '"':
+		return l.string()
+	}
+	return token{}, &SyntaxError{start, fmt.Sprintf("unexpected character %q", c)}
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	kind := tokInt
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() bool {
+		n := l.pos
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+		return l.pos > n
+	}
+	if !digits() {
+		return token{}, &SyntaxError{start, "invalid number"}
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokFloat
+		l.pos++
+		if !digits() {
+			return token{}, &SyntaxError{start, "invalid number"}
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if !digits() {
+			return token{}, &SyntaxError{start, "invalid number"}
+		}
+	}
+	return token{kind, l.src[start:l.pos], start}, nil
+}
+
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		end := strings.Index(l.src[l.pos+3:], `"""`)
+		if end < 0 {
+			return token{}, &SyntaxError{start, "unterminated string"}
+		}
+		l.pos += end + 6
+		return token{tokString, blockString(l.src[start+3 : l.pos-3]), start}, nil
+	}
+
+	l.pos++
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos += 2
+			continue
+		case '\n', '\r':
+			return token{}, &SyntaxError{start, "unterminated string"}
+		case '"':
+			l.pos++
+			// Escape sequences of GraphQL strings are the same as JSON.
+			var s string
+			if err := json.Unmarshal([]byte(l.src[start:l.pos]), &s); err != nil {
+				return token{}, &SyntaxError{start, "invalid string"}
+			}
+			return token{tokString, s, start}, nil
+		}
+		l.pos++
+	}
+	return token{}, &SyntaxError{start, "unterminated string"}
+}
+
+// blockString removes common indentation and leading and trailing blank lines of a block string.
+func blockString(raw string) string {
+	lines := strings.Split(strings.Replace(raw, `\"""`, `"""`, -1), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if len(trimmed) > 0 && (indent < 0 || len(line)-len(trimmed) < indent) {
+			indent = len(line) - len(trimmed)
+		}
+	}
+	for i := 1; i < len(lines) && indent > 0; i++ {
+		if len(lines[i]) >= indent {
+			lines[i] = lines[i][indent:]
+		} else {
+			lines[i] = ""
+		}
+	}
+	for len(lines) > 0 && len(strings.TrimSpace(lines[0])) == 0 {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && len(strings.TrimSpace(lines[len(lines)-1])) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Limits of documents, which protect servers from queries that are expensive to
+// parse and execute.
+const (
+	MaxDepth   = 15  // Nesting of selection sets, lists and input objects.
+	MaxFields  = 500 // Fields in the document, including aliased ones.
+	MaxAliases = 50  // Aliased fields in the document.
+)
+
+type parser struct {
+	lex *lexer
+	tok token
+
+	depth           int // Current nesting.
+	fields, aliases int
+}
+
+// enter increases the nesting, and returns error if it is too deep.
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > MaxDepth {
+		return &SyntaxError{p.tok.pos, fmt.Sprintf("nesting deeper than %d", MaxDepth)}
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+// Parse parses the GraphQL document.
+func Parse(src string) (*Document, error) {
+	p := &parser{lex: &lexer{src: strings.TrimPrefix(src, "\uFEFF")}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &Document{Fragments: make(map[string]*Fragment)}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.peek("{"):
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Type: "query", Selections: sels})
+		case p.tok.kind == tokName && p.tok.val == "fragment":
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if doc.Fragments[f.Name] != nil {
+				return nil, fmt.Errorf("duplicate fragment %q", f.Name)
+			}
+			doc.Fragments[f.Name] = f
+		case p.tok.kind == tokName:
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.Operations) == 0 {
+		return nil, &SyntaxError{0, "no operation"}
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() (err error) {
+	p.tok, err = p.lex.next()
+	return err
+}
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokPunct && p.tok.val == punct
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokEOF {
+		return &SyntaxError{p.tok.pos, "unexpected end of document"}
+	}
+	return &SyntaxError{p.tok.pos, fmt.Sprintf("unexpected %q", p.tok.val)}
+}
+
+// skip advances if the current token is the punctuator, and returns whether it is.
+func (p *parser) skip(punct string) (bool, error) {
+	if !p.peek(punct) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.peek(punct) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.unexpected()
+	}
+	name := p.tok.val
+	return name, p.advance()
+}
+
+func (p *parser) keyword(kw string) error {
+	if p.tok.kind != tokName || p.tok.val != kw {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+func (p *parser) operation() (*Operation, error) {
+	op := &Operation{Type: p.tok.val}
+	switch op.Type {
+	case "query", "mutation", "subscription":
+	default:
+		return nil, p.unexpected()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if p.tok.kind == tokName {
+		if op.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if ok, err := p.skip("("); err != nil {
+		return nil, err
+	} else if ok {
+		for !p.peek(")") {
+			v, err := p.variableDef()
+			if err != nil {
+				return nil, err
+			}
+			op.Variables = append(op.Variables, v)
+		}
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if op.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if op.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *parser) variableDef() (*VariableDef, error) {
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(":"); err != nil {
+		return nil, err
+	}
+	typ, err := p.typeRef()
+	if err != nil {
+		return nil, err
+	}
+
+	v := &VariableDef{
+		Name:    name,
+		Type:    typ,
+		NonNull: strings.HasSuffix(typ, "!"),
+	}
+	if ok, err := p.skip("="); err != nil {
+		return nil, err
+	} else if ok {
+		if v.Default, err = p.value(true); err != nil {
+			return nil, err
+		}
+		v.HasDefault = true
+	}
+	return v, nil
+}
+
+// typeRef returns the type reference as it is written, e.g. "[String!]!".
+func (p *parser) typeRef() (string, error) {
+	var typ string
+	if ok, err := p.skip("["); err != nil {
+		return "", err
+	} else if ok {
+		if err = p.enter(); err != nil {
+			return "", err
+		}
+		defer p.leave()
+		elem, err := p.typeRef()
+		if err != nil {
+			return "", err
+		}
+		if err = p.expect("]"); err != nil {
+			return "", err
+		}
+		typ = "[" + elem + "]"
+	} else {
+		if typ, err = p.name(); err != nil {
+			return "", err
+		}
+	}
+
+	if ok, err := p.skip("!"); err != nil {
+		return "", err
+	} else if ok {
+		typ += "!"
+	}
+	return typ, nil
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	if err := p.keyword("fragment"); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokName && p.tok.val == "on" {
+		return nil, p.unexpected()
+	}
+
+	f := new(Fragment)
+	var err error
+	if f.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err = p.keyword("on"); err != nil {
+		return nil, err
+	}
+	if f.TypeCond, err = p.name(); err != nil {
+		return nil, err
+	}
+	if f.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if f.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	} else if err = p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	var sels []Selection
+	for !p.peek("}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		return nil, &SyntaxError{p.tok.pos, "empty selection set"}
+	}
+	return sels, p.advance()
+}
+
+func (p *parser) selection() (Selection, error) {
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		return p.fragmentSelection()
+	}
+
+	p.fields++
+	if p.fields > MaxFields {
+		return nil, &SyntaxError{p.tok.pos, fmt.Sprintf("more than %d fields", MaxFields)}
+	}
+
+	f := new(Field)
+	var err error
+	if f.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		p.aliases++
+		if p.aliases > MaxAliases {
+			return nil, &SyntaxError{p.tok.pos, fmt.Sprintf("more than %d aliases", MaxAliases)}
+		}
+		f.Alias = f.Name
+		if f.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if f.Args, err = p.arguments(); err != nil {
+		return nil, err
+	}
+	if f.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if f.Selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// fragmentSelection parses a fragment spread or inline fragment after "...".
+func (p *parser) fragmentSelection() (Selection, error) {
+	if p.tok.kind == tokName && p.tok.val != "on" {
+		s := new(FragmentSpread)
+		var err error
+		if s.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if s.Directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
+	f := new(InlineFragment)
+	var err error
+	if p.tok.kind == tokName {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		if f.TypeCond, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if f.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if f.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *parser) arguments() ([]*Argument, error) {
+	if ok, err := p.skip("("); err != nil || !ok {
+		return nil, err
+	}
+
+	var args []*Argument
+	for !p.peek(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		val, err := p.value(false)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, &Argument{Name: name, Value: val})
+	}
+	return args, p.advance()
+}
+
+func (p *parser) directives() ([]*Directive, error) {
+	var dirs []*Directive
+	for p.peek("@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, &Directive{Name: name, Args: args})
+	}
+	return dirs, nil
+}
+
+// value parses a value, variables are not allowed in constant values.
+func (p *parser) value(constant bool) (Value, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokInt:
+		var n int64
+		if _, err := fmt.Sscan(tok.val, &n); err != nil {
+			return nil, &SyntaxError{tok.pos, "invalid integer"}
+		}
+		return n, p.advance()
+	case tokFloat:
+		var f float64
+		if _, err := fmt.Sscan(tok.val, &f); err != nil {
+			return nil, &SyntaxError{tok.pos, "invalid float"}
+		}
+		return f, p.advance()
+	case tokString:
+		return tok.val, p.advance()
+	case tokName:
+		var v Value
+		switch tok.val {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		default:
+			v = EnumValue(tok.val)
+		}
+		return v, p.advance()
+	}
+
+	switch {
+	case p.peek("$") && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return Variable(name), err
+	case p.peek("["):
+		if err := p.advance(); err != nil {
+			return nil, err
+		} else if err = p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		list := []Value{}
+		for !p.peek("]") {
+			v, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, p.advance()
+	case p.peek("{"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		} else if err = p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		obj := make(map[string]Value)
+		for !p.peek("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		return obj, p.advance()
+	}
+	return nil, p.unexpected()
+}
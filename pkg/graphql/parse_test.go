@@ -0,0 +1,158 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package graphql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want *Document
+	}{
+		{
+			name: "shorthand query",
+			src:  `{ package(path: "a/b") { name } }`,
+			want: &Document{
+				Operations: []*Operation{{
+					Type: "query",
+					Selections: []Selection{&Field{
+						Name:       "package",
+						Args:       []*Argument{{Name: "path", Value: "a/b"}},
+						Selections: []Selection{&Field{Name: "name"}},
+					}},
+				}},
+				Fragments: map[string]*Fragment{},
+			},
+		},
+		{
+			name: "variables, aliases and directives",
+			src: `# Comment
+query Q($path: String!, $n: [Int] = [1, 2]) {
+	p: package(path: $path, opts: {limit: 1.5, kind: FUNC, ok: true, no: null}) @include(if: true) {
+		name
+	}
+}`,
+			want: &Document{
+				Operations: []*Operation{{
+					Type: "query",
+					Name: "Q",
+					Variables: []*VariableDef{
+						{Name: "path", Type: "String!", NonNull: true},
+						{Name: "n", Type: "[Int]", Default: []Value{int64(1), int64(2)}, HasDefault: true},
+					},
+					Selections: []Selection{&Field{
+						Alias: "p",
+						Name:  "package",
+						Args: []*Argument{
+							{Name: "path", Value: Variable("path")},
+							{Name: "opts", Value: map[string]Value{
+								"limit": 1.5,
+								"kind":  EnumValue("FUNC"),
+								"ok":    true,
+								"no":    nil,
+							}},
+						},
+						Directives: []*Directive{{Name: "include", Args: []*Argument{{Name: "if", Value: true}}}},
+						Selections: []Selection{&Field{Name: "name"}},
+					}},
+				}},
+				Fragments: map[string]*Fragment{},
+			},
+		},
+		{
+			name: "fragments",
+			src: `{ ...F ... on Package { name } ... @skip(if: false) { path } }
+fragment F on Package { synopsis }`,
+			want: &Document{
+				Operations: []*Operation{{
+					Type: "query",
+					Selections: []Selection{
+						&FragmentSpread{Name: "F"},
+						&InlineFragment{TypeCond: "Package", Selections: []Selection{&Field{Name: "name"}}},
+						&InlineFragment{
+							Directives: []*Directive{{Name: "skip", Args: []*Argument{{Name: "if", Value: false}}}},
+							Selections: []Selection{&Field{Name: "path"}},
+						},
+					},
+				}},
+				Fragments: map[string]*Fragment{
+					"F": {Name: "F", TypeCond: "Package", Selections: []Selection{&Field{Name: "synopsis"}}},
+				},
+			},
+		},
+		{
+			name: "strings",
+			src:  "{ f(a: \"\\u0041\\n\", b: \"\"\"\n    block\n      string\n  \"\"\") }",
+			want: &Document{
+				Operations: []*Operation{{
+					Type: "query",
+					Selections: []Selection{&Field{
+						Name: "f",
+						Args: []*Argument{
+							{Name: "a", Value: "A\n"},
+							{Name: "b", Value: "block\n  string"},
+						},
+					}},
+				}},
+				Fragments: map[string]*Fragment{},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Parse(test.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"empty", ""},
+		{"empty selection set", "{}"},
+		{"unterminated selection set", "{ name"},
+		{"unknown operation type", "update { name }"},
+		{"variable in constant", "query($a: Int = $b) { name }"},
+		{"unterminated string", `{ f(a: "abc) }`},
+		{"invalid number", "{ f(a: 1.) }"},
+		{"unexpected character", "{ name; }"},
+		{"fragment named on", "fragment on on T { name }"},
+		{"duplicate fragment", "{ ...F } fragment F on T { a } fragment F on T { b }"},
+		{"too deep", strings.Repeat("{ f ", MaxDepth+1) + strings.Repeat("}", MaxDepth+1)},
+		{"too deep value", "{ f(a: " + strings.Repeat("[", MaxDepth+1) + strings.Repeat("]", MaxDepth+1) + ") }"},
+		{"too many fields", "{" + strings.Repeat(" f", MaxFields+1) + " }"},
+		{"too many aliases", "{" + strings.Repeat(" a: f", MaxAliases+1) + " }"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Parse(test.src); err == nil {
+				t.Fatal("got no error")
+			}
+		})
+	}
+}
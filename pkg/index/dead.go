@@ -0,0 +1,57 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package index
+
+import (
+	"sort"
+	"strings"
+)
+
+// DeadSymbols returns exported identifiers of packages whose import paths
+// have given prefix that are not referenced by any other indexed package,
+// which are candidates for deprecation. Empty prefix checks all packages.
+//
+// Methods are not reported, because references to them cannot be resolved
+// without type information of dependencies.
+func (idx *Index) DeadSymbols(prefix string) []*Symbol {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	var syms []*Symbol
+	for importPath, e := range idx.entries {
+		if e.IsCmd || !strings.HasPrefix(importPath, prefix) {
+			continue
+		}
+
+		for _, sym := range e.Symbols {
+			if sym.Kind == SK_Method {
+				continue
+			}
+
+			// Only other packages are recorded as usages.
+			if len(idx.usages[sym.QualifiedName()]) == 0 {
+				syms = append(syms, sym)
+			}
+		}
+	}
+
+	sort.Slice(syms, func(i, j int) bool {
+		if syms[i].ImportPath != syms[j].ImportPath {
+			return syms[i].ImportPath < syms[j].ImportPath
+		}
+		return syms[i].Name < syms[j].Name
+	})
+	return syms
+}
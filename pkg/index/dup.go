@@ -0,0 +1,202 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package index
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+const (
+	// Similarity of files that packages must reach to be considered duplicates.
+	duplicateThreshold = 0.8
+	// Minimum number of exported identifiers for fingerprint to be distinctive.
+	minFingerprintSymbols = 5
+)
+
+// Duplicate represents a package that is a fork or copy of another.
+type Duplicate struct {
+	ImportPath string
+	Similarity float64 // Jaccard similarity of file contents.
+	SameAPI    bool    // Whether they declare same exported identifiers.
+}
+
+func hash(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileHashes returns sorted hashes of file contents,
+// line endings are normalized so that checkouts on Windows still match.
+func fileHashes(files [][]byte) []string {
+	hashes := make([]string, 0, len(files))
+	for _, data := range files {
+		hashes = append(hashes, hash(bytes.Replace(data, []byte("\r\n"), []byte("\n"), -1)))
+	}
+	sort.Strings(hashes)
+	return hashes
+}
+
+// fingerprint returns the hash of exported identifiers of the package,
+// it returns empty string if the package does not have enough identifiers.
+func fingerprint(syms []*Symbol) string {
+	if len(syms) < minFingerprintSymbols {
+		return ""
+	}
+
+	names := make([]string, len(syms))
+	for i, sym := range syms {
+		names[i] = string(sym.Kind) + " " + sym.Name
+	}
+	sort.Strings(names)
+	return hash([]byte(strings.Join(names, "\n")))
+}
+
+func jaccard(a, b []string) float64 {
+	set := make(map[string]bool, len(a))
+	for _, s := range a {
+		set[s] = true
+	}
+	common := 0
+	for _, s := range b {
+		if set[s] {
+			common++
+		}
+	}
+	union := len(a) + len(b) - common
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+func (idx *Index) duplicates(e *Entry) []*Duplicate {
+	candidates := make(map[string]bool)
+	for _, h := range e.FileHashes {
+		for p := range idx.files[h] {
+			candidates[p] = true
+		}
+	}
+	if len(e.Fingerprint) > 0 {
+		for p := range idx.fingerprints[e.Fingerprint] {
+			candidates[p] = true
+		}
+	}
+	delete(candidates, e.ImportPath)
+
+	var dups []*Duplicate
+	for p := range candidates {
+		other := idx.entries[p]
+		dup := &Duplicate{
+			ImportPath: p,
+			Similarity: jaccard(e.FileHashes, other.FileHashes),
+			SameAPI:    len(e.Fingerprint) > 0 && e.Fingerprint == other.Fingerprint,
+		}
+		if dup.Similarity >= duplicateThreshold || dup.SameAPI {
+			dups = append(dups, dup)
+		}
+	}
+	sort.Slice(dups, func(i, j int) bool {
+		if dups[i].Similarity != dups[j].Similarity {
+			return dups[i].Similarity > dups[j].Similarity
+		}
+		return dups[i].ImportPath < dups[j].ImportPath
+	})
+	return dups
+}
+
+// Duplicates returns indexed packages that are forks or copies of the package
+// of given import path, most similar first. Packages are duplicates if most of
+// their files are identical or they declare the same exported identifiers.
+func (idx *Index) Duplicates(importPath string) []*Duplicate {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	e, ok := idx.entries[importPath]
+	if !ok {
+		return nil
+	}
+	return idx.duplicates(e)
+}
+
+// isVendored returns true if the import path is a copy inside another project.
+func isVendored(importPath string) bool {
+	return strings.Contains(importPath+"/", "/vendor/") ||
+		strings.Contains(importPath+"/", "/third_party/")
+}
+
+// preferred returns true if a is more likely to be the upstream than b.
+func preferred(a, b *Entry) bool {
+	switch {
+	case isVendored(a.ImportPath) != isVendored(b.ImportPath):
+		return !isVendored(a.ImportPath)
+	case (len(a.ForkOf) == 0) != (len(b.ForkOf) == 0):
+		return len(a.ForkOf) == 0
+	case a.Stars != b.Stars:
+		return a.Stars > b.Stars
+	case len(a.ImportPath) != len(b.ImportPath):
+		return len(a.ImportPath) < len(b.ImportPath)
+	}
+	return a.ImportPath < b.ImportPath
+}
+
+// Canonical returns import path of the upstream package if the package of
+// given import path is a fork or copy of it, or the import path itself.
+// Forks reported by the code hosting service follow their parents, others
+// prefer packages that are not vendored or forks, then the most starred.
+func (idx *Index) Canonical(importPath string) string {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	e, ok := idx.entries[importPath]
+	if !ok {
+		return importPath
+	}
+
+	best := e
+	for _, dup := range idx.duplicates(e) {
+		if other := idx.entries[dup.ImportPath]; preferred(other, best) {
+			best = other
+		}
+	}
+
+	// Follow chain of forks with a limit in case of bad data.
+	for i := 0; i < 5 && len(best.ForkOf) > 0; i++ {
+		parent, ok := idx.entries[best.ForkOf]
+		if !ok {
+			return best.ForkOf
+		}
+		best = parent
+	}
+	return best.ImportPath
+}
+
+// Collapse returns canonical import paths of given packages in order,
+// with forks and copies of the same package collapsed into one.
+func (idx *Index) Collapse(importPaths []string) []string {
+	seen := make(map[string]bool, len(importPaths))
+	collapsed := make([]string, 0, len(importPaths))
+	for _, p := range importPaths {
+		canonical := idx.Canonical(p)
+		if !seen[canonical] {
+			seen[canonical] = true
+			collapsed = append(collapsed, canonical)
+		}
+	}
+	return collapsed
+}
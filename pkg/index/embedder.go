@@ -0,0 +1,90 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package index
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// HTTPEmbedder computes vectors by an embeddings endpoint that is compatible with
+// the OpenAI API, which is served by most providers and local model servers.
+type HTTPEmbedder struct {
+	URL    string // e.g. https://api.openai.com/v1/embeddings
+	APIKey string // Sent in "Authorization: Bearer <key>" header if not empty.
+	Model  string
+	Client *http.Client // Default client with timeout is used if nil.
+}
+
+var defaultEmbedClient = &http.Client{Timeout: 60 * time.Second}
+
+func (e *HTTPEmbedder) Embed(texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model": e.Model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", e.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(e.APIKey) > 0 {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+
+	client := e.Client
+	if client == nil {
+		client = defaultEmbedClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int
+			Embedding []float32
+		}
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode: %v", err)
+	}
+	sort.Slice(result.Data, func(i, j int) bool {
+		return result.Data[i].Index < result.Data[j].Index
+	})
+
+	vecs := make([][]float32, len(result.Data))
+	for i := range result.Data {
+		vecs[i] = result.Data[i].Embedding
+	}
+	return vecs, nil
+}
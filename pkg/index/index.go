@@ -0,0 +1,491 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package index maintains a cross-package index of walked packages,
+// which is the base of corpus-wide analyses.
+package index
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Unknwon/com"
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+// SymbolKind is the kind of a declared identifier.
+type SymbolKind string
+
+const (
+	SK_Const  SymbolKind = "const"
+	SK_Var    SymbolKind = "var"
+	SK_Func   SymbolKind = "func"
+	SK_Type   SymbolKind = "type"
+	SK_Method SymbolKind = "method"
+)
+
+// Symbol represents an exported identifier of a package.
+type Symbol struct {
+	ImportPath string
+	Name       string // e.g. "Client" or "Client.Do" for methods.
+	Kind       SymbolKind
+	Recv       string // Receiver type name of methods.
+	Doc        string // Doc comment in plain text.
+	// Synonyms are other names the identifier is searched by, e.g. result type
+	// of constructors, receiver of String and Error methods, and aliased types.
+	Synonyms []string
+}
+
+// QualifiedName returns the name qualified by import path, e.g. "net/http.Client.Do".
+func (s *Symbol) QualifiedName() string {
+	return s.ImportPath + "." + s.Name
+}
+
+// Entry is the indexed information of a package.
+type Entry struct {
+	ImportPath string
+	Synopsis   string
+	Doc        string // Package documentation in plain text.
+	Readme     string // README in plain text.
+	IsCmd      bool
+	Symbols    []*Symbol
+	Imports    []string
+	Refs       []string // Referenced identifiers of other packages, e.g. "net/http.Get".
+
+	ForkOf      string
+	Stars       int64
+	FileHashes  []string // Hashes of Go source files.
+	Fingerprint string   // Hash of exported identifiers.
+
+	// Signals of quality ranking.
+	DocCoverage float64 // Fraction of exported identifiers that are documented.
+	HasTests    bool
+	NumExamples int
+	RefNum      int64     // Number of packages that import this one.
+	Commit      string    // Revision of the source from provenance.
+	LastChanged time.Time // When a new revision was first indexed.
+	Embedded    string    // Revision whose documentation has been embedded successfully.
+}
+
+// Index holds entries of walked packages and the usages between them.
+// It is safe for concurrent use.
+type Index struct {
+	lock    sync.RWMutex
+	storage Storage // Where to save, nil means not persisted.
+	dirty   bool
+	entries map[string]*Entry
+	// Import paths of packages that reference the qualified identifier.
+	usages map[string]map[string]bool
+	// Import paths of packages by file hash and fingerprint.
+	files        map[string]map[string]bool
+	fingerprints map[string]map[string]bool
+	// Postings of full-text search by term, and number of terms of texts.
+	terms    map[string]map[textDoc]int
+	textLens map[textDoc]int
+	// Semantic search, nil embedder means disabled.
+	embedder   Embedder
+	vectors    VectorStore
+	embedQueue chan *Entry // Entries to embed, nil until embedder is set.
+}
+
+// New returns a new empty index in memory.
+func New() *Index {
+	return &Index{
+		entries:      make(map[string]*Entry),
+		usages:       make(map[string]map[string]bool),
+		files:        make(map[string]map[string]bool),
+		fingerprints: make(map[string]map[string]bool),
+		terms:        make(map[string]map[textDoc]int),
+		textLens:     make(map[textDoc]int),
+	}
+}
+
+// Storage persists entries of an index.
+type Storage interface {
+	// Load returns nil if nothing has been saved.
+	Load() ([]*Entry, error)
+	Save(entries []*Entry) error
+}
+
+// fileStorage saves entries to a gob file with given path.
+type fileStorage string
+
+func (s fileStorage) Load() ([]*Entry, error) {
+	filename := string(s)
+	if !com.IsFile(filename) {
+		return nil, nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open: %v", err)
+	}
+	defer f.Close()
+
+	var entries []*Entry
+	if err = gob.NewDecoder(f).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("decode: %v", err)
+	}
+	return entries, nil
+}
+
+func (s fileStorage) Save(entries []*Entry) error {
+	filename := string(s)
+	os.MkdirAll(path.Dir(filename), os.ModePerm)
+	tmpPath := filename + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("create: %v", err)
+	}
+	if err = gob.NewEncoder(f).Encode(entries); err != nil {
+		f.Close()
+		return fmt.Errorf("encode: %v", err)
+	}
+	f.Close()
+
+	if err = os.Rename(tmpPath, filename); err != nil {
+		return fmt.Errorf("rename: %v", err)
+	}
+	return nil
+}
+
+// Open returns the index saved at given path, or an empty index
+// if the file does not exist.
+func Open(filename string) (*Index, error) {
+	return OpenStorage(fileStorage(filename))
+}
+
+// OpenStorage returns the index saved in given storage.
+func OpenStorage(s Storage) (*Index, error) {
+	entries, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	idx := New()
+	idx.storage = s
+	for _, e := range entries {
+		idx.add(e)
+	}
+	idx.dirty = false
+	return idx, nil
+}
+
+// Save writes entries to the storage where the index is opened
+// if it has been changed since last save.
+func (idx *Index) Save() error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if idx.storage == nil || !idx.dirty {
+		return nil
+	}
+
+	entries := make([]*Entry, 0, len(idx.entries))
+	for _, e := range idx.entries {
+		entries = append(entries, e)
+	}
+	if err := idx.storage.Save(entries); err != nil {
+		return err
+	}
+	idx.dirty = false
+	return nil
+}
+
+// WriteEntries writes all entries to w in gob.
+func (idx *Index) WriteEntries(w io.Writer) error {
+	idx.lock.RLock()
+	entries := make([]*Entry, 0, len(idx.entries))
+	for _, e := range idx.entries {
+		entries = append(entries, e)
+	}
+	idx.lock.RUnlock()
+
+	if err := gob.NewEncoder(w).Encode(entries); err != nil {
+		return fmt.Errorf("encode: %v", err)
+	}
+	return nil
+}
+
+// ReadEntries adds entries written by WriteEntries, replacing entries of
+// same import paths.
+func (idx *Index) ReadEntries(r io.Reader) error {
+	var entries []*Entry
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return fmt.Errorf("decode: %v", err)
+	}
+
+	idx.lock.Lock()
+	for _, e := range entries {
+		idx.add(e)
+	}
+	idx.lock.Unlock()
+	return nil
+}
+
+// NewEntry returns the indexed information of the package. It must be
+// called before the package is rendered because rendering overwrites
+// declarations with HTML.
+func NewEntry(pdoc *doc.Package) *Entry {
+	e := &Entry{
+		ImportPath: pdoc.ImportPath,
+		Synopsis:   pdoc.Synopsis,
+		IsCmd:      pdoc.IsCmd,
+		Stars:      pdoc.Stars,
+	}
+	if pdoc.PkgDecl == nil {
+		return e
+	}
+	e.Imports = pdoc.Imports
+	e.Refs = pdoc.Refs
+	e.ForkOf = pdoc.ForkOf
+	e.Doc = plainText(pdoc.Doc)
+	if readme, ok := pdoc.Readme["en"]; ok {
+		e.Readme = plainText(string(readme))
+	} else {
+		for _, readme := range pdoc.Readme {
+			e.Readme = plainText(string(readme))
+			break
+		}
+	}
+
+	files := make([][]byte, len(pdoc.Files))
+	for i, f := range pdoc.Files {
+		files[i] = f.Data()
+	}
+	e.FileHashes = fileHashes(files)
+
+	e.HasTests = len(pdoc.TestFiles) > 0
+	e.NumExamples = len(pdoc.Examples)
+	e.RefNum = pdoc.RefNum
+	if pdoc.Provenance != nil {
+		e.Commit = pdoc.Provenance.Commit
+		e.LastChanged = pdoc.Provenance.WalkedAt
+	}
+
+	addValues := func(vals []*doc.Value, kind SymbolKind) {
+		for _, v := range vals {
+			for _, name := range v.Names() {
+				e.addSymbol(name, kind, "", v.Doc)
+			}
+		}
+	}
+	addFuncs := func(funcs []*doc.Func, synonyms ...string) {
+		for _, f := range funcs {
+			e.addSymbol(f.Name, SK_Func, "", f.Doc, synonyms...)
+		}
+	}
+
+	addValues(pdoc.Consts, SK_Const)
+	addValues(pdoc.Vars, SK_Var)
+	addFuncs(pdoc.Funcs)
+	for _, t := range pdoc.Types {
+		e.addSymbol(t.Name, SK_Type, "", t.Doc, aliasOf(t)...)
+		addValues(t.Consts, SK_Const)
+		addValues(t.Vars, SK_Var)
+		// Functions grouped under the type are constructors that return it.
+		addFuncs(t.Funcs, t.Name)
+		for _, m := range t.Methods {
+			var synonyms []string
+			if m.Name == "String" || m.Name == "Error" {
+				synonyms = []string{t.Name}
+			}
+			e.addSymbol(t.Name+"."+m.Name, SK_Method, t.Name, m.Doc, synonyms...)
+		}
+	}
+	e.Fingerprint = fingerprint(e.Symbols)
+
+	documented := 0
+	for _, sym := range e.Symbols {
+		if len(strings.TrimSpace(sym.Doc)) > 0 {
+			documented++
+		}
+	}
+	if len(e.Symbols) > 0 {
+		e.DocCoverage = float64(documented) / float64(len(e.Symbols))
+	}
+	return e
+}
+
+func (e *Entry) addSymbol(name string, kind SymbolKind, recv, doc string, synonyms ...string) {
+	if !isExported(name) {
+		return
+	}
+	e.Symbols = append(e.Symbols, &Symbol{
+		ImportPath: e.ImportPath,
+		Name:       name,
+		Kind:       kind,
+		Recv:       recv,
+		Doc:        doc,
+		Synonyms:   synonyms,
+	})
+}
+
+// aliasOf returns the aliased type and its name without package qualifier
+// if the type is declared as an alias, e.g. "http.Client" and "Client" for
+// "type Client = http.Client".
+func aliasOf(t *doc.Type) []string {
+	if !t.IsAlias || len(t.AliasOf) == 0 {
+		return nil
+	}
+	synonyms := []string{t.AliasOf}
+	if i := strings.LastIndex(t.AliasOf, "."); i > -1 {
+		synonyms = append(synonyms, t.AliasOf[i+1:])
+	}
+	return synonyms
+}
+
+func isExported(name string) bool {
+	if i := strings.LastIndex(name, "."); i > -1 {
+		name = name[i+1:]
+	}
+	return len(name) > 0 && name[0] >= 'A' && name[0] <= 'Z'
+}
+
+func addToSet(sets map[string]map[string]bool, key, importPath string) {
+	if sets[key] == nil {
+		sets[key] = make(map[string]bool)
+	}
+	sets[key][importPath] = true
+}
+
+func removeFromSet(sets map[string]map[string]bool, key, importPath string) {
+	delete(sets[key], importPath)
+	if len(sets[key]) == 0 {
+		delete(sets, key)
+	}
+}
+
+func (idx *Index) add(e *Entry) {
+	// Keep the time of change and embedding if revision is same as before.
+	if old, ok := idx.entries[e.ImportPath]; ok && old.Commit == e.Commit {
+		if !old.LastChanged.IsZero() {
+			e.LastChanged = old.LastChanged
+		}
+		e.Embedded = old.Embedded
+	}
+	idx.remove(e.ImportPath)
+	idx.entries[e.ImportPath] = e
+	for _, ref := range e.Refs {
+		addToSet(idx.usages, ref, e.ImportPath)
+	}
+	for _, h := range e.FileHashes {
+		addToSet(idx.files, h, e.ImportPath)
+	}
+	if len(e.Fingerprint) > 0 {
+		addToSet(idx.fingerprints, e.Fingerprint, e.ImportPath)
+	}
+	idx.addTexts(e)
+	idx.dirty = true
+}
+
+func (idx *Index) remove(importPath string) {
+	e, ok := idx.entries[importPath]
+	if !ok {
+		return
+	}
+	for _, ref := range e.Refs {
+		removeFromSet(idx.usages, ref, importPath)
+	}
+	for _, h := range e.FileHashes {
+		removeFromSet(idx.files, h, importPath)
+	}
+	removeFromSet(idx.fingerprints, e.Fingerprint, importPath)
+	idx.removeTexts(e)
+	delete(idx.entries, importPath)
+	idx.dirty = true
+}
+
+// Add indexes the package, replacing the previous entry of same import path.
+func (idx *Index) Add(pdoc *doc.Package) {
+	e := NewEntry(pdoc)
+	idx.lock.Lock()
+	idx.add(e)
+	queue, embedded := idx.embedQueue, e.Embedded
+	idx.lock.Unlock()
+
+	// Embedding is slow, skip it when the revision has been embedded. Documentation
+	// of private packages must not be sent to the embedding service.
+	if queue == nil || (len(e.Commit) > 0 && embedded == e.Commit) || doc.IsPrivate(e.ImportPath) {
+		return
+	}
+	select {
+	case queue <- e:
+	default:
+		log.Warn("Embed queue is full, skip embedding %q", e.ImportPath)
+	}
+}
+
+// Remove deletes the package of given import path from the index.
+func (idx *Index) Remove(importPath string) {
+	idx.lock.Lock()
+	idx.remove(importPath)
+	store := idx.vectors
+	idx.lock.Unlock()
+
+	if store != nil {
+		if err := store.Delete(importPath); err != nil {
+			log.Warn("Failed to delete vectors of %q: %v", importPath, err)
+		}
+	}
+}
+
+// Entry returns the indexed information of given import path, or nil
+// if the package has not been indexed.
+func (idx *Index) Entry(importPath string) *Entry {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	return idx.entries[importPath]
+}
+
+// Importers returns import paths of packages that reference the identifier
+// declared in the package of given import path, in sorted order.
+func (idx *Index) Importers(importPath, name string) []string {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	paths := make([]string, 0, len(idx.usages[importPath+"."+name]))
+	for p := range idx.usages[importPath+"."+name] {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// HasSymbol returns whether the package of given import path is indexed,
+// and whether it declares the exported symbol, e.g. "Client" or "Client.Do".
+func (idx *Index) HasSymbol(importPath, name string) (indexed, declared bool) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	e := idx.entries[importPath]
+	if e == nil {
+		return false, false
+	}
+	for _, sym := range e.Symbols {
+		if sym.Name == name {
+			return true, true
+		}
+	}
+	return true, false
+}
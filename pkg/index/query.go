@@ -0,0 +1,234 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package index
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Query represents a parsed search query. Empty fields match everything.
+type Query struct {
+	Kind   SymbolKind // type:func also matches methods.
+	Recv   string     // Receiver type name, implies methods.
+	Name   string     // Name of the identifier, may contain wildcards like "New*".
+	Import string     // Import path that the package must import.
+	Pkg    string     // Import path prefix of the package.
+	Words  []string   // Words that must appear in the name or doc comment.
+}
+
+// splitQuery splits the query by spaces except those in double quotes.
+func splitQuery(s string) ([]string, error) {
+	var (
+		fields  []string
+		buf     strings.Builder
+		inQuote bool
+	)
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case unicode.IsSpace(r) && !inQuote:
+			if buf.Len() > 0 {
+				fields = append(fields, buf.String())
+				buf.Reset()
+			}
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unclosed quote")
+	}
+	if buf.Len() > 0 {
+		fields = append(fields, buf.String())
+	}
+	return fields, nil
+}
+
+// ParseQuery parses query in the form of "type:func recv:Client name:Do import:net/http".
+// Supported filters are type, recv, name, import and pkg, other words are matched
+// against names and doc comments. Values that contain spaces must be quoted.
+func ParseQuery(s string) (*Query, error) {
+	fields, err := splitQuery(s)
+	if err != nil {
+		return nil, err
+	}
+
+	q := new(Query)
+	for _, field := range fields {
+		i := strings.Index(field, ":")
+		if i == -1 {
+			q.Words = append(q.Words, field)
+			continue
+		}
+
+		key, val := strings.ToLower(field[:i]), field[i+1:]
+		if len(val) == 0 {
+			return nil, fmt.Errorf("empty value of filter %q", key)
+		}
+		switch key {
+		case "type", "kind":
+			q.Kind = SymbolKind(strings.ToLower(val))
+			switch q.Kind {
+			case SK_Const, SK_Var, SK_Func, SK_Type, SK_Method:
+			default:
+				return nil, fmt.Errorf("unknown type %q", val)
+			}
+		case "recv":
+			q.Recv = strings.TrimPrefix(val, "*")
+		case "name":
+			if _, err = path.Match(val, ""); err != nil {
+				return nil, fmt.Errorf("bad name pattern %q: %v", val, err)
+			}
+			q.Name = val
+		case "import":
+			q.Import = val
+		case "pkg":
+			q.Pkg = val
+		default:
+			return nil, fmt.Errorf("unknown filter %q", key)
+		}
+	}
+	return q, nil
+}
+
+// SymbolMatch is a result of query.
+type SymbolMatch struct {
+	*Symbol
+	Synopsis string // Synopsis of the package.
+	Score    float64
+}
+
+func hasImport(e *Entry, importPath string) bool {
+	for _, p := range e.Imports {
+		if p == importPath {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSymbol returns true if the symbol matches the query except words.
+func (q *Query) matchSymbol(sym *Symbol) bool {
+	switch {
+	case q.Kind == SK_Func && sym.Kind != SK_Func && sym.Kind != SK_Method,
+		q.Kind != SK_Func && len(q.Kind) > 0 && sym.Kind != q.Kind,
+		len(q.Recv) > 0 && !strings.EqualFold(sym.Recv, q.Recv):
+		return false
+	}
+
+	if len(q.Name) == 0 {
+		return true
+	}
+	for _, name := range append([]string{sym.Name}, sym.Synonyms...) {
+		name = name[strings.LastIndex(name, ".")+1:]
+		if ok, _ := path.Match(strings.ToLower(q.Name), strings.ToLower(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Search returns at most limit exported identifiers that match the query, exact
+// name matches first, then ordered by quality score of packages.
+func (idx *Index) Search(q *Query, limit int) []*SymbolMatch {
+	var words []string
+	for _, w := range q.Words {
+		words = append(words, tokenize(w)...)
+	}
+
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	now := time.Now()
+	var matches []*SymbolMatch
+	for importPath, e := range idx.entries {
+		if !strings.HasPrefix(importPath, q.Pkg) ||
+			(len(q.Import) > 0 && !hasImport(e, q.Import)) {
+			continue
+		}
+
+		var score float64
+		scored := false
+		for _, sym := range e.Symbols {
+			if !q.matchSymbol(sym) || !containsTerms(sym.Name+" "+strings.Join(sym.Synonyms, " ")+" "+sym.Doc, words) {
+				continue
+			}
+
+			if !scored {
+				score = idx.score(e, now)
+				scored = true
+			}
+			m := &SymbolMatch{
+				Symbol:   sym,
+				Synopsis: e.Synopsis,
+				Score:    score,
+			}
+			if len(q.Name) > 0 && strings.EqualFold(sym.Name[strings.LastIndex(sym.Name, ".")+1:], q.Name) {
+				m.Score++
+			}
+			matches = append(matches, m)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].QualifiedName() < matches[j].QualifiedName()
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// containsTerms returns true if the text contains all stemmed terms.
+func containsTerms(text string, terms []string) bool {
+	if len(terms) == 0 {
+		return true
+	}
+
+	set := make(map[string]bool)
+	for _, t := range tokenize(splitCamelCase(text)) {
+		set[t] = true
+	}
+	for _, t := range terms {
+		if !set[t] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitCamelCase inserts spaces between words of camel case identifiers,
+// e.g. "ReadFile" becomes "Read File".
+func splitCamelCase(s string) string {
+	var buf strings.Builder
+	var prev rune
+	for _, r := range s {
+		if unicode.IsUpper(r) && unicode.IsLower(prev) {
+			buf.WriteByte(' ')
+		}
+		buf.WriteRune(r)
+		prev = r
+	}
+	return buf.String()
+}
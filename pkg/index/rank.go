@@ -0,0 +1,97 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package index
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Weights of quality signals, which sum to 1.
+const (
+	weightDocs       = 0.3
+	weightTests      = 0.15
+	weightExamples   = 0.15
+	weightActivity   = 0.15
+	weightPopularity = 0.25
+)
+
+const (
+	// Number of examples to get full score.
+	enoughExamples = 5
+	// Number of importers to get full score, it grows logarithmically.
+	enoughImporters = 1000
+	// Period after which activity score is halved.
+	activityHalfLife = 365 * 24 * time.Hour
+)
+
+// importers returns number of packages that import the package.
+func (idx *Index) importers(e *Entry) int64 {
+	refs := make(map[string]bool)
+	for _, sym := range e.Symbols {
+		for p := range idx.usages[sym.QualifiedName()] {
+			refs[p] = true
+		}
+	}
+	if int64(len(refs)) > e.RefNum {
+		return int64(len(refs))
+	}
+	return e.RefNum
+}
+
+func (idx *Index) score(e *Entry, now time.Time) float64 {
+	score := weightDocs * e.DocCoverage
+	if e.HasTests {
+		score += weightTests
+	}
+	score += weightExamples * math.Min(float64(e.NumExamples)/enoughExamples, 1)
+	if !e.LastChanged.IsZero() {
+		age := now.Sub(e.LastChanged)
+		if age < 0 {
+			age = 0
+		}
+		score += weightActivity * math.Pow(0.5, float64(age)/float64(activityHalfLife))
+	}
+	score += weightPopularity * math.Min(math.Log1p(float64(idx.importers(e)))/math.Log1p(enoughImporters), 1)
+	return score
+}
+
+// Score returns composite quality score between 0 and 1 of the package
+// of given import path, based on documentation coverage, test presence,
+// number of examples, recent activity and import popularity.
+// It returns 0 if the package has not been indexed.
+func (idx *Index) Score(importPath string) float64 {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	e, ok := idx.entries[importPath]
+	if !ok {
+		return 0
+	}
+	return idx.score(e, time.Now())
+}
+
+// Rank sorts import paths by quality score in descending order,
+// the order of packages with same score is preserved.
+func (idx *Index) Rank(importPaths []string) {
+	scores := make(map[string]float64, len(importPaths))
+	for _, p := range importPaths {
+		scores[p] = idx.Score(p)
+	}
+	sort.SliceStable(importPaths, func(i, j int) bool {
+		return scores[importPaths[i]] > scores[importPaths[j]]
+	})
+}
@@ -0,0 +1,292 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package index
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"sync"
+
+	log "gopkg.in/clog.v1"
+)
+
+var ErrNoEmbedder = errors.New("embedder is not set")
+
+// Embedder computes vectors of texts for semantic search. Implementations usually
+// call external models, which keeps the index free of their dependencies.
+type Embedder interface {
+	// Embed returns a vector for each of the texts in the same order.
+	Embed(texts []string) ([][]float32, error)
+}
+
+// Vector is the embedding of a chunk of documentation.
+type Vector struct {
+	ImportPath string
+	Symbol     string // Name of the identifier, empty for documentation of the package.
+	Values     []float32
+}
+
+// SemanticMatch is a result of semantic search.
+type SemanticMatch struct {
+	ImportPath string
+	Symbol     string
+	Score      float64 // Cosine similarity to the query.
+}
+
+// VectorStore saves vectors of chunks and finds the nearest ones.
+type VectorStore interface {
+	// Put replaces vectors of the package.
+	Put(importPath string, vecs []*Vector) error
+	Delete(importPath string) error
+	// Nearest returns at most limit chunks that are most similar to the vector.
+	Nearest(vec []float32, limit int) ([]*SemanticMatch, error)
+}
+
+// Number of texts sent to the embedder at once, and length of each text in bytes.
+const (
+	embedBatchSize = 64
+	maxChunkSize   = 2000
+)
+
+// embedQueueSize is the maximum number of packages waiting to be embedded,
+// packages added when the queue is full are embedded when they are added again.
+const embedQueueSize = 100
+
+// SetEmbedder enables semantic search, chunks of documentation of packages are
+// embedded in background after they are added to the index and saved in the vector store.
+func (idx *Index) SetEmbedder(e Embedder, store VectorStore) {
+	idx.lock.Lock()
+	idx.embedder = e
+	idx.vectors = store
+	if idx.embedQueue == nil {
+		idx.embedQueue = make(chan *Entry, embedQueueSize)
+		go idx.embedEntries(idx.embedQueue)
+	}
+	idx.lock.Unlock()
+}
+
+// embedEntries embeds entries in the queue, and records the revision of
+// each entry is embedded if it is still the indexed one.
+func (idx *Index) embedEntries(queue <-chan *Entry) {
+	for e := range queue {
+		idx.lock.RLock()
+		embedder, store := idx.embedder, idx.vectors
+		idx.lock.RUnlock()
+
+		if err := embed(embedder, store, e); err != nil {
+			log.Warn("Failed to embed documentation of %q: %v", e.ImportPath, err)
+			continue
+		}
+
+		idx.lock.Lock()
+		if cur := idx.entries[e.ImportPath]; cur != nil && cur.Commit == e.Commit {
+			cur.Embedded = e.Commit
+			idx.dirty = true
+		}
+		idx.lock.Unlock()
+	}
+}
+
+// chunks returns vectors to fill and texts to embed of the entry, which are
+// documentation of the package and its symbols.
+func (e *Entry) chunks() ([]*Vector, []string) {
+	vecs := make([]*Vector, 0, len(e.Symbols)+1)
+	texts := make([]string, 0, len(e.Symbols)+1)
+	vecs = append(vecs, &Vector{ImportPath: e.ImportPath})
+	texts = append(texts, e.ImportPath+": "+e.Synopsis+"\n"+e.Doc)
+	for _, sym := range e.Symbols {
+		vecs = append(vecs, &Vector{ImportPath: e.ImportPath, Symbol: sym.Name})
+		texts = append(texts, sym.QualifiedName()+": "+sym.Doc)
+	}
+	for i := range texts {
+		if len(texts[i]) > maxChunkSize {
+			texts[i] = texts[i][:maxChunkSize]
+		}
+	}
+	return vecs, texts
+}
+
+// embed saves vectors of chunks of the entry to the vector store.
+func embed(embedder Embedder, store VectorStore, e *Entry) error {
+	vecs, texts := e.chunks()
+	for i := 0; i < len(texts); i += embedBatchSize {
+		end := i + embedBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		values, err := embedder.Embed(texts[i:end])
+		if err != nil {
+			return fmt.Errorf("embed: %v", err)
+		} else if len(values) != end-i {
+			return fmt.Errorf("embed: expect %d vectors but got %d", end-i, len(values))
+		}
+		for j := range values {
+			vecs[i+j].Values = values[j]
+		}
+	}
+	return store.Put(e.ImportPath, vecs)
+}
+
+// SemanticSearch returns at most limit packages and symbols whose documentation
+// is most similar to the query in meaning.
+func (idx *Index) SemanticSearch(query string, limit int) ([]*SemanticMatch, error) {
+	idx.lock.RLock()
+	embedder, store := idx.embedder, idx.vectors
+	idx.lock.RUnlock()
+	if embedder == nil {
+		return nil, ErrNoEmbedder
+	}
+
+	values, err := embedder.Embed([]string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed: %v", err)
+	} else if len(values) != 1 {
+		return nil, fmt.Errorf("embed: expect 1 vector but got %d", len(values))
+	}
+	return store.Nearest(values[0], limit)
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// MemoryVectorStore keeps vectors in memory and finds the nearest ones by
+// brute force, which is optionally persisted to a file. It is safe for
+// concurrent use.
+type MemoryVectorStore struct {
+	lock     sync.RWMutex
+	filename string
+	dirty    bool
+	vectors  map[string][]*Vector // Import path -> vectors.
+}
+
+// NewMemoryVectorStore returns a vector store that is saved to given file,
+// empty filename means not persisted. Vectors are loaded if the file exists.
+func NewMemoryVectorStore(filename string) (*MemoryVectorStore, error) {
+	s := &MemoryVectorStore{
+		filename: filename,
+		vectors:  make(map[string][]*Vector),
+	}
+	if len(filename) == 0 {
+		return s, nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	if err = gob.NewDecoder(f).Decode(&s.vectors); err != nil {
+		return nil, fmt.Errorf("decode: %v", err)
+	}
+	return s, nil
+}
+
+func (s *MemoryVectorStore) Put(importPath string, vecs []*Vector) error {
+	s.lock.Lock()
+	s.vectors[importPath] = vecs
+	s.dirty = true
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *MemoryVectorStore) Delete(importPath string) error {
+	s.lock.Lock()
+	if _, ok := s.vectors[importPath]; ok {
+		delete(s.vectors, importPath)
+		s.dirty = true
+	}
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *MemoryVectorStore) Nearest(vec []float32, limit int) ([]*SemanticMatch, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var matches []*SemanticMatch
+	for _, vecs := range s.vectors {
+		for _, v := range vecs {
+			matches = append(matches, &SemanticMatch{
+				ImportPath: v.ImportPath,
+				Symbol:     v.Symbol,
+				Score:      cosine(vec, v.Values),
+			})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ImportPath+"."+matches[i].Symbol < matches[j].ImportPath+"."+matches[j].Symbol
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// Save writes vectors to the file if they have been changed since last save.
+func (s *MemoryVectorStore) Save() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.filename) == 0 || !s.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(path.Dir(s.filename), os.ModePerm); err != nil {
+		return err
+	}
+
+	tmp := s.filename + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(f).Encode(s.vectors)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err = os.Rename(tmp, s.filename); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
@@ -0,0 +1,283 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package index
+
+import (
+	"html"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	htmlTagPattern = regexp.MustCompile(`<[^>]+>`)
+	wordPattern    = regexp.MustCompile(`[\p{L}\p{N}_]+`)
+)
+
+// plainText returns text content of HTML.
+func plainText(data string) string {
+	return html.UnescapeString(htmlTagPattern.ReplaceAllString(data, " "))
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "if": true, "in": true,
+	"is": true, "it": true, "its": true, "of": true, "on": true, "or": true,
+	"that": true, "the": true, "this": true, "to": true, "was": true, "will": true,
+	"with": true,
+}
+
+// stem reduces common English suffixes of the lowercase word,
+// e.g. "parses", "parsed" and "parsing" become "pars".
+func stem(w string) string {
+	if len(w) <= 3 {
+		return w
+	}
+
+	switch {
+	case strings.HasSuffix(w, "ies") && len(w) > 4:
+		w = w[:len(w)-3] + "y"
+	case strings.HasSuffix(w, "ing") && len(w) > 5:
+		w = w[:len(w)-3]
+	case strings.HasSuffix(w, "ed") && len(w) > 4:
+		w = w[:len(w)-2]
+	case strings.HasSuffix(w, "ly") && len(w) > 4:
+		w = w[:len(w)-2]
+	case strings.HasSuffix(w, "es") && len(w) > 4 && strings.ContainsAny(w[len(w)-3:len(w)-2], "sxzh"):
+		w = w[:len(w)-2]
+	case strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") &&
+		!strings.HasSuffix(w, "us") && !strings.HasSuffix(w, "is"):
+		w = w[:len(w)-1]
+	}
+
+	if strings.HasSuffix(w, "e") && len(w) > 4 {
+		w = w[:len(w)-1]
+	}
+	return w
+}
+
+// tokenize returns stemmed terms of the text without stop words.
+func tokenize(text string) []string {
+	words := wordPattern.FindAllString(strings.ToLower(text), -1)
+	terms := words[:0]
+	for _, w := range words {
+		if len(w) < 2 || stopWords[w] {
+			continue
+		}
+		terms = append(terms, stem(w))
+	}
+	return terms
+}
+
+// Sources of indexed text.
+const (
+	TS_Doc    = "doc"    // Package documentation.
+	TS_Readme = "readme" // README file.
+	TS_Symbol = "symbol" // Doc comment of an exported identifier.
+)
+
+// textDoc identifies a piece of indexed text.
+type textDoc struct {
+	ImportPath string
+	Source     string
+	Symbol     string // Name of the identifier for TS_Symbol.
+}
+
+// texts returns indexed texts of the entry.
+func (e *Entry) texts() map[textDoc]string {
+	texts := make(map[textDoc]string)
+	if len(e.Doc) > 0 {
+		texts[textDoc{e.ImportPath, TS_Doc, ""}] = e.Doc
+	}
+	if len(e.Readme) > 0 {
+		texts[textDoc{e.ImportPath, TS_Readme, ""}] = e.Readme
+	}
+	for _, sym := range e.Symbols {
+		text := sym.Doc
+		if len(sym.Synonyms) > 0 {
+			text += "\n" + splitCamelCase(strings.Join(sym.Synonyms, " "))
+		}
+		if len(strings.TrimSpace(text)) > 0 {
+			texts[textDoc{e.ImportPath, TS_Symbol, sym.Name}] = text
+		}
+	}
+	return texts
+}
+
+func (idx *Index) addTexts(e *Entry) {
+	for td, text := range e.texts() {
+		terms := tokenize(text)
+		for _, term := range terms {
+			if idx.terms[term] == nil {
+				idx.terms[term] = make(map[textDoc]int)
+			}
+			idx.terms[term][td]++
+		}
+		idx.textLens[td] = len(terms)
+	}
+}
+
+func (idx *Index) removeTexts(e *Entry) {
+	for td, text := range e.texts() {
+		for _, term := range tokenize(text) {
+			delete(idx.terms[term], td)
+			if len(idx.terms[term]) == 0 {
+				delete(idx.terms, term)
+			}
+		}
+		delete(idx.textLens, td)
+	}
+}
+
+// TextMatch is a result of full-text search.
+type TextMatch struct {
+	ImportPath string
+	Source     string // One of TS_Doc, TS_Readme and TS_Symbol.
+	Symbol     string // Name of the identifier if source is TS_Symbol.
+	Score      float64
+	// Escaped HTML of the text around matched words,
+	// which are wrapped by <mark> tags.
+	Snippet string
+}
+
+// Radius of snippets in bytes around the first matched word.
+const snippetRadius = 80
+
+// snippet returns highlighted HTML of the text around words that match terms.
+func snippet(text string, terms map[string]bool) string {
+	text = strings.Join(strings.Fields(text), " ")
+	locs := wordPattern.FindAllStringIndex(text, -1)
+
+	start, end := 0, len(text)
+	for _, loc := range locs {
+		if terms[stem(strings.ToLower(text[loc[0]:loc[1]]))] {
+			if loc[0] > snippetRadius {
+				start = loc[0] - snippetRadius
+			}
+			if loc[1]+snippetRadius < end {
+				end = loc[1] + snippetRadius
+			}
+			break
+		}
+	}
+	// Do not cut words in the middle, or runes of texts without spaces.
+	s, e := start, end
+	for s > 0 && s < len(text) && text[s-1] != ' ' {
+		s++
+	}
+	for e < len(text) && e > s && text[e] != ' ' {
+		e--
+	}
+	if e <= s {
+		s, e = start, end
+		for s < len(text) && !utf8.RuneStart(text[s]) {
+			s++
+		}
+		for e < len(text) && e > s && !utf8.RuneStart(text[e]) {
+			e--
+		}
+	}
+	start, end = s, e
+
+	var buf strings.Builder
+	if start > 0 {
+		buf.WriteString("...")
+	}
+	last := start
+	for _, loc := range locs {
+		if loc[0] < start || loc[1] > end {
+			continue
+		}
+		if !terms[stem(strings.ToLower(text[loc[0]:loc[1]]))] {
+			continue
+		}
+		buf.WriteString(html.EscapeString(text[last:loc[0]]))
+		buf.WriteString("<mark>")
+		buf.WriteString(html.EscapeString(text[loc[0]:loc[1]]))
+		buf.WriteString("</mark>")
+		last = loc[1]
+	}
+	buf.WriteString(html.EscapeString(text[last:end]))
+	if end < len(text) {
+		buf.WriteString("...")
+	}
+	return buf.String()
+}
+
+// SearchText returns at most limit texts that contain all words of the query,
+// ordered by TF-IDF relevance. Words are matched by their stems.
+func (idx *Index) SearchText(query string, limit int) []*TextMatch {
+	terms := make(map[string]bool)
+	for _, term := range tokenize(query) {
+		terms[term] = true
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	var scores map[textDoc]float64
+	for term := range terms {
+		postings := idx.terms[term]
+		if len(postings) == 0 {
+			return nil
+		}
+
+		idf := math.Log(1 + float64(len(idx.textLens))/float64(len(postings)))
+		matched := make(map[textDoc]float64, len(postings))
+		for td, freq := range postings {
+			if scores != nil {
+				if _, ok := scores[td]; !ok {
+					continue
+				}
+			}
+			matched[td] = scores[td] + float64(freq)/float64(idx.textLens[td])*idf
+		}
+		scores = matched
+	}
+
+	matches := make([]*TextMatch, 0, len(scores))
+	for td, score := range scores {
+		matches = append(matches, &TextMatch{
+			ImportPath: td.ImportPath,
+			Source:     td.Source,
+			Symbol:     td.Symbol,
+			Score:      score,
+		})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if matches[i].ImportPath != matches[j].ImportPath {
+			return matches[i].ImportPath < matches[j].ImportPath
+		}
+		return matches[i].Symbol < matches[j].Symbol
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	// Only generate snippets for returned matches.
+	for _, m := range matches {
+		e := idx.entries[m.ImportPath]
+		m.Snippet = snippet(e.texts()[textDoc{m.ImportPath, m.Source, m.Symbol}], terms)
+	}
+	return matches
+}
@@ -0,0 +1,384 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package notify sends messages to webhooks of subscriptions when
+// documentation of packages is stored or their APIs change.
+package notify
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Unknwon/com"
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+// Formats of webhook payloads.
+const (
+	FormatSlack   = "slack" // Also accepted by Mattermost and Rocket.Chat.
+	FormatDiscord = "discord"
+	FormatJSON    = "json"
+)
+
+// KindAPI is the kind of notifications for API changes of new versions or default branch.
+const KindAPI = "api"
+
+// Default kinds of notifications of subscriptions.
+var defaultKinds = []string{string(doc.EK_Version), KindAPI}
+
+// Subscription sends notifications of packages under the import path prefix to the webhook.
+type Subscription struct {
+	ID     string
+	Prefix string   // Import path prefix, empty for all packages.
+	URL    string   // Webhook URL.
+	Format string   // slack, discord or json.
+	Kinds  []string // Events to notify: new, updated, version and api.
+}
+
+// matches returns true if the subscription wants the notification of the package.
+func (s *Subscription) matches(importPath string, kinds []string) bool {
+	if len(s.Prefix) > 0 && importPath != s.Prefix && !strings.HasPrefix(importPath, s.Prefix+"/") {
+		return false
+	}
+	want := s.Kinds
+	if len(want) == 0 {
+		want = defaultKinds
+	}
+	for _, k := range kinds {
+		for _, w := range want {
+			if k == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Subscriptions is the list of subscriptions saved to a JSON file.
+// It is safe for concurrent use.
+type Subscriptions struct {
+	lock sync.RWMutex
+	path string // Where to save, empty means not persisted.
+	subs map[string]*Subscription
+}
+
+// OpenSubscriptions returns subscriptions saved at given path, or an empty
+// list if the file does not exist.
+func OpenSubscriptions(filename string) (*Subscriptions, error) {
+	s := &Subscriptions{
+		path: filename,
+		subs: make(map[string]*Subscription),
+	}
+	if len(filename) == 0 || !com.IsFile(filename) {
+		return s, nil
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read: %v", err)
+	}
+	var subs []*Subscription
+	if err = json.Unmarshal(data, &subs); err != nil {
+		return nil, fmt.Errorf("decode: %v", err)
+	}
+	for _, sub := range subs {
+		s.subs[sub.ID] = sub
+	}
+	return s, nil
+}
+
+// save writes subscriptions to the file, the caller must hold the lock.
+func (s *Subscriptions) save() error {
+	if len(s.path) == 0 {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.list(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode: %v", err)
+	}
+	os.MkdirAll(path.Dir(s.path), os.ModePerm)
+	tmpPath := s.path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("write: %v", err)
+	}
+	if err = os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("rename: %v", err)
+	}
+	return nil
+}
+
+// Add validates and adds the subscription, and sets its ID.
+func (s *Subscriptions) Add(sub *Subscription) error {
+	if !strings.HasPrefix(sub.URL, "https://") && !strings.HasPrefix(sub.URL, "http://") {
+		return errors.New("webhook URL must be HTTP or HTTPS")
+	}
+	switch sub.Format {
+	case "":
+		sub.Format = FormatJSON
+	case FormatSlack, FormatDiscord, FormatJSON:
+	default:
+		return fmt.Errorf("unknown format %q", sub.Format)
+	}
+	for _, k := range sub.Kinds {
+		switch k {
+		case string(doc.EK_New), string(doc.EK_Updated), string(doc.EK_Version), KindAPI:
+		default:
+			return fmt.Errorf("unknown kind %q", k)
+		}
+	}
+	sub.Prefix = strings.Trim(sub.Prefix, "/")
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	sub.ID = hex.EncodeToString(id)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.subs[sub.ID] = sub
+	return s.save()
+}
+
+// Remove removes the subscription of given ID.
+func (s *Subscriptions) Remove(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.subs, id)
+	return s.save()
+}
+
+func (s *Subscriptions) list() []*Subscription {
+	subs := make([]*Subscription, 0, len(s.subs))
+	for _, sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].Prefix != subs[j].Prefix {
+			return subs[i].Prefix < subs[j].Prefix
+		}
+		return subs[i].ID < subs[j].ID
+	})
+	return subs
+}
+
+// List returns all subscriptions sorted by import path prefix.
+func (s *Subscriptions) List() []*Subscription {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.list()
+}
+
+// Match returns subscriptions that want notifications of any kind of the package.
+func (s *Subscriptions) Match(importPath string, kinds []string) []*Subscription {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var subs []*Subscription
+	for _, sub := range s.list() {
+		if sub.matches(importPath, kinds) {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// Notification is a change of stored documentation to be sent to subscriptions.
+type Notification struct {
+	Kinds      []string
+	ImportPath string
+	Version    string
+	Synopsis   string
+	Commit     string
+	Time       time.Time
+	URL        string
+	Added      []string `json:",omitempty"`
+	Removed    []string `json:",omitempty"`
+	Changed    []string `json:",omitempty"`
+}
+
+func changeNames(changes []*doc.Change) []string {
+	names := make([]string, len(changes))
+	for i, c := range changes {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// newNotification returns the notification of the event and changes of exported identifiers.
+func newNotification(e *doc.DocEvent, d *doc.APIDiff, baseURL string) *Notification {
+	n := &Notification{
+		Kinds:      []string{string(e.Kind)},
+		ImportPath: e.ImportPath,
+		Version:    e.Version,
+		Synopsis:   e.Synopsis,
+		Commit:     e.Commit,
+		Time:       e.Time,
+		URL:        strings.TrimSuffix(baseURL, "/") + "/" + e.ImportPath,
+	}
+	if d != nil && !d.Empty() {
+		n.Kinds = append(n.Kinds, KindAPI)
+		n.Added = changeNames(d.Added)
+		n.Removed = changeNames(d.Removed)
+		n.Changed = changeNames(d.Changed)
+	}
+	return n
+}
+
+// Text returns the message of the notification in Markdown of Slack.
+func (n *Notification) Text() string {
+	return n.text("*")
+}
+
+// text returns the message of the notification in Markdown, bold text is
+// wrapped by given marker, which is "*" in Slack and "**" in Discord.
+func (n *Notification) text(bold string) string {
+	name := n.ImportPath
+	if len(n.Version) > 0 {
+		name += "@" + n.Version
+	}
+
+	var buf bytes.Buffer
+	switch doc.EventKind(n.Kinds[0]) {
+	case doc.EK_New:
+		fmt.Fprintf(&buf, "New package %s is documented", name)
+	case doc.EK_Updated:
+		fmt.Fprintf(&buf, "Documentation of %s is updated", name)
+	case doc.EK_Version:
+		fmt.Fprintf(&buf, "New version %s is documented", name)
+	}
+	fmt.Fprintf(&buf, ": %s", n.URL)
+	if len(n.Synopsis) > 0 {
+		fmt.Fprintf(&buf, "\n> %s", n.Synopsis)
+	}
+
+	for _, c := range []struct {
+		title string
+		names []string
+	}{
+		{"Removed", n.Removed},
+		{"Changed", n.Changed},
+		{"Added", n.Added},
+	} {
+		if len(c.names) == 0 {
+			continue
+		}
+		// Keep messages short for chat services.
+		names := c.names
+		if len(names) > 10 {
+			names = append(names[:10:10], fmt.Sprintf("and %d more", len(c.names)-10))
+		}
+		fmt.Fprintf(&buf, "\n%s%s:%s `%s`", bold, c.title, bold, strings.Join(names, "`, `"))
+	}
+	return buf.String()
+}
+
+// payload returns the body of the webhook request in the format.
+func (n *Notification) payload(format string) ([]byte, error) {
+	switch format {
+	case FormatSlack:
+		return json.Marshal(map[string]string{"text": n.Text()})
+	case FormatDiscord:
+		text := n.text("**")
+		// Discord limits the content to 2000 characters.
+		if rs := []rune(text); len(rs) > 2000 {
+			text = string(rs[:1997]) + "..."
+		}
+		return json.Marshal(map[string]string{"content": text})
+	}
+	return json.Marshal(n)
+}
+
+type delivery struct {
+	sub *Subscription
+	n   *Notification
+}
+
+// Notifier sends notifications of stored documentation to matched subscriptions
+// in background.
+type Notifier struct {
+	subs    *Subscriptions
+	baseURL string
+	client  *http.Client
+	queue   chan *delivery
+}
+
+// Maximum number of notifications waiting to be sent, new ones are dropped when the queue is full.
+const queueSize = 1000
+
+// New returns a notifier of the subscriptions, which links to documentation
+// under the base URL, and starts sending notifications.
+func New(subs *Subscriptions, baseURL string) *Notifier {
+	n := &Notifier{
+		subs:    subs,
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		queue:   make(chan *delivery, queueSize),
+	}
+	go n.run()
+	return n
+}
+
+func (n *Notifier) run() {
+	for d := range n.queue {
+		if err := n.send(d.sub, d.n); err != nil {
+			log.Error(2, "Failed to notify subscription %s of %s: %v", d.sub.ID, d.n.ImportPath, err)
+		}
+	}
+}
+
+func (n *Notifier) send(sub *Subscription, notif *Notification) error {
+	body, err := notif.payload(sub.Format)
+	if err != nil {
+		return fmt.Errorf("encode: %v", err)
+	}
+	resp, err := n.client.Post(sub.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// Handle queues notifications of the event to matched subscriptions,
+// it is a doc.EventHandler.
+func (n *Notifier) Handle(e *doc.DocEvent, d *doc.APIDiff) {
+	notif := newNotification(e, d, n.baseURL)
+	for _, sub := range n.subs.Match(e.ImportPath, notif.Kinds) {
+		select {
+		case n.queue <- &delivery{sub, notif}:
+		default:
+			log.Warn("Notification queue is full, dropped %s of %s", sub.ID, e.ImportPath)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package objstore implements storages of documentation on S3-compatible object
+// storage, e.g. AWS S3, DigitalOcean Spaces, MinIO and Google Cloud Storage
+// through its interoperability API with HMAC keys.
+package objstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go"
+
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+// Options contains the location of objects in the bucket.
+type Options struct {
+	Bucket string
+	Prefix string    // Prefix of object names, e.g. "gowalker/".
+	Codec  doc.Codec // Used to compress stored objects.
+	// Packages larger than it in bytes are split into shards by doc.EncodeShards,
+	// 0 means unlimited.
+	MaxObjectSize int
+}
+
+// objects wraps operations on objects under the prefix of the bucket.
+type objects struct {
+	client *minio.Client
+	Options
+}
+
+func isNotFound(err error) bool {
+	return minio.ToErrorResponse(err).Code == "NoSuchKey"
+}
+
+func (o *objects) put(name string, data []byte) error {
+	_, err := o.client.PutObject(o.Bucket, o.Prefix+name, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: "application/octet-stream"})
+	return err
+}
+
+// get returns content of the object, or nil if it does not exist.
+func (o *objects) get(name string) ([]byte, error) {
+	obj, err := o.client.GetObject(o.Bucket, o.Prefix+name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	// Errors of the request are returned by the first read.
+	data, err := ioutil.ReadAll(obj)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+func (o *objects) exists(name string) (bool, error) {
+	_, err := o.client.StatObject(o.Bucket, o.Prefix+name, minio.StatObjectOptions{})
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (o *objects) remove(name string) error {
+	return o.client.RemoveObject(o.Bucket, o.Prefix+name)
+}
+
+// list calls fn for every object with given prefix, names passed to fn are relative to the prefix.
+func (o *objects) list(prefix string, fn func(name string, info minio.ObjectInfo) error) error {
+	done := make(chan struct{})
+	defer close(done)
+	for info := range o.client.ListObjects(o.Bucket, o.Prefix+prefix, true, done) {
+		if info.Err != nil {
+			return info.Err
+		}
+		if err := fn(strings.TrimPrefix(info.Key, o.Prefix+prefix), info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	docsPrefix  = "docs/"
+	blobsPrefix = "blobs/"
+	cachePrefix = "cache/"
+)
+
+// docRef is the object that points a documentation to its content.
+type docRef struct {
+	Meta doc.DocMeta
+	Hash string // Hex-encoded SHA-256 of the encoded package.
+	// Hashes of blobs encoded by doc.EncodeShards when the package is
+	// too large to store in a single object, Hash is empty in that case.
+	Shards []string
+}
+
+// DocStore stores documentation on object storage. Packages are saved as
+// content-addressed blobs, which are shared by versions with the same
+// documentation, and referenced by small objects named by import path and version.
+type DocStore struct {
+	objects
+}
+
+// NewDocStore returns a new DocStore with given client.
+func NewDocStore(client *minio.Client, opts Options) *DocStore {
+	return &DocStore{objects{client, opts}}
+}
+
+func refName(importPath, version string) string {
+	if len(version) == 0 {
+		version = "_"
+	}
+	return docsPrefix + importPath + "/@v/" + version
+}
+
+func (s *DocStore) getRef(importPath, version string) (*docRef, error) {
+	data, err := s.get(refName(importPath, version))
+	if err != nil {
+		return nil, err
+	} else if data == nil {
+		return nil, doc.ErrDocNotFound
+	}
+
+	ref := new(docRef)
+	if err = gob.NewDecoder(bytes.NewReader(data)).Decode(ref); err != nil {
+		return nil, fmt.Errorf("decode ref: %v", err)
+	}
+	return ref, nil
+}
+
+func (s *DocStore) putRef(ref *docRef) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(ref); err != nil {
+		return err
+	}
+	return s.put(refName(ref.Meta.ImportPath, ref.Meta.Version), buf.Bytes())
+}
+
+func (s *DocStore) Get(importPath, version string) (*doc.Package, error) {
+	ref, err := s.getRef(importPath, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ref.Shards) > 0 {
+		blobs := make([][]byte, len(ref.Shards))
+		for i, hash := range ref.Shards {
+			if blobs[i], err = s.getBlob(hash); err != nil {
+				return nil, err
+			}
+		}
+		return doc.DecodeShards(blobs)
+	}
+
+	data, err := s.getBlob(ref.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return doc.DecodePackage(data)
+}
+
+func (s *DocStore) getBlob(hash string) ([]byte, error) {
+	data, err := s.get(blobsPrefix + hash)
+	if err != nil {
+		return nil, err
+	} else if data == nil {
+		return nil, fmt.Errorf("blob %s does not exist", hash)
+	}
+	return data, nil
+}
+
+// putBlob saves the blob if it does not exist, and returns its hash.
+func (s *DocStore) putBlob(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	has, err := s.exists(blobsPrefix + hash)
+	if err != nil {
+		return "", err
+	} else if !has {
+		if err = s.put(blobsPrefix+hash, data); err != nil {
+			return "", fmt.Errorf("put blob: %v", err)
+		}
+	}
+	return hash, nil
+}
+
+func (s *DocStore) Put(pdoc *doc.Package) error {
+	data, err := doc.EncodePackage(pdoc, s.Codec)
+	if err != nil {
+		return err
+	}
+
+	var (
+		hash   string
+		shards []string
+	)
+	if s.MaxObjectSize > 0 && len(data) > s.MaxObjectSize {
+		blobs, err := doc.EncodeShards(pdoc, s.Codec, s.MaxObjectSize)
+		if err != nil {
+			return err
+		}
+		shards = make([]string, len(blobs))
+		for i, blob := range blobs {
+			if shards[i], err = s.putBlob(blob); err != nil {
+				return err
+			}
+		}
+	} else if hash, err = s.putBlob(data); err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	return s.putRef(&docRef{
+		Meta: doc.DocMeta{
+			ImportPath: pdoc.ImportPath,
+			Module:     doc.ModuleOf(pdoc),
+			Version:    pdoc.Tag,
+			Walked:     now,
+			Viewed:     now,
+		},
+		Hash:   hash,
+		Shards: shards,
+	})
+}
+
+func (s *DocStore) Touch(importPath, version string) error {
+	ref, err := s.getRef(importPath, version)
+	if err != nil {
+		return err
+	}
+	ref.Meta.Viewed = time.Now().Unix()
+	return s.putRef(ref)
+}
+
+func (s *DocStore) Delete(importPath, version string) error {
+	return s.remove(refName(importPath, version))
+}
+
+func (s *DocStore) List() ([]*doc.DocMeta, error) {
+	var metas []*doc.DocMeta
+	err := s.list(docsPrefix, func(name string, _ minio.ObjectInfo) error {
+		i := strings.LastIndex(name, "/@v/")
+		if i < 0 {
+			return nil
+		}
+		version := name[i+4:]
+		if version == "_" {
+			version = ""
+		}
+
+		ref, err := s.getRef(name[:i], version)
+		if err != nil {
+			if err == doc.ErrDocNotFound {
+				return nil // Deleted in the meantime.
+			}
+			return err
+		}
+		metas = append(metas, &ref.Meta)
+		return nil
+	})
+	return metas, err
+}
+
+// sweepGracePeriod prevents blobs that are just uploaded and whose references
+// are not saved yet from being removed.
+const sweepGracePeriod = time.Hour
+
+// Sweep removes blobs that are not referenced by any documentation.
+func (s *DocStore) Sweep() error {
+	used := make(map[string]bool)
+	err := s.list(docsPrefix, func(name string, _ minio.ObjectInfo) error {
+		data, err := s.get(docsPrefix + name)
+		if err != nil || data == nil {
+			return err
+		}
+		ref := new(docRef)
+		if err = gob.NewDecoder(bytes.NewReader(data)).Decode(ref); err != nil {
+			return fmt.Errorf("decode ref %q: %v", name, err)
+		}
+		used[ref.Hash] = true
+		for _, hash := range ref.Shards {
+			used[hash] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.list(blobsPrefix, func(name string, info minio.ObjectInfo) error {
+		if used[name] || time.Since(info.LastModified) < sweepGracePeriod {
+			return nil
+		}
+		return s.remove(blobsPrefix + name)
+	})
+}
+
+// Cache stores cached data on object storage. Expiration is checked on read,
+// a lifecycle rule of the bucket on the prefix can be used to clean up
+// objects that are never read again.
+type Cache struct {
+	objects
+}
+
+// NewCache returns a new Cache with given client.
+func NewCache(client *minio.Client, opts Options) *Cache {
+	return &Cache{objects{client, opts}}
+}
+
+func cacheName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return cachePrefix + hex.EncodeToString(sum[:])
+}
+
+// cacheEntry is the content of a cached object.
+type cacheEntry struct {
+	Expires int64  // Unix time, zero means never.
+	Data    []byte // Compressed by doc.Compress.
+}
+
+func (c *Cache) Get(key string) ([]byte, error) {
+	data, err := c.get(cacheName(key))
+	if err != nil {
+		return nil, err
+	} else if data == nil {
+		return nil, doc.ErrCacheMiss
+	}
+
+	var e cacheEntry
+	if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&e); err != nil {
+		return nil, fmt.Errorf("decode entry: %v", err)
+	}
+	if e.Expires > 0 && time.Now().Unix() >= e.Expires {
+		c.remove(cacheName(key))
+		return nil, doc.ErrCacheMiss
+	}
+	return doc.Decompress(e.Data)
+}
+
+func (c *Cache) Set(key string, data []byte, ttl time.Duration) (err error) {
+	var e cacheEntry
+	if ttl > 0 {
+		e.Expires = time.Now().Add(ttl).Unix()
+	}
+	if e.Data, err = doc.Compress(data, c.Codec); err != nil {
+		return fmt.Errorf("compress: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err = gob.NewEncoder(&buf).Encode(&e); err != nil {
+		return err
+	}
+	return c.put(cacheName(key), buf.Bytes())
+}
+
+func (c *Cache) Delete(key string) error {
+	return c.remove(cacheName(key))
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package ratelimit limits rates of requests of clients by token buckets.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is the steady rate and burst of a token bucket.
+type Limit struct {
+	Rate  float64 // Tokens added per second.
+	Burst int     // Capacity of the bucket.
+}
+
+// Unlimited returns true if the limit does not limit anything.
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// fill adds tokens to the bucket for the elapsed time since last fill.
+func (b *bucket) fill(now time.Time) {
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+}
+
+// Result is the result of taking a token from the bucket of a client.
+type Result struct {
+	Allowed    bool
+	Limit      Limit
+	Remaining  int           // Whole tokens left in the bucket.
+	RetryAfter time.Duration // Time until next token is available when not allowed.
+}
+
+// Limiter keeps token buckets of clients in memory, buckets that
+// become full again are removed periodically.
+type Limiter struct {
+	lock      sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New returns a new Limiter.
+func New() *Limiter {
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+const sweepInterval = time.Minute
+
+// Allow takes a token from the bucket of the client identified by key,
+// the bucket is created full with given limit if it does not exist.
+func (l *Limiter) Allow(key string, limit Limit) Result {
+	if limit.Unlimited() {
+		return Result{Allowed: true, Limit: limit}
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+
+	b := l.buckets[key]
+	if b == nil {
+		b = &bucket{tokens: float64(limit.Burst), last: now, limit: limit}
+		l.buckets[key] = b
+	}
+	// Limit may be changed, e.g. when the client starts to use an API key.
+	if b.limit != limit {
+		b.fill(now)
+		b.limit = limit
+	}
+	b.fill(now)
+
+	r := Result{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		r.Allowed = true
+	} else {
+		r.RetryAfter = time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	}
+	r.Remaining = int(b.tokens)
+	return r
+}
+
+// sweep removes buckets that are full at given time.
+func (l *Limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		b.fill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,77 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	limit := Limit{Rate: 2, Burst: 3}
+	steps := []struct {
+		name      string
+		elapsed   time.Duration // Since the previous step.
+		key       string
+		limit     Limit
+		allowed   bool
+		remaining int
+		retry     time.Duration
+	}{
+		{"new bucket is full", 0, "a", limit, true, 2, 0},
+		{"burst", 0, "a", limit, true, 1, 0},
+		{"last token", 0, "a", limit, true, 0, 0},
+		{"empty", 0, "a", limit, false, 0, 500 * time.Millisecond},
+		{"other client", 0, "b", limit, true, 2, 0},
+		{"partially refilled", 250 * time.Millisecond, "a", limit, false, 0, 250 * time.Millisecond},
+		{"refilled", 250 * time.Millisecond, "a", limit, true, 0, 0},
+		{"capped at burst", time.Hour, "a", limit, true, 2, 0},
+		{"higher limit", 0, "a", Limit{Rate: 2, Burst: 10}, true, 1, 0},
+		{"unlimited", 0, "a", Limit{}, true, 0, 0},
+	}
+
+	now := time.Unix(0, 0)
+	l := New()
+	l.now = func() time.Time { return now }
+	for _, step := range steps {
+		now = now.Add(step.elapsed)
+		r := l.Allow(step.key, step.limit)
+		if r.Allowed != step.allowed || r.Remaining != step.remaining || r.RetryAfter != step.retry {
+			t.Fatalf("%s: got allowed %v, remaining %d, retry after %v, want %v, %d, %v", step.name,
+				r.Allowed, r.Remaining, r.RetryAfter, step.allowed, step.remaining, step.retry)
+		}
+	}
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := New()
+	l.now = func() time.Time { return now }
+
+	l.Allow("a", Limit{Rate: 1, Burst: 100})
+	l.Allow("b", Limit{Rate: 0.001, Burst: 100})
+
+	// Bucket "a" is full again after sweep interval, but "b" is not.
+	now = now.Add(sweepInterval + time.Second)
+	l.Allow("c", Limit{Rate: 1, Burst: 100})
+	if _, ok := l.buckets["a"]; ok {
+		t.Fatal("full bucket is not removed")
+	}
+	for _, key := range []string{"b", "c"} {
+		if _, ok := l.buckets[key]; !ok {
+			t.Fatalf("bucket %q is removed", key)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package redisstore implements the cache, locker and crawl queue on Redis,
+// which are shared by horizontally scaled servers.
+package redisstore
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+// Store implements doc.Cache, doc.Locker and doc.Queue.
+type Store struct {
+	pool   *redis.Pool
+	prefix string // Prefix of all keys.
+}
+
+// New returns a new Store connects to Redis with given address,
+// password and database.
+func New(addr, password string, db int, prefix string) *Store {
+	return &Store{
+		pool: &redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return redis.Dial("tcp", addr,
+					redis.DialPassword(password),
+					redis.DialDatabase(db))
+			},
+			MaxIdle:     10,
+			IdleTimeout: 5 * time.Minute,
+		},
+		prefix: prefix,
+	}
+}
+
+// Close closes connections to Redis.
+func (s *Store) Close() error {
+	return s.pool.Close()
+}
+
+func (s *Store) do(cmd string, args ...interface{}) (interface{}, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+	return conn.Do(cmd, args...)
+}
+
+func (s *Store) Get(key string) ([]byte, error) {
+	data, err := redis.Bytes(s.do("GET", s.prefix+"cache:"+key))
+	if err == redis.ErrNil {
+		return nil, doc.ErrCacheMiss
+	}
+	return data, err
+}
+
+func (s *Store) Set(key string, data []byte, ttl time.Duration) error {
+	args := []interface{}{s.prefix + "cache:" + key, data}
+	if ttl > 0 {
+		args = append(args, "PX", int64(ttl/time.Millisecond))
+	}
+	_, err := s.do("SET", args...)
+	return err
+}
+
+func (s *Store) Delete(key string) error {
+	_, err := s.do("DEL", s.prefix+"cache:"+key)
+	return err
+}
+
+func (s *Store) Lock(key string, ttl time.Duration) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	_, err := redis.String(s.do("SET", s.prefix+"lock:"+key, token, "NX", "PX", int64(ttl/time.Millisecond)))
+	if err == redis.ErrNil {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// unlockScript deletes the lock only if it is still held by the token,
+// so an expired lock that has been acquired by others is not released.
+var unlockScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+func (s *Store) Unlock(key, token string) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+	_, err := unlockScript.Do(conn, s.prefix+"lock:"+key, token)
+	return err
+}
+
+// Import paths in the queue are also kept in a set to prevent duplicates.
+func (s *Store) queueKeys() (list, set string) {
+	return s.prefix + "queue", s.prefix + "queued"
+}
+
+// pushScript adds the import path to the set and the list in one step,
+// the import path is not pushed if it is already in the queue.
+var pushScript = redis.NewScript(2, `
+if redis.call("SADD", KEYS[2], ARGV[1]) == 1 then
+	redis.call("LPUSH", KEYS[1], ARGV[1])
+end
+return 0`)
+
+func (s *Store) Push(importPath string) error {
+	list, set := s.queueKeys()
+	conn := s.pool.Get()
+	defer conn.Close()
+	_, err := pushScript.Do(conn, list, set, importPath)
+	return err
+}
+
+// Len returns the number of import paths in the queue.
+func (s *Store) Len() (int64, error) {
+	list, _ := s.queueKeys()
+	return redis.Int64(s.do("LLEN", list))
+}
+
+// popScript pops the import path from the list and removes it from the set
+// in one step, so the import path cannot be lost or pushed twice in between.
+var popScript = redis.NewScript(2, `
+local importPath = redis.call("RPOP", KEYS[1])
+if importPath then
+	redis.call("SREM", KEYS[2], importPath)
+end
+return importPath`)
+
+// popInterval is the interval to check the queue again when it is empty,
+// blocking commands cannot be used in scripts.
+const popInterval = 500 * time.Millisecond
+
+func (s *Store) Pop(timeout time.Duration) (string, error) {
+	// Empty queue is checked again until timeout, which is at least one second.
+	if timeout < time.Second {
+		timeout = time.Second
+	}
+	deadline := time.Now().Add(timeout)
+
+	list, set := s.queueKeys()
+	for {
+		conn := s.pool.Get()
+		importPath, err := redis.String(popScript.Do(conn, list, set))
+		conn.Close()
+		if err == nil {
+			return importPath, nil
+		} else if err != redis.ErrNil {
+			return "", err
+		}
+
+		if time.Now().Add(popInterval).After(deadline) {
+			return "", nil
+		}
+		time.Sleep(popInterval)
+	}
+}
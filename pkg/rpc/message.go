@@ -0,0 +1,301 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rpc
+
+// Messages of walker.proto.
+
+type WalkRequest struct {
+	ImportPath string
+}
+
+func (m *WalkRequest) Marshal() ([]byte, error) {
+	return appendString(nil, 1, m.ImportPath), nil
+}
+
+func (m *WalkRequest) Unmarshal(b []byte) error {
+	*m = WalkRequest{}
+	return eachField(b, func(f fieldValue) error {
+		if f.num == 1 {
+			m.ImportPath = string(f.data)
+		}
+		return nil
+	})
+}
+
+type GetPackageRequest struct {
+	ImportPath string
+	Version    string // Empty for the default branch.
+}
+
+func (m *GetPackageRequest) Marshal() ([]byte, error) {
+	b := appendString(nil, 1, m.ImportPath)
+	return appendString(b, 2, m.Version), nil
+}
+
+func (m *GetPackageRequest) Unmarshal(b []byte) error {
+	*m = GetPackageRequest{}
+	return eachField(b, func(f fieldValue) error {
+		switch f.num {
+		case 1:
+			m.ImportPath = string(f.data)
+		case 2:
+			m.Version = string(f.data)
+		}
+		return nil
+	})
+}
+
+type SearchRequest struct {
+	Query string
+	Limit int32 // Default is 20, at most 100.
+}
+
+func (m *SearchRequest) Marshal() ([]byte, error) {
+	b := appendString(nil, 1, m.Query)
+	return appendInt(b, 2, int64(m.Limit)), nil
+}
+
+func (m *SearchRequest) Unmarshal(b []byte) error {
+	*m = SearchRequest{}
+	return eachField(b, func(f fieldValue) error {
+		switch f.num {
+		case 1:
+			m.Query = string(f.data)
+		case 2:
+			m.Limit = int32(f.varint)
+		}
+		return nil
+	})
+}
+
+type DiffRequest struct {
+	ImportPath string
+	From, To   string // Default to the latest two tagged versions.
+}
+
+func (m *DiffRequest) Marshal() ([]byte, error) {
+	b := appendString(nil, 1, m.ImportPath)
+	b = appendString(b, 2, m.From)
+	return appendString(b, 3, m.To), nil
+}
+
+func (m *DiffRequest) Unmarshal(b []byte) error {
+	*m = DiffRequest{}
+	return eachField(b, func(f fieldValue) error {
+		switch f.num {
+		case 1:
+			m.ImportPath = string(f.data)
+		case 2:
+			m.From = string(f.data)
+		case 3:
+			m.To = string(f.data)
+		}
+		return nil
+	})
+}
+
+type Package struct {
+	ImportPath string
+	Version    string
+	Synopsis   string
+	Doc        string
+	ModulePath string
+	Imports    []string
+	Symbols    []*Symbol
+	Examples   []*Example
+}
+
+func (m *Package) Marshal() ([]byte, error) {
+	b := appendString(nil, 1, m.ImportPath)
+	b = appendString(b, 2, m.Version)
+	b = appendString(b, 3, m.Synopsis)
+	b = appendString(b, 4, m.Doc)
+	b = appendString(b, 5, m.ModulePath)
+	for _, imp := range m.Imports {
+		b = appendTag(b, 6, wireBytes)
+		b = appendUvarint(b, uint64(len(imp)))
+		b = append(b, imp...)
+	}
+	for _, s := range m.Symbols {
+		data, _ := s.Marshal()
+		b = appendMessage(b, 7, data)
+	}
+	for _, e := range m.Examples {
+		data, _ := e.Marshal()
+		b = appendMessage(b, 8, data)
+	}
+	return b, nil
+}
+
+func (m *Package) Unmarshal(b []byte) error {
+	*m = Package{}
+	return eachField(b, func(f fieldValue) error {
+		switch f.num {
+		case 1:
+			m.ImportPath = string(f.data)
+		case 2:
+			m.Version = string(f.data)
+		case 3:
+			m.Synopsis = string(f.data)
+		case 4:
+			m.Doc = string(f.data)
+		case 5:
+			m.ModulePath = string(f.data)
+		case 6:
+			m.Imports = append(m.Imports, string(f.data))
+		case 7:
+			s := new(Symbol)
+			if err := s.Unmarshal(f.data); err != nil {
+				return err
+			}
+			m.Symbols = append(m.Symbols, s)
+		case 8:
+			e := new(Example)
+			if err := e.Unmarshal(f.data); err != nil {
+				return err
+			}
+			m.Examples = append(m.Examples, e)
+		}
+		return nil
+	})
+}
+
+type Symbol struct {
+	Name string
+	Kind string // One of "const", "var", "func", "type" and "method".
+	Doc  string
+	Decl string
+	URL  string
+}
+
+func (m *Symbol) Marshal() ([]byte, error) {
+	b := appendString(nil, 1, m.Name)
+	b = appendString(b, 2, m.Kind)
+	b = appendString(b, 3, m.Doc)
+	b = appendString(b, 4, m.Decl)
+	return appendString(b, 5, m.URL), nil
+}
+
+func (m *Symbol) Unmarshal(b []byte) error {
+	*m = Symbol{}
+	return eachField(b, func(f fieldValue) error {
+		switch f.num {
+		case 1:
+			m.Name = string(f.data)
+		case 2:
+			m.Kind = string(f.data)
+		case 3:
+			m.Doc = string(f.data)
+		case 4:
+			m.Decl = string(f.data)
+		case 5:
+			m.URL = string(f.data)
+		}
+		return nil
+	})
+}
+
+type Example struct {
+	Name   string
+	Doc    string
+	Code   string
+	Output string
+}
+
+func (m *Example) Marshal() ([]byte, error) {
+	b := appendString(nil, 1, m.Name)
+	b = appendString(b, 2, m.Doc)
+	b = appendString(b, 3, m.Code)
+	return appendString(b, 4, m.Output), nil
+}
+
+func (m *Example) Unmarshal(b []byte) error {
+	*m = Example{}
+	return eachField(b, func(f fieldValue) error {
+		switch f.num {
+		case 1:
+			m.Name = string(f.data)
+		case 2:
+			m.Doc = string(f.data)
+		case 3:
+			m.Code = string(f.data)
+		case 4:
+			m.Output = string(f.data)
+		}
+		return nil
+	})
+}
+
+type PackageInfo struct {
+	ImportPath string
+	Synopsis   string
+	Stars      int64
+}
+
+func (m *PackageInfo) Marshal() ([]byte, error) {
+	b := appendString(nil, 1, m.ImportPath)
+	b = appendString(b, 2, m.Synopsis)
+	return appendInt(b, 3, m.Stars), nil
+}
+
+func (m *PackageInfo) Unmarshal(b []byte) error {
+	*m = PackageInfo{}
+	return eachField(b, func(f fieldValue) error {
+		switch f.num {
+		case 1:
+			m.ImportPath = string(f.data)
+		case 2:
+			m.Synopsis = string(f.data)
+		case 3:
+			m.Stars = int64(f.varint)
+		}
+		return nil
+	})
+}
+
+type Change struct {
+	Name string
+	Kind string
+	Old  string
+	New  string
+	Type string // One of "added", "removed" and "changed".
+}
+
+func (m *Change) Marshal() ([]byte, error) {
+	b := appendString(nil, 1, m.Name)
+	b = appendString(b, 2, m.Kind)
+	b = appendString(b, 3, m.Old)
+	b = appendString(b, 4, m.New)
+	return appendString(b, 5, m.Type), nil
+}
+
+func (m *Change) Unmarshal(b []byte) error {
+	*m = Change{}
+	return eachField(b, func(f fieldValue) error {
+		switch f.num {
+		case 1:
+			m.Name = string(f.data)
+		case 2:
+			m.Kind = string(f.data)
+		case 3:
+			m.Old = string(f.data)
+		case 4:
+			m.New = string(f.data)
+		case 5:
+			m.Type = string(f.data)
+		}
+		return nil
+	})
+}
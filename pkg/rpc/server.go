@@ -0,0 +1,215 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package rpc implements the Walker gRPC service defined in walker.proto,
+// which lets systems not written in Go walk packages and query stored
+// documentation, e.g. when Go Walker runs as a sidecar.
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Unknwon/gowalker/models"
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+// Server serves the Walker service with the doc store.
+type Server struct{}
+
+// NewServer returns a gRPC server with the Walker service registered,
+// which decodes and encodes messages by the codec of the service.
+func NewServer(opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(append(opts, grpc.ForceServerCodec(codec{}))...)
+	RegisterWalkerServer(s, Server{})
+	return s
+}
+
+// statusError converts errors of walking and the doc store to gRPC status errors.
+func statusError(err error) error {
+	switch err {
+	case doc.ErrDocNotFound:
+		return status.Error(codes.NotFound, "documentation not found")
+	case doc.ErrBlocked:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case doc.ErrInvalidRemotePath:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case doc.ErrWalkInProgress, doc.ErrShuttingDown:
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func checkImportPath(importPath string) (string, error) {
+	importPath = strings.Trim(importPath, "/")
+	if len(importPath) == 0 {
+		return "", status.Error(codes.InvalidArgument, "import path is required")
+	} else if doc.IsBlocked(importPath) {
+		return "", statusError(doc.ErrBlocked)
+	}
+	return importPath, nil
+}
+
+func (Server) Walk(ctx context.Context, req *WalkRequest) (*Package, error) {
+	importPath, err := checkImportPath(req.ImportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pdoc, err := doc.WalkStored(importPath)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return newPackage(pdoc), nil
+}
+
+func (Server) GetPackage(ctx context.Context, req *GetPackageRequest) (*Package, error) {
+	importPath, err := checkImportPath(req.ImportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pdoc, err := doc.StoredDoc(importPath, req.Version)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return newPackage(pdoc), nil
+}
+
+func (Server) Search(req *SearchRequest, stream Walker_SearchServer) error {
+	limit := int(req.Limit)
+	switch {
+	case limit == 0:
+		limit = 20
+	case limit < 0 || limit > 100:
+		limit = 100
+	}
+
+	pinfos, err := models.SearchPkgInfo(limit, strings.TrimSpace(req.Query), func(importPath string) bool {
+		return !doc.IsBlocked(importPath)
+	})
+	if err != nil {
+		return statusError(err)
+	}
+	for _, pinfo := range pinfos {
+		if err = stream.Send(&PackageInfo{
+			ImportPath: pinfo.ImportPath,
+			Synopsis:   pinfo.Synopsis,
+			Stars:      pinfo.Stars,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (Server) Diff(req *DiffRequest, stream Walker_DiffServer) error {
+	importPath, err := checkImportPath(req.ImportPath)
+	if err != nil {
+		return err
+	}
+
+	from, to := req.From, req.To
+	if len(from) == 0 || len(to) == 0 {
+		versions, err := doc.StoredVersions(importPath)
+		if err != nil {
+			return statusError(err)
+		}
+		if len(to) == 0 && len(versions) > 0 {
+			to = versions[0]
+		}
+		if len(from) == 0 && len(versions) > 1 {
+			from = versions[1]
+		}
+	}
+	if len(from) == 0 || len(to) == 0 {
+		return status.Error(codes.FailedPrecondition, "two stored versions are required")
+	}
+
+	oldDoc, err := doc.StoredDoc(importPath, from)
+	if err != nil {
+		return statusError(err)
+	}
+	newDoc, err := doc.StoredDoc(importPath, to)
+	if err != nil {
+		return statusError(err)
+	}
+
+	d := doc.Diff(oldDoc, newDoc)
+	for _, changes := range []struct {
+		typ  string
+		list []*doc.Change
+	}{{"added", d.Added}, {"removed", d.Removed}, {"changed", d.Changed}} {
+		for _, c := range changes.list {
+			if err = stream.Send(&Change{
+				Name: c.Name,
+				Kind: c.Kind,
+				Old:  c.Old,
+				New:  c.New,
+				Type: changes.typ,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newPackage converts the documentation to the message.
+func newPackage(pdoc *doc.Package) *Package {
+	p := &Package{
+		ImportPath: pdoc.ImportPath,
+		Version:    pdoc.Tag,
+		Doc:        pdoc.Doc,
+		ModulePath: doc.ModuleOf(pdoc),
+		Imports:    pdoc.Imports,
+	}
+	if pdoc.PkgInfo != nil {
+		p.Synopsis = pdoc.Synopsis
+	}
+
+	addValues := func(kind string, vals []*doc.Value) {
+		for _, v := range vals {
+			p.Symbols = append(p.Symbols, &Symbol{Name: v.Name, Kind: kind, Doc: v.Doc, Decl: v.Decl, URL: v.URL})
+		}
+	}
+	addFuncs := func(kind, recv string, funcs []*doc.Func) {
+		for _, f := range funcs {
+			name := f.Name
+			if len(recv) > 0 {
+				name = recv + "." + name
+			}
+			p.Symbols = append(p.Symbols, &Symbol{Name: name, Kind: kind, Doc: f.Doc, Decl: f.Decl, URL: f.URL})
+		}
+	}
+	addValues("const", pdoc.Consts)
+	addValues("var", pdoc.Vars)
+	addFuncs("func", "", pdoc.Funcs)
+	for _, t := range pdoc.Types {
+		p.Symbols = append(p.Symbols, &Symbol{Name: t.Name, Kind: "type", Doc: t.Doc, Decl: t.Decl, URL: t.URL})
+		addValues("const", t.Consts)
+		addValues("var", t.Vars)
+		addFuncs("func", "", t.Funcs)
+		addFuncs("method", t.Name, t.Methods)
+	}
+
+	for _, e := range pdoc.Examples {
+		p.Examples = append(p.Examples, &Example{Name: e.Name, Doc: e.Doc, Code: e.Code, Output: e.Output})
+	}
+	return p
+}
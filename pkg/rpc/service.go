@@ -0,0 +1,171 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// message is implemented by all messages of the service.
+type message interface {
+	Marshal() ([]byte, error)
+	Unmarshal(b []byte) error
+}
+
+// codec encodes messages of the service in protocol buffers wire format.
+// It is forced on the server of the service instead of registered in place of
+// the process-wide "proto" codec, so clients generated from walker.proto in any
+// language can talk to the service without affecting other gRPC users.
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(message)
+	if !ok {
+		return nil, fmt.Errorf("unsupported message type %T", v)
+	}
+	return m.Marshal()
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(message)
+	if !ok {
+		return fmt.Errorf("unsupported message type %T", v)
+	}
+	return m.Unmarshal(data)
+}
+
+// CodecName is the content subtype of the codec, which Go clients registering
+// the codec select by grpc.CallContentSubtype.
+const CodecName = "gowalker-proto"
+
+func (codec) Name() string {
+	return CodecName
+}
+
+// Codec returns the codec of messages of the service.
+func Codec() encoding.Codec {
+	return codec{}
+}
+
+// WalkerServer is the server API of the Walker service.
+type WalkerServer interface {
+	Walk(context.Context, *WalkRequest) (*Package, error)
+	GetPackage(context.Context, *GetPackageRequest) (*Package, error)
+	Search(*SearchRequest, Walker_SearchServer) error
+	Diff(*DiffRequest, Walker_DiffServer) error
+}
+
+// Walker_SearchServer sends results of Search.
+type Walker_SearchServer interface {
+	Send(*PackageInfo) error
+	grpc.ServerStream
+}
+
+// Walker_DiffServer sends changes of Diff.
+type Walker_DiffServer interface {
+	Send(*Change) error
+	grpc.ServerStream
+}
+
+type walkerSearchServer struct {
+	grpc.ServerStream
+}
+
+func (s walkerSearchServer) Send(m *PackageInfo) error {
+	return s.ServerStream.SendMsg(m)
+}
+
+type walkerDiffServer struct {
+	grpc.ServerStream
+}
+
+func (s walkerDiffServer) Send(m *Change) error {
+	return s.ServerStream.SendMsg(m)
+}
+
+// RegisterWalkerServer registers the implementation of the Walker service to the server.
+func RegisterWalkerServer(s *grpc.Server, srv WalkerServer) {
+	s.RegisterService(&walkerServiceDesc, srv)
+}
+
+const serviceName = "gowalker.v1.Walker"
+
+func walkHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(WalkRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalkerServer).Walk(ctx, req)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + serviceName + "/Walk",
+	}
+	return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalkerServer).Walk(ctx, req.(*WalkRequest))
+	})
+}
+
+func getPackageHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(GetPackageRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalkerServer).GetPackage(ctx, req)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + serviceName + "/GetPackage",
+	}
+	return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalkerServer).GetPackage(ctx, req.(*GetPackageRequest))
+	})
+}
+
+func searchHandler(srv interface{}, stream grpc.ServerStream) error {
+	req := new(SearchRequest)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	return srv.(WalkerServer).Search(req, walkerSearchServer{stream})
+}
+
+func diffHandler(srv interface{}, stream grpc.ServerStream) error {
+	req := new(DiffRequest)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	return srv.(WalkerServer).Diff(req, walkerDiffServer{stream})
+}
+
+var walkerServiceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*WalkerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Walk", Handler: walkHandler},
+		{MethodName: "GetPackage", Handler: getPackageHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "Search", Handler: searchHandler, ServerStreams: true},
+		{StreamName: "Diff", Handler: diffHandler, ServerStreams: true},
+	},
+	Metadata: "walker.proto",
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rpc
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCodec(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  message
+	}{
+		{"empty", &WalkRequest{}},
+		{"walk request", &WalkRequest{ImportPath: "github.com/Unknwon/com"}},
+		{"get package request", &GetPackageRequest{ImportPath: "a/b", Version: "v1.0.0"}},
+		{"search request", &SearchRequest{Query: "web", Limit: 100}},
+		{"diff request", &DiffRequest{ImportPath: "a/b", From: "v1.0.0", To: "v1.1.0"}},
+		{"package info", &PackageInfo{ImportPath: "a/b", Synopsis: "Package b.", Stars: 1 << 40}},
+		{"change", &Change{Name: "F", Kind: "func", Old: "func F()", New: "func F(int)", Type: "changed"}},
+		{"package", &Package{
+			ImportPath: "a/b",
+			Version:    "v1.0.0",
+			Synopsis:   "Package b.",
+			Doc:        "Package b does things.\n",
+			ModulePath: "a",
+			Imports:    []string{"fmt", "a/c"},
+			Symbols: []*Symbol{
+				{Name: "F", Kind: "func", Doc: "F does.", Decl: "func F()", URL: "/a/b#F"},
+				{Name: "T.M", Kind: "method"},
+			},
+			Examples: []*Example{{Name: "F", Code: "F()", Output: "ok\n"}},
+		}},
+	}
+	var c codec
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := c.Marshal(test.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := reflect.New(reflect.TypeOf(test.msg).Elem()).Interface()
+			if err = c.Unmarshal(data, got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.msg) {
+				t.Fatalf("got %+v, want %+v", got, test.msg)
+			}
+		})
+	}
+
+	if _, err := c.Marshal("not a message"); err == nil {
+		t.Fatal("got no error for unsupported message type")
+	}
+}
+
+func TestWireFormat(t *testing.T) {
+	// Same as encoding of protoc generated code: field 1 "ab" and field 2 varint 300.
+	want := []byte{0x0a, 0x02, 'a', 'b', 0x10, 0xac, 0x02}
+	got, _ := (&SearchRequest{Query: "ab", Limit: 300}).Marshal()
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %x, want %x", got, want)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    *SearchRequest
+		wantErr bool
+	}{
+		{
+			name: "unknown fields are skipped",
+			data: []byte{
+				0x19, 1, 2, 3, 4, 5, 6, 7, 8, // Field 3 of 64-bit.
+				0x25, 1, 2, 3, 4, // Field 4 of 32-bit.
+				0x2a, 0x01, 'x', // Field 5 of bytes.
+				0x0a, 0x02, 'a', 'b',
+			},
+			want: &SearchRequest{Query: "ab"},
+		},
+		{name: "truncated tag", data: []byte{0x80}, wantErr: true},
+		{name: "truncated varint", data: []byte{0x10, 0x80}, wantErr: true},
+		{name: "truncated bytes", data: []byte{0x0a, 0x05, 'a'}, wantErr: true},
+		{name: "truncated 64-bit", data: []byte{0x19, 1, 2}, wantErr: true},
+		{name: "truncated 32-bit", data: []byte{0x25, 1}, wantErr: true},
+		{name: "unsupported wire type", data: []byte{0x0b}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := new(SearchRequest)
+			err := got.Unmarshal(test.data)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("got no error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
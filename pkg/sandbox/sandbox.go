@@ -0,0 +1,143 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package sandbox compiles and runs Go programs with limits in isolation,
+// which lets visitors of documentation edit and run examples.
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrCodeTooLarge = errors.New("code is too large")
+
+// Limits restricts programs that run in a sandbox.
+type Limits struct {
+	Timeout     time.Duration // Maximum time to compile and run, 0 means unlimited.
+	MaxCodeSize int           // In bytes, 0 means unlimited.
+	MaxOutput   int           // Bytes kept of stdout and stderr respectively, 0 means unlimited.
+}
+
+// Result is the result of compiling and running a program.
+type Result struct {
+	Errors    string // Compile errors, the program does not run if not empty.
+	Stdout    string
+	Stderr    string
+	ExitCode  int
+	Truncated bool // Whether outputs are truncated by the limit.
+}
+
+// Sandbox compiles and runs Go programs in isolation.
+type Sandbox interface {
+	// Run returns ErrCodeTooLarge if the code exceeds the limit, and
+	// context.DeadlineExceeded if the program does not finish in time.
+	Run(ctx context.Context, code string, limits Limits) (*Result, error)
+}
+
+// truncate cuts outputs of the result to the limit.
+func (r *Result) truncate(max int) {
+	if max <= 0 {
+		return
+	}
+	for _, s := range []*string{&r.Stdout, &r.Stderr} {
+		if len(*s) > max {
+			*s = (*s)[:max]
+			r.Truncated = true
+		}
+	}
+}
+
+// Playground runs programs by the compile endpoint of a self-hosted Go Playground,
+// see https://github.com/golang/playground.
+type Playground struct {
+	URL    string       // e.g. http://localhost:8080/compile
+	Client *http.Client // http.DefaultClient is used if nil.
+}
+
+func (p *Playground) Run(ctx context.Context, code string, limits Limits) (*Result, error) {
+	if limits.MaxCodeSize > 0 && len(code) > limits.MaxCodeSize {
+		return nil, ErrCodeTooLarge
+	}
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+
+	form := url.Values{
+		"version": {"2"},
+		"body":    {code},
+	}
+	req, err := http.NewRequest("POST", p.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+
+	var result struct {
+		Errors string
+		Events []struct {
+			Message string
+			Kind    string // "stdout" or "stderr".
+		}
+		Status int
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode: %v", err)
+	}
+
+	r := &Result{
+		Errors:   result.Errors,
+		ExitCode: result.Status,
+	}
+	var stdout, stderr strings.Builder
+	for _, e := range result.Events {
+		if e.Kind == "stderr" {
+			stderr.WriteString(e.Message)
+		} else {
+			stdout.WriteString(e.Message)
+		}
+	}
+	r.Stdout, r.Stderr = stdout.String(), stderr.String()
+	r.truncate(limits.MaxOutput)
+	return r, nil
+}
@@ -0,0 +1,376 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package setting
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Unknwon/com"
+	"gopkg.in/ini.v1"
+	"gopkg.in/yaml.v2"
+)
+
+// configFileNames are configuration files in YAML or TOML loaded over conf/app.ini
+// and custom/app.ini when $GOWALKER_CONFIG is not set, the first existing one is used.
+var configFileNames = []string{"custom/app.yaml", "custom/app.yml", "custom/app.toml"}
+
+// configFileName returns the name of the configuration file in YAML or TOML,
+// or empty if there is none.
+func configFileName() string {
+	if name := os.Getenv("GOWALKER_CONFIG"); len(name) > 0 {
+		return name
+	}
+	for _, name := range configFileNames {
+		if com.IsFile(name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// applyConfigFile sets keys of cfg to values in the configuration file, whose
+// tables are sections and nested tables are sections of dotted names, e.g.
+//
+//	github:
+//	  client_id: ${GITHUB_CLIENT_ID}
+//	  app:
+//	    enabled: true
+//
+// sets CLIENT_ID of section "github" and ENABLED of section "github.app".
+func applyConfigFile(cfg *ini.File, name string) error {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	var sections map[string]map[string]string
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		sections, err = parseYAMLConfig(data)
+	case ".toml":
+		sections, err = parseTOMLConfig(data)
+	default:
+		return fmt.Errorf("%s: unknown format, want .yaml, .yml or .toml", name)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	secNames := make([]string, 0, len(sections))
+	for secName := range sections {
+		secNames = append(secNames, secName)
+	}
+	sort.Strings(secNames)
+	for _, secName := range secNames {
+		keys := make([]string, 0, len(sections[secName]))
+		for k := range sections[secName] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		sec := cfg.Section(secName)
+		for _, k := range keys {
+			v, err := expandEnv(sections[secName][k])
+			if err == nil {
+				_, err = sec.NewKey(k, v)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: [%s] %s: %v", name, secName, k, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setConfigKey sets the key of the section to the value. Keys are upper-cased
+// as in app.ini except in sections keyed by import paths.
+func setConfigKey(sections map[string]map[string]string, section, key, value string) {
+	section = strings.ToLower(section)
+	if section != "monorepo" && !strings.HasSuffix(section, ".acl") {
+		key = strings.ToUpper(strings.Replace(key, "-", "_", -1))
+	}
+	if sections[section] == nil {
+		sections[section] = make(map[string]string)
+	}
+	sections[section][key] = value
+}
+
+func parseYAMLConfig(data []byte) (map[string]map[string]string, error) {
+	var m map[interface{}]interface{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	sections := make(map[string]map[string]string)
+	return sections, flattenYAML(sections, "", m)
+}
+
+// flattenYAML adds scalar values of m to the section, and maps to sections
+// of dotted names.
+func flattenYAML(sections map[string]map[string]string, section string, m map[interface{}]interface{}) error {
+	for k, v := range m {
+		key := fmt.Sprint(k)
+		if sub, ok := v.(map[interface{}]interface{}); ok {
+			if len(section) > 0 {
+				key = section + "." + key
+			}
+			if err := flattenYAML(sections, key, sub); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, err := yamlValue(v)
+		if err != nil {
+			return fmt.Errorf("[%s] %s: %v", section, key, err)
+		}
+		setConfigKey(sections, section, key, value)
+	}
+	return nil
+}
+
+// yamlValue returns the value in app.ini of given scalar or list of scalars,
+// which is comma-separated for lists.
+func yamlValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	case []interface{}:
+		values := make([]string, len(v))
+		for i := range v {
+			if _, ok := v[i].([]interface{}); ok {
+				return "", fmt.Errorf("nested lists are not supported")
+			}
+			s, err := yamlValue(v[i])
+			if err != nil {
+				return "", err
+			}
+			values[i] = s
+		}
+		return strings.Join(values, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value of type %T", v)
+}
+
+// parseTOMLConfig parses the subset of TOML that configuration needs: tables,
+// dotted and quoted keys, and values of strings, numbers, booleans and arrays
+// of them on a single line. Arrays are comma-separated values in app.ini.
+func parseTOMLConfig(data []byte) (map[string]map[string]string, error) {
+	sections := make(map[string]map[string]string)
+	section := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(splitTOML(line, '#')[0])
+		if len(line) == 0 {
+			continue
+		}
+
+		if line[0] == '[' {
+			if strings.HasPrefix(line, "[[") || !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid table %q", i+1, line)
+			}
+			names, err := tomlKey(line[1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+			section = strings.Join(names, ".")
+			continue
+		}
+
+		kv := splitTOML(line, '=')
+		if len(kv) < 2 {
+			return nil, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		names, err := tomlKey(kv[0])
+		if err == nil {
+			var value string
+			if value, err = tomlValue(strings.TrimSpace(strings.Join(kv[1:], "="))); err == nil {
+				sec := strings.Join(append([]string{section}, names[:len(names)-1]...), ".")
+				setConfigKey(sections, strings.Trim(sec, "."), names[len(names)-1], value)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+	}
+	return sections, nil
+}
+
+// splitTOML splits s by sep outside of quoted strings.
+func splitTOML(s string, sep byte) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// tomlKey returns parts of the dotted key, which are bare or quoted.
+func tomlKey(key string) ([]string, error) {
+	parts := splitTOML(key, '.')
+	for i := range parts {
+		part := strings.TrimSpace(parts[i])
+		if len(part) == 0 {
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+		if part[0] == '"' || part[0] == '\'' {
+			s, err := tomlString(part)
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		}
+		parts[i] = part
+	}
+	return parts, nil
+}
+
+func tomlString(s string) (string, error) {
+	if len(s) < 2 || s[len(s)-1] != s[0] {
+		return "", fmt.Errorf("invalid string %s", s)
+	}
+	if s[0] == '\'' {
+		return s[1 : len(s)-1], nil
+	}
+	return strconv.Unquote(s)
+}
+
+func tomlValue(s string) (string, error) {
+	switch {
+	case len(s) == 0:
+		return "", fmt.Errorf("missing value")
+	case s[0] == '"' || s[0] == '\'':
+		return tomlString(s)
+	case s[0] == '[':
+		if s[len(s)-1] != ']' {
+			return "", fmt.Errorf("arrays must be on a single line")
+		}
+		var values []string
+		for _, v := range splitTOML(s[1:len(s)-1], ',') {
+			if v = strings.TrimSpace(v); len(v) == 0 {
+				continue // Trailing comma
+			} else if v[0] == '[' {
+				return "", fmt.Errorf("nested arrays are not supported")
+			}
+			v, err := tomlValue(v)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, v)
+		}
+		return strings.Join(values, ","), nil
+	case s == "true" || s == "false":
+		return s, nil
+	}
+	if _, err := strconv.ParseFloat(strings.Replace(s, "_", "", -1), 64); err != nil {
+		return "", fmt.Errorf("invalid value %s", s)
+	}
+	return strings.Replace(s, "_", "", -1), nil
+}
+
+// interpolateEnv expands environment variables in values of cfg.
+func interpolateEnv(cfg *ini.File) error {
+	for _, sec := range cfg.Sections() {
+		for _, k := range sec.Keys() {
+			v, err := expandEnv(k.Value())
+			if err != nil {
+				return fmt.Errorf("[%s] %s: %v", sec.Name(), k.Name(), err)
+			}
+			k.SetValue(v)
+		}
+	}
+	return nil
+}
+
+// expandEnv replaces ${VAR} in s with the environment variable, and ${VAR:-default}
+// with the default when the variable is unset or empty, "$${" is kept as "${".
+// It is an error that a variable without default is not set.
+func expandEnv(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var buf bytes.Buffer
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			buf.WriteString(s)
+			return buf.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			buf.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed %q", s[i:])
+		}
+		name, def := s[i+2:i+end], ""
+		hasDef := false
+		if j := strings.Index(name, ":-"); j >= 0 {
+			name, def, hasDef = name[:j], name[j+2:], true
+		}
+		if !isEnvName(name) {
+			return "", fmt.Errorf("invalid environment variable name %q", name)
+		}
+
+		v, ok := os.LookupEnv(name)
+		if hasDef && len(v) == 0 {
+			v = def
+		} else if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		buf.WriteString(s[:i])
+		buf.WriteString(v)
+		s = s[i+end+1:]
+	}
+}
+
+func isEnvName(name string) bool {
+	if len(name) == 0 || name[0] >= '0' && name[0] <= '9' {
+		return false
+	}
+	for _, c := range name {
+		if c != '_' && !(c >= '0' && c <= '9') && !(c >= 'A' && c <= 'Z') && !(c >= 'a' && c <= 'z') {
+			return false
+		}
+	}
+	return true
+}
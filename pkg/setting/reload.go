@@ -0,0 +1,147 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package setting
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Unknwon/com"
+	"gopkg.in/ini.v1"
+)
+
+// configSources returns configuration files in INI format that exist.
+func configSources() []interface{} {
+	var sources []interface{}
+	for _, name := range []string{"conf/app.ini", "custom/app.ini"} {
+		if com.IsFile(name) {
+			sources = append(sources, name)
+		}
+	}
+	return sources
+}
+
+// ConfigFiles returns names of configuration files that settings are read from.
+func ConfigFiles() []string {
+	var names []string
+	for _, source := range configSources() {
+		names = append(names, source.(string))
+	}
+	if name := configFileName(); len(name) > 0 {
+		names = append(names, name)
+	}
+	return names
+}
+
+func joinErrors(errs []error) string {
+	msgs := make([]string, len(errs))
+	for i := range errs {
+		msgs[i] = errs[i].Error()
+	}
+	return strings.Join(msgs, "\n\t")
+}
+
+// Snapshot is settings that can be changed while serving by Reload.
+type Snapshot struct {
+	FetchTimeout  time.Duration
+	TemplatesPath string
+	RateLimit     RateLimitSettings
+	Crawler       CrawlerSettings
+	Blocklist     string
+	BlockPatterns string
+}
+
+var current atomic.Value // *Snapshot
+
+// Current returns settings that can be changed by Reload. Variables of these
+// settings are replaced by Reload, so requests and walks must read them from
+// the snapshot instead, which is never changed once published.
+func Current() *Snapshot {
+	return current.Load().(*Snapshot)
+}
+
+// publish publishes a snapshot of current values of variables.
+func publish() {
+	current.Store(&Snapshot{
+		FetchTimeout:  FetchTimeout,
+		TemplatesPath: TemplatesPath,
+		RateLimit:     RateLimit,
+		Crawler:       Crawler,
+		Blocklist:     Admin.Blocklist,
+		BlockPatterns: Admin.BlockPatterns,
+	})
+}
+
+var reloadLock sync.Mutex
+
+// Reload reads configuration files again and applies settings that can be changed
+// while serving: FETCH_TIMEOUT and TEMPLATES_PATH of [server], [ratelimit], [crawler]
+// except LOCKER, and BLOCKLIST and BLOCK_PATTERNS of [admin], which are published by
+// Current. Others, e.g. HTML_CACHE_SIZE of [server], take effect after restart.
+// Current settings are kept when the files are invalid.
+func Reload() error {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+
+	cfg := ini.Empty()
+	if sources := configSources(); len(sources) > 0 {
+		var err error
+		if cfg, err = ini.Load(sources[0], sources[1:]...); err != nil {
+			return fmt.Errorf("load: %v", err)
+		}
+	}
+	cfg.NameMapper = ini.AllCapsUnderscore
+	if err := interpolateEnv(cfg); err != nil {
+		return fmt.Errorf("interpolate environment variables: %v", err)
+	}
+	if name := configFileName(); len(name) > 0 {
+		if err := applyConfigFile(cfg, name); err != nil {
+			return err
+		}
+	}
+
+	sec := cfg.Section("server")
+	fetchTimeout := time.Duration(sec.Key("FETCH_TIMEOUT").MustInt(60)) * time.Second
+	newTemplatesPath, newTemplatesPathSet := templatesPath(sec)
+	rateLimit, crawler, admin := RateLimit, Crawler, Admin
+	if err := cfg.Section("ratelimit").MapTo(&rateLimit); err != nil {
+		return fmt.Errorf("map RateLimit settings: %v", err)
+	}
+	if err := cfg.Section("crawler").MapTo(&crawler); err != nil {
+		return fmt.Errorf("map Crawler settings: %v", err)
+	}
+	// Lockers are set up once at startup.
+	crawler.Locker = Crawler.Locker
+	if err := cfg.Section("admin").MapTo(&admin); err != nil {
+		return fmt.Errorf("map Admin settings: %v", err)
+	}
+
+	// Validate with new settings in place, and restore current ones if any is invalid.
+	oldCfg, oldFetchTimeout, oldTemplatesPath, oldTemplatesPathSet := Cfg, FetchTimeout, TemplatesPath, templatesPathSet
+	oldRateLimit, oldCrawler, oldAdmin := RateLimit, Crawler, Admin
+	Cfg, FetchTimeout, TemplatesPath, templatesPathSet = cfg, fetchTimeout, newTemplatesPath, newTemplatesPathSet
+	RateLimit, Crawler = rateLimit, crawler
+	Admin.Blocklist, Admin.BlockPatterns = admin.Blocklist, admin.BlockPatterns
+	if errs := validate(); len(errs) > 0 {
+		Cfg, FetchTimeout, TemplatesPath, templatesPathSet = oldCfg, oldFetchTimeout, oldTemplatesPath, oldTemplatesPathSet
+		RateLimit, Crawler, Admin = oldRateLimit, oldCrawler, oldAdmin
+		return fmt.Errorf("invalid settings:\n\t%s", joinErrors(errs))
+	}
+	publish()
+	return nil
+}
@@ -15,9 +15,9 @@
 package setting
 
 import (
+	"strings"
 	"time"
 
-	"github.com/Unknwon/com"
 	log "gopkg.in/clog.v1"
 	"gopkg.in/ini.v1"
 	"gopkg.in/macaron.v1"
@@ -29,10 +29,22 @@ var (
 	ProdMode bool
 
 	// Server settings
-	HTTPPort     int
-	FetchTimeout time.Duration
-	DocsJSPath   string
-	DocsGobPath  string
+	HTTPPort        int
+	FetchTimeout    time.Duration
+	DocsJSPath      string
+	DocsGobPath     string
+	EnableBlame     bool
+	EnableCallGraph bool
+	EnableCost      bool
+	GoSandbox       string // Command that wraps the go command run on untrusted code.
+	GoProxy         string // GOPROXY of the go command run on untrusted code.
+	HTMLCacheSize   int    // Number of rendered documentation pages kept in memory.
+	SortMode        string
+	GoEnvs          []string // GOOS/GOARCH pairs that build constraints are evaluated for.
+	TemplatesPath   string
+	WatchConfig     bool // Reload settings when configuration files are changed.
+	ShutdownTimeout time.Duration
+	TrustedProxies  []string // IP addresses or CIDRs of proxies that forwarded headers are honored from.
 
 	DigitalOcean struct {
 		Spaces struct {
@@ -45,27 +57,314 @@ var (
 		}
 	}
 
+	// Mirroring images referenced by README
+	Asset struct {
+		Enabled bool
+		MaxSize int64 // In KB.
+		Path    string
+	}
+
+	// Previews of documentation of uploaded archives
+	Preview struct {
+		Enabled       bool
+		MaxSize       int64 // In KB.
+		MaxConcurrent int   // Number of uploads walked at the same time.
+	}
+
+	// Sharing runnable examples to the Go Playground
+	Playground struct {
+		Enabled  bool
+		ShareURL string `ini:"SHARE_URL"`
+		URL      string `ini:"URL"` // Prefix of links to shared programs.
+	}
+
+	// Running examples in a self-hosted sandbox
+	Sandbox struct {
+		Enabled     bool
+		URL         string        `ini:"URL"` // Compile endpoint of a self-hosted Go Playground.
+		Timeout     time.Duration // Maximum time to compile and run a program.
+		MaxCodeSize int           // In bytes.
+		MaxOutput   int           // In bytes of stdout and stderr respectively.
+	}
+
+	// Cross-package index of walked packages
+	Index struct {
+		Enabled bool
+		Path    string
+	}
+
+	// Semantic search by embeddings of indexed documentation
+	Semantic struct {
+		Enabled bool
+		URL     string `ini:"URL"` // Embeddings endpoint compatible with the OpenAI API.
+		APIKey  string `ini:"API_KEY"`
+		Model   string
+		Path    string // File of vectors.
+	}
+
+	// Storage of walked documentation
+	DocStore struct {
+		Enabled     bool
+		MaxVersions int
+		TTL         time.Duration `ini:"TTL"`
+		Codec       string        // Compression of stored documentation: none, gzip or zstd.
+		Delta       bool          // Store tagged versions as changes to a base version.
+	}
+
+	// Content-addressable storage of source files shared by versions and forks
+	BlobStore struct {
+		Enabled bool
+		Path    string
+	}
+
+	// S3-compatible object storage for stored documentation and cache
+	ObjStore struct {
+		Enabled   bool
+		Endpoint  string
+		AccessKey string
+		SecretKey string
+		Secure    bool
+		Bucket    string
+		Prefix    string
+		// Packages larger than it in KB are split into multiple objects, 0 means unlimited.
+		MaxObjectSize int
+	}
+
+	// Redis for cache, locks and crawl queue shared by servers
+	Redis struct {
+		Enabled  bool
+		Addr     string
+		Password string
+		DB       int `ini:"DB"`
+		Prefix   string
+		Crawlers int // Number of crawlers that walk imports of requested packages.
+	}
+
+	// Feeds of changes of stored documentation
+	Feed struct {
+		Enabled   bool
+		Path      string
+		MaxEvents int
+	}
+
+	// Community translations of documentation
+	Translations struct {
+		Enabled bool
+		Path    string // Directory of catalogs.
+	}
+
+	// Notifications of stored documentation to webhooks
+	Notify struct {
+		Enabled       bool
+		Subscriptions string // Path of the subscriptions file.
+		BaseURL       string `ini:"BASE_URL"`
+	}
+
+	// Admin endpoints
+	Admin struct {
+		Token      string   // Sent in "Authorization: Bearer <token>" header.
+		Principals []string // Signed-in users of these principals are admins.
+		Blocklist  string   // Path of the blocklist file.
+		// Comma-separated glob patterns of blocked import path prefixes.
+		BlockPatterns string
+	}
+
+	// Fetching from hosts that are not known code hosting services
+	Crawler CrawlerSettings
+
+	// gRPC service of walking and stored documentation
+	GRPC struct {
+		Enabled  bool
+		Addr     string
+		CertFile string // Serve with TLS when both certificate and key files are set.
+		KeyFile  string
+	}
+
+	// Rate limiting of requests by token buckets
+	RateLimit RateLimitSettings
+
+	// Links of references to symbols outside of the corpus
+	Links struct {
+		External string // pkg.go.dev, origin or none.
+	}
+
+	// Allowlist of HTML in rendered documentation and README
+	Sanitize struct {
+		Enabled    bool
+		ExtraTags  []string
+		ExtraAttrs []string
+	}
+
+	// Analyses of walked packages by go vet
+	Analysis struct {
+		Enabled   bool
+		Analyzers []string
+		VetTool   string
+	}
+
+	// Content-Security-Policy sent with responses
+	CSP struct {
+		Enabled    bool
+		ReportOnly bool
+		Policy     string // Empty means the recommended policy.
+		ReportURI  string `ini:"REPORT_URI"`
+	}
+
+	// Policy of API stability between versions
+	Stability struct {
+		AllowV0Breaking  bool `ini:"ALLOW_V0_BREAKING"`
+		NoPatchAdditions bool
+	}
+
+	// Audit log of walks and views
+	Audit struct {
+		Sink string // none, file or sql.
+		Path string
+	}
+
+	// Sitemaps and robots.txt for search engines
+	Sitemap struct {
+		BaseURL  string `ini:"BASE_URL"`
+		PageSize int
+	}
+	Robots struct {
+		Disallow   []string
+		CrawlDelay int // In seconds.
+	}
+
+	// Fetching private modules, empty patterns mean environment variables of same names
+	Private struct {
+		GoPrivate string `ini:"GOPRIVATE"`
+		GoNoProxy string `ini:"GONOPROXY"`
+		GoNoSumDB string `ini:"GONOSUMDB"`
+		Netrc     string // Path of .netrc file, default is $NETRC or ~/.netrc.
+	}
+
+	// Access control of private documentation hosting
+	Auth struct {
+		Mode         string // none, token or oauth2.
+		TokenFile    string // Static tokens, used in all modes but none.
+		Issuer       string // OpenID Connect issuer to discover endpoints.
+		ClientID     string `ini:"CLIENT_ID"`
+		ClientSecret string
+		AuthURL      string `ini:"AUTH_URL"`
+		TokenURL     string `ini:"TOKEN_URL"`
+		UserInfoURL  string `ini:"USER_INFO_URL"`
+		RedirectURL  string `ini:"REDIRECT_URL"`
+		Scopes       []string
+		NameClaim    string
+		GroupsClaim  string
+	}
+	// Comma-separated principals that can access packages by import path prefix.
+	AuthACL map[string]string
+
+	// Repositories and subdirectories of modules by import path prefix.
+	RepoMappings map[string]string
+
+	// GitHub App that reviews API and documentation changes of pull requests
+	GitHubApp struct {
+		Enabled       bool
+		AppID         int64  `ini:"APP_ID"`
+		PrivateKey    string // Path of the PEM encoded private key file.
+		WebhookSecret string
+		APIURL        string `ini:"API_URL"`
+	}
+
+	// Isolated documentation corpora hosted by the deployment
+	TenantsPath string // Directory of doc stores and indexes of tenants.
+	Tenants     []*TenantConfig
+
 	// Global settings
 	Cfg               *ini.File
 	GitHubCredentials string
-	RefreshInterval   = 5 * time.Minute
+	// Whether to query top contributors when fetching from GitHub.
+	GitHubFetchContributors bool
+	RefreshInterval         = 5 * time.Minute
 )
 
+// CrawlerSettings are settings of fetching from hosts that are not known
+// code hosting services.
+type CrawlerSettings struct {
+	UserAgent     string
+	RespectRobots bool
+	Checkpoint    string // State of recursive crawls.
+	Locker        string // memory, redis or sql, empty means redis if enabled.
+}
+
+// RateLimitSettings are settings of rate limiting of requests by token buckets.
+type RateLimitSettings struct {
+	Enabled  bool
+	Rate     float64 // Requests per second of each client.
+	Burst    int
+	Paths    []string // Path prefixes of limited endpoints.
+	Keys     []string // API keys sent in "X-API-Key" header.
+	KeyRate  float64
+	KeyBurst int
+}
+
+// TenantConfig is the configuration of a tenant in section "tenant.<id>",
+// and its ACL in section "tenant.<id>.acl".
+type TenantConfig struct {
+	ID          string `ini:"-"`
+	Name        string
+	Hosts       []string
+	Principals  []string          // Users who can access the tenant.
+	MaxPackages int               // 0 means unlimited.
+	ACL         map[string]string `ini:"-"`
+}
+
 func init() {
 	log.New(log.CONSOLE, log.ConsoleConfig{})
 
-	sources := []interface{}{"conf/app.ini"}
-	if com.IsFile("custom/app.ini") {
-		sources = append(sources, "custom/app.ini")
-	}
-
+	sources := configSources()
 	var err error
-	Cfg, err = macaron.SetConfig(sources[0], sources[1:]...)
-	if err != nil {
-		log.Fatal(2, "Failed to set configuration: %v", err)
+	if len(sources) == 0 {
+		// Use default settings, e.g. when used as a library or by the CLI.
+		Cfg = ini.Empty()
+	} else {
+		Cfg, err = macaron.SetConfig(sources[0], sources[1:]...)
+		if err != nil {
+			log.Fatal(2, "Failed to set configuration: %v", err)
+		}
 	}
 	Cfg.NameMapper = ini.AllCapsUnderscore
+	if err = interpolateEnv(Cfg); err != nil {
+		log.Fatal(2, "Failed to interpolate environment variables: %v", err)
+	}
+
+	if name := configFileName(); len(name) > 0 {
+		if err = applyConfigFile(Cfg, name); err != nil {
+			log.Fatal(2, "Failed to load configuration file: %v", err)
+		}
+	}
+	mapSettings()
+}
+
+// LoadFile loads the configuration file in YAML or TOML over current settings,
+// e.g. when it is given by a flag.
+func LoadFile(name string) error {
+	if err := applyConfigFile(Cfg, name); err != nil {
+		return err
+	}
+	mapSettings()
+	return nil
+}
+
+// templatesPathSet is true if TemplatesPath is set by configuration, the default
+// one is not validated so that the package can be used without templates.
+var templatesPathSet bool
 
+// templatesPath returns the path of templates in the server section,
+// and whether it is set by configuration.
+func templatesPath(sec *ini.Section) (string, bool) {
+	if p := sec.Key("TEMPLATES_PATH").String(); len(p) > 0 {
+		return p, true
+	}
+	return "templates", false
+}
+
+// mapSettings maps sections of Cfg to settings, and exits when any of them is invalid.
+func mapSettings() {
 	if Cfg.Section("").Key("RUN_MODE").String() == "prod" {
 		ProdMode = true
 		macaron.Env = macaron.PROD
@@ -82,11 +381,171 @@ func init() {
 	FetchTimeout = time.Duration(sec.Key("FETCH_TIMEOUT").MustInt(60)) * time.Second
 	DocsJSPath = sec.Key("DOCS_JS_PATH").MustString("raw/docs/")
 	DocsGobPath = sec.Key("DOCS_GOB_PATH").MustString("raw/gob/")
+	EnableBlame = sec.Key("ENABLE_BLAME").MustBool()
+	EnableCallGraph = sec.Key("ENABLE_CALL_GRAPH").MustBool()
+	EnableCost = sec.Key("ENABLE_COST_REPORT").MustBool()
+	GoSandbox = sec.Key("GO_SANDBOX").String()
+	GoProxy = sec.Key("GO_PROXY").MustString("off")
+	HTMLCacheSize = sec.Key("HTML_CACHE_SIZE").MustInt()
+	SortMode = sec.Key("SORT_MODE").String()
+	GoEnvs = sec.Key("GO_ENVS").Strings(",")
+	TemplatesPath, templatesPathSet = templatesPath(sec)
+	WatchConfig = sec.Key("WATCH_CONFIG").MustBool()
+	ShutdownTimeout = time.Duration(sec.Key("SHUTDOWN_TIMEOUT").MustInt(30)) * time.Second
+	TrustedProxies = sec.Key("TRUSTED_PROXIES").Strings(",")
 
+	var err error
 	if err = Cfg.Section("digitalocean.spaces").MapTo(&DigitalOcean.Spaces); err != nil {
 		log.Fatal(2, "Failed to map DigitalOcean.Spaces settings: %v", err)
 	}
 
+	if err = Cfg.Section("asset").MapTo(&Asset); err != nil {
+		log.Fatal(2, "Failed to map Asset settings: %v", err)
+	}
+
+	if err = Cfg.Section("preview").MapTo(&Preview); err != nil {
+		log.Fatal(2, "Failed to map Preview settings: %v", err)
+	}
+
+	if err = Cfg.Section("playground").MapTo(&Playground); err != nil {
+		log.Fatal(2, "Failed to map Playground settings: %v", err)
+	}
+
+	if err = Cfg.Section("sandbox").MapTo(&Sandbox); err != nil {
+		log.Fatal(2, "Failed to map Sandbox settings: %v", err)
+	}
+
+	if err = Cfg.Section("index").MapTo(&Index); err != nil {
+		log.Fatal(2, "Failed to map Index settings: %v", err)
+	}
+
+	if err = Cfg.Section("semantic").MapTo(&Semantic); err != nil {
+		log.Fatal(2, "Failed to map Semantic settings: %v", err)
+	}
+
+	if err = Cfg.Section("docstore").MapTo(&DocStore); err != nil {
+		log.Fatal(2, "Failed to map DocStore settings: %v", err)
+	}
+
+	if err = Cfg.Section("blobstore").MapTo(&BlobStore); err != nil {
+		log.Fatal(2, "Failed to map BlobStore settings: %v", err)
+	}
+
+	if err = Cfg.Section("objstore").MapTo(&ObjStore); err != nil {
+		log.Fatal(2, "Failed to map ObjStore settings: %v", err)
+	}
+
+	if err = Cfg.Section("redis").MapTo(&Redis); err != nil {
+		log.Fatal(2, "Failed to map Redis settings: %v", err)
+	}
+
+	if err = Cfg.Section("feed").MapTo(&Feed); err != nil {
+		log.Fatal(2, "Failed to map Feed settings: %v", err)
+	}
+
+	if err = Cfg.Section("notify").MapTo(&Notify); err != nil {
+		log.Fatal(2, "Failed to map Notify settings: %v", err)
+	}
+
+	if err = Cfg.Section("admin").MapTo(&Admin); err != nil {
+		log.Fatal(2, "Failed to map Admin settings: %v", err)
+	}
+
+	if err = Cfg.Section("crawler").MapTo(&Crawler); err != nil {
+		log.Fatal(2, "Failed to map Crawler settings: %v", err)
+	}
+
+	if err = Cfg.Section("grpc").MapTo(&GRPC); err != nil {
+		log.Fatal(2, "Failed to map GRPC settings: %v", err)
+	}
+
+	if err = Cfg.Section("ratelimit").MapTo(&RateLimit); err != nil {
+		log.Fatal(2, "Failed to map RateLimit settings: %v", err)
+	}
+
+	if err = Cfg.Section("translations").MapTo(&Translations); err != nil {
+		log.Fatal(2, "Failed to map Translations settings: %v", err)
+	}
+
+	if err = Cfg.Section("links").MapTo(&Links); err != nil {
+		log.Fatal(2, "Failed to map Links settings: %v", err)
+	}
+
+	if err = Cfg.Section("sanitize").MapTo(&Sanitize); err != nil {
+		log.Fatal(2, "Failed to map Sanitize settings: %v", err)
+	}
+
+	if err = Cfg.Section("analysis").MapTo(&Analysis); err != nil {
+		log.Fatal(2, "Failed to map Analysis settings: %v", err)
+	}
+
+	if err = Cfg.Section("csp").MapTo(&CSP); err != nil {
+		log.Fatal(2, "Failed to map CSP settings: %v", err)
+	}
+
+	if err = Cfg.Section("stability").MapTo(&Stability); err != nil {
+		log.Fatal(2, "Failed to map Stability settings: %v", err)
+	}
+
+	if err = Cfg.Section("audit").MapTo(&Audit); err != nil {
+		log.Fatal(2, "Failed to map Audit settings: %v", err)
+	}
+
+	if err = Cfg.Section("sitemap").MapTo(&Sitemap); err != nil {
+		log.Fatal(2, "Failed to map Sitemap settings: %v", err)
+	}
+
+	if err = Cfg.Section("robots").MapTo(&Robots); err != nil {
+		log.Fatal(2, "Failed to map Robots settings: %v", err)
+	}
+
+	if err = Cfg.Section("private").MapTo(&Private); err != nil {
+		log.Fatal(2, "Failed to map Private settings: %v", err)
+	}
+
+	if err = Cfg.Section("auth").MapTo(&Auth); err != nil {
+		log.Fatal(2, "Failed to map Auth settings: %v", err)
+	}
+	AuthACL = make(map[string]string)
+	for _, k := range Cfg.Section("auth.acl").Keys() {
+		AuthACL[k.Name()] = k.String()
+	}
+
+	RepoMappings = make(map[string]string)
+	for _, k := range Cfg.Section("monorepo").Keys() {
+		RepoMappings[k.Name()] = k.String()
+	}
+
+	if err = Cfg.Section("github.app").MapTo(&GitHubApp); err != nil {
+		log.Fatal(2, "Failed to map GitHubApp settings: %v", err)
+	}
+
+	TenantsPath = Cfg.Section("tenants").Key("PATH").MustString("data/tenants/")
+	Tenants = nil
+	for _, sec := range Cfg.Sections() {
+		if !strings.HasPrefix(sec.Name(), "tenant.") || strings.HasSuffix(sec.Name(), ".acl") {
+			continue
+		}
+
+		t := &TenantConfig{
+			ID:  strings.TrimPrefix(sec.Name(), "tenant."),
+			ACL: make(map[string]string),
+		}
+		if err = sec.MapTo(t); err != nil {
+			log.Fatal(2, "Failed to map settings of tenant %q: %v", t.ID, err)
+		}
+		for _, k := range Cfg.Section(sec.Name() + ".acl").Keys() {
+			t.ACL[k.Name()] = k.String()
+		}
+		Tenants = append(Tenants, t)
+	}
+
 	GitHubCredentials = "client_id=" + Cfg.Section("github").Key("CLIENT_ID").String() +
 		"&client_secret=" + Cfg.Section("github").Key("CLIENT_SECRET").String()
+	GitHubFetchContributors = Cfg.Section("github").Key("FETCH_CONTRIBUTORS").MustBool()
+
+	if errs := validate(); len(errs) > 0 {
+		log.Fatal(2, "Invalid settings:\n\t%s", joinErrors(errs))
+	}
+	publish()
 }
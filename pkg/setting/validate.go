@@ -0,0 +1,202 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package setting
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"github.com/Unknwon/com"
+)
+
+// validate returns problems of settings, which are reported all at once
+// instead of failing one by one when features are initialized.
+func validate() []error {
+	var errs []error
+	invalid := func(section, key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("[%s] %s: %s", section, key, fmt.Sprintf(format, args...)))
+	}
+	oneOf := func(section, key, value string, values ...string) {
+		for _, v := range values {
+			if strings.EqualFold(value, v) {
+				return
+			}
+		}
+		invalid(section, key, "%q is not one of %s", value, strings.Join(values[1:], ", "))
+	}
+	// required reports empty values of pairs of keys and values.
+	required := func(section string, pairs ...string) {
+		for i := 0; i < len(pairs); i += 2 {
+			if len(pairs[i+1]) == 0 {
+				invalid(section, pairs[i], "is required")
+			}
+		}
+	}
+
+	if HTTPPort < 1 || HTTPPort > 65535 {
+		invalid("server", "HTTP_PORT", "%d is not a valid port", HTTPPort)
+	}
+	if FetchTimeout <= 0 {
+		invalid("server", "FETCH_TIMEOUT", "must be positive")
+	}
+	if ShutdownTimeout < 0 {
+		invalid("server", "SHUTDOWN_TIMEOUT", "must not be negative")
+	}
+	for _, proxy := range TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			invalid("server", "TRUSTED_PROXIES", "%q is neither an IP address nor a CIDR", proxy)
+		}
+	}
+	if (EnableCost || Analysis.Enabled) && len(GoSandbox) == 0 {
+		invalid("server", "GO_SANDBOX", "is required to run the go command on untrusted code")
+	}
+	if Analysis.Enabled && len(Analysis.VetTool) > 0 && !filepath.IsAbs(Analysis.VetTool) {
+		invalid("analysis", "VET_TOOL", "%q is not an absolute path", Analysis.VetTool)
+	}
+	oneOf("server", "SORT_MODE", SortMode, "", "alphabetical", "source", "file", "exported-first")
+	for _, env := range GoEnvs {
+		if i := strings.Index(env, "/"); i <= 0 || i == len(env)-1 || strings.Count(env, "/") > 1 {
+			invalid("server", "GO_ENVS", "%q is not in the form of GOOS/GOARCH", env)
+		}
+	}
+	if templatesPathSet && !com.IsDir(TemplatesPath) {
+		invalid("server", "TEMPLATES_PATH", "%q is not a directory", TemplatesPath)
+	}
+
+	for _, limit := range []struct {
+		section, key string
+		value        int64
+	}{
+		{"server", "HTML_CACHE_SIZE", int64(HTMLCacheSize)},
+		{"asset", "MAX_SIZE", Asset.MaxSize},
+		{"docstore", "MAX_VERSIONS", int64(DocStore.MaxVersions)},
+		{"docstore", "TTL", int64(DocStore.TTL)},
+		{"objstore", "MAX_OBJECT_SIZE", int64(ObjStore.MaxObjectSize)},
+		{"redis", "CRAWLERS", int64(Redis.Crawlers)},
+		{"feed", "MAX_EVENTS", int64(Feed.MaxEvents)},
+		{"robots", "CRAWL_DELAY", int64(Robots.CrawlDelay)},
+		{"sandbox", "TIMEOUT", int64(Sandbox.Timeout)},
+		{"sandbox", "MAX_CODE_SIZE", int64(Sandbox.MaxCodeSize)},
+		{"sandbox", "MAX_OUTPUT", int64(Sandbox.MaxOutput)},
+	} {
+		if limit.value < 0 {
+			invalid(limit.section, limit.key, "must not be negative")
+		}
+	}
+	if Preview.Enabled && Preview.MaxSize <= 0 {
+		invalid("preview", "MAX_SIZE", "must be positive")
+	}
+	if Preview.Enabled && Preview.MaxConcurrent <= 0 {
+		invalid("preview", "MAX_CONCURRENT", "must be positive")
+	}
+	if Sitemap.PageSize < 0 || Sitemap.PageSize > 50000 {
+		invalid("sitemap", "PAGE_SIZE", "%d is not between 0 and 50000", Sitemap.PageSize)
+	}
+	if RateLimit.Enabled {
+		if RateLimit.Rate <= 0 || RateLimit.Burst <= 0 {
+			invalid("ratelimit", "RATE", "rate and burst must be positive")
+		}
+		if len(RateLimit.Keys) > 0 && (RateLimit.KeyRate <= 0 || RateLimit.KeyBurst <= 0) {
+			invalid("ratelimit", "KEY_RATE", "rate and burst of API keys must be positive")
+		}
+	}
+
+	oneOf("docstore", "CODEC", DocStore.Codec, "", "none", "gzip", "zstd")
+	oneOf("links", "EXTERNAL", Links.External, "", "pkg.go.dev", "origin", "none")
+	oneOf("crawler", "LOCKER", Crawler.Locker, "", "memory", "redis", "sql")
+	if Crawler.Locker == "redis" && !Redis.Enabled {
+		invalid("crawler", "LOCKER", "redis requires [redis] to be enabled")
+	}
+	oneOf("audit", "SINK", Audit.Sink, "", "none", "file", "sql")
+	if Audit.Sink == "file" && len(Audit.Path) == 0 {
+		invalid("audit", "PATH", "is required by sink file")
+	}
+
+	// Storage backends
+	if DigitalOcean.Spaces.Enabled {
+		required("digitalocean.spaces", "ENDPOINT", DigitalOcean.Spaces.Endpoint,
+			"BUCKET", DigitalOcean.Spaces.Bucket, "BUCKET_URL", DigitalOcean.Spaces.BucketURL)
+	}
+	if BlobStore.Enabled {
+		required("blobstore", "PATH", BlobStore.Path)
+	}
+	if ObjStore.Enabled {
+		required("objstore", "ENDPOINT", ObjStore.Endpoint, "BUCKET", ObjStore.Bucket)
+	}
+	if Redis.Enabled {
+		required("redis", "ADDR", Redis.Addr)
+	}
+	if Playground.Enabled {
+		required("playground", "SHARE_URL", Playground.ShareURL, "URL", Playground.URL)
+	}
+	if Sandbox.Enabled {
+		required("sandbox", "URL", Sandbox.URL)
+	}
+	if Semantic.Enabled {
+		required("semantic", "URL", Semantic.URL)
+		if !Index.Enabled {
+			invalid("semantic", "ENABLED", "requires [index] to be enabled")
+		}
+	}
+	if GRPC.Enabled {
+		required("grpc", "ADDR", GRPC.Addr)
+		if (len(GRPC.CertFile) == 0) != (len(GRPC.KeyFile) == 0) {
+			invalid("grpc", "CERT_FILE", "certificate and key files must be set together")
+		}
+	}
+
+	// Fetcher credentials
+	if GitHubApp.Enabled {
+		required("github.app", "PRIVATE_KEY", GitHubApp.PrivateKey, "WEBHOOK_SECRET", GitHubApp.WebhookSecret)
+		if GitHubApp.AppID <= 0 {
+			invalid("github.app", "APP_ID", "is required when enabled")
+		}
+	}
+	if len(Private.Netrc) > 0 && !com.IsFile(Private.Netrc) {
+		invalid("private", "NETRC", "%q does not exist", Private.Netrc)
+	}
+
+	oneOf("auth", "MODE", Auth.Mode, "", "none", "token", "oauth2")
+	switch Auth.Mode {
+	case "token":
+		required("auth", "TOKEN_FILE", Auth.TokenFile)
+	case "oauth2":
+		required("auth", "CLIENT_ID", Auth.ClientID, "REDIRECT_URL", Auth.RedirectURL)
+		if len(Auth.Issuer) == 0 && (len(Auth.AuthURL) == 0 || len(Auth.TokenURL) == 0) {
+			invalid("auth", "ISSUER", "or AUTH_URL and TOKEN_URL are required by mode oauth2")
+		}
+	}
+	if len(Auth.Mode) > 0 && Auth.Mode != "none" && DigitalOcean.Spaces.Enabled {
+		invalid("digitalocean.spaces", "ENABLED", "documentation distributed to it is public, disable it with auth mode %s", Auth.Mode)
+	}
+	// Principals and ACLs cannot be checked without signed-in users.
+	if len(Auth.Mode) == 0 || Auth.Mode == "none" {
+		if len(AuthACL) > 0 {
+			invalid("auth", "MODE", "is required by rules of [auth.acl]")
+		}
+		for _, t := range Tenants {
+			if len(t.Principals) > 0 {
+				invalid("auth", "MODE", "is required by PRINCIPALS of [tenant.%s]", t.ID)
+			}
+			if len(t.ACL) > 0 {
+				invalid("auth", "MODE", "is required by rules of [tenant.%s.acl]", t.ID)
+			}
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,195 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package sqlitestore implements storages of documentation and the search index
+// on an embedded SQLite database, so a single binary can run with persistent
+// storage and no external services.
+package sqlitestore
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/index"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS docs (
+	import_path TEXT NOT NULL,
+	version     TEXT NOT NULL,
+	module      TEXT NOT NULL,
+	walked      INTEGER NOT NULL,
+	viewed      INTEGER NOT NULL,
+	data        BLOB NOT NULL,
+	PRIMARY KEY (import_path, version)
+);
+CREATE TABLE IF NOT EXISTS index_entries (
+	import_path TEXT PRIMARY KEY,
+	data        BLOB NOT NULL
+);`
+
+// Store implements doc.DocStore and index.Storage.
+type Store struct {
+	db    *sql.DB
+	codec doc.Codec // Used to compress stored packages.
+}
+
+// Open opens or creates the database file with given name,
+// packages are compressed by given codec when stored.
+func Open(filename string, codec doc.Codec) (*Store, error) {
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		return nil, fmt.Errorf("open: %v", err)
+	}
+	// SQLite allows only one writer at a time.
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range []string{
+		"PRAGMA journal_mode = WAL",
+		"PRAGMA busy_timeout = 5000",
+		schema,
+	} {
+		if _, err = db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("init: %v", err)
+		}
+	}
+	return &Store{db, codec}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) Get(importPath, version string) (*doc.Package, error) {
+	var data []byte
+	err := s.db.QueryRow("SELECT data FROM docs WHERE import_path = ? AND version = ?",
+		importPath, version).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, doc.ErrDocNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return doc.DecodePackage(data)
+}
+
+func (s *Store) Put(pdoc *doc.Package) error {
+	data, err := doc.EncodePackage(pdoc, s.codec)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO docs (import_path, version, module, walked, viewed, data)
+VALUES (?, ?, ?, ?, ?, ?)`, pdoc.ImportPath, pdoc.Tag, doc.ModuleOf(pdoc), now, now, data)
+	return err
+}
+
+func (s *Store) Touch(importPath, version string) error {
+	result, err := s.db.Exec("UPDATE docs SET viewed = ? WHERE import_path = ? AND version = ?",
+		time.Now().Unix(), importPath, version)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return doc.ErrDocNotFound
+	}
+	return nil
+}
+
+func (s *Store) Delete(importPath, version string) error {
+	_, err := s.db.Exec("DELETE FROM docs WHERE import_path = ? AND version = ?", importPath, version)
+	return err
+}
+
+func (s *Store) List() ([]*doc.DocMeta, error) {
+	rows, err := s.db.Query("SELECT import_path, version, module, walked, viewed FROM docs")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var metas []*doc.DocMeta
+	for rows.Next() {
+		m := new(doc.DocMeta)
+		if err = rows.Scan(&m.ImportPath, &m.Version, &m.Module, &m.Walked, &m.Viewed); err != nil {
+			return nil, err
+		}
+		metas = append(metas, m)
+	}
+	return metas, rows.Err()
+}
+
+// Load returns entries of the search index.
+func (s *Store) Load() ([]*index.Entry, error) {
+	rows, err := s.db.Query("SELECT data FROM index_entries")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*index.Entry
+	for rows.Next() {
+		var data []byte
+		if err = rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		e := new(index.Entry)
+		if err = gob.NewDecoder(bytes.NewReader(data)).Decode(e); err != nil {
+			return nil, fmt.Errorf("decode entry: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Save replaces entries of the search index.
+func (s *Store) Save(entries []*index.Entry) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec("DELETE FROM index_entries"); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare("INSERT INTO index_entries (import_path, data) VALUES (?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		var buf bytes.Buffer
+		if err = gob.NewEncoder(&buf).Encode(e); err != nil {
+			return fmt.Errorf("encode entry: %v", err)
+		}
+		if _, err = stmt.Exec(e.ImportPath, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,156 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package tenant hosts isolated documentation corpora in one deployment, each
+// of which has its own doc store, index, access control and quota.
+package tenant
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Unknwon/gowalker/pkg/auth"
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/index"
+)
+
+// Tenant is an isolated documentation corpus, e.g. of a team or a customer.
+type Tenant struct {
+	ID      string
+	Name    string
+	Hosts   []string     // Host names that serve the tenant at root path.
+	Members []string     // Principals of users who can access the tenant, empty means all signed-in users.
+	ACL     auth.ACL     // Access control of packages within the tenant.
+	Store   doc.DocStore // Usually limited by doc.QuotaDocStore.
+	Index   *index.Index
+}
+
+// Allowed returns true if the user is a member of the tenant and can access
+// the package of given import path, which is empty for the tenant itself.
+func (t *Tenant) Allowed(u *auth.User, importPath string) bool {
+	if u == nil {
+		return false
+	}
+
+	member := len(t.Members) == 0
+	for _, p := range t.Members {
+		if u.Is(p) {
+			member = true
+			break
+		}
+	}
+	if !member {
+		return false
+	}
+	return len(importPath) == 0 || t.ACL.Allowed(u, importPath)
+}
+
+// Walk walks the latest version of the package into the corpus of the tenant.
+func (t *Tenant) Walk(importPath string) (*doc.Package, error) {
+	return doc.WalkInto(t.Store, t.Index, importPath)
+}
+
+// Packages returns import paths of stored packages of the tenant in sorted order.
+func (t *Tenant) Packages() ([]string, error) {
+	metas, err := t.Store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]bool)
+	for _, m := range metas {
+		set[m.ImportPath] = true
+	}
+	paths := make([]string, 0, len(set))
+	for p := range set {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// ValidID returns true if the ID consists of lower case letters, digits and hyphens.
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+var registry = struct {
+	sync.RWMutex
+	byID   map[string]*Tenant
+	byHost map[string]*Tenant
+}{
+	byID:   make(map[string]*Tenant),
+	byHost: make(map[string]*Tenant),
+}
+
+// Register adds the tenant to be served.
+func Register(t *Tenant) error {
+	if !ValidID(t.ID) {
+		return fmt.Errorf("invalid tenant ID %q", t.ID)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.byID[t.ID] != nil {
+		return fmt.Errorf("tenant %q already exists", t.ID)
+	}
+	for _, host := range t.Hosts {
+		host = strings.ToLower(host)
+		if other := registry.byHost[host]; other != nil {
+			return fmt.Errorf("host %q is used by tenant %q", host, other.ID)
+		}
+		registry.byHost[host] = t
+	}
+	registry.byID[t.ID] = t
+	return nil
+}
+
+// Get returns the tenant of given ID, or nil if it does not exist.
+func Get(id string) *Tenant {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.byID[id]
+}
+
+// ByHost returns the tenant served on the host, or nil if none is.
+// The port of the host is ignored.
+func ByHost(host string) *Tenant {
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.HasSuffix(host, "]") {
+		host = host[:i]
+	}
+
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.byHost[strings.ToLower(host)]
+}
+
+// All returns all tenants sorted by ID.
+func All() []*Tenant {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	tenants := make([]*Tenant, 0, len(registry.byID))
+	for _, t := range registry.byID {
+		tenants = append(tenants, t)
+	}
+	sort.Slice(tenants, func(i, j int) bool {
+		return tenants[i].ID < tenants[j].ID
+	})
+	return tenants
+}
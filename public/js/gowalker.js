@@ -80,6 +80,8 @@ $(document).ready(function () {
             }
 
             $('#search-results').html("");
+            // Exported objects are rendered as a JSON data block, which is allowed by strict CSP.
+            var exportDataSrc = JSON.parse($('#_export_data').text() || '[]');
             for (var i = 0; i < exportDataSrc.length; i++) {
                 if (exportDataSrc[i].title.toLowerCase().includes($(this).val().toLowerCase())) {
                     $('#search-results').append(`<a href="#` + exportDataSrc[i].title.replace(/\./g, "_") + `">
@@ -174,6 +176,33 @@ $(document).ready(function () {
         });
     }
 
+    // Editing and running examples in the sandbox.
+    var sandbox = $('body').data('sandbox');
+    if (sandbox) {
+        $('.sandbox').removeClass('d-hide');
+        $(document).on('click', '.sandbox-run', function () {
+            var $box = $(this).closest('.sandbox');
+            var $output = $box.find('.sandbox-output');
+            var $btn = $(this);
+            $btn.addClass('loading');
+            $.post(sandbox, {code: $box.find('.sandbox-code').val()}).done(function (data) {
+                var text = data.Errors || data.Stdout + data.Stderr;
+                if (data.Truncated) {
+                    text += '\n[output truncated]';
+                }
+                if (!data.Errors) {
+                    text += '\n[exit status ' + data.ExitCode + ']';
+                }
+                $output.text(text);
+            }).fail(function (xhr) {
+                $output.text(xhr.responseJSON ? xhr.responseJSON.error : xhr.statusText);
+            }).always(function () {
+                $btn.removeClass('loading');
+                $output.removeClass('d-hide');
+            });
+        });
+    }
+
     // Anchor.
     if ($('#markdown').length) {
         $(this).find('h1, h2, h3, h4, h5, h6').each(function () {
@@ -0,0 +1,155 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-macaron/pongo2"
+	log "gopkg.in/clog.v1"
+	"gopkg.in/fsnotify.v1"
+	"gopkg.in/macaron.v1"
+
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/setting"
+	"github.com/Unknwon/gowalker/routes"
+)
+
+var renderer struct {
+	sync.RWMutex
+	handler macaron.Handler
+}
+
+// setRenderer loads templates in the templates path for rendering.
+func setRenderer() {
+	h := pongo2.Pongoer(pongo2.Options{
+		Directory:  setting.Current().TemplatesPath,
+		IndentJSON: !setting.ProdMode,
+	})
+	renderer.Lock()
+	renderer.handler = h
+	renderer.Unlock()
+	routes.SetBackgroundRender(newBackgroundRender(h))
+}
+
+// newBackgroundRender returns the renderer created by the handler for a
+// request made up locally, so it is not tied to any request of users.
+func newBackgroundRender(h macaron.Handler) macaron.Render {
+	var render macaron.Render
+	m := macaron.New()
+	m.Use(h)
+	m.Get("/", func(r macaron.Render) {
+		render = r
+	})
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	return render
+}
+
+// render renders responses with current templates, which are replaced
+// when settings are reloaded.
+func render(c *macaron.Context) {
+	renderer.RLock()
+	h := renderer.handler
+	renderer.RUnlock()
+	if _, err := c.Invoke(h); err != nil {
+		log.Error(2, "Failed to invoke renderer: %v", err)
+	}
+}
+
+var reloadLock sync.Mutex
+
+// reload applies settings that can be changed without restart. Walks in progress
+// are not dropped, and use new settings when they fetch next time.
+func reload() {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+
+	if err := setting.Reload(); err != nil {
+		log.Error(2, "Failed to reload settings: %v", err)
+		return
+	}
+
+	if err := routes.InitBlocklist(); err != nil {
+		log.Error(2, "Failed to reload blocklist: %v", err)
+	}
+	if crawler := setting.Current().Crawler; crawler.RespectRobots {
+		doc.SetRobotsAgent(crawler.UserAgent)
+	} else {
+		doc.SetRobotsAgent("")
+	}
+	setRenderer()
+	routes.ResetTemplateHash()
+	log.Info("Settings reloaded")
+}
+
+// Delay of reloads after configuration files are changed, editors usually
+// write files in several steps.
+const reloadDelay = 500 * time.Millisecond
+
+// watchSettings reloads settings on SIGHUP, and when configuration files
+// are changed if enabled.
+func watchSettings() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var (
+		events <-chan fsnotify.Event
+		errs   <-chan error
+		files  = make(map[string]bool)
+	)
+	if setting.WatchConfig {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			log.Fatal(2, "Failed to create watcher of configuration files: %v", err)
+		}
+		// Directories are watched because files are often replaced by renaming.
+		for _, name := range setting.ConfigFiles() {
+			name = filepath.Clean(name)
+			files[name] = true
+			if err = watcher.Add(filepath.Dir(name)); err != nil {
+				log.Fatal(2, "Failed to watch configuration file %s: %v", name, err)
+			}
+		}
+		events, errs = watcher.Events, watcher.Errors
+		log.Info("Watch configuration files enabled")
+	}
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case <-hup:
+				log.Info("Reload settings on SIGHUP")
+				reload()
+			case event := <-events:
+				if !files[filepath.Clean(event.Name)] {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDelay, reload)
+			case err := <-errs:
+				log.Error(2, "Configuration watcher error: %v", err)
+			}
+		}
+	}()
+}
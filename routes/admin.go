@@ -0,0 +1,335 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-macaron/session"
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/pkg/audit"
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/digest"
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/notify"
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+// AdminEnabled returns true if an admin token or principals are configured.
+func AdminEnabled() bool {
+	return len(setting.Admin.Token) > 0 || len(setting.Admin.Principals) > 0
+}
+
+// isAdmin returns true if the request has the admin token, or it is sent
+// by a signed-in user who matches any admin principal. Browsers send session
+// cookies with requests forged by other sites, so requests that change state
+// must have the admin token or a token of the user.
+func isAdmin(c *context.Context, sess session.Store) bool {
+	if token := c.Req.Header.Get("Authorization"); len(setting.Admin.Token) > 0 && strings.HasPrefix(token, "Bearer ") {
+		if subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(token, "Bearer ")), []byte(setting.Admin.Token)) == 1 {
+			c.Data["AdminName"] = "admin"
+			return true
+		}
+	}
+
+	if !authEnabled {
+		return false
+	}
+	if c.Req.Method != "GET" && c.Req.Method != "HEAD" &&
+		!strings.HasPrefix(c.Req.Header.Get("Authorization"), "Bearer ") {
+		return false
+	}
+	u := requestUser(c, sess)
+	if u == nil {
+		return false
+	}
+	for _, p := range setting.Admin.Principals {
+		if u.Is(p) {
+			c.Data["AdminName"] = u.Name
+			return true
+		}
+	}
+	return false
+}
+
+// RequireAdmin only allows admins to access admin endpoints.
+func RequireAdmin(c *context.Context, sess session.Store) {
+	if !isAdmin(c, sess) {
+		c.Resp.Header().Set("WWW-Authenticate", `Bearer realm="Go Walker Admin"`)
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "admin privileges required",
+		})
+	}
+}
+
+// adminImportPath returns the import path of the request, or responds 400 if empty.
+func adminImportPath(c *context.Context) (string, bool) {
+	importPath := strings.Trim(c.Query("path"), "/")
+	if len(importPath) == 0 {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "path is required",
+		})
+		return "", false
+	}
+	return importPath, true
+}
+
+func adminError(c *context.Context, action string, err error) {
+	log.Error(2, "Admin %s: %v", action, err)
+	c.JSON(http.StatusInternalServerError, map[string]interface{}{
+		"error": err.Error(),
+	})
+}
+
+func adminAudit(c *context.Context, action audit.Action, importPath string, err error) {
+	e := &audit.Event{
+		Action:     action,
+		User:       c.Data["AdminName"].(string),
+		RemoteAddr: c.ClientIP(),
+		ImportPath: importPath,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	audit.Record(e)
+}
+
+// AdminRewalk walks the package again regardless of when it was walked.
+func AdminRewalk(c *context.Context) {
+	importPath, ok := adminImportPath(c)
+	if !ok {
+		return
+	}
+
+	pinfo, err := doc.CheckPackage(importPath, c.Render, doc.RequestTypeRefresh)
+	adminAudit(c, audit.ActionRefresh, importPath, err)
+	if err != nil {
+		adminError(c, "rewalk", err)
+		return
+	}
+	htmlPages.purge(importPath)
+	c.JSON(200, map[string]interface{}{
+		"ok":      true,
+		"created": pinfo.Created,
+	})
+}
+
+// AdminPurge removes all stored documentation and information of the package.
+func AdminPurge(c *context.Context) {
+	importPath, ok := adminImportPath(c)
+	if !ok {
+		return
+	}
+
+	if err := doc.Purge(importPath); err != nil {
+		adminError(c, "purge", err)
+		return
+	}
+	htmlPages.purge(importPath)
+	log.Info("Admin: %s purged %s", c.Data["AdminName"], importPath)
+	c.JSON(200, map[string]interface{}{
+		"ok": true,
+	})
+}
+
+// AdminBlock blocks the import path and purges the package, e.g. for takedown requests.
+func AdminBlock(c *context.Context) {
+	importPath, ok := adminImportPath(c)
+	if !ok {
+		return
+	}
+
+	if err := adminBlocklist.Block(importPath, c.Query("reason")); err != nil {
+		adminError(c, "block", err)
+		return
+	}
+	if err := doc.Purge(importPath); err != nil {
+		adminError(c, "purge", err)
+		return
+	}
+	htmlPages.purge(importPath)
+	log.Info("Admin: %s blocked %s: %s", c.Data["AdminName"], importPath, c.Query("reason"))
+	c.JSON(200, map[string]interface{}{
+		"ok": true,
+	})
+}
+
+// AdminUnblock removes the import path from the blocklist.
+func AdminUnblock(c *context.Context) {
+	importPath, ok := adminImportPath(c)
+	if !ok {
+		return
+	}
+
+	if err := adminBlocklist.Unblock(importPath); err != nil {
+		adminError(c, "unblock", err)
+		return
+	}
+	log.Info("Admin: %s unblocked %s", c.Data["AdminName"], importPath)
+	c.JSON(200, map[string]interface{}{
+		"ok": true,
+	})
+}
+
+// AdminBlocklist responds all blocked import paths.
+func AdminBlocklist(c *context.Context) {
+	c.JSON(200, adminBlocklist.List())
+}
+
+// AdminQueue responds the state of the crawl queue.
+func AdminQueue(c *context.Context) {
+	state, err := doc.GetQueueState()
+	if err != nil {
+		adminError(c, "queue", err)
+		return
+	} else if state == nil {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "crawl queue is not enabled",
+		})
+		return
+	}
+	c.JSON(200, state)
+}
+
+// AdminMetrics responds metrics of the doc service.
+func AdminMetrics(c *context.Context) {
+	c.JSON(200, doc.GetMetrics())
+}
+
+// AdminSubscriptions responds all subscriptions of notifications.
+func AdminSubscriptions(c *context.Context) {
+	if subscriptions == nil {
+		c.JSON(200, []*notify.Subscription{})
+		return
+	}
+	c.JSON(200, subscriptions.List())
+}
+
+// AdminSubscribe adds a subscription of notifications of packages under the import path
+// prefix, "kinds" is comma-separated kinds of notifications.
+func AdminSubscribe(c *context.Context) {
+	if subscriptions == nil {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "notifications are not enabled",
+		})
+		return
+	}
+
+	sub := &notify.Subscription{
+		Prefix: c.Query("prefix"),
+		URL:    c.Query("url"),
+		Format: c.Query("format"),
+	}
+	if kinds := c.Query("kinds"); len(kinds) > 0 {
+		sub.Kinds = strings.Split(kinds, ",")
+	}
+	if err := subscriptions.Add(sub); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	log.Info("Admin: %s subscribed %q to %s", c.Data["AdminName"], sub.Prefix, sub.Format)
+	c.JSON(200, sub)
+}
+
+// AdminUnsubscribe removes the subscription of given ID.
+func AdminUnsubscribe(c *context.Context) {
+	if subscriptions == nil {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "notifications are not enabled",
+		})
+		return
+	}
+
+	if err := subscriptions.Remove(c.Query("id")); err != nil {
+		adminError(c, "unsubscribe", err)
+		return
+	}
+	c.JSON(200, map[string]interface{}{
+		"ok": true,
+	})
+}
+
+// AdminDigest responds the HTML email digest of changes of packages under
+// comma-separated import path "prefixes" in the "period", default is a week.
+// Changes are read from the event log of feeds.
+func AdminDigest(c *context.Context) {
+	period := 7 * 24 * time.Hour
+	if len(c.Query("period")) > 0 {
+		var err error
+		if period, err = time.ParseDuration(c.Query("period")); err != nil {
+			c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	until := time.Now()
+	since := until.Add(-period)
+	w := &digest.Watchlist{
+		Prefixes: strings.Split(c.Query("prefixes"), ","),
+	}
+	html, err := digest.Generate(w, doc.EventsBetween(since, until), since, until, siteURL(c)).HTML()
+	if err != nil {
+		adminError(c, "digest", err)
+		return
+	}
+	c.Resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	c.Resp.Write(html)
+}
+
+var subscriptions *notify.Subscriptions
+
+// InitNotifier opens subscriptions and returns the notifier of them.
+func InitNotifier() (*notify.Notifier, error) {
+	subs, err := notify.OpenSubscriptions(setting.Notify.Subscriptions)
+	if err != nil {
+		return nil, fmt.Errorf("open subscriptions: %v", err)
+	}
+	subscriptions = subs
+	return notify.New(subs, setting.Notify.BaseURL), nil
+}
+
+var adminBlocklist *doc.Blocklist
+
+// InitBlocklist opens the blocklist and sets it to be consulted by walks
+// along with configured block patterns.
+func InitBlocklist() error {
+	b, err := doc.OpenBlocklist(setting.Current().Blocklist)
+	if err != nil {
+		return err
+	}
+	adminBlocklist = b
+	doc.SetBlocklist(b)
+	doc.SetBlockPatterns(setting.Current().BlockPatterns)
+	return nil
+}
+
+// RejectBlocked responds 404 for pages and generated files of blocked packages.
+func RejectBlocked(c *context.Context) {
+	for _, importPath := range requestedImportPaths(c.Req.URL.Path) {
+		if doc.IsBlocked(importPath) {
+			c.Handle(404, "RejectBlocked", nil)
+			return
+		}
+	}
+}
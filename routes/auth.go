@@ -0,0 +1,291 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/go-macaron/session"
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/models"
+	"github.com/Unknwon/gowalker/pkg/auth"
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+var (
+	authEnabled bool
+	authTokens  auth.Tokens
+	authOAuth2  *auth.OAuth2
+	authACL     auth.ACL
+)
+
+// InitAuth initializes authentication by settings, it does nothing
+// if the mode is none.
+func InitAuth() error {
+	switch setting.Auth.Mode {
+	case "", "none":
+		return nil
+	case "token":
+		if len(setting.Auth.TokenFile) == 0 {
+			return fmt.Errorf("token mode requires a token file")
+		}
+	case "oauth2":
+		authOAuth2 = &auth.OAuth2{
+			ClientID:     setting.Auth.ClientID,
+			ClientSecret: setting.Auth.ClientSecret,
+			AuthURL:      setting.Auth.AuthURL,
+			TokenURL:     setting.Auth.TokenURL,
+			UserInfoURL:  setting.Auth.UserInfoURL,
+			RedirectURL:  setting.Auth.RedirectURL,
+			Scopes:       setting.Auth.Scopes,
+			NameClaim:    setting.Auth.NameClaim,
+			GroupsClaim:  setting.Auth.GroupsClaim,
+		}
+		if len(setting.Auth.Issuer) > 0 {
+			if err := authOAuth2.Discover(setting.Auth.Issuer); err != nil {
+				return fmt.Errorf("discover OpenID Connect endpoints: %v", err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown auth mode %q", setting.Auth.Mode)
+	}
+
+	if len(setting.Auth.TokenFile) > 0 {
+		var err error
+		if authTokens, err = auth.LoadTokens(setting.Auth.TokenFile); err != nil {
+			return fmt.Errorf("load tokens: %v", err)
+		}
+	}
+	authACL = auth.ParseACL(setting.AuthACL)
+	authEnabled = true
+	return nil
+}
+
+// AuthEnabled returns true if users must sign in.
+func AuthEnabled() bool {
+	return authEnabled
+}
+
+// requestUser returns the user authenticated by token or session, or nil.
+func requestUser(c *context.Context, sess session.Store) *auth.User {
+	if token := c.Req.Header.Get("Authorization"); strings.HasPrefix(token, "Bearer ") {
+		return authTokens[strings.TrimPrefix(token, "Bearer ")]
+	}
+
+	name, _ := sess.Get("auth_user").(string)
+	if len(name) == 0 {
+		return nil
+	}
+	u := &auth.User{Name: name}
+	if groups, _ := sess.Get("auth_groups").(string); len(groups) > 0 {
+		u.Groups = strings.Split(groups, ",")
+	}
+	return u
+}
+
+// Pages of the site that are not documentation of packages.
+var sitePaths = []string{"/", "/search", "/search/", "/api/", "/feeds/new", "/feeds/updated",
+	"/auth/", "/admin/", "/github/", "/t/", "/embed/", "/oembed", "/robots.txt", "/opensearch.xml", "/sitemap.xml", "/sitemaps/"}
+
+// jsSuffixPattern matches suffixes of documentation and README JS files.
+var jsSuffixPattern = regexp.MustCompile(`(_RM_[a-zA-Z-]+|-\d+)?\.js$`)
+
+// requestedImportPaths returns possible import paths of the package
+// that the request accesses.
+func requestedImportPaths(reqPath string) []string {
+	if docsPrefix := "/" + setting.DocsJSPath; strings.HasPrefix(reqPath, docsPrefix) {
+		name := strings.TrimPrefix(reqPath, docsPrefix)
+		// Hyphen and digits may be part of the import path.
+		return []string{strings.TrimSuffix(name, ".js"), jsSuffixPattern.ReplaceAllString(name, "")}
+	} else if gobPrefix := "/" + setting.DocsGobPath; strings.HasPrefix(reqPath, gobPrefix) {
+		name := strings.TrimPrefix(reqPath, gobPrefix)
+		if i := strings.Index(name, "/@v/"); i >= 0 {
+			name = name[:i]
+		}
+		return []string{name}
+	} else if assetPrefix := "/" + setting.Asset.Path; strings.HasPrefix(reqPath, assetPrefix) {
+		// Assets are saved with names under import paths of packages.
+		return []string{path.Dir(strings.TrimPrefix(reqPath, assetPrefix))}
+	} else if strings.HasPrefix(reqPath, "/raw/") {
+		// Other files are checked as if names are import paths, so they are
+		// denied under prefixes of rules that the user does not match.
+		return []string{strings.TrimPrefix(reqPath, "/raw/")}
+	} else if strings.HasPrefix(reqPath, "/feeds/versions/") {
+		return []string{strings.TrimPrefix(reqPath, "/feeds/versions/")}
+	}
+
+	for _, p := range sitePaths {
+		if reqPath == p || (strings.HasSuffix(p, "/") && p != "/" && strings.HasPrefix(reqPath, p)) {
+			return nil
+		}
+	}
+	return []string{strings.TrimPrefix(reqPath, "/")}
+}
+
+// RequireAuth requires users to sign in, and checks the ACL for packages
+// being accessed.
+func RequireAuth(c *context.Context, sess session.Store) {
+	// Admin endpoints and webhooks have their own authentication.
+	if strings.HasPrefix(c.Req.URL.Path, "/auth/") || strings.HasPrefix(c.Req.URL.Path, "/admin/") ||
+		strings.HasPrefix(c.Req.URL.Path, "/github/") {
+		return
+	}
+	// Shared caches must not keep private pages.
+	c.Resp.Header().Set("Cache-Control", "private")
+
+	u := requestUser(c, sess)
+	if u == nil {
+		if authOAuth2 != nil && c.Req.Method == "GET" && len(c.Req.Header.Get("Authorization")) == 0 {
+			sess.Set("auth_redirect", c.Req.RequestURI)
+			c.Redirect("/auth/login")
+			return
+		}
+		c.Resp.Header().Set("WWW-Authenticate", `Bearer realm="Go Walker"`)
+		http.Error(c.Resp, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	for _, importPath := range requestedImportPaths(c.Req.URL.Path) {
+		if !authACL.Allowed(u, importPath) {
+			log.Trace("Auth: %s is not allowed to access %s", u.Name, importPath)
+			http.Error(c.Resp, "Forbidden", http.StatusForbidden)
+			return
+		}
+	}
+	c.Data["AuthUser"] = u
+}
+
+// canAccess returns true if the user of the request can access the package.
+func canAccess(c *context.Context, importPath string) bool {
+	if !authEnabled {
+		return true
+	}
+	u, _ := c.Data["AuthUser"].(*auth.User)
+	return authACL.Allowed(u, importPath)
+}
+
+// pkgAllowed returns the function that reports whether the package is not blocked
+// and the user of the request can access it.
+func pkgAllowed(c *context.Context) func(importPath string) bool {
+	return func(importPath string) bool {
+		return !doc.IsBlocked(importPath) && canAccess(c, importPath)
+	}
+}
+
+// filterPkgInfos removes packages that are blocked or the user of the request cannot access.
+func filterPkgInfos(c *context.Context, pinfos []*models.PkgInfo) []*models.PkgInfo {
+	isAllowed := pkgAllowed(c)
+	allowed := pinfos[:0]
+	for _, pinfo := range pinfos {
+		if isAllowed(pinfo.ImportPath) {
+			allowed = append(allowed, pinfo)
+		}
+	}
+	return allowed
+}
+
+// filterEvents removes events of packages that are blocked or the user of the request cannot access.
+func filterEvents(c *context.Context, events []*doc.DocEvent) []*doc.DocEvent {
+	allowed := events[:0]
+	for _, e := range events {
+		if !doc.IsBlocked(e.ImportPath) && canAccess(c, e.ImportPath) {
+			allowed = append(allowed, e)
+		}
+	}
+	return allowed
+}
+
+// AuthLogin redirects the user to the OAuth2 provider.
+func AuthLogin(c *context.Context, sess session.Store) {
+	if authOAuth2 == nil {
+		c.Handle(404, "AuthLogin", nil)
+		return
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		c.Handle(500, "generate state", err)
+		return
+	}
+	state := hex.EncodeToString(b)
+	sess.Set("auth_state", state)
+	c.Redirect(authOAuth2.AuthCodeURL(state))
+}
+
+// isLocalRedirect returns true if the redirect is to a page of this site. Browsers
+// treat backslashes as slashes and remove tabs and newlines, so "/\evil.com" and
+// "/\t/evil.com" are redirects to another site.
+func isLocalRedirect(redirect string) bool {
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.HasPrefix(redirect, "/\\") ||
+		strings.ContainsAny(redirect, "\t\r\n") {
+		return false
+	}
+	u, err := url.Parse(redirect)
+	return err == nil && len(u.Scheme) == 0 && len(u.Host) == 0
+}
+
+// AuthCallback signs in the user authorized by the OAuth2 provider.
+func AuthCallback(c *context.Context, sess session.Store) {
+	if authOAuth2 == nil {
+		c.Handle(404, "AuthCallback", nil)
+		return
+	}
+
+	state, _ := sess.Get("auth_state").(string)
+	if len(state) == 0 || c.Query("state") != state {
+		http.Error(c.Resp, "Invalid state", http.StatusBadRequest)
+		return
+	}
+	sess.Delete("auth_state")
+
+	u, err := authOAuth2.Exchange(c.Query("code"))
+	if err != nil {
+		c.Handle(500, "AuthCallback", err)
+		return
+	}
+
+	redirect, _ := sess.Get("auth_redirect").(string)
+	if !isLocalRedirect(redirect) {
+		redirect = "/"
+	}
+
+	// Use a new session to prevent session fixation.
+	raw, err := sess.RegenerateId(c.Context)
+	if err != nil {
+		c.Handle(500, "regenerate session", err)
+		return
+	}
+	raw.Set("auth_user", u.Name)
+	raw.Set("auth_groups", strings.Join(u.Groups, ","))
+	log.Trace("Auth: %s signed in", u.Name)
+	c.Redirect(redirect)
+}
+
+// AuthLogout signs out the user.
+func AuthLogout(c *context.Context, sess session.Store) {
+	sess.Delete("auth_user")
+	sess.Delete("auth_groups")
+	c.Redirect("/")
+}
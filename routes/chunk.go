@@ -0,0 +1,65 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+// APIChunks responds chunks of stored documentation of the package given by "path"
+// query at the version given by "v" query in JSON lines, for retrieval pipelines.
+func APIChunks(c *context.Context) {
+	importPath := strings.Trim(c.Query("path"), "/")
+	if len(importPath) == 0 {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "path is required",
+		})
+		return
+	} else if doc.IsBlocked(importPath) || !canAccess(c, importPath) {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "documentation not found",
+		})
+		return
+	}
+
+	pdoc, err := doc.StoredDoc(importPath, c.Query("v"))
+	if err != nil {
+		if err == doc.ErrDocNotFound {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"error": "documentation not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+
+	var buf bytes.Buffer
+	if err = doc.ExportChunks(&buf, pdoc); err != nil {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.Resp.Header().Set("Content-Type", "application/x-ndjson")
+	c.Resp.Write(buf.Bytes())
+}
@@ -0,0 +1,139 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	gocontext "context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Unknwon/com"
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/pkg/audit"
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+// recursiveCrawl is the latest recursive crawl.
+var recursiveCrawl struct {
+	sync.Mutex
+	crawl *doc.Crawl
+}
+
+func startCrawl(c *doc.Crawl) {
+	recursiveCrawl.Lock()
+	recursiveCrawl.crawl = c
+	recursiveCrawl.Unlock()
+
+	go func() {
+		if err := c.Run(gocontext.Background()); err == doc.ErrShuttingDown {
+			log.Info("Crawl is stopped and will be resumed")
+		} else if err != nil {
+			log.Error(2, "Failed to crawl: %v", err)
+		} else {
+			state := c.State()
+			log.Info("Crawl finished: %d walked, %d failed", state.Walked, state.Failed)
+		}
+	}()
+}
+
+// crawlEnabled returns true if walked packages are kept in the doc store.
+func crawlEnabled() bool {
+	return !setting.ProdMode || setting.DocStore.Enabled
+}
+
+// InitCrawl resumes the recursive crawl of the checkpoint if it is not done.
+func InitCrawl() error {
+	if !crawlEnabled() {
+		return nil
+	}
+
+	c, err := doc.ResumeCrawl(setting.Current().Crawler.Checkpoint)
+	if err == doc.ErrNoCheckpoint {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if state := c.State(); !state.Done {
+		log.Info("Resume crawl from %s: %d packages in frontier", setting.Current().Crawler.Checkpoint, len(state.Frontier))
+		startCrawl(c)
+	} else {
+		recursiveCrawl.Lock()
+		recursiveCrawl.crawl = c
+		recursiveCrawl.Unlock()
+	}
+	return nil
+}
+
+// AdminCrawl starts a recursive crawl from packages of comma-separated import paths
+// through their imports within "depth" hops, 0 means unlimited.
+func AdminCrawl(c *context.Context) {
+	if !crawlEnabled() {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "doc store is not enabled",
+		})
+		return
+	}
+	importPath, ok := adminImportPath(c)
+	if !ok {
+		return
+	}
+
+	recursiveCrawl.Lock()
+	running := recursiveCrawl.crawl != nil && recursiveCrawl.crawl.Running()
+	recursiveCrawl.Unlock()
+	if running {
+		c.JSON(http.StatusConflict, map[string]interface{}{
+			"error": "a crawl is running",
+		})
+		return
+	}
+
+	var seeds []string
+	for _, p := range strings.Split(importPath, ",") {
+		if p = strings.Trim(strings.TrimSpace(p), "/"); len(p) > 0 {
+			seeds = append(seeds, p)
+		}
+	}
+	crawl := doc.NewCrawl(setting.Current().Crawler.Checkpoint, seeds, com.StrTo(c.Query("depth")).MustInt())
+	startCrawl(crawl)
+	state := crawl.State()
+	adminAudit(c, audit.ActionCrawl, importPath, nil)
+	c.JSON(200, map[string]interface{}{
+		"ok":       true,
+		"frontier": len(state.Frontier),
+	})
+}
+
+// AdminCrawlState responds the state of the latest recursive crawl.
+func AdminCrawlState(c *context.Context) {
+	recursiveCrawl.Lock()
+	crawl := recursiveCrawl.crawl
+	recursiveCrawl.Unlock()
+	if crawl == nil {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "no crawl has been started",
+		})
+		return
+	}
+	c.JSON(200, map[string]interface{}{
+		"running": crawl.Running(),
+		"state":   crawl.State(),
+	})
+}
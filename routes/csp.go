@@ -0,0 +1,69 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+var cspHeader, cspPolicy string
+
+// InitCSP builds the Content-Security-Policy sent with responses, which defaults to
+// the recommended policy of documentation pages.
+func InitCSP() {
+	cspHeader = "Content-Security-Policy"
+	if setting.CSP.ReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+
+	cspPolicy = setting.CSP.Policy
+	if len(cspPolicy) == 0 {
+		var srcs []string
+		// Documentation files are distributed to the bucket.
+		if setting.DigitalOcean.Spaces.Enabled {
+			if u, err := url.Parse(setting.DigitalOcean.Spaces.BucketURL); err == nil && len(u.Host) > 0 {
+				srcs = append(srcs, u.Scheme+"://"+u.Host)
+			}
+		}
+		cspPolicy = doc.CSPPolicy(srcs...)
+	}
+	if len(setting.CSP.ReportURI) > 0 {
+		cspPolicy += "; report-uri " + setting.CSP.ReportURI
+	}
+}
+
+// withFrameAncestors returns the policy with the frame-ancestors directive
+// replaced by given sources.
+func withFrameAncestors(policy, sources string) string {
+	directives := []string{"frame-ancestors " + sources}
+	for _, d := range strings.Split(policy, ";") {
+		d = strings.TrimSpace(d)
+		if len(d) == 0 || strings.HasPrefix(strings.ToLower(d), "frame-ancestors") {
+			continue
+		}
+		directives = append(directives, d)
+	}
+	return strings.Join(directives, "; ")
+}
+
+// ContentSecurityPolicy sends the policy built by InitCSP with responses.
+func ContentSecurityPolicy(c *context.Context) {
+	c.Resp.Header().Set(cspHeader, cspPolicy)
+}
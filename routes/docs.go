@@ -15,8 +15,11 @@
 package routes
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"net/url"
 	"path"
 	"strings"
 	"time"
@@ -24,6 +27,8 @@ import (
 	"github.com/Unknwon/com"
 
 	"github.com/Unknwon/gowalker/models"
+	"github.com/Unknwon/gowalker/pkg/audit"
+	"github.com/Unknwon/gowalker/pkg/auth"
 	"github.com/Unknwon/gowalker/pkg/base"
 	"github.com/Unknwon/gowalker/pkg/context"
 	"github.com/Unknwon/gowalker/pkg/doc"
@@ -64,7 +69,10 @@ func updateHistory(ctx *context.Context, id int64) {
 
 func handleError(ctx *context.Context, err error) {
 	importPath := ctx.Params("*")
-	if err == doc.ErrInvalidRemotePath {
+	if err == doc.ErrBlocked {
+		ctx.Handle(404, "Docs", nil)
+		return
+	} else if err == doc.ErrInvalidRemotePath {
 		ctx.Redirect("/search?q=" + importPath)
 		return
 	}
@@ -79,6 +87,32 @@ func handleError(ctx *context.Context, err error) {
 	Home(ctx)
 }
 
+// requestUserName returns name of the signed-in user of the request, or empty.
+func requestUserName(c *context.Context) string {
+	if u, ok := c.Data["AuthUser"].(*auth.User); ok {
+		return u.Name
+	}
+	return ""
+}
+
+// recordAudit records the request of the package if auditing is enabled.
+func recordAudit(c *context.Context, action audit.Action, importPath string, err error) {
+	if !audit.Enabled() {
+		return
+	}
+
+	e := &audit.Event{
+		Action:     action,
+		User:       requestUserName(c),
+		RemoteAddr: c.ClientIP(),
+		ImportPath: importPath,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	audit.Record(e)
+}
+
 func specialHandles(ctx *context.Context, pinfo *models.PkgInfo) bool {
 	// Only show imports.
 	if strings.HasSuffix(ctx.Req.RequestURI, "?imports") {
@@ -103,6 +137,7 @@ func specialHandles(ctx *context.Context, pinfo *models.PkgInfo) bool {
 		} else {
 			importPath := ctx.Params("*")
 			_, err := doc.CheckPackage(importPath, ctx.Render, doc.RequestTypeRefresh)
+			recordAudit(ctx, audit.ActionRefresh, importPath, err)
 			if err != nil {
 				handleError(ctx, err)
 				return true
@@ -115,6 +150,18 @@ func specialHandles(ctx *context.Context, pinfo *models.PkgInfo) bool {
 	return false
 }
 
+// docsETag returns the strong ETag of the documentation page, which changes
+// when the documentation, statistics of the package or the locale changes.
+func docsETag(c *context.Context, pinfo *models.PkgInfo) string {
+	h := sha256.New()
+	fmt.Fprintln(h, setting.AppVer, templateHash(), c.Data["Lang"], pinfo.ImportPath, pinfo.Etag, pinfo.Created,
+		pinfo.Stars, pinfo.ImportNum, pinfo.RefNum, pinfo.Subdirs)
+	if pinfo.JSFile != nil {
+		fmt.Fprintln(h, pinfo.JSFile.Etag, pinfo.JSFile.Status, pinfo.JSFile.NumExtraFiles)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
 func Docs(c *context.Context) {
 	importPath := c.Params("*")
 
@@ -129,11 +176,42 @@ func Docs(c *context.Context) {
 		return
 	}
 
+	if newPath := doc.LegacyImportPath(importPath); len(newPath) > 0 {
+		c.Redirect("/" + newPath)
+		return
+	}
+
+	// Serve the stale page while the package is walked again, unless it is a special request.
+	key := pageKey(importPath, c.Data["Lang"].(string))
+	stale := htmlPages.get(key)
+	if len(c.Req.URL.RawQuery) > 0 {
+		stale = nil
+	}
+	if stale != nil {
+		if _, err := models.GetPkgInfo(importPath); err == models.ErrPackageVersionTooOld {
+			revalidate(c, importPath)
+			recordAudit(c, audit.ActionView, importPath, nil)
+			servePage(c, stale)
+			return
+		}
+	}
+
+	start := time.Now().Unix()
 	pinfo, err := doc.CheckPackage(importPath, c.Render, doc.RequestTypeHuman)
 	if err != nil {
+		if (err == doc.ErrWalkInProgress || err == doc.ErrShuttingDown) && stale != nil {
+			recordAudit(c, audit.ActionView, importPath, nil)
+			servePage(c, stale)
+			return
+		}
+		recordAudit(c, audit.ActionWalk, importPath, err)
 		handleError(c, err)
 		return
 	}
+	if pinfo.Created >= start {
+		recordAudit(c, audit.ActionWalk, importPath, nil)
+	}
+	recordAudit(c, audit.ActionView, importPath, nil)
 
 	c.PageIs("Docs")
 	c.Title(pinfo.ImportPath)
@@ -141,6 +219,7 @@ func Docs(c *context.Context) {
 	c.Data["ProjectName"] = path.Base(pinfo.ImportPath)
 	c.Data["ProjectPath"] = pinfo.ProjectPath
 	c.Data["NumStars"] = pinfo.Stars
+	c.Data["OEmbedURL"] = "/oembed?url=" + url.QueryEscape(siteURL(c)+pinfo.ImportPath)
 
 	if specialHandles(c, pinfo) {
 		return
@@ -150,6 +229,26 @@ func Docs(c *context.Context) {
 		c.Flash.Info(c.Tr("docs.turn_into_search", importPath), true)
 	}
 
+	updateHistory(c, pinfo.ID)
+
+	if localizedDocs(c, pinfo.ImportPath) {
+		return
+	}
+
+	// Pages with flash messages are not cacheable.
+	var etag string
+	if _, hasFlash := c.Data["Flash"]; !hasFlash && time.Now().Unix()-pinfo.Created > 5 {
+		etag = docsETag(c, pinfo)
+		c.Resp.Header().Set("Vary", "Accept-Language, Cookie")
+		if c.CheckModified(etag, time.Unix(pinfo.Created, 0)) {
+			return
+		}
+		if page := htmlPages.get(key); page != nil && page.ETag == etag {
+			servePage(c, page)
+			return
+		}
+	}
+
 	c.Data["PkgDesc"] = pinfo.Synopsis
 
 	// README
@@ -202,7 +301,9 @@ func Docs(c *context.Context) {
 	c.Data["TimeDuration"] = base.TimeSince(time.Unix(pinfo.Created, 0), c.Locale.Language())
 	c.Data["CanRefresh"] = pinfo.CanRefresh()
 
-	updateHistory(c, pinfo.ID)
-
-	c.Success(DOCS)
+	if len(etag) == 0 {
+		c.Success(DOCS)
+		return
+	}
+	renderPage(c, key, etag)
 }
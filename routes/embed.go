@@ -0,0 +1,158 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Unknwon/com"
+
+	"github.com/Unknwon/gowalker/models"
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+// Default size of embedded iframes in oEmbed responses.
+const (
+	embedWidth  = 600
+	embedHeight = 200
+)
+
+// symbolAnchor returns anchor of the symbol in documentation pages.
+func symbolAnchor(name string) string {
+	return strings.Replace(name, ".", "_", 1)
+}
+
+// Embed renders documentation of the symbol given by "s" query of the package
+// as a compact page that can be embedded in iframes. The version is given by
+// "v" query and defaults to the default branch.
+func Embed(c *context.Context) {
+	importPath := c.Params("*")
+	if doc.IsBlocked(importPath) || !canAccess(c, importPath) {
+		c.Handle(404, "Embed", nil)
+		return
+	}
+
+	version := c.Query("v")
+	pdoc, err := doc.StoredDoc(importPath, version)
+	if err == doc.ErrDocNotFound && len(version) == 0 {
+		// Documentation is not stored when the doc store is disabled.
+		var pinfo *models.PkgInfo
+		if pinfo, err = doc.CheckPackage(importPath, c.Render, doc.RequestTypeHuman); err == nil {
+			pdoc, err = doc.LatestDoc(pinfo)
+		}
+	}
+	if err != nil {
+		if err == doc.ErrDocNotFound || err == doc.ErrBlocked || err == doc.ErrInvalidRemotePath {
+			c.Handle(404, "Embed", nil)
+		} else {
+			c.Handle(500, "Embed", err)
+		}
+		return
+	}
+
+	sym := doc.LookupSymbol(pdoc, c.Query("s"))
+	if sym == nil {
+		c.Handle(404, "Embed", nil)
+		return
+	}
+
+	link := siteURL(c) + importPath + "#" + symbolAnchor(sym.Name)
+	var buf bytes.Buffer
+	if err = doc.WriteEmbed(&buf, pdoc, sym, link); err != nil {
+		c.Handle(500, "render embed", err)
+		return
+	}
+	c.Resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// Embeds can be framed by any site, other directives of the policy still apply.
+	if header := c.Resp.Header().Get(cspHeader); len(cspHeader) > 0 && len(header) > 0 {
+		c.Resp.Header().Set(cspHeader, withFrameAncestors(header, "*"))
+	} else {
+		c.Resp.Header().Set("Content-Security-Policy", "frame-ancestors *")
+	}
+	// Shared caches must not keep private pages.
+	if authEnabled {
+		c.Resp.Header().Set("Cache-Control", "private, max-age=3600")
+	} else {
+		c.Resp.Header().Set("Cache-Control", "public, max-age=3600")
+	}
+	c.Resp.Write(buf.Bytes())
+}
+
+// OEmbed responds oEmbed JSON of the documentation page of a symbol given by "url" query,
+// e.g. "https://gowalker.org/github.com/Unknwon/com#StrTo", which embeds the page of Embed.
+func OEmbed(c *context.Context) {
+	if format := c.Query("format"); len(format) > 0 && format != "json" {
+		c.JSON(http.StatusNotImplemented, map[string]interface{}{
+			"error": "only json format is supported",
+		})
+		return
+	}
+
+	u, err := url.Parse(c.Query("url"))
+	if err != nil || len(c.Query("url")) == 0 {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "url is invalid",
+		})
+		return
+	}
+	importPath := strings.Trim(u.Path, "/")
+	symbol := u.Fragment
+	if len(symbol) == 0 {
+		symbol = u.Query().Get("s")
+	}
+	if len(importPath) == 0 || len(symbol) == 0 {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "url does not refer to a symbol",
+		})
+		return
+	} else if doc.IsBlocked(importPath) || !canAccess(c, importPath) {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "documentation not found",
+		})
+		return
+	}
+
+	width, height := embedWidth, embedHeight
+	if max := com.StrTo(c.Query("maxwidth")).MustInt(); max > 0 && max < width {
+		width = max
+	}
+	if max := com.StrTo(c.Query("maxheight")).MustInt(); max > 0 && max < height {
+		height = max
+	}
+
+	query := url.Values{"s": {symbol}}
+	if v := u.Query().Get("v"); len(v) > 0 {
+		query.Set("v", v)
+	}
+	src := siteURL(c) + "embed/" + importPath + "?" + query.Encode()
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"type":          "rich",
+		"version":       "1.0",
+		"title":         importPath + "." + symbol,
+		"provider_name": "Go Walker",
+		"provider_url":  siteURL(c),
+		"cache_age":     3600,
+		"width":         width,
+		"height":        height,
+		"html": fmt.Sprintf(`<iframe src="%s" width="%d" height="%d" frameborder="0" style="border:1px solid #eee"></iframe>`,
+			html.EscapeString(src), width, height),
+	})
+}
@@ -0,0 +1,147 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+// feedSize is the number of entries in a feed.
+const feedSize = 50
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Link    atomLink    `xml:"link"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	ID      string   `xml:"id"`
+	Link    atomLink `xml:"link"`
+	Updated string   `xml:"updated"`
+	Summary string   `xml:"summary,omitempty"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+	Description string `xml:"description,omitempty"`
+}
+
+// eventTitle returns title of the feed entry of the event.
+func eventTitle(e *doc.DocEvent) string {
+	switch e.Kind {
+	case doc.EK_New:
+		return e.ImportPath
+	case doc.EK_Version:
+		return e.ImportPath + " " + e.Version
+	}
+	if len(e.Commit) > 0 {
+		return fmt.Sprintf("%s (%s)", e.ImportPath, e.Commit)
+	}
+	return e.ImportPath
+}
+
+// writeFeed responds events in Atom, or in RSS 2.0 if "format=rss" is given.
+func writeFeed(c *context.Context, title, link string, events []*doc.DocEvent) {
+	events = filterEvents(c, events)
+	base := siteURL(c)
+	if c.Query("format") == "rss" {
+		feed := rssFeed{
+			Version: "2.0",
+			Channel: rssChannel{
+				Title:       title,
+				Link:        base + link,
+				Description: title,
+			},
+		}
+		for _, e := range events {
+			feed.Channel.Items = append(feed.Channel.Items, rssItem{
+				Title:       eventTitle(e),
+				Link:        base + e.ImportPath,
+				GUID:        fmt.Sprintf("%s%s#%s-%s-%d", base, e.ImportPath, e.Kind, e.Version, e.Time.Unix()),
+				PubDate:     e.Time.UTC().Format(time.RFC1123Z),
+				Description: e.Synopsis,
+			})
+		}
+		writeXML(c, feed)
+		return
+	}
+
+	feed := atomFeed{
+		Title:   title,
+		ID:      base + link,
+		Link:    atomLink{base + link},
+		Updated: time.Now().UTC().Format(time.RFC3339),
+	}
+	if len(events) > 0 {
+		feed.Updated = events[0].Time.UTC().Format(time.RFC3339)
+	}
+	for _, e := range events {
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   eventTitle(e),
+			ID:      fmt.Sprintf("%s%s#%s-%s-%d", base, e.ImportPath, e.Kind, e.Version, e.Time.Unix()),
+			Link:    atomLink{base + e.ImportPath},
+			Updated: e.Time.UTC().Format(time.RFC3339),
+			Summary: e.Synopsis,
+		})
+	}
+	writeXML(c, feed)
+}
+
+// FeedNew responds the feed of newly documented packages.
+func FeedNew(c *context.Context) {
+	writeFeed(c, "New packages - Go Walker", "feeds/new", doc.RecentEvents(doc.EK_New, "", feedSize))
+}
+
+// FeedUpdated responds the feed of packages whose source files are changed.
+func FeedUpdated(c *context.Context) {
+	writeFeed(c, "Updated packages - Go Walker", "feeds/updated", doc.RecentEvents(doc.EK_Updated, "", feedSize))
+}
+
+// FeedVersions responds the feed of new tagged versions of the package.
+func FeedVersions(c *context.Context) {
+	importPath := c.Params("*")
+	writeFeed(c, "Versions of "+importPath+" - Go Walker", "feeds/versions/"+importPath,
+		doc.RecentEvents(doc.EK_Version, importPath, feedSize))
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/ghapp"
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+var githubApp *ghapp.App
+
+const (
+	// Number of pull requests reviewed at the same time.
+	reviewWorkers = 2
+	// Maximum number of pull requests waiting to be reviewed.
+	maxReviewQueue = 100
+)
+
+var reviewQueue = make(chan *ghapp.PullRequestEvent, maxReviewQueue)
+
+// InitGitHubApp initializes the GitHub App if it is enabled.
+func InitGitHubApp() (err error) {
+	if !setting.GitHubApp.Enabled {
+		return nil
+	}
+
+	githubApp, err = ghapp.New(setting.GitHubApp.AppID, setting.GitHubApp.PrivateKey, setting.GitHubApp.WebhookSecret)
+	if err != nil {
+		return err
+	}
+	if len(setting.GitHubApp.APIURL) > 0 {
+		githubApp.APIURL = setting.GitHubApp.APIURL
+	}
+
+	for i := 0; i < reviewWorkers; i++ {
+		go reviewPullRequests()
+	}
+	return nil
+}
+
+// reviewPullRequests reviews queued pull requests one by one.
+func reviewPullRequests() {
+	for e := range reviewQueue {
+		if err := githubApp.ReviewPullRequest(e); err != nil {
+			log.Error(2, "Failed to review %s#%d: %v", e.Repository.FullName, e.Number, err)
+		}
+	}
+}
+
+// Maximum size of webhook payloads that GitHub delivers.
+const maxWebhookPayload = 25 << 20
+
+// GitHubWebhook reviews pull requests in background on deliveries of pull request events.
+func GitHubWebhook(c *context.Context) {
+	payload, err := ioutil.ReadAll(io.LimitReader(c.Req.Request.Body, maxWebhookPayload))
+	if err != nil {
+		http.Error(c.Resp, err.Error(), http.StatusBadRequest)
+		return
+	} else if !githubApp.VerifySignature(payload, c.Req.Header.Get("X-Hub-Signature-256")) {
+		http.Error(c.Resp, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	// Other events, e.g. ping, are acknowledged only.
+	if c.Req.Header.Get("X-GitHub-Event") != "pull_request" {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	e := new(ghapp.PullRequestEvent)
+	if err = json.Unmarshal(payload, e); err != nil {
+		http.Error(c.Resp, err.Error(), http.StatusBadRequest)
+		return
+	} else if !e.NeedsReview() {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	// GitHub expects responses within 10 seconds, failed deliveries can be redelivered.
+	select {
+	case reviewQueue <- e:
+		c.Status(http.StatusAccepted)
+	default:
+		http.Error(c.Resp, "Too many pull requests to review", http.StatusServiceUnavailable)
+	}
+}
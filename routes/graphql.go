@@ -0,0 +1,468 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Unknwon/gowalker/models"
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/graphql"
+)
+
+// maxGraphQLRequest is the maximum size of GraphQL requests in bytes.
+const maxGraphQLRequest = 1 << 20
+
+// gqlQuery is the root object of GraphQL queries:
+//
+//	package(path: String!, version: String): Package
+//	search(q: String!, limit: Int = 20): [PackageInfo]
+//	diff(path: String!, from: String, to: String): Diff
+type gqlQuery struct {
+	c *context.Context
+}
+
+func (q *gqlQuery) TypeName() string { return "Query" }
+
+// storedDoc returns stored documentation of the package that the user can access,
+// or nil if it does not exist.
+func (q *gqlQuery) storedDoc(importPath, version string) (*doc.Package, error) {
+	importPath = strings.Trim(importPath, "/")
+	if len(importPath) == 0 || doc.IsBlocked(importPath) || !canAccess(q.c, importPath) {
+		return nil, nil
+	}
+
+	pdoc, err := doc.StoredDoc(importPath, version)
+	if err == doc.ErrDocNotFound {
+		return nil, nil
+	}
+	return pdoc, err
+}
+
+func (q *gqlQuery) Resolve(field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "package":
+		pdoc, err := q.storedDoc(graphql.String(args, "path"), graphql.String(args, "version"))
+		if err != nil || pdoc == nil {
+			return nil, err
+		}
+		return &gqlPackage{pdoc}, nil
+
+	case "search":
+		limit := graphql.Int(args, "limit", 20)
+		if limit <= 0 || limit > 100 {
+			limit = 100
+		}
+		pinfos, err := models.SearchPkgInfo(limit, cleanKeyword(graphql.String(args, "q")), pkgAllowed(q.c))
+		if err != nil {
+			return nil, err
+		}
+		pinfos = filterPkgInfos(q.c, rankResults(collapseForks(pinfos)))
+		results := make([]*gqlPackageInfo, len(pinfos))
+		for i := range pinfos {
+			results[i] = &gqlPackageInfo{pinfos[i]}
+		}
+		return results, nil
+
+	case "diff":
+		importPath := graphql.String(args, "path")
+		from, to := graphql.String(args, "from"), graphql.String(args, "to")
+		if len(from) == 0 || len(to) == 0 {
+			versions, err := doc.StoredVersions(importPath)
+			if err != nil {
+				return nil, err
+			}
+			if len(to) == 0 && len(versions) > 0 {
+				to = versions[0]
+			}
+			if len(from) == 0 && len(versions) > 1 {
+				from = versions[1]
+			}
+		}
+		if len(from) == 0 || len(to) == 0 {
+			return nil, nil
+		}
+
+		oldDoc, err := q.storedDoc(importPath, from)
+		if err != nil || oldDoc == nil {
+			return nil, err
+		}
+		newDoc, err := q.storedDoc(importPath, to)
+		if err != nil || newDoc == nil {
+			return nil, err
+		}
+		return &gqlDiff{from, to, doc.Diff(oldDoc, newDoc)}, nil
+	}
+	return nil, graphql.ErrNoField
+}
+
+// gqlPackage is the documentation of a package.
+type gqlPackage struct {
+	*doc.Package
+}
+
+func (p *gqlPackage) TypeName() string { return "Package" }
+
+func (p *gqlPackage) Resolve(field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "importPath":
+		return p.ImportPath, nil
+	case "version":
+		return p.Tag, nil
+	case "synopsis":
+		if p.PkgInfo == nil {
+			return "", nil
+		}
+		return p.Synopsis, nil
+	case "doc":
+		return p.Doc, nil
+	case "modulePath":
+		return doc.ModuleOf(p.Package), nil
+	case "deprecated":
+		return p.Deprecated, nil
+	case "supersededBy":
+		return p.SupersededBy, nil
+	case "imports":
+		return nonNilStrings(p.Imports), nil
+	case "licenses":
+		return nonNilStrings(p.Licenses), nil
+	case "versions":
+		versions, err := doc.StoredVersions(p.ImportPath)
+		return nonNilStrings(versions), err
+	case "consts":
+		return gqlValues(p.Consts), nil
+	case "vars":
+		return gqlValues(p.Vars), nil
+	case "funcs":
+		return gqlFuncs(p.Funcs), nil
+	case "types":
+		types := make([]*gqlType, len(p.Types))
+		for i := range p.Types {
+			types[i] = &gqlType{p.Types[i]}
+		}
+		return types, nil
+	case "examples":
+		return gqlExamples(p.Examples), nil
+	case "symbols":
+		return p.symbols(graphql.String(args, "kind")), nil
+	}
+	return nil, graphql.ErrNoField
+}
+
+// symbols returns all exported identifiers of the package of given kind,
+// or of all kinds if kind is empty.
+func (p *gqlPackage) symbols(kind string) []*gqlSymbol {
+	var syms []*gqlSymbol
+	add := func(k, name, text, decl, url string) {
+		if len(kind) == 0 || kind == k {
+			syms = append(syms, &gqlSymbol{Name: name, Kind: k, Doc: text, Decl: decl, URL: url})
+		}
+	}
+	addValues := func(k string, vals []*doc.Value) {
+		for _, v := range vals {
+			add(k, v.Name, v.Doc, v.Decl, v.URL)
+		}
+	}
+	addFuncs := func(k, recv string, funcs []*doc.Func) {
+		for _, f := range funcs {
+			name := f.Name
+			if len(recv) > 0 {
+				name = recv + "." + name
+			}
+			add(k, name, f.Doc, f.Decl, f.URL)
+		}
+	}
+
+	addValues("const", p.Consts)
+	addValues("var", p.Vars)
+	addFuncs("func", "", p.Funcs)
+	for _, t := range p.Types {
+		add("type", t.Name, t.Doc, t.Decl, t.URL)
+		addValues("const", t.Consts)
+		addValues("var", t.Vars)
+		addFuncs("func", "", t.Funcs)
+		addFuncs("method", t.Name, t.Methods)
+	}
+	return syms
+}
+
+// gqlSymbol is an exported identifier of a package.
+type gqlSymbol struct {
+	Name, Kind, Doc, Decl, URL string
+}
+
+func (s *gqlSymbol) TypeName() string { return "Symbol" }
+
+func (s *gqlSymbol) Resolve(field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "name":
+		return s.Name, nil
+	case "kind":
+		return s.Kind, nil
+	case "doc":
+		return s.Doc, nil
+	case "decl":
+		return s.Decl, nil
+	case "url":
+		return s.URL, nil
+	}
+	return nil, graphql.ErrNoField
+}
+
+// gqlType is an exported type of a package.
+type gqlType struct {
+	*doc.Type
+}
+
+func (t *gqlType) TypeName() string { return "Type" }
+
+func (t *gqlType) Resolve(field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "name":
+		return t.Name, nil
+	case "doc":
+		return t.Doc, nil
+	case "decl":
+		return t.Decl, nil
+	case "url":
+		return t.URL, nil
+	case "consts":
+		return gqlValues(t.Consts), nil
+	case "vars":
+		return gqlValues(t.Vars), nil
+	case "funcs":
+		return gqlFuncs(t.Funcs), nil
+	case "methods":
+		return gqlFuncs(t.Methods), nil
+	case "examples":
+		return gqlExamples(t.Examples), nil
+	}
+	return nil, graphql.ErrNoField
+}
+
+// gqlFunc is an exported function or method of a package.
+type gqlFunc struct {
+	*doc.Func
+}
+
+func gqlFuncs(funcs []*doc.Func) []*gqlFunc {
+	list := make([]*gqlFunc, len(funcs))
+	for i := range funcs {
+		list[i] = &gqlFunc{funcs[i]}
+	}
+	return list
+}
+
+func (f *gqlFunc) TypeName() string { return "Func" }
+
+func (f *gqlFunc) Resolve(field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "name":
+		return f.Name, nil
+	case "fullName":
+		return f.FullName, nil
+	case "doc":
+		return f.Doc, nil
+	case "decl":
+		return f.Decl, nil
+	case "url":
+		return f.URL, nil
+	case "code":
+		return f.Code, nil
+	case "examples":
+		return gqlExamples(f.Examples), nil
+	}
+	return nil, graphql.ErrNoField
+}
+
+// gqlValue is an exported constant or variable of a package.
+type gqlValue struct {
+	*doc.Value
+}
+
+func gqlValues(vals []*doc.Value) []*gqlValue {
+	list := make([]*gqlValue, len(vals))
+	for i := range vals {
+		list[i] = &gqlValue{vals[i]}
+	}
+	return list
+}
+
+func (v *gqlValue) TypeName() string { return "Value" }
+
+func (v *gqlValue) Resolve(field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "name":
+		return v.Name, nil
+	case "doc":
+		return v.Doc, nil
+	case "decl":
+		return v.Decl, nil
+	case "url":
+		return v.URL, nil
+	}
+	return nil, graphql.ErrNoField
+}
+
+// gqlExample is an example of a package, function or type.
+type gqlExample struct {
+	*doc.Example
+}
+
+func gqlExamples(examples []*doc.Example) []*gqlExample {
+	list := make([]*gqlExample, len(examples))
+	for i := range examples {
+		list[i] = &gqlExample{examples[i]}
+	}
+	return list
+}
+
+func (e *gqlExample) TypeName() string { return "Example" }
+
+func (e *gqlExample) Resolve(field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "name":
+		return e.Name, nil
+	case "doc":
+		return e.Doc, nil
+	case "code":
+		return e.Code, nil
+	case "output":
+		return e.Output, nil
+	}
+	return nil, graphql.ErrNoField
+}
+
+// gqlDiff is the API changes of a package between two versions.
+type gqlDiff struct {
+	from, to string
+	*doc.APIDiff
+}
+
+func (d *gqlDiff) TypeName() string { return "Diff" }
+
+func (d *gqlDiff) Resolve(field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "from":
+		return d.from, nil
+	case "to":
+		return d.to, nil
+	case "compatible":
+		return d.Compatible(), nil
+	case "added":
+		return gqlChanges(d.Added), nil
+	case "removed":
+		return gqlChanges(d.Removed), nil
+	case "changed":
+		return gqlChanges(d.Changed), nil
+	}
+	return nil, graphql.ErrNoField
+}
+
+// gqlChange is a change of an exported identifier.
+type gqlChange struct {
+	*doc.Change
+}
+
+func gqlChanges(changes []*doc.Change) []*gqlChange {
+	list := make([]*gqlChange, len(changes))
+	for i := range changes {
+		list[i] = &gqlChange{changes[i]}
+	}
+	return list
+}
+
+func (c *gqlChange) TypeName() string { return "Change" }
+
+func (c *gqlChange) Resolve(field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "name":
+		return c.Name, nil
+	case "kind":
+		return c.Kind, nil
+	case "old":
+		return c.Old, nil
+	case "new":
+		return c.New, nil
+	}
+	return nil, graphql.ErrNoField
+}
+
+// gqlPackageInfo is a package in search results.
+type gqlPackageInfo struct {
+	*models.PkgInfo
+}
+
+func (p *gqlPackageInfo) TypeName() string { return "PackageInfo" }
+
+func (p *gqlPackageInfo) Resolve(field string, args map[string]interface{}) (interface{}, error) {
+	switch field {
+	case "importPath":
+		return p.ImportPath, nil
+	case "synopsis":
+		return p.Synopsis, nil
+	case "stars":
+		return p.Stars, nil
+	case "importNum":
+		return p.ImportNum, nil
+	case "refNum":
+		return p.RefNum, nil
+	}
+	return nil, graphql.ErrNoField
+}
+
+// nonNilStrings returns an empty list instead of nil, which is encoded as null.
+func nonNilStrings(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// GraphQL executes GraphQL queries of stored documentation and search, which are
+// sent as JSON body of POST requests or as queries of GET requests.
+func GraphQL(c *context.Context) {
+	req := new(graphql.Request)
+	if c.Req.Method == "POST" {
+		if err := json.NewDecoder(io.LimitReader(c.Req.Request.Body, maxGraphQLRequest)).Decode(req); err != nil {
+			c.JSON(http.StatusBadRequest, &graphql.Response{
+				Errors: []*graphql.Error{{Message: "invalid request: " + err.Error()}},
+			})
+			return
+		}
+	} else {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if vars := c.Query("variables"); len(vars) > 0 {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, &graphql.Response{
+					Errors: []*graphql.Error{{Message: "invalid variables: " + err.Error()}},
+				})
+				return
+			}
+		}
+	}
+
+	resp := graphql.Execute(&gqlQuery{c}, req)
+	status := http.StatusOK
+	if resp.Data == nil {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, resp)
+}
@@ -0,0 +1,164 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	gocontext "context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/pkg/auth"
+	"github.com/Unknwon/gowalker/pkg/rpc"
+	"github.com/Unknwon/gowalker/pkg/setting"
+	"github.com/Unknwon/gowalker/pkg/tenant"
+)
+
+// GRPCServerOptions returns options of the gRPC server, which check calls by
+// the same authentication, ACL, tenants and rate limits as HTTP requests.
+func GRPCServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(grpcUnary),
+		grpc.StreamInterceptor(grpcStream),
+	}
+}
+
+// grpcMetadata returns the first value of the key in metadata of the call.
+func grpcMetadata(ctx gocontext.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if vals := md.Get(key); len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+// grpcCaller identifies the client of the call.
+type grpcCaller struct {
+	user   *auth.User
+	tenant *tenant.Tenant // Tenant served on the authority of the call.
+}
+
+// grpcAuthorize authenticates the client and takes a token from its bucket.
+func grpcAuthorize(ctx gocontext.Context) (*grpcCaller, error) {
+	caller := &grpcCaller{
+		tenant: tenant.ByHost(grpcMetadata(ctx, ":authority")),
+	}
+
+	if authEnabled {
+		token := grpcMetadata(ctx, "authorization")
+		if !strings.HasPrefix(token, "Bearer ") {
+			return nil, status.Error(codes.Unauthenticated, "bearer token is required")
+		}
+		caller.user = authTokens[strings.TrimPrefix(token, "Bearer ")]
+		if caller.user == nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+	}
+	if caller.tenant != nil && authEnabled && !caller.tenant.Allowed(caller.user, "") {
+		return nil, status.Error(codes.NotFound, "tenant not found")
+	}
+
+	if setting.Current().RateLimit.Enabled {
+		var ip string
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			ip = p.Addr.String()
+			if host, _, err := net.SplitHostPort(ip); err == nil {
+				ip = host
+			}
+		}
+		_, r, ok := takeToken(ip, grpcMetadata(ctx, "x-api-key"))
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "invalid API key")
+		} else if !r.Allowed {
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %s", r.RetryAfter)
+		}
+	}
+	return caller, nil
+}
+
+// allowed returns true if the client can access the package.
+func (c *grpcCaller) allowed(importPath string) bool {
+	importPath = strings.Trim(importPath, "/")
+	if !authEnabled {
+		return true
+	} else if !authACL.Allowed(c.user, importPath) {
+		return false
+	}
+	return c.tenant == nil || c.tenant.Allowed(c.user, importPath)
+}
+
+// importPathOf returns the import path that the request accesses.
+func importPathOf(req interface{}) (string, bool) {
+	switch req := req.(type) {
+	case *rpc.WalkRequest:
+		return req.ImportPath, true
+	case *rpc.GetPackageRequest:
+		return req.ImportPath, true
+	case *rpc.DiffRequest:
+		return req.ImportPath, true
+	}
+	return "", false
+}
+
+func grpcUnary(ctx gocontext.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	caller, err := grpcAuthorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if importPath, ok := importPathOf(req); ok && !caller.allowed(importPath) {
+		log.Trace("gRPC: %s is not allowed to access %s", info.FullMethod, importPath)
+		return nil, status.Error(codes.PermissionDenied, "permission denied")
+	}
+	return handler(ctx, req)
+}
+
+// grpcServerStream checks requests and filters results of streams
+// by access of the client.
+type grpcServerStream struct {
+	grpc.ServerStream
+	caller *grpcCaller
+}
+
+func (s grpcServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if importPath, ok := importPathOf(m); ok && !s.caller.allowed(importPath) {
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+	return nil
+}
+
+func (s grpcServerStream) SendMsg(m interface{}) error {
+	// Results of search must not reveal packages that the client cannot access.
+	if info, ok := m.(*rpc.PackageInfo); ok && !s.caller.allowed(info.ImportPath) {
+		return nil
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+func grpcStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	caller, err := grpcAuthorize(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, grpcServerStream{ServerStream: ss, caller: caller})
+}
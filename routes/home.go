@@ -22,6 +22,7 @@ import (
 	"github.com/Unknwon/gowalker/models"
 	"github.com/Unknwon/gowalker/pkg/base"
 	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
 )
 
 const (
@@ -61,6 +62,10 @@ func getBrowsingHistory(ctx *context.Context) []*pkgInfo {
 	}
 	pkgInfosSet := make(map[int64]*pkgInfo)
 	for i := range pkgInfos {
+		// Cookies are sent by clients, so IDs may be of packages the user cannot access.
+		if doc.IsBlocked(pkgInfos[i].ImportPath) || !canAccess(ctx, pkgInfos[i].ImportPath) {
+			continue
+		}
 		pkgInfosSet[pkgInfos[i].ID] = &pkgInfo{
 			ImportPath: pkgInfos[i].ImportPath,
 			IsGoRepo:   pkgInfos[i].IsGoRepo,
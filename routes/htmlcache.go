@@ -0,0 +1,229 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	log "gopkg.in/clog.v1"
+	"gopkg.in/macaron.v1"
+
+	"github.com/Unknwon/gowalker/pkg/audit"
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+// cachedPage is a rendered documentation page.
+type cachedPage struct {
+	key  string
+	ETag string
+	HTML []byte
+}
+
+// pageCache keeps rendered pages in memory, the least recently used
+// page is evicted when it is full. It is safe for concurrent use.
+type pageCache struct {
+	lock  sync.Mutex
+	size  int // Zero means caching is disabled.
+	order *list.List
+	pages map[string]*list.Element
+}
+
+func newPageCache(size int) *pageCache {
+	return &pageCache{
+		size:  size,
+		order: list.New(),
+		pages: make(map[string]*list.Element),
+	}
+}
+
+func (pc *pageCache) get(key string) *cachedPage {
+	if len(key) == 0 {
+		return nil
+	}
+
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	e, ok := pc.pages[key]
+	if !ok {
+		return nil
+	}
+	pc.order.MoveToFront(e)
+	return e.Value.(*cachedPage)
+}
+
+func (pc *pageCache) set(page *cachedPage) {
+	if pc.size <= 0 || len(page.key) == 0 {
+		return
+	}
+
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	if e, ok := pc.pages[page.key]; ok {
+		e.Value = page
+		pc.order.MoveToFront(e)
+		return
+	}
+	pc.pages[page.key] = pc.order.PushFront(page)
+	for pc.order.Len() > pc.size {
+		e := pc.order.Back()
+		pc.order.Remove(e)
+		delete(pc.pages, e.Value.(*cachedPage).key)
+	}
+}
+
+// purge removes all pages of the package.
+func (pc *pageCache) purge(importPath string) {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	for key, e := range pc.pages {
+		if strings.HasPrefix(key, importPath+"@") {
+			pc.order.Remove(e)
+			delete(pc.pages, key)
+		}
+	}
+}
+
+var htmlPages = newPageCache(setting.HTMLCacheSize)
+
+var tplHash struct {
+	sync.Mutex
+	hash string
+}
+
+// templateHash returns the hash of all template files, so cached pages
+// are not used after templates are changed.
+func templateHash() string {
+	tplHash.Lock()
+	defer tplHash.Unlock()
+
+	if len(tplHash.hash) == 0 {
+		h := sha256.New()
+		if err := filepath.Walk(setting.Current().TemplatesPath, func(p string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() {
+				return err
+			}
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			io.WriteString(h, p)
+			_, err = io.Copy(h, f)
+			return err
+		}); err != nil {
+			log.Error(2, "Failed to hash templates: %v", err)
+		}
+		tplHash.hash = hex.EncodeToString(h.Sum(nil)[:8])
+	}
+	return tplHash.hash
+}
+
+// ResetTemplateHash hashes templates again when they are used next time,
+// which makes cached pages rendered by previous templates stale.
+func ResetTemplateHash() {
+	tplHash.Lock()
+	tplHash.hash = ""
+	tplHash.Unlock()
+}
+
+// pageKey returns the cache key of documentation page of given import path and language.
+// Only the default branch is rendered, so the version is always empty.
+// It returns empty string when pages must not be cached, e.g. pages differ by signed in
+// users and their permissions when auth is enabled.
+func pageKey(importPath, lang string) string {
+	if authEnabled {
+		return ""
+	}
+	return importPath + "@:" + lang + ":" + templateHash()
+}
+
+func servePage(c *context.Context, page *cachedPage) {
+	c.Resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	c.Resp.Write(page.HTML)
+}
+
+// renderPage renders the documentation page, saves it to the cache and responds it.
+func renderPage(c *context.Context, key, etag string) {
+	data, err := c.Render.HTMLBytes(DOCS, c.Data)
+	if err != nil {
+		c.Handle(500, "render docs", err)
+		return
+	}
+
+	page := &cachedPage{
+		key:  key,
+		ETag: etag,
+		HTML: data,
+	}
+	htmlPages.set(page)
+	servePage(c, page)
+}
+
+// backgroundRender renders documentation of packages walked in background,
+// which must not use renderer of the request that has been responded.
+var backgroundRender atomic.Value // macaron.Render
+
+// SetBackgroundRender sets the renderer of current templates that is not tied to any request.
+func SetBackgroundRender(render macaron.Render) {
+	if render == nil {
+		log.Error(2, "Failed to create renderer for background walks")
+		return
+	}
+	backgroundRender.Store(render)
+}
+
+// Import paths of packages being walked in background.
+var revalidating sync.Map
+
+// revalidate walks the package in background while the stale page is served.
+func revalidate(c *context.Context, importPath string) {
+	render, _ := backgroundRender.Load().(macaron.Render)
+	if render == nil {
+		return
+	} else if _, loaded := revalidating.LoadOrStore(importPath, true); loaded {
+		return
+	}
+
+	e := &audit.Event{
+		Action:     audit.ActionWalk,
+		User:       requestUserName(c),
+		RemoteAddr: c.ClientIP(),
+		ImportPath: importPath,
+	}
+	go func() {
+		defer revalidating.Delete(importPath)
+		_, err := doc.CheckPackage(importPath, render, doc.RequestTypeHuman)
+		if err == doc.ErrWalkInProgress || err == doc.ErrShuttingDown {
+			return
+		} else if err != nil {
+			log.Error(2, "Failed to revalidate %q: %v", importPath, err)
+			e.Error = err.Error()
+		}
+		audit.Record(e)
+	}()
+}
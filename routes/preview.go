@@ -0,0 +1,109 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+const (
+	PREVIEW = "preview"
+)
+
+// previewSlots limits number of uploads walked at the same time.
+var previewSlots chan struct{}
+
+// InitPreview enables previews with at most n uploads walked at the same time.
+func InitPreview(n int) {
+	previewSlots = make(chan struct{}, n)
+}
+
+// Preview shows the form to upload an archive for previewing its documentation.
+func Preview(c *context.Context) {
+	if !setting.Preview.Enabled {
+		c.Handle(http.StatusNotFound, "Preview", nil)
+		return
+	}
+
+	c.PageIs("Preview")
+	c.Data["Title"] = c.Tr("preview.title")
+	c.Success(PREVIEW)
+}
+
+// PreviewPost renders documentation of the uploaded archive, which is
+// neither stored nor indexed, and its examples are never shared.
+func PreviewPost(c *context.Context) {
+	if !setting.Preview.Enabled || previewSlots == nil {
+		c.Handle(http.StatusNotFound, "Preview", nil)
+		return
+	}
+
+	c.PageIs("Preview")
+	c.Data["Title"] = c.Tr("preview.title")
+
+	select {
+	case previewSlots <- struct{}{}:
+		defer func() { <-previewSlots }()
+	default:
+		c.Flash.ErrorMsg = template.HTMLEscapeString("Too many previews in progress, please try again later.")
+		c.Data["Flash"] = c.Flash
+		c.HTML(http.StatusServiceUnavailable, PREVIEW)
+		return
+	}
+
+	c.Req.Request.Body = http.MaxBytesReader(c.Resp, c.Req.Request.Body, setting.Preview.MaxSize<<10)
+	f, header, err := c.Req.FormFile("archive")
+	if err != nil {
+		c.RenderWithErr(template.HTMLEscapeString("Cannot read uploaded archive: "+err.Error()), PREVIEW, nil)
+		return
+	}
+	defer f.Close()
+
+	// Form values are available once the multipart form is parsed.
+	dir := strings.Trim(c.Req.FormValue("dir"), "/")
+	importPath := strings.Trim(c.Req.FormValue("path"), "/")
+	c.Data["Dir"] = dir
+	c.Data["PreviewPath"] = importPath
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		c.RenderWithErr(template.HTMLEscapeString("Cannot read uploaded archive: "+err.Error()), PREVIEW, nil)
+		return
+	}
+
+	pdoc, err := doc.WalkArchiveData(header.Filename, data, dir, importPath)
+	if err != nil {
+		c.RenderWithErr(template.HTMLEscapeString("Cannot walk uploaded archive: "+err.Error()), PREVIEW, nil)
+		return
+	}
+	body, err := doc.RenderHTML(c.Render, pdoc)
+	if err != nil {
+		c.Handle(http.StatusInternalServerError, "RenderHTML", err)
+		return
+	}
+
+	// Previews must not be indexed by search engines.
+	c.Resp.Header().Set("X-Robots-Tag", "noindex")
+	c.Data["ImportPath"] = pdoc.ImportPath
+	c.Data["PkgDesc"] = pdoc.Synopsis
+	c.Data["Body"] = string(body)
+	c.Success(PREVIEW)
+}
@@ -0,0 +1,115 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"crypto/subtle"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/ratelimit"
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+var rateLimiter = ratelimit.New()
+
+// isDocPath returns true if the path is of a documentation page, whose
+// first element is the host of an import path, e.g. "/github.com/...".
+func isDocPath(reqPath string) bool {
+	host := strings.TrimPrefix(reqPath, "/")
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+	return strings.Contains(host, ".") && !strings.HasSuffix(host, ".xml") && !strings.HasSuffix(host, ".txt")
+}
+
+// rateLimited returns true if the path is of a limited endpoint.
+func rateLimited(reqPath string) bool {
+	for _, p := range setting.Current().RateLimit.Paths {
+		if p == "docs" {
+			if isDocPath(reqPath) {
+				return true
+			}
+		} else if len(p) > 0 && strings.HasPrefix(reqPath, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// validAPIKey returns true if the key is one of configured API keys.
+func validAPIKey(key string) bool {
+	valid := false
+	for _, k := range setting.Current().RateLimit.Keys {
+		if len(k) > 0 && subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// takeToken takes a token from the bucket of the client, which is identified
+// by the API key if present or the IP address. It returns false if the API key
+// is invalid.
+func takeToken(ip, apiKey string) (ratelimit.Limit, ratelimit.Result, bool) {
+	settings := setting.Current().RateLimit
+	key := "ip:" + ip
+	limit := ratelimit.Limit{
+		Rate:  settings.Rate,
+		Burst: settings.Burst,
+	}
+	if len(apiKey) > 0 {
+		if !validAPIKey(apiKey) {
+			return limit, ratelimit.Result{}, false
+		}
+		key = "key:" + apiKey
+		limit = ratelimit.Limit{
+			Rate:  settings.KeyRate,
+			Burst: settings.KeyBurst,
+		}
+	}
+	return limit, rateLimiter.Allow(key, limit), true
+}
+
+// RateLimit limits rates of requests to endpoints by token buckets of clients.
+// Clients with valid API keys have their own buckets and limits, and others
+// are identified by IP addresses. It does nothing when rate limiting is disabled.
+func RateLimit(c *context.Context) {
+	if !setting.Current().RateLimit.Enabled || !rateLimited(c.Req.URL.Path) {
+		return
+	}
+
+	limit, r, ok := takeToken(c.ClientIP(), c.Req.Header.Get("X-API-Key"))
+	if !ok {
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"error": "invalid API key",
+		})
+		return
+	}
+	if limit.Unlimited() {
+		return
+	}
+	c.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+	c.Header().Set("X-RateLimit-Remaining", strconv.Itoa(r.Remaining))
+	if !r.Allowed {
+		c.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(r.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+			"error": "rate limit exceeded",
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	gocontext "context"
+	"net/http"
+
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/sandbox"
+)
+
+var (
+	exampleSandbox sandbox.Sandbox
+	sandboxLimits  sandbox.Limits
+)
+
+// InitSandbox enables running examples in the sandbox with the limits.
+func InitSandbox(sb sandbox.Sandbox, limits sandbox.Limits) {
+	exampleSandbox = sb
+	sandboxLimits = limits
+}
+
+// APIRun compiles and runs the program of "code" form value in the sandbox,
+// and responds its compile errors or outputs.
+func APIRun(c *context.Context) {
+	if exampleSandbox == nil {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "sandbox is not enabled",
+		})
+		return
+	}
+
+	code := c.Req.FormValue("code")
+	if len(code) == 0 {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "code is required",
+		})
+		return
+	}
+
+	result, err := exampleSandbox.Run(c.Req.Request.Context(), code, sandboxLimits)
+	if err != nil {
+		status := http.StatusBadGateway
+		switch err {
+		case sandbox.ErrCodeTooLarge:
+			status = http.StatusRequestEntityTooLarge
+		case gocontext.DeadlineExceeded:
+			status = http.StatusGatewayTimeout
+		case gocontext.Canceled:
+			return
+		default:
+			log.Error(2, "Failed to run code in sandbox: %v", err)
+		}
+		c.JSON(status, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -15,6 +15,8 @@
 package routes
 
 import (
+	"encoding/xml"
+	"sort"
 	"strings"
 	"unicode"
 
@@ -23,19 +25,22 @@ import (
 	"github.com/Unknwon/gowalker/models"
 	"github.com/Unknwon/gowalker/pkg/base"
 	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
 )
 
 const (
 	SEARCH = "search"
 )
 
-func Search(ctx *context.Context) {
-	q := ctx.Query("q")
-
-	// Clean up keyword.
-	q = strings.TrimFunc(q, func(c rune) bool {
+// cleanKeyword trims spaces and quotes around the search keyword.
+func cleanKeyword(q string) string {
+	return strings.TrimFunc(q, func(c rune) bool {
 		return unicode.IsSpace(c) || c == '"'
 	})
+}
+
+func Search(ctx *context.Context) {
+	q := cleanKeyword(ctx.Query("q"))
 
 	if ctx.Query("auto_redirect") == "true" &&
 		(base.IsGoRepoPath(q) || base.IsGAERepoPath(q) ||
@@ -56,8 +61,10 @@ func Search(ctx *context.Context) {
 	case "gaesdk":
 		results, err = models.GetGAERepos()
 	default:
-		results, err = models.SearchPkgInfo(100, q)
+		results, err = models.SearchPkgInfo(100, q, pkgAllowed(ctx))
+		results = rankResults(collapseForks(results))
 	}
+	results = filterPkgInfos(ctx, results)
 	if err != nil {
 		ctx.Flash.Error(err.Error(), true)
 	} else {
@@ -68,6 +75,43 @@ func Search(ctx *context.Context) {
 	ctx.HTML(200, SEARCH)
 }
 
+// collapseForks replaces forks and copies of packages with their upstream
+// packages in search results, and removes duplicates.
+func collapseForks(pinfos []*models.PkgInfo) []*models.PkgInfo {
+	seen := make(map[string]bool, len(pinfos))
+	collapsed := pinfos[:0]
+	for _, pinfo := range pinfos {
+		canonical := doc.CanonicalPath(pinfo.ImportPath)
+		if seen[canonical] {
+			continue
+		}
+		seen[canonical] = true
+
+		if canonical != pinfo.ImportPath {
+			if upstream, err := models.GetPkgInfo(canonical); err == nil {
+				pinfo = upstream
+			}
+		}
+		collapsed = append(collapsed, pinfo)
+	}
+	return collapsed
+}
+
+// rankResults orders search results by priority and then quality score.
+func rankResults(pinfos []*models.PkgInfo) []*models.PkgInfo {
+	scores := make(map[string]float64, len(pinfos))
+	for _, pinfo := range pinfos {
+		scores[pinfo.ImportPath] = doc.Score(pinfo.ImportPath)
+	}
+	sort.SliceStable(pinfos, func(i, j int) bool {
+		if pinfos[i].Priority != pinfos[j].Priority {
+			return pinfos[i].Priority > pinfos[j].Priority
+		}
+		return scores[pinfos[i].ImportPath] > scores[pinfos[j].ImportPath]
+	})
+	return pinfos
+}
+
 type searchResult struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
@@ -75,18 +119,14 @@ type searchResult struct {
 }
 
 func SearchJSON(ctx *context.Context) {
-	q := ctx.Query("q")
-
-	// Clean up keyword
-	q = strings.TrimFunc(q, func(c rune) bool {
-		return unicode.IsSpace(c) || c == '"'
-	})
+	q := cleanKeyword(ctx.Query("q"))
 
-	pinfos, err := models.SearchPkgInfo(7, q)
+	pinfos, err := models.SearchPkgInfo(7, q, pkgAllowed(ctx))
 	if err != nil {
 		log.Error(2, "SearchPkgInfo '%s': %v", q, err)
 		return
 	}
+	pinfos = filterPkgInfos(ctx, rankResults(collapseForks(pinfos)))
 
 	results := make([]*searchResult, len(pinfos))
 	for i := range pinfos {
@@ -101,3 +141,81 @@ func SearchJSON(ctx *context.Context) {
 		"results": results,
 	})
 }
+
+// SearchSuggest responds import paths that match the keyword in the format of
+// OpenSearch suggestions, which is used by autocomplete of browser address bar.
+func SearchSuggest(ctx *context.Context) {
+	q := cleanKeyword(ctx.Query("q"))
+	pinfos, err := models.SearchPkgInfo(7, q, pkgAllowed(ctx))
+	if err != nil {
+		log.Error(2, "SearchPkgInfo '%s': %v", q, err)
+		ctx.JSON(200, []interface{}{q, []string{}})
+		return
+	}
+	pinfos = filterPkgInfos(ctx, rankResults(collapseForks(pinfos)))
+
+	base := siteURL(ctx)
+	paths := make([]string, len(pinfos))
+	synopses := make([]string, len(pinfos))
+	urls := make([]string, len(pinfos))
+	for i := range pinfos {
+		paths[i] = pinfos[i].ImportPath
+		synopses[i] = pinfos[i].Synopsis
+		urls[i] = base + pinfos[i].ImportPath
+	}
+
+	ctx.JSON(200, []interface{}{q, paths, synopses, urls})
+}
+
+type openSearchDescription struct {
+	XMLName       xml.Name        `xml:"http://a9.com/-/spec/opensearch/1.1/ OpenSearchDescription"`
+	ShortName     string          `xml:"ShortName"`
+	Description   string          `xml:"Description"`
+	InputEncoding string          `xml:"InputEncoding"`
+	Image         openSearchImage `xml:"Image"`
+	URLs          []openSearchURL `xml:"Url"`
+}
+
+type openSearchImage struct {
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+	Type   string `xml:"type,attr"`
+	URL    string `xml:",chardata"`
+}
+
+type openSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Method   string `xml:"method,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// OpenSearch responds the OpenSearch description, so browsers can add
+// Go Walker as a search engine with suggestions.
+func OpenSearch(ctx *context.Context) {
+	base := siteURL(ctx)
+	ctx.Resp.Header().Set("Content-Type", "application/opensearchdescription+xml; charset=utf-8")
+	writeXML(ctx, openSearchDescription{
+		ShortName:     "Go Walker",
+		Description:   "Search Go packages on Go Walker",
+		InputEncoding: "UTF-8",
+		Image: openSearchImage{
+			Width:  16,
+			Height: 16,
+			Type:   "image/png",
+			URL:    base + "img/favicon.png",
+		},
+		URLs: []openSearchURL{
+			{
+				Type:   "text/html",
+				Method: "get",
+				// Go to the package directly if the keyword is an import path.
+				Template: base + "search?q={searchTerms}&auto_redirect=true",
+			},
+			{
+				Type:     "application/x-suggestions+json",
+				Method:   "get",
+				Template: base + "search/suggest?q={searchTerms}",
+			},
+		},
+	})
+}
@@ -0,0 +1,74 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/index"
+)
+
+var semanticIndex *index.Index
+
+// InitSemanticSearch enables semantic search of the index.
+func InitSemanticSearch(idx *index.Index) {
+	semanticIndex = idx
+}
+
+// SemanticSearch responds packages and symbols whose documentation is most
+// similar to "q" query in meaning, at most "limit" query or 20 results.
+func SemanticSearch(c *context.Context) {
+	if semanticIndex == nil {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "semantic search is not enabled",
+		})
+		return
+	}
+
+	q := cleanKeyword(c.Query("q"))
+	if len(q) == 0 {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "q is required",
+		})
+		return
+	}
+	limit := c.QueryInt("limit")
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	// Inaccessible results are filtered afterwards.
+	matches, err := semanticIndex.SemanticSearch(q, limit*2)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	results := make([]*index.SemanticMatch, 0, limit)
+	for _, m := range matches {
+		if len(results) == limit {
+			break
+		}
+		if !doc.IsBlocked(m.ImportPath) && canAccess(c, m.ImportPath) {
+			results = append(results, m)
+		}
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"results": results,
+	})
+}
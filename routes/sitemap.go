@@ -0,0 +1,263 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Unknwon/com"
+
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+// maxSitemapSize is the maximum number of URLs in a sitemap allowed by the protocol.
+const maxSitemapSize = 50000
+
+// sitemapEntry is a package listed in sitemaps.
+type sitemapEntry struct {
+	ImportPath string
+	LastMod    time.Time // Last time any version of the package is walked.
+}
+
+// Listing stored documentation can be expensive, so entries are
+// only refreshed after sitemapTTL.
+const sitemapTTL = time.Hour
+
+var sitemapCache struct {
+	sync.Mutex
+	entries []sitemapEntry
+	updated time.Time
+}
+
+// sitemapEntries returns packages in the doc store sorted by import path.
+func sitemapEntries() ([]sitemapEntry, error) {
+	sitemapCache.Lock()
+	defer sitemapCache.Unlock()
+
+	if time.Since(sitemapCache.updated) < sitemapTTL {
+		return sitemapCache.entries, nil
+	}
+
+	metas, err := doc.StoredDocs()
+	if err != nil {
+		return nil, fmt.Errorf("list stored docs: %v", err)
+	}
+	lastMods := make(map[string]int64, len(metas))
+	for _, m := range metas {
+		// Documentation of private packages is not crawlable.
+		if doc.IsBlocked(m.ImportPath) || doc.IsPrivate(m.ImportPath) {
+			continue
+		}
+		if m.Walked > lastMods[m.ImportPath] {
+			lastMods[m.ImportPath] = m.Walked
+		}
+	}
+
+	entries := make([]sitemapEntry, 0, len(lastMods))
+	for importPath, walked := range lastMods {
+		entries = append(entries, sitemapEntry{importPath, time.Unix(walked, 0)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ImportPath < entries[j].ImportPath
+	})
+
+	sitemapCache.entries = entries
+	sitemapCache.updated = time.Now()
+	return entries, nil
+}
+
+// accessibleEntries returns entries of packages that the user of the request can access.
+func accessibleEntries(c *context.Context, entries []sitemapEntry) []sitemapEntry {
+	if !authEnabled {
+		return entries
+	}
+	allowed := make([]sitemapEntry, 0, len(entries))
+	for _, e := range entries {
+		if canAccess(c, e.ImportPath) {
+			allowed = append(allowed, e)
+		}
+	}
+	return allowed
+}
+
+func sitemapPageSize() int {
+	if setting.Sitemap.PageSize <= 0 || setting.Sitemap.PageSize > maxSitemapSize {
+		return maxSitemapSize
+	}
+	return setting.Sitemap.PageSize
+}
+
+// pageEnd returns the end index of entries of given page.
+func pageEnd(page, size, total int) int {
+	if page*size > total {
+		return total
+	}
+	return page * size
+}
+
+// siteURL returns absolute URL of the site with trailing slash.
+func siteURL(c *context.Context) string {
+	if len(setting.Sitemap.BaseURL) > 0 {
+		return strings.TrimSuffix(setting.Sitemap.BaseURL, "/") + "/"
+	}
+
+	scheme := "http"
+	if c.Req.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Req.Host + "/"
+}
+
+func writeXML(c *context.Context, v interface{}) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		c.Handle(500, "marshal XML", err)
+		return
+	}
+	if len(c.Resp.Header().Get("Content-Type")) == 0 {
+		c.Resp.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	}
+	c.Resp.Write([]byte(xml.Header))
+	c.Resp.Write(data)
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name           `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []sitemapIndexItem `xml:"sitemap"`
+}
+
+type sitemapIndexItem struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// SitemapIndex responds the index of sitemaps, each of which lists
+// at most PAGE_SIZE packages in the doc store.
+func SitemapIndex(c *context.Context) {
+	// Private documentation is not crawlable.
+	if authEnabled {
+		c.Handle(404, "SitemapIndex", nil)
+		return
+	}
+	entries, err := sitemapEntries()
+	if err != nil {
+		c.Handle(500, "SitemapIndex", err)
+		return
+	}
+	entries = accessibleEntries(c, entries)
+
+	base := siteURL(c)
+	size := sitemapPageSize()
+	index := sitemapIndex{Sitemaps: []sitemapIndexItem{}}
+	for page := 1; (page-1)*size < len(entries); page++ {
+		var lastMod time.Time
+		for _, e := range entries[(page-1)*size : pageEnd(page, size, len(entries))] {
+			if e.LastMod.After(lastMod) {
+				lastMod = e.LastMod
+			}
+		}
+		index.Sitemaps = append(index.Sitemaps, sitemapIndexItem{
+			Loc:     fmt.Sprintf("%ssitemaps/%d.xml", base, page),
+			LastMod: lastMod.UTC().Format(time.RFC3339),
+		})
+	}
+	writeXML(c, index)
+}
+
+type urlSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []urlSetItem `xml:"url"`
+}
+
+type urlSetItem struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Sitemap responds the sitemap of given page, which starts from 1.
+func Sitemap(c *context.Context) {
+	// Private documentation is not crawlable.
+	if authEnabled {
+		c.Handle(404, "Sitemap", nil)
+		return
+	}
+	page := com.StrTo(strings.TrimSuffix(c.Params(":page"), ".xml")).MustInt()
+	entries, err := sitemapEntries()
+	if err != nil {
+		c.Handle(500, "Sitemap", err)
+		return
+	}
+	entries = accessibleEntries(c, entries)
+
+	size := sitemapPageSize()
+	if page < 1 || (page-1)*size >= len(entries) {
+		c.Handle(404, "Sitemap", nil)
+		return
+	}
+
+	base := siteURL(c)
+	set := urlSet{}
+	for _, e := range entries[(page-1)*size : pageEnd(page, size, len(entries))] {
+		set.URLs = append(set.URLs, urlSetItem{
+			Loc:     base + e.ImportPath,
+			LastMod: e.LastMod.UTC().Format(time.RFC3339),
+		})
+	}
+	writeXML(c, set)
+}
+
+// customRobotsPath is the path of robots.txt that replaces the generated one.
+const customRobotsPath = "custom/robots.txt"
+
+// Robots responds robots.txt, which refers to sitemaps when the doc store is enabled.
+func Robots(c *context.Context) {
+	c.Resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if com.IsFile(customRobotsPath) {
+		data, err := ioutil.ReadFile(customRobotsPath)
+		if err != nil {
+			c.Handle(500, "Robots", err)
+			return
+		}
+		c.Resp.Write(data)
+		return
+	}
+
+	var buf strings.Builder
+	buf.WriteString("User-agent: *\n")
+	if authEnabled {
+		buf.WriteString("Disallow: /\n")
+		c.Resp.Write([]byte(buf.String()))
+		return
+	}
+	for _, p := range setting.Robots.Disallow {
+		fmt.Fprintf(&buf, "Disallow: %s\n", p)
+	}
+	if setting.Robots.CrawlDelay > 0 {
+		fmt.Fprintf(&buf, "Crawl-delay: %d\n", setting.Robots.CrawlDelay)
+	}
+	// Same condition as the doc store is set.
+	if !setting.ProdMode || setting.DocStore.Enabled {
+		fmt.Fprintf(&buf, "Sitemap: %ssitemap.xml\n", siteURL(c))
+	}
+	c.Resp.Write([]byte(buf.String()))
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+// stabilityResult evaluates API stability of the package in the request, and responds
+// errors if it fails.
+func stabilityResult(c *context.Context) (*doc.StabilityResult, bool) {
+	importPath := strings.Trim(c.Query("path"), "/")
+	if len(importPath) == 0 {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "path is required",
+		})
+		return nil, false
+	} else if doc.IsBlocked(importPath) || !canAccess(c, importPath) {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "documentation not found",
+		})
+		return nil, false
+	}
+
+	r, err := doc.CheckStoredStability(importPath, c.Query("from"), c.Query("to"), doc.StabilityPolicy{
+		AllowV0Breaking:  setting.Stability.AllowV0Breaking,
+		NoPatchAdditions: setting.Stability.NoPatchAdditions,
+	})
+	if err != nil {
+		if err == doc.ErrDocNotFound {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"error": "documentation of versions not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		return nil, false
+	}
+	return r, true
+}
+
+// APIStability responds whether API changes of the package between versions
+// follow the stability policy. Versions are given by "from" and "to" queries,
+// and default to the latest two tagged versions. It responds 409 when the
+// changes fail, so that it can be used by CI to gate releases.
+func APIStability(c *context.Context) {
+	r, ok := stabilityResult(c)
+	if !ok {
+		return
+	}
+
+	status := http.StatusOK
+	if !r.Pass {
+		status = http.StatusConflict
+	}
+	c.JSON(status, r)
+}
+
+// StabilityBadge redirects to the badge of API stability of the package between versions.
+func StabilityBadge(c *context.Context) {
+	r, ok := stabilityResult(c)
+	if !ok {
+		return
+	}
+
+	status, color := "stable", "green"
+	if !r.Pass {
+		status, color = "breaking", "red"
+	}
+	label := strings.Replace(url.PathEscape(r.NewVersion), "-", "--", -1)
+	if authEnabled {
+		c.Resp.Header().Set("Cache-Control", "private, max-age=3600")
+	} else {
+		c.Resp.Header().Set("Cache-Control", "public, max-age=3600")
+	}
+	c.Redirect("https://img.shields.io/badge/API%20" + label + "-" + status + "-" + color + ".svg?style=flat-square")
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+// maxSymbolPageSize is the maximum number of declarations in a page.
+const maxSymbolPageSize = 500
+
+// APISymbols responds a page of declarations of stored documentation of the package
+// given by "path" query at the version given by "v" query, the kind is given by "kind"
+// query and the page by "offset" and "limit" queries.
+func APISymbols(c *context.Context) {
+	importPath := strings.Trim(c.Query("path"), "/")
+	if len(importPath) == 0 {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "path is required",
+		})
+		return
+	} else if doc.IsBlocked(importPath) || !canAccess(c, importPath) {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "documentation not found",
+		})
+		return
+	}
+
+	limit := c.QueryInt("limit")
+	if limit <= 0 || limit > maxSymbolPageSize {
+		limit = maxSymbolPageSize
+	}
+
+	pdoc, err := doc.StoredDoc(importPath, c.Query("v"))
+	if err != nil {
+		if err == doc.ErrDocNotFound {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"error": "documentation not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+
+	page := pdoc.SymbolPage(c.Query("kind"), c.QueryInt("offset"), limit)
+	if page == nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "kind must be one of const, var, func and type",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,200 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Unknwon/com"
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/pkg/auth"
+	"github.com/Unknwon/gowalker/pkg/base"
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/index"
+	"github.com/Unknwon/gowalker/pkg/tenant"
+)
+
+const (
+	TENANT_HOME = "tenant/home"
+	TENANT_DOCS = "tenant/docs"
+)
+
+// TenantHosts serves tenants at root path of their hosts by prefixing paths
+// of requests with "/t/<id>", except for static files and sign-in endpoints.
+func TenantHosts(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t := tenant.ByHost(r.Host); t != nil &&
+			!strings.HasPrefix(r.URL.Path, "/t/") && !strings.HasPrefix(r.URL.Path, "/auth/") &&
+			!com.IsFile("public"+r.URL.Path) {
+			r.URL.Path = "/t/" + t.ID + r.URL.Path
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// tenantUser returns the signed-in user, or nil if auth is disabled.
+func tenantUser(c *context.Context) *auth.User {
+	u, _ := c.Data["AuthUser"].(*auth.User)
+	return u
+}
+
+// tenantAllowed returns true if the user of the request can access the package
+// of the tenant, all users can access tenants when auth is disabled, in which case
+// tenants have neither principals nor ACLs.
+func tenantAllowed(c *context.Context, t *tenant.Tenant, importPath string) bool {
+	return !authEnabled || t.Allowed(tenantUser(c), importPath)
+}
+
+// Tenanter finds the tenant of the request and checks if the user is its member.
+func Tenanter(c *context.Context) {
+	t := tenant.Get(c.Params(":tenant"))
+	if t == nil || !tenantAllowed(c, t, "") {
+		c.Handle(http.StatusNotFound, "Tenanter", nil)
+		return
+	}
+	c.Map(t)
+	c.Data["Tenant"] = t
+	c.Data["TenantLink"] = "/t/" + t.ID
+}
+
+// TenantHome lists stored packages of the tenant.
+func TenantHome(c *context.Context, t *tenant.Tenant) {
+	paths, err := t.Packages()
+	if err != nil {
+		c.Handle(http.StatusInternalServerError, "Packages", err)
+		return
+	}
+
+	allowed := paths[:0]
+	for _, p := range paths {
+		if tenantAllowed(c, t, p) {
+			allowed = append(allowed, p)
+		}
+	}
+	c.Data["Title"] = t.Name
+	c.Data["Packages"] = allowed
+	c.Success(TENANT_HOME)
+}
+
+// TenantSearch responds symbols of packages of the tenant that match the query in JSON.
+func TenantSearch(c *context.Context, t *tenant.Tenant) {
+	q, err := index.ParseQuery(c.Query("q"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	matches := t.Index.Search(q, 50)
+	allowed := matches[:0]
+	for _, m := range matches {
+		if tenantAllowed(c, t, m.ImportPath) {
+			allowed = append(allowed, m)
+		}
+	}
+	c.JSON(200, map[string]interface{}{
+		"results": allowed,
+	})
+}
+
+// tenantPages keeps rendered documentation of tenants in memory.
+var tenantPages = newPageCache(200)
+
+// tenantPageKey returns the cache key of rendered documentation of the package of the tenant.
+func tenantPageKey(t *tenant.Tenant, importPath, etag string) string {
+	return t.ID + "/" + importPath + "@" + etag + ":" + templateHash()
+}
+
+// TenantDocs renders stored documentation of the package of the tenant,
+// the package is walked into the tenant if it is not stored yet.
+// Stored packages are walked again by TenantWalk.
+func TenantDocs(c *context.Context, t *tenant.Tenant) {
+	importPath := strings.Trim(c.Params("*"), "/")
+	if !base.IsWellFormedPath(importPath) || !tenantAllowed(c, t, importPath) {
+		c.Handle(http.StatusNotFound, "TenantDocs", nil)
+		return
+	}
+
+	pdoc, err := t.Store.Get(importPath, "")
+	if err == doc.ErrDocNotFound {
+		pdoc, err = t.Walk(importPath)
+	}
+	if err != nil {
+		switch err {
+		case doc.ErrBlocked, doc.ErrInvalidRemotePath:
+			c.Handle(http.StatusNotFound, "TenantDocs", nil)
+		case doc.ErrQuotaExceeded:
+			c.Handle(http.StatusForbidden, "TenantDocs", err)
+		default:
+			log.Error(2, "Tenant %s: get %s: %v", t.ID, importPath, err)
+			c.Handle(http.StatusInternalServerError, "TenantDocs", err)
+		}
+		return
+	}
+
+	key := tenantPageKey(t, importPath, pdoc.Etag)
+	var body []byte
+	if page := tenantPages.get(key); page != nil {
+		body = page.HTML
+	} else {
+		body, err = doc.RenderHTML(c.Render, pdoc)
+		if err != nil {
+			c.Handle(http.StatusInternalServerError, "RenderHTML", err)
+			return
+		}
+		tenantPages.set(&cachedPage{key: key, HTML: body})
+	}
+	c.PageIs("Docs")
+	c.Data["Title"] = importPath
+	c.Data["ImportPath"] = importPath
+	c.Data["PkgDesc"] = pdoc.Synopsis
+	c.Data["Body"] = string(body)
+	c.Success(TENANT_DOCS)
+}
+
+// TenantWalk walks the package of query "path" into the tenant.
+func TenantWalk(c *context.Context, t *tenant.Tenant) {
+	importPath := strings.Trim(c.Query("path"), "/")
+	if !base.IsWellFormedPath(importPath) || !tenantAllowed(c, t, importPath) {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid import path",
+		})
+		return
+	}
+
+	pdoc, err := t.Walk(importPath)
+	tenantPages.purge(t.ID + "/" + importPath)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err {
+		case doc.ErrBlocked, doc.ErrInvalidRemotePath:
+			status = http.StatusNotFound
+		case doc.ErrQuotaExceeded:
+			status = http.StatusForbidden
+		}
+		c.JSON(status, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(200, map[string]interface{}{
+		"import_path": pdoc.ImportPath,
+		"synopsis":    pdoc.Synopsis,
+	})
+}
@@ -0,0 +1,113 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+const DOCS_LOCALIZED = "docs/localized"
+
+// APIStrings responds translatable doc comments of stored documentation of the package
+// given by "path" query at the version given by "v" query, in the format given by "format"
+// query, which is "pot" by default or "json".
+func APIStrings(c *context.Context) {
+	importPath := strings.Trim(c.Query("path"), "/")
+	if len(importPath) == 0 {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "path is required",
+		})
+		return
+	} else if doc.IsBlocked(importPath) || !canAccess(c, importPath) {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "documentation not found",
+		})
+		return
+	}
+
+	format := c.Query("format")
+	if len(format) == 0 {
+		format = "pot"
+	}
+
+	pdoc, err := doc.StoredDoc(importPath, c.Query("v"))
+	if err != nil {
+		if err == doc.ErrDocNotFound {
+			c.JSON(http.StatusNotFound, map[string]interface{}{
+				"error": "documentation not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+
+	var buf bytes.Buffer
+	if err = doc.ExportStrings(&buf, pdoc, format); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if format == "json" {
+		c.Resp.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	} else {
+		c.Resp.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+	}
+	c.Resp.Write(buf.Bytes())
+}
+
+// localizedDocs renders stored documentation of the package with translations to the
+// language of the request, it returns false if there are no translations to use.
+func localizedDocs(c *context.Context, importPath string) bool {
+	lang, _ := c.Data["Lang"].(string)
+	cat, err := doc.LoadCatalog(importPath, lang)
+	if err != nil {
+		if err != doc.ErrNoCatalog {
+			log.Error(2, "Failed to load catalog of %s in %s: %v", importPath, lang, err)
+		}
+		return false
+	}
+
+	pdoc, err := doc.StoredDoc(importPath, "")
+	if err != nil {
+		if err != doc.ErrDocNotFound {
+			log.Error(2, "Failed to get stored documentation of %s: %v", importPath, err)
+		}
+		return false
+	}
+	if doc.ApplyTranslations(pdoc, cat) == 0 {
+		return false
+	}
+
+	body, err := doc.RenderHTML(c.Render, pdoc)
+	if err != nil {
+		log.Error(2, "Failed to render localized documentation of %s: %v", importPath, err)
+		return false
+	}
+	c.Data["PkgDesc"] = pdoc.Synopsis
+	c.Data["Body"] = string(body)
+	c.Success(DOCS_LOCALIZED)
+	return true
+}
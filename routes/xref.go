@@ -0,0 +1,54 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routes
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Unknwon/gowalker/pkg/context"
+	"github.com/Unknwon/gowalker/pkg/doc"
+)
+
+// APIResolve responds URL of documentation of the symbol given by "symbol" query
+// declared by the package given by "path" query, e.g. "Client.Do" of "net/http".
+func APIResolve(c *context.Context) {
+	importPath := strings.Trim(c.Query("path"), "/")
+	if len(importPath) == 0 || len(c.Query("symbol")) == 0 {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "path and symbol are required",
+		})
+		return
+	} else if !canAccess(c, importPath) {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "symbol not found",
+		})
+		return
+	}
+
+	url, err := doc.ResolveRef(importPath, c.Query("symbol"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "symbol not found",
+		})
+		return
+	}
+	if strings.HasPrefix(url, "/") {
+		url = siteURL(c) + url[1:]
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"url": url,
+	})
+}
@@ -0,0 +1,78 @@
+// Copyright 2018 Unknwon
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"google.golang.org/grpc"
+	log "gopkg.in/clog.v1"
+
+	"github.com/Unknwon/gowalker/pkg/doc"
+	"github.com/Unknwon/gowalker/pkg/setting"
+)
+
+// grpcServer is the gRPC server if enabled.
+var grpcServer *grpc.Server
+
+// onShutdown registers the function to be called after walks are finished
+// when the server shuts down.
+func onShutdown(name string, fn func() error) {
+	if err := doc.RegisterShutdown(name, fn); err != nil {
+		log.Fatal(2, "Failed to register shutdown: %v", err)
+	}
+}
+
+// shutdownOnSignal shuts down servers on SIGINT or SIGTERM: stops accepting
+// requests and walks, waits for those in progress until the shutdown timeout,
+// and saves and closes stores. The returned channel is closed when it is done.
+func shutdownOnSignal(srv *http.Server) <-chan struct{} {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	stopped := make(chan struct{})
+
+	go func() {
+		log.Info("Shutting down on %v", <-sig)
+		signal.Stop(sig)
+		defer close(stopped)
+
+		ctx, cancel := context.WithTimeout(context.Background(), setting.ShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Error(2, "Failed to shut down server: %v", err)
+		}
+		if grpcServer != nil {
+			done := make(chan struct{})
+			go func() {
+				grpcServer.GracefulStop()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-ctx.Done():
+				grpcServer.Stop()
+			}
+		}
+		if err := doc.Shutdown(ctx); err != nil {
+			log.Error(2, "Failed to shut down walks: %v", err)
+		}
+		log.Info("Shut down")
+	}()
+	return stopped
+}